@@ -3,6 +3,7 @@ package main
 import (
 	"fmt"
 	"os"
+	"reflect"
 	"strconv"
 	"time"
 
@@ -15,15 +16,18 @@ import (
 	"github.com/lejianwen/rustdesk-api/v2/lib/lock"
 	"github.com/lejianwen/rustdesk-api/v2/lib/logger"
 	"github.com/lejianwen/rustdesk-api/v2/lib/orm"
+	"github.com/lejianwen/rustdesk-api/v2/lib/trace"
 	"github.com/lejianwen/rustdesk-api/v2/lib/upload"
 	"github.com/lejianwen/rustdesk-api/v2/model"
+	"github.com/lejianwen/rustdesk-api/v2/model/custom_types"
 	"github.com/lejianwen/rustdesk-api/v2/service"
 	"github.com/lejianwen/rustdesk-api/v2/utils"
 	"github.com/nicksnyder/go-i18n/v2/i18n"
 	"github.com/spf13/cobra"
+	"gorm.io/gorm"
 )
 
-const DatabaseVersion = 267
+const DatabaseVersion = 325
 
 // @title 管理系统API
 // @version 1.0
@@ -40,11 +44,30 @@ var rootCmd = &cobra.Command{
 	Use:   "apimain",
 	Short: "RUSTDESK API SERVER",
 	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		if validateConfig {
+			os.Exit(runValidateConfig())
+		}
 		InitGlobal()
 	},
 	Run: func(cmd *cobra.Command, args []string) {
 		global.Logger.Info("API SERVER START")
+		service.AllService.GeoIpService.Load()
+		service.AllService.SubscriptionService.StartOrderExpireJob()
+		service.AllService.SubscriptionService.StartReconcileJob()
+		service.AllService.SubscriptionService.StartSubscriptionExpireJob()
+		service.AllService.SubscriptionService.ResumePlanMigrations()
+		service.AllService.WebhookService.StartRetryJob()
+		service.AllService.ReminderService.StartReminderJob()
+		service.AllService.NotificationService.StartRelayTrialJob()
+		service.AllService.PeerService.StartCleanupJob()
+		service.AllService.PushService.StartOfflineJob()
+		service.AllService.RecordingService.StartCleanupJob()
+		service.AllService.LdapService.StartGroupSyncJob()
+		service.AllService.RelayWhitelistService.StartPersistence(global.Config.Rustdesk.RelayWhitelistFile)
 		http.ApiInit()
+		if err := service.AllService.RelayWhitelistService.Save(true); err != nil {
+			global.Logger.Error("Save relay whitelist snapshot failed: ", err)
+		}
 	},
 }
 
@@ -99,9 +122,24 @@ var resetUserPwdCmd = &cobra.Command{
 	},
 }
 
+// validateConfig 只校验配置文件后退出, 用于升级前检查
+var validateConfig bool
+
+// runValidateConfig 校验配置并输出结果, 返回进程退出码
+func runValidateConfig() int {
+	_, errs := config.Load(&global.Config, global.ConfigPath)
+	if len(errs) > 0 {
+		fmt.Fprintf(os.Stderr, "config %s is invalid:\n%s", global.ConfigPath, config.FormatErrors(errs))
+		return 1
+	}
+	fmt.Printf("config %s is valid\n", global.ConfigPath)
+	return 0
+}
+
 func init() {
 	rootCmd.PersistentFlags().StringVarP(&global.ConfigPath, "config", "c", "./conf/config.yaml", "choose config file")
-	rootCmd.AddCommand(resetPwdCmd, resetUserPwdCmd)
+	rootCmd.PersistentFlags().BoolVar(&validateConfig, "validate-config", false, "validate the config file and exit")
+	rootCmd.AddCommand(resetPwdCmd, resetUserPwdCmd, migrateDbCmd)
 }
 func main() {
 	if err := rootCmd.Execute(); err != nil {
@@ -111,8 +149,13 @@ func main() {
 }
 
 func InitGlobal() {
-	//配置解析
-	global.Viper = config.Init(&global.Config, global.ConfigPath)
+	//配置解析, 校验失败时列出全部错误后退出
+	v, errs := config.Load(&global.Config, global.ConfigPath)
+	if len(errs) > 0 {
+		fmt.Fprintf(os.Stderr, "config %s is invalid:\n%s", global.ConfigPath, config.FormatErrors(errs))
+		os.Exit(1)
+	}
+	global.Viper = v
 
 	//日志
 	global.Logger = logger.New(&logger.Config{
@@ -181,6 +224,21 @@ func InitGlobal() {
 		}, global.Logger)
 	}
 
+	//trace
+	if global.Config.Trace.Enable {
+		trace.SetDefault(trace.New(trace.Config{
+			Endpoint:    global.Config.Trace.Endpoint,
+			ServiceName: global.Config.Trace.ServiceName,
+			SampleRatio: global.Config.Trace.SampleRatio,
+			Headers:     global.Config.Trace.Headers,
+			Timeout:     global.Config.Trace.Timeout,
+			ErrorLog:    global.Logger.Warn,
+		}))
+		if err := global.DB.Use(trace.GormPlugin{}); err != nil {
+			global.Logger.Error("Register trace gorm plugin failed: ", err)
+		}
+	}
+
 	//validator
 	global.ApiInitValidator()
 
@@ -201,7 +259,18 @@ func InitGlobal() {
 	global.Lock = lock.NewLocal()
 
 	//service
-	service.New(&global.Config, global.DB, global.Logger, global.Jwt, global.Lock)
+	var opts []service.Option
+	if global.Config.Payment.Provider == service.PaymentProviderMock {
+		global.Logger.Warn("Payment provider is mock, every checkout succeeds without real payment, do not use in production")
+		opts = append(opts, service.WithPayment(service.NewMockPaymentService()))
+	}
+	service.NewService(&service.Dependencies{
+		Config: &global.Config,
+		DB:     global.DB,
+		Logger: global.Logger,
+		Jwt:    global.Jwt,
+		Lock:   &global.Lock,
+	}, opts...)
 
 	global.LoginLimiter = utils.NewLoginLimiter(utils.SecurityPolicy{
 		CaptchaThreshold: global.Config.App.CaptchaThreshold,
@@ -211,6 +280,7 @@ func InitGlobal() {
 	})
 	global.LoginLimiter.RegisterProvider(utils.B64StringCaptchaProvider{})
 	DatabaseAutoUpdate()
+	service.AllService.SystemSettingService.MigrateSecrets()
 }
 
 func DatabaseAutoUpdate() {
@@ -283,34 +353,102 @@ func DatabaseAutoUpdate() {
 		if v.Version < 246 {
 			db.Exec("update oauths set issuer = 'https://accounts.google.com' where op = 'google' and issuer is null")
 		}
+		// 274迁移: PostgreSQL 时间字段改为 timestamptz
+		if v.Version < 274 && global.Config.Gorm.Type == config.TypePostgresql {
+			postgresqlTimestamptz(db)
+		}
 	}
 
 }
+
+// postgresqlTimestamptz 将 AutoTime 字段由 timestamp 转为 timestamptz
+// 原有数据按连接时区(postgresql.time-zone)解释, AutoMigrate 不会自动修改这类类型
+func postgresqlTimestamptz(db *gorm.DB) {
+	autoTimeType := reflect.TypeOf(custom_types.AutoTime{})
+	for _, m := range migrateModels {
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(m); err != nil {
+			global.Logger.Error("parse model err :=>", err)
+			continue
+		}
+		for _, f := range stmt.Schema.Fields {
+			if f.DBName == "" || f.FieldType != autoTimeType {
+				continue
+			}
+			if err := db.Migrator().AlterColumn(m, f.Name); err != nil {
+				global.Logger.Error("alter column err :=>", stmt.Schema.Table, ".", f.DBName, " ", err)
+			}
+		}
+	}
+}
+
+// migrateModels 需要自动迁移的数据表, 数据库迁移命令(migrate-db)也按此顺序复制
+var migrateModels = []interface{}{
+	&model.Version{},
+	&model.User{},
+	&model.UserToken{},
+	&model.Tag{},
+	&model.AddressBook{},
+	&model.Peer{},
+	&model.Group{},
+	&model.UserThird{},
+	&model.Oauth{},
+	&model.LoginLog{},
+	&model.ShareRecord{},
+	&model.AuditConn{},
+	&model.AuditFile{},
+	&model.AddressBookCollection{},
+	&model.AddressBookCollectionRule{},
+	&model.ServerCmd{},
+	&model.DeviceGroup{},
+	&model.SubscriptionPlan{},
+	&model.Order{},
+	&model.UserSubscription{},
+	&model.SystemSetting{},
+	&model.ConnPermission{},
+	&model.RedeemCode{},
+	&model.PlanPrice{},
+	&model.AdminLog{},
+	&model.Refund{},
+	&model.RefundRequest{},
+	&model.PlanMigration{},
+	&model.Product{},
+	&model.WebhookEndpoint{},
+	&model.WebhookDelivery{},
+	&model.EmailLog{},
+	&model.PaymentNotifyLog{},
+	&model.Organization{},
+	&model.OrganizationMember{},
+	&model.ReferralCode{},
+	&model.Referral{},
+	&model.SubscriptionReminder{},
+	&model.ReminderOptOut{},
+	&model.AdminNote{},
+	&model.DeviceApproval{},
+	&model.DeviceSeat{},
+	&model.ConnectionLog{},
+	&model.RelaySession{},
+	&model.RelayIncident{},
+	&model.RelayGeoRule{},
+	&model.PeerBan{},
+	&model.StrategyProfile{},
+	&model.StrategyAssignment{},
+	&model.FileTransferLog{},
+	&model.SessionRecording{},
+	&model.ApiToken{},
+	&model.InternalKey{},
+	&model.MaintenanceWindow{},
+	&model.PaymentProof{},
+	&model.Notification{},
+	&model.NotificationRead{},
+	&model.EmailToken{},
+	&model.SubscriptionEvent{},
+	&model.AdminAudit{},
+}
+
 func Migrate(version uint) {
 	global.Logger.Info("Migrating....", version)
-	err := global.DB.AutoMigrate(
-		&model.Version{},
-		&model.User{},
-		&model.UserToken{},
-		&model.Tag{},
-		&model.AddressBook{},
-		&model.Peer{},
-		&model.Group{},
-		&model.UserThird{},
-		&model.Oauth{},
-		&model.LoginLog{},
-		&model.ShareRecord{},
-		&model.AuditConn{},
-		&model.AuditFile{},
-		&model.AddressBookCollection{},
-		&model.AddressBookCollectionRule{},
-		&model.ServerCmd{},
-		&model.DeviceGroup{},
-		&model.SubscriptionPlan{},
-		&model.Order{},
-		&model.UserSubscription{},
-		&model.SystemSetting{},
-	)
+	err := global.DB.AutoMigrate(migrateModels...)
 	if err != nil {
 		global.Logger.Error("migrate err :=>", err)
 	}
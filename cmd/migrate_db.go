@@ -0,0 +1,147 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/lejianwen/rustdesk-api/v2/config"
+	"github.com/lejianwen/rustdesk-api/v2/global"
+	"github.com/lejianwen/rustdesk-api/v2/lib/orm"
+	"github.com/spf13/cobra"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	migrateFromType string
+	migrateFromDsn  string
+	migrateToType   string
+	migrateToDsn    string
+)
+
+const migrateBatchSize = 500
+
+var migrateDbCmd = &cobra.Command{
+	Use:     "migrate-db",
+	Example: "migrate-db --to mysql --to-dsn 'user:pwd@(127.0.0.1:3306)/rustdesk?charset=utf8mb4&parseTime=True&loc=Local'",
+	Short:   "Copy all tables between databases (e.g. sqlite -> mysql/postgresql)",
+	Long: `Copy all tables from the source database to the target database and verify row counts and primary key checksums.
+The source defaults to the database in the config file. The target tables must be empty.
+Stop the API server before migrating to avoid missing writes.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		src := global.DB
+		if migrateFromType != "" {
+			src = openMigrateDB(migrateFromType, migrateFromDsn)
+		}
+		if migrateToType == "" {
+			global.Logger.Error("migrate-db: --to is required")
+			return
+		}
+		dst := openMigrateDB(migrateToType, migrateToDsn)
+		if err := migrateDatabase(src, dst, migrateToType); err != nil {
+			global.Logger.Error("migrate-db fail! ", err)
+			return
+		}
+		global.Logger.Info("migrate-db success!")
+	},
+}
+
+func init() {
+	f := migrateDbCmd.Flags()
+	f.StringVar(&migrateFromType, "from", "", "source type: sqlite/mysql/postgresql, default is the configured database")
+	f.StringVar(&migrateFromDsn, "from-dsn", "", "source dsn (file path for sqlite)")
+	f.StringVar(&migrateToType, "to", "", "target type: sqlite/mysql/postgresql")
+	f.StringVar(&migrateToDsn, "to-dsn", "", "target dsn (file path for sqlite)")
+}
+
+// openMigrateDB 按类型和 DSN 打开数据库
+func openMigrateDB(typ, dsn string) *gorm.DB {
+	switch typ {
+	case config.TypeMysql:
+		return orm.NewMysql(&orm.MysqlConfig{Dsn: dsn, MaxIdleConns: 2, MaxOpenConns: 4}, global.Logger)
+	case config.TypePostgresql:
+		return orm.NewPostgresql(&orm.PostgresqlConfig{Dsn: dsn, MaxIdleConns: 2, MaxOpenConns: 4}, global.Logger)
+	default:
+		return orm.NewSqlite(&orm.SqliteConfig{Path: dsn, MaxIdleConns: 1, MaxOpenConns: 1}, global.Logger)
+	}
+}
+
+// migrateDatabase 建表 -> 逐表分批复制 -> 校验行数和主键校验和
+func migrateDatabase(src, dst *gorm.DB, dstType string) error {
+	if err := dst.AutoMigrate(migrateModels...); err != nil {
+		return err
+	}
+
+	for _, m := range migrateModels {
+		stmt := &gorm.Statement{DB: dst}
+		if err := stmt.Parse(m); err != nil {
+			return err
+		}
+		table := stmt.Schema.Table
+		// 主键不一定是 id, 如 peers 的主键是 row_id, id 是字符串类型的设备ID
+		if stmt.Schema.PrioritizedPrimaryField == nil {
+			return fmt.Errorf("table %s has no primary key", table)
+		}
+		pk := stmt.Schema.PrioritizedPrimaryField.DBName
+
+		var existing int64
+		dst.Model(m).Count(&existing)
+		if existing > 0 {
+			return fmt.Errorf("target table %s is not empty (%d rows)", table, existing)
+		}
+
+		copied, err := copyTable(src, dst, m, pk)
+		if err != nil {
+			return fmt.Errorf("copy %s: %w", table, err)
+		}
+
+		if err := verifyTable(src, dst, m, table, pk); err != nil {
+			return err
+		}
+
+		// 显式写入了主键, PostgreSQL 需要同步自增序列
+		if dstType == config.TypePostgresql {
+			dst.Exec(fmt.Sprintf("SELECT setval(pg_get_serial_sequence('%s', '%s'), COALESCE((SELECT MAX(%s) FROM %s), 0) + 1, false)", table, pk, pk, table))
+		}
+		global.Logger.Info("migrate-db: ", table, " copied ", copied, " rows")
+	}
+	return nil
+}
+
+// copyTable 分批复制单表数据(不处理关联, 关联表单独复制)
+// FindInBatches 按主键翻页, 排序必须使用同一主键, 否则超过一批后会漏行
+func copyTable(src, dst *gorm.DB, m interface{}, pk string) (int64, error) {
+	rows := reflect.New(reflect.SliceOf(reflect.TypeOf(m))).Interface()
+	var copied int64
+	res := src.Model(m).Order(pk+" ASC").FindInBatches(rows, migrateBatchSize, func(tx *gorm.DB, batch int) error {
+		if tx.RowsAffected == 0 {
+			return nil
+		}
+		if err := dst.Omit(clause.Associations).Create(rows).Error; err != nil {
+			return err
+		}
+		copied += tx.RowsAffected
+		return nil
+	})
+	return copied, res.Error
+}
+
+// verifyTable 校验源表和目标表的行数与主键之和
+func verifyTable(src, dst *gorm.DB, m interface{}, table, pk string) error {
+	type checksum struct {
+		Cnt   int64
+		IdSum int64
+	}
+	var a, b checksum
+	if err := src.Model(m).Select(fmt.Sprintf("count(*) as cnt, COALESCE(sum(%s), 0) as id_sum", pk)).Scan(&a).Error; err != nil {
+		return err
+	}
+	if err := dst.Model(m).Select(fmt.Sprintf("count(*) as cnt, COALESCE(sum(%s), 0) as id_sum", pk)).Scan(&b).Error; err != nil {
+		return err
+	}
+	if a != b {
+		return errors.New(fmt.Sprintf("verify %s failed: source rows=%d idsum=%d, target rows=%d idsum=%d", table, a.Cnt, a.IdSum, b.Cnt, b.IdSum))
+	}
+	return nil
+}
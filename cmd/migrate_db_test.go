@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"github.com/lejianwen/rustdesk-api/v2/global"
+	"github.com/lejianwen/rustdesk-api/v2/model"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func openTestDB(t *testing.T, name string) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), name)), &gorm.Config{
+		DisableForeignKeyConstraintWhenMigrating: true,
+		Logger:                                   logger.Discard,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestMigrateDatabasePeers(t *testing.T) {
+	global.Logger = logrus.New()
+	src := openTestDB(t, "src.db")
+	dst := openTestDB(t, "dst.db")
+	if err := src.AutoMigrate(migrateModels...); err != nil {
+		t.Fatal(err)
+	}
+	// 设备ID的顺序与主键 row_id 相反, 跨越多个批次
+	const n = migrateBatchSize*2 + 3
+	peers := make([]*model.Peer, 0, n)
+	for i := 0; i < n; i++ {
+		peers = append(peers, &model.Peer{Id: fmt.Sprintf("%09d", n-i), Uuid: fmt.Sprintf("uuid-%d", i)})
+	}
+	if err := src.CreateInBatches(peers, 100).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if err := migrateDatabase(src, dst, "sqlite"); err != nil {
+		t.Fatal(err)
+	}
+	var count int64
+	dst.Model(&model.Peer{}).Count(&count)
+	if count != n {
+		t.Fatalf("copied peers = %d, want %d", count, n)
+	}
+	last := &model.Peer{}
+	dst.Order("row_id desc").First(last)
+	if last.RowId != n || last.Id != fmt.Sprintf("%09d", 1) {
+		t.Fatalf("last peer = %d %s", last.RowId, last.Id)
+	}
+}
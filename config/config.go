@@ -14,27 +14,51 @@ const (
 )
 
 type App struct {
-	WebClient        int           `mapstructure:"web-client"`
-	Register         bool          `mapstructure:"register"`
-	RegisterStatus   int           `mapstructure:"register-status"`
-	ShowSwagger      int           `mapstructure:"show-swagger"`
-	TokenExpire      time.Duration `mapstructure:"token-expire"`
-	WebSso           bool          `mapstructure:"web-sso"`
-	DisablePwdLogin  bool          `mapstructure:"disable-pwd-login"`
-	CaptchaThreshold int           `mapstructure:"captcha-threshold"`
-	BanThreshold     int           `mapstructure:"ban-threshold"`
+	WebClient               int           `mapstructure:"web-client"`
+	Register                bool          `mapstructure:"register"`
+	RegisterStatus          int           `mapstructure:"register-status"`
+	ShowSwagger             int           `mapstructure:"show-swagger"`
+	TokenExpire             time.Duration `mapstructure:"token-expire"`
+	RefreshTokenExpire      time.Duration `mapstructure:"refresh-token-expire"` // 刷新令牌有效期, 为 0 时不签发刷新令牌
+	AccessTokenExpire       time.Duration `mapstructure:"access-token-expire"`  // 签发刷新令牌时访问令牌的有效期, 为 0 时使用默认值 15m
+	WebSso                  bool          `mapstructure:"web-sso"`
+	DisablePwdLogin         bool          `mapstructure:"disable-pwd-login"`
+	CaptchaThreshold        int           `mapstructure:"captcha-threshold"`
+	BanThreshold            int           `mapstructure:"ban-threshold"`
+	PeerRetentionDays       int           `mapstructure:"peer-retention-days"`
+	PeerRetentionOrphanOnly bool          `mapstructure:"peer-retention-orphan-only"`
+	PublicId                string        `mapstructure:"public-id"`        // 订单/套餐/订阅的对外标识: numeric(默认) 或 hashid
+	PublicIdSecret          string        `mapstructure:"public-id-secret"` // hashid 密钥, 更换后已发出的标识失效
 }
 type Admin struct {
-	Title           string `mapstructure:"title"`
-	Hello           string `mapstructure:"hello"`
-	HelloFile       string `mapstructure:"hello-file"`
-	IdServerPort    int    `mapstructure:"id-server-port"`
-	RelayServerPort int    `mapstructure:"relay-server-port"`
+	Title           string   `mapstructure:"title"`
+	Hello           string   `mapstructure:"hello"`
+	HelloFile       string   `mapstructure:"hello-file"`
+	IdServerPort    int      `mapstructure:"id-server-port"`
+	RelayServerPort int      `mapstructure:"relay-server-port"`
+	AllowIps        []string `mapstructure:"allow-ips"`
+	BypassToken     string   `mapstructure:"bypass-token"`
+}
+type Internal struct {
+	AllowedCidrs []string     `mapstructure:"allowed-cidrs"`
+	Shed         InternalShed `mapstructure:"shed"`
+}
+
+// InternalShed 内部接口过载保护, 数据库变慢时限制并发, 订阅检查和 relay 放行优先使用最近的决策
+type InternalShed struct {
+	Enable        bool          `mapstructure:"enable"`
+	MinLimit      int           `mapstructure:"min-limit"`
+	MaxLimit      int           `mapstructure:"max-limit"`
+	TargetLatency time.Duration `mapstructure:"target-latency"` // 超过该耗时视为过载, 缩小并发上限
+	LowShare      float64       `mapstructure:"low-share"`      // 低优先级请求(统计、会话上报)可使用的并发比例
+	RetryAfter    int           `mapstructure:"retry-after"`    // 拒绝时返回的 Retry-After 秒数
+	DecisionTTL   time.Duration `mapstructure:"decision-ttl"`   // 降级时可使用的决策的最长时间
 }
 type Config struct {
 	Lang       string `mapstructure:"lang"`
 	App        App
 	Admin      Admin
+	Internal   Internal
 	Gorm       Gorm
 	Mysql      Mysql
 	Postgresql Postgresql
@@ -48,6 +72,11 @@ type Config struct {
 	Proxy      Proxy
 	Ldap       Ldap
 	Payment    Payment
+	Trace      Trace
+	Storage    Storage
+	RateLimit  RateLimit `mapstructure:"rate-limit"`
+	Encryption Encryption
+	GeoIp      GeoIp
 }
 
 func (a *Admin) Init() {
@@ -59,42 +88,24 @@ func (a *Admin) Init() {
 	}
 }
 
-// Init 初始化配置
+// Init 初始化配置, 校验失败时 panic 并列出全部错误
 func Init(rowVal *Config, path string) *viper.Viper {
-	if path == "" {
-		path = DefaultConfig
-	}
-	v := viper.GetViper()
-	v.AutomaticEnv()
-	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_", "-", "_"))
-	v.SetEnvPrefix("RUSTDESK_API")
-	v.SetConfigFile(path)
-	v.SetConfigType("yaml")
-	err := v.ReadInConfig()
-	if err != nil {
-		panic(fmt.Errorf("Fatal error config file: %s \n", err))
+	v, errs := Load(rowVal, path)
+	if len(errs) > 0 {
+		panic(fmt.Errorf("Fatal error config:\n%s", FormatErrors(errs)))
 	}
-	/*
-		v.WatchConfig()
-
+	return v
+}
 
-			//监听配置修改没什么必要
-			v.OnConfigChange(func(e fsnotify.Event) {
-				//配置文件修改监听
-				fmt.Println("config file changed:", e.Name)
-				if err2 := v.Unmarshal(rowVal); err2 != nil {
-					fmt.Println(err2)
-				}
-				rowVal.Rustdesk.LoadKeyFile()
-				rowVal.Rustdesk.ParsePort()
-			})
-	*/
-	if err := v.Unmarshal(rowVal); err != nil {
-		panic(fmt.Errorf("Fatal error config: %s \n", err))
+// FormatErrors 每行一个错误
+func FormatErrors(errs []error) string {
+	var b strings.Builder
+	for _, err := range errs {
+		b.WriteString("  - ")
+		b.WriteString(err.Error())
+		b.WriteString("\n")
 	}
-	rowVal.Rustdesk.LoadKeyFile()
-	rowVal.Admin.Init()
-	return v
+	return b.String()
 }
 
 // ReadEnv 读取环境变量
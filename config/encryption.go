@@ -0,0 +1,7 @@
+package config
+
+// Encryption 系统设置中密钥类字段(支付密钥、SMTP 密码、S3 密钥等)的加密
+type Encryption struct {
+	Key     string   `mapstructure:"key"`      // 主密钥, 任意长度字符串; 为空时不加密
+	OldKeys []string `mapstructure:"old-keys"` // 更换主密钥后保留的旧密钥, 仅用于解密, 启动时自动用新密钥重新加密
+}
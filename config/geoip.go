@@ -0,0 +1,6 @@
+package config
+
+// GeoIp 离线 IP 地理位置数据库
+type GeoIp struct {
+	DbFile string `mapstructure:"db-file"` // MaxMind DB(.mmdb) 文件路径, 如 GeoLite2-City.mmdb, 为空时不启用
+}
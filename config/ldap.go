@@ -1,5 +1,7 @@
 package config
 
+import "time"
+
 type LdapUser struct {
 	BaseDn          string `mapstructure:"base-dn"`           // The base DN of the user for searching
 	EnableAttr      string `mapstructure:"enable-attr"`       // The attribute name of the user for enabling, in AD it is "userAccountControl", empty means no enable attribute, all users are enabled
@@ -14,24 +16,25 @@ type LdapUser struct {
 	AllowGroup      string `mapstructure:"allow-group"` // Which group is allowed to login
 }
 
-// type LdapGroup struct {
-// 	BaseDn 		string            `mapstructure:"base-dn"` // The base DN of the group for searching
-// 	Name        string            `mapstructure:"name"`    // The attribute name of the group
-// 	Filter      string            `mapstructure:"filter"`
-// 	Admin       string            `mapstructure:"admin"`   // Which group is the admin group
-// 	Member      string            `mapstructure:"member"`  // How to get the member of the group: member, uniqueMember, or memberOf (default: member)
-// 	Mode        string            `mapstructure:"mode"`
-// 	Map         map[string]string `mapstructure:"map"`     // If mode is "map", map the LDAP group to the internal group
-// }
+// LdapGroupMapping maps an LDAP group to a local user group
+type LdapGroupMapping struct {
+	Group   string `mapstructure:"group"`    // The DN of the LDAP group
+	GroupId uint   `mapstructure:"group-id"` // The id of the local user group
+}
+
+type LdapGroup struct {
+	Mappings     []LdapGroupMapping `mapstructure:"mappings"`      // Matched in order, the first group the user belongs to wins
+	SyncInterval time.Duration      `mapstructure:"sync-interval"` // Periodically sync groups of all local LDAP users, 0 means only sync on login
+}
 
 type Ldap struct {
-	Enable       bool     `mapstructure:"enable"`
-	Url          string   `mapstructure:"url"`
-	TlsCaFile    string   `mapstructure:"tls-ca-file"`
-	TlsVerify    bool     `mapstructure:"tls-verify"`
-	BaseDn       string   `mapstructure:"base-dn"`
-	BindDn       string   `mapstructure:"bind-dn"`
-	BindPassword string   `mapstructure:"bind-password"`
-	User         LdapUser `mapstructure:"user"`
-	// Group        LdapGroup `mapstructure:"group"`
+	Enable       bool      `mapstructure:"enable"`
+	Url          string    `mapstructure:"url"`
+	TlsCaFile    string    `mapstructure:"tls-ca-file"`
+	TlsVerify    bool      `mapstructure:"tls-verify"`
+	BaseDn       string    `mapstructure:"base-dn"`
+	BindDn       string    `mapstructure:"bind-dn"`
+	BindPassword string    `mapstructure:"bind-password"`
+	User         LdapUser  `mapstructure:"user"`
+	Group        LdapGroup `mapstructure:"group"`
 }
@@ -3,15 +3,60 @@ package config
 import "time"
 
 type Payment struct {
-	EasyPay EasyPay `mapstructure:"epay"`
+	// Provider 支付网关: epay(默认)/mock, mock 为内置模拟网关, 收银台打开即支付成功, 仅用于开发环境
+	Provider          string                   `mapstructure:"provider"`
+	EasyPay           EasyPay                  `mapstructure:"epay"`
+	OrderExpire       time.Duration            `mapstructure:"order-expire"`           // 待支付订单超时自动关闭时长
+	OrderExpireBy     map[string]time.Duration `mapstructure:"order-expire-overrides"` // 按支付方式(online/manual)覆盖超时时长
+	ReconcileInterval time.Duration            `mapstructure:"reconcile-interval"`     // 主动对账间隔
+	Manual            Manual                   `mapstructure:"manual"`
+	// RequireVerifiedEmail 下单前要求已验证邮箱, 确保回执和续费提醒可以送达
+	RequireVerifiedEmail bool           `mapstructure:"require-verified-email"`
+	ExtensionGuard       ExtensionGuard `mapstructure:"extension-guard"`
+	OrderLimit           OrderLimit     `mapstructure:"order-limit"`
+	Tax                  Tax            `mapstructure:"tax"`
+}
+
+// Tax 税费(如增值税), 按国家/地区计算并单独记录在订单上
+type Tax struct {
+	Enable      bool               `mapstructure:"enable"`
+	Inclusive   bool               `mapstructure:"inclusive"`    // 套餐/商品价格是否已含税, 不含税时下单金额加上税额
+	DefaultRate float64            `mapstructure:"default-rate"` // 未配置国家/地区时的税率(百分比)
+	Rates       map[string]float64 `mapstructure:"rates"`        // 国家/地区代码(如 DE、FR) -> 税率(百分比)
+}
+
+// OrderLimit 下单限制, 防止刷单占满订单表
+type OrderLimit struct {
+	MaxPending  int           `mapstructure:"max-pending"`  // 每个用户的待支付订单上限, 超过时先关闭旧的重复订单, 为 0 时使用默认值 5
+	MinInterval time.Duration `mapstructure:"min-interval"` // 同一用户两次下单的最小间隔, 为 0 时使用默认值 5s
+}
+
+// ExtensionGuard 订阅续期异常防护, 防止程序缺陷或回调重放写入异常的到期时间
+type ExtensionGuard struct {
+	MaxHorizon   time.Duration `mapstructure:"max-horizon"`    // 到期时间距现在的上限, 为 0 时使用默认值 10 年
+	MaxPerMinute int           `mapstructure:"max-per-minute"` // 同一用户每分钟开通/续期次数上限, 为 0 时使用默认值 10
+	Action       string        `mapstructure:"action"`         // reject: 拒绝并告警; flag: 放行, 在变更记录中标记并告警
+}
+
+// Manual 线下转账支付, 用户上传付款凭证后由管理员审核入账
+type Manual struct {
+	Enable       bool     `mapstructure:"enable"`
+	Instructions string   `mapstructure:"instructions"`  // 收款说明(账户信息等), 下单时返回给用户
+	MaxSize      int64    `mapstructure:"max-size"`      // 凭证文件大小上限(字节), 文件保存在 storage 配置的存储中
+	AllowedTypes []string `mapstructure:"allowed-types"` // 允许的文件类型(按内容识别的 MIME)
 }
 
 type EasyPay struct {
-	Enable    bool          `mapstructure:"enable"`
-	BaseURL   string        `mapstructure:"base-url"`
-	Pid       string        `mapstructure:"pid"`
-	Key       string        `mapstructure:"key"`
-	NotifyURL string        `mapstructure:"notify-url"`
-	ReturnURL string        `mapstructure:"return-url"`
-	Timeout   time.Duration `mapstructure:"timeout"`
+	Enable    bool   `mapstructure:"enable"`
+	BaseURL   string `mapstructure:"base-url"`
+	Pid       string `mapstructure:"pid"`
+	Key       string `mapstructure:"key"`
+	NotifyURL string `mapstructure:"notify-url"`
+	ReturnURL string `mapstructure:"return-url"`
+	// RedirectURL return-url 指向 /api/payment/return 时, 确认支付结果后跳转的页面
+	RedirectURL string        `mapstructure:"redirect-url"`
+	Timeout     time.Duration `mapstructure:"timeout"`
+	Currencies  []string      `mapstructure:"currencies"` // 网关支持的币种, 默认仅 CNY
+	PayTypes    []string      `mapstructure:"pay-types"`  // 允许用户选择的支付渠道(alipay/wxpay/qqpay), 为空时由网关收银台选择
+	Sandbox     bool          `mapstructure:"sandbox"`    // 测试模式, 允许管理员模拟支付成功
 }
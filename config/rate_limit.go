@@ -0,0 +1,17 @@
+package config
+
+// RateLimit 令牌桶限流, 防止支付回调洪泛、刷单和暴力登录
+type RateLimit struct {
+	Enable bool          `mapstructure:"enable"`
+	Notify RateLimitRule `mapstructure:"notify"` // /api/payment/notify
+	Order  RateLimitRule `mapstructure:"order"`  // 创建订单
+	Login  RateLimitRule `mapstructure:"login"`  // 客户端和后台登录
+}
+
+// RateLimitRule rate 为每秒补充的令牌数, burst 为桶容量; rate 为 0 表示该维度不限流
+type RateLimitRule struct {
+	IpRate    float64 `mapstructure:"ip-rate"`
+	IpBurst   int     `mapstructure:"ip-burst"`
+	UserRate  float64 `mapstructure:"user-rate"` // 按登录用户限流, 仅对需要登录的接口生效
+	UserBurst int     `mapstructure:"user-burst"`
+}
@@ -21,6 +21,7 @@ type Rustdesk struct {
 	//webclient-magic-queryonline
 	WebclientMagicQueryonline int    `mapstructure:"webclient-magic-queryonline"`
 	WsHost                    string `mapstructure:"ws-host"`
+	RelayWhitelistFile        string `mapstructure:"relay-whitelist-file"` // relay 白名单快照文件
 }
 
 func (rd *Rustdesk) LoadKeyFile() {
@@ -0,0 +1,22 @@
+package config
+
+import "time"
+
+// Storage 文件存储: 付款凭证、发票、备份、导出文件等, 后台保存设置后以设置为准
+type Storage struct {
+	Driver    string        `mapstructure:"driver"`     // local/s3
+	LocalDir  string        `mapstructure:"local-dir"`  // 本地存储目录, 不对外公开
+	UrlExpire time.Duration `mapstructure:"url-expire"` // 临时下载地址有效期
+	S3        S3Storage     `mapstructure:"s3"`
+}
+
+// S3Storage S3 兼容的对象存储
+type S3Storage struct {
+	Endpoint  string `mapstructure:"endpoint"` // 如 https://s3.amazonaws.com, http://minio:9000
+	Region    string `mapstructure:"region"`
+	Bucket    string `mapstructure:"bucket"`
+	AccessKey string `mapstructure:"access-key"`
+	SecretKey string `mapstructure:"secret-key"`
+	PathStyle bool   `mapstructure:"path-style"` // MinIO 等通常需要开启
+	Prefix    string `mapstructure:"prefix"`     // key 前缀
+}
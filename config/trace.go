@@ -0,0 +1,13 @@
+package config
+
+import "time"
+
+// Trace OTLP 链路追踪
+type Trace struct {
+	Enable      bool              `mapstructure:"enable"`
+	Endpoint    string            `mapstructure:"endpoint"`     // OTLP/HTTP collector 地址, 如 http://otel-collector:4318
+	ServiceName string            `mapstructure:"service-name"` // service.name, 默认 rustdesk-api
+	SampleRatio float64           `mapstructure:"sample-ratio"` // 采样率 0-1
+	Headers     map[string]string `mapstructure:"headers"`      // 导出时附加的请求头, 如鉴权
+	Timeout     time.Duration     `mapstructure:"timeout"`      // 导出请求超时
+}
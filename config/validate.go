@@ -0,0 +1,250 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// Load 读取配置文件并校验, 返回全部校验错误(不会在第一个错误处停止)
+// 包括: 未知的配置项、格式错误的时长、启用支付时缺少的必填项以及内部接口和后台白名单的 CIDR
+func Load(rowVal *Config, path string) (*viper.Viper, []error) {
+	if path == "" {
+		path = DefaultConfig
+	}
+	v := viper.GetViper()
+	v.AutomaticEnv()
+	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_", "-", "_"))
+	v.SetEnvPrefix("RUSTDESK_API")
+	v.SetConfigFile(path)
+	v.SetConfigType("yaml")
+	if err := v.ReadInConfig(); err != nil {
+		return v, []error{fmt.Errorf("read config file: %w", err)}
+	}
+
+	// 时长格式错误时 Unmarshal 只返回第一个错误, 先逐项检查以便一次列出
+	errs := checkSchema(v)
+	if len(errs) > 0 {
+		return v, errs
+	}
+	if err := v.Unmarshal(rowVal); err != nil {
+		return v, []error{err}
+	}
+	rowVal.Rustdesk.LoadKeyFile()
+	rowVal.Admin.Init()
+	return v, rowVal.Validate()
+}
+
+// schemaKey 配置结构中的一项
+type schemaKey struct {
+	duration bool
+	anyChild bool // map 类型, 允许任意子项
+}
+
+// schemaKeys 根据 mapstructure 标签生成全部配置项(小写, 点分隔)
+func schemaKeys() map[string]schemaKey {
+	res := map[string]schemaKey{}
+	collectSchemaKeys(reflect.TypeOf(Config{}), "", res)
+	return res
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+func collectSchemaKeys(t reflect.Type, prefix string, res map[string]schemaKey) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name := strings.Split(f.Tag.Get("mapstructure"), ",")[0]
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		key := strings.ToLower(prefix + name)
+		switch {
+		case f.Type == durationType:
+			res[key] = schemaKey{duration: true}
+		case f.Type.Kind() == reflect.Struct:
+			collectSchemaKeys(f.Type, key+".", res)
+		case f.Type.Kind() == reflect.Map:
+			res[key] = schemaKey{anyChild: true, duration: f.Type.Elem() == durationType}
+		default:
+			res[key] = schemaKey{}
+		}
+	}
+}
+
+// checkSchema 检查未知的配置项和时长格式
+func checkSchema(v *viper.Viper) []error {
+	known := schemaKeys()
+	var errs []error
+	keys := v.AllKeys()
+	sort.Strings(keys)
+	for _, key := range keys {
+		sk, ok := known[key]
+		if !ok {
+			if i := strings.LastIndex(key, "."); i > 0 {
+				if parent, found := known[key[:i]]; found && parent.anyChild {
+					sk, ok = parent, true
+				}
+			}
+			if !ok {
+				errs = append(errs, fmt.Errorf("%s: unknown config key", key))
+				continue
+			}
+		}
+		if sk.duration {
+			if err := checkDuration(v.Get(key)); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %v", key, err))
+			}
+		}
+	}
+	return errs
+}
+
+// checkDuration 时长可写为 "30s"/"1h30m" 或纳秒整数, 不允许为负
+func checkDuration(val interface{}) error {
+	var d time.Duration
+	switch x := val.(type) {
+	case nil:
+		return nil
+	case int:
+		d = time.Duration(x)
+	case int64:
+		d = time.Duration(x)
+	case time.Duration:
+		d = x
+	case string:
+		s := strings.TrimSpace(x)
+		if s == "" {
+			return nil
+		}
+		if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+			d = time.Duration(n)
+			break
+		}
+		var err error
+		if d, err = time.ParseDuration(s); err != nil {
+			return fmt.Errorf("invalid duration %q, use a value like 30s, 15m or 24h", s)
+		}
+	default:
+		return fmt.Errorf("invalid duration %v", val)
+	}
+	if d < 0 {
+		return fmt.Errorf("duration must not be negative")
+	}
+	return nil
+}
+
+// Validate 语义校验, 返回全部错误
+func (c *Config) Validate() []error {
+	var errs []error
+	add := func(format string, a ...interface{}) {
+		errs = append(errs, fmt.Errorf(format, a...))
+	}
+
+	switch c.Payment.Provider {
+	case "", "epay", "mock":
+	default:
+		add("payment.provider: must be epay or mock, got %q", c.Payment.Provider)
+	}
+	ep := c.Payment.EasyPay
+	if ep.Enable && c.Payment.Provider != "mock" {
+		if ep.BaseURL == "" {
+			add("payment.epay.base-url: required when payment.epay.enable is true")
+		} else if u, err := url.Parse(ep.BaseURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			add("payment.epay.base-url: must be an http(s) URL, got %q", ep.BaseURL)
+		}
+		if ep.Pid == "" {
+			add("payment.epay.pid: required when payment.epay.enable is true")
+		}
+		if ep.Key == "" {
+			add("payment.epay.key: required when payment.epay.enable is true")
+		}
+		if ep.NotifyURL == "" {
+			add("payment.epay.notify-url: required when payment.epay.enable is true, otherwise paid orders are only picked up by reconciliation")
+		}
+	}
+	for _, cur := range ep.Currencies {
+		switch strings.ToUpper(strings.TrimSpace(cur)) {
+		case "CNY", "USD", "EUR":
+		default:
+			add("payment.epay.currencies: unsupported currency %q", cur)
+		}
+	}
+	for _, t := range ep.PayTypes {
+		switch strings.ToLower(strings.TrimSpace(t)) {
+		case "alipay", "wxpay", "qqpay":
+		default:
+			add("payment.epay.pay-types: unsupported pay type %q", t)
+		}
+	}
+	for method := range c.Payment.OrderExpireBy {
+		if method != "online" && method != "manual" {
+			add("payment.order-expire-overrides: unknown pay method %q", method)
+		}
+	}
+	if t := c.Payment.Tax; t.Enable {
+		if t.DefaultRate < 0 || t.DefaultRate >= 100 {
+			add("payment.tax.default-rate: must be between 0 and 100, got %v", t.DefaultRate)
+		}
+		for country, rate := range t.Rates {
+			if rate < 0 || rate >= 100 {
+				add("payment.tax.rates.%s: must be between 0 and 100, got %v", country, rate)
+			}
+		}
+	}
+	if a := c.Payment.ExtensionGuard.Action; a != "" && a != "reject" && a != "flag" {
+		add("payment.extension-guard.action: must be reject or flag, got %q", a)
+	}
+
+	for _, cidr := range c.Internal.AllowedCidrs {
+		if net.ParseIP(cidr) == nil {
+			if _, _, err := net.ParseCIDR(cidr); err != nil {
+				add("internal.allowed-cidrs: invalid IP or CIDR %q", cidr)
+			}
+		}
+	}
+	for _, cidr := range c.Admin.AllowIps {
+		cidr = strings.TrimSpace(cidr)
+		if cidr != "" && net.ParseIP(cidr) == nil {
+			if _, _, err := net.ParseCIDR(cidr); err != nil {
+				add("admin.allow-ips: invalid IP or CIDR %q", cidr)
+			}
+		}
+	}
+	if s := c.Internal.Shed; s.Enable {
+		if s.MinLimit < 0 || s.MaxLimit < 0 || (s.MaxLimit > 0 && s.MaxLimit < s.MinLimit) {
+			add("internal.shed: min-limit and max-limit must not be negative, and min-limit must not exceed max-limit")
+		}
+		if s.LowShare < 0 || s.LowShare > 1 {
+			add("internal.shed.low-share: must be between 0 and 1")
+		}
+	}
+	for i, m := range c.Ldap.Group.Mappings {
+		if strings.TrimSpace(m.Group) == "" || m.GroupId == 0 {
+			add("ldap.group.mappings[%d]: group and group-id are required", i)
+		}
+	}
+
+	switch c.App.PublicId {
+	case "", "numeric":
+	case "hashid":
+		if c.App.PublicIdSecret == "" {
+			add("app.public-id-secret: required when app.public-id is hashid")
+		}
+	default:
+		add("app.public-id: must be numeric or hashid, got %q", c.App.PublicId)
+	}
+	return errs
+}
@@ -0,0 +1,74 @@
+package config
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func readYaml(t *testing.T, s string) *viper.Viper {
+	v := viper.New()
+	v.SetConfigType("yaml")
+	if err := v.ReadConfig(strings.NewReader(s)); err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func TestCheckSchema(t *testing.T) {
+	v := readYaml(t, `
+app:
+  token-expire: 168h
+  unknown-flag: true
+payment:
+  order-expire: 30x
+  order-expire-overrides:
+    manual: 72h
+  reconcile-interval: -5m
+rate-limit:
+  enable: true
+`)
+	var got []string
+	for _, err := range checkSchema(v) {
+		got = append(got, err.Error())
+	}
+	want := []string{
+		"app.unknown-flag: unknown config key",
+		`payment.order-expire: invalid duration "30x", use a value like 30s, 15m or 24h`,
+		"payment.reconcile-interval: duration must not be negative",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("checkSchema =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestValidate(t *testing.T) {
+	c := &Config{}
+	c.Payment.EasyPay = EasyPay{Enable: true, BaseURL: "example.com", Pid: "1", PayTypes: []string{"alipay", "card"}}
+	c.Internal.AllowedCidrs = []string{"10.0.0.0/8", "127.0.0.1", "10.0.0.0/33"}
+	c.Admin.AllowIps = []string{"192.168.1.0/24", " ::1 ", "", "192.168.1.300"}
+	c.App.PublicId = "hashid"
+	c.Ldap.Group.Mappings = []LdapGroupMapping{{Group: "cn=ops,dc=example,dc=com"}}
+	errs := c.Validate()
+	var got []string
+	for _, err := range errs {
+		got = append(got, strings.SplitN(err.Error(), ":", 2)[0])
+	}
+	want := "payment.epay.base-url,payment.epay.key,payment.epay.notify-url,payment.epay.pay-types,internal.allowed-cidrs,admin.allow-ips,ldap.group.mappings[0],app.public-id-secret"
+	if strings.Join(got, ",") != want {
+		t.Errorf("Validate keys = %s, want %s", strings.Join(got, ","), want)
+	}
+	if errs := (&Config{}).Validate(); len(errs) != 0 {
+		t.Errorf("empty config: %v", errs)
+	}
+}
+
+func TestShippedConfigValid(t *testing.T) {
+	for _, path := range []string{"../conf/config.yaml", "../conf/config.local.yaml"} {
+		var c Config
+		if _, errs := Load(&c, path); len(errs) > 0 {
+			t.Errorf("%s:\n%s", path, FormatErrors(errs))
+		}
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/lejianwen/rustdesk-api/v2/global"
 	"github.com/lejianwen/rustdesk-api/v2/http/request/admin"
 	"github.com/lejianwen/rustdesk-api/v2/http/response"
+	"github.com/lejianwen/rustdesk-api/v2/model"
 	"github.com/lejianwen/rustdesk-api/v2/service"
 	"gorm.io/gorm"
 	"strconv"
@@ -360,3 +361,38 @@ func (ct *AddressBook) BatchCreateFromPeers(c *gin.Context) {
 	}
 	response.Success(c, nil)
 }
+
+// BulkEdit 批量编辑地址簿
+// @Tags 地址簿
+// @Summary 批量编辑地址簿
+// @Description 批量添加/移除标签、修改别名、移动到其他地址簿, 返回每条记录的结果
+// @Accept  json
+// @Produce  json
+// @Param body body admin.BulkEditForm true "批量编辑"
+// @Success 200 {object} response.Response{data=[]model.BulkEditResult}
+// @Failure 500 {object} response.Response
+// @Router /admin/address_book/bulkEdit [post]
+// @Security token
+func (ct *AddressBook) BulkEdit(c *gin.Context) {
+	f := &admin.BulkEditForm{}
+	if err := c.ShouldBindJSON(f); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	results, err := service.AllService.AddressBookService.BulkEdit(f.RowIds, &f.BulkEdit)
+	if err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, err.Error()))
+		return
+	}
+	response.Success(c, translateBulkResults(c, results))
+}
+
+// translateBulkResults 翻译每条记录的失败原因
+func translateBulkResults(c *gin.Context, results []*model.BulkEditResult) []*model.BulkEditResult {
+	for _, r := range results {
+		if r.Error != "" {
+			r.Error = response.TranslateMsg(c, r.Error)
+		}
+	}
+	return results
+}
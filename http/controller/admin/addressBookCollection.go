@@ -59,6 +59,15 @@ func (abc *AddressBookCollection) Create(c *gin.Context) {
 		response.Fail(c, 101, errList[0])
 		return
 	}
+	if f.OrgId > 0 {
+		// 团队地址簿归组织所有者
+		ownerId, err := service.AllService.AddressBookService.TeamCollectionOwner(f.OrgId)
+		if err != nil {
+			response.Fail(c, 101, response.TranslateMsg(c, err.Error()))
+			return
+		}
+		f.UserId = ownerId
+	}
 	if f.UserId == 0 {
 		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError"))
 		return
@@ -82,6 +91,7 @@ func (abc *AddressBookCollection) Create(c *gin.Context) {
 // @Param page_size query int false "页大小"
 // @Param is_my query int false "是否是我的"
 // @Param user_id query int false "用户id"
+// @Param org_id query int false "组织id"
 // @Success 200 {object} response.Response{data=model.AddressBookCollectionList}
 // @Failure 500 {object} response.Response
 // @Router /admin/address_book_collection/list [get]
@@ -96,6 +106,9 @@ func (abc *AddressBookCollection) List(c *gin.Context) {
 		if query.UserId > 0 {
 			tx.Where("user_id = ?", query.UserId)
 		}
+		if query.OrgId > 0 {
+			tx.Where("org_id = ?", query.OrgId)
+		}
 	})
 	response.Success(c, res)
 }
@@ -126,6 +139,21 @@ func (abc *AddressBookCollection) Update(c *gin.Context) {
 		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError"))
 		return
 	}
+	ex := service.AllService.AddressBookService.CollectionInfoById(f.Id)
+	if ex.Id == 0 {
+		response.Fail(c, 101, response.TranslateMsg(c, "ItemNotFound"))
+		return
+	}
+	if f.OrgId > 0 {
+		ownerId, err := service.AllService.AddressBookService.TeamCollectionOwner(f.OrgId)
+		if err != nil {
+			response.Fail(c, 101, response.TranslateMsg(c, err.Error()))
+			return
+		}
+		f.UserId = ownerId
+	} else if f.UserId == 0 {
+		f.UserId = ex.UserId
+	}
 	t := f //f.ToAddressBookCollection()
 	err := service.AllService.AddressBookService.UpdateCollection(t)
 	if err != nil {
@@ -0,0 +1,112 @@
+package admin
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/lejianwen/rustdesk-api/v2/http/request/admin"
+	"github.com/lejianwen/rustdesk-api/v2/http/response"
+	"github.com/lejianwen/rustdesk-api/v2/service"
+	"gorm.io/gorm"
+)
+
+type AdminLog struct {
+}
+
+// List 列表
+// @Tags 管理员日志
+// @Summary 管理员操作日志列表
+// @Description 管理员操作日志列表
+// @Accept  json
+// @Produce  json
+// @Param page query int false "页码"
+// @Param page_size query int false "页大小"
+// @Param user_id query int false "用户ID"
+// @Param action query string false "操作类型"
+// @Success 200 {object} response.Response{data=model.AdminLogList}
+// @Failure 500 {object} response.Response
+// @Router /admin/admin_log/list [get]
+// @Security token
+func (ct *AdminLog) List(c *gin.Context) {
+	query := &admin.AdminLogQuery{}
+	if err := c.ShouldBindQuery(query); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	res := service.AllService.AdminLogService.List(query.Page, query.PageSize, func(tx *gorm.DB) {
+		if query.UserId > 0 {
+			tx.Where("user_id = ?", query.UserId)
+		}
+		if query.Action != "" {
+			tx.Where("action = ?", query.Action)
+		}
+		tx.Order("id desc")
+	})
+	response.Success(c, res)
+}
+
+// Activity 管理员行为汇总
+// @Tags 管理员日志
+// @Summary 管理员行为汇总
+// @Description 汇总每个管理员近期的登录、退款、配置修改次数, 并标记退款量突增等异常
+// @Accept  json
+// @Produce  json
+// @Param days query int false "统计天数,默认7"
+// @Success 200 {object} response.Response{data=[]model.AdminActivity}
+// @Failure 500 {object} response.Response
+// @Router /admin/admin_log/activity [get]
+// @Security token
+func (ct *AdminLog) Activity(c *gin.Context) {
+	query := &admin.AdminActivityQuery{}
+	if err := c.ShouldBindQuery(query); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	if query.Days <= 0 {
+		query.Days = 7
+	}
+	if query.Days > 90 {
+		query.Days = 90
+	}
+	response.Success(c, service.AllService.AdminLogService.Activity(query.Days))
+}
+
+// AuditList 资金相关操作审计
+// @Tags 管理员日志
+// @Summary 资金相关操作审计
+// @Description 支付配置、套餐、手动赠送、退款的修改记录, 包含修改前后的值(密钥已遮蔽)和操作IP
+// @Accept  json
+// @Produce  json
+// @Param page query int false "页码"
+// @Param page_size query int false "页大小"
+// @Param user_id query int false "管理员ID"
+// @Param action query string false "操作类型"
+// @Param target_id query int false "目标ID"
+// @Success 200 {object} response.Response{data=model.AdminAuditList}
+// @Failure 500 {object} response.Response
+// @Router /admin/audit/list [get]
+// @Security token
+func (ct *AdminLog) AuditList(c *gin.Context) {
+	query := &admin.AdminAuditQuery{}
+	if err := c.ShouldBindQuery(query); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	res := service.AllService.AdminLogService.AuditList(query.Page, query.PageSize, func(tx *gorm.DB) {
+		if query.UserId > 0 {
+			tx.Where("user_id = ?", query.UserId)
+		}
+		if query.Action != "" {
+			tx.Where("action = ?", query.Action)
+		}
+		if query.TargetId > 0 {
+			tx.Where("target_id = ?", query.TargetId)
+		}
+		tx.Order("id desc")
+	})
+	response.Success(c, res)
+}
+
+// audit 记录当前管理员的资金相关操作
+func audit(c *gin.Context, action string, targetId uint, before, after interface{}) {
+	u := service.AllService.UserService.CurUser(c)
+	service.AllService.AdminLogService.Audit(u.Id, c.ClientIP(), action, targetId, before, after)
+}
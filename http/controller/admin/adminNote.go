@@ -0,0 +1,102 @@
+package admin
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/lejianwen/rustdesk-api/v2/global"
+	"github.com/lejianwen/rustdesk-api/v2/http/response"
+	"github.com/lejianwen/rustdesk-api/v2/model"
+	"github.com/lejianwen/rustdesk-api/v2/service"
+)
+
+// ========== 内部备注 ==========
+
+type NoteAddForm struct {
+	TargetId uint   `json:"target_id" validate:"required"` // 订单ID或订阅ID
+	Content  string `json:"content" validate:"required,max=5000"`
+}
+
+// OrderNoteAdd 添加订单内部备注
+// @Tags Admin-Payment
+// @Summary 添加订单内部备注
+// @Description 备注只在管理后台的订单详情中返回, 不对用户展示
+// @Accept  json
+// @Produce  json
+// @Param body body NoteAddForm true "target_id 为订单ID"
+// @Success 200 {object} response.Response{data=model.AdminNote}
+// @Router /api/admin/order/notes/add [post]
+func (p *Payment) OrderNoteAdd(c *gin.Context) {
+	p.noteAdd(c, model.AdminNoteTargetOrder)
+}
+
+// OrderNoteDelete 删除订单内部备注
+// @Tags Admin-Payment
+// @Summary 删除订单内部备注
+// @Description 只能删除自己撰写的备注
+// @Accept  json
+// @Produce  json
+// @Param body body IdForm true "备注ID"
+// @Success 200 {object} response.Response
+// @Router /api/admin/order/notes/delete [post]
+func (p *Payment) OrderNoteDelete(c *gin.Context) {
+	p.noteDelete(c, model.AdminNoteTargetOrder)
+}
+
+// SubscriptionNoteAdd 添加订阅内部备注
+// @Tags Admin-Payment
+// @Summary 添加订阅内部备注
+// @Description 备注只在管理后台的订阅详情中返回, 不对用户展示
+// @Accept  json
+// @Produce  json
+// @Param body body NoteAddForm true "target_id 为订阅ID"
+// @Success 200 {object} response.Response{data=model.AdminNote}
+// @Router /api/admin/subscription/notes/add [post]
+func (p *Payment) SubscriptionNoteAdd(c *gin.Context) {
+	p.noteAdd(c, model.AdminNoteTargetSubscription)
+}
+
+// SubscriptionNoteDelete 删除订阅内部备注
+// @Tags Admin-Payment
+// @Summary 删除订阅内部备注
+// @Description 只能删除自己撰写的备注
+// @Accept  json
+// @Produce  json
+// @Param body body IdForm true "备注ID"
+// @Success 200 {object} response.Response
+// @Router /api/admin/subscription/notes/delete [post]
+func (p *Payment) SubscriptionNoteDelete(c *gin.Context) {
+	p.noteDelete(c, model.AdminNoteTargetSubscription)
+}
+
+func (p *Payment) noteAdd(c *gin.Context, targetType string) {
+	var form NoteAddForm
+	if err := c.ShouldBindJSON(&form); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	errList := global.Validator.ValidStruct(c, &form)
+	if len(errList) > 0 {
+		response.Fail(c, 101, errList[0])
+		return
+	}
+	u := service.AllService.UserService.CurUser(c)
+	n, err := service.AllService.SubscriptionService.AddNote(targetType, form.TargetId, u.Id, form.Content)
+	if err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, err.Error()))
+		return
+	}
+	response.Success(c, n)
+}
+
+func (p *Payment) noteDelete(c *gin.Context, targetType string) {
+	var form IdForm
+	if err := c.ShouldBindJSON(&form); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	u := service.AllService.UserService.CurUser(c)
+	if _, err := service.AllService.SubscriptionService.DeleteNote(targetType, form.Id, u.Id); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, err.Error()))
+		return
+	}
+	response.Success(c, nil)
+}
@@ -0,0 +1,154 @@
+package admin
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lejianwen/rustdesk-api/v2/global"
+	"github.com/lejianwen/rustdesk-api/v2/http/request/admin"
+	"github.com/lejianwen/rustdesk-api/v2/http/response"
+	"github.com/lejianwen/rustdesk-api/v2/model"
+	"github.com/lejianwen/rustdesk-api/v2/service"
+	"gorm.io/gorm"
+)
+
+// SessionList 连接审计列表
+// @Tags 链接日志
+// @Summary 连接审计列表
+// @Description 已建立的远程连接(relay 及直连), 包含发起用户、被控设备、起止时间和 relay 流量
+// @Accept  json
+// @Produce  json
+// @Param page query int false "页码"
+// @Param page_size query int false "页大小"
+// @Param user_id query int false "发起用户"
+// @Param peer_id query string false "发起设备"
+// @Param target_peer_id query string false "被控设备"
+// @Param mode query string false "连接方式: relay/direct"
+// @Param country query string false "发起端国家/地区代码"
+// @Param city query string false "发起端城市"
+// @Param start_at query int false "开始时间起(unix 秒)"
+// @Param end_at query int false "开始时间止(unix 秒)"
+// @Success 200 {object} response.Response{data=model.ConnectionLogList}
+// @Failure 500 {object} response.Response
+// @Router /admin/audit_session/list [get]
+// @Security token
+func (a *Audit) SessionList(c *gin.Context) {
+	query := &admin.ConnectionLogQuery{}
+	if err := c.ShouldBindQuery(query); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	res := service.AllService.AuditService.ConnectionLogList(query.Page, query.PageSize, connectionLogFilter(query))
+	response.Success(c, res)
+}
+
+// SessionExport 导出连接审计
+// @Tags 链接日志
+// @Summary 导出连接审计
+// @Description 按列表相同的筛选条件导出 CSV 或 xlsx
+// @Produce  octet-stream
+// @Param format query string false "导出格式: csv(默认)/xlsx"
+// @Param user_id query int false "发起用户"
+// @Param peer_id query string false "发起设备"
+// @Param target_peer_id query string false "被控设备"
+// @Param mode query string false "连接方式: relay/direct"
+// @Param country query string false "发起端国家/地区代码"
+// @Param city query string false "发起端城市"
+// @Param start_at query int false "开始时间起(unix 秒)"
+// @Param end_at query int false "开始时间止(unix 秒)"
+// @Success 200 {file} file
+// @Router /admin/audit_session/export [get]
+// @Security token
+func (a *Audit) SessionExport(c *gin.Context) {
+	query := &admin.ConnectionLogQuery{}
+	if err := c.ShouldBindQuery(query); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	if query.Format == "" {
+		query.Format = "csv"
+	}
+	if query.Format != "csv" && query.Format != "xlsx" {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError"))
+		return
+	}
+	writeRow, flush, err := newExportWriter(c.Writer, query.Format)
+	if err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "OperationFailed")+err.Error())
+		return
+	}
+	filename := "connections_" + time.Now().Format("20060102150405") + "." + query.Format
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+	c.Header("Cache-Control", "no-store")
+	c.Header("Content-Type", orderExportTypes[query.Format])
+	err = writeRow(connectionLogExportHeader)
+	if err == nil {
+		err = service.AllService.AuditService.ExportConnectionLogs(connectionLogFilter(query), func(logs []*model.ConnectionLog) error {
+			for _, l := range logs {
+				if err := writeRow(connectionLogExportRow(l)); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	}
+	if err == nil {
+		err = flush()
+	}
+	if err != nil {
+		// 响应已开始写出, 只能记录日志
+		global.Logger.Error("Export connection logs failed: ", err)
+	}
+}
+
+func connectionLogFilter(query *admin.ConnectionLogQuery) func(tx *gorm.DB) {
+	return func(tx *gorm.DB) {
+		if query.UserId > 0 {
+			tx.Where("user_id = ?", query.UserId)
+		}
+		if query.PeerId != "" {
+			tx.Where("peer_id = ?", query.PeerId)
+		}
+		if query.TargetPeerId != "" {
+			tx.Where("target_peer_id = ?", query.TargetPeerId)
+		}
+		if query.Mode != "" {
+			tx.Where("mode = ?", query.Mode)
+		}
+		if query.Country != "" {
+			tx.Where("country = ?", strings.ToUpper(query.Country))
+		}
+		if query.City != "" {
+			tx.Where("city = ?", query.City)
+		}
+		if query.StartAt > 0 {
+			tx.Where("started_at >= ?", query.StartAt)
+		}
+		if query.EndAt > 0 {
+			tx.Where("started_at < ?", query.EndAt)
+		}
+	}
+}
+
+var connectionLogExportHeader = []string{"ID", "连接方式", "发起设备", "发起用户ID", "被控设备", "被控用户ID", "发起IP", "国家/地区", "城市", "开始时间", "结束时间", "时长(秒)", "上行字节", "下行字节"}
+
+func connectionLogExportRow(l *model.ConnectionLog) []string {
+	return []string{
+		strconv.FormatUint(uint64(l.Id), 10),
+		l.Mode,
+		l.PeerId,
+		strconv.FormatUint(uint64(l.UserId), 10),
+		l.TargetPeerId,
+		strconv.FormatUint(uint64(l.TargetUserId), 10),
+		l.Ip,
+		l.Country,
+		l.City,
+		formatUnix(l.StartedAt),
+		formatUnix(l.EndedAt),
+		strconv.FormatInt(l.Duration, 10),
+		strconv.FormatInt(l.BytesUp, 10),
+		strconv.FormatInt(l.BytesDown, 10),
+	}
+}
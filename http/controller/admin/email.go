@@ -0,0 +1,163 @@
+package admin
+
+import (
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lejianwen/rustdesk-api/v2/global"
+	"github.com/lejianwen/rustdesk-api/v2/http/request/admin"
+	"github.com/lejianwen/rustdesk-api/v2/http/response"
+	"github.com/lejianwen/rustdesk-api/v2/service"
+	"github.com/lejianwen/rustdesk-api/v2/utils"
+	"gorm.io/gorm"
+)
+
+type Email struct {
+}
+
+// ConfigGet 获取邮件配置
+// @Tags Email
+// @Summary 获取邮件配置
+// @Description 获取SMTP邮件配置(密码脱敏)
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} response.Response{data=model.EmailConfig}
+// @Failure 500 {object} response.Response
+// @Router /admin/email/config [get]
+// @Security token
+func (ct *Email) ConfigGet(c *gin.Context) {
+	cfg := *service.AllService.EmailService.GetConfig()
+	if cfg.Password != "" {
+		cfg.Password = utils.MaskString(cfg.Password)
+	}
+	response.Success(c, cfg)
+}
+
+// ConfigSave 保存邮件配置
+// @Tags Email
+// @Summary 保存邮件配置
+// @Description 保存SMTP邮件配置, 密码为空或为脱敏值时保留原密码
+// @Accept  json
+// @Produce  json
+// @Param body body admin.EmailConfigForm true "邮件配置"
+// @Success 200 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /admin/email/config [post]
+// @Security token
+func (ct *Email) ConfigSave(c *gin.Context) {
+	f := &admin.EmailConfigForm{}
+	if err := c.ShouldBindJSON(f); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	errList := global.Validator.ValidStruct(c, f)
+	if len(errList) > 0 {
+		response.Fail(c, 101, errList[0])
+		return
+	}
+	cfg := f.ToEmailConfig()
+	current := service.AllService.EmailService.GetConfig()
+	if cfg.Password == "" || strings.Contains(cfg.Password, "*") {
+		cfg.Password = current.Password
+	}
+	if err := service.AllService.SystemSettingService.SetEmailConfig(cfg); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "OperationFailed")+err.Error())
+		return
+	}
+	response.Success(c, nil)
+}
+
+// Test 发送测试邮件
+// @Tags Email
+// @Summary 发送测试邮件
+// @Description 使用已保存的SMTP配置同步发送测试邮件
+// @Accept  json
+// @Produce  json
+// @Param body body admin.EmailTestForm true "收件人"
+// @Success 200 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /admin/email/test [post]
+// @Security token
+func (ct *Email) Test(c *gin.Context) {
+	f := &admin.EmailTestForm{}
+	if err := c.ShouldBindJSON(f); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	errList := global.Validator.ValidStruct(c, f)
+	if len(errList) > 0 {
+		response.Fail(c, 101, errList[0])
+		return
+	}
+	if err := service.AllService.EmailService.SendTest(service.AllService.EmailService.GetConfig(), f.To); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "EmailSendFailed")+err.Error())
+		return
+	}
+	response.Success(c, nil)
+}
+
+// Logs 发送记录
+// @Tags Email
+// @Summary 邮件发送记录
+// @Description 邮件发送记录
+// @Accept  json
+// @Produce  json
+// @Param page query int false "页码"
+// @Param page_size query int false "页大小"
+// @Param user_id query int false "用户ID"
+// @Param kind query string false "类型"
+// @Param status query int false "状态: 0待发送 1已发送 2失败"
+// @Success 200 {object} response.Response{data=model.EmailLogList}
+// @Failure 500 {object} response.Response
+// @Router /admin/email/logs [get]
+// @Security token
+func (ct *Email) Logs(c *gin.Context) {
+	query := &admin.EmailLogQuery{}
+	if err := c.ShouldBindQuery(query); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	res := service.AllService.EmailService.LogList(query.Page, query.PageSize, func(tx *gorm.DB) {
+		if query.UserId > 0 {
+			tx.Where("user_id = ?", query.UserId)
+		}
+		if query.Kind != "" {
+			tx.Where("kind = ?", query.Kind)
+		}
+		if query.Status != nil {
+			tx.Where("status = ?", *query.Status)
+		}
+		tx.Order("id desc")
+	})
+	response.Success(c, res)
+}
+
+// Resend 重新发送
+// @Tags Email
+// @Summary 重新发送邮件
+// @Description 按发送记录重新发送一封邮件并返回新记录; rerender 时按当前数据重新生成支付回执和退款确认, 发送到用户当前邮箱
+// @Accept  json
+// @Produce  json
+// @Param body body admin.EmailResendForm true "发送记录"
+// @Success 200 {object} response.Response{data=model.EmailLog}
+// @Failure 500 {object} response.Response
+// @Router /admin/email/resend [post]
+// @Security token
+func (ct *Email) Resend(c *gin.Context) {
+	f := &admin.EmailResendForm{}
+	if err := c.ShouldBindJSON(f); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	errList := global.Validator.ValidStruct(c, f)
+	if len(errList) > 0 {
+		response.Fail(c, 101, errList[0])
+		return
+	}
+	l, err := service.AllService.EmailService.Resend(f.Id, f.Rerender)
+	if err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, err.Error()))
+		return
+	}
+	response.Success(c, l)
+}
@@ -0,0 +1,148 @@
+package admin
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lejianwen/rustdesk-api/v2/global"
+	"github.com/lejianwen/rustdesk-api/v2/http/request/admin"
+	"github.com/lejianwen/rustdesk-api/v2/http/response"
+	"github.com/lejianwen/rustdesk-api/v2/model"
+	"github.com/lejianwen/rustdesk-api/v2/service"
+	"gorm.io/gorm"
+)
+
+// FileTransferList 文件传输审计列表
+// @Tags 文件日志
+// @Summary 文件传输审计列表
+// @Description 内部接口上报的文件传输记录, 包含方向、文件名、大小和两端设备
+// @Accept  json
+// @Produce  json
+// @Param page query int false "页码"
+// @Param page_size query int false "页大小"
+// @Param user_id query int false "发起用户"
+// @Param peer_id query string false "发起设备"
+// @Param target_peer_id query string false "被控设备"
+// @Param direction query string false "方向: upload/download"
+// @Param file_name query string false "文件名"
+// @Param start_at query int false "传输时间起(unix 秒)"
+// @Param end_at query int false "传输时间止(unix 秒)"
+// @Success 200 {object} response.Response{data=model.FileTransferLogList}
+// @Failure 500 {object} response.Response
+// @Router /admin/audit_file_transfer/list [get]
+// @Security token
+func (a *Audit) FileTransferList(c *gin.Context) {
+	query := &admin.FileTransferLogQuery{}
+	if err := c.ShouldBindQuery(query); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	res := service.AllService.AuditService.FileTransferLogList(query.Page, query.PageSize, fileTransferLogFilter(query))
+	response.Success(c, res)
+}
+
+// FileTransferExport 导出文件传输审计
+// @Tags 文件日志
+// @Summary 导出文件传输审计
+// @Description 按列表相同的筛选条件导出 CSV 或 xlsx
+// @Produce  octet-stream
+// @Param format query string false "导出格式: csv(默认)/xlsx"
+// @Param user_id query int false "发起用户"
+// @Param peer_id query string false "发起设备"
+// @Param target_peer_id query string false "被控设备"
+// @Param direction query string false "方向: upload/download"
+// @Param file_name query string false "文件名"
+// @Param start_at query int false "传输时间起(unix 秒)"
+// @Param end_at query int false "传输时间止(unix 秒)"
+// @Success 200 {file} file
+// @Router /admin/audit_file_transfer/export [get]
+// @Security token
+func (a *Audit) FileTransferExport(c *gin.Context) {
+	query := &admin.FileTransferLogQuery{}
+	if err := c.ShouldBindQuery(query); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	if query.Format == "" {
+		query.Format = "csv"
+	}
+	if query.Format != "csv" && query.Format != "xlsx" {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError"))
+		return
+	}
+	writeRow, flush, err := newExportWriter(c.Writer, query.Format)
+	if err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "OperationFailed")+err.Error())
+		return
+	}
+	filename := "file_transfers_" + time.Now().Format("20060102150405") + "." + query.Format
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+	c.Header("Cache-Control", "no-store")
+	c.Header("Content-Type", orderExportTypes[query.Format])
+	err = writeRow(fileTransferLogExportHeader)
+	if err == nil {
+		err = service.AllService.AuditService.ExportFileTransferLogs(fileTransferLogFilter(query), func(logs []*model.FileTransferLog) error {
+			for _, l := range logs {
+				if err := writeRow(fileTransferLogExportRow(l)); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	}
+	if err == nil {
+		err = flush()
+	}
+	if err != nil {
+		// 响应已开始写出, 只能记录日志
+		global.Logger.Error("Export file transfer logs failed: ", err)
+	}
+}
+
+func fileTransferLogFilter(query *admin.FileTransferLogQuery) func(tx *gorm.DB) {
+	return func(tx *gorm.DB) {
+		if query.UserId > 0 {
+			tx.Where("user_id = ? OR target_user_id = ?", query.UserId, query.UserId)
+		}
+		if query.PeerId != "" {
+			tx.Where("peer_id = ?", query.PeerId)
+		}
+		if query.TargetPeerId != "" {
+			tx.Where("target_peer_id = ?", query.TargetPeerId)
+		}
+		if query.Direction != "" {
+			tx.Where("direction = ?", query.Direction)
+		}
+		if query.FileName != "" {
+			tx.Where("file_name like ?", "%"+query.FileName+"%")
+		}
+		if query.StartAt > 0 {
+			tx.Where("transferred_at >= ?", query.StartAt)
+		}
+		if query.EndAt > 0 {
+			tx.Where("transferred_at < ?", query.EndAt)
+		}
+	}
+}
+
+var fileTransferLogExportHeader = []string{"ID", "方向", "发起设备", "发起用户ID", "被控设备", "被控用户ID", "文件名", "路径", "大小(字节)", "目录", "发起IP", "国家/地区", "城市", "传输时间"}
+
+func fileTransferLogExportRow(l *model.FileTransferLog) []string {
+	return []string{
+		strconv.FormatUint(uint64(l.Id), 10),
+		l.Direction,
+		l.PeerId,
+		strconv.FormatUint(uint64(l.UserId), 10),
+		l.TargetPeerId,
+		strconv.FormatUint(uint64(l.TargetUserId), 10),
+		l.FileName,
+		l.Path,
+		strconv.FormatInt(l.Size, 10),
+		strconv.FormatBool(l.IsDir),
+		l.Ip,
+		l.Country,
+		l.City,
+		formatUnix(l.TransferredAt),
+	}
+}
@@ -0,0 +1,146 @@
+package admin
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/lejianwen/rustdesk-api/v2/global"
+	"github.com/lejianwen/rustdesk-api/v2/http/request/admin"
+	"github.com/lejianwen/rustdesk-api/v2/http/response"
+	"github.com/lejianwen/rustdesk-api/v2/service"
+	"gorm.io/gorm"
+)
+
+type InternalKey struct {
+}
+
+// List 列表
+// @Tags InternalKey
+// @Summary 内部接口密钥列表
+// @Description 后台管理的内部接口密钥, 以及环境变量中的密钥ID和最近使用情况, 用于确认旧密钥已无调用后再移除
+// @Accept  json
+// @Produce  json
+// @Param page query int false "页码"
+// @Param page_size query int false "页大小"
+// @Success 200 {object} response.Response{data=model.InternalKeyList}
+// @Failure 500 {object} response.Response
+// @Router /admin/internal_key/list [get]
+// @Security token
+func (ct *InternalKey) List(c *gin.Context) {
+	query := &admin.InternalKeyQuery{}
+	if err := c.ShouldBindQuery(query); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	res := service.AllService.InternalKeyService.List(query.Page, query.PageSize, func(tx *gorm.DB) {
+		tx.Order("id desc")
+	})
+	response.Success(c, gin.H{
+		"list":      res.InternalKeys,
+		"page":      res.Page,
+		"page_size": res.PageSize,
+		"total":     res.Total,
+		"env_keys":  service.AllService.InternalKeyService.EnvUsage(),
+	})
+}
+
+// Create 创建
+// @Tags InternalKey
+// @Summary 创建内部接口密钥
+// @Description 生成新的内部接口密钥, 明文仅在本次返回
+// @Accept  json
+// @Produce  json
+// @Param body body admin.InternalKeyForm true "密钥信息"
+// @Success 200 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /admin/internal_key/create [post]
+// @Security token
+func (ct *InternalKey) Create(c *gin.Context) {
+	f := &admin.InternalKeyForm{}
+	if err := c.ShouldBindJSON(f); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	errList := global.Validator.ValidStruct(c, f)
+	if len(errList) > 0 {
+		response.Fail(c, 101, errList[0])
+		return
+	}
+	k := f.ToInternalKey()
+	k.Id = 0
+	plain, err := service.AllService.InternalKeyService.Create(k)
+	if err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "OperationFailed")+err.Error())
+		return
+	}
+	response.Success(c, gin.H{
+		"key":   k,
+		"plain": plain,
+	})
+}
+
+// Update 编辑
+// @Tags InternalKey
+// @Summary 编辑内部接口密钥
+// @Description 启用/禁用密钥或设置过期时间
+// @Accept  json
+// @Produce  json
+// @Param body body admin.InternalKeyForm true "密钥信息"
+// @Success 200 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /admin/internal_key/update [post]
+// @Security token
+func (ct *InternalKey) Update(c *gin.Context) {
+	f := &admin.InternalKeyForm{}
+	if err := c.ShouldBindJSON(f); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	errList := global.Validator.ValidStruct(c, f)
+	if len(errList) > 0 {
+		response.Fail(c, 101, errList[0])
+		return
+	}
+	ex := service.AllService.InternalKeyService.InfoById(f.Id)
+	if f.Id == 0 || ex.Id == 0 {
+		response.Fail(c, 101, response.TranslateMsg(c, "ItemNotFound"))
+		return
+	}
+	if err := service.AllService.InternalKeyService.Update(f.ToInternalKey()); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "OperationFailed")+err.Error())
+		return
+	}
+	response.Success(c, nil)
+}
+
+// Delete 删除
+// @Tags InternalKey
+// @Summary 删除内部接口密钥
+// @Description 删除后使用该密钥的调用将被拒绝
+// @Accept  json
+// @Produce  json
+// @Param body body admin.InternalKeyForm true "密钥信息"
+// @Success 200 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /admin/internal_key/delete [post]
+// @Security token
+func (ct *InternalKey) Delete(c *gin.Context) {
+	f := &admin.InternalKeyForm{}
+	if err := c.ShouldBindJSON(f); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	errList := global.Validator.ValidVar(c, f.Id, "required,gt=0")
+	if len(errList) > 0 {
+		response.Fail(c, 101, errList[0])
+		return
+	}
+	ex := service.AllService.InternalKeyService.InfoById(f.Id)
+	if ex.Id == 0 {
+		response.Fail(c, 101, response.TranslateMsg(c, "ItemNotFound"))
+		return
+	}
+	if err := service.AllService.InternalKeyService.Delete(ex); err != nil {
+		response.Fail(c, 101, err.Error())
+		return
+	}
+	response.Success(c, nil)
+}
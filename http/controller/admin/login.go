@@ -97,7 +97,7 @@ func (ct *Login) Login(c *gin.Context) {
 
 	// 登录成功，清除登录限制
 	loginLimiter.RemoveAttempts(clientIp)
-	responseLoginSuccess(c, u, ut.Token)
+	responseLoginSuccess(c, u, ut)
 }
 func (ct *Login) Captcha(c *gin.Context) {
 	loginLimiter := global.LoginLimiter
@@ -230,13 +230,18 @@ func (ct *Login) OidcAuthQuery(c *gin.Context) {
 	if ut == nil {
 		return
 	}
-	responseLoginSuccess(c, u, ut.Token)
+	responseLoginSuccess(c, u, ut)
 }
 
-func responseLoginSuccess(c *gin.Context, u *model.User, token string) {
+func responseLoginSuccess(c *gin.Context, u *model.User, ut *model.UserToken) {
 	lp := &adResp.LoginPayload{}
 	lp.FromUser(u)
-	lp.Token = token
+	lp.Token = ut.Token
+	lp.RefreshToken = ut.RefreshToken
 	lp.RouteNames = service.AllService.UserService.RouteNames(u)
+	if service.AllService.UserService.IsAdmin(u) {
+		lp.AdminRole = model.NormalizeAdminRole(u.AdminRole)
+	}
+	lp.Permissions = service.AllService.UserService.Permissions(u)
 	response.Success(c, lp)
 }
@@ -0,0 +1,136 @@
+package admin
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/lejianwen/rustdesk-api/v2/global"
+	"github.com/lejianwen/rustdesk-api/v2/http/request/admin"
+	"github.com/lejianwen/rustdesk-api/v2/http/response"
+	"github.com/lejianwen/rustdesk-api/v2/service"
+	"gorm.io/gorm"
+)
+
+type Maintenance struct {
+}
+
+// List 列表
+// @Tags Maintenance
+// @Summary 维护窗口列表
+// @Description 计费系统维护窗口列表, 窗口期间订阅和relay配额校验只告警不拒绝
+// @Accept  json
+// @Produce  json
+// @Param page query int false "页码"
+// @Param page_size query int false "页大小"
+// @Success 200 {object} response.Response{data=model.MaintenanceWindowList}
+// @Failure 500 {object} response.Response
+// @Router /admin/maintenance/list [get]
+// @Security token
+func (ct *Maintenance) List(c *gin.Context) {
+	query := &admin.MaintenanceQuery{}
+	if err := c.ShouldBindQuery(query); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	res := service.AllService.MaintenanceService.List(query.Page, query.PageSize, func(tx *gorm.DB) {
+		tx.Order("id desc")
+	})
+	response.Success(c, res)
+}
+
+// Create 创建
+// @Tags Maintenance
+// @Summary 创建维护窗口
+// @Description 创建维护窗口, mode: warn 放行并记录告警, bypass 直接放行
+// @Accept  json
+// @Produce  json
+// @Param body body admin.MaintenanceForm true "维护窗口"
+// @Success 200 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /admin/maintenance/create [post]
+// @Security token
+func (ct *Maintenance) Create(c *gin.Context) {
+	f := &admin.MaintenanceForm{}
+	if err := c.ShouldBindJSON(f); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	errList := global.Validator.ValidStruct(c, f)
+	if len(errList) > 0 {
+		response.Fail(c, 101, errList[0])
+		return
+	}
+	m := f.ToMaintenanceWindow()
+	m.Id = 0
+	if err := service.AllService.MaintenanceService.Create(m); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "OperationFailed")+err.Error())
+		return
+	}
+	response.Success(c, m)
+}
+
+// Update 编辑
+// @Tags Maintenance
+// @Summary 编辑维护窗口
+// @Description 编辑维护窗口, 可通过禁用提前结束
+// @Accept  json
+// @Produce  json
+// @Param body body admin.MaintenanceForm true "维护窗口"
+// @Success 200 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /admin/maintenance/update [post]
+// @Security token
+func (ct *Maintenance) Update(c *gin.Context) {
+	f := &admin.MaintenanceForm{}
+	if err := c.ShouldBindJSON(f); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	errList := global.Validator.ValidStruct(c, f)
+	if len(errList) > 0 {
+		response.Fail(c, 101, errList[0])
+		return
+	}
+	ex := service.AllService.MaintenanceService.InfoById(f.Id)
+	if f.Id == 0 || ex.Id == 0 {
+		response.Fail(c, 101, response.TranslateMsg(c, "ItemNotFound"))
+		return
+	}
+	if err := service.AllService.MaintenanceService.Update(f.ToMaintenanceWindow()); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "OperationFailed")+err.Error())
+		return
+	}
+	response.Success(c, nil)
+}
+
+// Delete 删除
+// @Tags Maintenance
+// @Summary 删除维护窗口
+// @Description 删除维护窗口
+// @Accept  json
+// @Produce  json
+// @Param body body admin.MaintenanceForm true "维护窗口"
+// @Success 200 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /admin/maintenance/delete [post]
+// @Security token
+func (ct *Maintenance) Delete(c *gin.Context) {
+	f := &admin.MaintenanceForm{}
+	if err := c.ShouldBindJSON(f); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	errList := global.Validator.ValidVar(c, f.Id, "required,gt=0")
+	if len(errList) > 0 {
+		response.Fail(c, 101, errList[0])
+		return
+	}
+	ex := service.AllService.MaintenanceService.InfoById(f.Id)
+	if ex.Id == 0 {
+		response.Fail(c, 101, response.TranslateMsg(c, "ItemNotFound"))
+		return
+	}
+	if err := service.AllService.MaintenanceService.Delete(ex); err != nil {
+		response.Fail(c, 101, err.Error())
+		return
+	}
+	response.Success(c, nil)
+}
@@ -37,6 +37,10 @@ func (abc *AddressBookCollection) Create(c *gin.Context) {
 	}
 	u := service.AllService.UserService.CurUser(c)
 	f.UserId = u.Id
+	if msg, ok := abc.checkTeam(u, f); !ok {
+		response.Fail(c, 101, response.TranslateMsg(c, msg))
+		return
+	}
 	err := service.AllService.AddressBookService.CreateCollection(f)
 	if err != nil {
 		response.Fail(c, 101, response.TranslateMsg(c, "OperationFailed")+err.Error())
@@ -111,6 +115,11 @@ func (abc *AddressBookCollection) Update(c *gin.Context) {
 		response.Fail(c, 101, response.TranslateMsg(c, "NoAccess"))
 		return
 	}
+	f.UserId = u.Id
+	if msg, ok := abc.checkTeam(u, f); !ok {
+		response.Fail(c, 101, response.TranslateMsg(c, msg))
+		return
+	}
 
 	err := service.AllService.AddressBookService.UpdateCollection(f)
 	if err != nil {
@@ -160,3 +169,18 @@ func (abc *AddressBookCollection) Delete(c *gin.Context) {
 	}
 	response.Fail(c, 101, response.TranslateMsg(c, "OperationFailed")+err.Error())
 }
+
+// checkTeam 只有组织所有者可以创建团队地址簿
+func (abc *AddressBookCollection) checkTeam(u *model.User, f *model.AddressBookCollection) (string, bool) {
+	if f.OrgId == 0 {
+		return "", true
+	}
+	ownerId, err := service.AllService.AddressBookService.TeamCollectionOwner(f.OrgId)
+	if err != nil {
+		return err.Error(), false
+	}
+	if ownerId != u.Id {
+		return "NoAccess", false
+	}
+	return "", true
+}
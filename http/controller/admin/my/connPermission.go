@@ -0,0 +1,52 @@
+package my
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/lejianwen/rustdesk-api/v2/http/request/admin"
+	"github.com/lejianwen/rustdesk-api/v2/http/response"
+	"github.com/lejianwen/rustdesk-api/v2/service"
+)
+
+type ConnPermission struct{}
+
+// Detail 我的默认连接权限
+// @Tags 我的连接权限
+// @Summary 默认连接权限
+// @Description 获取我的设备默认授予来访连接的权限
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} response.Response{data=model.ConnPermission}
+// @Failure 500 {object} response.Response
+// @Router /admin/my/conn_permission/detail [get]
+// @Security token
+func (ct *ConnPermission) Detail(c *gin.Context) {
+	u := service.AllService.UserService.CurUser(c)
+	response.Success(c, service.AllService.ConnPermissionService.GetByUserId(u.Id))
+}
+
+// Save 保存默认连接权限
+// @Tags 我的连接权限
+// @Summary 保存默认连接权限
+// @Description 保存我的设备默认授予来访连接的权限
+// @Accept  json
+// @Produce  json
+// @Param body body admin.ConnPermissionForm true "权限信息"
+// @Success 200 {object} response.Response{data=model.ConnPermission}
+// @Failure 500 {object} response.Response
+// @Router /admin/my/conn_permission/save [post]
+// @Security token
+func (ct *ConnPermission) Save(c *gin.Context) {
+	f := &admin.ConnPermissionForm{}
+	if err := c.ShouldBindJSON(f); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	u := service.AllService.UserService.CurUser(c)
+	p := f.ToConnPermission()
+	p.UserId = u.Id
+	if err := service.AllService.ConnPermissionService.Save(p); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "OperationFailed")+err.Error())
+		return
+	}
+	response.Success(c, p)
+}
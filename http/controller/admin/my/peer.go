@@ -2,6 +2,7 @@ package my
 
 import (
 	"github.com/gin-gonic/gin"
+	"github.com/lejianwen/rustdesk-api/v2/global"
 	"github.com/lejianwen/rustdesk-api/v2/http/request/admin"
 	"github.com/lejianwen/rustdesk-api/v2/http/response"
 	"github.com/lejianwen/rustdesk-api/v2/service"
@@ -57,3 +58,33 @@ func (ct *Peer) List(c *gin.Context) {
 	})
 	response.Success(c, res)
 }
+
+// Deactivate 解绑设备
+// @Tags 我的设备
+// @Summary 解绑设备
+// @Description 解绑自己的设备以释放套餐设备数, 该设备的登录同时失效
+// @Accept  json
+// @Produce  json
+// @Param body body admin.PeerDeactivateForm true "设备"
+// @Success 200 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /admin/my/peer/deactivate [post]
+// @Security token
+func (ct *Peer) Deactivate(c *gin.Context) {
+	f := &admin.PeerDeactivateForm{}
+	if err := c.ShouldBindJSON(f); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	errList := global.Validator.ValidStruct(c, f)
+	if len(errList) > 0 {
+		response.Fail(c, 101, errList[0])
+		return
+	}
+	u := service.AllService.UserService.CurUser(c)
+	if err := service.AllService.PeerService.DeactivateDevice(f.RowId, u.Id); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, err.Error()))
+		return
+	}
+	response.Success(c, nil)
+}
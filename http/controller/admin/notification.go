@@ -0,0 +1,104 @@
+package admin
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/lejianwen/rustdesk-api/v2/global"
+	"github.com/lejianwen/rustdesk-api/v2/http/request/admin"
+	"github.com/lejianwen/rustdesk-api/v2/http/response"
+	"github.com/lejianwen/rustdesk-api/v2/model"
+	"github.com/lejianwen/rustdesk-api/v2/service"
+	"gorm.io/gorm"
+)
+
+type Announcement struct {
+}
+
+// List 列表
+// @Tags Announcement
+// @Summary 公告列表
+// @Description 面向全部用户的站内公告
+// @Accept  json
+// @Produce  json
+// @Param page query int false "页码"
+// @Param page_size query int false "页大小"
+// @Success 200 {object} response.Response{data=model.NotificationList}
+// @Failure 500 {object} response.Response
+// @Router /admin/announcement/list [get]
+// @Security token
+func (ct *Announcement) List(c *gin.Context) {
+	query := &admin.AnnouncementQuery{}
+	if err := c.ShouldBindQuery(query); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	res := service.AllService.NotificationService.List(query.Page, query.PageSize, func(tx *gorm.DB) {
+		tx.Where("user_id = 0 AND kind = ?", model.NotificationKindAnnouncement)
+		tx.Order("id desc")
+	})
+	response.Success(c, res)
+}
+
+// Create 发布
+// @Tags Announcement
+// @Summary 发布公告
+// @Description 发布后所有用户的站内通知中可见
+// @Accept  json
+// @Produce  json
+// @Param body body admin.AnnouncementForm true "公告"
+// @Success 200 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /admin/announcement/create [post]
+// @Security token
+func (ct *Announcement) Create(c *gin.Context) {
+	f := &admin.AnnouncementForm{}
+	if err := c.ShouldBindJSON(f); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	errList := global.Validator.ValidStruct(c, f)
+	if len(errList) > 0 {
+		response.Fail(c, 101, errList[0])
+		return
+	}
+	u := service.AllService.UserService.CurUser(c)
+	n, err := service.AllService.NotificationService.Announce(f.Title, f.Content, u.Id)
+	if err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "OperationFailed")+err.Error())
+		return
+	}
+	response.Success(c, n)
+}
+
+// Delete 删除
+// @Tags Announcement
+// @Summary 删除公告
+// @Description 删除公告及用户的已读记录
+// @Accept  json
+// @Produce  json
+// @Param body body admin.AnnouncementForm true "公告"
+// @Success 200 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /admin/announcement/delete [post]
+// @Security token
+func (ct *Announcement) Delete(c *gin.Context) {
+	f := &admin.AnnouncementForm{}
+	if err := c.ShouldBindJSON(f); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	errList := global.Validator.ValidVar(c, f.Id, "required,gt=0")
+	if len(errList) > 0 {
+		response.Fail(c, 101, errList[0])
+		return
+	}
+	ex := service.AllService.NotificationService.InfoById(f.Id)
+	if ex.Id == 0 || ex.UserId != 0 {
+		response.Fail(c, 101, response.TranslateMsg(c, "ItemNotFound"))
+		return
+	}
+	if err := service.AllService.NotificationService.Delete(ex); err != nil {
+		response.Fail(c, 101, err.Error())
+		return
+	}
+	response.Success(c, nil)
+}
@@ -0,0 +1,186 @@
+package admin
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lejianwen/rustdesk-api/v2/global"
+	"github.com/lejianwen/rustdesk-api/v2/http/response"
+	"github.com/lejianwen/rustdesk-api/v2/model"
+	"github.com/lejianwen/rustdesk-api/v2/service"
+	"gorm.io/gorm"
+)
+
+// ========== 组织/团队订阅 ==========
+
+type OrganizationForm struct {
+	Name    string `json:"name" validate:"required,max=128"`
+	OwnerId uint   `json:"owner_id" validate:"required"` // 所有者, 其订阅为成员提供席位
+}
+
+type OrganizationMemberForm struct {
+	OrgId  uint `json:"org_id" validate:"required"`
+	UserId uint `json:"user_id" validate:"required"`
+}
+
+// OrganizationList 组织列表
+// @Tags Admin-Payment
+// @Summary 组织列表
+// @Description 组织及席位占用, 席位数取所有者当前有效订阅的套餐
+// @Accept  json
+// @Produce  json
+// @Param page query int false "页码"
+// @Param page_size query int false "每页数量"
+// @Param owner_id query int false "所有者ID"
+// @Success 200 {object} response.Response{data=model.OrganizationList}
+// @Router /api/admin/organization/list [get]
+func (p *Payment) OrganizationList(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 10
+	}
+	ownerId, _ := strconv.Atoi(c.Query("owner_id"))
+	res := service.AllService.SubscriptionService.ListOrganizations(uint(page), uint(pageSize), func(tx *gorm.DB) {
+		if ownerId > 0 {
+			tx.Where("owner_id = ?", ownerId)
+		}
+	})
+	response.Success(c, res)
+}
+
+// OrganizationDetail 组织详情
+// @Tags Admin-Payment
+// @Summary 组织详情
+// @Description 组织成员及是否在席位内
+// @Produce  json
+// @Param id path int true "组织ID"
+// @Success 200 {object} response.Response{data=model.Organization}
+// @Router /api/admin/organization/detail/{id} [get]
+func (p *Payment) OrganizationDetail(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	org := service.AllService.SubscriptionService.GetOrganizationById(uint(id))
+	if org.Id == 0 {
+		response.Fail(c, 101, response.TranslateMsg(c, "OrganizationNotFound"))
+		return
+	}
+	response.Success(c, service.AllService.SubscriptionService.OrganizationDetail(org))
+}
+
+// OrganizationCreate 创建组织
+// @Tags Admin-Payment
+// @Summary 创建组织
+// @Description 为用户创建组织, 不要求所有者当前已有多席位套餐(可随后赠送)
+// @Accept  json
+// @Produce  json
+// @Param body body OrganizationForm true "组织信息"
+// @Success 200 {object} response.Response{data=model.Organization}
+// @Router /api/admin/organization/create [post]
+func (p *Payment) OrganizationCreate(c *gin.Context) {
+	var form OrganizationForm
+	if err := c.ShouldBindJSON(&form); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	errList := global.Validator.ValidStruct(c, &form)
+	if len(errList) > 0 {
+		response.Fail(c, 101, errList[0])
+		return
+	}
+	org, err := service.AllService.SubscriptionService.CreateOrganization(form.OwnerId, form.Name, false)
+	if err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, err.Error()))
+		return
+	}
+	audit(c, model.AdminAuditOrganization, org.Id, nil, org)
+	response.Success(c, org)
+}
+
+// OrganizationDelete 解散组织
+// @Tags Admin-Payment
+// @Summary 解散组织
+// @Description 删除组织和全部成员关系, 成员失去组织提供的订阅
+// @Accept  json
+// @Produce  json
+// @Param body body IdForm true "组织ID"
+// @Success 200 {object} response.Response
+// @Router /api/admin/organization/delete [post]
+func (p *Payment) OrganizationDelete(c *gin.Context) {
+	var form IdForm
+	if err := c.ShouldBindJSON(&form); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	org := service.AllService.SubscriptionService.GetOrganizationById(form.Id)
+	if org.Id == 0 {
+		response.Fail(c, 101, response.TranslateMsg(c, "OrganizationNotFound"))
+		return
+	}
+	before := service.AllService.SubscriptionService.OrganizationDetail(org)
+	if err := service.AllService.SubscriptionService.DeleteOrganization(form.Id); err != nil {
+		response.Fail(c, 101, err.Error())
+		return
+	}
+	audit(c, model.AdminAuditOrganization, form.Id, before, nil)
+	response.Success(c, nil)
+}
+
+// OrganizationMemberAdd 添加组织成员
+// @Tags Admin-Payment
+// @Summary 添加组织成员
+// @Description 席位已满或用户已加入其他组织时失败
+// @Accept  json
+// @Produce  json
+// @Param body body OrganizationMemberForm true "成员"
+// @Success 200 {object} response.Response
+// @Router /api/admin/organization/members/add [post]
+func (p *Payment) OrganizationMemberAdd(c *gin.Context) {
+	var form OrganizationMemberForm
+	if err := c.ShouldBindJSON(&form); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	errList := global.Validator.ValidStruct(c, &form)
+	if len(errList) > 0 {
+		response.Fail(c, 101, errList[0])
+		return
+	}
+	u := service.AllService.UserService.CurUser(c)
+	if err := service.AllService.SubscriptionService.AddOrganizationMember(form.OrgId, form.UserId, u.Id); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, err.Error()))
+		return
+	}
+	audit(c, model.AdminAuditOrganization, form.OrgId, nil, form)
+	response.Success(c, nil)
+}
+
+// OrganizationMemberRemove 移除组织成员
+// @Tags Admin-Payment
+// @Summary 移除组织成员
+// @Description 所有者不能移除, 需解散组织
+// @Accept  json
+// @Produce  json
+// @Param body body OrganizationMemberForm true "成员"
+// @Success 200 {object} response.Response
+// @Router /api/admin/organization/members/remove [post]
+func (p *Payment) OrganizationMemberRemove(c *gin.Context) {
+	var form OrganizationMemberForm
+	if err := c.ShouldBindJSON(&form); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	errList := global.Validator.ValidStruct(c, &form)
+	if len(errList) > 0 {
+		response.Fail(c, 101, errList[0])
+		return
+	}
+	if err := service.AllService.SubscriptionService.RemoveOrganizationMember(form.OrgId, form.UserId); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, err.Error()))
+		return
+	}
+	audit(c, model.AdminAuditOrganization, form.OrgId, form, nil)
+	response.Success(c, nil)
+}
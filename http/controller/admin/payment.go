@@ -1,14 +1,23 @@
 package admin
 
 import (
+	"encoding/csv"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/lejianwen/rustdesk-api/v2/global"
 	"github.com/lejianwen/rustdesk-api/v2/http/response"
+	"github.com/lejianwen/rustdesk-api/v2/lib/publicid"
 	"github.com/lejianwen/rustdesk-api/v2/model"
 	"github.com/lejianwen/rustdesk-api/v2/service"
+	"github.com/lejianwen/rustdesk-api/v2/utils"
 	"gorm.io/gorm"
 )
 
@@ -40,6 +49,10 @@ func (p *Payment) PlanList(c *gin.Context) {
 	}
 
 	plans := service.AllService.SubscriptionService.ListPlans(uint(page), uint(pageSize), nil)
+	locale := response.Locale(c)
+	for _, plan := range plans.Plans {
+		plan.Localize(locale)
+	}
 	response.Success(c, plans)
 }
 
@@ -53,15 +66,25 @@ func (p *Payment) PlanList(c *gin.Context) {
 // @Success 200 {object} response.Response
 // @Router /api/admin/subscription_plan/detail/{id} [get]
 func (p *Payment) PlanDetail(c *gin.Context) {
-	id, _ := strconv.Atoi(c.Param("id"))
-	plan := service.AllService.SubscriptionService.GetPlanById(uint(id))
+	plan := service.AllService.Subscription().GetPlanById(refId(publicid.KindPlan, c.Param("id")))
 	if plan.Id == 0 {
 		response.Fail(c, 101, response.TranslateMsg(c, "PlanNotFound"))
 		return
 	}
+	plan.Localize(response.Locale(c))
+	plan.Publicize(service.AllService.PublicIds(), false)
 	response.Success(c, plan)
 }
 
+// refId 后台接口中的ID, 数字ID或用户提供的对外标识均可
+func refId(kind, s string) uint {
+	if id, err := strconv.Atoi(s); err == nil && id > 0 {
+		return uint(id)
+	}
+	id, _ := service.AllService.PublicIds().Decode(kind, s)
+	return id
+}
+
 // PlanCreate 创建套餐
 // @Tags Admin-Payment
 // @Summary 创建套餐
@@ -84,29 +107,51 @@ func (p *Payment) PlanCreate(c *gin.Context) {
 		response.Fail(c, 101, errList[0])
 		return
 	}
+	if err := form.NormalizePeriod(); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "PeriodInvalid"))
+		return
+	}
 
 	// 检查编码是否重复
-	existing := service.AllService.SubscriptionService.GetPlanByCode(form.Code)
+	existing := service.AllService.Subscription().GetPlanByCode(form.Code)
 	if existing.Id != 0 {
 		response.Fail(c, 101, response.TranslateMsg(c, "PlanCodeExists"))
 		return
 	}
 
 	plan := &model.SubscriptionPlan{
-		Code:        form.Code,
-		Name:        form.Name,
-		Description: form.Description,
-		Price:       form.Price,
-		PeriodUnit:  form.PeriodUnit,
-		PeriodCount: form.PeriodCount,
-		Status:      model.StatusCode(form.Status),
-		SortOrder:   form.SortOrder,
+		Code:                   form.Code,
+		Name:                   form.Name,
+		Description:            form.Description,
+		Price:                  form.Price,
+		PeriodUnit:             form.PeriodUnit,
+		PeriodCount:            form.PeriodCount,
+		Period:                 form.Period,
+		BonusDaysFirstPurchase: form.BonusDaysFirstPurchase,
+		MaxDevices:             form.MaxDevices,
+		MaxSessions:            form.MaxSessions,
+		DeviceSeats:            form.DeviceSeats,
+		RelayQuotaBytes:        form.RelayQuotaBytes,
+		RelayQuotaMinutes:      form.RelayQuotaMinutes,
+		PremiumRelay:           form.PremiumRelay,
+		PremiumRelayTrialDays:  form.PremiumRelayTrialDays,
+		Seats:                  form.Seats,
+		OncePerUser:            form.OncePerUser,
+		NewUsersOnly:           form.NewUsersOnly,
+		UpgradeOnly:            form.UpgradeOnly,
+		Visibility:             form.Visibility,
+		Status:                 model.StatusCode(form.Status),
+		SortOrder:              form.SortOrder,
+		Prices:                 form.ToPlanPrices(),
 	}
+	plan.SetGroupIds(form.VisibleGroups)
+	plan.SetFeatures(form.Features)
 
 	if err := service.AllService.SubscriptionService.CreatePlan(plan); err != nil {
 		response.Fail(c, 101, err.Error())
 		return
 	}
+	audit(c, model.AdminAuditPlanCreate, plan.Id, nil, plan)
 
 	response.Success(c, plan)
 }
@@ -132,7 +177,17 @@ func (p *Payment) PlanUpdate(c *gin.Context) {
 		return
 	}
 
-	plan := service.AllService.SubscriptionService.GetPlanById(form.Id)
+	errList := global.Validator.ValidStruct(c, &form)
+	if len(errList) > 0 {
+		response.Fail(c, 101, errList[0])
+		return
+	}
+	if err := form.NormalizePeriod(); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "PeriodInvalid"))
+		return
+	}
+
+	plan := service.AllService.Subscription().GetPlanById(form.Id)
 	if plan.Id == 0 {
 		response.Fail(c, 101, response.TranslateMsg(c, "PlanNotFound"))
 		return
@@ -140,26 +195,49 @@ func (p *Payment) PlanUpdate(c *gin.Context) {
 
 	// 检查编码是否重复(排除自身)
 	if form.Code != plan.Code {
-		existing := service.AllService.SubscriptionService.GetPlanByCode(form.Code)
+		existing := service.AllService.Subscription().GetPlanByCode(form.Code)
 		if existing.Id != 0 && existing.Id != plan.Id {
 			response.Fail(c, 101, response.TranslateMsg(c, "PlanCodeExists"))
 			return
 		}
 	}
 
+	before := *plan
 	plan.Code = form.Code
 	plan.Name = form.Name
 	plan.Description = form.Description
 	plan.Price = form.Price
 	plan.PeriodUnit = form.PeriodUnit
 	plan.PeriodCount = form.PeriodCount
+	plan.Period = form.Period
+	plan.BonusDaysFirstPurchase = form.BonusDaysFirstPurchase
+	plan.MaxDevices = form.MaxDevices
+	plan.MaxSessions = form.MaxSessions
+	plan.DeviceSeats = form.DeviceSeats
+	plan.RelayQuotaBytes = form.RelayQuotaBytes
+	plan.RelayQuotaMinutes = form.RelayQuotaMinutes
+	plan.PremiumRelay = form.PremiumRelay
+	plan.PremiumRelayTrialDays = form.PremiumRelayTrialDays
+	plan.Seats = form.Seats
+	plan.OncePerUser = form.OncePerUser
+	plan.NewUsersOnly = form.NewUsersOnly
+	plan.UpgradeOnly = form.UpgradeOnly
+	plan.Visibility = form.Visibility
+	plan.SetGroupIds(form.VisibleGroups)
+	if form.Features != nil {
+		plan.SetFeatures(form.Features)
+	}
 	plan.Status = model.StatusCode(form.Status)
 	plan.SortOrder = form.SortOrder
+	if form.Prices != nil {
+		plan.Prices = form.ToPlanPrices()
+	}
 
 	if err := service.AllService.SubscriptionService.UpdatePlan(plan); err != nil {
 		response.Fail(c, 101, err.Error())
 		return
 	}
+	audit(c, model.AdminAuditPlanUpdate, plan.Id, &before, plan)
 
 	response.Success(c, plan)
 }
@@ -174,151 +252,264 @@ func (p *Payment) PlanUpdate(c *gin.Context) {
 // @Success 200 {object} response.Response
 // @Router /api/admin/subscription_plan/delete [post]
 func (p *Payment) PlanDelete(c *gin.Context) {
-	var form IdForm
+	var form PlanDeleteForm
 	if err := c.ShouldBindJSON(&form); err != nil {
 		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
 		return
 	}
+	errList := global.Validator.ValidStruct(c, &form)
+	if len(errList) > 0 {
+		response.Fail(c, 101, errList[0])
+		return
+	}
 
-	if err := service.AllService.SubscriptionService.DeletePlan(form.Id); err != nil {
-		response.Fail(c, 101, err.Error())
+	u := service.AllService.UserService.CurUser(c)
+	before := service.AllService.Subscription().GetPlanById(form.Id)
+	m, err := service.AllService.SubscriptionService.ArchivePlan(form.Id, form.SuccessorId, u.Id)
+	if err != nil {
+		if err.Error() == "PlanSuccessorRequired" {
+			response.Fail(c, 101, response.TranslateParamMsg(c, "PlanSuccessorRequired",
+				strconv.FormatInt(service.AllService.SubscriptionService.CountActiveSubscribers(form.Id), 10)))
+			return
+		}
+		response.Fail(c, 101, response.TranslateMsg(c, err.Error()))
 		return
 	}
+	audit(c, model.AdminAuditPlanDelete, form.Id, before, gin.H{
+		"plan":      service.AllService.Subscription().GetPlanById(form.Id),
+		"migration": m,
+	})
 
-	response.Success(c, nil)
+	response.Success(c, gin.H{"migration": m})
 }
 
-// ========== 订单管理 ==========
-
-// OrderList 订单列表
+// PlanMigrations 套餐迁移任务列表
 // @Tags Admin-Payment
-// @Summary 获取订单列表
-// @Description 获取所有订单(分页)
+// @Summary 套餐迁移任务列表
+// @Description 归档套餐时将有效订阅迁移到继任套餐的任务及进度
 // @Accept  json
 // @Produce  json
 // @Param page query int false "页码"
 // @Param page_size query int false "每页数量"
-// @Param user_id query int false "用户ID"
-// @Param status query int false "状态"
-// @Param out_trade_no query string false "订单号"
-// @Success 200 {object} response.Response
-// @Router /api/admin/order/list [get]
-func (p *Payment) OrderList(c *gin.Context) {
+// @Param plan_id query int false "被归档的套餐ID"
+// @Success 200 {object} response.Response{data=model.PlanMigrationList}
+// @Router /api/admin/subscription_plan/migrations [get]
+func (p *Payment) PlanMigrations(c *gin.Context) {
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
 	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
-	userId, _ := strconv.Atoi(c.DefaultQuery("user_id", "0"))
-	status, _ := strconv.Atoi(c.DefaultQuery("status", "-1"))
-	outTradeNo := c.DefaultQuery("out_trade_no", "")
+	planId, _ := strconv.Atoi(c.DefaultQuery("plan_id", "0"))
 	if page < 1 {
 		page = 1
 	}
-	if pageSize < 1 {
+	if pageSize < 1 || pageSize > 100 {
 		pageSize = 10
 	}
-	if pageSize > 100 {
-		pageSize = 100
-	}
-
-	orders := service.AllService.SubscriptionService.ListOrders(uint(page), uint(pageSize), func(tx *gorm.DB) {
-		if userId > 0 {
-			tx.Where("user_id = ?", userId)
-		}
-		if status >= 0 {
-			tx.Where("status = ?", status)
-		}
-		if outTradeNo != "" {
-			tx.Where("out_trade_no LIKE ?", "%"+outTradeNo+"%")
+	res := service.AllService.SubscriptionService.ListPlanMigrations(uint(page), uint(pageSize), func(tx *gorm.DB) {
+		if planId > 0 {
+			tx.Where("from_plan_id = ?", planId)
 		}
 	})
-	response.Success(c, orders)
+	response.Success(c, res)
 }
 
-// OrderDetail 订单详情
+// PlanMigrationDetail 套餐迁移任务进度
 // @Tags Admin-Payment
-// @Summary 获取订单详情
-// @Description 根据ID获取订单详情
+// @Summary 套餐迁移任务进度
 // @Accept  json
 // @Produce  json
-// @Param id path int true "订单ID"
-// @Success 200 {object} response.Response
-// @Router /api/admin/order/detail/{id} [get]
-func (p *Payment) OrderDetail(c *gin.Context) {
+// @Param id path int true "任务ID"
+// @Success 200 {object} response.Response{data=model.PlanMigration}
+// @Router /api/admin/subscription_plan/migration/{id} [get]
+func (p *Payment) PlanMigrationDetail(c *gin.Context) {
 	id, _ := strconv.Atoi(c.Param("id"))
-	order := service.AllService.SubscriptionService.GetOrderById(uint(id))
-	if order.Id == 0 {
-		response.Fail(c, 101, response.TranslateMsg(c, "OrderNotFound"))
+	m := service.AllService.SubscriptionService.GetPlanMigrationById(uint(id))
+	if m.Id == 0 {
+		response.Fail(c, 101, response.TranslateMsg(c, "ItemNotFound"))
 		return
 	}
-	response.Success(c, order)
+	response.Success(c, m)
 }
 
-// OrderRefund 订单退款
+// ========== 一次性商品 ==========
+
+// ProductList 商品列表
 // @Tags Admin-Payment
-// @Summary 订单退款
-// @Description 对已支付订单发起退款
+// @Summary 一次性商品列表
 // @Accept  json
 // @Produce  json
-// @Param body body RefundForm true "退款信息"
-// @Success 200 {object} response.Response
-// @Router /api/admin/order/refund [post]
-func (p *Payment) OrderRefund(c *gin.Context) {
-	var form RefundForm
+// @Param page query int false "页码"
+// @Param page_size query int false "每页数量"
+// @Success 200 {object} response.Response{data=model.ProductList}
+// @Router /api/admin/product/list [get]
+func (p *Payment) ProductList(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 10
+	}
+	res := service.AllService.SubscriptionService.ListProducts(uint(page), uint(pageSize), nil)
+	locale := response.Locale(c)
+	for _, pr := range res.Products {
+		pr.Localize(locale)
+	}
+	response.Success(c, res)
+}
+
+// ProductCreate 创建商品
+// @Tags Admin-Payment
+// @Summary 创建一次性商品
+// @Accept  json
+// @Produce  json
+// @Param body body ProductForm true "商品信息"
+// @Success 200 {object} response.Response{data=model.Product}
+// @Router /api/admin/product/create [post]
+func (p *Payment) ProductCreate(c *gin.Context) {
+	var form ProductForm
 	if err := c.ShouldBindJSON(&form); err != nil {
 		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
 		return
 	}
-
-	if err := service.AllService.SubscriptionService.RefundOrder(form.OrderId, form.Reason); err != nil {
-		response.Fail(c, 101, response.TranslateMsg(c, err.Error()))
+	errList := global.Validator.ValidStruct(c, &form)
+	if len(errList) > 0 {
+		response.Fail(c, 101, errList[0])
+		return
+	}
+	if ex := service.AllService.SubscriptionService.GetProductByCode(form.Code); ex.Id != 0 {
+		response.Fail(c, 101, response.TranslateMsg(c, "ProductCodeExists"))
 		return
 	}
+	pr := form.ToProduct()
+	pr.Id = 0
+	if err := service.AllService.SubscriptionService.CreateProduct(pr); err != nil {
+		response.Fail(c, 101, err.Error())
+		return
+	}
+	audit(c, model.AdminAuditProduct, pr.Id, nil, pr)
+	response.Success(c, pr)
+}
 
+// ProductUpdate 更新商品
+// @Tags Admin-Payment
+// @Summary 更新一次性商品
+// @Accept  json
+// @Produce  json
+// @Param body body ProductForm true "商品信息"
+// @Success 200 {object} response.Response
+// @Router /api/admin/product/update [post]
+func (p *Payment) ProductUpdate(c *gin.Context) {
+	var form ProductForm
+	if err := c.ShouldBindJSON(&form); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	errList := global.Validator.ValidStruct(c, &form)
+	if len(errList) > 0 {
+		response.Fail(c, 101, errList[0])
+		return
+	}
+	before := service.AllService.SubscriptionService.GetProductById(form.Id)
+	if form.Id == 0 || before.Id == 0 {
+		response.Fail(c, 101, response.TranslateMsg(c, "ProductNotFound"))
+		return
+	}
+	if ex := service.AllService.SubscriptionService.GetProductByCode(form.Code); ex.Id != 0 && ex.Id != form.Id {
+		response.Fail(c, 101, response.TranslateMsg(c, "ProductCodeExists"))
+		return
+	}
+	pr := form.ToProduct()
+	pr.CreatedAt = before.CreatedAt
+	if err := service.AllService.SubscriptionService.UpdateProduct(pr); err != nil {
+		response.Fail(c, 101, err.Error())
+		return
+	}
+	audit(c, model.AdminAuditProduct, pr.Id, before, pr)
 	response.Success(c, nil)
 }
 
-// OrderClose 关闭订单
+// ProductDelete 禁用商品
 // @Tags Admin-Payment
-// @Summary 关闭订单
-// @Description 关闭待支付订单
+// @Summary 删除(禁用)一次性商品
+// @Description 已购买的订单仍可继续履约
 // @Accept  json
 // @Produce  json
-// @Param body body IdForm true "订单ID"
+// @Param body body IdForm true "商品ID"
 // @Success 200 {object} response.Response
-// @Router /api/admin/order/close [post]
-func (p *Payment) OrderClose(c *gin.Context) {
+// @Router /api/admin/product/delete [post]
+func (p *Payment) ProductDelete(c *gin.Context) {
 	var form IdForm
 	if err := c.ShouldBindJSON(&form); err != nil {
 		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
 		return
 	}
-
-	if err := service.AllService.SubscriptionService.CloseOrder(form.Id); err != nil {
-		response.Fail(c, 101, response.TranslateMsg(c, err.Error()))
+	before := service.AllService.SubscriptionService.GetProductById(form.Id)
+	if before.Id == 0 {
+		response.Fail(c, 101, response.TranslateMsg(c, "ProductNotFound"))
 		return
 	}
-
+	if err := service.AllService.SubscriptionService.DeleteProduct(form.Id); err != nil {
+		response.Fail(c, 101, err.Error())
+		return
+	}
+	audit(c, model.AdminAuditProduct, form.Id, before, service.AllService.SubscriptionService.GetProductById(form.Id))
 	response.Success(c, nil)
 }
 
-// ========== 订阅管理 ==========
+// ========== 订单管理 ==========
 
-// SubscriptionList 订阅列表
+// NotifyLogList 支付回调记录
 // @Tags Admin-Payment
-// @Summary 获取订阅列表
-// @Description 获取所有用户订阅(分页)
+// @Summary 支付回调记录
+// @Description 每次收到的支付回调的原始参数、来源 IP、验签和处理结果, 用于支付争议排查
+// @Accept  json
+// @Produce  json
+// @Param page query int false "页码"
+// @Param page_size query int false "每页数量"
+// @Param out_trade_no query string false "业务订单号"
+// @Param result query string false "处理结果: success/sign_fail/amount_mismatch/invalid/order_not_found/replay/error"
+// @Success 200 {object} response.Response{data=model.PaymentNotifyLogList}
+// @Router /api/admin/payment/notify_logs [get]
+func (p *Payment) NotifyLogList(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 10
+	}
+	outTradeNo := c.Query("out_trade_no")
+	result := c.Query("result")
+	res := service.AllService.SubscriptionService.ListNotifyLogs(uint(page), uint(pageSize), func(tx *gorm.DB) {
+		if outTradeNo != "" {
+			tx.Where("out_trade_no = ?", outTradeNo)
+		}
+		if result != "" {
+			tx.Where("result = ?", result)
+		}
+	})
+	response.Success(c, res)
+}
+
+// OrderList 订单列表
+// @Tags Admin-Payment
+// @Summary 获取订单列表
+// @Description 获取所有订单(分页)
 // @Accept  json
 // @Produce  json
 // @Param page query int false "页码"
 // @Param page_size query int false "每页数量"
 // @Param user_id query int false "用户ID"
 // @Param status query int false "状态"
+// @Param out_trade_no query string false "订单号"
+// @Param pay_method query string false "支付方式: online/manual"
 // @Success 200 {object} response.Response
-// @Router /api/admin/subscription/list [get]
-func (p *Payment) SubscriptionList(c *gin.Context) {
+// @Router /api/admin/order/list [get]
+func (p *Payment) OrderList(c *gin.Context) {
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
 	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
-	userId, _ := strconv.Atoi(c.DefaultQuery("user_id", "0"))
-	status, _ := strconv.Atoi(c.DefaultQuery("status", "0"))
 	if page < 1 {
 		page = 1
 	}
@@ -329,132 +520,1471 @@ func (p *Payment) SubscriptionList(c *gin.Context) {
 		pageSize = 100
 	}
 
-	subs := service.AllService.SubscriptionService.ListSubscriptions(uint(page), uint(pageSize), func(tx *gorm.DB) {
+	orders := service.AllService.SubscriptionService.ListOrders(uint(page), uint(pageSize), orderListFilter(c))
+	locale := response.Locale(c)
+	for _, order := range orders.Orders {
+		order.Localize(locale)
+		if order.Status == model.OrderStatusPending {
+			order.ExpireAt = service.AllService.SubscriptionService.OrderExpireAt(order)
+		}
+	}
+	response.Success(c, orders)
+}
+
+// orderListFilter 订单列表/导出共用的筛选条件
+func orderListFilter(c *gin.Context) func(tx *gorm.DB) {
+	userId, _ := strconv.Atoi(c.DefaultQuery("user_id", "0"))
+	status, _ := strconv.Atoi(c.DefaultQuery("status", "-1"))
+	outTradeNo := c.DefaultQuery("out_trade_no", "")
+	payMethod := c.DefaultQuery("pay_method", "")
+	return func(tx *gorm.DB) {
+		if payMethod != "" {
+			tx.Where("pay_method = ?", payMethod)
+		}
 		if userId > 0 {
 			tx.Where("user_id = ?", userId)
 		}
-		if status > 0 {
+		if status >= 0 {
 			tx.Where("status = ?", status)
 		}
-	})
-	response.Success(c, subs)
+		if outTradeNo != "" {
+			tx.Where("out_trade_no LIKE ?", "%"+outTradeNo+"%")
+		}
+	}
 }
 
-// SubscriptionDetail 订阅详情
+// OrderExport 导出订单
 // @Tags Admin-Payment
-// @Summary 获取订阅详情
-// @Description 根据ID获取订阅详情
-// @Accept  json
-// @Produce  json
-// @Param id path int true "订阅ID"
-// @Success 200 {object} response.Response
-// @Router /api/admin/subscription/detail/{id} [get]
-func (p *Payment) SubscriptionDetail(c *gin.Context) {
-	id, _ := strconv.Atoi(c.Param("id"))
-	if id <= 0 {
+// @Summary 导出订单
+// @Description 按订单列表相同的筛选条件导出 CSV 或 xlsx
+// @Produce  octet-stream
+// @Param format query string false "导出格式: csv(默认)/xlsx"
+// @Param store query int false "1: 保存到文件存储并返回临时下载地址, 不直接下载"
+// @Param user_id query int false "用户ID"
+// @Param status query int false "订单状态"
+// @Param out_trade_no query string false "业务订单号"
+// @Success 200 {file} file
+// @Router /api/admin/order/export [get]
+func (p *Payment) OrderExport(c *gin.Context) {
+	format := c.DefaultQuery("format", "csv")
+	if format != "csv" && format != "xlsx" {
 		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError"))
 		return
 	}
-	sub := service.AllService.SubscriptionService.GetSubscriptionById(uint(id))
-	if sub.Id == 0 {
-		response.Fail(c, 101, response.TranslateMsg(c, "ItemNotFound"))
+	if c.Query("store") == "1" {
+		p.orderExportStore(c, format)
 		return
 	}
-	response.Success(c, sub)
+
+	filename := "orders_" + time.Now().Format("20060102150405") + "." + format
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+	c.Header("Cache-Control", "no-store")
+	c.Header("Content-Type", orderExportTypes[format])
+	if err := writeOrderExport(c.Writer, format, orderListFilter(c)); err != nil {
+		// 响应已开始写出, 只能记录日志
+		global.Logger.Error("Export orders failed: ", err)
+	}
 }
 
-// SubscriptionGrant 赠送订阅
-// @Tags Admin-Payment
-// @Summary 赠送订阅时长
-// @Description 管理员为用户赠送订阅时长
-// @Accept  json
-// @Produce  json
-// @Param body body GrantForm true "赠送信息"
-// @Success 200 {object} response.Response
-// @Router /api/admin/subscription/grant [post]
-func (p *Payment) SubscriptionGrant(c *gin.Context) {
-	var form GrantForm
-	if err := c.ShouldBindJSON(&form); err != nil {
-		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+// orderExportStore 导出到临时文件后保存到文件存储, 返回临时下载地址, 适合数据量较大或需要分享的场景
+func (p *Payment) orderExportStore(c *gin.Context, format string) {
+	tmp, err := os.CreateTemp("", "orders-export-*")
+	if err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "OperationFailed")+err.Error())
 		return
 	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
 
-	if err := service.AllService.SubscriptionService.GrantSubscription(form.UserId, form.PlanId, form.Days); err != nil {
-		response.Fail(c, 101, response.TranslateMsg(c, err.Error()))
+	if err = writeOrderExport(tmp, format, orderListFilter(c)); err == nil {
+		_, err = tmp.Seek(0, io.SeekStart)
+	}
+	var fi os.FileInfo
+	if err == nil {
+		fi, err = tmp.Stat()
+	}
+	key := service.AllService.StorageService.NewKey("exports", "orders", "."+format)
+	if err == nil {
+		err = service.AllService.StorageService.Put(c.Request.Context(), key, tmp, fi.Size(), orderExportTypes[format])
+	}
+	if err != nil {
+		global.Logger.Error("Export orders failed: ", err)
+		response.Fail(c, 101, response.TranslateMsg(c, "OperationFailed")+err.Error())
+		return
+	}
+	u, expiresAt, err := service.AllService.StorageService.SignedURL(key)
+	if err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "OperationFailed")+err.Error())
 		return
 	}
+	response.Success(c, gin.H{"key": key, "url": u, "expires_at": expiresAt})
+}
 
-	response.Success(c, nil)
+var orderExportTypes = map[string]string{
+	"csv":  "text/csv; charset=utf-8",
+	"xlsx": "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
 }
 
-// SubscriptionCancel 取消订阅
-// @Tags Admin-Payment
-// @Summary 取消用户订阅
-// @Description 管理员取消用户订阅
-// @Accept  json
-// @Produce  json
-// @Param body body UserIdForm true "用户ID"
-// @Success 200 {object} response.Response
-// @Router /api/admin/subscription/cancel [post]
-func (p *Payment) SubscriptionCancel(c *gin.Context) {
-	var form UserIdForm
-	if err := c.ShouldBindJSON(&form); err != nil {
-		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
-		return
+// newExportWriter 按格式创建逐行写出的导出器, 写完后必须调用 flush
+func newExportWriter(w io.Writer, format string) (writeRow func([]string) error, flush func() error, err error) {
+	if format == "xlsx" {
+		xw, err := utils.NewXlsxWriter(w)
+		if err != nil {
+			return nil, nil, err
+		}
+		return xw.WriteRow, xw.Close, nil
 	}
-
-	if err := service.AllService.SubscriptionService.CancelSubscription(form.UserId); err != nil {
-		response.Fail(c, 101, err.Error())
-		return
+	// UTF-8 BOM, 便于 Excel 直接打开
+	if _, err := io.WriteString(w, "\xEF\xBB\xBF"); err != nil {
+		return nil, nil, err
 	}
+	cw := csv.NewWriter(w)
+	return cw.Write, func() error {
+		cw.Flush()
+		return cw.Error()
+	}, nil
+}
 
-	response.Success(c, nil)
+// writeOrderExport 按筛选条件分批写出订单
+func writeOrderExport(w io.Writer, format string, where func(tx *gorm.DB)) error {
+	writeRow, flush, err := newExportWriter(w, format)
+	if err != nil {
+		return err
+	}
+	if err := writeRow(orderExportHeader); err != nil {
+		return err
+	}
+	err = service.AllService.SubscriptionService.ExportOrders(where, func(orders []*model.Order) error {
+		for _, o := range orders {
+			if err := writeRow(orderExportRow(o)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return flush()
 }
 
-// ========== 表单结构体 ==========
+var orderExportHeader = []string{"ID", "业务订单号", "平台订单号", "用户ID", "用户名", "套餐", "币种", "金额(元)", "税额(元)", "税率(%)", "计税地区", "已退款(元)", "状态", "创建时间", "支付时间", "退款时间"}
 
-type PlanForm struct {
-	Id          uint   `json:"id"`
-	Code        string `json:"code" validate:"required"`
-	Name        string `json:"name" validate:"required"`
-	Description string `json:"description"`
-	Price       int64  `json:"price" validate:"gte=0"`
-	PeriodUnit  string `json:"period_unit" validate:"required,oneof=day month year"`
-	PeriodCount int    `json:"period_count" validate:"gt=0"`
-	Status      int    `json:"status" validate:"oneof=1 2"`
-	SortOrder   int    `json:"sort_order"`
+var orderStatusNames = map[int]string{
+	model.OrderStatusPending:  "待支付",
+	model.OrderStatusPaid:     "已支付",
+	model.OrderStatusRefunded: "已退款",
+	model.OrderStatusClosed:   "已关闭",
 }
 
-type IdForm struct {
+func orderExportRow(o *model.Order) []string {
+	username, planName := "", ""
+	if o.User != nil {
+		username = o.User.Username
+	}
+	if o.Plan != nil {
+		planName = o.Plan.Name
+	}
+	return []string{
+		strconv.FormatUint(uint64(o.Id), 10),
+		o.OutTradeNo,
+		o.TradeNo,
+		strconv.FormatUint(uint64(o.UserId), 10),
+		username,
+		planName,
+		o.Currency,
+		o.AmountYuan,
+		o.Tax().String(),
+		strconv.FormatFloat(o.TaxRate, 'f', -1, 64),
+		o.TaxCountry,
+		o.Refunded().String(),
+		orderStatusNames[o.Status],
+		time.Time(o.CreatedAt).Format(time.DateTime),
+		formatUnix(o.PaidAt),
+		formatUnix(o.RefundedAt),
+	}
+}
+
+func formatUnix(ts int64) string {
+	if ts <= 0 {
+		return ""
+	}
+	return time.Unix(ts, 0).Format(time.DateTime)
+}
+
+// OrderDetail 订单详情
+// @Tags Admin-Payment
+// @Summary 获取订单详情
+// @Description 根据ID获取订单详情
+// @Accept  json
+// @Produce  json
+// @Param id path int true "订单ID"
+// @Success 200 {object} response.Response
+// @Router /api/admin/order/detail/{id} [get]
+func (p *Payment) OrderDetail(c *gin.Context) {
+	order := service.AllService.SubscriptionService.GetOrderById(refId(publicid.KindOrder, c.Param("id")))
+	if order.Id == 0 {
+		response.Fail(c, 101, response.TranslateMsg(c, "OrderNotFound"))
+		return
+	}
+	order.Refunds = service.AllService.SubscriptionService.ListOrderRefunds(order.Id)
+	order.RefundRequests = service.AllService.SubscriptionService.ListOrderRefundRequests(order.Id)
+	order.Proofs = service.AllService.PaymentProofService.ListByOrder(order.Id)
+	order.Notes = service.AllService.SubscriptionService.ListNotes(model.AdminNoteTargetOrder, order.Id)
+	order.Localize(response.Locale(c))
+	if order.Status == model.OrderStatusPending {
+		order.ExpireAt = service.AllService.SubscriptionService.OrderExpireAt(order)
+	}
+	order.Publicize(service.AllService.PublicIds(), false)
+	response.Success(c, order)
+}
+
+// OrderRefund 订单退款或提交退款申请
+// @Tags Admin-Payment
+// @Summary 订单退款或提交退款申请
+// @Description 超级管理员与之前一样立即退款(同时记录一条已批准的申请), 返回退款记录;
+// @Description 其他角色提交退款申请, 由其他有退款审批权限的管理员批准后才会退款, 返回申请
+// @Accept  json
+// @Produce  json
+// @Param body body RefundForm true "退款信息"
+// @Success 200 {object} response.Response
+// @Router /api/admin/order/refund [post]
+func (p *Payment) OrderRefund(c *gin.Context) {
+	var form RefundForm
+	if err := c.ShouldBindJSON(&form); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+
+	errList := global.Validator.ValidStruct(c, &form)
+	if len(errList) > 0 {
+		response.Fail(c, 101, errList[0])
+		return
+	}
+
+	u := service.AllService.UserService.CurUser(c)
+	if model.NormalizeAdminRole(u.AdminRole) == model.AdminRoleSuper {
+		before := service.AllService.SubscriptionService.GetOrderById(form.OrderId)
+		r, refund, err := service.AllService.SubscriptionService.RefundDirect(form.OrderId, form.Amount, form.Reason, u.Id)
+		if err != nil {
+			response.Fail(c, 101, response.TranslateMsg(c, err.Error()))
+			return
+		}
+		audit(c, model.AdminAuditRefund, form.OrderId, before, gin.H{
+			"order":   service.AllService.SubscriptionService.GetOrderById(form.OrderId),
+			"request": r,
+			"refund":  refund,
+		})
+		response.Success(c, refund)
+		return
+	}
+	r, err := service.AllService.SubscriptionService.RequestRefund(form.OrderId, form.Amount, form.Reason, u.Id)
+	if err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, err.Error()))
+		return
+	}
+	audit(c, model.AdminAuditRefundRequest, form.OrderId, nil, r)
+
+	response.Success(c, r)
+}
+
+// RefundRequestList 退款申请列表
+// @Tags Admin-Payment
+// @Summary 退款申请列表
+// @Accept  json
+// @Produce  json
+// @Param page query int false "页码"
+// @Param page_size query int false "每页数量"
+// @Param order_id query int false "订单ID"
+// @Param status query int false "状态: 0待审批 1已批准 2已驳回"
+// @Success 200 {object} response.Response{data=model.RefundRequestList}
+// @Router /api/admin/order/refund/requests [get]
+func (p *Payment) RefundRequestList(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+	orderId, _ := strconv.Atoi(c.DefaultQuery("order_id", "0"))
+	status, _ := strconv.Atoi(c.DefaultQuery("status", "-1"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 10
+	}
+	res := service.AllService.SubscriptionService.ListRefundRequests(uint(page), uint(pageSize), func(tx *gorm.DB) {
+		if orderId > 0 {
+			tx.Where("order_id = ?", orderId)
+		}
+		if status >= 0 {
+			tx.Where("status = ?", status)
+		}
+	})
+	response.Success(c, res)
+}
+
+// RefundApprove 批准退款申请
+// @Tags Admin-Payment
+// @Summary 批准退款申请
+// @Description 批准后调用支付网关退款并按退款比例扣减订阅时长; 不能审批自己提交的申请, 网关失败时申请保持待审批
+// @Accept  json
+// @Produce  json
+// @Param body body RefundReviewForm true "审批信息"
+// @Success 200 {object} response.Response
+// @Router /api/admin/order/refund/approve [post]
+func (p *Payment) RefundApprove(c *gin.Context) {
+	var form RefundReviewForm
+	if err := c.ShouldBindJSON(&form); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	errList := global.Validator.ValidStruct(c, &form)
+	if len(errList) > 0 {
+		response.Fail(c, 101, errList[0])
+		return
+	}
+
+	u := service.AllService.UserService.CurUser(c)
+	req := service.AllService.SubscriptionService.GetRefundRequestById(form.Id)
+	before := service.AllService.SubscriptionService.GetOrderById(req.OrderId)
+	r, refund, err := service.AllService.SubscriptionService.ApproveRefund(form.Id, u.Id, form.Note)
+	if err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, err.Error()))
+		return
+	}
+	audit(c, model.AdminAuditRefund, r.OrderId, before, gin.H{
+		"order":   service.AllService.SubscriptionService.GetOrderById(r.OrderId),
+		"request": r,
+		"refund":  refund,
+	})
+
+	response.Success(c, gin.H{"request": r, "refund": refund})
+}
+
+// RefundReject 驳回退款申请
+// @Tags Admin-Payment
+// @Summary 驳回退款申请
+// @Accept  json
+// @Produce  json
+// @Param body body RefundReviewForm true "审批信息"
+// @Success 200 {object} response.Response{data=model.RefundRequest}
+// @Router /api/admin/order/refund/reject [post]
+func (p *Payment) RefundReject(c *gin.Context) {
+	var form RefundReviewForm
+	if err := c.ShouldBindJSON(&form); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	errList := global.Validator.ValidStruct(c, &form)
+	if len(errList) > 0 {
+		response.Fail(c, 101, errList[0])
+		return
+	}
+
+	u := service.AllService.UserService.CurUser(c)
+	r, err := service.AllService.SubscriptionService.RejectRefund(form.Id, u.Id, form.Note)
+	if err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, err.Error()))
+		return
+	}
+	audit(c, model.AdminAuditRefundReject, r.OrderId, nil, r)
+
+	response.Success(c, r)
+}
+
+// OrderFulfillment 更新商品订单履约状态
+// @Tags Admin-Payment
+// @Summary 更新商品订单履约状态
+// @Description 已支付的商品订单: 1待履约 2处理中 3已履约 4已取消, 变为已履约时发送 product.fulfilled webhook
+// @Accept  json
+// @Produce  json
+// @Param body body FulfillmentForm true "履约信息"
+// @Success 200 {object} response.Response{data=model.Order}
+// @Router /api/admin/order/fulfillment [post]
+func (p *Payment) OrderFulfillment(c *gin.Context) {
+	var form FulfillmentForm
+	if err := c.ShouldBindJSON(&form); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	errList := global.Validator.ValidStruct(c, &form)
+	if len(errList) > 0 {
+		response.Fail(c, 101, errList[0])
+		return
+	}
+	before := service.AllService.SubscriptionService.GetOrderById(form.OrderId)
+	order, err := service.AllService.SubscriptionService.UpdateFulfillment(form.OrderId, form.Status, form.Note)
+	if err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, err.Error()))
+		return
+	}
+	audit(c, model.AdminAuditFulfillment, order.Id, before, order)
+	response.Success(c, order)
+}
+
+// OrderClose 关闭订单
+// @Tags Admin-Payment
+// @Summary 关闭订单
+// @Description 关闭待支付订单
+// @Accept  json
+// @Produce  json
+// @Param body body IdForm true "订单ID"
+// @Success 200 {object} response.Response
+// @Router /api/admin/order/close [post]
+func (p *Payment) OrderClose(c *gin.Context) {
+	var form IdForm
+	if err := c.ShouldBindJSON(&form); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+
+	if err := service.AllService.SubscriptionService.CloseOrder(form.Id); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, err.Error()))
+		return
+	}
+
+	response.Success(c, nil)
+}
+
+// OrderMarkPaid 手动标记订单已支付
+// @Tags Admin-Payment
+// @Summary 手动标记订单已支付
+// @Description 线下收款(银行转账、现金等)后将待支付订单标记为已支付并激活订阅
+// @Accept  json
+// @Produce  json
+// @Param body body MarkPaidForm true "订单信息"
+// @Success 200 {object} response.Response
+// @Router /api/admin/order/mark_paid [post]
+func (p *Payment) OrderMarkPaid(c *gin.Context) {
+	var form MarkPaidForm
+	if err := c.ShouldBindJSON(&form); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+
+	errList := global.Validator.ValidStruct(c, &form)
+	if len(errList) > 0 {
+		response.Fail(c, 101, errList[0])
+		return
+	}
+
+	u := service.AllService.UserService.CurUser(c)
+	if err := service.AllService.SubscriptionService.MarkOrderPaid(form.OrderId, strings.TrimSpace(form.TradeNo), u.Id, form.Remark); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, err.Error()))
+		return
+	}
+
+	response.Success(c, nil)
+}
+
+// OrderCreate 代用户下单
+// @Tags Admin-Payment
+// @Summary 代用户创建订单
+// @Description 为用户创建线下订单(如对公转账的企业合同), 可覆盖金额; paid 为 true 时立即入账并按正常流程激活/续期订阅
+// @Accept  json
+// @Produce  json
+// @Param body body OrderCreateForm true "订单信息"
+// @Success 200 {object} response.Response{data=model.Order}
+// @Router /api/admin/order/create [post]
+func (p *Payment) OrderCreate(c *gin.Context) {
+	var form OrderCreateForm
+	if err := c.ShouldBindJSON(&form); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	errList := global.Validator.ValidStruct(c, &form)
+	if len(errList) > 0 {
+		response.Fail(c, 101, errList[0])
+		return
+	}
+	u := service.AllService.UserService.CurUser(c)
+	order, err := service.AllService.SubscriptionService.CreateAdminOrder(&service.AdminOrderParams{
+		UserId:   form.UserId,
+		PlanId:   form.PlanId,
+		Currency: form.Currency,
+		Amount:   form.Amount,
+		Subject:  form.Subject,
+		Paid:     form.Paid,
+		TradeNo:  form.TradeNo,
+		Remark:   form.Remark,
+	}, u.Id)
+	if err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, err.Error()))
+		return
+	}
+	audit(c, model.AdminAuditOrderCreate, order.Id, nil, gin.H{"form": form, "order": order})
+	response.Success(c, order)
+}
+
+// OrderSimulatePaid 测试模式下模拟支付成功
+// @Tags Admin-Payment
+// @Summary 模拟支付成功(测试模式)
+// @Description 支付配置开启测试模式时, 以签名后的网关回调走完整入账和订阅激活流程, 用于上线前验证套餐、周期和权益
+// @Accept  json
+// @Produce  json
+// @Param body body SimulatePaidForm true "订单信息"
+// @Success 200 {object} response.Response
+// @Router /api/admin/order/simulate_paid [post]
+func (p *Payment) OrderSimulatePaid(c *gin.Context) {
+	var form SimulatePaidForm
+	if err := c.ShouldBindJSON(&form); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+
+	errList := global.Validator.ValidStruct(c, &form)
+	if len(errList) > 0 {
+		response.Fail(c, 101, errList[0])
+		return
+	}
+
+	u := service.AllService.UserService.CurUser(c)
+	if err := service.AllService.SubscriptionService.SimulatePaid(c.Request.Context(), form.OrderId, u.Id); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, err.Error()))
+		return
+	}
+
+	response.Success(c, service.AllService.SubscriptionService.GetOrderById(form.OrderId))
+}
+
+// OrderExtendHold 延长待支付订单保留时间
+// @Tags Admin-Payment
+// @Summary 延长订单保留时间
+// @Description 到账较慢的订单(如银行转账)延长保留时间, 期间不会被自动关闭
+// @Accept  json
+// @Produce  json
+// @Param body body ExtendHoldForm true "订单信息"
+// @Success 200 {object} response.Response
+// @Router /api/admin/order/extend_hold [post]
+func (p *Payment) OrderExtendHold(c *gin.Context) {
+	var form ExtendHoldForm
+	if err := c.ShouldBindJSON(&form); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	errList := global.Validator.ValidStruct(c, &form)
+	if len(errList) > 0 {
+		response.Fail(c, 101, errList[0])
+		return
+	}
+
+	u := service.AllService.UserService.CurUser(c)
+	holdUntil, err := service.AllService.SubscriptionService.ExtendOrderHold(form.OrderId, time.Duration(form.Hours)*time.Hour, u.Id)
+	if err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, err.Error()))
+		return
+	}
+
+	response.Success(c, gin.H{"hold_until": holdUntil})
+}
+
+// ========== 线下转账凭证审核 ==========
+
+// ProofList 付款凭证审核队列
+// @Tags Admin-Payment
+// @Summary 付款凭证列表
+// @Description 线下转账付款凭证, 默认按待审核筛选
+// @Accept  json
+// @Produce  json
+// @Param page query int false "页码"
+// @Param page_size query int false "每页数量"
+// @Param status query int false "状态: 0待审核(默认) 1已通过 2已驳回 -1全部"
+// @Param user_id query int false "用户ID"
+// @Param order_id query int false "订单ID"
+// @Success 200 {object} response.Response{data=model.PaymentProofList}
+// @Router /api/admin/payment/proof/list [get]
+func (p *Payment) ProofList(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 {
+		pageSize = 10
+	}
+	if pageSize > 100 {
+		pageSize = 100
+	}
+	status, _ := strconv.Atoi(c.DefaultQuery("status", "0"))
+	userId, _ := strconv.Atoi(c.DefaultQuery("user_id", "0"))
+	orderId, _ := strconv.Atoi(c.DefaultQuery("order_id", "0"))
+
+	res := service.AllService.PaymentProofService.List(uint(page), uint(pageSize), func(tx *gorm.DB) {
+		if status >= 0 {
+			tx.Where("status = ?", status)
+		}
+		if userId > 0 {
+			tx.Where("user_id = ?", userId)
+		}
+		if orderId > 0 {
+			tx.Where("order_id = ?", orderId)
+		}
+		// 待审核按提交先后处理, 其余按最新优先
+		if status == model.PaymentProofPending {
+			tx.Order("id ASC")
+		} else {
+			tx.Order("id DESC")
+		}
+	})
+	locale := response.Locale(c)
+	for _, proof := range res.PaymentProofs {
+		if proof.Order != nil {
+			proof.Order.Localize(locale)
+		}
+	}
+	response.Success(c, res)
+}
+
+// ProofFile 查看付款凭证文件
+// @Tags Admin-Payment
+// @Summary 查看付款凭证文件
+// @Description 凭证文件不公开存储, 仅管理员通过该接口查看
+// @Produce  octet-stream
+// @Param id path int true "凭证ID"
+// @Success 200 {file} file
+// @Router /api/admin/payment/proof/file/{id} [get]
+func (p *Payment) ProofFile(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	proof := service.AllService.PaymentProofService.InfoById(uint(id))
+	if proof.Id == 0 {
+		response.Fail(c, 101, response.TranslateMsg(c, "PaymentProofNotFound"))
+		return
+	}
+	f, err := service.AllService.PaymentProofService.OpenFile(c.Request.Context(), proof)
+	if err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "OperationFailed")+err.Error())
+		return
+	}
+	defer f.Close()
+	c.Header("Content-Disposition", `inline; filename="`+url.PathEscape(proof.FileName)+`"`)
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.DataFromReader(http.StatusOK, proof.FileSize, proof.ContentType, f, nil)
+}
+
+// ProofFileURL 付款凭证临时下载地址
+// @Tags Admin-Payment
+// @Summary 获取付款凭证临时下载地址
+// @Description 生成带签名的临时下载地址, 过期时间由存储设置决定
+// @Produce  json
+// @Param id path int true "凭证ID"
+// @Success 200 {object} response.Response
+// @Router /api/admin/payment/proof/url/{id} [get]
+func (p *Payment) ProofFileURL(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	proof := service.AllService.PaymentProofService.InfoById(uint(id))
+	if proof.Id == 0 {
+		response.Fail(c, 101, response.TranslateMsg(c, "PaymentProofNotFound"))
+		return
+	}
+	u, expiresAt, err := service.AllService.PaymentProofService.FileURL(proof)
+	if err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "OperationFailed")+err.Error())
+		return
+	}
+	response.Success(c, gin.H{"url": u, "expires_at": expiresAt})
+}
+
+// ProofApprove 审核通过付款凭证
+// @Tags Admin-Payment
+// @Summary 审核通过付款凭证
+// @Description 通过后订单入账并激活/续期订阅
+// @Accept  json
+// @Produce  json
+// @Param body body ProofReviewForm true "审核信息"
+// @Success 200 {object} response.Response
+// @Router /api/admin/payment/proof/approve [post]
+func (p *Payment) ProofApprove(c *gin.Context) {
+	p.proofReview(c, true)
+}
+
+// ProofReject 驳回付款凭证
+// @Tags Admin-Payment
+// @Summary 驳回付款凭证
+// @Description 驳回后订单保持待支付, 用户可重新上传凭证
+// @Accept  json
+// @Produce  json
+// @Param body body ProofReviewForm true "审核信息"
+// @Success 200 {object} response.Response
+// @Router /api/admin/payment/proof/reject [post]
+func (p *Payment) ProofReject(c *gin.Context) {
+	p.proofReview(c, false)
+}
+
+func (p *Payment) proofReview(c *gin.Context, approve bool) {
+	var form ProofReviewForm
+	if err := c.ShouldBindJSON(&form); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+
+	errList := global.Validator.ValidStruct(c, &form)
+	if len(errList) > 0 {
+		response.Fail(c, 101, errList[0])
+		return
+	}
+
+	u := service.AllService.UserService.CurUser(c)
+	var err error
+	if approve {
+		err = service.AllService.PaymentProofService.Approve(form.Id, u.Id, form.Remark)
+	} else {
+		err = service.AllService.PaymentProofService.Reject(form.Id, u.Id, form.Remark)
+	}
+	if err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, err.Error()))
+		return
+	}
+	response.Success(c, nil)
+}
+
+// ========== 订阅管理 ==========
+
+// SubscriptionList 订阅列表
+// @Tags Admin-Payment
+// @Summary 获取订阅列表
+// @Description 获取所有用户订阅(分页)
+// @Accept  json
+// @Produce  json
+// @Param page query int false "页码"
+// @Param page_size query int false "每页数量"
+// @Param user_id query int false "用户ID"
+// @Param status query int false "状态"
+// @Success 200 {object} response.Response
+// @Router /api/admin/subscription/list [get]
+func (p *Payment) SubscriptionList(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+	userId, _ := strconv.Atoi(c.DefaultQuery("user_id", "0"))
+	status, _ := strconv.Atoi(c.DefaultQuery("status", "0"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 {
+		pageSize = 10
+	}
+	if pageSize > 100 {
+		pageSize = 100
+	}
+
+	subs := service.AllService.SubscriptionService.ListSubscriptions(uint(page), uint(pageSize), func(tx *gorm.DB) {
+		if userId > 0 {
+			tx.Where("user_id = ?", userId)
+		}
+		if status > 0 {
+			tx.Where("status = ?", status)
+		}
+	})
+	locale := response.Locale(c)
+	for _, sub := range subs.Subscriptions {
+		sub.Localize(locale)
+	}
+	response.Success(c, subs)
+}
+
+// SubscriptionEvents 订阅变更记录
+// @Tags Admin-Payment
+// @Summary 订阅变更记录
+// @Description 订阅的开通、续期、赠送、取消、退款、到期记录, 按时间倒序
+// @Accept  json
+// @Produce  json
+// @Param page query int false "页码"
+// @Param page_size query int false "每页数量"
+// @Param user_id query int false "用户ID"
+// @Param subscription_id query int false "订阅ID"
+// @Param type query string false "类型"
+// @Param source query string false "来源: notify/admin/job/user"
+// @Success 200 {object} response.Response{data=model.SubscriptionEventList}
+// @Router /api/admin/subscription/events [get]
+func (p *Payment) SubscriptionEvents(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+	userId, _ := strconv.Atoi(c.DefaultQuery("user_id", "0"))
+	subId, _ := strconv.Atoi(c.DefaultQuery("subscription_id", "0"))
+	typ := c.Query("type")
+	source := c.Query("source")
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 {
+		pageSize = 10
+	}
+	if pageSize > 100 {
+		pageSize = 100
+	}
+
+	res := service.AllService.SubscriptionService.ListSubscriptionEvents(uint(page), uint(pageSize), func(tx *gorm.DB) {
+		if userId > 0 {
+			tx.Where("user_id = ?", userId)
+		}
+		if subId > 0 {
+			tx.Where("subscription_id = ?", subId)
+		}
+		if typ != "" {
+			tx.Where("type = ?", typ)
+		}
+		if source != "" {
+			tx.Where("source = ?", source)
+		}
+	})
+	response.Success(c, res)
+}
+
+// SubscriptionDetail 订阅详情
+// @Tags Admin-Payment
+// @Summary 获取订阅详情
+// @Description 根据ID获取订阅详情
+// @Accept  json
+// @Produce  json
+// @Param id path int true "订阅ID"
+// @Success 200 {object} response.Response
+// @Router /api/admin/subscription/detail/{id} [get]
+func (p *Payment) SubscriptionDetail(c *gin.Context) {
+	id := refId(publicid.KindSubscription, c.Param("id"))
+	if id == 0 {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError"))
+		return
+	}
+	sub := service.AllService.SubscriptionService.GetSubscriptionById(id)
+	if sub.Id == 0 {
+		response.Fail(c, 101, response.TranslateMsg(c, "ItemNotFound"))
+		return
+	}
+	sub.Notes = service.AllService.SubscriptionService.ListNotes(model.AdminNoteTargetSubscription, sub.Id)
+	sub.Localize(response.Locale(c))
+	sub.Publicize(service.AllService.PublicIds(), false)
+	response.Success(c, sub)
+}
+
+// UserBilling 用户计费概览
+// @Tags Admin-Payment
+// @Summary 用户计费概览
+// @Description 一次返回用户的订阅、权益、最近订单、退款及退款申请、累计金额和风险标记(禁用、relay 异常、续期异常)
+// @Accept  json
+// @Produce  json
+// @Param id path int true "用户ID"
+// @Success 200 {object} response.Response{data=model.UserBilling}
+// @Router /api/admin/user/{id}/billing [get]
+func (p *Payment) UserBilling(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	if id <= 0 {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError"))
+		return
+	}
+	res := service.AllService.SubscriptionService.UserBilling(uint(id))
+	if res == nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ItemNotFound"))
+		return
+	}
+	locale := response.Locale(c)
+	ids := service.AllService.PublicIds()
+	if res.Subscription != nil {
+		res.Subscription.Localize(locale)
+		res.Subscription.Publicize(ids, false)
+	}
+	for _, o := range res.RecentOrders {
+		o.Localize(locale)
+		o.Publicize(ids, false)
+	}
+	response.Success(c, res)
+}
+
+// SubscriptionSimulate 模拟订阅有效性
+// @Tags Admin-Payment
+// @Summary 模拟订阅有效性
+// @Description 只读评估用户在任意时刻的订阅有效性, 并按订单/退款/兑换记录重放, 用于排查订阅争议
+// @Accept  json
+// @Produce  json
+// @Param user_id query int true "用户ID"
+// @Param at query string false "时刻: unix秒、RFC3339 或 2006-01-02 15:04:05, 默认当前时间"
+// @Success 200 {object} response.Response{data=model.SubscriptionSimulation}
+// @Router /api/admin/subscription/simulate [get]
+func (p *Payment) SubscriptionSimulate(c *gin.Context) {
+	var query SimulateQuery
+	if err := c.ShouldBindQuery(&query); err != nil || query.UserId == 0 {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError"))
+		return
+	}
+	at, err := query.AtUnix()
+	if err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	response.Success(c, service.AllService.SubscriptionService.SimulateSubscription(query.UserId, at))
+}
+
+// SubscriptionGrant 赠送订阅
+// @Tags Admin-Payment
+// @Summary 赠送订阅时长
+// @Description 管理员为用户赠送订阅时长
+// @Accept  json
+// @Produce  json
+// @Param body body GrantForm true "赠送信息"
+// @Success 200 {object} response.Response
+// @Router /api/admin/subscription/grant [post]
+func (p *Payment) SubscriptionGrant(c *gin.Context) {
+	var form GrantForm
+	if err := c.ShouldBindJSON(&form); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+
+	period, err := form.GrantPeriod()
+	if err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "PeriodInvalid"))
+		return
+	}
+
+	u := service.AllService.UserService.CurUser(c)
+	before := service.AllService.SubscriptionService.GetUserSubscription(form.UserId)
+	if err := service.AllService.SubscriptionService.GrantSubscription(form.UserId, form.PlanId, period, u.Id); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, err.Error()))
+		return
+	}
+	audit(c, model.AdminAuditGrant, form.UserId, before, gin.H{
+		"grant":        form,
+		"subscription": service.AllService.SubscriptionService.GetUserSubscription(form.UserId),
+	})
+
+	response.Success(c, nil)
+}
+
+// SubscriptionGrantBatch 批量赠送订阅
+// @Tags Admin-Payment
+// @Summary 批量赠送订阅时长
+// @Description 为多个用户(user_ids 和/或用户组)赠送订阅, 在同一事务内执行并返回每个用户的结果, 单个用户失败不影响其他用户; 记录一条审计
+// @Accept  json
+// @Produce  json
+// @Param body body GrantBatchForm true "赠送信息"
+// @Success 200 {object} response.Response{data=[]model.GrantBatchResult}
+// @Router /api/admin/subscription/grant_batch [post]
+func (p *Payment) SubscriptionGrantBatch(c *gin.Context) {
+	var form GrantBatchForm
+	if err := c.ShouldBindJSON(&form); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	errList := global.Validator.ValidStruct(c, &form)
+	if len(errList) > 0 {
+		response.Fail(c, 101, errList[0])
+		return
+	}
+
+	period, err := (&GrantForm{Days: form.Days, Period: form.Period}).GrantPeriod()
+	if err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "PeriodInvalid"))
+		return
+	}
+	userIds := form.UserIds
+	if form.GroupId > 0 {
+		userIds = append(userIds, service.AllService.UserService.ListIdsByGroupId(form.GroupId)...)
+	}
+
+	u := service.AllService.UserService.CurUser(c)
+	results, err := service.AllService.SubscriptionService.GrantSubscriptionBatch(userIds, form.PlanId, period, u.Id)
+	if err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, err.Error()))
+		return
+	}
+	for _, r := range results {
+		if r.Error != "" {
+			r.Error = response.TranslateMsg(c, r.Error)
+		}
+	}
+	audit(c, model.AdminAuditGrantBatch, 0, nil, gin.H{
+		"grant":   form,
+		"results": results,
+	})
+
+	response.Success(c, results)
+}
+
+// SubscriptionCancel 取消订阅
+// @Tags Admin-Payment
+// @Summary 取消用户订阅
+// @Description 管理员取消用户订阅
+// @Accept  json
+// @Produce  json
+// @Param body body UserIdForm true "用户ID"
+// @Success 200 {object} response.Response
+// @Router /api/admin/subscription/cancel [post]
+func (p *Payment) SubscriptionCancel(c *gin.Context) {
+	var form UserIdForm
+	if err := c.ShouldBindJSON(&form); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+
+	u := service.AllService.UserService.CurUser(c)
+	if err := service.AllService.SubscriptionService.CancelSubscription(form.UserId, u.Id); err != nil {
+		response.Fail(c, 101, err.Error())
+		return
+	}
+
+	response.Success(c, nil)
+}
+
+// SubscriptionPause 暂停订阅
+// @Tags Admin-Payment
+// @Summary 暂停用户订阅
+// @Description 冻结订阅的剩余时长(如长期休假、滥用调查), 暂停期间订阅无效; days 为 0 时需手动恢复
+// @Accept  json
+// @Produce  json
+// @Param body body PauseForm true "暂停信息"
+// @Success 200 {object} response.Response
+// @Router /api/admin/subscription/pause [post]
+func (p *Payment) SubscriptionPause(c *gin.Context) {
+	var form PauseForm
+	if err := c.ShouldBindJSON(&form); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	errList := global.Validator.ValidStruct(c, &form)
+	if len(errList) > 0 {
+		response.Fail(c, 101, errList[0])
+		return
+	}
+	u := service.AllService.UserService.CurUser(c)
+	before := service.AllService.SubscriptionService.GetUserSubscription(form.UserId)
+	if err := service.AllService.SubscriptionService.PauseSubscription(form.UserId, form.Days, form.Reason, u.Id); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, err.Error()))
+		return
+	}
+	after := service.AllService.SubscriptionService.GetUserSubscription(form.UserId)
+	audit(c, model.AdminAuditPause, form.UserId, before, after)
+	response.Success(c, after)
+}
+
+// SubscriptionResume 恢复订阅
+// @Tags Admin-Payment
+// @Summary 恢复暂停的订阅
+// @Description 到期时间为恢复时间加暂停时冻结的剩余时长
+// @Accept  json
+// @Produce  json
+// @Param body body UserIdForm true "用户ID"
+// @Success 200 {object} response.Response
+// @Router /api/admin/subscription/resume [post]
+func (p *Payment) SubscriptionResume(c *gin.Context) {
+	var form UserIdForm
+	if err := c.ShouldBindJSON(&form); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	u := service.AllService.UserService.CurUser(c)
+	before := service.AllService.SubscriptionService.GetUserSubscription(form.UserId)
+	if err := service.AllService.SubscriptionService.ResumeSubscription(form.UserId, u.Id); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, err.Error()))
+		return
+	}
+	after := service.AllService.SubscriptionService.GetUserSubscription(form.UserId)
+	audit(c, model.AdminAuditPause, form.UserId, before, after)
+	response.Success(c, after)
+}
+
+// ========== 兑换码管理 ==========
+
+// RedeemCodeList 兑换码列表
+// @Tags Admin-Payment
+// @Summary 获取兑换码列表
+// @Description 获取已生成/已使用的兑换码(分页)
+// @Accept  json
+// @Produce  json
+// @Param page query int false "页码"
+// @Param page_size query int false "每页数量"
+// @Param batch_no query string false "批次号"
+// @Param status query int false "状态"
+// @Param code query string false "兑换码"
+// @Success 200 {object} response.Response
+// @Router /api/admin/redeem_code/list [get]
+func (p *Payment) RedeemCodeList(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+	status, _ := strconv.Atoi(c.DefaultQuery("status", "-1"))
+	batchNo := c.DefaultQuery("batch_no", "")
+	code := service.AllService.RedeemCodeService.NormalizeCode(c.DefaultQuery("code", ""))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 {
+		pageSize = 10
+	}
+	if pageSize > 100 {
+		pageSize = 100
+	}
+
+	codes := service.AllService.RedeemCodeService.List(uint(page), uint(pageSize), func(tx *gorm.DB) {
+		if status >= 0 {
+			tx.Where("status = ?", status)
+		}
+		if batchNo != "" {
+			tx.Where("batch_no = ?", batchNo)
+		}
+		if code != "" {
+			tx.Where("code LIKE ?", "%"+code+"%")
+		}
+	})
+	response.Success(c, codes)
+}
+
+// RedeemCodeGenerate 批量生成兑换码
+// @Tags Admin-Payment
+// @Summary 批量生成兑换码
+// @Description 为指定套餐批量生成兑换码
+// @Accept  json
+// @Produce  json
+// @Param body body RedeemCodeGenerateForm true "生成信息"
+// @Success 200 {object} response.Response
+// @Router /api/admin/redeem_code/generate [post]
+func (p *Payment) RedeemCodeGenerate(c *gin.Context) {
+	var form RedeemCodeGenerateForm
+	if err := c.ShouldBindJSON(&form); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+
+	errList := global.Validator.ValidStruct(c, &form)
+	if len(errList) > 0 {
+		response.Fail(c, 101, errList[0])
+		return
+	}
+
+	u := service.AllService.UserService.CurUser(c)
+	codes, err := service.AllService.RedeemCodeService.Generate(form.PlanId, form.Days, form.Count, form.ExpireAt, u.Id)
+	if err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, err.Error()))
+		return
+	}
+
+	response.Success(c, codes)
+}
+
+// RedeemCodeDisable 作废兑换码
+// @Tags Admin-Payment
+// @Summary 作废兑换码
+// @Description 作废未使用的兑换码
+// @Accept  json
+// @Produce  json
+// @Param body body IdsForm true "兑换码ID"
+// @Success 200 {object} response.Response
+// @Router /api/admin/redeem_code/disable [post]
+func (p *Payment) RedeemCodeDisable(c *gin.Context) {
+	var form IdsForm
+	if err := c.ShouldBindJSON(&form); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	if len(form.Ids) == 0 {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError"))
+		return
+	}
+
+	if err := service.AllService.RedeemCodeService.Disable(form.Ids); err != nil {
+		response.Fail(c, 101, err.Error())
+		return
+	}
+
+	response.Success(c, nil)
+}
+
+// Funnel 下单转化漏斗
+// @Tags Admin-Payment
+// @Summary 下单转化漏斗
+// @Description 按套餐和支付方式统计套餐展示、下单预览、创建订单、提交支付、收到回调、入账的次数(本实例启动以来)
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} response.Response{data=[]model.FunnelStat}
+// @Router /api/admin/payment/funnel [get]
+func (p *Payment) Funnel(c *gin.Context) {
+	response.Success(c, service.AllService.SubscriptionService.FunnelStats())
+}
+
+// Dashboard 支付统计
+// @Tags Admin-Payment
+// @Summary 支付与订阅统计
+// @Description 收入(今日/7天/30天, 按币种)、订单状态分布、订阅数量、新增订阅与流失
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} response.Response{data=model.PaymentDashboard}
+// @Router /api/admin/payment/dashboard [get]
+func (p *Payment) Dashboard(c *gin.Context) {
+	response.Success(c, service.AllService.SubscriptionService.Dashboard())
+}
+
+// ConsistencyAudit 一致性检查
+// @Tags Admin-Payment
+// @Summary 订单/订阅一致性检查
+// @Description 检查已支付未入订阅、订阅指向不存在的订单/套餐、重复待支付订单、金额快照及退款合计不一致, 只输出报告不修改数据
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} response.Response{data=model.ConsistencyReport}
+// @Router /api/admin/payment/consistency [get]
+func (p *Payment) ConsistencyAudit(c *gin.Context) {
+	response.Success(c, service.AllService.SubscriptionService.AuditConsistency(false, nil))
+}
+
+// ConsistencyRepair 一致性修复
+// @Tags Admin-Payment
+// @Summary 订单/订阅一致性修复
+// @Description 重新检查并自动修复可修复的问题, types 为空表示全部可修复类型
+// @Accept  json
+// @Produce  json
+// @Param body body ConsistencyRepairForm true "修复类型"
+// @Success 200 {object} response.Response{data=model.ConsistencyReport}
+// @Router /api/admin/payment/consistency/repair [post]
+func (p *Payment) ConsistencyRepair(c *gin.Context) {
+	var form ConsistencyRepairForm
+	if err := c.ShouldBindJSON(&form); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	errList := global.Validator.ValidStruct(c, &form)
+	if len(errList) > 0 {
+		response.Fail(c, 101, errList[0])
+		return
+	}
+	response.Success(c, service.AllService.SubscriptionService.AuditConsistency(true, form.Types))
+}
+
+// ========== 表单结构体 ==========
+
+type PlanForm struct {
+	Id                     uint   `json:"id"`
+	Code                   string `json:"code" validate:"required"`
+	Name                   string `json:"name" validate:"required"`
+	Description            string `json:"description"`
+	Price                  int64  `json:"price" validate:"gte=0"`
+	PeriodUnit             string `json:"period_unit" validate:"required_without=Period,omitempty,oneof=day month year"`
+	PeriodCount            int    `json:"period_count" validate:"required_without=Period,omitempty,gt=0"`
+	Period                 string `json:"period"`                                     // ISO-8601 周期(如 P90D、P1M7D), 非空时优先
+	BonusDaysFirstPurchase int    `json:"bonus_days_first_purchase" validate:"gte=0"` // 首购赠送天数, 如"买12个月送2个月"填 60
+	MaxDevices             int    `json:"max_devices" validate:"gte=0"`               // 可绑定设备数, 0 不限
+	MaxSessions            int    `json:"max_sessions" validate:"gte=0"`              // 同时进行的连接会话数, 0 不限
+	DeviceSeats            bool   `json:"device_seats"`                               // 按设备席位授权, 席位数为 max_devices
+	RelayQuotaBytes        int64  `json:"relay_quota_bytes" validate:"gte=0"`         // 每月 relay 流量配额(字节), 0 不限
+	RelayQuotaMinutes      int64  `json:"relay_quota_minutes" validate:"gte=0"`       // 每月 relay 时长配额(分钟), 0 不限
+	PremiumRelay           bool   `json:"premium_relay"`                              // 使用高级 relay
+	PremiumRelayTrialDays  int    `json:"premium_relay_trial_days" validate:"gte=0"`  // 免费套餐注册后试用高级 relay 的天数, 0 不试用
+	Seats                  int    `json:"seats" validate:"gte=0"`                     // 组织席位数(含所有者), 0 为个人套餐
+	OncePerUser            bool   `json:"once_per_user"`                              // 每个用户只能购买一次
+	NewUsersOnly           bool   `json:"new_users_only"`                             // 仅限从未订阅过的新用户
+	UpgradeOnly            bool   `json:"upgrade_only"`                               // 需要当前有有效订阅
+	Status                 int    `json:"status" validate:"oneof=1 2"`
+	SortOrder              int    `json:"sort_order"`
+	// Prices 其他币种价格, 更新时为 null 表示不修改
+	Prices []PlanPriceForm `json:"prices" validate:"omitempty,dive"`
+
+	// Visibility 可见范围: public/groups/hidden, 为空时为 public
+	Visibility    string `json:"visibility" validate:"omitempty,oneof=public groups hidden"`
+	VisibleGroups []uint `json:"visible_groups" validate:"required_if=Visibility groups"` // visibility 为 groups 时可见的用户组ID
+	// Features 定价页展示的自定义功能项, 更新时为 null 表示不修改
+	Features []model.PlanFeature `json:"features"`
+}
+
+type PlanPriceForm struct {
+	Currency string `json:"currency" validate:"required,oneof=USD EUR"`
+	Price    int64  `json:"price" validate:"gte=0"`
+}
+
+// NormalizePeriod 校验并规范化 ISO-8601 周期, 单一单位时同步 PeriodUnit/PeriodCount 以兼容旧客户端
+func (f *PlanForm) NormalizePeriod() error {
+	if f.Period == "" {
+		return nil
+	}
+	period, err := model.ParsePeriod(f.Period)
+	if err != nil {
+		return err
+	}
+	f.Period = period.String()
+	if unit, count, ok := period.Unit(); ok {
+		f.PeriodUnit, f.PeriodCount = unit, count
+	} else if f.PeriodUnit == "" || f.PeriodCount <= 0 {
+		f.PeriodUnit, f.PeriodCount = model.PeriodUnitMonth, 1
+	}
+	return nil
+}
+
+// ToPlanPrices 转换为多币种价格, 同一币种只保留最后一条
+func (f *PlanForm) ToPlanPrices() []*model.PlanPrice {
+	idx := make(map[string]int)
+	prices := make([]*model.PlanPrice, 0, len(f.Prices))
+	for _, p := range f.Prices {
+		pp := &model.PlanPrice{Currency: strings.ToUpper(p.Currency), Price: p.Price}
+		if i, ok := idx[pp.Currency]; ok {
+			prices[i] = pp
+			continue
+		}
+		idx[pp.Currency] = len(prices)
+		prices = append(prices, pp)
+	}
+	return prices
+}
+
+type ProductForm struct {
+	Id          uint   `json:"id"`
+	Code        string `json:"code" validate:"required"`
+	Name        string `json:"name" validate:"required"`
+	Description string `json:"description"`
+	Price       int64  `json:"price" validate:"gt=0"` // 价格(分, 基础币种)
+	Status      int    `json:"status" validate:"oneof=1 2"`
+	SortOrder   int    `json:"sort_order"`
+}
+
+func (f *ProductForm) ToProduct() *model.Product {
+	pr := &model.Product{
+		Code:        f.Code,
+		Name:        f.Name,
+		Description: f.Description,
+		Price:       f.Price,
+		Status:      model.StatusCode(f.Status),
+		SortOrder:   f.SortOrder,
+	}
+	pr.Id = f.Id
+	return pr
+}
+
+type FulfillmentForm struct {
+	OrderId uint   `json:"order_id" validate:"required"`
+	Status  int    `json:"status" validate:"oneof=1 2 3 4"`
+	Note    string `json:"note"`
+}
+
+type PlanDeleteForm struct {
+	Id          uint `json:"id" validate:"required"`
+	SuccessorId uint `json:"successor_id"` // 继任套餐, 有有效订阅时必填
+}
+
+type IdForm struct {
 	Id uint `json:"id" validate:"required"`
 }
 
+type IdsForm struct {
+	Ids []uint `json:"ids" validate:"required"`
+}
+
 type UserIdForm struct {
 	UserId uint `json:"user_id" validate:"required"`
 }
 
+type PauseForm struct {
+	UserId uint   `json:"user_id" validate:"required"`
+	Days   int    `json:"days" validate:"gte=0"` // 暂停天数, 0 为需手动恢复
+	Reason string `json:"reason" validate:"max=255"`
+}
+
 type RefundForm struct {
 	OrderId uint   `json:"order_id" validate:"required"`
-	Reason  string `json:"reason"`
+	Amount  int64  `json:"amount" validate:"gte=0"` // 退款金额(分), 0为退还剩余全部
+	Reason  string `json:"reason" validate:"required"`
+}
+
+type RefundReviewForm struct {
+	Id   uint   `json:"id" validate:"required"` // 退款申请ID
+	Note string `json:"note"`
+}
+
+type RedeemCodeGenerateForm struct {
+	PlanId   uint  `json:"plan_id" validate:"required"`
+	Days     int   `json:"days" validate:"required,gt=0"`
+	Count    int   `json:"count" validate:"required,gt=0,lte=1000"`
+	ExpireAt int64 `json:"expire_at" validate:"gte=0"` // 兑换截止时间(0为不限)
+}
+
+type OrderCreateForm struct {
+	UserId   uint   `json:"user_id" validate:"required"`
+	PlanId   uint   `json:"plan_id" validate:"required"`
+	Currency string `json:"currency"`                          // 为空时使用套餐基础币种
+	Amount   *int64 `json:"amount" validate:"omitempty,gte=0"` // 金额(分), 不传时按套餐价格
+	Subject  string `json:"subject" validate:"max=255"`        // 订单标题, 为空时使用套餐名称
+	Paid     bool   `json:"paid"`                              // 立即标记为已支付
+	TradeNo  string `json:"trade_no"`                          // 线下收款流水号(可选)
+	Remark   string `json:"remark"`
+}
+
+type MarkPaidForm struct {
+	OrderId uint   `json:"order_id" validate:"required"`
+	TradeNo string `json:"trade_no"` // 线下收款流水号(可选)
+	Remark  string `json:"remark"`
+}
+
+type SimulatePaidForm struct {
+	OrderId uint `json:"order_id" validate:"required"`
+}
+
+type ExtendHoldForm struct {
+	OrderId uint `json:"order_id" validate:"required"`
+	Hours   int  `json:"hours" validate:"required,min=1,max=720"` // 顺延小时数
+}
+
+type ProofReviewForm struct {
+	Id     uint   `json:"id" validate:"required"`
+	Remark string `json:"remark" validate:"max=500"`
+}
+
+type ConsistencyRepairForm struct {
+	Types []string `json:"types" validate:"omitempty,dive,oneof=paid_no_subscription subscription_missing_order subscription_missing_plan duplicate_pending_order amount_mismatch refund_mismatch"`
+}
+
+type SimulateQuery struct {
+	UserId uint   `form:"user_id"`
+	At     string `form:"at"`
+}
+
+// AtUnix 解析模拟时刻, 为空时使用当前时间
+func (q *SimulateQuery) AtUnix() (int64, error) {
+	at := strings.TrimSpace(q.At)
+	if at == "" {
+		return time.Now().Unix(), nil
+	}
+	if ts, err := strconv.ParseInt(at, 10, 64); err == nil {
+		return ts, nil
+	}
+	if t, err := time.Parse(time.RFC3339, at); err == nil {
+		return t.Unix(), nil
+	}
+	t, err := time.ParseInLocation(time.DateTime, at, time.Local)
+	if err != nil {
+		return 0, errors.New("invalid time: " + at)
+	}
+	return t.Unix(), nil
 }
 
 type GrantForm struct {
-	UserId uint `json:"user_id" validate:"required"`
-	PlanId uint `json:"plan_id" validate:"required"`
-	Days   int  `json:"days" validate:"required,gt=0"`
+	UserId uint   `json:"user_id" validate:"required"`
+	PlanId uint   `json:"plan_id" validate:"required"`
+	Days   int    `json:"days" validate:"required_without=Period,omitempty,gt=0"`
+	Period string `json:"period"` // ISO-8601 周期(如 P1M7D), 非空时优先于 Days
+}
+
+type GrantBatchForm struct {
+	UserIds []uint `json:"user_ids"`
+	GroupId uint   `json:"group_id"` // 用户组, 组内用户与 user_ids 合并
+	PlanId  uint   `json:"plan_id" validate:"required"`
+	Days    int    `json:"days" validate:"required_without=Period,omitempty,gt=0"`
+	Period  string `json:"period"` // ISO-8601 周期(如 P1M7D), 非空时优先于 Days
+}
+
+// GrantPeriod 赠送的周期
+func (f *GrantForm) GrantPeriod() (model.Period, error) {
+	if f.Period != "" {
+		return model.ParsePeriod(f.Period)
+	}
+	if f.Days <= 0 {
+		return model.Period{}, errors.New("invalid days")
+	}
+	return model.Period{Days: f.Days}, nil
 }
 
 // ========== 支付配置管理 ==========
 
 // PaymentConfigForm 支付配置表单
 type PaymentConfigForm struct {
-	Enable    bool   `json:"enable"`
-	BaseURL   string `json:"base_url"`
-	Pid       string `json:"pid"`
-	Key       string `json:"key"`
-	NotifyURL string `json:"notify_url"`
-	ReturnURL string `json:"return_url"`
-	Timeout   int    `json:"timeout"`
+	Enable      bool     `json:"enable"`
+	BaseURL     string   `json:"base_url"`
+	Pid         string   `json:"pid"`
+	Key         string   `json:"key"`
+	NotifyURL   string   `json:"notify_url"`
+	ReturnURL   string   `json:"return_url"`
+	RedirectURL string   `json:"redirect_url"`
+	Timeout     int      `json:"timeout"`
+	PayTypes    []string `json:"pay_types" validate:"dive,oneof=alipay wxpay qqpay"` // 允许用户选择的支付渠道
+	Sandbox     bool     `json:"sandbox"`                                            // 测试模式
 }
 
 // ConfigGet 获取支付配置
@@ -466,16 +1996,19 @@ type PaymentConfigForm struct {
 // @Success 200 {object} response.Response
 // @Router /api/admin/payment/config [get]
 func (p *Payment) ConfigGet(c *gin.Context) {
-	cfg := service.AllService.PaymentService.GetConfig()
+	cfg := service.AllService.Payment().GetConfig()
 	// 隐藏敏感信息的部分字符
 	maskedCfg := &model.PaymentConfig{
-		Enable:    cfg.Enable,
-		BaseURL:   cfg.BaseURL,
-		Pid:       maskString(cfg.Pid),
-		Key:       maskString(cfg.Key),
-		NotifyURL: cfg.NotifyURL,
-		ReturnURL: cfg.ReturnURL,
-		Timeout:   cfg.Timeout,
+		Enable:      cfg.Enable,
+		BaseURL:     cfg.BaseURL,
+		Pid:         utils.MaskString(cfg.Pid),
+		Key:         utils.MaskString(cfg.Key),
+		NotifyURL:   cfg.NotifyURL,
+		ReturnURL:   cfg.ReturnURL,
+		RedirectURL: cfg.RedirectURL,
+		Timeout:     cfg.Timeout,
+		PayTypes:    cfg.PayTypes,
+		Sandbox:     cfg.Sandbox,
 	}
 	response.Success(c, maskedCfg)
 }
@@ -489,7 +2022,7 @@ func (p *Payment) ConfigGet(c *gin.Context) {
 // @Success 200 {object} response.Response
 // @Router /api/admin/payment/config/full [get]
 func (p *Payment) ConfigGetFull(c *gin.Context) {
-	cfg := service.AllService.PaymentService.GetConfig()
+	cfg := service.AllService.Payment().GetConfig()
 	response.Success(c, cfg)
 }
 
@@ -508,40 +2041,41 @@ func (p *Payment) ConfigSave(c *gin.Context) {
 		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
 		return
 	}
+	errList := global.Validator.ValidStruct(c, &form)
+	if len(errList) > 0 {
+		response.Fail(c, 101, errList[0])
+		return
+	}
 
 	// 避免前端拿到脱敏后的 pid/key 直接保存，导致覆盖真实密钥
-	current := service.AllService.PaymentService.GetConfig()
+	current := service.AllService.Payment().GetConfig()
 	pid := strings.TrimSpace(form.Pid)
 	key := strings.TrimSpace(form.Key)
-	if pid == "" || pid == maskString(current.Pid) || strings.Contains(pid, "*") {
+	if pid == "" || pid == utils.MaskString(current.Pid) || strings.Contains(pid, "*") {
 		pid = current.Pid
 	}
-	if key == "" || key == maskString(current.Key) || strings.Contains(key, "*") {
+	if key == "" || key == utils.MaskString(current.Key) || strings.Contains(key, "*") {
 		key = current.Key
 	}
 
 	cfg := &model.PaymentConfig{
-		Enable:    form.Enable,
-		BaseURL:   form.BaseURL,
-		Pid:       pid,
-		Key:       key,
-		NotifyURL: form.NotifyURL,
-		ReturnURL: form.ReturnURL,
-		Timeout:   form.Timeout,
+		Enable:      form.Enable,
+		BaseURL:     form.BaseURL,
+		Pid:         pid,
+		Key:         key,
+		NotifyURL:   form.NotifyURL,
+		ReturnURL:   form.ReturnURL,
+		RedirectURL: form.RedirectURL,
+		Timeout:     form.Timeout,
+		PayTypes:    model.EnabledPayTypes(form.PayTypes),
+		Sandbox:     form.Sandbox,
 	}
 
 	if err := service.AllService.SystemSettingService.SetPaymentConfig(cfg); err != nil {
 		response.Fail(c, 101, err.Error())
 		return
 	}
+	audit(c, model.AdminAuditPaymentConfig, 0, current, cfg)
 
 	response.Success(c, nil)
 }
-
-// maskString 遮蔽字符串中间部分
-func maskString(s string) string {
-	if len(s) <= 8 {
-		return "****"
-	}
-	return s[:4] + "****" + s[len(s)-4:]
-}
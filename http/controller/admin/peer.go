@@ -5,9 +5,11 @@ import (
 	"github.com/lejianwen/rustdesk-api/v2/global"
 	"github.com/lejianwen/rustdesk-api/v2/http/request/admin"
 	"github.com/lejianwen/rustdesk-api/v2/http/response"
+	"github.com/lejianwen/rustdesk-api/v2/model"
 	"github.com/lejianwen/rustdesk-api/v2/service"
 	"gorm.io/gorm"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -80,6 +82,7 @@ func (ct *Peer) Create(c *gin.Context) {
 // @Param id query string false "ID"
 // @Param hostname query string false "主机名"
 // @Param uuids query string false "uuids 用逗号分隔"
+// @Param country query string false "国家/地区代码"
 // @Success 200 {object} response.Response{data=model.PeerList}
 // @Failure 500 {object} response.Response
 // @Router /admin/peer/list [get]
@@ -117,6 +120,9 @@ func (ct *Peer) List(c *gin.Context) {
 		if query.Alias != "" {
 			tx.Where("alias like ?", "%"+query.Alias+"%")
 		}
+		if query.Country != "" {
+			tx.Where("country = ?", strings.ToUpper(query.Country))
+		}
 	})
 	response.Success(c, res)
 }
@@ -221,6 +227,70 @@ func (ct *Peer) BatchDelete(c *gin.Context) {
 	response.Success(c, nil)
 }
 
+// Stale 过期设备列表
+// @Tags 设备
+// @Summary 过期设备列表
+// @Description 未绑定用户或超过N天未在线的设备, 同时指定时需同时满足
+// @Accept  json
+// @Produce  json
+// @Param page query int false "页码"
+// @Param page_size query int false "页大小"
+// @Param orphan query bool false "未绑定用户"
+// @Param inactive_days query int false "未在线天数"
+// @Success 200 {object} response.Response{data=model.PeerList}
+// @Failure 500 {object} response.Response
+// @Router /admin/peer/stale [get]
+// @Security token
+func (ct *Peer) Stale(c *gin.Context) {
+	query := &admin.PeerStaleQuery{}
+	if err := c.ShouldBindQuery(query); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	if !query.Orphan && query.InactiveDays <= 0 {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError"))
+		return
+	}
+	res := service.AllService.PeerService.ListStale(query.Page, query.PageSize, query.Orphan, query.InactiveDays)
+	response.Success(c, res)
+}
+
+// Cleanup 清理过期设备
+// @Tags 设备
+// @Summary 清理过期设备
+// @Description 删除未绑定用户或超过N天未在线的设备, 同时指定时需同时满足
+// @Accept  json
+// @Produce  json
+// @Param body body admin.PeerCleanupForm true "清理条件"
+// @Success 200 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /admin/peer/cleanup [post]
+// @Security token
+func (ct *Peer) Cleanup(c *gin.Context) {
+	f := &admin.PeerCleanupForm{}
+	if err := c.ShouldBindJSON(f); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	errList := global.Validator.ValidStruct(c, f)
+	if len(errList) > 0 {
+		response.Fail(c, 101, errList[0])
+		return
+	}
+	if !f.Orphan && f.InactiveDays <= 0 {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError"))
+		return
+	}
+	n, err := service.AllService.PeerService.CleanupStale(f.Orphan, f.InactiveDays)
+	if err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "OperationFailed")+err.Error())
+		return
+	}
+	response.Success(c, gin.H{
+		"deleted": n,
+	})
+}
+
 func (ct *Peer) SimpleData(c *gin.Context) {
 	f := &admin.SimpleDataQuery{}
 	if err := c.ShouldBindJSON(f); err != nil {
@@ -238,3 +308,202 @@ func (ct *Peer) SimpleData(c *gin.Context) {
 	})
 	response.Success(c, res)
 }
+
+// ApprovalList 设备绑定审批列表
+// @Tags 设备
+// @Summary 设备绑定审批列表
+// @Description 用户绑定设备超出套餐设备数且设置为审批时提交的申请
+// @Accept  json
+// @Produce  json
+// @Param page query int false "页码"
+// @Param page_size query int false "页大小"
+// @Param status query int false "状态: 0待审批 1已批准 2已驳回, -1全部"
+// @Param user_id query int false "用户ID"
+// @Success 200 {object} response.Response{data=model.DeviceApprovalList}
+// @Failure 500 {object} response.Response
+// @Router /admin/peer/approval/list [get]
+// @Security token
+func (ct *Peer) ApprovalList(c *gin.Context) {
+	query := &admin.DeviceApprovalQuery{}
+	if err := c.ShouldBindQuery(query); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	res := service.AllService.PeerService.ListDeviceApprovals(query.Page, query.PageSize, func(tx *gorm.DB) {
+		if query.Status >= 0 {
+			tx.Where("status = ?", query.Status)
+		}
+		if query.UserId > 0 {
+			tx.Where("user_id = ?", query.UserId)
+		}
+	})
+	response.Success(c, res)
+}
+
+// ApprovalApprove 批准设备绑定
+// @Tags 设备
+// @Summary 批准设备绑定
+// @Description 批准后用户可在该设备登录并绑定, 不受套餐设备数限制
+// @Accept  json
+// @Produce  json
+// @Param body body admin.DeviceApprovalReviewForm true "申请"
+// @Success 200 {object} response.Response{data=model.DeviceApproval}
+// @Failure 500 {object} response.Response
+// @Router /admin/peer/approval/approve [post]
+// @Security token
+func (ct *Peer) ApprovalApprove(c *gin.Context) {
+	ct.reviewApproval(c, true)
+}
+
+// ApprovalReject 驳回设备绑定
+// @Tags 设备
+// @Summary 驳回设备绑定
+// @Description 驳回后该设备登录仍被拒绝, 用户可解绑其他设备后重试
+// @Accept  json
+// @Produce  json
+// @Param body body admin.DeviceApprovalReviewForm true "申请"
+// @Success 200 {object} response.Response{data=model.DeviceApproval}
+// @Failure 500 {object} response.Response
+// @Router /admin/peer/approval/reject [post]
+// @Security token
+func (ct *Peer) ApprovalReject(c *gin.Context) {
+	ct.reviewApproval(c, false)
+}
+
+func (ct *Peer) reviewApproval(c *gin.Context, approve bool) {
+	f := &admin.DeviceApprovalReviewForm{}
+	if err := c.ShouldBindJSON(f); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	errList := global.Validator.ValidStruct(c, f)
+	if len(errList) > 0 {
+		response.Fail(c, 101, errList[0])
+		return
+	}
+	u := service.AllService.UserService.CurUser(c)
+	a, err := service.AllService.PeerService.ReviewDeviceApproval(f.Id, approve, u.Id)
+	if err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, err.Error()))
+		return
+	}
+	response.Success(c, a)
+}
+
+// BanList 封禁列表
+// @Tags 设备
+// @Summary 设备封禁列表
+// @Description 设备封禁列表, 含已到期的记录
+// @Accept  json
+// @Produce  json
+// @Param page query int false "页码"
+// @Param page_size query int false "页大小"
+// @Param peer_id query string false "设备ID"
+// @Success 200 {object} response.Response{data=model.PeerBanList}
+// @Failure 500 {object} response.Response
+// @Router /admin/peer/ban/list [get]
+// @Security token
+func (ct *Peer) BanList(c *gin.Context) {
+	query := &admin.PeerBanQuery{}
+	if err := c.ShouldBindQuery(query); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	res := service.AllService.PeerBanService.List(query.Page, query.PageSize, func(tx *gorm.DB) {
+		if query.PeerId != "" {
+			tx.Where("peer_id = ?", query.PeerId)
+		}
+		tx.Order("id desc")
+	})
+	response.Success(c, res)
+}
+
+// Ban 封禁设备
+// @Tags 设备
+// @Summary 封禁设备
+// @Description 按设备ID或uuid封禁, 订阅检查和 relay 放行均拒绝该设备(不论订阅状态), 并通知 hbbs 断开现有连接
+// @Accept  json
+// @Produce  json
+// @Param body body admin.PeerBanForm true "封禁信息"
+// @Success 200 {object} response.Response{data=model.PeerBan}
+// @Failure 500 {object} response.Response
+// @Router /admin/peer/ban [post]
+// @Security token
+func (ct *Peer) Ban(c *gin.Context) {
+	f := &admin.PeerBanForm{}
+	if err := c.ShouldBindJSON(f); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	errList := global.Validator.ValidStruct(c, f)
+	if len(errList) > 0 {
+		response.Fail(c, 101, errList[0])
+		return
+	}
+	u := service.AllService.UserService.CurUser(c)
+	b := &model.PeerBan{PeerId: f.PeerId, Uuid: f.Uuid, Reason: f.Reason, ExpireAt: f.ExpireAt, OperatorId: u.Id}
+	if err := service.AllService.PeerBanService.Ban(b); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, err.Error()))
+		return
+	}
+	response.Success(c, b)
+}
+
+// Unban 解除封禁
+// @Tags 设备
+// @Summary 解除设备封禁
+// @Description 解除设备封禁
+// @Accept  json
+// @Produce  json
+// @Param body body admin.PeerUnbanForm true "封禁记录"
+// @Success 200 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /admin/peer/unban [post]
+// @Security token
+func (ct *Peer) Unban(c *gin.Context) {
+	f := &admin.PeerUnbanForm{}
+	if err := c.ShouldBindJSON(f); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	errList := global.Validator.ValidStruct(c, f)
+	if len(errList) > 0 {
+		response.Fail(c, 101, errList[0])
+		return
+	}
+	b := service.AllService.PeerBanService.InfoById(f.Id)
+	if b.Id == 0 {
+		response.Fail(c, 101, response.TranslateMsg(c, "ItemNotFound"))
+		return
+	}
+	if err := service.AllService.PeerBanService.Unban(b); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "OperationFailed")+err.Error())
+		return
+	}
+	response.Success(c, nil)
+}
+
+// BulkEdit 批量编辑设备
+// @Tags 设备
+// @Summary 批量编辑设备
+// @Description 批量修改设备别名、移动设备分组, 返回每台设备的结果
+// @Accept  json
+// @Produce  json
+// @Param body body admin.BulkEditForm true "批量编辑"
+// @Success 200 {object} response.Response{data=[]model.BulkEditResult}
+// @Failure 500 {object} response.Response
+// @Router /admin/peer/bulkEdit [post]
+// @Security token
+func (ct *Peer) BulkEdit(c *gin.Context) {
+	f := &admin.BulkEditForm{}
+	if err := c.ShouldBindJSON(f); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	results, err := service.AllService.PeerService.BulkEdit(f.RowIds, &f.BulkEdit)
+	if err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, err.Error()))
+		return
+	}
+	response.Success(c, translateBulkResults(c, results))
+}
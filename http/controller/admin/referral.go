@@ -0,0 +1,56 @@
+package admin
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lejianwen/rustdesk-api/v2/http/response"
+	"github.com/lejianwen/rustdesk-api/v2/service"
+	"gorm.io/gorm"
+)
+
+// ========== 推荐计划 ==========
+
+// ReferralList 推荐记录
+// @Tags Admin-Payment
+// @Summary 推荐记录
+// @Description 推荐关系及发放的注册/首购奖励天数
+// @Produce  json
+// @Param page query int false "页码"
+// @Param page_size query int false "每页数量"
+// @Param referrer_id query int false "推荐人ID"
+// @Success 200 {object} response.Response{data=model.ReferralList}
+// @Router /api/admin/referral/list [get]
+func (p *Payment) ReferralList(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 10
+	}
+	referrerId, _ := strconv.Atoi(c.Query("referrer_id"))
+	res := service.AllService.ReferralService.List(uint(page), uint(pageSize), func(tx *gorm.DB) {
+		if referrerId > 0 {
+			tx.Where("referrer_id = ?", referrerId)
+		}
+	})
+	response.Success(c, res)
+}
+
+// ReferralReport 推荐效果统计
+// @Tags Admin-Payment
+// @Summary 推荐效果统计
+// @Description 按推荐人统计推荐注册数、首购人数和累计奖励天数, 按注册数倒序
+// @Produce  json
+// @Param limit query int false "返回的推荐人数量, 默认 50, 最大 500"
+// @Success 200 {object} response.Response{data=[]model.ReferralStat}
+// @Router /api/admin/referral/report [get]
+func (p *Payment) ReferralReport(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if limit < 1 || limit > 500 {
+		limit = 50
+	}
+	response.Success(c, service.AllService.ReferralService.Report(limit))
+}
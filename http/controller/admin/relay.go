@@ -0,0 +1,336 @@
+package admin
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lejianwen/rustdesk-api/v2/global"
+	"github.com/lejianwen/rustdesk-api/v2/http/request/admin"
+	"github.com/lejianwen/rustdesk-api/v2/http/response"
+	"github.com/lejianwen/rustdesk-api/v2/service"
+	"gorm.io/gorm"
+)
+
+type Relay struct {
+}
+
+// Whitelist 白名单列表
+// @Tags Relay
+// @Summary Relay白名单列表
+// @Description 列出未过期的relay白名单条目(uuid、剩余次数、过期时间)
+// @Accept  json
+// @Produce  json
+// @Param uuid query string false "uuid(包含匹配)"
+// @Success 200 {object} response.Response{data=[]service.RelayWhitelistEntry}
+// @Failure 500 {object} response.Response
+// @Router /admin/relay/whitelist [get]
+// @Security token
+func (ct *Relay) Whitelist(c *gin.Context) {
+	query := &admin.RelayWhitelistQuery{}
+	if err := c.ShouldBindQuery(query); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	list := service.AllService.RelayWhitelist().List(query.Uuid)
+	response.Success(c, gin.H{
+		"list":  list,
+		"total": len(list),
+	})
+}
+
+// WhitelistDelete 删除白名单条目
+// @Tags Relay
+// @Summary 删除Relay白名单条目
+// @Description 删除指定uuid的relay白名单条目
+// @Accept  json
+// @Produce  json
+// @Param body body admin.RelayWhitelistDeleteForm true "条目"
+// @Success 200 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /admin/relay/whitelist/delete [post]
+// @Security token
+func (ct *Relay) WhitelistDelete(c *gin.Context) {
+	f := &admin.RelayWhitelistDeleteForm{}
+	if err := c.ShouldBindJSON(f); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	errList := global.Validator.ValidStruct(c, f)
+	if len(errList) > 0 {
+		response.Fail(c, 101, errList[0])
+		return
+	}
+	if !service.AllService.RelayWhitelist().Remove(f.Uuid) {
+		response.Fail(c, 101, response.TranslateMsg(c, "ItemNotFound"))
+		return
+	}
+	response.Success(c, nil)
+}
+
+// WhitelistFlush 清空白名单
+// @Tags Relay
+// @Summary 清空Relay白名单
+// @Description 清空全部relay白名单条目, 正在协商的relay会被拒绝
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /admin/relay/whitelist/flush [post]
+// @Security token
+func (ct *Relay) WhitelistFlush(c *gin.Context) {
+	n := service.AllService.RelayWhitelist().Flush()
+	response.Success(c, gin.H{"flushed": n})
+}
+
+// Sessions relay 会话列表
+// @Tags Relay
+// @Summary Relay会话列表
+// @Description hbbr 上报的relay会话记录, 用于审计和计费
+// @Accept  json
+// @Produce  json
+// @Param page query int false "页码"
+// @Param page_size query int false "页大小"
+// @Param user_id query int false "发起端用户ID"
+// @Param peer_id query string false "发起端或被控端设备ID"
+// @Param status query int false "状态: 1进行中 2已结束"
+// @Param since query int false "开始时间>=since"
+// @Param until query int false "开始时间<until"
+// @Success 200 {object} response.Response{data=model.RelaySessionList}
+// @Failure 500 {object} response.Response
+// @Router /admin/relay/sessions [get]
+// @Security token
+func (ct *Relay) Sessions(c *gin.Context) {
+	query := &admin.RelaySessionQuery{}
+	if err := c.ShouldBindQuery(query); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	res := service.AllService.RelaySessionService.List(query.Page, query.PageSize, func(tx *gorm.DB) {
+		if query.UserId > 0 {
+			tx.Where("user_id = ?", query.UserId)
+		}
+		if query.PeerId != "" {
+			tx.Where("peer_id = ? OR target_peer_id = ?", query.PeerId, query.PeerId)
+		}
+		if query.Status > 0 {
+			tx.Where("status = ?", query.Status)
+		}
+		if query.Since > 0 {
+			tx.Where("started_at >= ?", query.Since)
+		}
+		if query.Until > 0 {
+			tx.Where("started_at < ?", query.Until)
+		}
+		tx.Order("id desc")
+	})
+	response.Success(c, res)
+}
+
+// Usage relay 用量汇总
+// @Tags Relay
+// @Summary Relay用量汇总
+// @Description 按发起端用户汇总已结束会话的次数、时长(秒)和流量(字节), 默认最近30天
+// @Accept  json
+// @Produce  json
+// @Param user_id query int false "用户ID"
+// @Param since query int false "开始时间"
+// @Param until query int false "结束时间"
+// @Success 200 {object} response.Response{data=[]model.RelayUsage}
+// @Failure 500 {object} response.Response
+// @Router /admin/relay/usage [get]
+// @Security token
+func (ct *Relay) Usage(c *gin.Context) {
+	query := &admin.RelayUsageQuery{}
+	if err := c.ShouldBindQuery(query); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	if query.Until == 0 {
+		query.Until = time.Now().Unix()
+	}
+	if query.Since == 0 {
+		query.Since = query.Until - 30*86400
+	}
+	list := service.AllService.RelaySessionService.Usage(query.UserId, query.Since, query.Until)
+	response.Success(c, gin.H{
+		"list":  list,
+		"since": query.Since,
+		"until": query.Until,
+	})
+}
+
+// Incidents relay 异常记录
+// @Tags Relay
+// @Summary Relay异常记录
+// @Description 检测到的relay异常(同一uuid大量消费失败、同一用户大量写入白名单)
+// @Accept  json
+// @Produce  json
+// @Param page query int false "页码"
+// @Param page_size query int false "页大小"
+// @Param kind query string false "类型: consume_failures/allow_flood/geo_restricted"
+// @Param uuid query string false "uuid"
+// @Param user_id query int false "用户ID"
+// @Success 200 {object} response.Response{data=model.RelayIncidentList}
+// @Failure 500 {object} response.Response
+// @Router /admin/relay/incidents [get]
+// @Security token
+func (ct *Relay) Incidents(c *gin.Context) {
+	query := &admin.RelayIncidentQuery{}
+	if err := c.ShouldBindQuery(query); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	res := service.AllService.RelayWhitelistService.IncidentList(query.Page, query.PageSize, func(tx *gorm.DB) {
+		if query.Kind != "" {
+			tx.Where("kind = ?", query.Kind)
+		}
+		if query.Uuid != "" {
+			tx.Where("uuid = ?", query.Uuid)
+		}
+		if query.UserId > 0 {
+			tx.Where("user_id = ?", query.UserId)
+		}
+		tx.Order("id desc")
+	})
+	response.Success(c, res)
+}
+
+// GeoRuleList 地区限制列表
+// @Tags Relay
+// @Summary Relay地区限制列表
+// @Description 按用户组或套餐限制 relay 白名单消费的国家/网段
+// @Accept  json
+// @Produce  json
+// @Param scope query string false "范围: group/plan"
+// @Param scope_id query int false "用户组ID或套餐ID"
+// @Param page query int false "页码"
+// @Param page_size query int false "页大小"
+// @Success 200 {object} response.Response{data=model.RelayGeoRuleList}
+// @Failure 500 {object} response.Response
+// @Router /admin/relay/geo_rule/list [get]
+// @Security token
+func (ct *Relay) GeoRuleList(c *gin.Context) {
+	query := &admin.RelayGeoRuleQuery{}
+	if err := c.ShouldBindQuery(query); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	res := service.AllService.RelayGeoService.List(query.Page, query.PageSize, func(tx *gorm.DB) {
+		if query.Scope != "" {
+			tx.Where("scope = ?", query.Scope)
+		}
+		if query.ScopeId > 0 {
+			tx.Where("scope_id = ?", query.ScopeId)
+		}
+		tx.Order("id desc")
+	})
+	response.Success(c, res)
+}
+
+// GeoRuleCreate 创建地区限制
+// @Tags Relay
+// @Summary 创建Relay地区限制
+// @Description 同一用户命中的规则中, 任一 deny 匹配即拒绝; 存在 allow 规则时需至少匹配一条. 国家按 geoip 数据库判断
+// @Accept  json
+// @Produce  json
+// @Param body body admin.RelayGeoRuleForm true "规则"
+// @Success 200 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /admin/relay/geo_rule/create [post]
+// @Security token
+func (ct *Relay) GeoRuleCreate(c *gin.Context) {
+	f := &admin.RelayGeoRuleForm{}
+	if err := c.ShouldBindJSON(f); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	errList := global.Validator.ValidStruct(c, f)
+	if len(errList) > 0 {
+		response.Fail(c, 101, errList[0])
+		return
+	}
+	r := f.ToRelayGeoRule()
+	r.Id = 0
+	if err := service.AllService.RelayGeoService.ValidateRule(r); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	if err := service.AllService.RelayGeoService.Create(r); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "OperationFailed")+err.Error())
+		return
+	}
+	response.Success(c, r)
+}
+
+// GeoRuleUpdate 编辑地区限制
+// @Tags Relay
+// @Summary 编辑Relay地区限制
+// @Description 编辑Relay地区限制
+// @Accept  json
+// @Produce  json
+// @Param body body admin.RelayGeoRuleForm true "规则"
+// @Success 200 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /admin/relay/geo_rule/update [post]
+// @Security token
+func (ct *Relay) GeoRuleUpdate(c *gin.Context) {
+	f := &admin.RelayGeoRuleForm{}
+	if err := c.ShouldBindJSON(f); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	errList := global.Validator.ValidStruct(c, f)
+	if len(errList) > 0 {
+		response.Fail(c, 101, errList[0])
+		return
+	}
+	ex := service.AllService.RelayGeoService.InfoById(f.Id)
+	if f.Id == 0 || ex.Id == 0 {
+		response.Fail(c, 101, response.TranslateMsg(c, "ItemNotFound"))
+		return
+	}
+	r := f.ToRelayGeoRule()
+	if err := service.AllService.RelayGeoService.ValidateRule(r); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	if err := service.AllService.RelayGeoService.Update(r); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "OperationFailed")+err.Error())
+		return
+	}
+	response.Success(c, nil)
+}
+
+// GeoRuleDelete 删除地区限制
+// @Tags Relay
+// @Summary 删除Relay地区限制
+// @Description 删除Relay地区限制
+// @Accept  json
+// @Produce  json
+// @Param body body admin.RelayGeoRuleForm true "规则"
+// @Success 200 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /admin/relay/geo_rule/delete [post]
+// @Security token
+func (ct *Relay) GeoRuleDelete(c *gin.Context) {
+	f := &admin.RelayGeoRuleForm{}
+	if err := c.ShouldBindJSON(f); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	errList := global.Validator.ValidVar(c, f.Id, "required,gt=0")
+	if len(errList) > 0 {
+		response.Fail(c, 101, errList[0])
+		return
+	}
+	ex := service.AllService.RelayGeoService.InfoById(f.Id)
+	if ex.Id == 0 {
+		response.Fail(c, 101, response.TranslateMsg(c, "ItemNotFound"))
+		return
+	}
+	if err := service.AllService.RelayGeoService.Delete(ex); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "OperationFailed")+err.Error())
+		return
+	}
+	response.Success(c, nil)
+}
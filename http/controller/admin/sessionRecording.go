@@ -0,0 +1,168 @@
+package admin
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lejianwen/rustdesk-api/v2/global"
+	"github.com/lejianwen/rustdesk-api/v2/http/request/admin"
+	"github.com/lejianwen/rustdesk-api/v2/http/response"
+	"github.com/lejianwen/rustdesk-api/v2/model"
+	"github.com/lejianwen/rustdesk-api/v2/service"
+	"gorm.io/gorm"
+)
+
+// RecordingList 会话录像列表
+// @Tags 会话录像
+// @Summary 会话录像列表
+// @Description 录制组件登记的会话录像, 保留天数见设置 recording.retention_days
+// @Accept  json
+// @Produce  json
+// @Param page query int false "页码"
+// @Param page_size query int false "页大小"
+// @Param user_id query int false "被控用户"
+// @Param session_id query string false "会话ID"
+// @Param peer_id query string false "发起设备"
+// @Param target_peer_id query string false "被控设备"
+// @Param hold query bool false "是否保留"
+// @Param start_at query int false "录制时间起(unix 秒)"
+// @Param end_at query int false "录制时间止(unix 秒)"
+// @Success 200 {object} response.Response{data=model.SessionRecordingList}
+// @Failure 500 {object} response.Response
+// @Router /admin/audit_recording/list [get]
+// @Security token
+func (a *Audit) RecordingList(c *gin.Context) {
+	query := &admin.SessionRecordingQuery{}
+	if err := c.ShouldBindQuery(query); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	res := service.AllService.RecordingService.List(query.Page, query.PageSize, func(tx *gorm.DB) {
+		if query.UserId > 0 {
+			tx.Where("user_id = ?", query.UserId)
+		}
+		if query.SessionId != "" {
+			tx.Where("session_id = ?", query.SessionId)
+		}
+		if query.PeerId != "" {
+			tx.Where("peer_id = ?", query.PeerId)
+		}
+		if query.TargetPeerId != "" {
+			tx.Where("target_peer_id = ?", query.TargetPeerId)
+		}
+		if query.Hold != nil {
+			tx.Where("hold = ?", *query.Hold)
+		}
+		if query.StartAt > 0 {
+			tx.Where("recorded_at >= ?", query.StartAt)
+		}
+		if query.EndAt > 0 {
+			tx.Where("recorded_at < ?", query.EndAt)
+		}
+	})
+	response.Success(c, gin.H{
+		"list":           res.SessionRecordings,
+		"total":          res.Total,
+		"page":           res.Page,
+		"page_size":      res.PageSize,
+		"retention_days": service.AllService.SystemSettingService.SettingInt(service.SettingRecordingRetentionDays),
+	})
+}
+
+// RecordingDownload 会话录像下载地址
+// @Tags 会话录像
+// @Summary 会话录像下载地址
+// @Description 存储中的录像返回带过期时间的签名地址, 录制组件自行托管的录像返回登记的地址
+// @Accept  json
+// @Produce  json
+// @Param id path int true "ID"
+// @Success 200 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /admin/audit_recording/download/{id} [get]
+// @Security token
+func (a *Audit) RecordingDownload(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	r := service.AllService.RecordingService.InfoById(uint(id))
+	if r.Id == 0 {
+		response.Fail(c, 101, response.TranslateMsg(c, "ItemNotFound"))
+		return
+	}
+	url, expireAt, err := service.AllService.RecordingService.DownloadURL(r)
+	if err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "OperationFailed")+err.Error())
+		return
+	}
+	response.Success(c, gin.H{
+		"url":       url,
+		"expire_at": expireAt,
+		"checksum":  r.Checksum,
+	})
+}
+
+// RecordingHold 设置会话录像保留
+// @Tags 会话录像
+// @Summary 设置会话录像保留
+// @Description 保留的录像不受保留天数清理
+// @Accept  json
+// @Produce  json
+// @Param body body admin.SessionRecordingHoldForm true "保留设置"
+// @Success 200 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /admin/audit_recording/hold [post]
+// @Security token
+func (a *Audit) RecordingHold(c *gin.Context) {
+	f := &admin.SessionRecordingHoldForm{}
+	if err := c.ShouldBindJSON(f); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	errList := global.Validator.ValidStruct(c, f)
+	if len(errList) > 0 {
+		response.Fail(c, 101, errList[0])
+		return
+	}
+	r := service.AllService.RecordingService.InfoById(f.Id)
+	if r.Id == 0 {
+		response.Fail(c, 101, response.TranslateMsg(c, "ItemNotFound"))
+		return
+	}
+	if err := service.AllService.RecordingService.SetHold(r, f.Hold, f.Remark); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "OperationFailed")+err.Error())
+		return
+	}
+	response.Success(c, nil)
+}
+
+// RecordingDelete 删除会话录像
+// @Tags 会话录像
+// @Summary 删除会话录像
+// @Description 删除登记记录, 存储中的录像文件一并删除
+// @Accept  json
+// @Produce  json
+// @Param body body model.SessionRecording true "会话录像"
+// @Success 200 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /admin/audit_recording/delete [post]
+// @Security token
+func (a *Audit) RecordingDelete(c *gin.Context) {
+	f := &model.SessionRecording{}
+	if err := c.ShouldBindJSON(f); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	errList := global.Validator.ValidVar(c, f.Id, "required,gt=0")
+	if len(errList) > 0 {
+		response.Fail(c, 101, errList[0])
+		return
+	}
+	r := service.AllService.RecordingService.InfoById(f.Id)
+	if r.Id == 0 {
+		response.Fail(c, 101, response.TranslateMsg(c, "ItemNotFound"))
+		return
+	}
+	if err := service.AllService.RecordingService.Delete(r); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "OperationFailed")+err.Error())
+		return
+	}
+	response.Success(c, nil)
+}
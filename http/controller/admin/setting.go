@@ -0,0 +1,109 @@
+package admin
+
+import (
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lejianwen/rustdesk-api/v2/global"
+	"github.com/lejianwen/rustdesk-api/v2/http/request/admin"
+	"github.com/lejianwen/rustdesk-api/v2/http/response"
+	"github.com/lejianwen/rustdesk-api/v2/model"
+	"github.com/lejianwen/rustdesk-api/v2/service"
+)
+
+type Setting struct {
+}
+
+// List 通用设置列表
+// @Tags Setting
+// @Summary 通用设置列表
+// @Description 已注册的设置定义及当前值, 密钥类设置的值已脱敏
+// @Accept  json
+// @Produce  json
+// @Param group query string false "分组"
+// @Success 200 {object} response.Response{data=[]model.SettingItem}
+// @Failure 500 {object} response.Response
+// @Router /admin/settings/list [get]
+// @Security token
+func (ct *Setting) List(c *gin.Context) {
+	response.Success(c, service.AllService.SystemSettingService.ListSettings(c.Query("group")))
+}
+
+// Update 修改通用设置
+// @Tags Setting
+// @Summary 修改通用设置
+// @Description 按设置定义校验类型、可选值和范围后保存; 密钥类设置提交脱敏值时保持不变
+// @Accept  json
+// @Produce  json
+// @Param body body admin.SettingUpdateForm true "设置"
+// @Success 200 {object} response.Response{data=model.SettingItem}
+// @Failure 500 {object} response.Response
+// @Router /admin/settings/update [post]
+// @Security token
+func (ct *Setting) Update(c *gin.Context) {
+	f := &admin.SettingUpdateForm{}
+	if err := c.ShouldBindJSON(f); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	errList := global.Validator.ValidStruct(c, f)
+	if len(errList) > 0 {
+		response.Fail(c, 101, errList[0])
+		return
+	}
+	ss := service.AllService.SystemSettingService
+	before := ss.SettingItem(f.Key)
+	if before == nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "SettingNotFound"))
+		return
+	}
+	if str, ok := f.Value.(string); ok && before.Secret && strings.Contains(str, "*") {
+		response.Success(c, before)
+		return
+	}
+	if err := ss.SetSetting(f.Key, f.Value); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, err.Error()))
+		return
+	}
+	after := ss.SettingItem(f.Key)
+	audit(c, model.AdminAuditSetting, 0, settingAuditValue(before), settingAuditValue(after))
+	response.Success(c, after)
+}
+
+// Reset 恢复默认值
+// @Tags Setting
+// @Summary 恢复通用设置默认值
+// @Description 删除已保存的值, 恢复为配置文件或定义中的默认值
+// @Accept  json
+// @Produce  json
+// @Param body body admin.SettingResetForm true "设置"
+// @Success 200 {object} response.Response{data=model.SettingItem}
+// @Failure 500 {object} response.Response
+// @Router /admin/settings/reset [post]
+// @Security token
+func (ct *Setting) Reset(c *gin.Context) {
+	f := &admin.SettingResetForm{}
+	if err := c.ShouldBindJSON(f); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	errList := global.Validator.ValidStruct(c, f)
+	if len(errList) > 0 {
+		response.Fail(c, 101, errList[0])
+		return
+	}
+	ss := service.AllService.SystemSettingService
+	before := ss.SettingItem(f.Key)
+	if err := ss.ResetSetting(f.Key); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, err.Error()))
+		return
+	}
+	after := ss.SettingItem(f.Key)
+	audit(c, model.AdminAuditSetting, 0, settingAuditValue(before), settingAuditValue(after))
+	response.Success(c, after)
+}
+
+// settingAuditValue 审计记录中的设置值(密钥类已脱敏)
+func settingAuditValue(item *model.SettingItem) gin.H {
+	return gin.H{"key": item.Key, "value": item.Value, "is_default": item.IsDefault}
+}
@@ -0,0 +1,97 @@
+package admin
+
+import (
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lejianwen/rustdesk-api/v2/global"
+	"github.com/lejianwen/rustdesk-api/v2/http/request/admin"
+	"github.com/lejianwen/rustdesk-api/v2/http/response"
+	"github.com/lejianwen/rustdesk-api/v2/service"
+	"github.com/lejianwen/rustdesk-api/v2/utils"
+)
+
+type Storage struct {
+}
+
+// ConfigGet 获取存储配置
+// @Tags Storage
+// @Summary 获取存储配置
+// @Description 获取文件存储配置(密钥脱敏)
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} response.Response{data=model.StorageConfig}
+// @Failure 500 {object} response.Response
+// @Router /admin/storage/config [get]
+// @Security token
+func (ct *Storage) ConfigGet(c *gin.Context) {
+	cfg := *service.AllService.StorageService.GetConfig()
+	if cfg.S3SecretKey != "" {
+		cfg.S3SecretKey = utils.MaskString(cfg.S3SecretKey)
+	}
+	response.Success(c, cfg)
+}
+
+// bindConfig 绑定并校验表单, 密钥为空或为脱敏值时使用已保存的密钥
+func (ct *Storage) bindConfig(c *gin.Context) (*admin.StorageConfigForm, bool) {
+	f := &admin.StorageConfigForm{}
+	if err := c.ShouldBindJSON(f); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return nil, false
+	}
+	errList := global.Validator.ValidStruct(c, f)
+	if len(errList) > 0 {
+		response.Fail(c, 101, errList[0])
+		return nil, false
+	}
+	if f.S3SecretKey == "" || strings.Contains(f.S3SecretKey, "*") {
+		f.S3SecretKey = service.AllService.StorageService.GetConfig().S3SecretKey
+	}
+	return f, true
+}
+
+// ConfigSave 保存存储配置
+// @Tags Storage
+// @Summary 保存存储配置
+// @Description 保存文件存储配置, 密钥为空或为脱敏值时保留原密钥; 切换存储不会迁移已有文件
+// @Accept  json
+// @Produce  json
+// @Param body body admin.StorageConfigForm true "存储配置"
+// @Success 200 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /admin/storage/config [post]
+// @Security token
+func (ct *Storage) ConfigSave(c *gin.Context) {
+	f, ok := ct.bindConfig(c)
+	if !ok {
+		return
+	}
+	if err := service.AllService.SystemSettingService.SetStorageConfig(f.ToStorageConfig()); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "OperationFailed")+err.Error())
+		return
+	}
+	response.Success(c, nil)
+}
+
+// Test 测试存储配置
+// @Tags Storage
+// @Summary 测试存储配置
+// @Description 使用提交的配置(不保存)写入、读取并删除一个测试文件
+// @Accept  json
+// @Produce  json
+// @Param body body admin.StorageConfigForm true "存储配置"
+// @Success 200 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /admin/storage/test [post]
+// @Security token
+func (ct *Storage) Test(c *gin.Context) {
+	f, ok := ct.bindConfig(c)
+	if !ok {
+		return
+	}
+	if err := service.AllService.StorageService.Test(c.Request.Context(), f.ToStorageConfig()); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "StorageTestFailed")+err.Error())
+		return
+	}
+	response.Success(c, nil)
+}
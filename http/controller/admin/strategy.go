@@ -0,0 +1,235 @@
+package admin
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/lejianwen/rustdesk-api/v2/global"
+	"github.com/lejianwen/rustdesk-api/v2/http/request/admin"
+	"github.com/lejianwen/rustdesk-api/v2/http/response"
+	"github.com/lejianwen/rustdesk-api/v2/service"
+	"gorm.io/gorm"
+)
+
+type Strategy struct {
+}
+
+// List 策略列表
+// @Tags 设备策略
+// @Summary 设备策略列表
+// @Description 设备策略(连接权限、录制、密码策略), 分配给用户组或套餐后通过心跳下发到设备
+// @Accept  json
+// @Produce  json
+// @Param page query int false "页码"
+// @Param page_size query int false "页大小"
+// @Success 200 {object} response.Response{data=model.StrategyProfileList}
+// @Failure 500 {object} response.Response
+// @Router /admin/strategy/list [get]
+// @Security token
+func (ct *Strategy) List(c *gin.Context) {
+	query := &admin.PageQuery{}
+	if err := c.ShouldBindQuery(query); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	res := service.AllService.StrategyService.ProfileList(query.Page, query.PageSize, func(tx *gorm.DB) {
+		tx.Order("id desc")
+	})
+	response.Success(c, res)
+}
+
+// Create 创建策略
+// @Tags 设备策略
+// @Summary 创建设备策略
+// @Description 创建设备策略
+// @Accept  json
+// @Produce  json
+// @Param body body admin.StrategyProfileForm true "策略"
+// @Success 200 {object} response.Response{data=model.StrategyProfile}
+// @Failure 500 {object} response.Response
+// @Router /admin/strategy/create [post]
+// @Security token
+func (ct *Strategy) Create(c *gin.Context) {
+	f := &admin.StrategyProfileForm{}
+	if err := c.ShouldBindJSON(f); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	errList := global.Validator.ValidStruct(c, f)
+	if len(errList) > 0 {
+		response.Fail(c, 101, errList[0])
+		return
+	}
+	p := f.ToStrategyProfile()
+	p.Id = 0
+	if err := service.AllService.StrategyService.ValidateProfile(p); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, err.Error()))
+		return
+	}
+	if err := service.AllService.StrategyService.CreateProfile(p); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "OperationFailed")+err.Error())
+		return
+	}
+	response.Success(c, p)
+}
+
+// Update 编辑策略
+// @Tags 设备策略
+// @Summary 编辑设备策略
+// @Description 编辑后设备在下次心跳时获取新策略
+// @Accept  json
+// @Produce  json
+// @Param body body admin.StrategyProfileForm true "策略"
+// @Success 200 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /admin/strategy/update [post]
+// @Security token
+func (ct *Strategy) Update(c *gin.Context) {
+	f := &admin.StrategyProfileForm{}
+	if err := c.ShouldBindJSON(f); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	errList := global.Validator.ValidStruct(c, f)
+	if len(errList) > 0 {
+		response.Fail(c, 101, errList[0])
+		return
+	}
+	if f.Id == 0 || service.AllService.StrategyService.ProfileById(f.Id).Id == 0 {
+		response.Fail(c, 101, response.TranslateMsg(c, "ItemNotFound"))
+		return
+	}
+	p := f.ToStrategyProfile()
+	if err := service.AllService.StrategyService.ValidateProfile(p); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, err.Error()))
+		return
+	}
+	if err := service.AllService.StrategyService.UpdateProfile(p); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "OperationFailed")+err.Error())
+		return
+	}
+	response.Success(c, nil)
+}
+
+// Delete 删除策略
+// @Tags 设备策略
+// @Summary 删除设备策略
+// @Description 同时删除该策略的分配, 设备在下次心跳时清除已下发的配置
+// @Accept  json
+// @Produce  json
+// @Param body body admin.StrategyProfileForm true "策略"
+// @Success 200 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /admin/strategy/delete [post]
+// @Security token
+func (ct *Strategy) Delete(c *gin.Context) {
+	f := &admin.StrategyProfileForm{}
+	if err := c.ShouldBindJSON(f); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	errList := global.Validator.ValidVar(c, f.Id, "required,gt=0")
+	if len(errList) > 0 {
+		response.Fail(c, 101, errList[0])
+		return
+	}
+	p := service.AllService.StrategyService.ProfileById(f.Id)
+	if p.Id == 0 {
+		response.Fail(c, 101, response.TranslateMsg(c, "ItemNotFound"))
+		return
+	}
+	if err := service.AllService.StrategyService.DeleteProfile(p); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "OperationFailed")+err.Error())
+		return
+	}
+	response.Success(c, nil)
+}
+
+// Assignments 分配列表
+// @Tags 设备策略
+// @Summary 设备策略分配列表
+// @Description 用户组或套餐的策略分配, 套餐的策略优先于用户组
+// @Accept  json
+// @Produce  json
+// @Param page query int false "页码"
+// @Param page_size query int false "页大小"
+// @Param scope query string false "范围: group/plan"
+// @Param profile_id query int false "策略ID"
+// @Success 200 {object} response.Response{data=model.StrategyAssignmentList}
+// @Failure 500 {object} response.Response
+// @Router /admin/strategy/assignments [get]
+// @Security token
+func (ct *Strategy) Assignments(c *gin.Context) {
+	query := &admin.StrategyAssignmentQuery{}
+	if err := c.ShouldBindQuery(query); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	res := service.AllService.StrategyService.AssignmentList(query.Page, query.PageSize, func(tx *gorm.DB) {
+		if query.Scope != "" {
+			tx.Where("scope = ?", query.Scope)
+		}
+		if query.ProfileId > 0 {
+			tx.Where("profile_id = ?", query.ProfileId)
+		}
+		tx.Order("id desc")
+	})
+	response.Success(c, res)
+}
+
+// Assign 分配策略
+// @Tags 设备策略
+// @Summary 分配设备策略
+// @Description 为用户组或套餐分配策略, 已有分配时替换
+// @Accept  json
+// @Produce  json
+// @Param body body admin.StrategyAssignForm true "分配"
+// @Success 200 {object} response.Response{data=model.StrategyAssignment}
+// @Failure 500 {object} response.Response
+// @Router /admin/strategy/assign [post]
+// @Security token
+func (ct *Strategy) Assign(c *gin.Context) {
+	f := &admin.StrategyAssignForm{}
+	if err := c.ShouldBindJSON(f); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	errList := global.Validator.ValidStruct(c, f)
+	if len(errList) > 0 {
+		response.Fail(c, 101, errList[0])
+		return
+	}
+	a, err := service.AllService.StrategyService.Assign(f.Scope, f.ScopeId, f.ProfileId)
+	if err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, err.Error()))
+		return
+	}
+	response.Success(c, a)
+}
+
+// Unassign 取消分配
+// @Tags 设备策略
+// @Summary 取消设备策略分配
+// @Description 取消后设备在下次心跳时清除已下发的配置
+// @Accept  json
+// @Produce  json
+// @Param body body admin.StrategyUnassignForm true "分配"
+// @Success 200 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /admin/strategy/unassign [post]
+// @Security token
+func (ct *Strategy) Unassign(c *gin.Context) {
+	f := &admin.StrategyUnassignForm{}
+	if err := c.ShouldBindJSON(f); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	errList := global.Validator.ValidVar(c, f.Id, "required,gt=0")
+	if len(errList) > 0 {
+		response.Fail(c, 101, errList[0])
+		return
+	}
+	if err := service.AllService.StrategyService.Unassign(f.Id); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, err.Error()))
+		return
+	}
+	response.Success(c, nil)
+}
@@ -217,7 +217,7 @@ func (ct *User) Current(c *gin.Context) {
 	u := service.AllService.UserService.CurUser(c)
 	token, _ := c.Get("token")
 	t := token.(string)
-	responseLoginSuccess(c, u, t)
+	responseLoginSuccess(c, u, &model.UserToken{Token: t})
 }
 
 // ChangeCurPwd 修改当前用户密码
@@ -331,6 +331,7 @@ func (ct *User) Register(c *gin.Context) {
 		response.Fail(c, 101, response.TranslateMsg(c, "OperationFailed"))
 		return
 	}
+	service.AllService.ReferralService.Attribute(u.Id, f.ReferralCode)
 	if regStatus == model.COMMON_STATUS_DISABLED {
 		// 需要管理员审核
 		response.Fail(c, 101, response.TranslateMsg(c, "RegisterSuccessWaitAdminConfirm"))
@@ -344,5 +345,5 @@ func (ct *User) Register(c *gin.Context) {
 		Ip:     c.ClientIP(),
 		Type:   model.LoginLogTypeAccount,
 	})
-	responseLoginSuccess(c, u, ut.Token)
+	responseLoginSuccess(c, u, ut)
 }
@@ -82,6 +82,63 @@ func (ct *UserToken) Delete(c *gin.Context) {
 	response.Fail(c, 101, response.TranslateMsg(c, "ItemNotFound"))
 }
 
+// Sessions 活跃会话列表
+// @Tags 登录凭证
+// @Summary 活跃会话列表
+// @Description 未过期的登录会话(设备、IP、最近活动时间)
+// @Accept  json
+// @Produce  json
+// @Param page query int false "页码"
+// @Param page_size query int false "页大小"
+// @Param user_id query int false "用户ID"
+// @Success 200 {object} response.Response{data=model.UserTokenList}
+// @Failure 500 {object} response.Response
+// @Router /admin/user_token/sessions [get]
+// @Security token
+func (ct *UserToken) Sessions(c *gin.Context) {
+	query := &admin.LoginTokenQuery{}
+	if err := c.ShouldBindQuery(query); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	res := service.AllService.UserService.ActiveSessionList(query.Page, query.PageSize, func(tx *gorm.DB) {
+		if query.UserId > 0 {
+			tx.Where("user_id = ?", query.UserId)
+		}
+		tx.Order("last_active_at desc")
+	})
+	response.Success(c, res)
+}
+
+// RevokeUser 撤销用户的全部会话
+// @Tags 登录凭证
+// @Summary 撤销用户全部会话
+// @Description 删除指定用户的所有登录凭证, 用户需重新登录
+// @Accept  json
+// @Produce  json
+// @Param body body UserIdForm true "用户ID"
+// @Success 200 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /admin/user_token/revokeUser [post]
+// @Security token
+func (ct *UserToken) RevokeUser(c *gin.Context) {
+	f := &UserIdForm{}
+	if err := c.ShouldBindJSON(f); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	u := service.AllService.UserService.InfoById(f.UserId)
+	if u.Id == 0 {
+		response.Fail(c, 101, response.TranslateMsg(c, "ItemNotFound"))
+		return
+	}
+	if err := service.AllService.UserService.FlushToken(u); err != nil {
+		response.Fail(c, 101, err.Error())
+		return
+	}
+	response.Success(c, nil)
+}
+
 // BatchDelete 批量删除
 // @Tags 登录凭证
 // @Summary 登录凭证批量删除
@@ -0,0 +1,271 @@
+package admin
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/lejianwen/rustdesk-api/v2/global"
+	"github.com/lejianwen/rustdesk-api/v2/http/request/admin"
+	"github.com/lejianwen/rustdesk-api/v2/http/response"
+	"github.com/lejianwen/rustdesk-api/v2/service"
+	"gorm.io/gorm"
+)
+
+type Webhook struct {
+}
+
+// List 列表
+// @Tags Webhook
+// @Summary Webhook地址列表
+// @Description Webhook地址列表
+// @Accept  json
+// @Produce  json
+// @Param page query int false "页码"
+// @Param page_size query int false "页大小"
+// @Success 200 {object} response.Response{data=model.WebhookEndpointList}
+// @Failure 500 {object} response.Response
+// @Router /admin/webhook/list [get]
+// @Security token
+func (ct *Webhook) List(c *gin.Context) {
+	query := &admin.WebhookQuery{}
+	if err := c.ShouldBindQuery(query); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	res := service.AllService.WebhookService.List(query.Page, query.PageSize, func(tx *gorm.DB) {
+		tx.Order("id desc")
+	})
+	response.Success(c, res)
+}
+
+// Create 创建
+// @Tags Webhook
+// @Summary 创建Webhook地址
+// @Description 创建Webhook地址, 未填写密钥时自动生成
+// @Accept  json
+// @Produce  json
+// @Param body body admin.WebhookForm true "Webhook信息"
+// @Success 200 {object} response.Response{data=model.WebhookEndpoint}
+// @Failure 500 {object} response.Response
+// @Router /admin/webhook/create [post]
+// @Security token
+func (ct *Webhook) Create(c *gin.Context) {
+	f := &admin.WebhookForm{}
+	if err := c.ShouldBindJSON(f); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	errList := global.Validator.ValidStruct(c, f)
+	if len(errList) > 0 {
+		response.Fail(c, 101, errList[0])
+		return
+	}
+	e := f.ToWebhookEndpoint()
+	e.Id = 0
+	if err := service.AllService.WebhookService.Create(e); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "OperationFailed")+err.Error())
+		return
+	}
+	response.Success(c, e)
+}
+
+// Update 编辑
+// @Tags Webhook
+// @Summary 编辑Webhook地址
+// @Description 编辑Webhook地址, 密钥为空时保留原密钥
+// @Accept  json
+// @Produce  json
+// @Param body body admin.WebhookForm true "Webhook信息"
+// @Success 200 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /admin/webhook/update [post]
+// @Security token
+func (ct *Webhook) Update(c *gin.Context) {
+	f := &admin.WebhookForm{}
+	if err := c.ShouldBindJSON(f); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	errList := global.Validator.ValidStruct(c, f)
+	if len(errList) > 0 {
+		response.Fail(c, 101, errList[0])
+		return
+	}
+	if f.Id == 0 {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError"))
+		return
+	}
+	ex := service.AllService.WebhookService.InfoById(f.Id)
+	if ex.Id == 0 {
+		response.Fail(c, 101, response.TranslateMsg(c, "ItemNotFound"))
+		return
+	}
+	if err := service.AllService.WebhookService.Update(f.ToWebhookEndpoint()); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "OperationFailed")+err.Error())
+		return
+	}
+	response.Success(c, nil)
+}
+
+// Delete 删除
+// @Tags Webhook
+// @Summary 删除Webhook地址
+// @Description 删除Webhook地址
+// @Accept  json
+// @Produce  json
+// @Param body body admin.WebhookForm true "Webhook信息"
+// @Success 200 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /admin/webhook/delete [post]
+// @Security token
+func (ct *Webhook) Delete(c *gin.Context) {
+	f := &admin.WebhookForm{}
+	if err := c.ShouldBindJSON(f); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	errList := global.Validator.ValidVar(c, f.Id, "required,gt=0")
+	if len(errList) > 0 {
+		response.Fail(c, 101, errList[0])
+		return
+	}
+	ex := service.AllService.WebhookService.InfoById(f.Id)
+	if ex.Id == 0 {
+		response.Fail(c, 101, response.TranslateMsg(c, "ItemNotFound"))
+		return
+	}
+	if err := service.AllService.WebhookService.Delete(ex); err != nil {
+		response.Fail(c, 101, err.Error())
+		return
+	}
+	response.Success(c, nil)
+}
+
+// Deliveries 投递记录
+// @Tags Webhook
+// @Summary Webhook投递记录
+// @Description Webhook投递记录
+// @Accept  json
+// @Produce  json
+// @Param page query int false "页码"
+// @Param page_size query int false "页大小"
+// @Param endpoint_id query int false "地址ID"
+// @Param event query string false "事件"
+// @Param status query int false "状态: 0待投递 1成功 2失败"
+// @Success 200 {object} response.Response{data=model.WebhookDeliveryList}
+// @Failure 500 {object} response.Response
+// @Router /admin/webhook/deliveries [get]
+// @Security token
+func (ct *Webhook) Deliveries(c *gin.Context) {
+	query := &admin.WebhookDeliveryQuery{}
+	if err := c.ShouldBindQuery(query); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	res := service.AllService.WebhookService.DeliveryList(query.Page, query.PageSize, func(tx *gorm.DB) {
+		if query.EndpointId > 0 {
+			tx.Where("endpoint_id = ?", query.EndpointId)
+		}
+		if query.Event != "" {
+			tx.Where("event = ?", query.Event)
+		}
+		if query.Status != nil {
+			tx.Where("status = ?", *query.Status)
+		}
+		tx.Order("id desc")
+	})
+	response.Success(c, res)
+}
+
+// Redeliver 重新投递
+// @Tags Webhook
+// @Summary 重新投递
+// @Description 按投递记录重新投递一次并返回新记录, 事件ID不变, 失败时按正常节奏重试
+// @Accept  json
+// @Produce  json
+// @Param body body admin.WebhookRedeliverForm true "投递记录"
+// @Success 200 {object} response.Response{data=model.WebhookDelivery}
+// @Failure 500 {object} response.Response
+// @Router /admin/webhook/redeliver [post]
+// @Security token
+func (ct *Webhook) Redeliver(c *gin.Context) {
+	f := &admin.WebhookRedeliverForm{}
+	if err := c.ShouldBindJSON(f); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	errList := global.Validator.ValidStruct(c, f)
+	if len(errList) > 0 {
+		response.Fail(c, 101, errList[0])
+		return
+	}
+	d, err := service.AllService.WebhookService.Redeliver(f.Id)
+	if err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, err.Error()))
+		return
+	}
+	response.Success(c, d)
+}
+
+// Requeue 失败记录重新排队
+// @Tags Webhook
+// @Summary 失败记录重新排队
+// @Description 将地址在指定时间之后重试耗尽的投递重新排队, 由重试任务补发, 用于接收方故障恢复后
+// @Accept  json
+// @Produce  json
+// @Param body body admin.WebhookRequeueForm true "地址和起始时间"
+// @Success 200 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /admin/webhook/requeue [post]
+// @Security token
+func (ct *Webhook) Requeue(c *gin.Context) {
+	f := &admin.WebhookRequeueForm{}
+	if err := c.ShouldBindJSON(f); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	errList := global.Validator.ValidStruct(c, f)
+	if len(errList) > 0 {
+		response.Fail(c, 101, errList[0])
+		return
+	}
+	n, err := service.AllService.WebhookService.RequeueFailed(f.EndpointId, f.Since)
+	if err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, err.Error()))
+		return
+	}
+	response.Success(c, gin.H{"count": n})
+}
+
+// Test 发送测试事件
+// @Tags Webhook
+// @Summary 发送测试事件
+// @Description 向地址同步投递一条示例事件并返回投递记录, 不重试, 事件ID以 test_ 开头
+// @Accept  json
+// @Produce  json
+// @Param body body admin.WebhookTestForm true "地址和事件"
+// @Success 200 {object} response.Response{data=model.WebhookDelivery}
+// @Failure 500 {object} response.Response
+// @Router /admin/webhook/test [post]
+// @Security token
+func (ct *Webhook) Test(c *gin.Context) {
+	f := &admin.WebhookTestForm{}
+	if err := c.ShouldBindJSON(f); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	errList := global.Validator.ValidStruct(c, f)
+	if len(errList) > 0 {
+		response.Fail(c, 101, errList[0])
+		return
+	}
+	e := service.AllService.WebhookService.InfoById(f.EndpointId)
+	if e.Id == 0 {
+		response.Fail(c, 101, response.TranslateMsg(c, "ItemNotFound"))
+		return
+	}
+	d, err := service.AllService.WebhookService.SendTest(e, f.Event)
+	if err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, err.Error()))
+		return
+	}
+	response.Success(c, d)
+}
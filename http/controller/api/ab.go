@@ -440,6 +440,31 @@ func (a *Ab) SharedProfiles(c *gin.Context) {
 		})
 	}
 
+	//团队地址簿, 已通过规则共享的不重复列出
+	teams := service.AllService.AddressBookService.TeamCollectionsForUser(user)
+	if len(teams) > 0 {
+		org := service.AllService.SubscriptionService.GetUserOrganization(user.Id)
+		owner := org.Name
+		if owner == "" && org.Owner != nil {
+			owner = org.Owner.Username
+		}
+		for _, collection := range teams {
+			if _, ok := allAbIds[collection.Id]; ok {
+				continue
+			}
+			rule := service.AllService.AddressBookService.UserMaxRule(user, collection.UserId, collection.Id)
+			if rule == 0 || org.Owner == nil {
+				continue
+			}
+			res = append(res, &api.SharedProfilesPayload{
+				Guid:  a.ComposeGuid(org.Owner.GroupId, org.Owner.Id, collection.Id),
+				Name:  collection.Name,
+				Owner: owner,
+				Rule:  rule,
+			})
+		}
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"total": 0, //len(res),
 		"data":  res,
@@ -604,7 +629,7 @@ func (a *Ab) PeerAdd(c *gin.Context) {
 		}
 	}
 
-	err = service.AllService.AddressBookService.AddAddressBook(ab)
+	err = service.AllService.AddressBookService.MergeAddressBook(ab)
 	if err != nil {
 		response.Error(c, response.TranslateMsg(c, "OperationFailed")+err.Error())
 		return
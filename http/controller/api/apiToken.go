@@ -0,0 +1,79 @@
+package api
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lejianwen/rustdesk-api/v2/http/response"
+	"github.com/lejianwen/rustdesk-api/v2/service"
+)
+
+type ApiToken struct {
+}
+
+type ApiTokenForm struct {
+	Name       string   `json:"name" binding:"required,max=64"`
+	Scopes     []string `json:"scopes" binding:"required"`
+	ExpireDays int      `json:"expire_days"` // 有效天数, 0 表示不过期
+}
+
+// List 个人访问令牌列表
+// @Tags 用户
+// @Summary 个人访问令牌列表
+// @Description 当前用户的个人访问令牌, 不包含明文
+// @Produce  json
+// @Success 200 {object} response.Response{data=[]model.ApiToken}
+// @Router /api/user/tokens [get]
+// @Security BearerAuth
+func (t *ApiToken) List(c *gin.Context) {
+	user := service.AllService.UserService.CurUser(c)
+	response.Success(c, service.AllService.ApiTokenService.ListByUser(user.Id))
+}
+
+// Create 创建个人访问令牌
+// @Tags 用户
+// @Summary 创建个人访问令牌
+// @Description 权限范围可选 read:peers、write:addressbook、read:billing; 明文令牌只在此返回一次, 通过 Authorization: Bearer 使用
+// @Accept  json
+// @Produce  json
+// @Param body body ApiTokenForm true "令牌信息"
+// @Success 200 {object} response.Response
+// @Router /api/user/tokens [post]
+// @Security BearerAuth
+func (t *ApiToken) Create(c *gin.Context) {
+	user := service.AllService.UserService.CurUser(c)
+	f := &ApiTokenForm{}
+	if err := c.ShouldBindJSON(f); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	plain, at, err := service.AllService.ApiTokenService.Create(user.Id, f.Name, f.Scopes, f.ExpireDays)
+	if err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, err.Error()))
+		return
+	}
+	response.Success(c, gin.H{"token": plain, "info": at})
+}
+
+// Delete 撤销个人访问令牌
+// @Tags 用户
+// @Summary 撤销个人访问令牌
+// @Produce  json
+// @Param id path int true "令牌ID"
+// @Success 200 {object} response.Response
+// @Router /api/user/tokens/{id} [delete]
+// @Security BearerAuth
+func (t *ApiToken) Delete(c *gin.Context) {
+	user := service.AllService.UserService.CurUser(c)
+	id, _ := strconv.Atoi(c.Param("id"))
+	at := service.AllService.ApiTokenService.InfoById(uint(id))
+	if at.Id == 0 || at.UserId != user.Id {
+		response.Fail(c, 101, response.TranslateMsg(c, "ItemNotFound"))
+		return
+	}
+	if err := service.AllService.ApiTokenService.Delete(at); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "OperationFailed")+err.Error())
+		return
+	}
+	response.Success(c, nil)
+}
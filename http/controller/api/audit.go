@@ -37,6 +37,7 @@ func (a *Audit) AuditConn(c *gin.Context) {
 	if af.Action == model.AuditActionNew {
 		service.AllService.AuditService.CreateAuditConn(ac)
 	} else if af.Action == model.AuditActionClose {
+		service.AllService.AuditService.CloseClientConnection(af.Id, af.ConnId)
 		ex := service.AllService.AuditService.InfoByPeerIdAndConnId(af.Id, af.ConnId)
 		if ex.Id != 0 {
 			ex.CloseTime = time.Now().Unix()
@@ -53,6 +54,9 @@ func (a *Audit) AuditConn(c *gin.Context) {
 				Type:      ac.Type,
 			}
 			service.AllService.AuditService.UpdateAuditConn(up)
+			// 来源设备在连接建立后才上报, 此时记录连接审计
+			ex.FromPeer, ex.Type = ac.FromPeer, ac.Type
+			service.AllService.AuditService.RecordClientConnection(ex)
 		}
 	}
 	response.Success(c, "")
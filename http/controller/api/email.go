@@ -0,0 +1,134 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lejianwen/rustdesk-api/v2/http/response"
+	"github.com/lejianwen/rustdesk-api/v2/model"
+	"github.com/lejianwen/rustdesk-api/v2/service"
+)
+
+type Email struct {
+}
+
+type EmailChangeForm struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type EmailConfirmForm struct {
+	Token string `json:"token" form:"token" binding:"required"`
+}
+
+// SendVerification 发送验证邮件
+// @Tags Email
+// @Summary 发送邮箱验证邮件
+// @Description 向当前用户的邮箱发送验证链接和验证码, 60 秒内只能发送一次
+// @Produce  json
+// @Success 200 {object} response.Response
+// @Failure 500 {object} response.ErrorResponse
+// @Router /api/email/verification/send [post]
+// @Security BearerAuth
+func (e *Email) SendVerification(c *gin.Context) {
+	user := service.AllService.UserService.CurUser(c)
+	if err := service.AllService.EmailVerificationService.SendVerification(user); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, err.Error()))
+		return
+	}
+	response.Success(c, nil)
+}
+
+// Change 申请更换邮箱
+// @Tags Email
+// @Summary 申请更换邮箱
+// @Description 向新邮箱发送确认邮件; 当前邮箱已验证时同时向旧邮箱发送确认邮件, 两者都确认后才生效
+// @Accept  json
+// @Produce  json
+// @Param body body EmailChangeForm true "新邮箱"
+// @Success 200 {object} response.Response
+// @Failure 500 {object} response.ErrorResponse
+// @Router /api/email/change [post]
+// @Security BearerAuth
+func (e *Email) Change(c *gin.Context) {
+	f := &EmailChangeForm{}
+	if err := c.ShouldBindJSON(f); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	user := service.AllService.UserService.CurUser(c)
+	requireOld, err := service.AllService.EmailVerificationService.RequestChange(user, f.Email)
+	if err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, err.Error()))
+		return
+	}
+	response.Success(c, gin.H{"require_old": requireOld})
+}
+
+// ConfirmPage 邮件中的确认链接, 只展示确认按钮, 由用户提交后才确认
+// @Tags Email
+// @Summary 邮箱确认页面
+// @Produce  html
+// @Param token query string true "令牌"
+// @Router /api/email/confirm [get]
+func (e *Email) ConfirmPage(c *gin.Context) {
+	token := c.Query("token")
+	if token == "" {
+		e.renderResult(c, "EmailTokenInvalid")
+		return
+	}
+	c.HTML(http.StatusOK, "email_confirm.html", gin.H{
+		"title":  response.TranslateMsg(c, "EmailConfirmTitle"),
+		"token":  token,
+		"button": response.TranslateMsg(c, "Confirm"),
+	})
+}
+
+// Confirm 提交令牌完成确认, 表单提交返回页面, 其他请求返回 JSON
+// @Tags Email
+// @Summary 确认邮箱
+// @Accept  json
+// @Produce  json
+// @Param body body EmailConfirmForm true "令牌"
+// @Success 200 {object} response.Response
+// @Failure 500 {object} response.ErrorResponse
+// @Router /api/email/confirm [post]
+func (e *Email) Confirm(c *gin.Context) {
+	isForm := c.ContentType() == "application/x-www-form-urlencoded"
+	f := &EmailConfirmForm{}
+	if err := c.ShouldBind(f); err != nil {
+		if isForm {
+			e.renderResult(c, "EmailTokenInvalid")
+			return
+		}
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	purpose, done, err := service.AllService.EmailVerificationService.Confirm(f.Token)
+	msg := ""
+	switch {
+	case err != nil:
+		msg = err.Error()
+	case !done:
+		msg = "EmailChangeWaiting"
+	case purpose == model.EmailTokenVerify:
+		msg = "EmailVerified"
+	default:
+		msg = "EmailChanged"
+	}
+	if isForm {
+		e.renderResult(c, msg)
+		return
+	}
+	if err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, msg))
+		return
+	}
+	response.Success(c, gin.H{"purpose": purpose, "done": done, "message": response.TranslateMsg(c, msg)})
+}
+
+func (e *Email) renderResult(c *gin.Context, msg string) {
+	c.HTML(http.StatusOK, "email_confirm.html", gin.H{
+		"title":   response.TranslateMsg(c, "EmailConfirmTitle"),
+		"message": response.TranslateMsg(c, msg),
+	})
+}
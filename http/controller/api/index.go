@@ -54,12 +54,82 @@ func (i *Index) Heartbeat(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{})
 		return
 	}
+	service.AllService.PushService.PeerSeen(peer)
 	//如果在40s以内则不更新
 	if time.Now().Unix()-peer.LastOnlineTime >= 30 {
 		upp := &model.Peer{RowId: peer.RowId, LastOnlineTime: time.Now().Unix(), LastOnlineIp: c.ClientIP()}
+		if upp.LastOnlineIp != peer.LastOnlineIp || peer.Country == "" {
+			loc := service.AllService.GeoIpService.Locate(upp.LastOnlineIp)
+			upp.Country, upp.City = loc.Country, loc.City
+		}
 		service.AllService.PeerService.Update(upp)
 	}
-	c.JSON(http.StatusOK, gin.H{})
+	c.JSON(http.StatusOK, heartbeatStrategy(peer.UserId, info.ModifiedAt))
+}
+
+// heartbeatStrategy 设备归属用户的策略有变更时随心跳下发, 客户端写入本地配置并记录 modified_at
+// 策略被取消时下发空值清除之前写入的配置
+func heartbeatStrategy(userId uint, clientModifiedAt int64) gin.H {
+	profile, modifiedAt := service.AllService.StrategyService.ForUser(userId)
+	if modifiedAt == clientModifiedAt {
+		return gin.H{}
+	}
+	if profile == nil {
+		profile = &model.StrategyProfile{}
+	}
+	return gin.H{
+		"modified_at": modifiedAt,
+		"strategy": gin.H{
+			"config_options": profile.ConfigOptions(),
+			"extra":          gin.H{},
+		},
+	}
+}
+
+// Health 健康检查
+// @Tags 首页
+// @Summary 健康检查
+// @Description 服务与数据库状态, 包含数据库类型
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} nil
+// @Failure 503 {object} nil
+// @Router /health [get]
+func (i *Index) Health(c *gin.Context) {
+	dbOk := false
+	if sqlDB, err := service.DB.DB(); err == nil {
+		dbOk = sqlDB.Ping() == nil
+	}
+	status := http.StatusOK
+	if !dbOk {
+		status = http.StatusServiceUnavailable
+	}
+	c.JSON(status, gin.H{
+		"status": map[bool]string{true: "ok", false: "error"}[dbOk],
+		"db": gin.H{
+			"dialect": service.DB.Dialector.Name(),
+			"ok":      dbOk,
+		},
+	})
+}
+
+// Status 状态页
+// @Tags 首页
+// @Summary 状态页
+// @Description 粗粒度的组件状态(API、数据库、支付网关)和relay白名单数量, 不含敏感信息, 结果缓存15秒; 数据库不可用时返回503
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} service.SystemStatus
+// @Failure 503 {object} service.SystemStatus
+// @Router /status [get]
+func (i *Index) Status(c *gin.Context) {
+	s := service.AllService.StatusService.Status()
+	code := http.StatusOK
+	if s.Status == service.StatusDown {
+		code = http.StatusServiceUnavailable
+	}
+	c.Header("Cache-Control", "public, max-age=15")
+	c.JSON(code, s)
 }
 
 // Version 版本
@@ -1,8 +1,14 @@
 package api
 
 import (
+	"fmt"
+	"net/http"
+	"time"
+
 	"github.com/gin-gonic/gin"
 	"github.com/lejianwen/rustdesk-api/v2/http/response"
+	"github.com/lejianwen/rustdesk-api/v2/lib/metrics"
+	"github.com/lejianwen/rustdesk-api/v2/model"
 	"github.com/lejianwen/rustdesk-api/v2/service"
 )
 
@@ -23,11 +29,13 @@ type RelayAllowRequest struct {
 	UUID   string `json:"uuid" binding:"required"`
 	Slots  int    `json:"slots"`   // 默认 2，最大 10
 	TTLSec int    `json:"ttl_sec"` // 默认 120，最大 300
+	PeerId string `json:"peer_id"` // 可选，发起端设备ID，用于识别发起用户(异常检测、relay 配额)
 }
 
 // RelayConsumeRequest relay 白名单消费请求
 type RelayConsumeRequest struct {
 	UUID string `json:"uuid" binding:"required"`
+	Ip   string `json:"ip"` // 可选，连接到 hbbr 的客户端IP，用于地区限制
 }
 
 // SubscriptionCheckRequest 订阅检查请求 (支持 POST body)
@@ -36,6 +44,65 @@ type SubscriptionCheckRequest struct {
 	UUID  string `json:"uuid"`
 }
 
+// RelaySessionStartRequest relay 会话开始上报
+type RelaySessionStartRequest struct {
+	UUID         string `json:"uuid" binding:"required"`
+	PeerId       string `json:"peer_id"`        // 发起端设备ID
+	TargetPeerId string `json:"target_peer_id"` // 被控端设备ID
+	StartedAt    int64  `json:"started_at"`     // unix 秒, 为空时取当前时间
+}
+
+// RelaySessionEndRequest relay 会话结束上报
+type RelaySessionEndRequest struct {
+	UUID         string `json:"uuid" binding:"required"`
+	PeerId       string `json:"peer_id"`
+	TargetPeerId string `json:"target_peer_id"`
+	EndedAt      int64  `json:"ended_at"`   // unix 秒, 为空时取当前时间
+	Duration     int64  `json:"duration"`   // 时长(秒)
+	BytesUp      int64  `json:"bytes_up"`   // 发起端 -> 被控端
+	BytesDown    int64  `json:"bytes_down"` // 被控端 -> 发起端
+}
+
+// SessionCheckRequest 并发会话检查请求
+type SessionCheckRequest struct {
+	PeerId string `json:"peer_id" binding:"required"` // 发起端设备ID
+}
+
+// PeerResolveRequest 设备解析请求
+type PeerResolveRequest struct {
+	Id   string `json:"id"`
+	UUID string `json:"uuid"`
+}
+
+// FileTransferRequest 文件传输上报, 一次传输任务可包含多个文件
+type FileTransferRequest struct {
+	PeerId       string             `json:"peer_id" binding:"required"`        // 发起端设备ID
+	TargetPeerId string             `json:"target_peer_id" binding:"required"` // 被控端设备ID
+	Direction    string             `json:"direction" binding:"required"`      // upload: 发起端 -> 被控端, download: 被控端 -> 发起端
+	Ip           string             `json:"ip"`                                // 发起端IP
+	At           int64              `json:"at"`                                // unix 秒, 为空时取当前时间
+	Files        []FileTransferFile `json:"files" binding:"required"`
+}
+
+// RecordingRegisterRequest 会话录像登记, storage_key 与 url 至少填写一个
+type RecordingRegisterRequest struct {
+	SessionId    string `json:"session_id" binding:"required"` // relay uuid 或连接ID
+	StorageKey   string `json:"storage_key"`                   // 已写入本服务存储的 key
+	Url          string `json:"url"`                           // 录制组件自行托管的地址
+	PeerId       string `json:"peer_id"`
+	TargetPeerId string `json:"target_peer_id"`
+	Size         int64  `json:"size"`        // 字节
+	Duration     int64  `json:"duration"`    // 秒
+	Checksum     string `json:"checksum"`    // sha256 十六进制
+	RecordedAt   int64  `json:"recorded_at"` // unix 秒, 为空时取当前时间
+}
+
+type FileTransferFile struct {
+	Path  string `json:"path"`
+	Size  int64  `json:"size"` // 字节
+	IsDir bool   `json:"is_dir"`
+}
+
 // RelayAllow 写入 relay 白名单
 // @Tags Internal
 // @Summary 写入 relay 白名单
@@ -76,10 +143,50 @@ func (i *Internal) RelayAllow(c *gin.Context) {
 		req.TTLSec = MaxTTLSec
 	}
 
-	service.AllService.RelayWhitelistService.Allow(req.UUID, req.Slots, req.TTLSec)
+	// 被封禁的设备不写入白名单
+	if service.AllService.PeerBanService.Banned(req.PeerId, "") != nil {
+		service.MetricRelayAllow.Inc("peer_banned")
+		response.Success(c, gin.H{
+			"uuid":    req.UUID,
+			"allowed": false,
+			"reason":  "peer_banned",
+		})
+		return
+	}
+
+	var userId uint
+	if req.PeerId != "" && len(req.PeerId) <= MaxUUIDLength {
+		uid, ok := i.peerOwner(c, req.PeerId)
+		if !ok {
+			return
+		}
+		userId = uid
+		if !service.AllService.LoadShedService.Overloaded(c) {
+			service.AllService.RelayWhitelist().RecordAllow(userId, req.PeerId, req.UUID)
+		}
+	}
+
+	// relay 配额已用尽时不写入白名单, hbbr 消费时将被拒绝
+	exceeded, ok := i.quotaExceeded(c, userId)
+	if !ok {
+		return
+	}
+	if exceeded {
+		service.MetricRelayAllow.Inc("quota_exceeded")
+		response.Success(c, gin.H{
+			"uuid":    req.UUID,
+			"allowed": false,
+			"reason":  "relay_quota_exceeded",
+		})
+		return
+	}
+
+	service.AllService.RelayWhitelist().AllowFor(req.UUID, req.Slots, req.TTLSec, userId)
+	service.MetricRelayAllow.Inc("allowed")
 
 	response.Success(c, gin.H{
 		"uuid":    req.UUID,
+		"allowed": true,
 		"slots":   req.Slots,
 		"ttl_sec": req.TTLSec,
 	})
@@ -88,7 +195,7 @@ func (i *Internal) RelayAllow(c *gin.Context) {
 // RelayConsume 消费 relay 白名单
 // @Tags Internal
 // @Summary 消费 relay 白名单
-// @Description hbbr 调用，验证并消费指定 uuid 的白名单额度
+// @Description hbbr 调用，验证并消费指定 uuid 的白名单额度; 传入 ip 时按发起用户的用户组/套餐地区限制检查
 // @Accept json
 // @Produce json
 // @Param request body RelayConsumeRequest true "请求参数"
@@ -112,7 +219,42 @@ func (i *Internal) RelayConsume(c *gin.Context) {
 		return
 	}
 
-	allowed := service.AllService.RelayWhitelistService.Consume(req.UUID)
+	// 发起用户 relay 配额已用尽时拒绝, 不扣减次数
+	exceeded, ok := i.quotaExceeded(c, service.AllService.RelayWhitelist().Owner(req.UUID))
+	if !ok {
+		return
+	}
+	if exceeded {
+		service.MetricRelayConsume.Inc("quota_exceeded")
+		response.Success(c, gin.H{
+			"uuid":    req.UUID,
+			"allowed": false,
+			"reason":  "relay_quota_exceeded",
+		})
+		return
+	}
+
+	// 不满足地区限制时拒绝, 不扣减次数
+	geoAllowed, ok := i.geoAllowed(c, service.AllService.RelayWhitelist().Owner(req.UUID), req.UUID, req.Ip)
+	if !ok {
+		return
+	}
+	if !geoAllowed {
+		service.MetricRelayConsume.Inc("geo_restricted")
+		response.Success(c, gin.H{
+			"uuid":    req.UUID,
+			"allowed": false,
+			"reason":  "geo_restricted",
+		})
+		return
+	}
+
+	allowed := service.AllService.RelayWhitelist().Consume(req.UUID)
+	if allowed {
+		service.MetricRelayConsume.Inc("hit")
+	} else {
+		service.MetricRelayConsume.Inc("miss")
+	}
 
 	response.Success(c, gin.H{
 		"uuid":    req.UUID,
@@ -163,16 +305,18 @@ func (i *Internal) SubscriptionCheck(c *gin.Context) {
 		}
 	}
 
-	// 如果 token 无效，尝试通过 uuid 获取 user_id
-	if userId == 0 && uuid != "" {
-		peer := service.AllService.PeerService.FindByUuid(uuid)
-		if peer.RowId > 0 {
-			userId = peer.UserId
-		}
+	// 被封禁的设备不论订阅状态和支付开关均拒绝
+	if service.AllService.PeerBanService.Banned("", uuid) != nil {
+		response.Success(c, gin.H{
+			"active":          false,
+			"payment_enabled": service.AllService.Payment().IsEnabled(),
+			"reason":          "peer_banned",
+		})
+		return
 	}
 
 	// 检查支付功能是否启用
-	paymentEnabled := service.AllService.PaymentService.IsEnabled()
+	paymentEnabled := service.AllService.Payment().IsEnabled()
 
 	// 如果支付未启用，直接放行
 	if !paymentEnabled {
@@ -184,9 +328,34 @@ func (i *Internal) SubscriptionCheck(c *gin.Context) {
 		return
 	}
 
+	// 过载时使用最近的决策
+	decisionKey := "sub:uuid:" + uuid
+	if userId > 0 {
+		decisionKey = fmt.Sprintf("sub:user:%d:%s", userId, uuid)
+	}
+	ls := service.AllService.LoadShedService
+	if ls.Overloaded(c) {
+		if res, ok := i.recall(c, decisionKey); ok {
+			response.Success(c, res)
+		}
+		return
+	}
+	reply := func(res gin.H) {
+		ls.Remember(decisionKey, res)
+		response.Success(c, res)
+	}
+
+	// 如果 token 无效，尝试通过 uuid 获取 user_id
+	if userId == 0 && uuid != "" {
+		peer := service.AllService.PeerService.FindByUuid(uuid)
+		if peer.RowId > 0 {
+			userId = peer.UserId
+		}
+	}
+
 	// 无法识别用户
 	if userId == 0 {
-		response.Success(c, gin.H{
+		reply(gin.H{
 			"active":          false,
 			"payment_enabled": true,
 			"reason":          "user_not_found",
@@ -195,13 +364,160 @@ func (i *Internal) SubscriptionCheck(c *gin.Context) {
 	}
 
 	// 检查订阅状态
-	active := service.AllService.SubscriptionService.IsSubscriptionActive(userId)
+	start := time.Now()
+	active := service.AllService.Subscription().IsSubscriptionActive(userId)
+	// 按设备席位授权的套餐只允许已分配席位的设备
+	seatDenied := active && !service.AllService.SubscriptionService.SeatAllowed(userId, uuid)
+	if seatDenied {
+		active = false
+	}
+	service.ObserveSubscriptionCheck("internal", active, start)
 
-	response.Success(c, gin.H{
+	// 维护窗口期间放行，由调用方决定是否提示
+	if !active {
+		if m := service.AllService.MaintenanceService.Relax("subscription check", userId); m != nil {
+			reply(gin.H{
+				"active":           true,
+				"payment_enabled":  true,
+				"user_id":          userId,
+				"reason":           "maintenance",
+				"maintenance_mode": m.Mode,
+				"maintenance_end":  m.EndAt,
+			})
+			return
+		}
+	}
+
+	res := gin.H{
 		"active":          active,
 		"payment_enabled": true,
 		"user_id":         userId,
-	})
+	}
+	if seatDenied {
+		res["reason"] = "seat_not_assigned"
+	}
+	if tier := service.AllService.EntitlementService.RelayTier(userId); tier != nil {
+		res["relay_tier"] = tier.Tier
+	}
+	reply(res)
+}
+
+// recall 过载时的最近决策, 没有时返回 503 和 Retry-After
+func (i *Internal) recall(c *gin.Context, key string) (gin.H, bool) {
+	ls := service.AllService.LoadShedService
+	if res, ok := ls.Recall(key); ok {
+		service.MetricInternalShed.Inc(c.FullPath(), "cached")
+		return res, true
+	}
+	service.MetricInternalShed.Inc(c.FullPath(), "rejected")
+	response.Overloaded(c, ls.RetryAfter())
+	return nil, false
+}
+
+// peerOwner 设备归属用户, 过载时使用最近的结果; 返回 false 时已响应 503
+func (i *Internal) peerOwner(c *gin.Context, peerId string) (uint, bool) {
+	key := "peer_owner:" + peerId
+	if service.AllService.LoadShedService.Overloaded(c) {
+		res, ok := i.recall(c, key)
+		if !ok {
+			return 0, false
+		}
+		uid, _ := res["user_id"].(uint)
+		return uid, true
+	}
+	uid := service.AllService.PeerService.FindById(peerId).UserId
+	service.AllService.LoadShedService.Remember(key, gin.H{"user_id": uid})
+	return uid, true
+}
+
+// quotaExceeded 用户 relay 配额是否已用尽, 过载时使用最近的结果; 返回 false 时已响应 503
+func (i *Internal) quotaExceeded(c *gin.Context, userId uint) (bool, bool) {
+	if userId == 0 {
+		return false, true
+	}
+	key := fmt.Sprintf("relay_quota:%d", userId)
+	if service.AllService.LoadShedService.Overloaded(c) {
+		res, ok := i.recall(c, key)
+		if !ok {
+			return false, false
+		}
+		exceeded, _ := res["exceeded"].(bool)
+		return exceeded, true
+	}
+	exceeded := service.AllService.RelaySessionService.QuotaExceeded(userId)
+	service.AllService.LoadShedService.Remember(key, gin.H{"exceeded": exceeded})
+	return exceeded, true
+}
+
+// geoAllowed 地区限制检查, 过载时使用最近的决策; 第二个返回值为 false 时已写入响应
+func (i *Internal) geoAllowed(c *gin.Context, userId uint, uuid, ip string) (bool, bool) {
+	if userId == 0 || ip == "" {
+		return true, true
+	}
+	key := fmt.Sprintf("relay_geo:%d:%s", userId, ip)
+	if service.AllService.LoadShedService.Overloaded(c) {
+		res, ok := i.recall(c, key)
+		if !ok {
+			return false, false
+		}
+		allowed, _ := res["allowed"].(bool)
+		return allowed, true
+	}
+	allowed := service.AllService.RelayGeoService.Allowed(userId, uuid, ip)
+	service.AllService.LoadShedService.Remember(key, gin.H{"allowed": allowed})
+	return allowed, true
+}
+
+// SessionCheck 并发会话检查
+// @Tags Internal
+// @Summary 并发会话检查
+// @Description hbbs 在每次连接尝试时调用, 按发起端设备归属用户的套餐限制并发会话数(进行中的 relay 会话), 返回是否允许; 未识别用户或套餐不限时允许
+// @Accept json
+// @Produce json
+// @Param request body SessionCheckRequest true "请求参数"
+// @Success 200 {object} response.Response
+// @Router /api/internal/session/check [post]
+func (i *Internal) SessionCheck(c *gin.Context) {
+	var req SessionCheckRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Fail(c, 400, "invalid request: "+err.Error())
+		return
+	}
+	if len(req.PeerId) > MaxUUIDLength {
+		response.Fail(c, 400, "peer id too long")
+		return
+	}
+
+	userId, ok := i.peerOwner(c, req.PeerId)
+	if !ok {
+		return
+	}
+	key := fmt.Sprintf("session_check:%d", userId)
+	if service.AllService.LoadShedService.Overloaded(c) {
+		res, ok := i.recall(c, key)
+		if !ok {
+			return
+		}
+		response.Success(c, res)
+		return
+	}
+	check := service.AllService.RelaySessionService.SessionCheck(userId)
+	res := gin.H{"allow": check.Allow, "limit": check.Limit, "active": check.Active, "user_id": userId}
+	service.AllService.LoadShedService.Remember(key, res)
+	response.Success(c, res)
+}
+
+// Metrics Prometheus 指标
+// @Tags Internal
+// @Summary Prometheus 指标
+// @Description HTTP 请求、支付回调、relay 白名单、下单及订阅检查耗时等指标, Prometheus 文本格式
+// @Produce plain
+// @Success 200 {string} string "metrics"
+// @Router /metrics [get]
+func (i *Internal) Metrics(c *gin.Context) {
+	c.Status(http.StatusOK)
+	c.Header("Content-Type", metrics.ContentType)
+	metrics.Default.WriteTo(c.Writer)
 }
 
 // RelayStats 白名单统计信息
@@ -212,6 +528,273 @@ func (i *Internal) SubscriptionCheck(c *gin.Context) {
 // @Success 200 {object} response.Response
 // @Router /api/internal/relay/stats [get]
 func (i *Internal) RelayStats(c *gin.Context) {
-	stats := service.AllService.RelayWhitelistService.Stats()
+	stats := service.AllService.RelayWhitelist().Stats()
 	response.Success(c, stats)
 }
+
+// PeerBans 封禁名单
+// @Tags Internal
+// @Summary 设备封禁名单
+// @Description hbbs 启动或未收到断开通知时同步当前生效的封禁(设备ID、uuid、解封时间)
+// @Produce json
+// @Success 200 {object} response.Response
+// @Router /api/internal/peer/bans [get]
+func (i *Internal) PeerBans(c *gin.Context) {
+	bans := service.AllService.PeerBanService.ActiveBans()
+	list := make([]gin.H, 0, len(bans))
+	for _, b := range bans {
+		list = append(list, gin.H{"peer_id": b.PeerId, "uuid": b.Uuid, "expire_at": b.ExpireAt})
+	}
+	response.Success(c, gin.H{"list": list})
+}
+
+// PeerResolve 设备解析
+// @Tags Internal
+// @Summary 设备解析
+// @Description 通过 id 或 uuid 解析设备归属用户，并返回该用户的默认连接权限，供被控端执行
+// @Accept json
+// @Produce json
+// @Param request body PeerResolveRequest true "请求参数"
+// @Success 200 {object} response.Response
+// @Router /api/internal/peer/resolve [post]
+func (i *Internal) PeerResolve(c *gin.Context) {
+	var req PeerResolveRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Fail(c, 400, "invalid request: "+err.Error())
+		return
+	}
+
+	if req.Id == "" && req.UUID == "" {
+		response.Fail(c, 400, "id or uuid is required")
+		return
+	}
+
+	// 安全检查: 长度限制
+	if len(req.Id) > MaxUUIDLength || len(req.UUID) > MaxUUIDLength {
+		response.Fail(c, 400, "id or uuid too long")
+		return
+	}
+
+	peer := &model.Peer{}
+	if req.UUID != "" {
+		peer = service.AllService.PeerService.FindByUuid(req.UUID)
+	}
+	if peer.RowId == 0 && req.Id != "" {
+		peer = service.AllService.PeerService.FindById(req.Id)
+	}
+	if peer.RowId == 0 {
+		response.Success(c, gin.H{
+			"found": false,
+		})
+		return
+	}
+
+	res := gin.H{
+		"found":   true,
+		"id":      peer.Id,
+		"uuid":    peer.Uuid,
+		"user_id": peer.UserId,
+	}
+	// 未绑定用户的设备不下发权限预设，由客户端按本地配置处理
+	if peer.UserId > 0 {
+		res["permissions"] = service.AllService.ConnPermissionService.GetByUserId(peer.UserId)
+	}
+	response.Success(c, res)
+}
+
+// RelaySessionStart relay 会话开始
+// @Tags Internal
+// @Summary relay 会话开始
+// @Description hbbr 调用，上报 relay 会话开始，同一 uuid 重复上报时忽略
+// @Accept json
+// @Produce json
+// @Param request body RelaySessionStartRequest true "请求参数"
+// @Success 200 {object} response.Response
+// @Router /api/internal/relay/session/start [post]
+func (i *Internal) RelaySessionStart(c *gin.Context) {
+	var req RelaySessionStartRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Fail(c, 400, "invalid request: "+err.Error())
+		return
+	}
+
+	// 安全检查: 长度限制
+	if len(req.UUID) > MaxUUIDLength || len(req.PeerId) > MaxUUIDLength || len(req.TargetPeerId) > MaxUUIDLength {
+		response.Fail(c, 400, "uuid or peer id too long")
+		return
+	}
+
+	s, err := service.AllService.RelaySessionService.Start(&service.RelaySessionReport{
+		Uuid:         req.UUID,
+		PeerId:       req.PeerId,
+		TargetPeerId: req.TargetPeerId,
+		RelayAddr:    c.ClientIP(),
+		At:           req.StartedAt,
+	})
+	if err != nil {
+		response.Fail(c, 400, "save session failed: "+err.Error())
+		return
+	}
+
+	response.Success(c, gin.H{
+		"id":   s.Id,
+		"uuid": s.Uuid,
+	})
+}
+
+// RelaySessionEnd relay 会话结束
+// @Tags Internal
+// @Summary relay 会话结束
+// @Description hbbr 调用，上报 relay 会话结束及传输字节数、时长，未收到开始上报时补建记录
+// @Accept json
+// @Produce json
+// @Param request body RelaySessionEndRequest true "请求参数"
+// @Success 200 {object} response.Response
+// @Router /api/internal/relay/session/end [post]
+func (i *Internal) RelaySessionEnd(c *gin.Context) {
+	var req RelaySessionEndRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Fail(c, 400, "invalid request: "+err.Error())
+		return
+	}
+
+	// 安全检查: 长度限制
+	if len(req.UUID) > MaxUUIDLength || len(req.PeerId) > MaxUUIDLength || len(req.TargetPeerId) > MaxUUIDLength {
+		response.Fail(c, 400, "uuid or peer id too long")
+		return
+	}
+
+	s, err := service.AllService.RelaySessionService.End(&service.RelaySessionReport{
+		Uuid:         req.UUID,
+		PeerId:       req.PeerId,
+		TargetPeerId: req.TargetPeerId,
+		RelayAddr:    c.ClientIP(),
+		At:           req.EndedAt,
+		Duration:     req.Duration,
+		BytesUp:      req.BytesUp,
+		BytesDown:    req.BytesDown,
+	})
+	if err != nil {
+		response.Fail(c, 400, "save session failed: "+err.Error())
+		return
+	}
+
+	response.Success(c, gin.H{
+		"id":       s.Id,
+		"uuid":     s.Uuid,
+		"duration": s.Duration,
+	})
+}
+
+// FileTransfer 文件传输审计上报
+// @Tags Internal
+// @Summary 文件传输审计上报
+// @Description 上报文件传输的方向、文件名和大小, 仅在发起端或被控端归属用户的策略开启文件传输审计时记录
+// @Accept json
+// @Produce json
+// @Param request body FileTransferRequest true "请求参数"
+// @Success 200 {object} response.Response
+// @Router /api/internal/file/transfer [post]
+func (i *Internal) FileTransfer(c *gin.Context) {
+	var req FileTransferRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Fail(c, 400, "invalid request: "+err.Error())
+		return
+	}
+
+	// 安全检查: 长度限制
+	if len(req.PeerId) > MaxUUIDLength || len(req.TargetPeerId) > MaxUUIDLength {
+		response.Fail(c, 400, "peer id too long")
+		return
+	}
+	if req.Direction != model.FileTransferUpload && req.Direction != model.FileTransferDownload {
+		response.Fail(c, 400, "invalid direction")
+		return
+	}
+	if len(req.Files) == 0 || len(req.Files) > service.FileTransferReportMax {
+		response.Fail(c, 400, "invalid files count")
+		return
+	}
+
+	reports := make([]*service.FileTransferReport, 0, len(req.Files))
+	for _, f := range req.Files {
+		reports = append(reports, &service.FileTransferReport{
+			PeerId:       req.PeerId,
+			TargetPeerId: req.TargetPeerId,
+			Direction:    req.Direction,
+			Path:         f.Path,
+			Size:         f.Size,
+			IsDir:        f.IsDir,
+			Ip:           req.Ip,
+			At:           req.At,
+		})
+	}
+	recorded, skipped := service.AllService.AuditService.RecordFileTransfers(reports)
+	response.Success(c, gin.H{
+		"recorded": recorded,
+		"skipped":  skipped,
+	})
+}
+
+// RecordingRegister 会话录像登记
+// @Tags Internal
+// @Summary 会话录像登记
+// @Description relay/录制组件调用, 登记录像文件的位置、时长和校验值, 同一会话的同一文件重复上报时更新
+// @Accept json
+// @Produce json
+// @Param request body RecordingRegisterRequest true "请求参数"
+// @Success 200 {object} response.Response
+// @Router /api/internal/recording/register [post]
+func (i *Internal) RecordingRegister(c *gin.Context) {
+	var req RecordingRegisterRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Fail(c, 400, "invalid request: "+err.Error())
+		return
+	}
+
+	// 安全检查: 长度限制
+	if len(req.SessionId) > MaxUUIDLength || len(req.PeerId) > MaxUUIDLength || len(req.TargetPeerId) > MaxUUIDLength ||
+		len(req.StorageKey) > 191 || len(req.Url) > 1024 {
+		response.Fail(c, 400, "session id, peer id or location too long")
+		return
+	}
+
+	r, err := service.AllService.RecordingService.Register(&model.SessionRecording{
+		SessionId:    req.SessionId,
+		StorageKey:   req.StorageKey,
+		Url:          req.Url,
+		PeerId:       req.PeerId,
+		TargetPeerId: req.TargetPeerId,
+		Size:         req.Size,
+		Duration:     req.Duration,
+		Checksum:     req.Checksum,
+		RecordedAt:   req.RecordedAt,
+	})
+	if err != nil {
+		response.Fail(c, 400, "register recording failed: "+err.Error())
+		return
+	}
+
+	response.Success(c, gin.H{
+		"id":         r.Id,
+		"session_id": r.SessionId,
+	})
+}
+
+// Health 内部健康检查
+// @Tags Internal
+// @Summary 内部健康检查
+// @Description 实时检查数据库、支付网关、relay 白名单存储，并返回版本信息；数据库不可用时返回 503，其他依赖异常时 status 为 degraded
+// @Produce json
+// @Success 200 {object} service.HealthReport
+// @Failure 503 {object} service.HealthReport
+// @Router /api/internal/health [get]
+func (i *Internal) Health(c *gin.Context) {
+	h := service.AllService.StatusService.Health()
+	code := http.StatusOK
+	if h.Status == service.StatusDown {
+		code = http.StatusServiceUnavailable
+	}
+	c.Header("Cache-Control", "no-store")
+	c.JSON(code, h)
+}
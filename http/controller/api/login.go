@@ -74,6 +74,11 @@ func (l *Login) Login(c *gin.Context) {
 		f.DeviceInfo.Type = model.LoginLogClientWeb
 	}
 
+	if err := service.AllService.PeerService.CheckDeviceLimit(f.Uuid, f.Id, u.Id); err != nil {
+		response.Error(c, response.TranslateMsg(c, err.Error()))
+		return
+	}
+
 	ut := service.AllService.UserService.Login(u, &model.LoginLog{
 		UserId:   u.Id,
 		Client:   f.DeviceInfo.Type,
@@ -85,9 +90,44 @@ func (l *Login) Login(c *gin.Context) {
 	})
 
 	c.JSON(http.StatusOK, apiResp.LoginRes{
-		AccessToken: ut.Token,
-		Type:        "access_token",
-		User:        *(&apiResp.UserPayload{}).FromUser(u),
+		AccessToken:  ut.Token,
+		RefreshToken: ut.RefreshToken,
+		Type:         "access_token",
+		User:         *(&apiResp.UserPayload{}).FromUser(u),
+	})
+}
+
+type RefreshForm struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// Refresh 刷新令牌
+// @Tags 登录
+// @Summary 刷新令牌
+// @Description 使用刷新令牌换取新的访问令牌和刷新令牌, 旧的刷新令牌立即失效; 需启用 app.refresh-token-expire
+// @Accept  json
+// @Produce  json
+// @Param body body RefreshForm true "刷新令牌"
+// @Success 200 {object} apiResp.LoginRes
+// @Failure 401 {object} response.ErrorResponse
+// @Router /refresh [post]
+func (l *Login) Refresh(c *gin.Context) {
+	f := &RefreshForm{}
+	if err := c.ShouldBindJSON(f); err != nil {
+		response.Error(c, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	ut, err := service.AllService.UserService.RefreshSession(f.RefreshToken, c.ClientIP())
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, response.ErrorResponse{Error: response.TranslateMsg(c, err.Error())})
+		return
+	}
+	u := service.AllService.UserService.InfoById(ut.UserId)
+	c.JSON(http.StatusOK, apiResp.LoginRes{
+		AccessToken:  ut.Token,
+		RefreshToken: ut.RefreshToken,
+		Type:         "access_token",
+		User:         *(&apiResp.UserPayload{}).FromUser(u),
 	})
 }
 
@@ -0,0 +1,132 @@
+package api
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/lejianwen/rustdesk-api/v2/http/response"
+	"github.com/lejianwen/rustdesk-api/v2/service"
+)
+
+type Notification struct {
+}
+
+type NotificationQuery struct {
+	Page     int  `form:"page"`
+	PageSize int  `form:"page_size"`
+	Unread   bool `form:"unread"` // 只返回未读
+}
+
+type NotificationReadForm struct {
+	Ids []uint `json:"ids"`
+	All bool   `json:"all"` // 全部标记为已读
+}
+
+// List 通知列表
+// @Tags Notification
+// @Summary 站内通知列表
+// @Description 当前用户的站内通知(支付确认、到期提醒、公告), 按时间倒序
+// @Produce  json
+// @Param page query int false "页码"
+// @Param page_size query int false "每页数量"
+// @Param unread query bool false "只返回未读"
+// @Success 200 {object} response.Response{data=model.NotificationList}
+// @Router /api/notifications [get]
+// @Security BearerAuth
+func (n *Notification) List(c *gin.Context) {
+	user := service.AllService.UserService.CurUser(c)
+	q := &NotificationQuery{}
+	_ = c.ShouldBindQuery(q)
+	if q.Page <= 0 {
+		q.Page = 1
+	}
+	if q.PageSize <= 0 {
+		q.PageSize = 10
+	}
+	if q.PageSize > 100 {
+		q.PageSize = 100
+	}
+	res := service.AllService.NotificationService.ListForUser(user.Id, uint(q.Page), uint(q.PageSize), q.Unread)
+	response.Success(c, res)
+}
+
+// UnreadCount 未读数量
+// @Tags Notification
+// @Summary 未读通知数量
+// @Description 客户端/门户轮询使用
+// @Produce  json
+// @Success 200 {object} response.Response
+// @Router /api/notifications/unread_count [get]
+// @Security BearerAuth
+func (n *Notification) UnreadCount(c *gin.Context) {
+	user := service.AllService.UserService.CurUser(c)
+	response.Success(c, gin.H{"unread": service.AllService.NotificationService.UnreadCount(user.Id)})
+}
+
+// Read 标记已读
+// @Tags Notification
+// @Summary 标记通知已读
+// @Description 按 ids 标记, all 为 true 时全部标记为已读
+// @Accept  json
+// @Produce  json
+// @Param body body NotificationReadForm true "通知ID"
+// @Success 200 {object} response.Response
+// @Router /api/notifications/read [post]
+// @Security BearerAuth
+func (n *Notification) Read(c *gin.Context) {
+	user := service.AllService.UserService.CurUser(c)
+	f := &NotificationReadForm{}
+	if err := c.ShouldBindJSON(f); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	var err error
+	if f.All {
+		err = service.AllService.NotificationService.MarkAllRead(user.Id)
+	} else {
+		err = service.AllService.NotificationService.MarkRead(user.Id, f.Ids)
+	}
+	if err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "OperationFailed")+err.Error())
+		return
+	}
+	response.Success(c, gin.H{"unread": service.AllService.NotificationService.UnreadCount(user.Id)})
+}
+
+type ReminderPreferenceForm struct {
+	OptOut bool `json:"opt_out"` // 退订到期和催缴提醒
+}
+
+// ReminderPreference 订阅提醒设置
+// @Tags Notification
+// @Summary 订阅提醒设置
+// @Description opt_out 为 true 表示已退订到期前提醒和到期后催缴提醒(邮件、站内通知和 webhook)
+// @Produce  json
+// @Success 200 {object} response.Response
+// @Router /api/notifications/reminders [get]
+// @Security BearerAuth
+func (n *Notification) ReminderPreference(c *gin.Context) {
+	user := service.AllService.UserService.CurUser(c)
+	response.Success(c, gin.H{"opt_out": service.AllService.ReminderService.OptedOut(user.Id)})
+}
+
+// SetReminderPreference 修改订阅提醒设置
+// @Tags Notification
+// @Summary 退订/恢复订阅提醒
+// @Accept  json
+// @Produce  json
+// @Param body body ReminderPreferenceForm true "提醒设置"
+// @Success 200 {object} response.Response
+// @Router /api/notifications/reminders [post]
+// @Security BearerAuth
+func (n *Notification) SetReminderPreference(c *gin.Context) {
+	user := service.AllService.UserService.CurUser(c)
+	f := &ReminderPreferenceForm{}
+	if err := c.ShouldBindJSON(f); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	if err := service.AllService.ReminderService.SetOptOut(user.Id, f.OptOut); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "OperationFailed"))
+		return
+	}
+	response.Success(c, gin.H{"opt_out": f.OptOut})
+}
@@ -0,0 +1,190 @@
+package api
+
+import (
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lejianwen/rustdesk-api/v2/http/response"
+	"github.com/lejianwen/rustdesk-api/v2/model"
+	"github.com/lejianwen/rustdesk-api/v2/service"
+)
+
+type Organization struct {
+}
+
+type OrganizationCreateRequest struct {
+	Name string `json:"name" binding:"required,max=128"`
+}
+
+type OrganizationInviteRequest struct {
+	Account string `json:"account" binding:"required"` // 用户名或邮箱
+}
+
+type OrganizationRemoveRequest struct {
+	UserId uint `json:"user_id" binding:"required"`
+}
+
+// ownedOrganization 当前用户拥有的组织, 不是所有者时返回 nil
+func ownedOrganization(c *gin.Context, user *model.User) *model.Organization {
+	org := service.AllService.SubscriptionService.GetUserOrganization(user.Id)
+	if org.Id == 0 {
+		response.Fail(c, 101, response.TranslateMsg(c, "OrganizationNotFound"))
+		return nil
+	}
+	if org.OwnerId != user.Id {
+		response.Fail(c, 101, response.TranslateMsg(c, "NoAccess"))
+		return nil
+	}
+	return org
+}
+
+// Detail 我的组织
+// @Tags Organization
+// @Summary 当前用户所在的组织
+// @Description 返回组织、成员和席位占用; 未加入组织时 data 为 null
+// @Produce  json
+// @Success 200 {object} response.Response{data=model.Organization}
+// @Router /api/organization [get]
+// @Security BearerAuth
+func (o *Organization) Detail(c *gin.Context) {
+	user := service.AllService.UserService.CurUser(c)
+	org := service.AllService.SubscriptionService.GetUserOrganization(user.Id)
+	if org.Id == 0 {
+		response.Success(c, nil)
+		return
+	}
+	response.Success(c, service.AllService.SubscriptionService.OrganizationDetail(org))
+}
+
+// Create 创建组织
+// @Tags Organization
+// @Summary 创建组织
+// @Description 当前订阅为多席位套餐时可创建组织, 创建者为所有者并占用一个席位
+// @Accept  json
+// @Produce  json
+// @Param body body OrganizationCreateRequest true "组织信息"
+// @Success 200 {object} response.Response{data=model.Organization}
+// @Router /api/organization/create [post]
+// @Security BearerAuth
+func (o *Organization) Create(c *gin.Context) {
+	var req OrganizationCreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	user := service.AllService.UserService.CurUser(c)
+	org, err := service.AllService.SubscriptionService.CreateOrganization(user.Id, req.Name, true)
+	if err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, err.Error()))
+		return
+	}
+	response.Success(c, service.AllService.SubscriptionService.OrganizationDetail(org))
+}
+
+// Invite 添加成员
+// @Tags Organization
+// @Summary 添加组织成员
+// @Description 所有者按用户名或邮箱添加已注册用户, 成员共享所有者的订阅; 席位已满或对方已加入其他组织时失败
+// @Accept  json
+// @Produce  json
+// @Param body body OrganizationInviteRequest true "成员账号"
+// @Success 200 {object} response.Response
+// @Router /api/organization/members/invite [post]
+// @Security BearerAuth
+func (o *Organization) Invite(c *gin.Context) {
+	var req OrganizationInviteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	user := service.AllService.UserService.CurUser(c)
+	org := ownedOrganization(c, user)
+	if org == nil {
+		return
+	}
+	account := strings.TrimSpace(req.Account)
+	member := service.AllService.UserService.InfoByUsername(account)
+	if member.Id == 0 && strings.Contains(account, "@") {
+		member = service.AllService.UserService.InfoByEmail(account)
+	}
+	if member.Id == 0 {
+		response.Fail(c, 101, response.TranslateMsg(c, "UserNotFound"))
+		return
+	}
+	if err := service.AllService.SubscriptionService.AddOrganizationMember(org.Id, member.Id, user.Id); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, err.Error()))
+		return
+	}
+	response.Success(c, nil)
+}
+
+// Remove 移除成员
+// @Tags Organization
+// @Summary 移除组织成员
+// @Description 所有者移除成员, 释放席位
+// @Accept  json
+// @Produce  json
+// @Param body body OrganizationRemoveRequest true "成员"
+// @Success 200 {object} response.Response
+// @Router /api/organization/members/remove [post]
+// @Security BearerAuth
+func (o *Organization) Remove(c *gin.Context) {
+	var req OrganizationRemoveRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	user := service.AllService.UserService.CurUser(c)
+	org := ownedOrganization(c, user)
+	if org == nil {
+		return
+	}
+	if err := service.AllService.SubscriptionService.RemoveOrganizationMember(org.Id, req.UserId); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, err.Error()))
+		return
+	}
+	response.Success(c, nil)
+}
+
+// Leave 退出组织
+// @Tags Organization
+// @Summary 退出组织
+// @Description 成员主动退出所在组织; 所有者不能退出
+// @Produce  json
+// @Success 200 {object} response.Response
+// @Router /api/organization/leave [post]
+// @Security BearerAuth
+func (o *Organization) Leave(c *gin.Context) {
+	user := service.AllService.UserService.CurUser(c)
+	org := service.AllService.SubscriptionService.GetUserOrganization(user.Id)
+	if org.Id == 0 {
+		response.Fail(c, 101, response.TranslateMsg(c, "OrganizationNotFound"))
+		return
+	}
+	if err := service.AllService.SubscriptionService.RemoveOrganizationMember(org.Id, user.Id); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, err.Error()))
+		return
+	}
+	response.Success(c, nil)
+}
+
+// Dissolve 解散组织
+// @Tags Organization
+// @Summary 解散组织
+// @Description 所有者解散组织, 全部成员失去组织提供的订阅
+// @Produce  json
+// @Success 200 {object} response.Response
+// @Router /api/organization/dissolve [post]
+// @Security BearerAuth
+func (o *Organization) Dissolve(c *gin.Context) {
+	user := service.AllService.UserService.CurUser(c)
+	org := ownedOrganization(c, user)
+	if org == nil {
+		return
+	}
+	if err := service.AllService.SubscriptionService.DeleteOrganization(org.Id); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, err.Error()))
+		return
+	}
+	response.Success(c, nil)
+}
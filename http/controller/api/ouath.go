@@ -92,6 +92,11 @@ func (o *Oauth) OidcAuthQueryPre(c *gin.Context) (*model.User, *model.UserToken)
 		return nil, nil
 	}
 
+	if err := service.AllService.PeerService.CheckDeviceLimit(v.Uuid, v.Id, u.Id); err != nil {
+		response.Error(c, response.TranslateMsg(c, err.Error()))
+		return nil, nil
+	}
+
 	// 删除 OAuth 缓存
 	service.AllService.OauthService.DeleteOauthCache(q.Code)
 
@@ -130,9 +135,10 @@ func (o *Oauth) OidcAuthQuery(c *gin.Context) {
 		return
 	}
 	c.JSON(http.StatusOK, apiResp.LoginRes{
-		AccessToken: ut.Token,
-		Type:        "access_token",
-		User:        *(&apiResp.UserPayload{}).FromUser(u),
+		AccessToken:  ut.Token,
+		RefreshToken: ut.RefreshToken,
+		Type:         "access_token",
+		User:         *(&apiResp.UserPayload{}).FromUser(u),
 	})
 }
 
@@ -240,6 +246,7 @@ func (o *Oauth) OauthCallback(c *gin.Context) {
 				return
 			}
 		}
+		oauthService.ApplyGroupMappings(op, user, oauthUser.Groups)
 		oauthCache.UserId = user.Id
 		oauthService.SetOauthCache(cacheKey, oauthCache, 0)
 		// 如果是webadmin，登录成功后跳转到webadmin
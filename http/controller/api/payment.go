@@ -3,12 +3,15 @@ package api
 import (
 	"errors"
 	"html"
+	"net/http"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/lejianwen/rustdesk-api/v2/http/response"
+	"github.com/lejianwen/rustdesk-api/v2/lib/publicid"
 	"github.com/lejianwen/rustdesk-api/v2/model"
 	"github.com/lejianwen/rustdesk-api/v2/service"
 	"gorm.io/gorm"
@@ -37,7 +40,7 @@ type Payment struct{}
 // @Router /api/payment/notify [get]
 func (p *Payment) Notify(c *gin.Context) {
 	// 检查支付功能是否启用
-	if !service.AllService.PaymentService.IsEnabled() {
+	if !service.AllService.Payment().IsEnabled() {
 		c.String(200, "fail")
 		return
 	}
@@ -52,7 +55,7 @@ func (p *Payment) Notify(c *gin.Context) {
 	}
 
 	// 处理回调
-	err := service.AllService.SubscriptionService.HandleNotify(params)
+	err := service.AllService.SubscriptionService.HandleNotify(c.Request.Context(), params, c.ClientIP())
 	if err != nil {
 		c.String(200, "fail")
 		return
@@ -68,10 +71,12 @@ func (p *Payment) Notify(c *gin.Context) {
 // @Description 生成自动提交表单，以 POST 方式提交到 EasyPay 网关
 // @Produce  html
 // @Param out_trade_no query string true "业务订单号"
+// @Param expires query int true "链接过期时间"
+// @Param sign query string true "链接签名"
 // @Success 200 {string} string "HTML"
 // @Router /api/payment/submit [get]
 func (p *Payment) Submit(c *gin.Context) {
-	if !service.AllService.PaymentService.IsEnabled() {
+	if !service.AllService.Payment().IsEnabled() {
 		c.String(200, "支付未启用")
 		return
 	}
@@ -82,6 +87,19 @@ func (p *Payment) Submit(c *gin.Context) {
 		return
 	}
 
+	// 校验支付链接签名，防止篡改参数或枚举订单号
+	if err := service.AllService.Payment().VerifyPayURL(outTradeNo, c.Query("expires"), c.Query("sign")); err != nil {
+		if err.Error() == "PayURLExpired" {
+			c.String(403, "支付链接已过期，请重新下单")
+			return
+		}
+		c.String(403, "支付链接无效")
+		return
+	}
+
+	// 携带登录态时，仅允许订单所属用户发起支付
+	sessionUserId := submitSessionUserId(c)
+
 	// 防止连点/重复打开导致重复提交到网关（部分网关会因同 out_trade_no 重复建单报唯一约束冲突）
 	const (
 		submitDebounceSeconds = int64(3)
@@ -100,8 +118,23 @@ func (p *Payment) Submit(c *gin.Context) {
 			return err
 		}
 
-		// 订单不存在/状态不正确/金额不合法：不做任何副作用
-		if cur.Id == 0 || cur.Status != model.OrderStatusPending || cur.Amount <= 0 || strings.TrimSpace(cur.AmountYuan) == "" {
+		if sessionUserId > 0 && cur.UserId != sessionUserId {
+			return errOrderOwnerMismatch
+		}
+
+		// 订单不存在/状态不正确/金额不合法/非在线支付：不做任何副作用
+		if cur.Id == 0 || cur.Status != model.OrderStatusPending || cur.PayMethod != model.PayMethodOnline ||
+			cur.Amount <= 0 || strings.TrimSpace(cur.AmountYuan) == "" {
+			order = cur
+			return nil
+		}
+
+		// 超时未支付的订单直接关闭，避免旧的支付链接被再次使用
+		if service.AllService.SubscriptionService.IsOrderExpired(cur) {
+			if err := tx.Model(cur).Update("status", model.OrderStatusClosed).Error; err != nil {
+				return err
+			}
+			cur.Status = model.OrderStatusClosed
 			order = cur
 			return nil
 		}
@@ -116,26 +149,10 @@ func (p *Payment) Submit(c *gin.Context) {
 		createdAt := time.Time(cur.CreatedAt)
 		isStale := !createdAt.IsZero() && time.Since(createdAt) > pendingOrderStaleAfter
 
-		// 已发起过支付或订单过期：关闭旧订单并生成新订单号，避免网关侧重复建单
+		// 已发起过支付或订单过期：关闭该订单并生成新订单号，避免网关侧重复建单
 		if cur.PaySubmitAt > 0 || isStale {
-			if err := tx.Model(&model.Order{}).
-				Where("user_id = ? AND plan_id = ? AND status = ?", cur.UserId, cur.PlanId, model.OrderStatusPending).
-				Update("status", model.OrderStatusClosed).Error; err != nil {
-				return err
-			}
-
-			newOutTradeNo := service.AllService.SubscriptionService.GenerateOutTradeNo(cur.UserId)
-			newOrder := &model.Order{
-				UserId:      cur.UserId,
-				PlanId:      cur.PlanId,
-				OutTradeNo:  newOutTradeNo,
-				Subject:     cur.Subject,
-				Amount:      cur.Amount,
-				AmountYuan:  cur.AmountYuan,
-				Status:      model.OrderStatusPending,
-				PaySubmitAt: now,
-			}
-			if err := tx.Create(newOrder).Error; err != nil {
+			newOrder, err := service.AllService.SubscriptionService.ReissueOrder(tx, cur, now)
+			if err != nil {
 				return err
 			}
 			order = newOrder
@@ -151,7 +168,7 @@ func (p *Payment) Submit(c *gin.Context) {
 		return nil
 	})
 	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
+		if errors.Is(err, gorm.ErrRecordNotFound) || errors.Is(err, errOrderOwnerMismatch) {
 			c.String(404, "订单不存在")
 			return
 		}
@@ -162,7 +179,7 @@ func (p *Payment) Submit(c *gin.Context) {
 		c.String(404, "订单不存在")
 		return
 	}
-	if order.Status != model.OrderStatusPending {
+	if order.Status != model.OrderStatusPending || order.PayMethod != model.PayMethodOnline {
 		c.String(200, "订单状态不可支付")
 		return
 	}
@@ -175,14 +192,185 @@ func (p *Payment) Submit(c *gin.Context) {
 		return
 	}
 
-	action := service.AllService.PaymentService.PaySubmitURL()
-	params := service.AllService.PaymentService.BuildPayParams(order.OutTradeNo, order.Subject, order.AmountYuan)
+	action := service.AllService.Payment().PaySubmitURL()
+	params := service.AllService.Payment().BuildPayParams(order.OutTradeNo, order.Subject, order.Total(), order.PayType)
+	service.ObserveFunnel(service.FunnelPaySubmit, order.PlanId, model.PayMethodOnline)
 
 	c.Header("Content-Type", "text/html; charset=utf-8")
 	c.Header("Cache-Control", "no-store")
 	c.String(200, buildAutoSubmitHTML(action, params))
 }
 
+var errOrderOwnerMismatch = errors.New("OrderOwnerMismatch")
+
+// MockCheckout 模拟网关收银台(免鉴权, 仅 payment.provider 为 mock 时可用)
+// @Tags Payment
+// @Summary 模拟网关收银台
+// @Description 校验下单参数签名后立即向 notify_url 发送签名的支付成功回调, 然后跳转到 return_url, 用于开发环境端到端测试
+// @Produce  html
+// @Param out_trade_no query string true "业务订单号"
+// @Param sign query string true "签名"
+// @Success 200 {string} string "HTML"
+// @Router /api/payment/mock/checkout [get]
+func (p *Payment) MockCheckout(c *gin.Context) {
+	mock, ok := service.AllService.Payment().(*service.MockPaymentService)
+	if !ok {
+		c.String(http.StatusNotFound, "404 page not found")
+		return
+	}
+
+	c.Request.ParseForm()
+	params := make(map[string]string)
+	for key, values := range c.Request.Form {
+		if len(values) > 0 {
+			params[key] = values[0]
+		}
+	}
+	page := gin.H{
+		"title":   response.TranslateMsg(c, "MockCheckoutTitle"),
+		"orderNo": params["out_trade_no"],
+	}
+	returnURL, err := mock.Checkout(c.Request.Context(), params)
+	if err != nil {
+		page["message"] = response.TranslateMsg(c, err.Error())
+		c.HTML(http.StatusOK, "payment_return.html", page)
+		return
+	}
+	page["message"] = response.TranslateMsg(c, "MockCheckoutPaid")
+	if returnURL != "" {
+		page["link"] = returnURL
+		page["button"] = response.TranslateMsg(c, "Continue")
+		page["refresh"] = 2
+		page["refreshUrl"] = returnURL
+	}
+	c.HTML(http.StatusOK, "payment_return.html", page)
+}
+
+const (
+	returnPollParam    = "_n" // 同步跳转页自动刷新次数, 不参与验签
+	returnPollMax      = 20
+	returnPollInterval = 3 // 秒
+)
+
+// Return 支付同步跳转页(免鉴权)
+// @Tags Payment
+// @Summary 支付同步跳转页
+// @Description 网关 return_url 指向该页面: 验签后向网关确认订单的真实状态, 已入账时跳转到 payment.epay.redirect-url, 未入账时自动刷新等待
+// @Produce  html
+// @Param out_trade_no query string true "业务订单号"
+// @Param sign query string true "签名"
+// @Success 200 {string} string "HTML"
+// @Router /api/payment/return [get]
+func (p *Payment) Return(c *gin.Context) {
+	redirect := service.AllService.SubscriptionService.ReturnRedirectURL()
+	page := gin.H{
+		"title":  response.TranslateMsg(c, "PaymentReturnTitle"),
+		"link":   redirect,
+		"button": response.TranslateMsg(c, "Continue"),
+	}
+	if !service.AllService.Payment().IsEnabled() {
+		page["message"] = response.TranslateMsg(c, "PaymentDisabled")
+		c.HTML(http.StatusOK, "payment_return.html", page)
+		return
+	}
+
+	query := c.Request.URL.Query()
+	polls, _ := strconv.Atoi(query.Get(returnPollParam))
+	params := make(map[string]string)
+	for key, values := range query {
+		if key != returnPollParam && len(values) > 0 {
+			params[key] = values[0]
+		}
+	}
+	order, err := service.AllService.SubscriptionService.ConfirmReturn(params)
+	if err != nil {
+		page["message"] = response.TranslateMsg(c, "PaymentReturnInvalid")
+		c.HTML(http.StatusOK, "payment_return.html", page)
+		return
+	}
+	page["orderNo"] = order.OutTradeNo
+	switch {
+	case order.PaidAt > 0:
+		page["message"] = response.TranslateMsg(c, "PaymentReturnPaid")
+		page["refresh"] = 2
+		page["refreshUrl"] = redirect
+	case order.Status != model.OrderStatusPending:
+		page["message"] = response.TranslateMsg(c, "PaymentReturnClosed")
+	case polls < returnPollMax:
+		page["message"] = response.TranslateMsg(c, "PaymentReturnPending")
+		query.Set(returnPollParam, strconv.Itoa(polls+1))
+		page["refresh"] = returnPollInterval
+		page["refreshUrl"] = c.Request.URL.Path + "?" + query.Encode()
+	default:
+		page["message"] = response.TranslateMsg(c, "PaymentReturnTimeout")
+	}
+	c.HTML(http.StatusOK, "payment_return.html", page)
+}
+
+// QRCode 扫码支付
+// @Tags Payment
+// @Summary 获取扫码支付内容
+// @Description 通过网关 API 下单获取二维码内容(由客户端渲染), 供桌面客户端免跳转浏览器支付; 返回的 out_trade_no 可能因重新下单而变化, 之后轮询 /api/payment/qrcode/status
+// @Accept  json
+// @Produce  json
+// @Param out_trade_no query string true "业务订单号"
+// @Param pay_type query string false "支付渠道(alipay/wxpay/qqpay), 默认使用订单渠道或第一个启用的渠道"
+// @Success 200 {object} response.Response{data=model.QRCodePayment}
+// @Router /api/payment/qrcode [get]
+func (p *Payment) QRCode(c *gin.Context) {
+	if !service.AllService.Payment().IsEnabled() {
+		response.Fail(c, 101, response.TranslateMsg(c, "PaymentDisabled"))
+		return
+	}
+	outTradeNo := strings.TrimSpace(c.Query("out_trade_no"))
+	if outTradeNo == "" {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError"))
+		return
+	}
+	user := service.AllService.UserService.CurUser(c)
+	res, err := service.AllService.SubscriptionService.QRCodePay(c.Request.Context(), user.Id, outTradeNo, strings.TrimSpace(c.Query("pay_type")), c.ClientIP())
+	if err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, err.Error()))
+		return
+	}
+	res.AmountDisplay = response.Locale(c).Money(res.Amount, res.Currency)
+	response.Success(c, res)
+}
+
+// QRCodeStatus 扫码支付状态轮询
+// @Tags Payment
+// @Summary 查询订单支付状态
+// @Description 供扫码支付后轮询, 回调未到达时会按间隔主动向网关查单
+// @Accept  json
+// @Produce  json
+// @Param out_trade_no query string true "业务订单号"
+// @Success 200 {object} response.Response{data=model.OrderPayStatus}
+// @Router /api/payment/qrcode/status [get]
+func (p *Payment) QRCodeStatus(c *gin.Context) {
+	outTradeNo := strings.TrimSpace(c.Query("out_trade_no"))
+	if outTradeNo == "" {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError"))
+		return
+	}
+	user := service.AllService.UserService.CurUser(c)
+	res, err := service.AllService.SubscriptionService.OrderPayStatus(user.Id, outTradeNo)
+	if err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, err.Error()))
+		return
+	}
+	response.Success(c, res)
+}
+
+// submitSessionUserId 从可选的 Authorization 头解析当前登录用户，未登录返回 0
+func submitSessionUserId(c *gin.Context) uint {
+	token := c.GetHeader("Authorization")
+	if len(token) <= 7 {
+		return 0
+	}
+	user, _ := service.AllService.UserService.InfoByAccessToken(token[7:])
+	return user.Id
+}
+
 func buildAutoSubmitHTML(action string, params map[string]string) string {
 	keys := make([]string, 0, len(params))
 	for k := range params {
@@ -212,25 +400,212 @@ func buildAutoSubmitHTML(action string, params map[string]string) string {
 // Plans 获取套餐列表
 // @Tags Payment
 // @Summary 获取可用套餐列表
-// @Description 获取所有启用的订阅套餐
+// @Description 获取所有启用的订阅套餐, 按 currency 参数或 Accept-Language 返回对应币种价格
 // @Accept  json
 // @Produce  json
+// @Param currency query string false "币种(CNY/USD/EUR)"
 // @Success 200 {object} response.Response
 // @Router /api/subscription/plans [get]
 func (p *Payment) Plans(c *gin.Context) {
-	if !service.AllService.PaymentService.IsEnabled() {
+	if !service.AllService.Payment().IsEnabled() {
 		response.Fail(c, 101, response.TranslateMsg(c, "PaymentDisabled"))
 		return
 	}
 
-	plans := service.AllService.SubscriptionService.ListActivePlans()
+	user := service.AllService.UserService.CurUser(c)
+	plans := service.AllService.SubscriptionService.ListPlansForUser(user.Id)
+	currency := service.AllService.SubscriptionService.ResolveCurrency(c.Query("currency"), c.GetHeader("Accept-Language"))
+	service.AllService.SubscriptionService.LocalizePlans(plans, currency)
+	service.AllService.SubscriptionService.MarkBonusEligible(plans, user.Id)
+	locale := response.Locale(c)
+	ids := service.AllService.PublicIds()
+	for _, plan := range plans {
+		plan.Localize(locale)
+		service.ObserveFunnel(service.FunnelPlanView, plan.Id, "")
+		plan.Publicize(ids, ids.Opaque())
+	}
 	response.Success(c, plans)
 }
 
+// PlanCompare 套餐功能对比
+// @Tags Payment
+// @Summary 套餐功能对比矩阵
+// @Description 返回当前用户可见的套餐和功能对比矩阵(套餐能力字段 + 管理员配置的自定义功能项), 用于渲染定价页
+// @Produce  json
+// @Param currency query string false "币种(CNY/USD/EUR)"
+// @Success 200 {object} response.Response{data=model.PlanComparison}
+// @Router /api/subscription/plans/compare [get]
+func (p *Payment) PlanCompare(c *gin.Context) {
+	if !service.AllService.Payment().IsEnabled() {
+		response.Fail(c, 101, response.TranslateMsg(c, "PaymentDisabled"))
+		return
+	}
+
+	user := service.AllService.UserService.CurUser(c)
+	plans := service.AllService.SubscriptionService.ListPlansForUser(user.Id)
+	currency := service.AllService.SubscriptionService.ResolveCurrency(c.Query("currency"), c.GetHeader("Accept-Language"))
+	service.AllService.SubscriptionService.LocalizePlans(plans, currency)
+	locale := response.Locale(c)
+	ids := service.AllService.PublicIds()
+	for _, plan := range plans {
+		plan.Localize(locale)
+		plan.Publicize(ids, ids.Opaque())
+	}
+	response.Success(c, model.BuildPlanComparison(plans))
+}
+
+// PlanByCode 按套餐编码获取套餐
+// @Tags Payment
+// @Summary 按套餐编码获取套餐(直接链接)
+// @Description 隐藏套餐不出现在套餐列表中, 通过带套餐编码的链接访问; 其他用户组的专属套餐按不存在处理
+// @Produce  json
+// @Param code path string true "套餐编码"
+// @Param currency query string false "币种(CNY/USD/EUR)"
+// @Success 200 {object} response.Response{data=model.SubscriptionPlan}
+// @Router /api/subscription/plans/{code} [get]
+func (p *Payment) PlanByCode(c *gin.Context) {
+	if !service.AllService.Payment().IsEnabled() {
+		response.Fail(c, 101, response.TranslateMsg(c, "PaymentDisabled"))
+		return
+	}
+
+	user := service.AllService.UserService.CurUser(c)
+	plan := service.AllService.Subscription().GetPlanByCode(c.Param("code"))
+	if plan.Id == 0 || plan.Status != model.COMMON_STATUS_ENABLE || !plan.Purchasable(user.GroupId) {
+		response.Fail(c, 101, response.TranslateMsg(c, "PlanNotFound"))
+		return
+	}
+	plans := []*model.SubscriptionPlan{plan}
+	currency := service.AllService.SubscriptionService.ResolveCurrency(c.Query("currency"), c.GetHeader("Accept-Language"))
+	service.AllService.SubscriptionService.LocalizePlans(plans, currency)
+	service.AllService.SubscriptionService.MarkBonusEligible(plans, user.Id)
+	plan.Localize(response.Locale(c))
+	ids := service.AllService.PublicIds()
+	plan.Publicize(ids, ids.Opaque())
+	response.Success(c, plan)
+}
+
+// PayTypes 获取可选的支付渠道
+// @Tags Payment
+// @Summary 获取可选的在线支付渠道
+// @Description 返回管理员启用的支付渠道, 为空时下单无需传 pay_type, 由网关收银台选择
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} response.Response{data=[]string}
+// @Router /api/subscription/pay_types [get]
+func (p *Payment) PayTypes(c *gin.Context) {
+	if !service.AllService.Payment().IsEnabled() {
+		response.Fail(c, 101, response.TranslateMsg(c, "PaymentDisabled"))
+		return
+	}
+	types := service.AllService.Payment().PayTypes()
+	if types == nil {
+		types = []string{}
+	}
+	response.Success(c, types)
+}
+
+// Products 获取可购买的一次性商品
+// @Tags Payment
+// @Summary 获取一次性商品列表
+// @Description 获取所有启用的一次性商品(如优先支持、定制客户端), 购买后不影响订阅
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} response.Response{data=[]model.Product}
+// @Router /api/subscription/products [get]
+func (p *Payment) Products(c *gin.Context) {
+	if !service.AllService.Payment().IsEnabled() {
+		response.Fail(c, 101, response.TranslateMsg(c, "PaymentDisabled"))
+		return
+	}
+	products := service.AllService.SubscriptionService.ListEnabledProducts()
+	locale := response.Locale(c)
+	for _, pr := range products {
+		pr.Localize(locale)
+	}
+	response.Success(c, products)
+}
+
+// CreateProductOrder 购买一次性商品
+// @Tags Payment
+// @Summary 创建商品订单
+// @Description 创建一次性商品的在线支付订单, 支付后由管理员履约
+// @Accept  json
+// @Produce  json
+// @Param body body CreateProductOrderRequest true "商品"
+// @Success 200 {object} response.Response
+// @Router /api/subscription/products/orders [post]
+func (p *Payment) CreateProductOrder(c *gin.Context) {
+	var req CreateProductOrderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	if !service.AllService.Payment().IsEnabled() {
+		response.Fail(c, 101, response.TranslateMsg(c, "PaymentDisabled"))
+		return
+	}
+	user := service.AllService.UserService.CurUser(c)
+	if !service.AllService.EmailVerificationService.PurchaseAllowed(user) {
+		response.Fail(c, 101, response.TranslateMsg(c, "EmailNotVerified"))
+		return
+	}
+	outTradeNo, payURL, err := service.AllService.SubscriptionService.CreateProductOrder(c.Request.Context(), user.Id, req.ProductId, req.PayType, service.ResolveTaxCountry(req.Country, c.GetHeader("Accept-Language")))
+	if err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, err.Error()))
+		return
+	}
+	response.Success(c, gin.H{
+		"out_trade_no": outTradeNo,
+		"pay_url":      payURL,
+	})
+}
+
+// CheckoutPreview 下单预览
+// @Tags Payment
+// @Summary 下单预览
+// @Description 返回套餐在指定币种下的实付金额、首购赠送天数和支付后的预计到期时间, 不创建订单
+// @Accept  json
+// @Produce  json
+// @Param plan_id query string true "套餐ID或对外标识"
+// @Param currency query string false "币种(CNY/USD/EUR)"
+// @Param pay_method query string false "支付方式: online(默认)/manual"
+// @Param country query string false "计税国家/地区(如 DE), 为空时按 Accept-Language 推断"
+// @Success 200 {object} response.Response{data=model.CheckoutPreview}
+// @Router /api/subscription/checkout/preview [get]
+func (p *Payment) CheckoutPreview(c *gin.Context) {
+	var req CheckoutPreviewRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	if req.PayMethod != model.PayMethodManual && !service.AllService.Payment().IsEnabled() {
+		response.Fail(c, 101, response.TranslateMsg(c, "PaymentDisabled"))
+		return
+	}
+	planId, err := service.AllService.ResolvePublicId(publicid.KindPlan, req.PlanId)
+	if err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "PlanNotFound"))
+		return
+	}
+	user := service.AllService.UserService.CurUser(c)
+	currency := service.AllService.SubscriptionService.ResolveCurrency(req.Currency, c.GetHeader("Accept-Language"))
+	country := service.ResolveTaxCountry(req.Country, c.GetHeader("Accept-Language"))
+	res, err := service.AllService.SubscriptionService.PreviewCheckout(user.Id, planId, currency, req.PayMethod, country)
+	if err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, err.Error()))
+		return
+	}
+	res.Plan.Localize(response.Locale(c))
+	ids := service.AllService.PublicIds()
+	res.Plan.Publicize(ids, ids.Opaque())
+	response.Success(c, res)
+}
+
 // CreateOrder 创建订单
 // @Tags Payment
 // @Summary 创建支付订单
-// @Description 创建订单并返回支付跳转URL
+// @Description 创建订单并返回支付跳转URL; pay_method=manual 时创建线下转账订单并返回收款说明
 // @Accept  json
 // @Produce  json
 // @Param body body CreateOrderRequest true "创建订单请求"
@@ -238,14 +613,19 @@ func (p *Payment) Plans(c *gin.Context) {
 // @Failure 400 {object} response.ErrorResponse
 // @Router /api/subscription/orders [post]
 func (p *Payment) CreateOrder(c *gin.Context) {
-	if !service.AllService.PaymentService.IsEnabled() {
+	var req CreateOrderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	if req.PayMethod != model.PayMethodManual && !service.AllService.Payment().IsEnabled() {
 		response.Fail(c, 101, response.TranslateMsg(c, "PaymentDisabled"))
 		return
 	}
 
-	var req CreateOrderRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		response.Error(c, response.TranslateMsg(c, "ParamsError")+err.Error())
+	planId, err := service.AllService.ResolvePublicId(publicid.KindPlan, req.PlanId)
+	if err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "PlanNotFound"))
 		return
 	}
 
@@ -255,9 +635,28 @@ func (p *Payment) CreateOrder(c *gin.Context) {
 		response.Error(c, response.TranslateMsg(c, "UserNotFound"))
 		return
 	}
+	if !service.AllService.EmailVerificationService.PurchaseAllowed(user) {
+		response.Fail(c, 101, response.TranslateMsg(c, "EmailNotVerified"))
+		return
+	}
 
 	// 创建订单
-	outTradeNo, payURL, err := service.AllService.SubscriptionService.CreateOrder(user.Id, req.PlanId)
+	currency := service.AllService.SubscriptionService.ResolveCurrency(req.Currency, c.GetHeader("Accept-Language"))
+	country := service.ResolveTaxCountry(req.Country, c.GetHeader("Accept-Language"))
+	if req.PayMethod == model.PayMethodManual {
+		outTradeNo, err := service.AllService.SubscriptionService.CreateManualOrder(c.Request.Context(), user.Id, planId, currency, country)
+		if err != nil {
+			response.Fail(c, 101, response.TranslateMsg(c, err.Error()))
+			return
+		}
+		response.Success(c, gin.H{
+			"out_trade_no": outTradeNo,
+			"pay_method":   model.PayMethodManual,
+			"instructions": service.AllService.PaymentProofService.Instructions(),
+		})
+		return
+	}
+	outTradeNo, payURL, err := service.AllService.SubscriptionService.CreateOrder(c.Request.Context(), user.Id, planId, currency, req.PayType, country)
 	if err != nil {
 		response.Fail(c, 101, response.TranslateMsg(c, err.Error()))
 		return
@@ -269,6 +668,29 @@ func (p *Payment) CreateOrder(c *gin.Context) {
 	})
 }
 
+// CancelOrder 取消订单
+// @Tags Payment
+// @Summary 取消待支付订单
+// @Description 关闭当前用户自己的待支付订单, 关闭后原支付链接失效, 可重新选择套餐下单
+// @Accept  json
+// @Produce  json
+// @Param body body CancelOrderRequest true "订单号"
+// @Success 200 {object} response.Response
+// @Router /api/subscription/orders/cancel [post]
+func (p *Payment) CancelOrder(c *gin.Context) {
+	var req CancelOrderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	user := service.AllService.UserService.CurUser(c)
+	if err := service.AllService.SubscriptionService.CancelOrder(user.Id, strings.TrimSpace(req.OutTradeNo)); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, err.Error()))
+		return
+	}
+	response.Success(c, nil)
+}
+
 // Status 获取订阅状态
 // @Tags Payment
 // @Summary 获取当前用户订阅状态
@@ -286,16 +708,50 @@ func (p *Payment) Status(c *gin.Context) {
 	}
 
 	// 获取订阅信息
-	sub := service.AllService.SubscriptionService.GetUserSubscription(user.Id)
-	active := service.AllService.SubscriptionService.IsSubscriptionActive(user.Id)
+	sub := service.AllService.Subscription().GetUserSubscription(user.Id)
+	active := service.AllService.Subscription().IsSubscriptionActive(user.Id)
 
 	// 检查支付功能是否启用
-	paymentEnabled := service.AllService.PaymentService.IsEnabled()
+	paymentEnabled := service.AllService.Payment().IsEnabled()
+	if sub.Id > 0 {
+		sub.Localize(response.Locale(c))
+		ids := service.AllService.PublicIds()
+		sub.Publicize(ids, ids.Opaque())
+	}
+
+	// 通过组织席位获得订阅时, 返回所在组织
+	var org *model.Organization
+	if o := service.AllService.SubscriptionService.GetUserOrganization(user.Id); o.Id > 0 {
+		org = o
+	}
+
+	// 接近或达到限额的权益提醒
+	warnings := make([]*model.QuotaWarning, 0)
+	for _, e := range service.AllService.EntitlementService.NearLimit(user.Id) {
+		msgId := "QuotaNearLimit"
+		if e.Remaining == 0 {
+			msgId = "QuotaExceeded"
+		}
+		warnings = append(warnings, &model.QuotaWarning{
+			Name:      e.Name,
+			Limit:     e.Limit,
+			Used:      e.Used,
+			Remaining: e.Remaining,
+			Reset:     e.Reset,
+			Percent:   e.UsedPercent(),
+			Exceeded:  e.Remaining == 0,
+			Message:   response.TranslateParamMsg(c, msgId, e.Name, strconv.FormatInt(e.UsedPercent(), 10)),
+		})
+	}
 
 	response.Success(c, gin.H{
 		"payment_enabled": paymentEnabled,
 		"active":          active,
 		"subscription":    sub,
+		"relay_quota":     service.AllService.RelaySessionService.Quota(user.Id),
+		"relay_tier":      service.AllService.EntitlementService.RelayTier(user.Id),
+		"organization":    org,
+		"warnings":        warnings,
 	})
 }
 
@@ -337,24 +793,345 @@ func (p *Payment) Orders(c *gin.Context) {
 		if req.Status != nil {
 			tx.Where("status = ?", *req.Status)
 		}
+		tx.Preload("Proofs")
 	})
+	locale := response.Locale(c)
+	for _, order := range orders.Orders {
+		if order != nil {
+			order.Localize(locale)
+		}
+	}
+	ids := service.AllService.PublicIds()
 	// 仅对待支付订单补充 pay_url，便于前端“立即支付”直接跳转，避免重复创建订单
-	if service.AllService.PaymentService.IsEnabled() {
+	if service.AllService.Payment().IsEnabled() {
 		for _, order := range orders.Orders {
 			if order == nil {
 				continue
 			}
-			if order.Status == model.OrderStatusPending && order.Amount > 0 {
-				order.PayURL = service.AllService.PaymentService.BuildPayURL(order.OutTradeNo)
+			if order.Status == model.OrderStatusPending && order.Amount > 0 && order.PayMethod != model.PayMethodManual {
+				order.PayURL = service.AllService.Payment().BuildPayURL(order.OutTradeNo)
 			}
 		}
 	}
+	for _, order := range orders.Orders {
+		if order != nil {
+			order.Publicize(ids, ids.Opaque())
+		}
+	}
 	response.Success(c, orders)
 }
 
+// Events 当前用户的订阅变更记录
+// @Tags Payment
+// @Summary 获取当前用户订阅变更记录
+// @Description 开通、续期、赠送、取消、退款、到期记录, 按时间倒序; 不包含操作人和内部备注
+// @Accept  json
+// @Produce  json
+// @Param page query int false "页码"
+// @Param page_size query int false "每页数量"
+// @Success 200 {object} response.Response{data=model.SubscriptionEventList}
+// @Router /api/subscription/events [get]
+func (p *Payment) Events(c *gin.Context) {
+	user := service.AllService.UserService.CurUser(c)
+	if user == nil {
+		response.Error(c, response.TranslateMsg(c, "UserNotFound"))
+		return
+	}
+
+	var req PageRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		req.Page = 1
+		req.PageSize = 10
+	}
+	if req.Page <= 0 {
+		req.Page = 1
+	}
+	if req.PageSize <= 0 {
+		req.PageSize = 10
+	}
+	if req.PageSize > 100 {
+		req.PageSize = 100
+	}
+
+	res := service.AllService.SubscriptionService.ListSubscriptionEvents(uint(req.Page), uint(req.PageSize), func(tx *gorm.DB) {
+		tx.Where("user_id = ?", user.Id)
+	})
+	ids := service.AllService.PublicIds()
+	for _, e := range res.SubscriptionEvents {
+		e.ActorId = 0
+		e.Metadata = nil
+		e.Publicize(ids, ids.Opaque())
+	}
+	response.Success(c, res)
+}
+
+// Redeem 兑换码兑换
+// @Tags Payment
+// @Summary 使用兑换码
+// @Description 使用兑换码激活或续期当前用户的订阅
+// @Accept  json
+// @Produce  json
+// @Param body body RedeemRequest true "兑换请求"
+// @Success 200 {object} response.Response
+// @Router /api/subscription/redeem [post]
+func (p *Payment) Redeem(c *gin.Context) {
+	var req RedeemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+
+	// 获取当前用户
+	user := service.AllService.UserService.CurUser(c)
+	if user == nil {
+		response.Error(c, response.TranslateMsg(c, "UserNotFound"))
+		return
+	}
+
+	if _, err := service.AllService.RedeemCodeService.Redeem(user.Id, req.Code); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, err.Error()))
+		return
+	}
+
+	sub := service.AllService.Subscription().GetUserSubscription(user.Id)
+	ids := service.AllService.PublicIds()
+	sub.Publicize(ids, ids.Opaque())
+	response.Success(c, gin.H{
+		"subscription": sub,
+	})
+}
+
+// Pause 暂停订阅
+// @Tags Payment
+// @Summary 暂停订阅
+// @Description 冻结订阅的剩余时长, 暂停期间订阅无效, 到 days 天后自动恢复; 需管理员开启用户自助暂停
+// @Accept  json
+// @Produce  json
+// @Param body body PauseRequest true "暂停天数"
+// @Success 200 {object} response.Response
+// @Router /api/subscription/pause [post]
+func (p *Payment) Pause(c *gin.Context) {
+	var req PauseRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	user := service.AllService.UserService.CurUser(c)
+	if err := service.AllService.SubscriptionService.UserPauseSubscription(user.Id, req.Days, req.Reason); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, err.Error()))
+		return
+	}
+	p.respondSubscription(c, user.Id)
+}
+
+// Resume 恢复订阅
+// @Tags Payment
+// @Summary 恢复暂停的订阅
+// @Description 到期时间为恢复时间加暂停时冻结的剩余时长; 管理员发起的暂停只能由管理员恢复
+// @Produce  json
+// @Success 200 {object} response.Response
+// @Router /api/subscription/resume [post]
+func (p *Payment) Resume(c *gin.Context) {
+	user := service.AllService.UserService.CurUser(c)
+	if err := service.AllService.SubscriptionService.UserResumeSubscription(user.Id); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, err.Error()))
+		return
+	}
+	p.respondSubscription(c, user.Id)
+}
+
+// SchedulePlanChange 预约套餐变更
+// @Tags Payment
+// @Summary 预约在当前周期结束时切换套餐
+// @Description 只能切换到价格不高于当前套餐的套餐(降级), 当前周期到期时自动切换; 免费套餐直接开始新周期, 付费套餐需按新套餐续费; 期间购买任意套餐会取消预约
+// @Accept  json
+// @Produce  json
+// @Param body body PlanChangeRequest true "目标套餐"
+// @Success 200 {object} response.Response
+// @Router /api/subscription/plan-change [post]
+func (p *Payment) SchedulePlanChange(c *gin.Context) {
+	var req PlanChangeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	planId, err := service.AllService.ResolvePublicId(publicid.KindPlan, req.PlanId)
+	if err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "PlanNotFound"))
+		return
+	}
+	user := service.AllService.UserService.CurUser(c)
+	if err := service.AllService.SubscriptionService.SchedulePlanChange(user.Id, planId); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, err.Error()))
+		return
+	}
+	p.respondSubscription(c, user.Id)
+}
+
+// CancelPlanChange 取消预约的套餐变更
+// @Tags Payment
+// @Summary 取消预约的套餐变更
+// @Produce  json
+// @Success 200 {object} response.Response
+// @Router /api/subscription/plan-change/cancel [post]
+func (p *Payment) CancelPlanChange(c *gin.Context) {
+	user := service.AllService.UserService.CurUser(c)
+	if err := service.AllService.SubscriptionService.CancelPlanChange(user.Id); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, err.Error()))
+		return
+	}
+	p.respondSubscription(c, user.Id)
+}
+
+// Seats 设备席位
+// @Tags Payment
+// @Summary 设备席位
+// @Description 按设备席位授权的套餐中, 只有分配到席位的设备可以使用订阅, 席位数为套餐的设备数
+// @Produce  json
+// @Success 200 {object} response.Response{data=model.DeviceSeatSummary}
+// @Router /api/subscription/seats [get]
+func (p *Payment) Seats(c *gin.Context) {
+	user := service.AllService.UserService.CurUser(c)
+	response.Success(c, service.AllService.SubscriptionService.ListSeats(user.Id))
+}
+
+// AssignSeat 分配设备席位
+// @Tags Payment
+// @Summary 将自己的设备分配到席位
+// @Accept  json
+// @Produce  json
+// @Param body body SeatRequest true "设备ID"
+// @Success 200 {object} response.Response{data=model.DeviceSeatSummary}
+// @Router /api/subscription/seats/assign [post]
+func (p *Payment) AssignSeat(c *gin.Context) {
+	var req SeatRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	user := service.AllService.UserService.CurUser(c)
+	if _, err := service.AllService.SubscriptionService.AssignSeat(user.Id, req.PeerId); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, err.Error()))
+		return
+	}
+	response.Success(c, service.AllService.SubscriptionService.ListSeats(user.Id))
+}
+
+// ReleaseSeat 释放设备席位
+// @Tags Payment
+// @Summary 释放设备占用的席位
+// @Accept  json
+// @Produce  json
+// @Param body body SeatRequest true "设备ID"
+// @Success 200 {object} response.Response{data=model.DeviceSeatSummary}
+// @Router /api/subscription/seats/release [post]
+func (p *Payment) ReleaseSeat(c *gin.Context) {
+	var req SeatRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	user := service.AllService.UserService.CurUser(c)
+	if err := service.AllService.SubscriptionService.ReleaseSeat(user.Id, req.PeerId); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, err.Error()))
+		return
+	}
+	response.Success(c, service.AllService.SubscriptionService.ListSeats(user.Id))
+}
+
+func (p *Payment) respondSubscription(c *gin.Context, userId uint) {
+	sub := service.AllService.Subscription().GetUserSubscription(userId)
+	ids := service.AllService.PublicIds()
+	sub.Publicize(ids, ids.Opaque())
+	response.Success(c, gin.H{
+		"subscription": sub,
+	})
+}
+
+// UploadProof 上传付款凭证
+// @Tags Payment
+// @Summary 上传线下转账付款凭证
+// @Description 为线下转账订单上传付款凭证(图片或PDF), 提交后进入管理员审核队列; 重复上传将取代之前待审核的凭证
+// @Accept  multipart/form-data
+// @Produce  json
+// @Param out_trade_no formData string true "业务订单号"
+// @Param file formData file true "付款凭证"
+// @Param note formData string false "备注, 如转账流水号"
+// @Success 200 {object} response.Response{data=model.PaymentProof}
+// @Router /api/subscription/orders/proof [post]
+func (p *Payment) UploadProof(c *gin.Context) {
+	var req UploadProofRequest
+	if err := c.ShouldBind(&req); err != nil {
+		response.Error(c, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	file, err := c.FormFile("file")
+	if err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ProofFileRequired"))
+		return
+	}
+
+	user := service.AllService.UserService.CurUser(c)
+	if user == nil {
+		response.Error(c, response.TranslateMsg(c, "UserNotFound"))
+		return
+	}
+
+	proof, err := service.AllService.PaymentProofService.Upload(c.Request.Context(), user.Id, strings.TrimSpace(req.OutTradeNo), file, strings.TrimSpace(req.Note))
+	if err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, err.Error()))
+		return
+	}
+	proof.Publicize(service.AllService.PublicIds().Opaque())
+	response.Success(c, proof)
+}
+
 // Request/Response 结构体
 type CreateOrderRequest struct {
-	PlanId uint `json:"plan_id" binding:"required,gt=0"`
+	PlanId    publicid.Ref `json:"plan_id" binding:"required" swaggertype:"string"`    // 套餐ID或对外标识
+	Currency  string       `json:"currency"`                                           // 币种(可选), 为空时按 Accept-Language 推断
+	PayMethod string       `json:"pay_method" binding:"omitempty,oneof=online manual"` // 支付方式, 默认 online
+	PayType   string       `json:"pay_type"`                                           // 在线支付渠道(alipay/wxpay/qqpay), 为空时由网关收银台选择
+	Country   string       `json:"country"`                                            // 计税国家/地区(可选), 为空时按 Accept-Language 推断
+}
+
+type CancelOrderRequest struct {
+	OutTradeNo string `json:"out_trade_no" binding:"required"`
+}
+
+type CreateProductOrderRequest struct {
+	ProductId uint   `json:"product_id" binding:"required"`
+	PayType   string `json:"pay_type"` // 在线支付渠道, 同 CreateOrderRequest
+	Country   string `json:"country"`  // 计税国家/地区, 同 CreateOrderRequest
+}
+
+type CheckoutPreviewRequest struct {
+	PlanId    publicid.Ref `form:"plan_id" binding:"required" swaggertype:"string"`
+	Currency  string       `form:"currency"`
+	PayMethod string       `form:"pay_method" binding:"omitempty,oneof=online manual"`
+	Country   string       `form:"country"`
+}
+
+type UploadProofRequest struct {
+	OutTradeNo string `form:"out_trade_no" binding:"required"`
+	Note       string `form:"note" binding:"max=500"`
+}
+
+type RedeemRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+type SeatRequest struct {
+	PeerId string `json:"peer_id" binding:"required,max=100"` // 设备ID
+}
+
+type PlanChangeRequest struct {
+	PlanId publicid.Ref `json:"plan_id" binding:"required" swaggertype:"string"` // 套餐ID或对外标识
+}
+
+type PauseRequest struct {
+	Days   int    `json:"days" binding:"required,gt=0"` // 暂停天数, 不超过后台设置的上限
+	Reason string `json:"reason" binding:"max=255"`
 }
 
 type PageRequest struct {
@@ -6,6 +6,7 @@ import (
 	"github.com/gin-gonic/gin/binding"
 	requstform "github.com/lejianwen/rustdesk-api/v2/http/request/api"
 	"github.com/lejianwen/rustdesk-api/v2/http/response"
+	"github.com/lejianwen/rustdesk-api/v2/model"
 	"github.com/lejianwen/rustdesk-api/v2/service"
 	"net/http"
 )
@@ -34,7 +35,7 @@ func (p *Peer) SysInfo(c *gin.Context) {
 	pe := service.AllService.PeerService.FindById(f.Id)
 	if pe.RowId == 0 {
 		pe = f.ToPeer()
-		pe.UserId = service.AllService.UserService.FindLatestUserIdFromLoginLogByUuid(pe.Uuid, pe.Id)
+		pe.UserId = p.loginUserId(pe)
 		err = service.AllService.PeerService.Create(pe)
 		if err != nil {
 			response.Error(c, response.TranslateMsg(c, "OperationFailed")+err.Error())
@@ -42,7 +43,7 @@ func (p *Peer) SysInfo(c *gin.Context) {
 		}
 	} else {
 		if pe.UserId == 0 {
-			pe.UserId = service.AllService.UserService.FindLatestUserIdFromLoginLogByUuid(pe.Uuid, pe.Id)
+			pe.UserId = p.loginUserId(pe)
 		}
 		fpe.RowId = pe.RowId
 		fpe.UserId = pe.UserId
@@ -58,6 +59,15 @@ func (p *Peer) SysInfo(c *gin.Context) {
 	c.String(http.StatusOK, "SYSINFO_UPDATED")
 }
 
+// loginUserId 按登录记录确定设备归属用户, 超出套餐设备数时不绑定
+func (p *Peer) loginUserId(pe *model.Peer) uint {
+	userId := service.AllService.UserService.FindLatestUserIdFromLoginLogByUuid(pe.Uuid, pe.Id)
+	if userId > 0 && service.AllService.PeerService.CheckDeviceLimit(pe.Uuid, pe.Id, userId) != nil {
+		return 0
+	}
+	return userId
+}
+
 // SysInfoVer
 // @Tags System
 // @Summary 获取系统版本信息
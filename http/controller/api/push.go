@@ -0,0 +1,121 @@
+package api
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lejianwen/rustdesk-api/v2/global"
+	"github.com/lejianwen/rustdesk-api/v2/lib/ws"
+	"github.com/lejianwen/rustdesk-api/v2/model"
+	"github.com/lejianwen/rustdesk-api/v2/service"
+)
+
+const (
+	pushWriteTimeout = 10 * time.Second
+	pushPingInterval = 30 * time.Second
+	// pushReadTimeout 客户端需在该时间内有任意帧(含 pong), 否则断开
+	pushReadTimeout = 75 * time.Second
+)
+
+// pushOriginAllowed 允许同源页面和 rustdesk.api-server 配置的地址(反向代理改写 Host 时)
+func pushOriginAllowed(r *http.Request) bool {
+	if ws.SameOrigin(r) {
+		return true
+	}
+	origin, err := url.Parse(r.Header.Get("Origin"))
+	if err != nil {
+		return false
+	}
+	api, err := url.Parse(global.Config.Rustdesk.ApiServer)
+	return err == nil && api.Host != "" && strings.EqualFold(origin.Host, api.Host)
+}
+
+// pushUser token 对应的有效用户和会话, token 失效或用户被禁用时返回 nil
+func pushUser(token string) (*model.User, *model.UserToken) {
+	user, ut := service.AllService.UserService.InfoByAccessToken(token)
+	if user.Id == 0 || !service.AllService.UserService.CheckUserEnable(user) {
+		return nil, nil
+	}
+	return user, ut
+}
+
+type Push struct {
+}
+
+// pushToken 浏览器无法为 WebSocket 设置请求头, 优先使用 token 参数
+func pushToken(c *gin.Context) string {
+	if token := c.Query("token"); token != "" {
+		return token
+	}
+	if token := c.GetHeader("api-token"); token != "" {
+		return token
+	}
+	return strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+}
+
+// Ws 实时推送
+// @Tags 推送
+// @Summary 实时推送
+// @Description WebSocket 推送设备上下线(peer.online/peer.offline)和订阅变更(subscription.changed), 管理员接收所有用户的事件
+// @Description 浏览器只能从同源页面或 rustdesk.api-server 连接; 退出登录、撤销会话、修改密码或禁用用户时立即断开, 每次心跳也会重新校验 token
+// @Param token query string false "登录 token, 也可通过 api-token 或 Authorization 头传递"
+// @Success 101 {object} nil
+// @Failure 401 {object} nil
+// @Router /ws [get]
+func (p *Push) Ws(c *gin.Context) {
+	token := pushToken(c)
+	if token == "" {
+		c.JSON(401, gin.H{"error": "Unauthorized"})
+		return
+	}
+	user, ut := pushUser(token)
+	if user == nil {
+		c.JSON(401, gin.H{"error": "Unauthorized"})
+		return
+	}
+	conn, err := ws.Upgrade(c.Writer, c.Request, pushOriginAllowed)
+	if err != nil {
+		return
+	}
+	ps := service.AllService.PushService
+	client := ps.Subscribe(user.Id, ut.Id, service.AllService.UserService.IsAdmin(user))
+
+	go func() {
+		ticker := time.NewTicker(pushPingInterval)
+		defer ticker.Stop()
+		defer conn.Close()
+		for {
+			select {
+			case msg, ok := <-client.Send:
+				if !ok {
+					return
+				}
+				if err := conn.WriteText(msg, pushWriteTimeout); err != nil {
+					ps.Unsubscribe(client)
+					return
+				}
+			case <-ticker.C:
+				if u, _ := pushUser(token); u == nil {
+					ps.Unsubscribe(client)
+					return
+				}
+				if err := conn.Ping(pushWriteTimeout); err != nil {
+					ps.Unsubscribe(client)
+					return
+				}
+			}
+		}
+	}()
+
+	// 只读取控制帧用于保活和感知断开, 客户端消息忽略
+	for {
+		conn.SetReadDeadline(time.Now().Add(pushReadTimeout))
+		if _, _, err := conn.ReadMessage(); err != nil {
+			break
+		}
+	}
+	ps.Unsubscribe(client)
+}
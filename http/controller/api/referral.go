@@ -0,0 +1,32 @@
+package api
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/lejianwen/rustdesk-api/v2/http/response"
+	"github.com/lejianwen/rustdesk-api/v2/service"
+)
+
+type Referral struct {
+}
+
+// Summary 我的推荐码
+// @Tags Referral
+// @Summary 当前用户的推荐码和推荐效果
+// @Description 首次调用时生成推荐码; 新用户注册时填写推荐码, 推荐人按后台规则获得奖励天数
+// @Produce  json
+// @Success 200 {object} response.Response{data=model.ReferralSummary}
+// @Router /api/referral [get]
+// @Security BearerAuth
+func (r *Referral) Summary(c *gin.Context) {
+	if !service.AllService.ReferralService.IsEnabled() {
+		response.Fail(c, 101, response.TranslateMsg(c, "ReferralDisabled"))
+		return
+	}
+	user := service.AllService.UserService.CurUser(c)
+	res, err := service.AllService.ReferralService.Summary(user.Id)
+	if err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "OperationFailed"))
+		return
+	}
+	response.Success(c, res)
+}
@@ -0,0 +1,53 @@
+package api
+
+import (
+	"errors"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lejianwen/rustdesk-api/v2/lib/storage"
+	"github.com/lejianwen/rustdesk-api/v2/service"
+)
+
+type Storage struct {
+}
+
+// File 本地存储文件下载(免鉴权, 校验签名)
+// @Tags Storage
+// @Summary 下载文件
+// @Description 通过带签名和过期时间的临时地址下载本地存储中的文件, 地址由后台接口生成
+// @Produce  octet-stream
+// @Param key query string true "文件key"
+// @Param expires query int true "过期时间"
+// @Param sign query string true "签名"
+// @Success 200 {file} file
+// @Router /api/storage/file [get]
+func (s *Storage) File(c *gin.Context) {
+	key := c.Query("key")
+	if !service.AllService.StorageService.VerifyLocalURL(key, c.Query("expires"), c.Query("sign")) {
+		c.String(http.StatusForbidden, "链接无效或已过期")
+		return
+	}
+	f, err := service.AllService.StorageService.Open(c.Request.Context(), key)
+	if errors.Is(err, storage.ErrNotFound) {
+		c.String(http.StatusNotFound, "文件不存在")
+		return
+	}
+	if err != nil {
+		service.Logger.Error("Open storage file failed: ", err)
+		c.String(http.StatusInternalServerError, "读取文件失败")
+		return
+	}
+	defer f.Close()
+	ct := mime.TypeByExtension(path.Ext(key))
+	if ct == "" {
+		ct = "application/octet-stream"
+	}
+	c.Header("Content-Disposition", `attachment; filename="`+url.PathEscape(path.Base(key))+`"`)
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Header("Cache-Control", "private, no-store")
+	c.DataFromReader(http.StatusOK, -1, ct, f, nil)
+}
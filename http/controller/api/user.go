@@ -2,9 +2,11 @@ package api
 
 import (
 	"github.com/gin-gonic/gin"
+	"github.com/lejianwen/rustdesk-api/v2/http/response"
 	apiResp "github.com/lejianwen/rustdesk-api/v2/http/response/api"
 	"github.com/lejianwen/rustdesk-api/v2/service"
 	"net/http"
+	"strconv"
 )
 
 type User struct {
@@ -41,3 +43,45 @@ func (u *User) Info(c *gin.Context) {
 	up := (&apiResp.UserPayload{}).FromUser(user)
 	c.JSON(http.StatusOK, up)
 }
+
+// Sessions 登录会话列表
+// @Tags 用户
+// @Summary 登录会话列表
+// @Description 当前用户未过期的登录会话(设备), current 标记发起请求的会话
+// @Produce  json
+// @Success 200 {object} response.Response{data=[]apiResp.SessionPayload}
+// @Router /api/user/sessions [get]
+// @Security BearerAuth
+func (u *User) Sessions(c *gin.Context) {
+	user := service.AllService.UserService.CurUser(c)
+	token := c.GetString("token")
+	res := make([]*apiResp.SessionPayload, 0)
+	for _, ut := range service.AllService.UserService.SessionsByUser(user.Id) {
+		res = append(res, (&apiResp.SessionPayload{}).FromUserToken(ut, ut.Token == token))
+	}
+	response.Success(c, res)
+}
+
+// RevokeSession 撤销登录会话
+// @Tags 用户
+// @Summary 撤销登录会话
+// @Description 会话的访问令牌和刷新令牌立即失效, 并解绑对应设备
+// @Produce  json
+// @Param id path int true "会话ID"
+// @Success 200 {object} response.Response
+// @Router /api/user/sessions/{id} [delete]
+// @Security BearerAuth
+func (u *User) RevokeSession(c *gin.Context) {
+	user := service.AllService.UserService.CurUser(c)
+	id, _ := strconv.Atoi(c.Param("id"))
+	ut := service.AllService.UserService.TokenInfoById(uint(id))
+	if ut.Id == 0 || ut.UserId != user.Id {
+		response.Fail(c, 101, response.TranslateMsg(c, "ItemNotFound"))
+		return
+	}
+	if err := service.AllService.UserService.RevokeSession(ut); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "OperationFailed")+err.Error())
+		return
+	}
+	response.Success(c, nil)
+}
@@ -36,6 +36,7 @@ func BackendUserAuth() gin.HandlerFunc {
 		c.Set("token", token)
 		//如果时间小于1天,token自动续期
 		service.AllService.UserService.AutoRefreshAccessToken(ut)
+		service.AllService.UserService.TouchAccessToken(ut, c.ClientIP())
 
 		c.Next()
 	}
@@ -0,0 +1,119 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lejianwen/rustdesk-api/v2/global"
+)
+
+// AdminIpAllow 后台接口IP白名单中间件
+// 1. 未配置 admin.allow-ips 时不做限制; 配置了但没有一项合法时拒绝全部请求(不会失效放行)
+// 2. 客户端IP(c.ClientIP, 受 gin.trust-proxy 影响)命中白名单则放行
+// 3. 紧急旁路: 配置了 admin.bypass-token 且请求头 X-Admin-Bypass-Token 匹配时放行, 并记录警告日志
+func AdminIpAllow() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		list := global.Config.Admin.AllowIps
+		nets := adminAllowNets(list)
+		if len(nets) == 0 && !hasEntries(list) {
+			c.Next()
+			return
+		}
+
+		clientIP := c.ClientIP()
+		if ipInNets(clientIP, nets) {
+			c.Next()
+			return
+		}
+
+		bypass := global.Config.Admin.BypassToken
+		if got := c.GetHeader("X-Admin-Bypass-Token"); bypass != "" && got != "" &&
+			subtle.ConstantTimeCompare([]byte(got), []byte(bypass)) == 1 {
+			global.Logger.Warn("Admin ip allowlist bypassed by token, ip: ", clientIP, " path: ", c.Request.URL.Path)
+			c.Next()
+			return
+		}
+
+		global.Logger.Warn("Admin access denied by ip allowlist, ip: ", clientIP, " path: ", c.Request.URL.Path)
+		c.JSON(403, gin.H{
+			"code":  403,
+			"error": "Forbidden: ip not allowed",
+		})
+		c.Abort()
+	}
+}
+
+var adminAllowCache struct {
+	sync.Mutex
+	key  string
+	nets []*net.IPNet
+}
+
+// adminAllowNets 返回解析后的白名单, 配置未变化时复用上次结果
+func adminAllowNets(list []string) []*net.IPNet {
+	key := strings.Join(list, ",")
+	adminAllowCache.Lock()
+	defer adminAllowCache.Unlock()
+	if adminAllowCache.nets == nil || adminAllowCache.key != key {
+		adminAllowCache.key = key
+		adminAllowCache.nets = parseCIDRs(list)
+	}
+	return adminAllowCache.nets
+}
+
+// parseCIDRs 解析IP/CIDR列表, 单个IP按 /32 或 /128 处理, 非法项忽略
+func parseCIDRs(list []string) []*net.IPNet {
+	nets := make([]*net.IPNet, 0, len(list))
+	for _, item := range list {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		if !strings.Contains(item, "/") {
+			ip := net.ParseIP(item)
+			if ip == nil {
+				global.Logger.Warn("Invalid ip in allowlist: ", item)
+				continue
+			}
+			if ip.To4() != nil {
+				item += "/32"
+			} else {
+				item += "/128"
+			}
+		}
+		_, network, err := net.ParseCIDR(item)
+		if err != nil {
+			global.Logger.Warn("Invalid cidr in allowlist: ", item)
+			continue
+		}
+		nets = append(nets, network)
+	}
+	return nets
+}
+
+// hasEntries 列表中是否有非空项
+func hasEntries(list []string) bool {
+	for _, item := range list {
+		if strings.TrimSpace(item) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// ipInNets 检查IP是否在任一网段内
+func ipInNets(ipStr string, nets []*net.IPNet) bool {
+	ip := net.ParseIP(ipStr)
+	if ip == nil {
+		return false
+	}
+	for _, n := range nets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
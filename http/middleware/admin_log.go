@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lejianwen/rustdesk-api/v2/http/response"
+	"github.com/lejianwen/rustdesk-api/v2/model"
+	"github.com/lejianwen/rustdesk-api/v2/service"
+)
+
+// AdminActionLog 记录管理员的写操作, 用于行为审计
+func AdminActionLog() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		if c.Request.Method == http.MethodGet || c.Request.Method == http.MethodOptions || c.Request.Method == http.MethodHead {
+			return
+		}
+		u := service.AllService.UserService.CurUser(c)
+		if !service.AllService.UserService.IsAdmin(u) {
+			return
+		}
+		path := c.FullPath()
+		action := service.AllService.AdminLogService.ActionFromPath(path, response.Succeeded(c))
+		if path == "" {
+			path = c.Request.URL.Path
+		}
+		err := service.AllService.AdminLogService.Create(&model.AdminLog{
+			UserId: u.Id,
+			Action: action,
+			Method: c.Request.Method,
+			Path:   path,
+			Ip:     c.ClientIP(),
+			Status: c.Writer.Status(),
+		})
+		if err != nil {
+			service.Logger.Error("Create admin log failed: ", err)
+		}
+	}
+}
@@ -3,15 +3,21 @@ package middleware
 import (
 	"github.com/gin-gonic/gin"
 	"github.com/lejianwen/rustdesk-api/v2/http/response"
+	"github.com/lejianwen/rustdesk-api/v2/model"
 	"github.com/lejianwen/rustdesk-api/v2/service"
 )
 
-// AdminPrivilege ...
+// AdminPrivilege 系统管理权限, 财务、客服等受限角色无权访问
 func AdminPrivilege() gin.HandlerFunc {
+	return AdminPermission(model.PermSystem)
+}
+
+// AdminPermission 要求当前管理员的角色拥有指定权限
+func AdminPermission(perm string) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		u := service.AllService.UserService.CurUser(c)
 
-		if !service.AllService.UserService.IsAdmin(u) {
+		if !service.AllService.UserService.HasPermission(u, perm) {
 			response.Fail(c, 403, response.TranslateMsg(c, "NoAccess"))
 			c.Abort()
 			return
@@ -0,0 +1,67 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/lejianwen/rustdesk-api/v2/model"
+	"github.com/lejianwen/rustdesk-api/v2/service"
+)
+
+// apiTokenRoutes 个人访问令牌可访问的接口及所需权限范围, 空字符串表示任意令牌均可访问
+// 未列出的接口(如登出、令牌管理、下单)只能使用登录令牌
+var apiTokenRoutes = map[string]string{
+	"GET /api/user/info":    "",
+	"POST /api/currentUser": "",
+
+	"GET /api/users":                   model.ApiTokenScopeReadPeers,
+	"GET /api/peers":                   model.ApiTokenScopeReadPeers,
+	"GET /api/device-group/accessible": model.ApiTokenScopeReadPeers,
+	"GET /api/ab":                      model.ApiTokenScopeReadPeers,
+	"POST /api/ab/personal":            model.ApiTokenScopeReadPeers,
+	"POST /api/ab/settings":            model.ApiTokenScopeReadPeers,
+	"POST /api/ab/shared/profiles":     model.ApiTokenScopeReadPeers,
+	"POST /api/ab/peers":               model.ApiTokenScopeReadPeers,
+	"POST /api/ab/tags/:guid":          model.ApiTokenScopeReadPeers,
+
+	"POST /api/ab":                  model.ApiTokenScopeWriteAddressBook,
+	"POST /api/ab/peer/add/:guid":   model.ApiTokenScopeWriteAddressBook,
+	"DELETE /api/ab/peer/:guid":     model.ApiTokenScopeWriteAddressBook,
+	"PUT /api/ab/peer/update/:guid": model.ApiTokenScopeWriteAddressBook,
+	"POST /api/ab/tag/add/:guid":    model.ApiTokenScopeWriteAddressBook,
+	"PUT /api/ab/tag/rename/:guid":  model.ApiTokenScopeWriteAddressBook,
+	"PUT /api/ab/tag/update/:guid":  model.ApiTokenScopeWriteAddressBook,
+	"DELETE /api/ab/tag/:guid":      model.ApiTokenScopeWriteAddressBook,
+
+	"GET /api/subscription/plans":         model.ApiTokenScopeReadBilling,
+	"GET /api/subscription/plans/compare": model.ApiTokenScopeReadBilling,
+	"GET /api/subscription/plans/:code":   model.ApiTokenScopeReadBilling,
+	"GET /api/subscription/products":      model.ApiTokenScopeReadBilling,
+	"GET /api/subscription/orders":        model.ApiTokenScopeReadBilling,
+	"GET /api/subscription/status":        model.ApiTokenScopeReadBilling,
+	"GET /api/subscription/events":        model.ApiTokenScopeReadBilling,
+	"GET /api/subscription/seats":         model.ApiTokenScopeReadBilling,
+}
+
+// apiTokenAuth 个人访问令牌鉴权, 由 RustAuth 在令牌带有 service.ApiTokenPrefix 前缀时调用
+func apiTokenAuth(c *gin.Context, token string) {
+	user, at := service.AllService.ApiTokenService.Verify(token, c.ClientIP())
+	if user.Id == 0 || !service.AllService.UserService.CheckUserEnable(user) {
+		c.JSON(401, gin.H{
+			"error": "Unauthorized",
+		})
+		c.Abort()
+		return
+	}
+	scope, ok := apiTokenRoutes[c.Request.Method+" "+c.FullPath()]
+	if !ok || (scope != "" && !at.HasScope(scope)) {
+		c.JSON(403, gin.H{
+			"error": "Forbidden",
+		})
+		c.Abort()
+		return
+	}
+
+	c.Set("curUser", user)
+	c.Set("apiToken", at)
+
+	c.Next()
+}
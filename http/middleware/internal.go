@@ -2,31 +2,44 @@ package middleware
 
 import (
 	"net"
-	"os"
 	"strings"
+	"sync"
 
 	"github.com/gin-gonic/gin"
+	"github.com/lejianwen/rustdesk-api/v2/global"
+	"github.com/lejianwen/rustdesk-api/v2/service"
 )
 
 // InternalAuth 内部接口鉴权中间件
 // 用于保护 /api/internal/* 接口
 //
 // 安全策略:
-// 1. 如果配置了 RUSTDESK_API_INTERNAL_KEY，则必须携带正确的 X-Internal-Key 头
+// 1. 如果配置了密钥 (RUSTDESK_API_INTERNAL_KEY、RUSTDESK_API_INTERNAL_KEYS 逗号分隔的多个密钥或后台管理的密钥)，则必须携带其中之一作为 X-Internal-Key 头
 // 2. 如果未配置密钥，则仅允许本地回环地址访问 (127.0.0.1/::1)
 // 3. 内网 IP 不再自动放行，必须配合密钥使用
+// 4. 远端地址命中 internal.allowed-cidrs 时无需密钥直接放行，用于 hbbs/hbbr 部署在其他主机的场景
+// 通过鉴权时使用的密钥ID写入上下文 internal_key_id，便于审计
 func InternalAuth() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		internalKey := os.Getenv("RUSTDESK_API_INTERNAL_KEY")
+		keys := service.AllService.InternalKeyService
 
 		// 获取真实客户端 IP (使用 RemoteAddr，不信任代理头)
 		clientIP := getRemoteIP(c)
 
+		// 情况0: 命中配置的网段
+		if ipInNets(clientIP, internalAllowNets(global.Config.Internal.AllowedCidrs)) {
+			c.Set("internal_key_id", "cidr")
+			service.Logger.Debugf("Internal API: %s %s cidr ip=%s", c.Request.Method, c.Request.URL.Path, clientIP)
+			c.Next()
+			return
+		}
+
 		// 情况1: 配置了内部密钥
-		if internalKey != "" {
-			headerKey := c.GetHeader("X-Internal-Key")
-			if headerKey == internalKey {
+		if keys.Configured() {
+			if keyId, ok := keys.Verify(c.GetHeader("X-Internal-Key"), clientIP); ok {
 				// 密钥正确，放行
+				c.Set("internal_key_id", keyId)
+				service.Logger.Debugf("Internal API: %s %s key=%s ip=%s", c.Request.Method, c.Request.URL.Path, keyId, clientIP)
 				c.Next()
 				return
 			}
@@ -54,6 +67,24 @@ func InternalAuth() gin.HandlerFunc {
 	}
 }
 
+var internalAllowCache struct {
+	sync.Mutex
+	key  string
+	nets []*net.IPNet
+}
+
+// internalAllowNets 返回解析后的内部接口网段, 配置未变化时复用上次结果
+func internalAllowNets(list []string) []*net.IPNet {
+	key := strings.Join(list, ",")
+	internalAllowCache.Lock()
+	defer internalAllowCache.Unlock()
+	if internalAllowCache.nets == nil || internalAllowCache.key != key {
+		internalAllowCache.key = key
+		internalAllowCache.nets = parseCIDRs(list)
+	}
+	return internalAllowCache.nets
+}
+
 // getRemoteIP 获取真实客户端 IP (不信任代理头)
 func getRemoteIP(c *gin.Context) string {
 	// 直接从 RemoteAddr 获取，格式为 "ip:port"
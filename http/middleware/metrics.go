@@ -0,0 +1,24 @@
+package middleware
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lejianwen/rustdesk-api/v2/service"
+)
+
+// Metrics 记录 HTTP 请求数和耗时, 按路由模板分组以避免路径参数导致指标膨胀
+func Metrics() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		path := c.FullPath()
+		if path == "" {
+			path = "unmatched"
+		}
+		service.MetricHTTPRequests.Inc(c.Request.Method, path, strconv.Itoa(c.Writer.Status()))
+		service.MetricHTTPDuration.Observe(time.Since(start).Seconds(), c.Request.Method, path)
+	}
+}
@@ -0,0 +1,27 @@
+package middleware
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lejianwen/rustdesk-api/v2/service"
+)
+
+// QuotaHeaders 用户接近权益限额时在响应中附加 X-Quota-* 头, 便于客户端在硬性拒绝前提醒
+// 多项接近限额时取用量比例最高的一项; 必须在 RustAuth() 之后使用
+func QuotaHeaders() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user := service.AllService.UserService.CurUser(c)
+		if user != nil {
+			if e := service.AllService.EntitlementService.Tightest(user.Id); e != nil {
+				c.Header("X-Quota-Resource", e.Name)
+				c.Header("X-Quota-Limit", strconv.FormatInt(e.Limit, 10))
+				c.Header("X-Quota-Remaining", strconv.FormatInt(e.Remaining, 10))
+				if e.Reset > 0 {
+					c.Header("X-Quota-Reset", strconv.FormatInt(e.Reset, 10))
+				}
+			}
+		}
+		c.Next()
+	}
+}
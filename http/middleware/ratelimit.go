@@ -0,0 +1,99 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lejianwen/rustdesk-api/v2/config"
+	"github.com/lejianwen/rustdesk-api/v2/global"
+	"github.com/lejianwen/rustdesk-api/v2/http/response"
+	"github.com/lejianwen/rustdesk-api/v2/service"
+	"github.com/lejianwen/rustdesk-api/v2/utils"
+)
+
+// 限流范围, 同一范围的路由共享令牌桶
+const (
+	RateLimitNotify = "notify"
+	RateLimitOrder  = "order"
+	RateLimitLogin  = "login"
+)
+
+type rateLimiters struct {
+	ip   *utils.RateLimiter
+	user *utils.RateLimiter
+}
+
+var (
+	rateLimitMu     sync.Mutex
+	rateLimitScopes = map[string]*rateLimiters{}
+)
+
+func rateLimitRule(scope string) config.RateLimitRule {
+	switch scope {
+	case RateLimitNotify:
+		return global.Config.RateLimit.Notify
+	case RateLimitOrder:
+		return global.Config.RateLimit.Order
+	case RateLimitLogin:
+		return global.Config.RateLimit.Login
+	}
+	return config.RateLimitRule{}
+}
+
+func scopeLimiters(scope string) *rateLimiters {
+	rateLimitMu.Lock()
+	defer rateLimitMu.Unlock()
+	if l, ok := rateLimitScopes[scope]; ok {
+		return l
+	}
+	rule := rateLimitRule(scope)
+	l := &rateLimiters{}
+	if rule.IpRate > 0 {
+		l.ip = utils.NewRateLimiter(rule.IpRate, rule.IpBurst)
+	}
+	if rule.UserRate > 0 {
+		l.user = utils.NewRateLimiter(rule.UserRate, rule.UserBurst)
+	}
+	rateLimitScopes[scope] = l
+	return l
+}
+
+// RateLimit 按 IP 和登录用户限流, 超限返回 429 和 Retry-After
+// 按用户限流需要放在鉴权中间件之后, 未登录时只按 IP 限流
+func RateLimit(scope string) gin.HandlerFunc {
+	if !global.Config.RateLimit.Enable {
+		return func(c *gin.Context) { c.Next() }
+	}
+	l := scopeLimiters(scope)
+	return func(c *gin.Context) {
+		if l.ip != nil {
+			if ok, wait := l.ip.Allow(c.ClientIP()); !ok {
+				rateLimitReject(c, scope, "ip", wait)
+				return
+			}
+		}
+		if l.user != nil {
+			if u := service.AllService.UserService.CurUser(c); u != nil && u.Id > 0 {
+				if ok, wait := l.user.Allow(strconv.FormatUint(uint64(u.Id), 10)); !ok {
+					rateLimitReject(c, scope, "user", wait)
+					return
+				}
+			}
+		}
+		c.Next()
+	}
+}
+
+func rateLimitReject(c *gin.Context, scope, by string, wait time.Duration) {
+	service.MetricRateLimited.Inc(scope, by)
+	c.Header("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+	c.JSON(http.StatusTooManyRequests, gin.H{
+		"code":  http.StatusTooManyRequests,
+		"error": response.TranslateMsg(c, "TooManyRequests"),
+	})
+	c.Abort()
+}
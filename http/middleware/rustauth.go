@@ -29,6 +29,12 @@ func RustAuth() gin.HandlerFunc {
 		//这里只是简单的提取
 		token = token[7:]
 
+		//个人访问令牌
+		if service.IsApiToken(token) {
+			apiTokenAuth(c, token)
+			return
+		}
+
 		//验证token
 
 		//检查是否设置了jwt key
@@ -63,6 +69,7 @@ func RustAuth() gin.HandlerFunc {
 		c.Set("token", token)
 
 		service.AllService.UserService.AutoRefreshAccessToken(ut)
+		service.AllService.UserService.TouchAccessToken(ut, c.ClientIP())
 
 		c.Next()
 	}
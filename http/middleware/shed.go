@@ -0,0 +1,37 @@
+package middleware
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lejianwen/rustdesk-api/v2/http/response"
+	"github.com/lejianwen/rustdesk-api/v2/lib/shed"
+	"github.com/lejianwen/rustdesk-api/v2/service"
+)
+
+// InternalShed 内部接口过载保护
+// 超出自适应并发上限时: 低优先级请求直接返回 503 和 Retry-After;
+// 高优先级请求标记为过载后继续, 由处理函数返回最近的决策或拒绝, 不占用并发槽位
+func InternalShed(p shed.Priority) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ls := service.AllService.LoadShedService
+		if !ls.Enabled() {
+			c.Next()
+			return
+		}
+		route := c.FullPath()
+		if !ls.Acquire(route, p) {
+			if p >= shed.High {
+				ls.MarkOverloaded(c)
+				c.Next()
+				return
+			}
+			service.MetricInternalShed.Inc(route, "rejected")
+			response.Overloaded(c, ls.RetryAfter())
+			return
+		}
+		start := time.Now()
+		c.Next()
+		ls.Release(time.Since(start), c.Writer.Status() >= 500)
+	}
+}
@@ -1,8 +1,11 @@
 package middleware
 
 import (
+	"time"
+
 	"github.com/gin-gonic/gin"
 	"github.com/lejianwen/rustdesk-api/v2/http/response"
+	"github.com/lejianwen/rustdesk-api/v2/model"
 	"github.com/lejianwen/rustdesk-api/v2/service"
 )
 
@@ -11,7 +14,7 @@ import (
 func RequireSubscription() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// 检查支付功能是否启用
-		if !service.AllService.PaymentService.IsEnabled() {
+		if !service.AllService.Payment().IsEnabled() {
 			// 支付功能未启用,直接放行
 			c.Next()
 			return
@@ -34,7 +37,18 @@ func RequireSubscription() gin.HandlerFunc {
 		}
 
 		// 检查订阅状态
-		if !service.AllService.SubscriptionService.IsSubscriptionActive(user.Id) {
+		start := time.Now()
+		active := service.AllService.Subscription().IsSubscriptionActive(user.Id)
+		service.ObserveSubscriptionCheck("api", active, start)
+		if !active {
+			// 维护窗口期间放行, warn 模式下提示客户端
+			if m := service.AllService.MaintenanceService.Relax("api "+c.FullPath(), user.Id); m != nil {
+				if m.Mode == model.MaintenanceModeWarn {
+					c.Header("X-Subscription-Warning", "maintenance")
+				}
+				c.Next()
+				return
+			}
 			// 返回 402 Payment Required
 			response.Fail(c, 402, response.TranslateMsg(c, "SubscriptionRequired"))
 			c.Abort()
@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lejianwen/rustdesk-api/v2/lib/trace"
+)
+
+// Trace 为每个请求创建 server span, 沿用上游 traceparent, 并通过 X-Trace-Id 返回 trace id
+// 处理函数通过 c.Request.Context() 向下传递, 服务层据此关联 GORM 查询和出站请求
+func Trace() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !trace.Enabled() {
+			c.Next()
+			return
+		}
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		ctx := trace.Extract(c.Request.Context(), c.GetHeader("traceparent"))
+		ctx, span := trace.Start(ctx, c.Request.Method+" "+route, trace.KindServer)
+		defer span.End()
+
+		c.Request = c.Request.WithContext(ctx)
+		c.Header("X-Trace-Id", span.TraceID())
+		span.SetAttr("http.method", c.Request.Method)
+		span.SetAttr("http.route", route)
+		span.SetAttr("http.client_ip", c.ClientIP())
+
+		c.Next()
+
+		status := c.Writer.Status()
+		span.SetAttr("http.status_code", status)
+		if len(c.Errors) > 0 {
+			span.SetError(c.Errors.Last())
+		} else if status >= 500 {
+			span.SetError(errors.New(http.StatusText(status)))
+		}
+	}
+}
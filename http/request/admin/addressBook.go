@@ -109,6 +109,7 @@ func (sbwcf ShareByWebClientForm) ToShareRecord() *model.ShareRecord {
 
 type AddressBookCollectionQuery struct {
 	UserId int `form:"user_id"`
+	OrgId  int `form:"org_id"`
 	IsMy   int `form:"is_my"`
 	PageQuery
 }
@@ -133,3 +134,9 @@ type BatchUpdateTagsForm struct {
 	RowIds []uint   `json:"row_ids"`
 	Tags   []string `json:"tags"`
 }
+
+// BulkEditForm 批量编辑地址簿或设备, row_ids 与 aliases 中的记录都会处理
+type BulkEditForm struct {
+	RowIds []uint `json:"row_ids"`
+	model.BulkEdit
+}
@@ -12,3 +12,45 @@ type AuditConnLogIds struct {
 type AuditFileLogIds struct {
 	Ids []uint `json:"ids" validate:"required"`
 }
+
+type ConnectionLogQuery struct {
+	PageQuery
+	UserId       uint   `form:"user_id"`
+	PeerId       string `form:"peer_id"`
+	TargetPeerId string `form:"target_peer_id"`
+	Mode         string `form:"mode"`
+	Country      string `form:"country"` // 发起端国家/地区代码
+	City         string `form:"city"`
+	StartAt      int64  `form:"start_at"` // 开始时间范围(unix 秒)
+	EndAt        int64  `form:"end_at"`
+	Format       string `form:"format"` // 导出格式: csv(默认)/xlsx
+}
+
+type FileTransferLogQuery struct {
+	PageQuery
+	UserId       uint   `form:"user_id"`
+	PeerId       string `form:"peer_id"`
+	TargetPeerId string `form:"target_peer_id"`
+	Direction    string `form:"direction"`
+	FileName     string `form:"file_name"` // 模糊匹配
+	StartAt      int64  `form:"start_at"`  // 传输时间范围(unix 秒)
+	EndAt        int64  `form:"end_at"`
+	Format       string `form:"format"` // 导出格式: csv(默认)/xlsx
+}
+
+type SessionRecordingQuery struct {
+	PageQuery
+	UserId       uint   `form:"user_id"`
+	SessionId    string `form:"session_id"`
+	PeerId       string `form:"peer_id"`
+	TargetPeerId string `form:"target_peer_id"`
+	Hold         *bool  `form:"hold"`
+	StartAt      int64  `form:"start_at"` // 录制时间范围(unix 秒)
+	EndAt        int64  `form:"end_at"`
+}
+
+type SessionRecordingHoldForm struct {
+	Id     uint   `json:"id" validate:"required,gt=0"`
+	Hold   bool   `json:"hold"`
+	Remark string `json:"remark" validate:"max=255"`
+}
@@ -0,0 +1,21 @@
+package admin
+
+import "github.com/lejianwen/rustdesk-api/v2/model"
+
+type ConnPermissionForm struct {
+	ViewOnly            bool `json:"view_only"`
+	DisableFileTransfer bool `json:"disable_file_transfer"`
+	DisableClipboard    bool `json:"disable_clipboard"`
+	DisableAudio        bool `json:"disable_audio"`
+	DisableTerminal     bool `json:"disable_terminal"`
+}
+
+func (f *ConnPermissionForm) ToConnPermission() *model.ConnPermission {
+	return &model.ConnPermission{
+		ViewOnly:            f.ViewOnly,
+		DisableFileTransfer: f.DisableFileTransfer,
+		DisableClipboard:    f.DisableClipboard,
+		DisableAudio:        f.DisableAudio,
+		DisableTerminal:     f.DisableTerminal,
+	}
+}
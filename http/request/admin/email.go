@@ -0,0 +1,47 @@
+package admin
+
+import "github.com/lejianwen/rustdesk-api/v2/model"
+
+type EmailConfigForm struct {
+	Enable     bool   `json:"enable"`
+	Host       string `json:"host" validate:"required_if=Enable true"`
+	Port       int    `json:"port" validate:"omitempty,gt=0,lte=65535"`
+	Username   string `json:"username"`
+	Password   string `json:"password"` // 为空或为脱敏值时保留原密码
+	From       string `json:"from" validate:"omitempty,email"`
+	FromName   string `json:"from_name"`
+	Encryption string `json:"encryption" validate:"omitempty,oneof=none starttls tls"`
+}
+
+func (f *EmailConfigForm) ToEmailConfig() *model.EmailConfig {
+	cfg := &model.EmailConfig{
+		Enable:     f.Enable,
+		Host:       f.Host,
+		Port:       f.Port,
+		Username:   f.Username,
+		Password:   f.Password,
+		From:       f.From,
+		FromName:   f.FromName,
+		Encryption: f.Encryption,
+	}
+	if cfg.Encryption == "" {
+		cfg.Encryption = model.EmailEncryptionStartTLS
+	}
+	return cfg
+}
+
+type EmailTestForm struct {
+	To string `json:"to" validate:"required,email"`
+}
+
+type EmailLogQuery struct {
+	UserId int    `form:"user_id"`
+	Kind   string `form:"kind"`
+	Status *int   `form:"status"`
+	PageQuery
+}
+
+type EmailResendForm struct {
+	Id       uint `json:"id" validate:"required,gt=0"`
+	Rerender bool `json:"rerender"` // 按当前数据重新生成(仅支付回执和退款确认)
+}
@@ -0,0 +1,23 @@
+package admin
+
+import "github.com/lejianwen/rustdesk-api/v2/model"
+
+type InternalKeyForm struct {
+	Id       uint             `json:"id"`
+	Name     string           `json:"name" validate:"required,max=64"` // 密钥ID, 创建后不可修改
+	Status   model.StatusCode `json:"status" validate:"oneof=1 2"`
+	ExpireAt int64            `json:"expire_at" validate:"gte=0"` // 0 表示不过期
+}
+
+func (f *InternalKeyForm) ToInternalKey() *model.InternalKey {
+	k := &model.InternalKey{}
+	k.Id = f.Id
+	k.Name = f.Name
+	k.Status = f.Status
+	k.ExpireAt = f.ExpireAt
+	return k
+}
+
+type InternalKeyQuery struct {
+	PageQuery
+}
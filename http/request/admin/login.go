@@ -21,3 +21,20 @@ type LoginTokenQuery struct {
 type LoginLogIds struct {
 	Ids []uint `json:"ids" validate:"required"`
 }
+
+type AdminLogQuery struct {
+	UserId int    `form:"user_id"`
+	Action string `form:"action"`
+	PageQuery
+}
+
+type AdminAuditQuery struct {
+	UserId   int    `form:"user_id"`
+	Action   string `form:"action"`
+	TargetId int    `form:"target_id"`
+	PageQuery
+}
+
+type AdminActivityQuery struct {
+	Days int `form:"days"` // 统计窗口(天), 默认7
+}
@@ -0,0 +1,32 @@
+package admin
+
+import "github.com/lejianwen/rustdesk-api/v2/model"
+
+type MaintenanceForm struct {
+	Id      uint             `json:"id"`
+	Title   string           `json:"title" validate:"required,max=128"`
+	Reason  string           `json:"reason"`
+	StartAt int64            `json:"start_at" validate:"required,gt=0"`
+	EndAt   int64            `json:"end_at" validate:"required,gtfield=StartAt"`
+	Mode    string           `json:"mode" validate:"omitempty,oneof=warn bypass"` // 默认 warn
+	Status  model.StatusCode `json:"status" validate:"oneof=1 2"`
+}
+
+func (f *MaintenanceForm) ToMaintenanceWindow() *model.MaintenanceWindow {
+	m := &model.MaintenanceWindow{}
+	m.Id = f.Id
+	m.Title = f.Title
+	m.Reason = f.Reason
+	m.StartAt = f.StartAt
+	m.EndAt = f.EndAt
+	m.Mode = f.Mode
+	if m.Mode == "" {
+		m.Mode = model.MaintenanceModeWarn
+	}
+	m.Status = f.Status
+	return m
+}
+
+type MaintenanceQuery struct {
+	PageQuery
+}
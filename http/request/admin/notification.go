@@ -0,0 +1,11 @@
+package admin
+
+type AnnouncementForm struct {
+	Id      uint   `json:"id"`
+	Title   string `json:"title" validate:"required,max=255"`
+	Content string `json:"content" validate:"required"`
+}
+
+type AnnouncementQuery struct {
+	PageQuery
+}
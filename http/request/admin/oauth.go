@@ -25,6 +25,9 @@ type OauthForm struct {
 	AutoRegister *bool  `json:"auto_register"`
 	PkceEnable   *bool  `json:"pkce_enable"`
 	PkceMethod   string `json:"pkce_method"`
+
+	GroupsClaim   string                    `json:"groups_claim"`
+	GroupMappings []model.OauthGroupMapping `json:"group_mappings"`
 }
 
 func (of *OauthForm) ToOauth() *model.Oauth {
@@ -38,7 +41,9 @@ func (of *OauthForm) ToOauth() *model.Oauth {
 		Scopes:       of.Scopes,
 		PkceEnable:   of.PkceEnable,
 		PkceMethod:   of.PkceMethod,
+		GroupsClaim:  of.GroupsClaim,
 	}
+	oa.SetGroupMappings(of.GroupMappings)
 	oa.Id = of.Id
 	return oa
 }
@@ -46,8 +46,50 @@ type PeerQuery struct {
 	Ip       string `json:"ip" form:"ip"`
 	Username string `json:"username" form:"username"`
 	Alias    string `json:"alias" form:"alias"`
+	Country  string `json:"country" form:"country"` // ISO 国家/地区代码
 }
 
 type SimpleDataQuery struct {
 	Ids []string `json:"ids" form:"ids"`
 }
+
+type PeerStaleQuery struct {
+	PageQuery
+	Orphan       bool `json:"orphan" form:"orphan"`
+	InactiveDays int  `json:"inactive_days" form:"inactive_days"`
+}
+
+type PeerCleanupForm struct {
+	Orphan       bool `json:"orphan"`
+	InactiveDays int  `json:"inactive_days" validate:"gte=0"`
+}
+
+type PeerDeactivateForm struct {
+	RowId uint `json:"row_id" validate:"required,gt=0"`
+}
+
+type DeviceApprovalQuery struct {
+	PageQuery
+	Status int  `json:"status" form:"status,default=-1"` // -1 全部
+	UserId uint `json:"user_id" form:"user_id"`
+}
+
+type DeviceApprovalReviewForm struct {
+	Id uint `json:"id" validate:"required,gt=0"`
+}
+
+type PeerBanForm struct {
+	PeerId   string `json:"peer_id" validate:"max=100"`
+	Uuid     string `json:"uuid" validate:"max=128"`
+	Reason   string `json:"reason" validate:"max=255"`
+	ExpireAt int64  `json:"expire_at" validate:"gte=0"` // 解封时间, 0 表示永久
+}
+
+type PeerBanQuery struct {
+	PageQuery
+	PeerId string `json:"peer_id" form:"peer_id"`
+}
+
+type PeerUnbanForm struct {
+	Id uint `json:"id" validate:"required,gt=0"`
+}
@@ -0,0 +1,63 @@
+package admin
+
+import "github.com/lejianwen/rustdesk-api/v2/model"
+
+type RelayWhitelistQuery struct {
+	Uuid string `form:"uuid"` // uuid 包含匹配
+}
+
+type RelayWhitelistDeleteForm struct {
+	Uuid string `json:"uuid" validate:"required"`
+}
+
+type RelaySessionQuery struct {
+	UserId uint   `form:"user_id"`
+	PeerId string `form:"peer_id"` // 发起端或被控端设备ID
+	Status int    `form:"status"`
+	Since  int64  `form:"since"` // 开始时间 >= since
+	Until  int64  `form:"until"` // 开始时间 < until
+	PageQuery
+}
+
+type RelayUsageQuery struct {
+	UserId uint  `form:"user_id"`
+	Since  int64 `form:"since"` // 默认最近30天
+	Until  int64 `form:"until"` // 默认当前时间
+}
+
+type RelayIncidentQuery struct {
+	Kind   string `form:"kind"`
+	Uuid   string `form:"uuid"`
+	UserId uint   `form:"user_id"`
+	PageQuery
+}
+
+type RelayGeoRuleForm struct {
+	Id        uint             `json:"id"`
+	Scope     string           `json:"scope" validate:"required,oneof=group plan"`
+	ScopeId   uint             `json:"scope_id" validate:"required,gt=0"`
+	Mode      string           `json:"mode" validate:"required,oneof=allow deny"`
+	Countries string           `json:"countries"` // ISO 国家/地区代码, 逗号分隔
+	Cidrs     string           `json:"cidrs"`     // IP 或 CIDR, 逗号或换行分隔
+	Status    model.StatusCode `json:"status" validate:"oneof=1 2"`
+	Remark    string           `json:"remark" validate:"max=255"`
+}
+
+func (f *RelayGeoRuleForm) ToRelayGeoRule() *model.RelayGeoRule {
+	r := &model.RelayGeoRule{}
+	r.Id = f.Id
+	r.Scope = f.Scope
+	r.ScopeId = f.ScopeId
+	r.Mode = f.Mode
+	r.Countries = f.Countries
+	r.Cidrs = f.Cidrs
+	r.Status = f.Status
+	r.Remark = f.Remark
+	return r
+}
+
+type RelayGeoRuleQuery struct {
+	Scope   string `form:"scope"`
+	ScopeId uint   `form:"scope_id"`
+	PageQuery
+}
@@ -0,0 +1,10 @@
+package admin
+
+type SettingUpdateForm struct {
+	Key   string      `json:"key" validate:"required"`
+	Value interface{} `json:"value" swaggertype:"object"` // 类型由设置定义决定
+}
+
+type SettingResetForm struct {
+	Key string `json:"key" validate:"required"`
+}
@@ -0,0 +1,31 @@
+package admin
+
+import "github.com/lejianwen/rustdesk-api/v2/model"
+
+type StorageConfigForm struct {
+	Driver      string `json:"driver" validate:"required,oneof=local s3"`
+	LocalDir    string `json:"local_dir"`
+	UrlExpire   int    `json:"url_expire" validate:"omitempty,gte=60,lte=604800"` // 秒
+	S3Endpoint  string `json:"s3_endpoint" validate:"required_if=Driver s3"`
+	S3Region    string `json:"s3_region"`
+	S3Bucket    string `json:"s3_bucket" validate:"required_if=Driver s3"`
+	S3AccessKey string `json:"s3_access_key"`
+	S3SecretKey string `json:"s3_secret_key"` // 为空或为脱敏值时保留原密钥
+	S3PathStyle bool   `json:"s3_path_style"`
+	S3Prefix    string `json:"s3_prefix"`
+}
+
+func (f *StorageConfigForm) ToStorageConfig() *model.StorageConfig {
+	return &model.StorageConfig{
+		Driver:      f.Driver,
+		LocalDir:    f.LocalDir,
+		UrlExpire:   f.UrlExpire,
+		S3Endpoint:  f.S3Endpoint,
+		S3Region:    f.S3Region,
+		S3Bucket:    f.S3Bucket,
+		S3AccessKey: f.S3AccessKey,
+		S3SecretKey: f.S3SecretKey,
+		S3PathStyle: f.S3PathStyle,
+		S3Prefix:    f.S3Prefix,
+	}
+}
@@ -0,0 +1,53 @@
+package admin
+
+import "github.com/lejianwen/rustdesk-api/v2/model"
+
+type StrategyProfileForm struct {
+	Id                      uint             `json:"id"`
+	Name                    string           `json:"name" validate:"required,max=64"`
+	DisableFileTransfer     bool             `json:"disable_file_transfer"`
+	DisableClipboard        bool             `json:"disable_clipboard"`
+	DisableKeyboard         bool             `json:"disable_keyboard"`
+	DisableAudio            bool             `json:"disable_audio"`
+	DisableRecording        bool             `json:"disable_recording"`
+	AutoRecordIncoming      bool             `json:"auto_record_incoming"`
+	VerificationMethod      string           `json:"verification_method" validate:"omitempty,oneof=temporary permanent both"`
+	TemporaryPasswordLength int              `json:"temporary_password_length" validate:"omitempty,oneof=6 8 10"`
+	AuditFileTransfer       bool             `json:"audit_file_transfer"`
+	Status                  model.StatusCode `json:"status" validate:"oneof=1 2"`
+	Remark                  string           `json:"remark" validate:"max=255"`
+}
+
+func (f *StrategyProfileForm) ToStrategyProfile() *model.StrategyProfile {
+	p := &model.StrategyProfile{}
+	p.Id = f.Id
+	p.Name = f.Name
+	p.DisableFileTransfer = f.DisableFileTransfer
+	p.DisableClipboard = f.DisableClipboard
+	p.DisableKeyboard = f.DisableKeyboard
+	p.DisableAudio = f.DisableAudio
+	p.DisableRecording = f.DisableRecording
+	p.AutoRecordIncoming = f.AutoRecordIncoming
+	p.VerificationMethod = f.VerificationMethod
+	p.TemporaryPasswordLength = f.TemporaryPasswordLength
+	p.AuditFileTransfer = f.AuditFileTransfer
+	p.Status = f.Status
+	p.Remark = f.Remark
+	return p
+}
+
+type StrategyAssignForm struct {
+	Scope     string `json:"scope" validate:"required,oneof=group plan"`
+	ScopeId   uint   `json:"scope_id" validate:"required,gt=0"` // 用户组ID或套餐ID
+	ProfileId uint   `json:"profile_id" validate:"required,gt=0"`
+}
+
+type StrategyAssignmentQuery struct {
+	PageQuery
+	Scope     string `form:"scope"`
+	ProfileId uint   `form:"profile_id"`
+}
+
+type StrategyUnassignForm struct {
+	Id uint `json:"id" validate:"required,gt=0"`
+}
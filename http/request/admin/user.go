@@ -9,12 +9,13 @@ type UserForm struct {
 	Username string `json:"username" validate:"required,gte=2,lte=32"`
 	Email    string `json:"email"` //validate:"required,email" email不强制
 	//Password string           `json:"password" validate:"required,gte=4,lte=20"`
-	Nickname string           `json:"nickname"`
-	Avatar   string           `json:"avatar"`
-	GroupId  uint             `json:"group_id" validate:"required"`
-	IsAdmin  *bool            `json:"is_admin" `
-	Status   model.StatusCode `json:"status" validate:"required,gte=0"`
-	Remark   string           `json:"remark"`
+	Nickname  string           `json:"nickname"`
+	Avatar    string           `json:"avatar"`
+	GroupId   uint             `json:"group_id" validate:"required"`
+	IsAdmin   *bool            `json:"is_admin" `
+	AdminRole string           `json:"admin_role" validate:"omitempty,oneof=super_admin finance support"` // 为空时不修改
+	Status    model.StatusCode `json:"status" validate:"required,gte=0"`
+	Remark    string           `json:"remark"`
 }
 
 func (uf *UserForm) FromUser(user *model.User) *UserForm {
@@ -25,6 +26,7 @@ func (uf *UserForm) FromUser(user *model.User) *UserForm {
 	uf.Avatar = user.Avatar
 	uf.GroupId = user.GroupId
 	uf.IsAdmin = user.IsAdmin
+	uf.AdminRole = user.AdminRole
 	uf.Status = user.Status
 	uf.Remark = user.Remark
 	return uf
@@ -38,6 +40,7 @@ func (uf *UserForm) ToUser() *model.User {
 	user.Avatar = uf.Avatar
 	user.GroupId = uf.GroupId
 	user.IsAdmin = uf.IsAdmin
+	user.AdminRole = uf.AdminRole
 	user.Status = uf.Status
 	user.Remark = uf.Remark
 	return user
@@ -71,6 +74,7 @@ type RegisterForm struct {
 	Email           string `json:"email"` // validate:"required,email"
 	Password        string `json:"password" validate:"required,gte=4,lte=32"`
 	ConfirmPassword string `json:"confirm_password" validate:"required,gte=4,lte=32"`
+	ReferralCode    string `json:"referral_code" validate:"max=32"` // 推荐码(可选)
 }
 
 type UserTokenBatchDeleteForm struct {
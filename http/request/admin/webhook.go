@@ -0,0 +1,52 @@
+package admin
+
+import (
+	"strings"
+
+	"github.com/lejianwen/rustdesk-api/v2/model"
+)
+
+type WebhookForm struct {
+	Id     uint             `json:"id"`
+	Name   string           `json:"name" validate:"required"`
+	Url    string           `json:"url" validate:"required,url,startswith=http"`
+	Secret string           `json:"secret"` // 为空时创建自动生成, 更新时保留原值
+	Events []string         `json:"events" validate:"omitempty,dive,oneof=order.paid order.refunded subscription.activated subscription.expired relay.abuse_detected user.banned subscription.anomaly product.purchased product.fulfilled"`
+	Status model.StatusCode `json:"status" validate:"oneof=1 2"`
+}
+
+func (f *WebhookForm) ToWebhookEndpoint() *model.WebhookEndpoint {
+	e := &model.WebhookEndpoint{}
+	e.Id = f.Id
+	e.Name = f.Name
+	e.Url = f.Url
+	e.Secret = f.Secret
+	e.Events = strings.Join(f.Events, ",")
+	e.Status = f.Status
+	return e
+}
+
+type WebhookQuery struct {
+	PageQuery
+}
+
+type WebhookDeliveryQuery struct {
+	EndpointId int    `form:"endpoint_id"`
+	Event      string `form:"event"`
+	Status     *int   `form:"status"`
+	PageQuery
+}
+
+type WebhookRedeliverForm struct {
+	Id uint `json:"id" validate:"required,gt=0"`
+}
+
+type WebhookRequeueForm struct {
+	EndpointId uint  `json:"endpoint_id" validate:"required,gt=0"`
+	Since      int64 `json:"since" validate:"gte=0"` // 只重新排队该时间(unix 秒)之后创建的失败记录
+}
+
+type WebhookTestForm struct {
+	EndpointId uint   `json:"endpoint_id" validate:"required,gt=0"`
+	Event      string `json:"event" validate:"required,oneof=order.paid order.refunded subscription.activated subscription.expired relay.abuse_detected user.banned subscription.anomaly product.purchased product.fulfilled"`
+}
@@ -76,4 +76,6 @@ type PeerInfoInHeartbeat struct {
 	Id   string `json:"id"`
 	Uuid string `json:"uuid"`
 	Ver  int    `json:"ver"`
+	// 客户端本地策略的修改时间, 与服务端不一致时下发策略
+	ModifiedAt int64 `json:"modified_at"`
 }
@@ -3,12 +3,15 @@ package admin
 import "github.com/lejianwen/rustdesk-api/v2/model"
 
 type LoginPayload struct {
-	Username   string   `json:"username"`
-	Email      string   `json:"email"`
-	Avatar     string   `json:"avatar"`
-	Token      string   `json:"token"`
-	RouteNames []string `json:"route_names"`
-	Nickname   string   `json:"nickname"`
+	Username     string   `json:"username"`
+	Email        string   `json:"email"`
+	Avatar       string   `json:"avatar"`
+	Token        string   `json:"token"`
+	RefreshToken string   `json:"refresh_token,omitempty"` // 启用 app.refresh-token-expire 时返回, 通过 /api/refresh 刷新
+	RouteNames   []string `json:"route_names"`
+	Nickname     string   `json:"nickname"`
+	AdminRole    string   `json:"admin_role,omitempty"` // 管理员角色
+	Permissions  []string `json:"permissions"`          // 后台权限, * 表示全部
 }
 
 func (lp *LoginPayload) FromUser(user *model.User) {
@@ -50,9 +50,36 @@ func (up *UserPayload) FromUser(user *model.User) *UserPayload {
 	}
 */
 type LoginRes struct {
-	Type        string      `json:"type"`
-	AccessToken string      `json:"access_token"`
-	User        UserPayload `json:"user"`
-	Secret      string      `json:"secret,omitempty"`
-	TfaType     string      `json:"tfa_type,omitempty"`
+	Type         string      `json:"type"`
+	AccessToken  string      `json:"access_token"`
+	RefreshToken string      `json:"refresh_token,omitempty"` // 启用 app.refresh-token-expire 时返回
+	User         UserPayload `json:"user"`
+	Secret       string      `json:"secret,omitempty"`
+	TfaType      string      `json:"tfa_type,omitempty"`
+}
+
+// SessionPayload 登录会话, 不包含令牌
+type SessionPayload struct {
+	Id               uint   `json:"id"`
+	Client           string `json:"client"`
+	Platform         string `json:"platform"`
+	DeviceId         string `json:"device_id"`
+	Ip               string `json:"ip"`
+	LastActiveAt     int64  `json:"last_active_at"`
+	ExpiredAt        int64  `json:"expired_at"`
+	RefreshExpiredAt int64  `json:"refresh_expired_at"`
+	Current          bool   `json:"current"` // 是否为发起请求的会话
+}
+
+func (sp *SessionPayload) FromUserToken(ut *model.UserToken, current bool) *SessionPayload {
+	sp.Id = ut.Id
+	sp.Client = ut.Client
+	sp.Platform = ut.Platform
+	sp.DeviceId = ut.DeviceId
+	sp.Ip = ut.Ip
+	sp.LastActiveAt = ut.LastActiveAt
+	sp.ExpiredAt = ut.ExpiredAt
+	sp.RefreshExpiredAt = ut.RefreshExpiredAt
+	sp.Current = current
+	return sp
 }
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/lejianwen/rustdesk-api/v2/global"
+	"github.com/lejianwen/rustdesk-api/v2/utils"
 	"github.com/nicksnyder/go-i18n/v2/i18n"
 	"net/http"
 )
@@ -28,7 +29,11 @@ type ErrorResponse struct {
 	Error string `json:"error"`
 }
 
+// codeKey 记录本次请求返回的业务 code, 见 Succeeded
+const codeKey = "response_code"
+
 func SendResponse(c *gin.Context, code int, message string, data interface{}) {
+	c.Set(codeKey, code)
 	c.JSON(http.StatusOK, Response{
 		code, message, data,
 	})
@@ -38,6 +43,14 @@ func Success(c *gin.Context, data interface{}) {
 	SendResponse(c, 0, "success", data)
 }
 
+// Succeeded 请求是否成功: HTTP 状态为 2xx 且业务 code 为 0(未通过 SendResponse 返回时只看状态)
+func Succeeded(c *gin.Context) bool {
+	if s := c.Writer.Status(); s < 200 || s >= 300 {
+		return false
+	}
+	return c.GetInt(codeKey) == 0
+}
+
 func Fail(c *gin.Context, code int, message string) {
 	SendResponse(c, code, message, nil)
 }
@@ -55,6 +68,15 @@ type ServerConfigResponse struct {
 	ApiServer   string `json:"api_server"`
 }
 
+// Locale 按请求的 Accept-Language 和 X-Timezone(IANA 时区名, 也可用 tz 参数) 格式化展示字段
+func Locale(c *gin.Context) *utils.Locale {
+	tz := c.GetHeader("X-Timezone")
+	if tz == "" {
+		tz = c.Query("tz")
+	}
+	return utils.NewLocale(c.GetHeader("Accept-Language"), tz)
+}
+
 func TranslateMsg(c *gin.Context, messageId string) string {
 	localizer := global.Localizer(c.GetHeader("Accept-Language"))
 	errMsg, err := localizer.LocalizeMessage(&i18n.Message{
@@ -99,3 +121,13 @@ func TranslateParamMsg(c *gin.Context, messageId string, params ...string) strin
 	}
 	return errMsg
 }
+
+// Overloaded 服务过载, 返回 503 和 Retry-After(秒)
+func Overloaded(c *gin.Context, retryAfter int) {
+	c.Header("Retry-After", fmt.Sprint(retryAfter))
+	c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{
+		"code":        http.StatusServiceUnavailable,
+		"error":       "Service overloaded, retry later",
+		"retry_after": retryAfter,
+	})
+}
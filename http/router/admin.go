@@ -7,6 +7,7 @@ import (
 	"github.com/lejianwen/rustdesk-api/v2/http/controller/admin"
 	"github.com/lejianwen/rustdesk-api/v2/http/controller/admin/my"
 	"github.com/lejianwen/rustdesk-api/v2/http/middleware"
+	"github.com/lejianwen/rustdesk-api/v2/model"
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
 )
@@ -20,12 +21,13 @@ func Init(g *gin.Engine) {
 	}
 
 	adg := g.Group("/api/admin")
+	adg.Use(middleware.AdminIpAllow())
 	LoginBind(adg)
 	adg.POST("/user/register", (&admin.User{}).Register)
 
 	ConfigBind(adg)
 
-	adg.Use(middleware.BackendUserAuth())
+	adg.Use(middleware.BackendUserAuth(), middleware.AdminActionLog())
 	//FileBind(adg)
 	UserBind(adg)
 	GroupBind(adg)
@@ -34,6 +36,7 @@ func Init(g *gin.Engine) {
 	PeerBind(adg)
 	OauthBind(adg)
 	LoginLogBind(adg)
+	AdminLogBind(adg)
 	AuditBind(adg)
 	AddressBookCollectionBind(adg)
 	AddressBookCollectionRuleBind(adg)
@@ -51,6 +54,15 @@ func Init(g *gin.Engine) {
 	RustdeskCmdBind(adg)
 	DeviceGroupBind(adg)
 	PaymentBind(adg)
+	WebhookBind(adg)
+	EmailBind(adg)
+	StorageBind(adg)
+	SettingBind(adg)
+	RelayBind(adg)
+	InternalKeyBind(adg)
+	StrategyBind(adg)
+	MaintenanceBind(adg)
+	AnnouncementBind(adg)
 	//访问静态文件
 	//g.StaticFS("/upload", http.Dir(global.Config.Gin.ResourcesPath+"/upload"))
 }
@@ -65,7 +77,7 @@ func RustdeskCmdBind(adg *gin.RouterGroup) {
 }
 func LoginBind(rg *gin.RouterGroup) {
 	cont := &admin.Login{}
-	rg.POST("/login", cont.Login)
+	rg.POST("/login", middleware.RateLimit(middleware.RateLimitLogin), cont.Login)
 	rg.GET("/captcha", cont.Captcha)
 	rg.POST("/logout", cont.Logout)
 	rg.GET("/login-options", cont.LoginOptions)
@@ -83,11 +95,15 @@ func UserBind(rg *gin.RouterGroup) {
 		//aR.GET("/myPeer", cont.MyPeer)
 		aR.POST("/groupUsers", cont.GroupUsers)
 	}
+	aRV := rg.Group("/user").Use(middleware.AdminPermission(model.PermUserView))
+	{
+		cont := &admin.User{}
+		aRV.GET("/list", cont.List)
+		aRV.GET("/detail/:id", cont.Detail)
+	}
 	aRP := rg.Group("/user").Use(middleware.AdminPrivilege())
 	{
 		cont := &admin.User{}
-		aRP.GET("/list", cont.List)
-		aRP.GET("/detail/:id", cont.Detail)
 		aRP.POST("/create", cont.Create)
 		aRP.POST("/update", cont.Update)
 		aRP.POST("/delete", cont.Delete)
@@ -145,6 +161,7 @@ func AddressBookBind(rg *gin.RouterGroup) {
 		arp.POST("/delete", cont.Delete)
 		arp.POST("/batchCreate", cont.BatchCreate)
 		arp.POST("/batchCreateFromPeers", cont.BatchCreateFromPeers)
+		arp.POST("/bulkEdit", cont.BulkEdit)
 
 	}
 }
@@ -160,6 +177,15 @@ func PeerBind(rg *gin.RouterGroup) {
 		aR.POST("/update", cont.Update)
 		aR.POST("/delete", cont.Delete)
 		aR.POST("/batchDelete", cont.BatchDelete)
+		aR.POST("/bulkEdit", cont.BulkEdit)
+		aR.GET("/stale", cont.Stale)
+		aR.POST("/cleanup", cont.Cleanup)
+		aR.GET("/approval/list", cont.ApprovalList)
+		aR.POST("/approval/approve", cont.ApprovalApprove)
+		aR.POST("/approval/reject", cont.ApprovalReject)
+		aR.GET("/ban/list", cont.BanList)
+		aR.POST("/ban", cont.Ban)
+		aR.POST("/unban", cont.Unban)
 	}
 }
 
@@ -192,6 +218,15 @@ func LoginLogBind(rg *gin.RouterGroup) {
 	aR.POST("/delete", cont.Delete)
 	aR.POST("/batchDelete", cont.BatchDelete)
 }
+func AdminLogBind(rg *gin.RouterGroup) {
+	cont := &admin.AdminLog{}
+	aR := rg.Group("/admin_log").Use(middleware.AdminPrivilege())
+	aR.GET("/list", cont.List)
+	aR.GET("/activity", cont.Activity)
+
+	auR := rg.Group("/audit").Use(middleware.AdminPrivilege())
+	auR.GET("/list", cont.AuditList)
+}
 func AuditBind(rg *gin.RouterGroup) {
 	cont := &admin.Audit{}
 	aR := rg.Group("/audit_conn").Use(middleware.AdminPrivilege())
@@ -202,6 +237,17 @@ func AuditBind(rg *gin.RouterGroup) {
 	afR.GET("/list", cont.FileList)
 	afR.POST("/delete", cont.FileDelete)
 	afR.POST("/batchDelete", cont.BatchFileDelete)
+	asR := rg.Group("/audit_session").Use(middleware.AdminPrivilege())
+	asR.GET("/list", cont.SessionList)
+	asR.GET("/export", cont.SessionExport)
+	aftR := rg.Group("/audit_file_transfer").Use(middleware.AdminPrivilege())
+	aftR.GET("/list", cont.FileTransferList)
+	aftR.GET("/export", cont.FileTransferExport)
+	arR := rg.Group("/audit_recording").Use(middleware.AdminPrivilege())
+	arR.GET("/list", cont.RecordingList)
+	arR.GET("/download/:id", cont.RecordingDownload)
+	arR.POST("/hold", cont.RecordingHold)
+	arR.POST("/delete", cont.RecordingDelete)
 }
 func AddressBookCollectionBind(rg *gin.RouterGroup) {
 	aR := rg.Group("/address_book_collection").Use(middleware.AdminPrivilege())
@@ -232,6 +278,8 @@ func UserTokenBind(rg *gin.RouterGroup) {
 	aR.GET("/list", cont.List)
 	aR.POST("/delete", cont.Delete)
 	aR.POST("/batchDelete", cont.BatchDelete)
+	aR.GET("/sessions", cont.Sessions)
+	aR.POST("/revokeUser", cont.RevokeUser)
 }
 func ConfigBind(rg *gin.RouterGroup) {
 	aR := rg.Group("/config")
@@ -302,9 +350,16 @@ func MyBind(rg *gin.RouterGroup) {
 	{
 		cont := &my.Peer{}
 		rg.GET("/my/peer/list", cont.List)
+		rg.POST("/my/peer/deactivate", cont.Deactivate)
 
 	}
 
+	{
+		cont := &my.ConnPermission{}
+		rg.GET("/my/conn_permission/detail", cont.Detail)
+		rg.POST("/my/conn_permission/save", cont.Save)
+	}
+
 	{
 		cont := &my.LoginLog{}
 		rg.GET("/my/login_log/list", cont.List)
@@ -324,42 +379,207 @@ func ShareRecordBind(rg *gin.RouterGroup) {
 
 }
 
+func WebhookBind(rg *gin.RouterGroup) {
+	aR := rg.Group("/webhook").Use(middleware.AdminPrivilege())
+	cont := &admin.Webhook{}
+	aR.GET("/list", cont.List)
+	aR.POST("/create", cont.Create)
+	aR.POST("/update", cont.Update)
+	aR.POST("/delete", cont.Delete)
+	aR.GET("/deliveries", cont.Deliveries)
+	aR.POST("/redeliver", cont.Redeliver)
+	aR.POST("/requeue", cont.Requeue)
+	aR.POST("/test", cont.Test)
+}
+
+func RelayBind(rg *gin.RouterGroup) {
+	aR := rg.Group("/relay").Use(middleware.AdminPrivilege())
+	cont := &admin.Relay{}
+	aR.GET("/whitelist", cont.Whitelist)
+	aR.POST("/whitelist/delete", cont.WhitelistDelete)
+	aR.POST("/whitelist/flush", cont.WhitelistFlush)
+	aR.GET("/sessions", cont.Sessions)
+	aR.GET("/usage", cont.Usage)
+	aR.GET("/incidents", cont.Incidents)
+	aR.GET("/geo_rule/list", cont.GeoRuleList)
+	aR.POST("/geo_rule/create", cont.GeoRuleCreate)
+	aR.POST("/geo_rule/update", cont.GeoRuleUpdate)
+	aR.POST("/geo_rule/delete", cont.GeoRuleDelete)
+}
+
+func StrategyBind(rg *gin.RouterGroup) {
+	aR := rg.Group("/strategy").Use(middleware.AdminPrivilege())
+	cont := &admin.Strategy{}
+	aR.GET("/list", cont.List)
+	aR.POST("/create", cont.Create)
+	aR.POST("/update", cont.Update)
+	aR.POST("/delete", cont.Delete)
+	aR.GET("/assignments", cont.Assignments)
+	aR.POST("/assign", cont.Assign)
+	aR.POST("/unassign", cont.Unassign)
+}
+
+func InternalKeyBind(rg *gin.RouterGroup) {
+	aR := rg.Group("/internal_key").Use(middleware.AdminPrivilege())
+	cont := &admin.InternalKey{}
+	aR.GET("/list", cont.List)
+	aR.POST("/create", cont.Create)
+	aR.POST("/update", cont.Update)
+	aR.POST("/delete", cont.Delete)
+}
+
+func MaintenanceBind(rg *gin.RouterGroup) {
+	aR := rg.Group("/maintenance").Use(middleware.AdminPrivilege())
+	cont := &admin.Maintenance{}
+	aR.GET("/list", cont.List)
+	aR.POST("/create", cont.Create)
+	aR.POST("/update", cont.Update)
+	aR.POST("/delete", cont.Delete)
+}
+
+func AnnouncementBind(rg *gin.RouterGroup) {
+	aR := rg.Group("/announcement").Use(middleware.AdminPrivilege())
+	cont := &admin.Announcement{}
+	aR.GET("/list", cont.List)
+	aR.POST("/create", cont.Create)
+	aR.POST("/delete", cont.Delete)
+}
+
+func EmailBind(rg *gin.RouterGroup) {
+	aR := rg.Group("/email").Use(middleware.AdminPrivilege())
+	cont := &admin.Email{}
+	aR.GET("/config", cont.ConfigGet)
+	aR.POST("/config", cont.ConfigSave)
+	aR.POST("/test", cont.Test)
+	aR.GET("/logs", cont.Logs)
+	aR.POST("/resend", cont.Resend)
+}
+
+func StorageBind(rg *gin.RouterGroup) {
+	aR := rg.Group("/storage").Use(middleware.AdminPrivilege())
+	cont := &admin.Storage{}
+	aR.GET("/config", cont.ConfigGet)
+	aR.POST("/config", cont.ConfigSave)
+	aR.POST("/test", cont.Test)
+}
+
+func SettingBind(rg *gin.RouterGroup) {
+	aR := rg.Group("/settings").Use(middleware.AdminPrivilege())
+	cont := &admin.Setting{}
+	aR.GET("/list", cont.List)
+	aR.POST("/update", cont.Update)
+	aR.POST("/reset", cont.Reset)
+}
+
 func PaymentBind(rg *gin.RouterGroup) {
 	cont := &admin.Payment{}
+	view := middleware.AdminPermission(model.PermOrderView)
+	plan := middleware.AdminPermission(model.PermPlanManage)
+	manage := middleware.AdminPermission(model.PermOrderManage)
+	grant := middleware.AdminPermission(model.PermSubscriptionGrant)
+	payConfig := middleware.AdminPermission(model.PermPaymentConfig)
 
 	// 套餐管理
-	planR := rg.Group("/subscription_plan").Use(middleware.AdminPrivilege())
+	planR := rg.Group("/subscription_plan")
 	{
-		planR.GET("/list", cont.PlanList)
-		planR.GET("/detail/:id", cont.PlanDetail)
-		planR.POST("/create", cont.PlanCreate)
-		planR.POST("/update", cont.PlanUpdate)
-		planR.POST("/delete", cont.PlanDelete)
+		planR.GET("/list", view, cont.PlanList)
+		planR.GET("/detail/:id", view, cont.PlanDetail)
+		planR.POST("/create", plan, cont.PlanCreate)
+		planR.POST("/update", plan, cont.PlanUpdate)
+		planR.POST("/delete", plan, cont.PlanDelete)
+		planR.GET("/migrations", view, cont.PlanMigrations)
+		planR.GET("/migration/:id", view, cont.PlanMigrationDetail)
+	}
+
+	// 一次性商品
+	productR := rg.Group("/product")
+	{
+		productR.GET("/list", view, cont.ProductList)
+		productR.POST("/create", plan, cont.ProductCreate)
+		productR.POST("/update", plan, cont.ProductUpdate)
+		productR.POST("/delete", plan, cont.ProductDelete)
+	}
+
+	// 组织/团队订阅
+	orgR := rg.Group("/organization")
+	{
+		orgR.GET("/list", view, cont.OrganizationList)
+		orgR.GET("/detail/:id", view, cont.OrganizationDetail)
+		orgR.POST("/create", grant, cont.OrganizationCreate)
+		orgR.POST("/delete", grant, cont.OrganizationDelete)
+		orgR.POST("/members/add", grant, cont.OrganizationMemberAdd)
+		orgR.POST("/members/remove", grant, cont.OrganizationMemberRemove)
+	}
+
+	// 推荐计划, 奖励规则在通用设置 referral.* 中配置
+	refR := rg.Group("/referral")
+	{
+		refR.GET("/list", view, cont.ReferralList)
+		refR.GET("/report", view, cont.ReferralReport)
 	}
 
 	// 订单管理
-	orderR := rg.Group("/order").Use(middleware.AdminPrivilege())
+	orderR := rg.Group("/order")
 	{
-		orderR.GET("/list", cont.OrderList)
-		orderR.GET("/detail/:id", cont.OrderDetail)
-		orderR.POST("/refund", cont.OrderRefund)
-		orderR.POST("/close", cont.OrderClose)
+		orderR.GET("/list", view, cont.OrderList)
+		orderR.GET("/detail/:id", view, cont.OrderDetail)
+		orderR.GET("/export", view, cont.OrderExport)
+		orderR.POST("/refund", middleware.AdminPermission(model.PermRefundRequest), cont.OrderRefund)
+		orderR.GET("/refund/requests", view, cont.RefundRequestList)
+		orderR.POST("/refund/approve", middleware.AdminPermission(model.PermOrderRefund), cont.RefundApprove)
+		orderR.POST("/refund/reject", middleware.AdminPermission(model.PermOrderRefund), cont.RefundReject)
+		orderR.POST("/close", manage, cont.OrderClose)
+		orderR.POST("/fulfillment", manage, cont.OrderFulfillment)
+		orderR.POST("/create", manage, cont.OrderCreate)
+		orderR.POST("/notes/add", manage, cont.OrderNoteAdd)
+		orderR.POST("/notes/delete", manage, cont.OrderNoteDelete)
+		orderR.POST("/mark_paid", manage, cont.OrderMarkPaid)
+		orderR.POST("/simulate_paid", manage, cont.OrderSimulatePaid)
+		orderR.POST("/extend_hold", manage, cont.OrderExtendHold)
 	}
 
 	// 订阅管理
-	subR := rg.Group("/subscription").Use(middleware.AdminPrivilege())
+	subR := rg.Group("/subscription")
+	{
+		subR.GET("/list", view, cont.SubscriptionList)
+		subR.GET("/detail/:id", view, cont.SubscriptionDetail)
+		subR.GET("/simulate", view, cont.SubscriptionSimulate)
+		subR.GET("/events", view, cont.SubscriptionEvents)
+		subR.POST("/grant", grant, cont.SubscriptionGrant)
+		subR.POST("/grant_batch", grant, cont.SubscriptionGrantBatch)
+		subR.POST("/cancel", grant, cont.SubscriptionCancel)
+		subR.POST("/pause", grant, cont.SubscriptionPause)
+		subR.POST("/resume", grant, cont.SubscriptionResume)
+		subR.POST("/notes/add", grant, cont.SubscriptionNoteAdd)
+		subR.POST("/notes/delete", grant, cont.SubscriptionNoteDelete)
+	}
+
+	// 用户计费概览
+	rg.GET("/user/:id/billing", view, cont.UserBilling)
+
+	// 兑换码管理
+	codeR := rg.Group("/redeem_code")
 	{
-		subR.GET("/list", cont.SubscriptionList)
-		subR.GET("/detail/:id", cont.SubscriptionDetail)
-		subR.POST("/grant", cont.SubscriptionGrant)
-		subR.POST("/cancel", cont.SubscriptionCancel)
+		codeR.GET("/list", view, cont.RedeemCodeList)
+		codeR.POST("/generate", plan, cont.RedeemCodeGenerate)
+		codeR.POST("/disable", plan, cont.RedeemCodeDisable)
 	}
 
-	// 支付配置
-	payR := rg.Group("/payment").Use(middleware.AdminPrivilege())
+	// 付款凭证、报表和支付配置
+	payR := rg.Group("/payment")
 	{
-		payR.GET("/config", cont.ConfigGet)
-		payR.GET("/config/full", cont.ConfigGetFull)
-		payR.POST("/config", cont.ConfigSave)
+		payR.GET("/proof/list", view, cont.ProofList)
+		payR.GET("/proof/file/:id", view, cont.ProofFile)
+		payR.GET("/proof/url/:id", view, cont.ProofFileURL)
+		payR.POST("/proof/approve", manage, cont.ProofApprove)
+		payR.POST("/proof/reject", manage, cont.ProofReject)
+		payR.GET("/config", payConfig, cont.ConfigGet)
+		payR.GET("/config/full", payConfig, cont.ConfigGetFull)
+		payR.POST("/config", payConfig, cont.ConfigSave)
+		payR.GET("/dashboard", view, cont.Dashboard)
+		payR.GET("/funnel", view, cont.Funnel)
+		payR.GET("/notify_logs", view, cont.NotifyLogList)
+		payR.GET("/consistency", payConfig, cont.ConsistencyAudit)
+		payR.POST("/consistency/repair", payConfig, cont.ConsistencyRepair)
 	}
 }
@@ -6,6 +6,7 @@ import (
 	"github.com/lejianwen/rustdesk-api/v2/global"
 	"github.com/lejianwen/rustdesk-api/v2/http/controller/api"
 	"github.com/lejianwen/rustdesk-api/v2/http/middleware"
+	"github.com/lejianwen/rustdesk-api/v2/lib/shed"
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
 	"net/http"
@@ -27,15 +28,20 @@ func ApiInit(g *gin.Engine) {
 		i := &api.Index{}
 		frg.GET("/", i.Index)
 		frg.GET("/version", i.Version)
+		frg.GET("/health", i.Health)
+		frg.GET("/status", i.Status)
 
 		frg.POST("/heartbeat", i.Heartbeat)
 	}
+	// 实时推送, 自行校验 token(浏览器无法设置 WebSocket 请求头)
+	frg.GET("/ws", (&api.Push{}).Ws)
 
 	{
 		l := &api.Login{}
 		// 如果返回oidc则可以通过oidc登录
 		frg.GET("/login-options", l.LoginOptions)
-		frg.POST("/login", l.Login)
+		frg.POST("/login", middleware.RateLimit(middleware.RateLimitLogin), l.Login)
+		frg.POST("/refresh", middleware.RateLimit(middleware.RateLimitLogin), l.Refresh)
 
 	}
 
@@ -76,15 +82,36 @@ func ApiInit(g *gin.Engine) {
 	// 支付回调(免鉴权)
 	{
 		pay := &api.Payment{}
-		frg.GET("/payment/notify", pay.Notify)
+		frg.GET("/payment/notify", middleware.RateLimit(middleware.RateLimitNotify), pay.Notify)
 		frg.GET("/payment/submit", pay.Submit)
+		frg.GET("/payment/return", pay.Return)
+		frg.GET("/payment/mock/checkout", pay.MockCheckout)
+		frg.POST("/payment/mock/checkout", pay.MockCheckout)
 	}
 
-	frg.Use(middleware.RustAuth())
+	// 存储文件下载(免鉴权, 校验签名)
+	frg.GET("/storage/file", (&api.Storage{}).File)
+
+	// 邮箱确认链接(免鉴权, 校验令牌)
+	{
+		em := &api.Email{}
+		frg.GET("/email/confirm", em.ConfirmPage)
+		frg.POST("/email/confirm", middleware.RateLimit(middleware.RateLimitLogin), em.Confirm)
+	}
+
+	frg.Use(middleware.RustAuth(), middleware.QuotaHeaders())
 	{
 		u := &api.User{}
 		frg.GET("/user/info", u.Info)
 		frg.POST("/currentUser", u.Info)
+		frg.GET("/user/sessions", u.Sessions)
+		frg.DELETE("/user/sessions/:id", u.RevokeSession)
+	}
+	{
+		t := &api.ApiToken{}
+		frg.GET("/user/tokens", t.List)
+		frg.POST("/user/tokens", t.Create)
+		frg.DELETE("/user/tokens/:id", t.Delete)
 	}
 	{
 		l := &api.Login{}
@@ -95,9 +122,62 @@ func ApiInit(g *gin.Engine) {
 	{
 		pay := &api.Payment{}
 		frg.GET("/subscription/plans", pay.Plans)
-		frg.POST("/subscription/orders", pay.CreateOrder)
+		frg.GET("/subscription/plans/compare", pay.PlanCompare)
+		frg.GET("/subscription/plans/:code", pay.PlanByCode)
+		frg.GET("/subscription/checkout/preview", pay.CheckoutPreview)
+		frg.POST("/subscription/orders", middleware.RateLimit(middleware.RateLimitOrder), pay.CreateOrder)
+		frg.GET("/subscription/pay_types", pay.PayTypes)
+		frg.GET("/subscription/products", pay.Products)
+		frg.POST("/subscription/products/orders", middleware.RateLimit(middleware.RateLimitOrder), pay.CreateProductOrder)
+		frg.POST("/subscription/orders/proof", pay.UploadProof)
+		frg.POST("/subscription/orders/cancel", pay.CancelOrder)
 		frg.GET("/subscription/orders", pay.Orders)
 		frg.GET("/subscription/status", pay.Status)
+		frg.GET("/subscription/events", pay.Events)
+		frg.POST("/subscription/redeem", pay.Redeem)
+		frg.POST("/subscription/pause", pay.Pause)
+		frg.POST("/subscription/resume", pay.Resume)
+		frg.POST("/subscription/plan-change", pay.SchedulePlanChange)
+		frg.POST("/subscription/plan-change/cancel", pay.CancelPlanChange)
+		frg.GET("/subscription/seats", pay.Seats)
+		frg.POST("/subscription/seats/assign", pay.AssignSeat)
+		frg.POST("/subscription/seats/release", pay.ReleaseSeat)
+		frg.GET("/payment/qrcode", middleware.RateLimit(middleware.RateLimitOrder), pay.QRCode)
+		frg.GET("/payment/qrcode/status", pay.QRCodeStatus)
+	}
+
+	// 组织/团队订阅(需登录,但不需要订阅检查)
+	{
+		org := &api.Organization{}
+		frg.GET("/organization", org.Detail)
+		frg.POST("/organization/create", org.Create)
+		frg.POST("/organization/members/invite", org.Invite)
+		frg.POST("/organization/members/remove", org.Remove)
+		frg.POST("/organization/leave", org.Leave)
+		frg.POST("/organization/dissolve", org.Dissolve)
+	}
+
+	// 推荐计划(需登录,但不需要订阅检查)
+	{
+		ref := &api.Referral{}
+		frg.GET("/referral", ref.Summary)
+	}
+
+	// 站内通知(需登录,但不需要订阅检查)
+	{
+		nt := &api.Notification{}
+		frg.GET("/notifications", nt.List)
+		frg.GET("/notifications/unread_count", nt.UnreadCount)
+		frg.POST("/notifications/read", nt.Read)
+		frg.GET("/notifications/reminders", nt.ReminderPreference)
+		frg.POST("/notifications/reminders", nt.SetReminderPreference)
+	}
+
+	// 邮箱验证和更换(需登录,但不需要订阅检查)
+	{
+		em := &api.Email{}
+		frg.POST("/email/verification/send", em.SendVerification)
+		frg.POST("/email/change", em.Change)
 	}
 
 	// 以下路由需要订阅检查(启用支付功能时)
@@ -177,12 +257,31 @@ func InternalRoutes(g *gin.Engine) {
 	internal.Use(middleware.InternalAuth())
 	{
 		i := &api.Internal{}
+		// 过载保护: 影响连接建立的接口为高优先级, 其余为低优先级; health 不受限制
+		high := middleware.InternalShed(shed.High)
+		low := middleware.InternalShed(shed.Low)
 		// Relay 白名单管理
-		internal.POST("/relay/allow", i.RelayAllow)
-		internal.POST("/relay/consume", i.RelayConsume)
-		internal.GET("/relay/stats", i.RelayStats)
+		internal.POST("/relay/allow", high, i.RelayAllow)
+		internal.POST("/relay/consume", high, i.RelayConsume)
+		internal.GET("/relay/stats", low, i.RelayStats)
+		internal.GET("/health", i.Health)
+		internal.POST("/relay/session/start", low, i.RelaySessionStart)
+		internal.POST("/relay/session/end", low, i.RelaySessionEnd)
 		// 订阅状态检查 (支持 GET 和 POST，推荐 POST 以避免 token 泄露)
-		internal.GET("/subscription/check", i.SubscriptionCheck)
-		internal.POST("/subscription/check", i.SubscriptionCheck)
+		internal.GET("/subscription/check", high, i.SubscriptionCheck)
+		internal.POST("/subscription/check", high, i.SubscriptionCheck)
+		// 设备解析 (返回归属用户及默认连接权限)
+		internal.POST("/peer/resolve", low, i.PeerResolve)
+		// 设备封禁名单 (hbbs 同步, 封禁时另通过管理端口通知断开)
+		internal.GET("/peer/bans", low, i.PeerBans)
+		// 并发会话检查 (hbbs 每次连接尝试时调用)
+		internal.POST("/session/check", high, i.SessionCheck)
+		// 文件传输审计上报
+		internal.POST("/file/transfer", low, i.FileTransfer)
+		// 会话录像登记
+		internal.POST("/recording/register", low, i.RecordingRegister)
 	}
+
+	// Prometheus 指标, 与内部接口使用相同鉴权
+	g.GET("/metrics", middleware.InternalAuth(), (&api.Internal{}).Metrics)
 }
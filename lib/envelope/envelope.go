@@ -0,0 +1,163 @@
+// Package envelope 信封加密: 每个值使用随机数据密钥(DEK)以 AES-256-GCM 加密,
+// DEK 再由主密钥(KEK)加密后与密文一起保存. 更换主密钥时只需保留旧密钥用于解密, 再重新加密即可.
+package envelope
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+)
+
+// Prefix 加密值的前缀, 格式: enc:v1:<kid>:<wrapped dek>:<ciphertext>
+const Prefix = "enc:v1:"
+
+var (
+	ErrNoKey      = errors.New("envelope: no encryption key configured")
+	ErrUnknownKey = errors.New("envelope: unknown key id")
+	ErrMalformed  = errors.New("envelope: malformed value")
+)
+
+var b64 = base64.RawURLEncoding
+
+// Keyring 主密钥集合, 使用 primary 加密, 可用全部密钥解密
+type Keyring struct {
+	primary string
+	keys    map[string][]byte
+}
+
+// DeriveKey 由任意长度的口令派生 256 位主密钥
+func DeriveKey(secret string) []byte {
+	sum := sha256.Sum256([]byte(secret))
+	return sum[:]
+}
+
+// keyId 主密钥标识, 用于解密时选择密钥, 不泄露密钥本身
+func keyId(key []byte) string {
+	sum := sha256.Sum256(append([]byte("kid:"), key...))
+	return hex.EncodeToString(sum[:4])
+}
+
+// NewKeyring primary 为当前主密钥, old 为仍需解密的旧密钥; primary 为空时返回 nil
+func NewKeyring(primary string, old ...string) *Keyring {
+	if primary == "" {
+		return nil
+	}
+	k := &Keyring{keys: map[string][]byte{}}
+	key := DeriveKey(primary)
+	k.primary = keyId(key)
+	k.keys[k.primary] = key
+	for _, o := range old {
+		if o == "" {
+			continue
+		}
+		ok := DeriveKey(o)
+		k.keys[keyId(ok)] = ok
+	}
+	return k
+}
+
+// IsEncrypted 值是否为加密格式
+func IsEncrypted(s string) bool {
+	return strings.HasPrefix(s, Prefix)
+}
+
+// Encrypt 加密
+func (k *Keyring) Encrypt(plain []byte) (string, error) {
+	if k == nil {
+		return "", ErrNoKey
+	}
+	dek := make([]byte, 32)
+	if _, err := rand.Read(dek); err != nil {
+		return "", err
+	}
+	kid := k.primary
+	wrapped, err := seal(k.keys[kid], dek, []byte(kid))
+	if err != nil {
+		return "", err
+	}
+	ct, err := seal(dek, plain, []byte(kid))
+	if err != nil {
+		return "", err
+	}
+	return Prefix + kid + ":" + b64.EncodeToString(wrapped) + ":" + b64.EncodeToString(ct), nil
+}
+
+// Decrypt 解密
+func (k *Keyring) Decrypt(s string) ([]byte, error) {
+	if k == nil {
+		return nil, ErrNoKey
+	}
+	kid, wrapped, ct, err := parse(s)
+	if err != nil {
+		return nil, err
+	}
+	key, ok := k.keys[kid]
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+	dek, err := open(key, wrapped, []byte(kid))
+	if err != nil {
+		return nil, err
+	}
+	return open(dek, ct, []byte(kid))
+}
+
+// NeedsRotate 值不是由当前主密钥加密(包括明文)时返回 true
+func (k *Keyring) NeedsRotate(s string) bool {
+	kid, _, _, err := parse(s)
+	return err != nil || kid != k.primary
+}
+
+func parse(s string) (kid string, wrapped, ct []byte, err error) {
+	if !IsEncrypted(s) {
+		return "", nil, nil, ErrMalformed
+	}
+	parts := strings.Split(strings.TrimPrefix(s, Prefix), ":")
+	if len(parts) != 3 {
+		return "", nil, nil, ErrMalformed
+	}
+	if wrapped, err = b64.DecodeString(parts[1]); err != nil {
+		return "", nil, nil, ErrMalformed
+	}
+	if ct, err = b64.DecodeString(parts[2]); err != nil {
+		return "", nil, nil, ErrMalformed
+	}
+	return parts[0], wrapped, ct, nil
+}
+
+// seal AES-GCM 加密, 输出 nonce||密文
+func seal(key, plain, aad []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, plain, aad), nil
+}
+
+func open(key, data, aad []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < gcm.NonceSize() {
+		return nil, ErrMalformed
+	}
+	return gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], aad)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,65 @@
+package envelope
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRoundTrip(t *testing.T) {
+	k := NewKeyring("master secret")
+	s, err := k.Encrypt([]byte("epay-key-123"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsEncrypted(s) || strings.Contains(s, "epay-key-123") {
+		t.Fatalf("unexpected ciphertext %s", s)
+	}
+	got, err := k.Decrypt(s)
+	if err != nil || string(got) != "epay-key-123" {
+		t.Fatalf("Decrypt = %q, %v", got, err)
+	}
+	if k.NeedsRotate(s) {
+		t.Fatal("value encrypted with primary key should not need rotation")
+	}
+	if !k.NeedsRotate("plain") {
+		t.Fatal("plaintext should need rotation")
+	}
+	s2, _ := k.Encrypt([]byte("epay-key-123"))
+	if s == s2 {
+		t.Fatal("encryption should be randomized")
+	}
+}
+
+func TestRotation(t *testing.T) {
+	old := NewKeyring("old")
+	s, _ := old.Encrypt([]byte("v"))
+
+	if _, err := NewKeyring("new").Decrypt(s); err != ErrUnknownKey {
+		t.Fatalf("want ErrUnknownKey, got %v", err)
+	}
+	k := NewKeyring("new", "old")
+	got, err := k.Decrypt(s)
+	if err != nil || string(got) != "v" {
+		t.Fatalf("Decrypt with old key = %q, %v", got, err)
+	}
+	if !k.NeedsRotate(s) {
+		t.Fatal("value encrypted with old key should need rotation")
+	}
+}
+
+func TestTampered(t *testing.T) {
+	k := NewKeyring("k")
+	s, _ := k.Encrypt([]byte("secret"))
+	b := []byte(s)
+	b[len(b)-2] ^= 1
+	if _, err := k.Decrypt(string(b)); err == nil {
+		t.Fatal("tampered value should fail to decrypt")
+	}
+	if _, err := k.Decrypt("enc:v1:bad"); err != ErrMalformed {
+		t.Fatalf("want ErrMalformed, got %v", err)
+	}
+	var nilRing *Keyring
+	if _, err := nilRing.Encrypt([]byte("x")); err != ErrNoKey {
+		t.Fatalf("want ErrNoKey, got %v", err)
+	}
+}
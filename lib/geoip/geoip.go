@@ -0,0 +1,49 @@
+package geoip
+
+import (
+	"net"
+)
+
+// Location 查询结果, 字段取自 GeoLite2 的 country/city 结构, 未收录时为空
+type Location struct {
+	Country string `json:"country"` // ISO 3166-1 国家/地区代码
+	City    string `json:"city"`
+}
+
+// Locate 查询 IP 所在国家和城市, lang 为城市名称的语言(如 en、zh-CN), 无对应语言时回退到 en
+func (r *Reader) Locate(ip string, lang string) Location {
+	addr := net.ParseIP(ip)
+	if r == nil || addr == nil {
+		return Location{}
+	}
+	rec, err := r.Lookup(addr)
+	if err != nil {
+		return Location{}
+	}
+	loc := Location{}
+	if m, ok := rec["country"].(map[string]interface{}); ok {
+		loc.Country, _ = m["iso_code"].(string)
+	}
+	// 部分数据库只有注册国家(如卫星、匿名网络)
+	if loc.Country == "" {
+		if m, ok := rec["registered_country"].(map[string]interface{}); ok {
+			loc.Country, _ = m["iso_code"].(string)
+		}
+	}
+	if m, ok := rec["city"].(map[string]interface{}); ok {
+		loc.City = localName(m, lang)
+	}
+	return loc
+}
+
+func localName(m map[string]interface{}, lang string) string {
+	names, ok := m["names"].(map[string]interface{})
+	if !ok {
+		return ""
+	}
+	if s, ok := names[lang].(string); ok && s != "" {
+		return s
+	}
+	s, _ := names["en"].(string)
+	return s
+}
@@ -0,0 +1,118 @@
+package geoip
+
+import (
+	"bytes"
+	"sort"
+	"testing"
+)
+
+// 以下为测试用的最小 mmdb 编码, 只覆盖短字符串、map、array 和 uint16/uint32
+func encStr(s string) []byte {
+	return append([]byte{byte(typeString<<5 | len(s))}, s...)
+}
+
+func encMap(m map[string][]byte) []byte {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	b := []byte{byte(typeMap<<5 | len(m))}
+	for _, k := range keys {
+		b = append(b, encStr(k)...)
+		b = append(b, m[k]...)
+	}
+	return b
+}
+
+func encUint(typ, n int, v uint64) []byte {
+	b := []byte{byte(typ<<5 | n)}
+	for i := n - 1; i >= 0; i-- {
+		b = append(b, byte(v>>(8*uint(i))))
+	}
+	return b
+}
+
+func encArray(items ...[]byte) []byte {
+	b := []byte{byte(typeExtended<<5 | len(items)), typeArray - 7}
+	for _, it := range items {
+		b = append(b, it...)
+	}
+	return b
+}
+
+// testDB IPv4 数据库: 0.0.0.0/1 收录为北京, 128.0.0.0/1 未收录
+func testDB() []byte {
+	const nodeCount = 1
+	data := encMap(map[string][]byte{
+		"country": encMap(map[string][]byte{"iso_code": encStr("CN")}),
+		"city": encMap(map[string][]byte{"names": encMap(map[string][]byte{
+			"en":    encStr("Beijing"),
+			"zh-CN": encStr("北京"),
+		})}),
+	})
+	left := nodeCount + dataSectionSeparator
+	tree := []byte{0, 0, byte(left), 0, 0, nodeCount}
+	var buf bytes.Buffer
+	buf.Write(tree)
+	buf.Write(make([]byte, dataSectionSeparator))
+	buf.Write(data)
+	buf.Write(metadataMarker)
+	buf.Write(encMap(map[string][]byte{
+		"node_count":    encUint(typeUint32, 1, nodeCount),
+		"record_size":   encUint(typeUint16, 1, 24),
+		"ip_version":    encUint(typeUint16, 1, 4),
+		"database_type": encStr("Test-City"),
+		"languages":     encArray(encStr("en"), encStr("zh-CN")),
+	}))
+	return buf.Bytes()
+}
+
+func TestLocate(t *testing.T) {
+	r, err := FromBytes(testDB())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Type != "Test-City" || len(r.Languages) != 2 {
+		t.Fatalf("metadata = %s %v", r.Type, r.Languages)
+	}
+	if loc := r.Locate("1.2.3.4", "zh-CN"); loc.Country != "CN" || loc.City != "北京" {
+		t.Fatalf("locate = %+v", loc)
+	}
+	if loc := r.Locate("1.2.3.4", "de"); loc.City != "Beijing" {
+		t.Fatalf("fallback city = %q", loc.City)
+	}
+	if loc := r.Locate("200.1.1.1", "en"); loc != (Location{}) {
+		t.Fatalf("unlisted ip = %+v", loc)
+	}
+	if loc := r.Locate("::1", "en"); loc != (Location{}) {
+		t.Fatalf("ipv6 in ipv4 db = %+v", loc)
+	}
+	if loc := r.Locate("bad", "en"); loc != (Location{}) {
+		t.Fatalf("invalid ip = %+v", loc)
+	}
+	var nilReader *Reader
+	if loc := nilReader.Locate("1.2.3.4", "en"); loc != (Location{}) {
+		t.Fatalf("nil reader = %+v", loc)
+	}
+}
+
+func TestDecodePointer(t *testing.T) {
+	// 偏移 0 为字符串, 之后是指向偏移 0 的指针
+	buf := append(encStr("hi"), typePointer<<5, 0)
+	d := decoder{buf: buf}
+	v, next, err := d.decode(3, 0)
+	if err != nil || v != "hi" || next != 5 {
+		t.Fatalf("pointer = %v %d %v", v, next, err)
+	}
+}
+
+func TestInvalidDatabase(t *testing.T) {
+	if _, err := FromBytes([]byte("not a database")); err != ErrInvalidDatabase {
+		t.Fatalf("err = %v", err)
+	}
+	db := testDB()
+	if _, err := FromBytes(db[:10]); err == nil {
+		t.Fatal("truncated database should fail")
+	}
+}
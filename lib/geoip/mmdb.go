@@ -0,0 +1,328 @@
+// Package geoip 离线 IP 地理位置查询, 读取 MaxMind DB(.mmdb) 格式的数据库(GeoLite2-City/Country、DB-IP 等)
+package geoip
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math"
+	"net"
+	"os"
+)
+
+var metadataMarker = []byte("\xAB\xCD\xEFMaxMind.com")
+
+var (
+	ErrInvalidDatabase = errors.New("geoip: invalid database")
+	ErrNotFound        = errors.New("geoip: address not found")
+)
+
+// dataSectionSeparator 搜索树与数据区之间的 16 字节分隔
+const dataSectionSeparator = 16
+
+// Reader 已加载到内存的数据库, 只读, 并发安全
+type Reader struct {
+	buf        []byte
+	data       []byte
+	nodeCount  uint
+	recordSize uint
+	ipVersion  uint
+	ipv4Start  uint
+	Languages  []string
+	Type       string
+	BuildEpoch uint64
+}
+
+// Open 读取数据库文件
+func Open(path string) (*Reader, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return FromBytes(b)
+}
+
+// FromBytes 从内存中的数据库内容创建 Reader
+func FromBytes(buf []byte) (*Reader, error) {
+	i := bytes.LastIndex(buf, metadataMarker)
+	if i < 0 {
+		return nil, ErrInvalidDatabase
+	}
+	metaStart := i + len(metadataMarker)
+	d := decoder{buf: buf[metaStart:]}
+	v, _, err := d.decode(0, 0)
+	if err != nil {
+		return nil, err
+	}
+	meta, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, ErrInvalidDatabase
+	}
+	r := &Reader{
+		buf:        buf,
+		nodeCount:  uint(toUint(meta["node_count"])),
+		recordSize: uint(toUint(meta["record_size"])),
+		ipVersion:  uint(toUint(meta["ip_version"])),
+		BuildEpoch: toUint(meta["build_epoch"]),
+	}
+	r.Type, _ = meta["database_type"].(string)
+	if langs, ok := meta["languages"].([]interface{}); ok {
+		for _, l := range langs {
+			if s, ok := l.(string); ok {
+				r.Languages = append(r.Languages, s)
+			}
+		}
+	}
+	if r.recordSize != 24 && r.recordSize != 28 && r.recordSize != 32 {
+		return nil, ErrInvalidDatabase
+	}
+	treeSize := r.nodeCount * r.recordSize / 4
+	if treeSize+dataSectionSeparator > uint(i) {
+		return nil, ErrInvalidDatabase
+	}
+	r.data = buf[treeSize+dataSectionSeparator : i]
+	// IPv4 地址位于 IPv6 树的 ::/96 下
+	if r.ipVersion == 6 {
+		node := uint(0)
+		for j := 0; j < 96 && node < r.nodeCount; j++ {
+			node = r.record(node, 0)
+		}
+		r.ipv4Start = node
+	}
+	return r, nil
+}
+
+func (r *Reader) record(node uint, bit uint) uint {
+	b := r.buf[node*r.recordSize/4:]
+	switch r.recordSize {
+	case 24:
+		o := bit * 3
+		return uint(b[o])<<16 | uint(b[o+1])<<8 | uint(b[o+2])
+	case 28:
+		if bit == 0 {
+			return uint(b[3]&0xF0)<<20 | uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2])
+		}
+		return uint(b[3]&0x0F)<<24 | uint(b[4])<<16 | uint(b[5])<<8 | uint(b[6])
+	default:
+		return uint(binary.BigEndian.Uint32(b[bit*4:]))
+	}
+}
+
+// Lookup 查询 IP 对应的记录, 未收录时返回 ErrNotFound
+func (r *Reader) Lookup(ip net.IP) (map[string]interface{}, error) {
+	bits := ip.To4()
+	node := uint(0)
+	if bits != nil {
+		node = r.ipv4Start
+	} else {
+		if r.ipVersion == 4 {
+			return nil, ErrNotFound
+		}
+		bits = ip.To16()
+		if bits == nil {
+			return nil, ErrNotFound
+		}
+	}
+	for i := 0; i < len(bits)*8 && node < r.nodeCount; i++ {
+		bit := uint(bits[i/8]>>(7-uint(i%8))) & 1
+		node = r.record(node, bit)
+	}
+	if node <= r.nodeCount {
+		return nil, ErrNotFound
+	}
+	offset := node - r.nodeCount - dataSectionSeparator
+	d := decoder{buf: r.data}
+	v, _, err := d.decode(offset, 0)
+	if err != nil {
+		return nil, err
+	}
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, ErrInvalidDatabase
+	}
+	return m, nil
+}
+
+const (
+	typeExtended = 0
+	typePointer  = 1
+	typeString   = 2
+	typeDouble   = 3
+	typeBytes    = 4
+	typeUint16   = 5
+	typeUint32   = 6
+	typeMap      = 7
+	typeInt32    = 8
+	typeUint64   = 9
+	typeUint128  = 10
+	typeArray    = 11
+	typeBool     = 14
+	typeFloat    = 15
+)
+
+// maxDepth 嵌套深度上限, 防止损坏的数据库导致无限递归
+const maxDepth = 32
+
+type decoder struct {
+	buf []byte
+}
+
+func (d *decoder) byteAt(off uint) (byte, error) {
+	if off >= uint(len(d.buf)) {
+		return 0, ErrInvalidDatabase
+	}
+	return d.buf[off], nil
+}
+
+func (d *decoder) slice(off, n uint) ([]byte, error) {
+	if off+n > uint(len(d.buf)) || off+n < off {
+		return nil, ErrInvalidDatabase
+	}
+	return d.buf[off : off+n], nil
+}
+
+// decode 解码 off 处的值, 返回值和下一个值的偏移
+func (d *decoder) decode(off uint, depth int) (interface{}, uint, error) {
+	if depth > maxDepth {
+		return nil, 0, ErrInvalidDatabase
+	}
+	ctrl, err := d.byteAt(off)
+	if err != nil {
+		return nil, 0, err
+	}
+	off++
+	typ := uint(ctrl >> 5)
+	if typ == typePointer {
+		ptr, next, err := d.pointer(ctrl, off)
+		if err != nil {
+			return nil, 0, err
+		}
+		v, _, err := d.decode(ptr, depth+1)
+		return v, next, err
+	}
+	if typ == typeExtended {
+		b, err := d.byteAt(off)
+		if err != nil {
+			return nil, 0, err
+		}
+		typ = uint(b) + 7
+		off++
+	}
+	size := uint(ctrl & 0x1F)
+	if size >= 29 {
+		n := size - 28
+		b, err := d.slice(off, n)
+		if err != nil {
+			return nil, 0, err
+		}
+		off += n
+		switch n {
+		case 1:
+			size = 29 + uint(b[0])
+		case 2:
+			size = 285 + (uint(b[0])<<8 | uint(b[1]))
+		default:
+			size = 65821 + (uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2]))
+		}
+	}
+	switch typ {
+	case typeMap:
+		m := make(map[string]interface{}, size)
+		for i := uint(0); i < size; i++ {
+			k, next, err := d.decode(off, depth+1)
+			if err != nil {
+				return nil, 0, err
+			}
+			key, ok := k.(string)
+			if !ok {
+				return nil, 0, ErrInvalidDatabase
+			}
+			v, next, err := d.decode(next, depth+1)
+			if err != nil {
+				return nil, 0, err
+			}
+			m[key] = v
+			off = next
+		}
+		return m, off, nil
+	case typeArray:
+		a := make([]interface{}, 0, size)
+		for i := uint(0); i < size; i++ {
+			v, next, err := d.decode(off, depth+1)
+			if err != nil {
+				return nil, 0, err
+			}
+			a = append(a, v)
+			off = next
+		}
+		return a, off, nil
+	case typeBool:
+		return size != 0, off, nil
+	}
+	b, err := d.slice(off, size)
+	if err != nil {
+		return nil, 0, err
+	}
+	off += size
+	switch typ {
+	case typeString:
+		return string(b), off, nil
+	case typeBytes:
+		return append([]byte(nil), b...), off, nil
+	case typeDouble:
+		if size != 8 {
+			return nil, 0, ErrInvalidDatabase
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(b)), off, nil
+	case typeFloat:
+		if size != 4 {
+			return nil, 0, ErrInvalidDatabase
+		}
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(b))), off, nil
+	case typeUint16, typeUint32, typeUint64:
+		var v uint64
+		for _, c := range b {
+			v = v<<8 | uint64(c)
+		}
+		return v, off, nil
+	case typeInt32:
+		var v uint32
+		for _, c := range b {
+			v = v<<8 | uint32(c)
+		}
+		return int64(int32(v)), off, nil
+	case typeUint128:
+		// 只用于少量元数据字段, 保留原始字节
+		return append([]byte(nil), b...), off, nil
+	}
+	return nil, 0, ErrInvalidDatabase
+}
+
+// pointer 指针指向数据区内的偏移
+func (d *decoder) pointer(ctrl byte, off uint) (uint, uint, error) {
+	ss := uint(ctrl>>3) & 0x3
+	vvv := uint(ctrl & 0x7)
+	b, err := d.slice(off, ss+1)
+	if err != nil {
+		return 0, 0, err
+	}
+	var p uint
+	switch ss {
+	case 0:
+		p = vvv<<8 | uint(b[0])
+	case 1:
+		p = (vvv<<16 | uint(b[0])<<8 | uint(b[1])) + 2048
+	case 2:
+		p = (vvv<<24 | uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2])) + 526336
+	default:
+		p = uint(binary.BigEndian.Uint32(b))
+	}
+	return p, off + ss + 1, nil
+}
+
+func toUint(v interface{}) uint64 {
+	if n, ok := v.(uint64); ok {
+		return n
+	}
+	return 0
+}
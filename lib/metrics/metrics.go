@@ -0,0 +1,316 @@
+// Package metrics 简单的计数器/直方图, 按 Prometheus 文本格式(0.0.4)输出
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ContentType Prometheus 文本格式
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// DefBuckets 默认直方图分桶(秒)
+var DefBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+type collector interface {
+	write(w *bufio.Writer)
+}
+
+// Registry 指标注册表
+type Registry struct {
+	mu         sync.Mutex
+	names      map[string]bool
+	collectors []collector
+}
+
+func NewRegistry() *Registry {
+	return &Registry{names: make(map[string]bool)}
+}
+
+// Default 默认注册表
+var Default = NewRegistry()
+
+func (r *Registry) register(name string, c collector) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.names[name] {
+		panic("metrics: duplicate metric " + name)
+	}
+	r.names[name] = true
+	r.collectors = append(r.collectors, c)
+}
+
+// WriteTo 按注册顺序输出全部指标
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	r.mu.Lock()
+	collectors := append([]collector(nil), r.collectors...)
+	r.mu.Unlock()
+
+	cw := &countWriter{w: w}
+	bw := bufio.NewWriter(cw)
+	for _, c := range collectors {
+		c.write(bw)
+	}
+	err := bw.Flush()
+	return cw.n, err
+}
+
+type countWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// vec 按标签值分组的公共部分
+type vec struct {
+	name   string
+	help   string
+	labels []string
+}
+
+func (v *vec) key(values []string) string {
+	if len(values) != len(v.labels) {
+		panic(fmt.Sprintf("metrics: %s expects %d label values, got %d", v.name, len(v.labels), len(values)))
+	}
+	return strings.Join(values, "\xff")
+}
+
+func (v *vec) header(w *bufio.Writer, typ string) {
+	w.WriteString("# HELP " + v.name + " " + escapeHelp(v.help) + "\n")
+	w.WriteString("# TYPE " + v.name + " " + typ + "\n")
+}
+
+// labelPairs 生成 {a="x",b="y"}, extra 追加在最后(如 le)
+func (v *vec) labelPairs(values []string, extra ...string) string {
+	if len(values) == 0 && len(extra) == 0 {
+		return ""
+	}
+	pairs := make([]string, 0, len(values)+1)
+	for i, l := range v.labels {
+		pairs = append(pairs, l+`="`+escapeLabel(values[i])+`"`)
+	}
+	for i := 0; i+1 < len(extra); i += 2 {
+		pairs = append(pairs, extra[i]+`="`+escapeLabel(extra[i+1])+`"`)
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// CounterVec 计数器
+type CounterVec struct {
+	vec
+	mu     sync.Mutex
+	values map[string]*counterValue
+}
+
+type counterValue struct {
+	labels []string
+	value  float64
+}
+
+// NewCounterVec 创建并注册计数器
+func (r *Registry) NewCounterVec(name, help string, labels ...string) *CounterVec {
+	c := &CounterVec{vec: vec{name: name, help: help, labels: labels}, values: make(map[string]*counterValue)}
+	r.register(name, c)
+	return c
+}
+
+// Inc 加 1
+func (c *CounterVec) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add 增加 v, v 不能为负
+func (c *CounterVec) Add(v float64, labelValues ...string) {
+	if v < 0 {
+		panic("metrics: counter cannot decrease")
+	}
+	k := c.key(labelValues)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cv, ok := c.values[k]
+	if !ok {
+		cv = &counterValue{labels: append([]string(nil), labelValues...)}
+		c.values[k] = cv
+	}
+	cv.value += v
+}
+
+// Value 当前值, 主要用于测试
+func (c *CounterVec) Value(labelValues ...string) float64 {
+	k := c.key(labelValues)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if cv, ok := c.values[k]; ok {
+		return cv.value
+	}
+	return 0
+}
+
+// Each 按标签值排序遍历全部计数
+func (c *CounterVec) Each(fn func(labelValues []string, value float64)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, k := range sortedKeys(c.values) {
+		cv := c.values[k]
+		fn(append([]string(nil), cv.labels...), cv.value)
+	}
+}
+
+func (c *CounterVec) write(w *bufio.Writer) {
+	c.header(w, "counter")
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, k := range sortedKeys(c.values) {
+		cv := c.values[k]
+		w.WriteString(c.name + c.labelPairs(cv.labels) + " " + formatFloat(cv.value) + "\n")
+	}
+}
+
+// GaugeVec 可增可减的瞬时值
+type GaugeVec struct {
+	vec
+	mu     sync.Mutex
+	values map[string]*counterValue
+}
+
+// NewGaugeVec 创建并注册仪表
+func (r *Registry) NewGaugeVec(name, help string, labels ...string) *GaugeVec {
+	g := &GaugeVec{vec: vec{name: name, help: help, labels: labels}, values: make(map[string]*counterValue)}
+	r.register(name, g)
+	return g
+}
+
+// Set 设置当前值
+func (g *GaugeVec) Set(v float64, labelValues ...string) {
+	k := g.key(labelValues)
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	gv, ok := g.values[k]
+	if !ok {
+		gv = &counterValue{labels: append([]string(nil), labelValues...)}
+		g.values[k] = gv
+	}
+	gv.value = v
+}
+
+// Value 当前值, 主要用于测试
+func (g *GaugeVec) Value(labelValues ...string) float64 {
+	k := g.key(labelValues)
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if gv, ok := g.values[k]; ok {
+		return gv.value
+	}
+	return 0
+}
+
+func (g *GaugeVec) write(w *bufio.Writer) {
+	g.header(w, "gauge")
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for _, k := range sortedKeys(g.values) {
+		gv := g.values[k]
+		w.WriteString(g.name + g.labelPairs(gv.labels) + " " + formatFloat(gv.value) + "\n")
+	}
+}
+
+// HistogramVec 直方图
+type HistogramVec struct {
+	vec
+	buckets []float64
+	mu      sync.Mutex
+	values  map[string]*histogramValue
+}
+
+type histogramValue struct {
+	labels []string
+	counts []uint64 // 每个分桶(非累计)的数量
+	sum    float64
+	count  uint64
+}
+
+// NewHistogramVec 创建并注册直方图, buckets 为空时使用 DefBuckets
+func (r *Registry) NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	if len(buckets) == 0 {
+		buckets = DefBuckets
+	}
+	b := append([]float64(nil), buckets...)
+	sort.Float64s(b)
+	h := &HistogramVec{vec: vec{name: name, help: help, labels: labels}, buckets: b, values: make(map[string]*histogramValue)}
+	r.register(name, h)
+	return h
+}
+
+// Observe 记录一次观测值
+func (h *HistogramVec) Observe(v float64, labelValues ...string) {
+	k := h.key(labelValues)
+	i := sort.SearchFloat64s(h.buckets, v)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	hv, ok := h.values[k]
+	if !ok {
+		hv = &histogramValue{labels: append([]string(nil), labelValues...), counts: make([]uint64, len(h.buckets))}
+		h.values[k] = hv
+	}
+	if i < len(h.buckets) {
+		hv.counts[i]++
+	}
+	hv.sum += v
+	hv.count++
+}
+
+func (h *HistogramVec) write(w *bufio.Writer) {
+	h.header(w, "histogram")
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, k := range sortedKeys(h.values) {
+		hv := h.values[k]
+		var cum uint64
+		for i, b := range h.buckets {
+			cum += hv.counts[i]
+			w.WriteString(h.name + "_bucket" + h.labelPairs(hv.labels, "le", formatFloat(b)) + " " + strconv.FormatUint(cum, 10) + "\n")
+		}
+		w.WriteString(h.name + "_bucket" + h.labelPairs(hv.labels, "le", "+Inf") + " " + strconv.FormatUint(hv.count, 10) + "\n")
+		w.WriteString(h.name + "_sum" + h.labelPairs(hv.labels) + " " + formatFloat(hv.sum) + "\n")
+		w.WriteString(h.name + "_count" + h.labelPairs(hv.labels) + " " + strconv.FormatUint(hv.count, 10) + "\n")
+	}
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+var (
+	labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+)
+
+func escapeLabel(s string) string { return labelEscaper.Replace(s) }
+
+func escapeHelp(s string) string { return helpEscaper.Replace(s) }
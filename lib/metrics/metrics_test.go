@@ -0,0 +1,105 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCounterVec(t *testing.T) {
+	r := NewRegistry()
+	c := r.NewCounterVec("test_total", "Test counter", "result")
+	c.Inc("ok")
+	c.Inc("ok")
+	c.Add(3, "fail")
+	if v := c.Value("ok"); v != 2 {
+		t.Fatalf("ok = %v, want 2", v)
+	}
+
+	var sb strings.Builder
+	if _, err := r.WriteTo(&sb); err != nil {
+		t.Fatal(err)
+	}
+	want := "# HELP test_total Test counter\n" +
+		"# TYPE test_total counter\n" +
+		"test_total{result=\"fail\"} 3\n" +
+		"test_total{result=\"ok\"} 2\n"
+	if sb.String() != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", sb.String(), want)
+	}
+}
+
+func TestCounterVecEach(t *testing.T) {
+	c := NewRegistry().NewCounterVec("each_total", "Each", "stage", "plan")
+	c.Inc("paid", "2")
+	c.Add(2, "view", "1")
+	var got []string
+	c.Each(func(labels []string, v float64) {
+		got = append(got, strings.Join(labels, "/")+"="+formatFloat(v))
+	})
+	if strings.Join(got, ",") != "paid/2=1,view/1=2" {
+		t.Fatalf("got %v", got)
+	}
+}
+
+func TestGaugeVec(t *testing.T) {
+	r := NewRegistry()
+	g := r.NewGaugeVec("test_inflight", "Test gauge")
+	g.Set(3)
+	g.Set(1.5)
+	if v := g.Value(); v != 1.5 {
+		t.Fatalf("value = %v, want 1.5", v)
+	}
+	var sb strings.Builder
+	r.WriteTo(&sb)
+	want := "# HELP test_inflight Test gauge\n# TYPE test_inflight gauge\ntest_inflight 1.5\n"
+	if sb.String() != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", sb.String(), want)
+	}
+}
+
+func TestHistogramVec(t *testing.T) {
+	r := NewRegistry()
+	h := r.NewHistogramVec("test_seconds", "Test histogram", []float64{1, 0.1}, "path")
+	h.Observe(0.05, "/a")
+	h.Observe(0.1, "/a")
+	h.Observe(5, "/a")
+
+	var sb strings.Builder
+	r.WriteTo(&sb)
+	for _, line := range []string{
+		`test_seconds_bucket{path="/a",le="0.1"} 2`,
+		`test_seconds_bucket{path="/a",le="1"} 2`,
+		`test_seconds_bucket{path="/a",le="+Inf"} 3`,
+		`test_seconds_sum{path="/a"} 5.15`,
+		`test_seconds_count{path="/a"} 3`,
+	} {
+		if !strings.Contains(sb.String(), line+"\n") {
+			t.Errorf("missing %q in:\n%s", line, sb.String())
+		}
+	}
+}
+
+func TestEscapeLabel(t *testing.T) {
+	r := NewRegistry()
+	c := r.NewCounterVec("esc_total", "a\nb", "v")
+	c.Inc("x\"y\\z\n")
+	var sb strings.Builder
+	r.WriteTo(&sb)
+	if !strings.Contains(sb.String(), `esc_total{v="x\"y\\z\n"} 1`) {
+		t.Fatalf("bad escaping:\n%s", sb.String())
+	}
+	if !strings.Contains(sb.String(), `# HELP esc_total a\nb`) {
+		t.Fatalf("bad help escaping:\n%s", sb.String())
+	}
+}
+
+func TestDuplicateName(t *testing.T) {
+	r := NewRegistry()
+	r.NewCounterVec("dup_total", "")
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected panic on duplicate metric")
+		}
+	}()
+	r.NewCounterVec("dup_total", "")
+}
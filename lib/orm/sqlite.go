@@ -9,12 +9,17 @@ import (
 )
 
 type SqliteConfig struct {
+	Path         string // 数据库文件路径, 默认 ./data/rustdeskapi.db
 	MaxIdleConns int
 	MaxOpenConns int
 }
 
 func NewSqlite(sqliteConf *SqliteConfig, logwriter logger.Writer) *gorm.DB {
-	db, err := gorm.Open(sqlite.Open("./data/rustdeskapi.db"), &gorm.Config{
+	path := sqliteConf.Path
+	if path == "" {
+		path = "./data/rustdeskapi.db"
+	}
+	db, err := gorm.Open(sqlite.Open(path), &gorm.Config{
 		DisableForeignKeyConstraintWhenMigrating: true,
 		Logger: logger.New(
 			logwriter, // io writer
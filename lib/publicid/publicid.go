@@ -0,0 +1,171 @@
+// Package publicid 对外标识: 数据库内部使用自增主键, 接口返回不可推测的标识, 避免通过编号推算订单量、用户量
+package publicid
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"math/bits"
+	"strconv"
+	"strings"
+)
+
+// 标识类型, 也是 hashid 的前缀
+const (
+	KindOrder        = "ord"
+	KindPlan         = "pln"
+	KindSubscription = "sub"
+)
+
+var ErrInvalid = errors.New("publicid: invalid identifier")
+
+// Codec 主键与对外标识的相互转换
+type Codec interface {
+	Encode(kind string, id uint) string
+	Decode(kind string, s string) (uint, error)
+	// Opaque 对外标识是否隐藏了主键, 为 true 时接口不再返回数字主键
+	Opaque() bool
+}
+
+// Numeric 直接使用十进制主键, 兼容旧客户端
+type Numeric struct{}
+
+func (Numeric) Encode(kind string, id uint) string {
+	return strconv.FormatUint(uint64(id), 10)
+}
+
+func (Numeric) Decode(kind string, s string) (uint, error) {
+	id, err := strconv.ParseUint(strings.TrimSpace(s), 10, 32)
+	if err != nil || id == 0 {
+		return 0, ErrInvalid
+	}
+	return uint(id), nil
+}
+
+func (Numeric) Opaque() bool { return false }
+
+// Hashid 使用密钥对 32 位主键做 64 位 Feistel 置换后 base62 编码, 形如 ord_3kTMd9pXq2a
+// 高 32 位固定为 0, 解码时校验, 随机猜测命中有效标识的概率约为 2^-32; 不同类型使用不同的轮密钥
+type Hashid struct {
+	key []byte
+}
+
+const (
+	hashidRounds = 4
+	hashidWidth  = 11 // 62^11 > 2^64
+	base62       = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+)
+
+func NewHashid(secret string) *Hashid {
+	return &Hashid{key: []byte(secret)}
+}
+
+func (h *Hashid) Opaque() bool { return true }
+
+func (h *Hashid) round(kind string, r int, half uint32) uint32 {
+	m := hmac.New(sha256.New, h.key)
+	m.Write([]byte(kind))
+	var buf [5]byte
+	buf[0] = byte(r)
+	binary.BigEndian.PutUint32(buf[1:], half)
+	m.Write(buf[:])
+	return binary.BigEndian.Uint32(m.Sum(nil))
+}
+
+func (h *Hashid) Encode(kind string, id uint) string {
+	l, r := uint32(0), uint32(id)
+	for i := 0; i < hashidRounds; i++ {
+		l, r = r, l^h.round(kind, i, r)
+	}
+	return kind + "_" + encode62(uint64(l)<<32|uint64(r))
+}
+
+func (h *Hashid) Decode(kind string, s string) (uint, error) {
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, kind+"_") {
+		return 0, ErrInvalid
+	}
+	v, ok := decode62(s[len(kind)+1:])
+	if !ok {
+		return 0, ErrInvalid
+	}
+	l, r := uint32(v>>32), uint32(v)
+	for i := hashidRounds - 1; i >= 0; i-- {
+		l, r = r^h.round(kind, i, l), l
+	}
+	if l != 0 || r == 0 {
+		return 0, ErrInvalid
+	}
+	return uint(r), nil
+}
+
+func encode62(v uint64) string {
+	var b [hashidWidth]byte
+	for i := hashidWidth - 1; i >= 0; i-- {
+		b[i] = base62[v%62]
+		v /= 62
+	}
+	return string(b[:])
+}
+
+func decode62(s string) (uint64, bool) {
+	if len(s) != hashidWidth {
+		return 0, false
+	}
+	var v uint64
+	for i := 0; i < len(s); i++ {
+		d := strings.IndexByte(base62, s[i])
+		if d < 0 {
+			return 0, false
+		}
+		hi, lo := bits.Mul64(v, 62)
+		sum, carry := bits.Add64(lo, uint64(d), 0)
+		if hi != 0 || carry != 0 { // 溢出
+			return 0, false
+		}
+		v = sum
+	}
+	return v, true
+}
+
+// New 按名称创建编码: hashid 需要密钥, 其他名称(包括空)使用 Numeric
+func New(name, secret string) (Codec, error) {
+	switch name {
+	case "", "numeric":
+		return Numeric{}, nil
+	case "hashid":
+		if secret == "" {
+			return nil, errors.New("publicid: hashid requires a secret")
+		}
+		return NewHashid(secret), nil
+	}
+	return nil, errors.New("publicid: unknown codec " + name)
+}
+
+// Ref 请求中的标识, JSON 中可以是数字(旧客户端)或字符串
+type Ref string
+
+func (r *Ref) UnmarshalJSON(b []byte) error {
+	b = bytes.TrimSpace(b)
+	if len(b) > 0 && b[0] == '"' {
+		var s string
+		if err := json.Unmarshal(b, &s); err != nil {
+			return err
+		}
+		*r = Ref(s)
+		return nil
+	}
+	if bytes.Equal(b, []byte("null")) {
+		*r = ""
+		return nil
+	}
+	var n json.Number
+	if err := json.Unmarshal(b, &n); err != nil {
+		return err
+	}
+	*r = Ref(n.String())
+	return nil
+}
@@ -0,0 +1,73 @@
+package publicid
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestHashidRoundTrip(t *testing.T) {
+	h := NewHashid("secret")
+	seen := map[string]bool{}
+	for _, id := range []uint{1, 2, 3, 1000, 1<<32 - 1} {
+		s := h.Encode(KindOrder, id)
+		if !strings.HasPrefix(s, "ord_") || len(s) != 4+hashidWidth {
+			t.Fatalf("bad format %q", s)
+		}
+		if seen[s] {
+			t.Fatalf("duplicate %q", s)
+		}
+		seen[s] = true
+		got, err := h.Decode(KindOrder, s)
+		if err != nil || got != id {
+			t.Fatalf("decode %q = %d, %v; want %d", s, got, err, id)
+		}
+	}
+}
+
+func TestHashidRejects(t *testing.T) {
+	h := NewHashid("secret")
+	s := h.Encode(KindOrder, 42)
+	// 类型不同、密钥不同、篡改、格式错误均无效
+	if _, err := h.Decode(KindPlan, "pln_"+s[4:]); err == nil {
+		t.Error("kind swap accepted")
+	}
+	if _, err := NewHashid("other").Decode(KindOrder, s); err == nil {
+		t.Error("other secret accepted")
+	}
+	tampered := s[:len(s)-1] + string(base62[(strings.IndexByte(base62, s[len(s)-1])+1)%62])
+	if _, err := h.Decode(KindOrder, tampered); err == nil {
+		t.Error("tampered id accepted")
+	}
+	for _, bad := range []string{"", "42", "ord_", "ord_zzzzzzzzzzz", "ord_abc!defghij"} {
+		if _, err := h.Decode(KindOrder, bad); err == nil {
+			t.Errorf("%q accepted", bad)
+		}
+	}
+}
+
+func TestNumeric(t *testing.T) {
+	var c Codec = Numeric{}
+	if c.Encode(KindPlan, 7) != "7" || c.Opaque() {
+		t.Fatal("numeric encode")
+	}
+	if id, err := c.Decode(KindPlan, "7"); err != nil || id != 7 {
+		t.Fatalf("decode = %d, %v", id, err)
+	}
+	if _, err := c.Decode(KindPlan, "pln_x"); err == nil {
+		t.Fatal("non numeric accepted")
+	}
+}
+
+func TestRefUnmarshal(t *testing.T) {
+	var v struct {
+		A Ref `json:"a"`
+		B Ref `json:"b"`
+	}
+	if err := json.Unmarshal([]byte(`{"a":12,"b":"pln_x"}`), &v); err != nil {
+		t.Fatal(err)
+	}
+	if v.A != "12" || v.B != "pln_x" {
+		t.Fatalf("got %q %q", v.A, v.B)
+	}
+}
@@ -0,0 +1,125 @@
+// Package shed 自适应并发限制: 按请求耗时调整并发上限(AIMD), 超出上限时按优先级拒绝
+package shed
+
+import (
+	"sync"
+	"time"
+)
+
+// Priority 请求优先级, 负载升高时先拒绝低优先级请求
+type Priority int
+
+const (
+	Low  Priority = iota // 统计、会话上报等可重试的请求
+	High                 // 订阅检查、relay 放行等影响连接建立的请求
+)
+
+// Options 限制参数, 零值使用默认值
+type Options struct {
+	MinLimit      int           // 并发上限的下限, 默认 4
+	MaxLimit      int           // 并发上限的上限, 默认 256
+	InitialLimit  int           // 初始上限, 默认 MaxLimit/4
+	TargetLatency time.Duration // 耗时超过该值视为过载, 默认 200ms
+	LowShare      float64       // 低优先级可使用的上限比例, 默认 0.5
+	Backoff       float64       // 过载时上限的缩小比例, 默认 0.9
+}
+
+func (o *Options) setDefaults() {
+	if o.MinLimit <= 0 {
+		o.MinLimit = 4
+	}
+	if o.MaxLimit < o.MinLimit {
+		o.MaxLimit = 256
+		if o.MaxLimit < o.MinLimit {
+			o.MaxLimit = o.MinLimit
+		}
+	}
+	if o.InitialLimit <= 0 {
+		o.InitialLimit = o.MaxLimit / 4
+	}
+	if o.InitialLimit < o.MinLimit {
+		o.InitialLimit = o.MinLimit
+	}
+	if o.InitialLimit > o.MaxLimit {
+		o.InitialLimit = o.MaxLimit
+	}
+	if o.TargetLatency <= 0 {
+		o.TargetLatency = 200 * time.Millisecond
+	}
+	if o.LowShare <= 0 || o.LowShare > 1 {
+		o.LowShare = 0.5
+	}
+	if o.Backoff <= 0 || o.Backoff >= 1 {
+		o.Backoff = 0.9
+	}
+}
+
+// Limiter 自适应并发限制
+// 请求正常完成时上限每轮(约 limit 个请求)加 1, 耗时超过目标或失败时乘以 Backoff,
+// 同一个目标耗时周期内最多缩小一次, 避免同一批慢请求连续缩小
+type Limiter struct {
+	mu           sync.Mutex
+	opts         Options
+	limit        float64
+	inflight     int
+	lastDecrease time.Time
+	now          func() time.Time
+}
+
+func New(opts Options) *Limiter {
+	opts.setDefaults()
+	return &Limiter{opts: opts, limit: float64(opts.InitialLimit), now: time.Now}
+}
+
+// Acquire 获取一个并发槽位, 返回 false 时应拒绝请求; 成功时必须调用 Release
+func (l *Limiter) Acquire(p Priority) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	max := l.limit
+	if p < High {
+		max = l.limit * l.opts.LowShare
+		if max < 1 {
+			max = 1
+		}
+	}
+	if float64(l.inflight) >= max {
+		return false
+	}
+	l.inflight++
+	return true
+}
+
+// Release 归还槽位并根据耗时调整上限, failed 表示请求出错(如数据库超时)
+func (l *Limiter) Release(latency time.Duration, failed bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.inflight > 0 {
+		l.inflight--
+	}
+	if failed || latency > l.opts.TargetLatency {
+		now := l.now()
+		if now.Sub(l.lastDecrease) < l.opts.TargetLatency {
+			return
+		}
+		l.lastDecrease = now
+		l.limit *= l.opts.Backoff
+		if l.limit < float64(l.opts.MinLimit) {
+			l.limit = float64(l.opts.MinLimit)
+		}
+		return
+	}
+	// 只在上限接近用满时增加, 避免空闲时上限无意义地涨到最大
+	if float64(l.inflight+1) >= l.limit/2 {
+		l.limit += 1 / l.limit
+		if l.limit > float64(l.opts.MaxLimit) {
+			l.limit = float64(l.opts.MaxLimit)
+		}
+	}
+}
+
+// Stats 当前上限和进行中的请求数
+func (l *Limiter) Stats() (limit int, inflight int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return int(l.limit), l.inflight
+}
@@ -0,0 +1,75 @@
+package shed
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLimiterPriority(t *testing.T) {
+	l := New(Options{MinLimit: 4, MaxLimit: 4})
+	for i := 0; i < 2; i++ {
+		if !l.Acquire(Low) {
+			t.Fatalf("low %d rejected", i)
+		}
+	}
+	if l.Acquire(Low) {
+		t.Fatal("low priority should be limited to half of the limit")
+	}
+	for i := 0; i < 2; i++ {
+		if !l.Acquire(High) {
+			t.Fatalf("high %d rejected", i)
+		}
+	}
+	if l.Acquire(High) {
+		t.Fatal("high priority should be limited to the limit")
+	}
+	l.Release(time.Millisecond, false)
+	if !l.Acquire(High) {
+		t.Fatal("released slot not reusable")
+	}
+}
+
+func TestLimiterAdapt(t *testing.T) {
+	now := time.Unix(1000, 0)
+	l := New(Options{MinLimit: 2, MaxLimit: 20, InitialLimit: 10, TargetLatency: 100 * time.Millisecond})
+	l.now = func() time.Time { return now }
+
+	// 慢请求缩小上限, 同一周期内只缩小一次
+	for i := 0; i < 3; i++ {
+		l.Acquire(High)
+	}
+	l.Release(time.Second, false)
+	l.Release(time.Second, false)
+	if limit, _ := l.Stats(); limit != 9 {
+		t.Fatalf("limit after slow = %d, want 9", limit)
+	}
+	now = now.Add(time.Second)
+	l.Release(0, true)
+	if limit, inflight := l.Stats(); limit != 8 || inflight != 0 {
+		t.Fatalf("limit after failure = %d inflight %d, want 8 0", limit, inflight)
+	}
+
+	// 接近用满时的正常请求逐步增加上限
+	for i := 0; i < 200; i++ {
+		n := 0
+		for l.Acquire(High) {
+			n++
+		}
+		for j := 0; j < n; j++ {
+			l.Release(time.Millisecond, false)
+		}
+	}
+	if limit, _ := l.Stats(); limit != 20 {
+		t.Fatalf("limit after recovery = %d, want 20", limit)
+	}
+
+	// 不低于下限
+	for i := 0; i < 50; i++ {
+		now = now.Add(time.Second)
+		l.Acquire(High)
+		l.Release(time.Second, false)
+	}
+	if limit, _ := l.Stats(); limit != 2 {
+		t.Fatalf("limit floor = %d, want 2", limit)
+	}
+}
@@ -0,0 +1,46 @@
+// Package storage 文件存储抽象, 支持本地磁盘和 S3 兼容的对象存储, 下载通过带过期时间的签名 URL
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"path"
+	"strings"
+	"time"
+)
+
+var (
+	ErrNotFound   = errors.New("storage: object not found")
+	ErrInvalidKey = errors.New("storage: invalid key")
+)
+
+// Storage 存储后端, key 为 / 分隔的相对路径
+type Storage interface {
+	// Put 写入对象, 已存在时覆盖; size 未知时传 -1(S3 需要先缓存到内存)
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	// Open 读取对象, 不存在时返回 ErrNotFound
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete 删除对象, 不存在时不报错
+	Delete(ctx context.Context, key string) error
+	// SignedURL 生成有效期为 expire 的临时下载地址
+	SignedURL(key string, expire time.Duration) (string, error)
+}
+
+// CleanKey 规范化 key, 拒绝空值、绝对路径和 ..
+func CleanKey(key string) (string, error) {
+	key = strings.ReplaceAll(strings.TrimSpace(key), "\\", "/")
+	if key == "" || strings.HasPrefix(key, "/") {
+		return "", ErrInvalidKey
+	}
+	for _, seg := range strings.Split(key, "/") {
+		if seg == ".." {
+			return "", ErrInvalidKey
+		}
+	}
+	key = path.Clean(key)
+	if key == "." {
+		return "", ErrInvalidKey
+	}
+	return key, nil
+}
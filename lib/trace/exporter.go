@@ -0,0 +1,268 @@
+package trace
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	queueSize     = 2048
+	batchSize     = 256
+	flushInterval = 5 * time.Second
+)
+
+// Config 导出配置
+type Config struct {
+	Endpoint    string            // collector 地址, 如 http://otel-collector:4318, 未带路径时追加 /v1/traces
+	ServiceName string            // service.name
+	SampleRatio float64           // 采样率 0-1, 仅对没有上游的新 trace 生效
+	Headers     map[string]string // 附加请求头, 如鉴权
+	Timeout     time.Duration     // 导出请求超时
+	ErrorLog    func(args ...interface{})
+}
+
+// Tracer 收集结束的 span 并批量导出
+type Tracer struct {
+	cfg       Config
+	endpoint  string
+	client    *http.Client
+	queue     chan *Span
+	stop      chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// New 创建 Tracer 并启动后台导出
+func New(cfg Config) *Tracer {
+	if cfg.ServiceName == "" {
+		cfg.ServiceName = "rustdesk-api"
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+	if cfg.SampleRatio < 0 {
+		cfg.SampleRatio = 0
+	} else if cfg.SampleRatio > 1 {
+		cfg.SampleRatio = 1
+	}
+	endpoint := strings.TrimRight(cfg.Endpoint, "/")
+	if !strings.HasSuffix(endpoint, "/v1/traces") {
+		endpoint += "/v1/traces"
+	}
+	t := &Tracer{
+		cfg:      cfg,
+		endpoint: endpoint,
+		// 导出请求不经过追踪 Transport, 避免自身产生 span
+		client: &http.Client{Timeout: cfg.Timeout},
+		queue:  make(chan *Span, queueSize),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	go t.loop()
+	return t
+}
+
+// sample 按 trace id 低 8 字节决定是否采样, 同一 trace 在各服务间结果一致
+func (t *Tracer) sample(id [16]byte) bool {
+	switch {
+	case t.cfg.SampleRatio >= 1:
+		return true
+	case t.cfg.SampleRatio <= 0:
+		return false
+	}
+	v := binary.BigEndian.Uint64(id[8:]) >> 1
+	return float64(v) < t.cfg.SampleRatio*float64(uint64(1)<<63)
+}
+
+// enqueue 队列满时丢弃, 不阻塞业务
+func (t *Tracer) enqueue(s *Span) {
+	select {
+	case t.queue <- s:
+	default:
+	}
+}
+
+// Shutdown 停止并导出剩余 span
+func (t *Tracer) Shutdown() {
+	t.closeOnce.Do(func() {
+		close(t.stop)
+		<-t.done
+	})
+}
+
+func (t *Tracer) loop() {
+	defer close(t.done)
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]*Span, 0, batchSize)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := t.export(batch); err != nil && t.cfg.ErrorLog != nil {
+			t.cfg.ErrorLog("Trace export failed: ", err)
+		}
+		batch = make([]*Span, 0, batchSize)
+	}
+	for {
+		select {
+		case s := <-t.queue:
+			batch = append(batch, s)
+			if len(batch) >= batchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		case <-t.stop:
+			for {
+				select {
+				case s := <-t.queue:
+					batch = append(batch, s)
+				default:
+					flush()
+					return
+				}
+			}
+		}
+	}
+}
+
+func (t *Tracer) export(spans []*Span) error {
+	body, err := json.Marshal(t.encode(spans))
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, t.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range t.cfg.Headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("collector returned %s", resp.Status)
+	}
+	return nil
+}
+
+// OTLP/HTTP JSON 结构, 参见 opentelemetry-proto 的 JSON 映射
+type otlpRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpKeyValue `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpSpan struct {
+	TraceId           string         `json:"traceId"`
+	SpanId            string         `json:"spanId"`
+	ParentSpanId      string         `json:"parentSpanId,omitempty"`
+	Name              string         `json:"name"`
+	Kind              int            `json:"kind"`
+	StartTimeUnixNano string         `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+	Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+	Status            otlpStatus     `json:"status"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+type otlpKeyValue struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+type otlpValue struct {
+	StringValue *string  `json:"stringValue,omitempty"`
+	BoolValue   *bool    `json:"boolValue,omitempty"`
+	IntValue    *string  `json:"intValue,omitempty"`
+	DoubleValue *float64 `json:"doubleValue,omitempty"`
+}
+
+func (t *Tracer) encode(spans []*Span) *otlpRequest {
+	out := make([]otlpSpan, 0, len(spans))
+	for _, s := range spans {
+		s.mu.Lock()
+		o := otlpSpan{
+			TraceId:           hex.EncodeToString(s.traceID[:]),
+			SpanId:            hex.EncodeToString(s.spanID[:]),
+			Name:              s.name,
+			Kind:              int(s.kind),
+			StartTimeUnixNano: strconv.FormatInt(s.start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(s.end.UnixNano(), 10),
+			Status:            otlpStatus{Code: s.statusCode, Message: s.statusMsg},
+		}
+		if s.parentID != [8]byte{} {
+			o.ParentSpanId = hex.EncodeToString(s.parentID[:])
+		}
+		for _, a := range s.attrs {
+			o.Attributes = append(o.Attributes, keyValue(a.Key, a.Value))
+		}
+		s.mu.Unlock()
+		out = append(out, o)
+	}
+	return &otlpRequest{ResourceSpans: []otlpResourceSpans{{
+		Resource:   otlpResource{Attributes: []otlpKeyValue{keyValue("service.name", t.cfg.ServiceName)}},
+		ScopeSpans: []otlpScopeSpans{{Scope: otlpScope{Name: "rustdesk-api"}, Spans: out}},
+	}}}
+}
+
+func keyValue(key string, v interface{}) otlpKeyValue {
+	var val otlpValue
+	switch x := v.(type) {
+	case string:
+		val.StringValue = &x
+	case bool:
+		val.BoolValue = &x
+	case int:
+		s := strconv.FormatInt(int64(x), 10)
+		val.IntValue = &s
+	case int64:
+		s := strconv.FormatInt(x, 10)
+		val.IntValue = &s
+	case uint:
+		s := strconv.FormatUint(uint64(x), 10)
+		val.IntValue = &s
+	case float64:
+		val.DoubleValue = &x
+	default:
+		s := fmt.Sprint(v)
+		val.StringValue = &s
+	}
+	return otlpKeyValue{Key: key, Value: val}
+}
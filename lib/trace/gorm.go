@@ -0,0 +1,87 @@
+package trace
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+const gormSpanKey = "trace:span"
+
+// GormPlugin 为携带 span 的查询(DB.WithContext(ctx))创建子 span, 没有父级的查询不记录
+type GormPlugin struct{}
+
+func (GormPlugin) Name() string {
+	return "trace"
+}
+
+func (GormPlugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	if err := cb.Create().Before("gorm:create").Register("trace:before_create", gormBefore("gorm.create")); err != nil {
+		return err
+	}
+	if err := cb.Create().After("gorm:create").Register("trace:after_create", gormAfter); err != nil {
+		return err
+	}
+	if err := cb.Query().Before("gorm:query").Register("trace:before_query", gormBefore("gorm.query")); err != nil {
+		return err
+	}
+	if err := cb.Query().After("gorm:query").Register("trace:after_query", gormAfter); err != nil {
+		return err
+	}
+	if err := cb.Update().Before("gorm:update").Register("trace:before_update", gormBefore("gorm.update")); err != nil {
+		return err
+	}
+	if err := cb.Update().After("gorm:update").Register("trace:after_update", gormAfter); err != nil {
+		return err
+	}
+	if err := cb.Delete().Before("gorm:delete").Register("trace:before_delete", gormBefore("gorm.delete")); err != nil {
+		return err
+	}
+	if err := cb.Delete().After("gorm:delete").Register("trace:after_delete", gormAfter); err != nil {
+		return err
+	}
+	if err := cb.Row().Before("gorm:row").Register("trace:before_row", gormBefore("gorm.row")); err != nil {
+		return err
+	}
+	if err := cb.Row().After("gorm:row").Register("trace:after_row", gormAfter); err != nil {
+		return err
+	}
+	if err := cb.Raw().Before("gorm:raw").Register("trace:before_raw", gormBefore("gorm.raw")); err != nil {
+		return err
+	}
+	return cb.Raw().After("gorm:raw").Register("trace:after_raw", gormAfter)
+}
+
+type gormCallback = func(*gorm.DB)
+
+func gormBefore(name string) gormCallback {
+	return func(db *gorm.DB) {
+		if db.Statement == nil || FromContext(db.Statement.Context) == nil {
+			return
+		}
+		_, span := Start(db.Statement.Context, name, KindClient)
+		if span != nil {
+			db.InstanceSet(gormSpanKey, span)
+		}
+	}
+}
+
+func gormAfter(db *gorm.DB) {
+	v, ok := db.InstanceGet(gormSpanKey)
+	if !ok {
+		return
+	}
+	span, _ := v.(*Span)
+	if span == nil {
+		return
+	}
+	span.SetAttr("db.system", db.Dialector.Name())
+	span.SetAttr("db.sql.table", db.Statement.Table)
+	span.SetAttr("db.statement", db.Statement.SQL.String())
+	span.SetAttr("db.rows_affected", db.Statement.RowsAffected)
+	if db.Error != nil && !errors.Is(db.Error, gorm.ErrRecordNotFound) {
+		span.SetError(db.Error)
+	}
+	span.End()
+}
@@ -0,0 +1,47 @@
+package trace
+
+import (
+	"net/http"
+)
+
+type transport struct {
+	base http.RoundTripper
+}
+
+// Transport 为出站 HTTP 请求创建 client span 并注入 traceparent, base 为空时使用 http.DefaultTransport
+func Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &transport{base: base}
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx, span := Start(req.Context(), "HTTP "+req.Method+" "+req.URL.Host, KindClient)
+	if span == nil {
+		return t.base.RoundTrip(req)
+	}
+	defer span.End()
+
+	req = req.Clone(ctx)
+	req.Header.Set("traceparent", span.TraceParent())
+	span.SetAttr("http.method", req.Method)
+	// 不记录查询参数, 避免泄露签名/密钥
+	span.SetAttr("http.url", req.URL.Scheme+"://"+req.URL.Host+req.URL.Path)
+	span.SetAttr("net.peer.name", req.URL.Hostname())
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		span.SetError(err)
+		return nil, err
+	}
+	span.SetAttr("http.status_code", resp.StatusCode)
+	if resp.StatusCode >= 500 {
+		span.SetError(httpStatusError(resp.Status))
+	}
+	return resp, nil
+}
+
+type httpStatusError string
+
+func (e httpStatusError) Error() string { return string(e) }
@@ -0,0 +1,193 @@
+// Package trace 轻量链路追踪, 兼容 W3C traceparent, 通过 OTLP/HTTP(JSON) 批量导出到 collector
+package trace
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// SpanKind 与 OTLP 定义一致
+type SpanKind int
+
+const (
+	KindInternal SpanKind = 1
+	KindServer   SpanKind = 2
+	KindClient   SpanKind = 3
+)
+
+// statusError OTLP 状态码: 失败
+const statusError = 2
+
+type attribute struct {
+	Key   string
+	Value interface{}
+}
+
+// Span 一次操作的耗时记录, nil Span 的所有方法均为空操作
+type Span struct {
+	tracer   *Tracer
+	traceID  [16]byte
+	spanID   [8]byte
+	parentID [8]byte
+	name     string
+	kind     SpanKind
+	start    time.Time
+	sampled  bool
+	remote   bool // 从请求头解析的上游 span, 只用于关联, 不导出
+
+	mu         sync.Mutex
+	end        time.Time
+	attrs      []attribute
+	statusCode int
+	statusMsg  string
+	ended      bool
+}
+
+// SetAttr 设置属性
+func (s *Span) SetAttr(key string, value interface{}) {
+	if s == nil || !s.sampled {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.attrs = append(s.attrs, attribute{Key: key, Value: value})
+}
+
+// SetError 标记为失败
+func (s *Span) SetError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.statusCode = statusError
+	s.statusMsg = err.Error()
+}
+
+// End 结束并提交导出, 重复调用无效
+func (s *Span) End() {
+	if s == nil || s.remote {
+		return
+	}
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.end = time.Now()
+	s.mu.Unlock()
+	if s.sampled && s.tracer != nil {
+		s.tracer.enqueue(s)
+	}
+}
+
+// TraceID 十六进制 trace id
+func (s *Span) TraceID() string {
+	if s == nil {
+		return ""
+	}
+	return hex.EncodeToString(s.traceID[:])
+}
+
+// TraceParent W3C traceparent 头
+func (s *Span) TraceParent() string {
+	if s == nil {
+		return ""
+	}
+	flags := "00"
+	if s.sampled {
+		flags = "01"
+	}
+	return "00-" + s.TraceID() + "-" + hex.EncodeToString(s.spanID[:]) + "-" + flags
+}
+
+type spanKey struct{}
+
+// FromContext 获取 ctx 中的当前 span
+func FromContext(ctx context.Context) *Span {
+	if ctx == nil {
+		return nil
+	}
+	s, _ := ctx.Value(spanKey{}).(*Span)
+	return s
+}
+
+// ContextWithSpan 将 span 放入 ctx
+func ContextWithSpan(ctx context.Context, s *Span) context.Context {
+	return context.WithValue(ctx, spanKey{}, s)
+}
+
+// Extract 解析上游的 traceparent 头, 作为后续 span 的父级; 格式不正确时忽略
+func Extract(ctx context.Context, traceparent string) context.Context {
+	parts := strings.Split(strings.TrimSpace(traceparent), "-")
+	if len(parts) != 4 || parts[0] != "00" || len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return ctx
+	}
+	s := &Span{remote: true}
+	if _, err := hex.Decode(s.traceID[:], []byte(parts[1])); err != nil {
+		return ctx
+	}
+	if _, err := hex.Decode(s.spanID[:], []byte(parts[2])); err != nil {
+		return ctx
+	}
+	flags, err := hex.DecodeString(parts[3])
+	if err != nil || s.traceID == [16]byte{} || s.spanID == [8]byte{} {
+		return ctx
+	}
+	s.sampled = flags[0]&1 == 1
+	return ContextWithSpan(ctx, s)
+}
+
+var defaultTracer atomic.Pointer[Tracer]
+
+// SetDefault 设置全局 Tracer, nil 表示关闭追踪
+func SetDefault(t *Tracer) {
+	defaultTracer.Store(t)
+}
+
+// Default 全局 Tracer, 未启用时为 nil
+func Default() *Tracer {
+	return defaultTracer.Load()
+}
+
+// Enabled 是否启用了追踪
+func Enabled() bool {
+	return Default() != nil
+}
+
+// Start 开始一个 span, 未启用追踪时返回原 ctx 和 nil
+// 有父级时继承其 trace id 和采样结果, 否则按采样率决定是否记录
+func Start(ctx context.Context, name string, kind SpanKind) (context.Context, *Span) {
+	t := Default()
+	if t == nil {
+		return ctx, nil
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	s := &Span{tracer: t, name: name, kind: kind, start: time.Now()}
+	if parent := FromContext(ctx); parent != nil {
+		s.traceID = parent.traceID
+		s.parentID = parent.spanID
+		s.sampled = parent.sampled
+	} else {
+		randomBytes(s.traceID[:])
+		s.sampled = t.sample(s.traceID)
+	}
+	randomBytes(s.spanID[:])
+	return ContextWithSpan(ctx, s), s
+}
+
+func randomBytes(b []byte) {
+	if _, err := rand.Read(b); err != nil {
+		// crypto/rand 不可用时退化为时间戳, 仅影响 id 的随机性
+		binary.BigEndian.PutUint64(b[len(b)-8:], uint64(time.Now().UnixNano()))
+	}
+}
@@ -0,0 +1,98 @@
+package trace
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStartDisabled(t *testing.T) {
+	SetDefault(nil)
+	ctx, span := Start(context.Background(), "noop", KindInternal)
+	if span != nil || FromContext(ctx) != nil {
+		t.Fatal("expected no span when tracing is disabled")
+	}
+	// nil span 的方法均为空操作
+	span.SetAttr("k", "v")
+	span.SetError(errors.New("x"))
+	span.End()
+}
+
+func TestExtractAndPropagate(t *testing.T) {
+	tr := New(Config{Endpoint: "http://127.0.0.1:0", SampleRatio: 0})
+	SetDefault(tr)
+	defer SetDefault(nil)
+
+	ctx := Extract(context.Background(), "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	_, span := Start(ctx, "child", KindServer)
+	if span.TraceID() != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Fatalf("trace id not inherited: %s", span.TraceID())
+	}
+	if !span.sampled {
+		t.Fatal("sampled flag should be inherited from upstream")
+	}
+	if !strings.HasPrefix(span.TraceParent(), "00-4bf92f3577b34da6a3ce929d0e0e4736-") || !strings.HasSuffix(span.TraceParent(), "-01") {
+		t.Fatalf("bad traceparent %s", span.TraceParent())
+	}
+
+	for _, bad := range []string{"", "garbage", "00-00000000000000000000000000000000-00f067aa0ba902b7-01", "01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7"} {
+		if FromContext(Extract(context.Background(), bad)) != nil {
+			t.Errorf("traceparent %q should be ignored", bad)
+		}
+	}
+}
+
+func TestExport(t *testing.T) {
+	got := make(chan map[string]interface{}, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/traces" || r.Header.Get("X-Token") != "secret" {
+			t.Errorf("unexpected request %s %v", r.URL.Path, r.Header)
+		}
+		var body map[string]interface{}
+		b, _ := io.ReadAll(r.Body)
+		json.Unmarshal(b, &body)
+		got <- body
+	}))
+	defer srv.Close()
+
+	tr := New(Config{Endpoint: srv.URL, SampleRatio: 1, Headers: map[string]string{"X-Token": "secret"}})
+	SetDefault(tr)
+	defer SetDefault(nil)
+
+	ctx, parent := Start(context.Background(), "parent", KindServer)
+	_, child := Start(ctx, "child", KindClient)
+	child.SetAttr("http.status_code", 500)
+	child.SetError(errors.New("boom"))
+	child.End()
+	parent.End()
+	tr.Shutdown()
+
+	select {
+	case body := <-got:
+		spans := body["resourceSpans"].([]interface{})[0].(map[string]interface{})["scopeSpans"].([]interface{})[0].(map[string]interface{})["spans"].([]interface{})
+		if len(spans) != 2 {
+			t.Fatalf("expected 2 spans, got %d", len(spans))
+		}
+		c := spans[0].(map[string]interface{})
+		if c["name"] != "child" || c["parentSpanId"] == nil || c["status"].(map[string]interface{})["code"].(float64) != statusError {
+			t.Fatalf("bad child span %v", c)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("spans were not exported")
+	}
+}
+
+func TestSampleRatio(t *testing.T) {
+	tr := &Tracer{cfg: Config{SampleRatio: 0.5}}
+	var low, high [16]byte
+	high[8] = 0xff
+	if !tr.sample(low) || tr.sample(high) {
+		t.Fatal("unexpected sampling decision")
+	}
+}
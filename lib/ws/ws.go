@@ -0,0 +1,229 @@
+// Package ws 最小的 WebSocket(RFC 6455) 服务端实现, 仅支持服务端推送文本消息和读取客户端控制帧
+package ws
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const acceptGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// MaxPayload 客户端单帧负载上限, 推送通道只需要接收控制帧和少量文本
+const MaxPayload = 4096
+
+const (
+	OpText  = 0x1
+	OpClose = 0x8
+	OpPing  = 0x9
+	OpPong  = 0xA
+)
+
+var (
+	ErrNotWebSocket = errors.New("ws: not a websocket handshake")
+	ErrBadVersion   = errors.New("ws: unsupported websocket version")
+	ErrBadOrigin    = errors.New("ws: origin not allowed")
+	ErrTooLarge     = errors.New("ws: frame too large")
+	ErrUnmasked     = errors.New("ws: client frame not masked")
+	ErrClosed       = errors.New("ws: closed")
+)
+
+// Conn 已升级的连接, 写操作并发安全, 读操作只允许单协程调用
+type Conn struct {
+	conn net.Conn
+	br   *bufio.Reader
+	mu   sync.Mutex
+}
+
+// AcceptKey 根据客户端 Sec-WebSocket-Key 计算握手响应
+func AcceptKey(key string) string {
+	h := sha1.New()
+	h.Write([]byte(key + acceptGUID))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+func headerContains(h http.Header, name, value string) bool {
+	for _, v := range h.Values(name) {
+		for _, s := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(s), value) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// SameOrigin 没有 Origin 头(非浏览器客户端)或 Origin 的主机与请求的 Host 相同
+func SameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(u.Host, r.Host)
+}
+
+// Upgrade 完成握手并接管底层连接, 失败时已写回错误响应
+// 只支持版本 13; checkOrigin 为 nil 时使用 SameOrigin, 防止其他站点的页面借用浏览器中的凭据建立连接
+func Upgrade(w http.ResponseWriter, r *http.Request, checkOrigin func(r *http.Request) bool) (*Conn, error) {
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if r.Method != http.MethodGet || key == "" ||
+		!headerContains(r.Header, "Connection", "upgrade") ||
+		!headerContains(r.Header, "Upgrade", "websocket") {
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+		return nil, ErrNotWebSocket
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		http.Error(w, "Upgrade Required", http.StatusUpgradeRequired)
+		return nil, ErrBadVersion
+	}
+	if checkOrigin == nil {
+		checkOrigin = SameOrigin
+	}
+	if !checkOrigin(r) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return nil, ErrBadOrigin
+	}
+	hj, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+		return nil, ErrNotWebSocket
+	}
+	conn, rw, err := hj.Hijack()
+	if err != nil {
+		return nil, err
+	}
+	resp := "HTTP/1.1 101 Switching Protocols\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + AcceptKey(key) + "\r\n\r\n"
+	if _, err := conn.Write([]byte(resp)); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return &Conn{conn: conn, br: rw.Reader}, nil
+}
+
+// WriteText 发送一条文本消息
+func (c *Conn) WriteText(data []byte, timeout time.Duration) error {
+	return c.writeFrame(OpText, data, timeout)
+}
+
+// Ping 发送心跳
+func (c *Conn) Ping(timeout time.Duration) error {
+	return c.writeFrame(OpPing, nil, timeout)
+}
+
+func (c *Conn) writeFrame(op byte, data []byte, timeout time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if timeout > 0 {
+		c.conn.SetWriteDeadline(time.Now().Add(timeout))
+	}
+	_, err := c.conn.Write(encodeFrame(op, data))
+	return err
+}
+
+// encodeFrame 服务端帧不加掩码
+func encodeFrame(op byte, data []byte) []byte {
+	n := len(data)
+	buf := make([]byte, 0, n+10)
+	buf = append(buf, 0x80|op)
+	switch {
+	case n < 126:
+		buf = append(buf, byte(n))
+	case n <= 0xFFFF:
+		buf = append(buf, 126, byte(n>>8), byte(n))
+	default:
+		buf = append(buf, 127)
+		buf = binary.BigEndian.AppendUint64(buf, uint64(n))
+	}
+	return append(buf, data...)
+}
+
+// ReadMessage 读取下一条数据消息, 自动回复 ping, 收到 close 时回复并返回 ErrClosed
+func (c *Conn) ReadMessage() (byte, []byte, error) {
+	for {
+		op, data, err := readFrame(c.br)
+		if err != nil {
+			return 0, nil, err
+		}
+		switch op {
+		case OpPing:
+			if err := c.writeFrame(OpPong, data, time.Second*5); err != nil {
+				return 0, nil, err
+			}
+		case OpPong:
+		case OpClose:
+			c.writeFrame(OpClose, nil, time.Second)
+			return 0, nil, ErrClosed
+		default:
+			return op, data, nil
+		}
+	}
+}
+
+// readFrame 读取单个客户端帧, 客户端帧必须带掩码; 分片消息按帧返回, 推送通道不做拼接
+func readFrame(r io.Reader) (byte, []byte, error) {
+	var h [2]byte
+	if _, err := io.ReadFull(r, h[:]); err != nil {
+		return 0, nil, err
+	}
+	op := h[0] & 0x0F
+	if h[1]&0x80 == 0 {
+		return 0, nil, ErrUnmasked
+	}
+	n := uint64(h[1] & 0x7F)
+	switch n {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(r, ext[:]); err != nil {
+			return 0, nil, err
+		}
+		n = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(r, ext[:]); err != nil {
+			return 0, nil, err
+		}
+		n = binary.BigEndian.Uint64(ext[:])
+	}
+	if n > MaxPayload {
+		return 0, nil, ErrTooLarge
+	}
+	var mask [4]byte
+	if _, err := io.ReadFull(r, mask[:]); err != nil {
+		return 0, nil, err
+	}
+	data := make([]byte, n)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return 0, nil, err
+	}
+	for i := range data {
+		data[i] ^= mask[i%4]
+	}
+	return op, data, nil
+}
+
+// SetReadDeadline 设置读超时, 用于检测失联的客户端
+func (c *Conn) SetReadDeadline(t time.Time) error {
+	return c.conn.SetReadDeadline(t)
+}
+
+// Close 关闭底层连接
+func (c *Conn) Close() error {
+	return c.conn.Close()
+}
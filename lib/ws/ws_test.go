@@ -0,0 +1,90 @@
+package ws
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAcceptKey(t *testing.T) {
+	// RFC 6455 1.3 示例
+	if got := AcceptKey("dGhlIHNhbXBsZSBub25jZQ=="); got != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Fatalf("accept key = %s", got)
+	}
+}
+
+func maskedFrame(op byte, payload []byte) []byte {
+	mask := [4]byte{1, 2, 3, 4}
+	buf := []byte{0x80 | op, 0x80 | byte(len(payload))}
+	buf = append(buf, mask[:]...)
+	for i, b := range payload {
+		buf = append(buf, b^mask[i%4])
+	}
+	return buf
+}
+
+func TestReadFrame(t *testing.T) {
+	op, data, err := readFrame(bytes.NewReader(maskedFrame(OpText, []byte("hello"))))
+	if err != nil || op != OpText || string(data) != "hello" {
+		t.Fatalf("got op=%d data=%q err=%v", op, data, err)
+	}
+
+	unmasked := []byte{0x81, 0x01, 'x'}
+	if _, _, err := readFrame(bytes.NewReader(unmasked)); err != ErrUnmasked {
+		t.Fatalf("unmasked frame err = %v", err)
+	}
+
+	large := []byte{0x81, 0x80 | 127, 0, 0, 0, 0, 0, 1, 0, 0}
+	if _, _, err := readFrame(bytes.NewReader(large)); err != ErrTooLarge {
+		t.Fatalf("large frame err = %v", err)
+	}
+}
+
+func TestEncodeFrame(t *testing.T) {
+	if f := encodeFrame(OpText, []byte("hi")); !bytes.Equal(f, []byte{0x81, 2, 'h', 'i'}) {
+		t.Fatalf("short frame = %v", f)
+	}
+	f := encodeFrame(OpText, make([]byte, 300))
+	if f[1] != 126 || f[2] != 1 || f[3] != 44 || len(f) != 304 {
+		t.Fatalf("extended frame header = %v", f[:4])
+	}
+}
+
+func handshake(version, origin string) *http.Request {
+	r := httptest.NewRequest(http.MethodGet, "http://api.example.com/ws", nil)
+	r.Header.Set("Connection", "Upgrade")
+	r.Header.Set("Upgrade", "websocket")
+	r.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+	r.Header.Set("Sec-WebSocket-Version", version)
+	if origin != "" {
+		r.Header.Set("Origin", origin)
+	}
+	return r
+}
+
+func TestUpgradeRejects(t *testing.T) {
+	w := httptest.NewRecorder()
+	if _, err := Upgrade(w, handshake("8", ""), nil); err != ErrBadVersion || w.Code != http.StatusUpgradeRequired || w.Header().Get("Sec-WebSocket-Version") != "13" {
+		t.Errorf("old version: err = %v, code = %d", err, w.Code)
+	}
+	w = httptest.NewRecorder()
+	if _, err := Upgrade(w, handshake("13", "https://evil.example.com"), nil); err != ErrBadOrigin || w.Code != http.StatusForbidden {
+		t.Errorf("cross origin: err = %v, code = %d", err, w.Code)
+	}
+}
+
+func TestSameOrigin(t *testing.T) {
+	cases := map[string]bool{
+		"":                          true, // 非浏览器客户端
+		"http://api.example.com":    true,
+		"https://API.example.com":   true,
+		"https://evil.example.com":  false,
+		"http://api.example.com:81": false,
+	}
+	for origin, want := range cases {
+		if got := SameOrigin(handshake("13", origin)); got != want {
+			t.Errorf("SameOrigin(%q) = %v, want %v", origin, got, want)
+		}
+	}
+}
@@ -45,10 +45,14 @@ type AddressBookList struct {
 	Pagination
 }
 
+// AddressBookCollection 地址簿
+// OrgId 大于 0 时为团队地址簿, 归组织所有, UserId 为组织所有者, 组织成员按 MemberRule 或单独设置的个人规则访问
 type AddressBookCollection struct {
 	IdModel
-	UserId uint   `json:"user_id" gorm:"default:0;not null;index"`
-	Name   string `json:"name" gorm:"default:'';not null;" validate:"required"`
+	UserId     uint   `json:"user_id" gorm:"default:0;not null;index"`
+	Name       string `json:"name" gorm:"default:'';not null;" validate:"required"`
+	OrgId      uint   `json:"org_id" gorm:"default:0;not null;index"`
+	MemberRule int    `json:"member_rule" gorm:"default:0;not null;" validate:"gte=0,lte=3"` // 组织成员默认权限 0: 无 1: 读 2: 读写 3: 完全控制
 	TimeModel
 }
 type AddressBookCollectionList struct {
@@ -69,6 +73,24 @@ type AddressBookCollectionRuleList struct {
 	Pagination
 }
 
+// BulkEdit 批量编辑地址簿或设备
+// Atomic 为 true 时任一记录失败则全部回滚, 否则只跳过失败的记录
+type BulkEdit struct {
+	AddTags      []string        `json:"add_tags"`      // 添加标签(仅地址簿)
+	RemoveTags   []string        `json:"remove_tags"`   // 移除标签(仅地址簿)
+	Aliases      map[uint]string `json:"aliases"`       // row_id => 新别名
+	CollectionId *uint           `json:"collection_id"` // 移动到的地址簿, 0 为所有者的默认地址簿(仅地址簿)
+	GroupId      *uint           `json:"group_id"`      // 移动到的设备分组, 0 为不分组(仅设备)
+	Atomic       bool            `json:"atomic"`
+}
+
+// BulkEditResult 批量编辑中单条记录的结果
+type BulkEditResult struct {
+	RowId   uint   `json:"row_id"`
+	Success bool   `json:"success"`
+	Error   string `json:"error,omitempty"` // 失败原因
+}
+
 const (
 	ShareAddressBookRuleTypePersonal = 1
 	ShareAddressBookRuleTypeGroup    = 2
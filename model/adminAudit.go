@@ -0,0 +1,40 @@
+package model
+
+import "github.com/lejianwen/rustdesk-api/v2/model/custom_types"
+
+// 涉及资金的管理员操作
+const (
+	AdminAuditPaymentConfig = "payment_config" // 修改支付配置
+	AdminAuditPlanCreate    = "plan_create"    // 创建套餐
+	AdminAuditPlanUpdate    = "plan_update"    // 修改套餐
+	AdminAuditPlanDelete    = "plan_delete"    // 删除(禁用)套餐
+	AdminAuditProduct       = "product"        // 创建/修改/禁用商品
+	AdminAuditFulfillment   = "fulfillment"    // 更新商品订单履约状态
+	AdminAuditGrant         = "grant"          // 手动赠送订阅
+	AdminAuditOrderCreate   = "order_create"   // 代用户下单
+	AdminAuditGrantBatch    = "grant_batch"    // 批量赠送订阅
+	AdminAuditOrganization  = "organization"   // 创建/解散组织, 增删成员
+	AdminAuditPause         = "pause"          // 暂停/恢复订阅
+	AdminAuditRefund        = "refund"         // 退款
+	AdminAuditRefundRequest = "refund_request" // 提交退款申请
+	AdminAuditRefundReject  = "refund_reject"  // 驳回退款申请
+	AdminAuditSetting       = "setting"        // 修改通用设置
+)
+
+// AdminAudit 涉及资金的管理员操作审计, 记录修改前后的值(密钥已遮蔽)
+// 与 AdminLog 不同, AdminLog 只记录请求路径, 这里记录具体改了什么
+type AdminAudit struct {
+	IdModel
+	UserId   uint                  `json:"user_id" gorm:"default:0;not null;index"` // 操作的管理员
+	Action   string                `json:"action" gorm:"size:32;default:'';not null;index"`
+	TargetId uint                  `json:"target_id" gorm:"default:0;not null;index"` // 套餐/订单/用户ID, 配置类为0
+	Before   custom_types.AutoJson `json:"before" gorm:"type:text" swaggertype:"object"`
+	After    custom_types.AutoJson `json:"after" gorm:"type:text" swaggertype:"object"`
+	Ip       string                `json:"ip" gorm:"default:'';not null"`
+	TimeModel
+}
+
+type AdminAuditList struct {
+	AdminAudits []*AdminAudit `json:"list"`
+	Pagination
+}
@@ -0,0 +1,46 @@
+package model
+
+// 管理员操作类型
+const (
+	AdminLogActionRefund   = "refund"    // 退款
+	AdminLogActionMarkPaid = "mark_paid" // 手动标记支付
+	AdminLogActionConfig   = "config"    // 修改配置
+	AdminLogActionOther    = "other"     // 其他写操作
+)
+
+// AdminLog 管理员操作日志(仅记录写操作)
+type AdminLog struct {
+	IdModel
+	UserId uint   `json:"user_id" gorm:"default:0;not null;index"`
+	Action string `json:"action" gorm:"default:'';not null;index"`
+	Method string `json:"method" gorm:"default:'';not null;"`
+	Path   string `json:"path" gorm:"default:'';not null;"`
+	Ip     string `json:"ip" gorm:"default:'';not null;"`
+	Status int    `json:"status" gorm:"default:0;not null;"` // HTTP状态码
+	TimeModel
+}
+
+type AdminLogList struct {
+	AdminLogs []*AdminLog `json:"list"`
+	Pagination
+}
+
+// 管理员行为异常标记
+const (
+	AdminActivityFlagRefundSpike = "refund_spike" // 近24小时退款量明显高于统计窗口内日均
+)
+
+// AdminActivity 管理员近期行为汇总
+type AdminActivity struct {
+	UserId         uint     `json:"user_id"`
+	Username       string   `json:"username"`
+	IsAdmin        bool     `json:"is_admin"`
+	Logins         int64    `json:"logins"`           // 后台登录次数
+	Actions        int64    `json:"actions"`          // 写操作总数
+	Refunds        int64    `json:"refunds"`          // 退款次数
+	MarkPaid       int64    `json:"mark_paid"`        // 手动标记支付次数
+	ConfigChanges  int64    `json:"config_changes"`   // 配置修改次数
+	RefundsLast24h int64    `json:"refunds_last_24h"` // 近24小时退款次数
+	RefundDailyAvg float64  `json:"refund_daily_avg"` // 统计窗口内(不含近24小时)日均退款次数
+	Flags          []string `json:"flags"`
+}
@@ -0,0 +1,18 @@
+package model
+
+// 内部备注的对象类型
+const (
+	AdminNoteTargetOrder        = "order"
+	AdminNoteTargetSubscription = "subscription"
+)
+
+// AdminNote 管理员对订单/订阅的内部备注(客服上下文、争议细节等), 只在管理后台返回, 不对用户展示
+type AdminNote struct {
+	IdModel
+	TargetType string `json:"target_type" gorm:"size:16;not null;index:idx_admin_note_target"`
+	TargetId   uint   `json:"target_id" gorm:"not null;index:idx_admin_note_target"`
+	AdminId    uint   `json:"admin_id" gorm:"not null"` // 撰写备注的管理员
+	Content    string `json:"content" gorm:"type:text"`
+	Admin      *User  `json:"admin,omitempty" gorm:"foreignKey:AdminId"`
+	TimeModel
+}
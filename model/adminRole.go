@@ -0,0 +1,50 @@
+package model
+
+// 管理员角色, 为空表示超级管理员(兼容升级前创建的管理员)
+const (
+	AdminRoleSuper   = "super_admin"
+	AdminRoleFinance = "finance"
+	AdminRoleSupport = "support"
+)
+
+// 后台权限
+const (
+	PermSystem            = "system"               // 用户、设备、OAuth、系统设置等其余后台功能
+	PermUserView          = "user.view"            // 查看用户列表和详情
+	PermOrderView         = "order.view"           // 查看套餐、订单、订阅、兑换码、付款凭证和支付报表
+	PermOrderManage       = "order.manage"         // 关闭订单、标记已支付、延长保留、审核付款凭证
+	PermRefundRequest     = "order.refund_request" // 提交退款申请
+	PermOrderRefund       = "order.refund"         // 审批退款申请(批准后退款)
+	PermSubscriptionGrant = "subscription.grant"   // 赠送和取消订阅
+	PermPlanManage        = "plan.manage"          // 管理套餐和兑换码
+	PermPaymentConfig     = "payment.config"       // 支付配置(含完整密钥)和一致性修复
+)
+
+// AdminRolePermissions 各角色的权限, * 表示全部
+var AdminRolePermissions = map[string][]string{
+	AdminRoleSuper: {"*"},
+	AdminRoleFinance: {
+		PermUserView, PermOrderView, PermOrderManage, PermRefundRequest, PermOrderRefund, PermPlanManage, PermPaymentConfig,
+	},
+	AdminRoleSupport: {
+		PermUserView, PermOrderView, PermRefundRequest, PermSubscriptionGrant,
+	},
+}
+
+// NormalizeAdminRole 空角色视为超级管理员
+func NormalizeAdminRole(role string) string {
+	if role == "" {
+		return AdminRoleSuper
+	}
+	return role
+}
+
+// AdminRoleAllows 角色是否拥有权限, 未知角色没有任何权限
+func AdminRoleAllows(role, perm string) bool {
+	for _, p := range AdminRolePermissions[NormalizeAdminRole(role)] {
+		if p == "*" || p == perm {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,26 @@
+package model
+
+import "testing"
+
+func TestAdminRoleAllows(t *testing.T) {
+	cases := []struct {
+		role, perm string
+		want       bool
+	}{
+		{"", PermPaymentConfig, true}, // 升级前的管理员
+		{AdminRoleSuper, PermSystem, true},
+		{AdminRoleFinance, PermOrderRefund, true},
+		{AdminRoleFinance, PermSystem, false},
+		{AdminRoleSupport, PermOrderView, true},
+		{AdminRoleSupport, PermSubscriptionGrant, true},
+		{AdminRoleSupport, PermRefundRequest, true},
+		{AdminRoleSupport, PermOrderRefund, false},
+		{AdminRoleSupport, PermPaymentConfig, false},
+		{"unknown", PermOrderView, false},
+	}
+	for _, c := range cases {
+		if got := AdminRoleAllows(c.role, c.perm); got != c.want {
+			t.Errorf("AdminRoleAllows(%q, %q) = %v, want %v", c.role, c.perm, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package model
+
+import "strings"
+
+// 个人访问令牌的权限范围
+const (
+	ApiTokenScopeReadPeers        = "read:peers"        // 读取设备、用户组和地址簿
+	ApiTokenScopeWriteAddressBook = "write:addressbook" // 修改地址簿
+	ApiTokenScopeReadBilling      = "read:billing"      // 读取订阅、订单和套餐
+)
+
+var ApiTokenScopes = []string{ApiTokenScopeReadPeers, ApiTokenScopeWriteAddressBook, ApiTokenScopeReadBilling}
+
+// ApiToken 用户个人访问令牌, 用于脚本调用接口, 与登录令牌同样通过 Authorization: Bearer 传递
+// 只保存令牌的 sha256, 明文仅在创建时返回一次
+type ApiToken struct {
+	IdModel
+	UserId     uint   `json:"user_id" gorm:"default:0;not null;index"`
+	Name       string `json:"name" gorm:"size:64;default:'';not null"`
+	TokenHash  string `json:"-" gorm:"size:64;not null;uniqueIndex"`
+	Prefix     string `json:"prefix" gorm:"size:16;default:'';not null"` // 明文前缀, 便于识别
+	Scopes     string `json:"scopes" gorm:"default:'';not null"`         // 逗号分隔
+	ExpireAt   int64  `json:"expire_at" gorm:"default:0;not null"`       // 过期时间, 0 表示不过期
+	LastUsedAt int64  `json:"last_used_at" gorm:"default:0;not null"`
+	LastUsedIp string `json:"last_used_ip" gorm:"size:64;default:'';not null"`
+	TimeModel
+}
+
+type ApiTokenList struct {
+	ApiTokens []*ApiToken `json:"list"`
+	Pagination
+}
+
+// NormalizeApiTokenScopes 去重并校验权限范围, 返回未知的范围
+func NormalizeApiTokenScopes(scopes []string) ([]string, string) {
+	res := make([]string, 0, len(scopes))
+	for _, s := range scopes {
+		s = strings.ToLower(strings.TrimSpace(s))
+		known := false
+		for _, k := range ApiTokenScopes {
+			if s == k {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return nil, s
+		}
+		dup := false
+		for _, r := range res {
+			if r == s {
+				dup = true
+				break
+			}
+		}
+		if !dup {
+			res = append(res, s)
+		}
+	}
+	return res, ""
+}
+
+func (t *ApiToken) ScopeList() []string {
+	res := make([]string, 0)
+	for _, s := range strings.Split(t.Scopes, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			res = append(res, s)
+		}
+	}
+	return res
+}
+
+func (t *ApiToken) HasScope(scope string) bool {
+	for _, s := range t.ScopeList() {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// Expired 是否已过期
+func (t *ApiToken) Expired(now int64) bool {
+	return t.ExpireAt > 0 && t.ExpireAt <= now
+}
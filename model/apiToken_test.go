@@ -0,0 +1,26 @@
+package model
+
+import "testing"
+
+func TestNormalizeApiTokenScopes(t *testing.T) {
+	scopes, unknown := NormalizeApiTokenScopes([]string{" Read:Peers", "read:billing", "read:peers"})
+	if unknown != "" || len(scopes) != 2 || scopes[0] != ApiTokenScopeReadPeers || scopes[1] != ApiTokenScopeReadBilling {
+		t.Fatalf("scopes = %v unknown = %q", scopes, unknown)
+	}
+	if _, unknown := NormalizeApiTokenScopes([]string{"read:peers", "admin"}); unknown != "admin" {
+		t.Fatalf("unknown = %q", unknown)
+	}
+}
+
+func TestApiTokenScope(t *testing.T) {
+	at := &ApiToken{Scopes: "read:peers, write:addressbook", ExpireAt: 100}
+	if !at.HasScope(ApiTokenScopeWriteAddressBook) || at.HasScope(ApiTokenScopeReadBilling) {
+		t.Fatalf("scopes = %v", at.ScopeList())
+	}
+	if at.Expired(99) || !at.Expired(100) {
+		t.Fatal("expire check failed")
+	}
+	if (&ApiToken{}).Expired(1 << 40) {
+		t.Fatal("zero expire_at should never expire")
+	}
+}
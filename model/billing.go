@@ -0,0 +1,32 @@
+package model
+
+// UserBilling 用户计费概览(管理后台客户页), 汇总订阅、权益、订单、退款和风险标记
+type UserBilling struct {
+	User           *User             `json:"user"`
+	Subscription   *UserSubscription `json:"subscription"` // 没有订阅时为空
+	Active         bool              `json:"active"`
+	Entitlements   []*Entitlement    `json:"entitlements"`
+	RelayTier      *RelayTier        `json:"relay_tier"`
+	RecentOrders   []*Order          `json:"recent_orders"`
+	Refunds        []*Refund         `json:"refunds"`
+	RefundRequests []*RefundRequest  `json:"refund_requests"`
+	Totals         UserBillingTotals `json:"totals"`
+	Flags          UserBillingFlags  `json:"flags"`
+}
+
+// UserBillingTotals 累计金额(分), 按币种汇总
+type UserBillingTotals struct {
+	PaidOrders int64            `json:"paid_orders"`
+	Paid       map[string]int64 `json:"paid"`
+	Refunded   map[string]int64 `json:"refunded"`
+}
+
+// UserBillingFlags 风险和状态标记
+type UserBillingFlags struct {
+	Banned                bool  `json:"banned"` // 账号已禁用
+	EmailVerified         bool  `json:"email_verified"`
+	PendingRefundRequests int64 `json:"pending_refund_requests"`
+	RelayIncidents        int64 `json:"relay_incidents"`        // relay 异常记录数
+	LastRelayIncidentAt   int64 `json:"last_relay_incident_at"` // 最近一次 relay 异常时间
+	FlaggedExtensions     int64 `json:"flagged_extensions"`     // 续期防护放行但标记的异常次数
+}
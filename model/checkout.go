@@ -0,0 +1,51 @@
+package model
+
+// CheckoutPreview 下单前预览: 实付金额、首购赠送和支付后的预计到期时间
+type CheckoutPreview struct {
+	Plan            *SubscriptionPlan `json:"plan"`
+	PayMethod       string            `json:"pay_method"`
+	Currency        string            `json:"currency"`
+	Amount          int64             `json:"amount"` // 最小货币单位
+	AmountDisplay   string            `json:"amount_display"`
+	BonusDays       int               `json:"bonus_days"`        // 首购赠送天数, 不符合条件时为 0
+	CurrentExpireAt int64             `json:"current_expire_at"` // 当前订阅到期时间, 无有效订阅时为 0
+	ExpireAt        int64             `json:"expire_at"`         // 支付成功后的预计到期时间
+
+	// 税费(payment.tax 启用时), Amount 为含税总额
+	TaxCountry   string  `json:"tax_country,omitempty"`
+	TaxRate      float64 `json:"tax_rate"`
+	TaxAmount    int64   `json:"tax_amount"`
+	TaxInclusive bool    `json:"tax_inclusive"`
+}
+
+// QRCodePayment 扫码支付信息, 客户端将 QRCode 渲染为二维码后轮询订单状态
+type QRCodePayment struct {
+	OutTradeNo    string `json:"out_trade_no"` // 可能因重新下单而与请求中的订单号不同, 轮询时使用该值
+	PayType       string `json:"pay_type"`
+	QRCode        string `json:"qrcode"`
+	Amount        int64  `json:"amount"`
+	Currency      string `json:"currency"`
+	AmountDisplay string `json:"amount_display"`
+	ExpireAt      int64  `json:"expire_at"` // 订单自动关闭时间
+}
+
+// OrderPayStatus 订单支付状态(供扫码支付轮询)
+type OrderPayStatus struct {
+	OutTradeNo string `json:"out_trade_no"`
+	Status     int    `json:"status"`
+	Paid       bool   `json:"paid"`
+	PaidAt     int64  `json:"paid_at"`
+}
+
+// FunnelStat 某套餐/支付方式的下单转化漏斗(进程启动以来的计数)
+type FunnelStat struct {
+	PlanId         uint    `json:"plan_id"`
+	PlanName       string  `json:"plan_name"`
+	Provider       string  `json:"provider"`   // online/manual/free
+	PlanViews      float64 `json:"plan_views"` // 套餐列表展示次数, 与支付方式无关
+	Previews       float64 `json:"previews"`
+	OrdersCreated  float64 `json:"orders_created"`
+	PaySubmits     float64 `json:"pay_submits"`
+	NotifyReceived float64 `json:"notify_received"`
+	Paid           float64 `json:"paid"`
+}
@@ -0,0 +1,14 @@
+package model
+
+// ConnPermission 用户默认连接权限预设
+// 用户名下设备在被控时默认授予来访连接的权限，由被控端通过内部接口获取并执行
+type ConnPermission struct {
+	IdModel
+	UserId              uint `json:"user_id" gorm:"default:0;not null;uniqueIndex"`
+	ViewOnly            bool `json:"view_only" gorm:"default:0;not null;"`             // 仅查看(禁止键鼠控制)
+	DisableFileTransfer bool `json:"disable_file_transfer" gorm:"default:0;not null;"` // 禁止文件传输
+	DisableClipboard    bool `json:"disable_clipboard" gorm:"default:0;not null;"`     // 禁止剪贴板
+	DisableAudio        bool `json:"disable_audio" gorm:"default:0;not null;"`         // 禁止音频
+	DisableTerminal     bool `json:"disable_terminal" gorm:"default:0;not null;"`      // 禁止终端
+	TimeModel
+}
@@ -0,0 +1,37 @@
+package model
+
+// 连接方式
+const (
+	ConnectionModeRelay  = "relay"  // 经 hbbr 中继
+	ConnectionModeDirect = "direct" // 直连(仅客户端上报, 未经 relay)
+)
+
+// ConnectionLog 远程连接审计记录, 只追加不提供删除
+// 由 hbbr 的 relay 会话上报和被控端的连接审计上报汇总, 同一次 relay 连接的两类上报合并为一条
+type ConnectionLog struct {
+	IdModel
+	RefKey       string `json:"ref_key" gorm:"size:191;not null;uniqueIndex"`       // relay:<uuid> 或 conn:<被控端ID>:<conn_id>
+	ConnKey      string `json:"conn_key" gorm:"size:191;default:'';not null;index"` // 合并到 relay 记录的客户端上报
+	Mode         string `json:"mode" gorm:"size:16;default:'';not null;index"`
+	PeerId       string `json:"peer_id" gorm:"size:100;default:'';not null;index"`        // 发起端设备ID
+	UserId       uint   `json:"user_id" gorm:"default:0;not null;index"`                  // 发起端设备归属用户
+	TargetPeerId string `json:"target_peer_id" gorm:"size:100;default:'';not null;index"` // 被控端设备ID
+	TargetUserId uint   `json:"target_user_id" gorm:"default:0;not null;index"`           // 被控端设备归属用户
+	Ip           string `json:"ip" gorm:"size:64;default:'';not null"`                    // 客户端上报的发起端IP
+	ConnType     int    `json:"conn_type" gorm:"default:0;not null"`                      // 客户端上报的连接类型
+	StartedAt    int64  `json:"started_at" gorm:"default:0;not null;index"`
+	EndedAt      int64  `json:"ended_at" gorm:"default:0;not null"`
+	Duration     int64  `json:"duration" gorm:"default:0;not null"`   // 时长(秒)
+	BytesUp      int64  `json:"bytes_up" gorm:"default:0;not null"`   // 发起端 -> 被控端, 仅 relay 上报
+	BytesDown    int64  `json:"bytes_down" gorm:"default:0;not null"` // 被控端 -> 发起端, 仅 relay 上报
+
+	// 发起端地理位置, 按上报IP查询, 无IP时取发起设备最近的位置
+	Country string `json:"country" gorm:"size:8;default:'';not null;index"`
+	City    string `json:"city" gorm:"size:100;default:'';not null"`
+	TimeModel
+}
+
+type ConnectionLogList struct {
+	ConnectionLogs []*ConnectionLog `json:"list"`
+	Pagination
+}
@@ -2,12 +2,24 @@ package custom_types
 
 import (
 	"database/sql/driver"
+	"fmt"
 	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
 )
 
 // AutoTime 自定义时间格式
 type AutoTime time.Time
 
+// autoTimeLayouts 驱动以字符串返回时间时(如 sqlite)尝试的格式
+var autoTimeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.999999999-07:00",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02 15:04:05",
+}
+
 func (mt AutoTime) Value() (driver.Value, error) {
 	var zeroTime time.Time
 	t := time.Time(mt)
@@ -17,6 +29,42 @@ func (mt AutoTime) Value() (driver.Value, error) {
 	return t, nil
 }
 
+// Scan 兼容各驱动返回的时间类型: time.Time(mysql/postgresql) 或字符串(sqlite)
+func (mt *AutoTime) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*mt = AutoTime(time.Time{})
+		return nil
+	case time.Time:
+		*mt = AutoTime(v)
+		return nil
+	case []byte:
+		return mt.parse(string(v))
+	case string:
+		return mt.parse(v)
+	}
+	return fmt.Errorf("failed to scan AutoTime value: %v", value)
+}
+
+func (mt *AutoTime) parse(s string) error {
+	for _, layout := range autoTimeLayouts {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			*mt = AutoTime(t)
+			return nil
+		}
+	}
+	return fmt.Errorf("failed to parse AutoTime value: %s", s)
+}
+
+// GormDBDataType PostgreSQL 使用 timestamptz, 避免 timestamp(无时区)读回时被当作 UTC 导致时间偏移
+// 其他数据库返回空, 沿用字段 tag 中的 type
+func (AutoTime) GormDBDataType(db *gorm.DB, field *schema.Field) string {
+	if db.Dialector.Name() == "postgres" {
+		return "timestamptz"
+	}
+	return ""
+}
+
 func (mt AutoTime) MarshalJSON() ([]byte, error) {
 	//b := make([]byte, 0, len("2006-01-02 15:04:05")+2)
 	b := time.Time(mt).AppendFormat([]byte{}, "\"2006-01-02 15:04:05\"")
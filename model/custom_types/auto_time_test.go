@@ -0,0 +1,33 @@
+package custom_types
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAutoTimeScan(t *testing.T) {
+	want := time.Date(2024, 5, 6, 7, 8, 9, 0, time.Local)
+	cases := []interface{}{
+		want,
+		"2024-05-06 07:08:09",
+		[]byte("2024-05-06 07:08:09"),
+		want.Format(time.RFC3339Nano),
+	}
+	for _, v := range cases {
+		var mt AutoTime
+		if err := mt.Scan(v); err != nil {
+			t.Fatalf("Scan(%v) error: %v", v, err)
+		}
+		if !time.Time(mt).Equal(want) {
+			t.Errorf("Scan(%v) = %v, want %v", v, time.Time(mt), want)
+		}
+	}
+
+	var mt AutoTime
+	if err := mt.Scan(nil); err != nil || !time.Time(mt).IsZero() {
+		t.Errorf("Scan(nil) = %v, %v", time.Time(mt), err)
+	}
+	if err := mt.Scan(123); err == nil {
+		t.Error("Scan(int) should fail")
+	}
+}
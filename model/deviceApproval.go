@@ -0,0 +1,26 @@
+package model
+
+// 超出设备数的绑定审批状态
+const (
+	DeviceApprovalPending  = 0 // 待审批
+	DeviceApprovalApproved = 1 // 已批准, 下次登录时绑定
+	DeviceApprovalRejected = 2 // 已驳回
+)
+
+// DeviceApproval 用户绑定设备超出套餐设备数时的审批申请, 同一用户同一设备只保留一条
+type DeviceApproval struct {
+	IdModel
+	UserId     uint   `json:"user_id" gorm:"uniqueIndex:idx_device_approval;not null"`
+	Uuid       string `json:"uuid" gorm:"uniqueIndex:idx_device_approval;size:128;not null"`
+	PeerId     string `json:"peer_id" gorm:"size:100;default:''"` // 设备ID
+	Status     int    `json:"status" gorm:"default:0;not null;index"`
+	ReviewerId uint   `json:"reviewer_id" gorm:"default:0;not null"`
+	ReviewedAt int64  `json:"reviewed_at" gorm:"default:0;not null"`
+	User       *User  `json:"user,omitempty" gorm:"foreignKey:UserId"`
+	TimeModel
+}
+
+type DeviceApprovalList struct {
+	DeviceApprovals []*DeviceApproval `json:"list"`
+	Pagination
+}
@@ -0,0 +1,18 @@
+package model
+
+// DeviceSeat 按设备席位授权的套餐中分配到席位的设备
+type DeviceSeat struct {
+	IdModel
+	UserId uint   `json:"user_id" gorm:"uniqueIndex:idx_device_seat;not null"`
+	Uuid   string `json:"uuid" gorm:"uniqueIndex:idx_device_seat;size:128;not null"`
+	PeerId string `json:"peer_id" gorm:"size:100;default:''"` // 分配时的设备ID
+	Peer   *Peer  `json:"peer,omitempty" gorm:"-"`
+	TimeModel
+}
+
+// DeviceSeatSummary 用户的设备席位
+type DeviceSeatSummary struct {
+	Enabled bool          `json:"enabled"` // 当前套餐是否按设备席位授权
+	Limit   int           `json:"limit"`
+	Seats   []*DeviceSeat `json:"seats"`
+}
@@ -0,0 +1,41 @@
+package model
+
+// 邮件类型
+const (
+	EmailKindTest           = "test"
+	EmailKindOrderPaid      = "order_paid"
+	EmailKindRefund         = "refund"
+	EmailKindExpiryReminder = "expiry_reminder"
+	EmailKindLapsedReminder = "lapsed_reminder"
+	EmailKindRelayAbuse     = "relay_abuse"
+	EmailKindVerify         = "verify"
+	EmailKindAnomaly        = "subscription_anomaly"
+	EmailKindGatewayRefund  = "gateway_refund"
+)
+
+// 发送状态
+const (
+	EmailStatusPending = 0 // 待发送
+	EmailStatusSent    = 1 // 已发送
+	EmailStatusFailed  = 2 // 发送失败
+)
+
+// EmailLog 邮件发送记录, RefKey 唯一, 用于防止同一事件重复发送(如到期提醒)
+type EmailLog struct {
+	IdModel
+	UserId   uint   `json:"user_id" gorm:"default:0;not null;index"`
+	Kind     string `json:"kind" gorm:"size:32;default:'';not null;index"`
+	RefKey   string `json:"ref_key" gorm:"size:128;not null;uniqueIndex"`
+	To       string `json:"to" gorm:"default:'';not null;"`
+	Subject  string `json:"subject" gorm:"default:'';not null;"`
+	Body     string `json:"body" gorm:"type:text"`
+	Status   int    `json:"status" gorm:"default:0;not null;index"`
+	Error    string `json:"error" gorm:"type:text"`
+	ResendOf uint   `json:"resend_of" gorm:"default:0;not null"` // 管理员重新发送时为原记录ID
+	TimeModel
+}
+
+type EmailLogList struct {
+	EmailLogs []*EmailLog `json:"list"`
+	Pagination
+}
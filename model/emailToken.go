@@ -0,0 +1,23 @@
+package model
+
+// 邮箱令牌用途
+const (
+	EmailTokenVerify    = "verify"     // 验证当前邮箱
+	EmailTokenChangeOld = "change_old" // 更换邮箱: 旧邮箱确认
+	EmailTokenChangeNew = "change_new" // 更换邮箱: 新邮箱确认
+)
+
+// EmailToken 邮箱验证/更换确认令牌, 只保存令牌的哈希
+// 更换邮箱时旧邮箱和新邮箱各一个令牌, 通过 RequestId 关联, 两个都确认后才生效
+type EmailToken struct {
+	IdModel
+	UserId    uint   `json:"user_id" gorm:"default:0;not null;index"`
+	Purpose   string `json:"purpose" gorm:"size:16;default:'';not null"`
+	Email     string `json:"email" gorm:"default:'';not null"`     // 令牌发送到的地址
+	NewEmail  string `json:"new_email" gorm:"default:'';not null"` // 更换后的邮箱
+	RequestId string `json:"request_id" gorm:"size:36;default:'';not null;index"`
+	TokenHash string `json:"-" gorm:"size:64;not null;uniqueIndex"`
+	ExpireAt  int64  `json:"expire_at" gorm:"default:0;not null"`
+	UsedAt    int64  `json:"used_at" gorm:"default:0;not null"`
+	TimeModel
+}
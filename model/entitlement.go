@@ -0,0 +1,74 @@
+package model
+
+// 权益项
+const (
+	EntitlementDevices      = "devices"       // 绑定设备数
+	EntitlementRelayBytes   = "relay_bytes"   // 每月 relay 流量(字节)
+	EntitlementRelayMinutes = "relay_minutes" // 每月 relay 时长(分钟)
+	EntitlementSessions     = "sessions"      // 并发会话数
+)
+
+// Entitlement 用户某项权益的限额和用量, 仅包含有限额的项
+type Entitlement struct {
+	Name      string `json:"name"`
+	Limit     int64  `json:"limit"`
+	Used      int64  `json:"used"`
+	Remaining int64  `json:"remaining"`
+	Reset     int64  `json:"reset"` // 用量重置时间(unix 秒), 0 表示不重置
+}
+
+// UsedPercent 已用百分比
+func (e *Entitlement) UsedPercent() int64 {
+	if e.Limit <= 0 {
+		return 0
+	}
+	return e.Used * 100 / e.Limit
+}
+
+// relay 等级, 由 hbbs/hbbr 按等级分配 relay 服务器
+const (
+	RelayTierStandard = "standard"
+	RelayTierPremium  = "premium"
+)
+
+// RelayTier 用户当前的 relay 等级
+type RelayTier struct {
+	Tier        string `json:"tier"`
+	Trial       bool   `json:"trial"`                   // 试用中
+	TrialEndsAt int64  `json:"trial_ends_at,omitempty"` // 试用结束时间(unix 秒)
+	TrialEnded  bool   `json:"trial_ended"`             // 试用已结束, 已回落到普通 relay
+}
+
+// ResolveRelayTier 计算 relay 等级: 有效订阅的套餐开启高级 relay 时为 premium;
+// 免费套餐配置了试用天数时, 注册后该天数内为 premium, 之后回落到 standard
+func ResolveRelayTier(plan *SubscriptionPlan, active bool, signupAt, now int64) *RelayTier {
+	t := &RelayTier{Tier: RelayTierStandard}
+	if plan == nil || !active {
+		return t
+	}
+	if plan.PremiumRelay {
+		t.Tier = RelayTierPremium
+		return t
+	}
+	if plan.Price == 0 && plan.PremiumRelayTrialDays > 0 && signupAt > 0 {
+		t.TrialEndsAt = signupAt + int64(plan.PremiumRelayTrialDays)*86400
+		if now < t.TrialEndsAt {
+			t.Tier, t.Trial = RelayTierPremium, true
+		} else {
+			t.TrialEnded = true
+		}
+	}
+	return t
+}
+
+// QuotaWarning 接近或达到限额的提醒
+type QuotaWarning struct {
+	Name      string `json:"name"`
+	Limit     int64  `json:"limit"`
+	Used      int64  `json:"used"`
+	Remaining int64  `json:"remaining"`
+	Reset     int64  `json:"reset"`
+	Percent   int64  `json:"percent"`
+	Exceeded  bool   `json:"exceeded"`
+	Message   string `json:"message"`
+}
@@ -0,0 +1,32 @@
+package model
+
+import "testing"
+
+func TestResolveRelayTier(t *testing.T) {
+	day := int64(86400)
+	signup := int64(1_700_000_000)
+	free := &SubscriptionPlan{Price: 0, PremiumRelayTrialDays: 7}
+	paid := &SubscriptionPlan{Price: 1000, PremiumRelay: true}
+	cases := []struct {
+		name   string
+		plan   *SubscriptionPlan
+		active bool
+		now    int64
+		tier   string
+		trial  bool
+		ended  bool
+	}{
+		{"no plan", nil, true, signup, RelayTierStandard, false, false},
+		{"inactive", paid, false, signup, RelayTierStandard, false, false},
+		{"premium plan", paid, true, signup, RelayTierPremium, false, false},
+		{"in trial", free, true, signup + 6*day, RelayTierPremium, true, false},
+		{"trial ended", free, true, signup + 7*day, RelayTierStandard, false, true},
+		{"paid plan without trial", &SubscriptionPlan{Price: 1000, PremiumRelayTrialDays: 7}, true, signup, RelayTierStandard, false, false},
+	}
+	for _, c := range cases {
+		got := ResolveRelayTier(c.plan, c.active, signup, c.now)
+		if got.Tier != c.tier || got.Trial != c.trial || got.TrialEnded != c.ended {
+			t.Errorf("%s: got %+v", c.name, got)
+		}
+	}
+}
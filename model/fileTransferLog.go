@@ -0,0 +1,32 @@
+package model
+
+// 文件传输方向
+const (
+	FileTransferUpload   = "upload"   // 发起端 -> 被控端
+	FileTransferDownload = "download" // 被控端 -> 发起端
+)
+
+// FileTransferLog 文件传输审计记录, 只追加不提供删除
+// 由内部接口上报, 仅在发起端或被控端归属用户的策略开启文件传输审计时记录
+type FileTransferLog struct {
+	IdModel
+	PeerId        string `json:"peer_id" gorm:"size:100;default:'';not null;index"`        // 发起端设备ID
+	UserId        uint   `json:"user_id" gorm:"default:0;not null;index"`                  // 发起端设备归属用户
+	TargetPeerId  string `json:"target_peer_id" gorm:"size:100;default:'';not null;index"` // 被控端设备ID
+	TargetUserId  uint   `json:"target_user_id" gorm:"default:0;not null;index"`           // 被控端设备归属用户
+	Direction     string `json:"direction" gorm:"size:16;default:'';not null;index"`       // upload/download
+	FileName      string `json:"file_name" gorm:"size:255;default:'';not null;index"`
+	Path          string `json:"path" gorm:"size:1024;default:'';not null"`
+	Size          int64  `json:"size" gorm:"default:0;not null"`    // 字节
+	IsDir         bool   `json:"is_dir" gorm:"default:0;not null;"` // 传输的是目录
+	Ip            string `json:"ip" gorm:"size:64;default:'';not null"`
+	Country       string `json:"country" gorm:"size:8;default:'';not null;index"`
+	City          string `json:"city" gorm:"size:100;default:'';not null"`
+	TransferredAt int64  `json:"transferred_at" gorm:"default:0;not null;index"` // unix 秒
+	TimeModel
+}
+
+type FileTransferLogList struct {
+	FileTransferLogs []*FileTransferLog `json:"list"`
+	Pagination
+}
@@ -0,0 +1,20 @@
+package model
+
+// InternalKey 数据库管理的内部接口密钥, 与环境变量 RUSTDESK_API_INTERNAL_KEY(S) 中的密钥同时生效
+// 只保存密钥的 sha256, 明文仅在创建时返回一次
+type InternalKey struct {
+	IdModel
+	Name       string     `json:"name" gorm:"size:64;not null;uniqueIndex"` // 密钥ID, 记录在访问日志中
+	KeyHash    string     `json:"-" gorm:"size:64;not null;uniqueIndex"`
+	Prefix     string     `json:"prefix" gorm:"size:16;default:'';not null"` // 明文前缀, 便于识别
+	Status     StatusCode `json:"status" gorm:"default:1;not null;index"`    // 1启用 2禁用
+	ExpireAt   int64      `json:"expire_at" gorm:"default:0;not null"`       // 过期时间, 0 表示不过期
+	LastUsedAt int64      `json:"last_used_at" gorm:"default:0;not null"`
+	LastUsedIp string     `json:"last_used_ip" gorm:"size:64;default:'';not null"`
+	TimeModel
+}
+
+type InternalKeyList struct {
+	InternalKeys []*InternalKey `json:"list"`
+	Pagination
+}
@@ -0,0 +1,29 @@
+package model
+
+// 维护期间订阅校验方式
+const (
+	MaintenanceModeWarn   = "warn"   // 放行并记录/提示, 不拒绝
+	MaintenanceModeBypass = "bypass" // 直接放行, 不提示
+)
+
+// MaintenanceWindow 计费系统维护窗口, 期间放宽订阅和 relay 配额校验, 避免付费用户被误拒
+type MaintenanceWindow struct {
+	IdModel
+	Title   string     `json:"title" gorm:"size:128;default:'';not null"`
+	Reason  string     `json:"reason" gorm:"type:text"`
+	StartAt int64      `json:"start_at" gorm:"default:0;not null;index"`
+	EndAt   int64      `json:"end_at" gorm:"default:0;not null;index"`
+	Mode    string     `json:"mode" gorm:"size:16;default:'warn';not null"`
+	Status  StatusCode `json:"status" gorm:"default:1;not null;index"` // 1启用 2禁用
+	TimeModel
+}
+
+type MaintenanceWindowList struct {
+	MaintenanceWindows []*MaintenanceWindow `json:"list"`
+	Pagination
+}
+
+// ActiveAt 指定时间是否处于该窗口内
+func (m *MaintenanceWindow) ActiveAt(ts int64) bool {
+	return m.Status == COMMON_STATUS_ENABLE && m.StartAt <= ts && ts < m.EndAt
+}
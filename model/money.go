@@ -0,0 +1,223 @@
+package model
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var ErrCurrencyMismatch = errors.New("currency mismatch")
+
+// Money 金额值类型: 最小货币单位(如分) + 币种, 避免元/分混用
+// 订单和套餐仍以 int64 分存储, 通过 Total/PriceIn 等方法得到 Money
+type Money struct {
+	Amount   int64  // 最小货币单位
+	Currency string // 币种, 空则为基础币种
+}
+
+// NewMoney 由最小货币单位创建金额
+func NewMoney(amount int64, currency string) Money {
+	if currency == "" {
+		currency = SupportedCurrencies[0]
+	}
+	return Money{Amount: amount, Currency: strings.ToUpper(currency)}
+}
+
+// ParseMoney 解析主单位金额字符串(如 "12.34"), 字符串严格解析, 避免浮点精度问题
+func ParseMoney(s, currency string) (Money, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return Money{}, errors.New("invalid money")
+	}
+	// 去除正号
+	s = strings.TrimPrefix(s, "+")
+	// 不支持负数
+	if strings.HasPrefix(s, "-") {
+		return Money{}, errors.New("invalid money: negative")
+	}
+
+	parts := strings.SplitN(s, ".", 3)
+	if len(parts) > 2 {
+		return Money{}, errors.New("invalid money format")
+	}
+
+	intPart := parts[0]
+	fracPart := ""
+	if len(parts) == 2 {
+		fracPart = parts[1]
+	}
+	if intPart == "" {
+		intPart = "0"
+	}
+
+	// 处理小数部分
+	switch len(fracPart) {
+	case 0:
+		fracPart = "00"
+	case 1:
+		fracPart += "0"
+	case 2:
+		// OK
+	default:
+		return Money{}, errors.New("invalid money: too many decimal places")
+	}
+
+	// 验证是否全为数字
+	if !isAllDigits(intPart) || !isAllDigits(fracPart) {
+		return Money{}, errors.New("invalid money: non-digit characters")
+	}
+
+	whole, err := strconv.ParseInt(intPart, 10, 64)
+	if err != nil || whole < 0 {
+		return Money{}, errors.New("invalid money: integer part")
+	}
+	cents, err := strconv.ParseInt(fracPart, 10, 64)
+	if err != nil || cents < 0 || cents > 99 {
+		return Money{}, errors.New("invalid money: decimal part")
+	}
+
+	// 溢出检查
+	const maxInt64 = int64(^uint64(0) >> 1)
+	if whole > (maxInt64-cents)/100 {
+		return Money{}, errors.New("invalid money: overflow")
+	}
+	return NewMoney(whole*100+cents, currency), nil
+}
+
+// String 主单位金额字符串, 如 "12.34", 用于网关参数和对账
+func (m Money) String() string {
+	amount := m.Amount
+	sign := ""
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, amount/100, amount%100)
+}
+
+// Display 带币种的展示字符串, 如 "12.34 CNY"
+func (m Money) Display() string {
+	return m.String() + " " + m.Currency
+}
+
+func (m Money) IsZero() bool {
+	return m.Amount == 0
+}
+
+func (m Money) IsNegative() bool {
+	return m.Amount < 0
+}
+
+// SameCurrency 币种是否一致(空币种视为基础币种)
+func (m Money) SameCurrency(o Money) bool {
+	return NewMoney(0, m.Currency).Currency == NewMoney(0, o.Currency).Currency
+}
+
+// Equal 金额和币种均相同
+func (m Money) Equal(o Money) bool {
+	return m.Amount == o.Amount && m.SameCurrency(o)
+}
+
+// Add 相加, 币种不一致时返回错误
+func (m Money) Add(o Money) (Money, error) {
+	if !m.SameCurrency(o) {
+		return Money{}, ErrCurrencyMismatch
+	}
+	return NewMoney(m.Amount+o.Amount, m.Currency), nil
+}
+
+// Sub 相减, 币种不一致时返回错误
+func (m Money) Sub(o Money) (Money, error) {
+	if !m.SameCurrency(o) {
+		return Money{}, ErrCurrencyMismatch
+	}
+	return NewMoney(m.Amount-o.Amount, m.Currency), nil
+}
+
+// Cmp 比较大小: -1 小于, 0 等于, 1 大于; 币种不一致时返回错误
+func (m Money) Cmp(o Money) (int, error) {
+	if !m.SameCurrency(o) {
+		return 0, ErrCurrencyMismatch
+	}
+	switch {
+	case m.Amount < o.Amount:
+		return -1, nil
+	case m.Amount > o.Amount:
+		return 1, nil
+	}
+	return 0, nil
+}
+
+// Ratio 返回 n * m / o, 用于按金额比例折算(如部分退款扣减时长), o 为 0 或币种不一致时返回 0
+func (m Money) Ratio(n int64, o Money) int64 {
+	if o.Amount == 0 || !m.SameCurrency(o) {
+		return 0
+	}
+	return n * m.Amount / o.Amount
+}
+
+type moneyJSON struct {
+	Amount   int64  `json:"amount"`
+	Currency string `json:"currency"`
+	Display  string `json:"display,omitempty"`
+}
+
+func (m Money) MarshalJSON() ([]byte, error) {
+	return json.Marshal(moneyJSON{Amount: m.Amount, Currency: m.Currency, Display: m.String()})
+}
+
+func (m *Money) UnmarshalJSON(data []byte) error {
+	var v moneyJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*m = NewMoney(v.Amount, v.Currency)
+	return nil
+}
+
+// Value 以 "CNY 1234"(币种 最小单位) 存储到单列
+func (m Money) Value() (driver.Value, error) {
+	return fmt.Sprintf("%s %d", NewMoney(0, m.Currency).Currency, m.Amount), nil
+}
+
+func (m *Money) Scan(value interface{}) error {
+	var s string
+	switch v := value.(type) {
+	case nil:
+		*m = Money{}
+		return nil
+	case string:
+		s = v
+	case []byte:
+		s = string(v)
+	default:
+		return fmt.Errorf("can not convert %v to Money", value)
+	}
+	parts := strings.Fields(s)
+	if len(parts) != 2 {
+		return fmt.Errorf("invalid money value %q", s)
+	}
+	amount, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid money value %q", s)
+	}
+	*m = NewMoney(amount, parts[0])
+	return nil
+}
+
+// GormDataType 存储为字符串
+func (Money) GormDataType() string {
+	return "string"
+}
+
+func isAllDigits(s string) bool {
+	for _, ch := range s {
+		if ch < '0' || ch > '9' {
+			return false
+		}
+	}
+	return true
+}
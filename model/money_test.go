@@ -0,0 +1,67 @@
+package model
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestParseMoney(t *testing.T) {
+	cases := map[string]int64{
+		"0":      0,
+		"1":      100,
+		"1.5":    150,
+		"12.34":  1234,
+		".05":    5,
+		"+3.00":  300,
+		" 9.99 ": 999,
+	}
+	for s, want := range cases {
+		m, err := ParseMoney(s, CurrencyUSD)
+		if err != nil || m.Amount != want || m.Currency != CurrencyUSD {
+			t.Errorf("ParseMoney(%q) = %+v, %v, want %d", s, m, err, want)
+		}
+	}
+	for _, s := range []string{"", "-1", "1.234", "1.2.3", "abc", "1e3", "99999999999999999999"} {
+		if _, err := ParseMoney(s, ""); err == nil {
+			t.Errorf("ParseMoney(%q) expected error", s)
+		}
+	}
+}
+
+func TestMoneyArithmetic(t *testing.T) {
+	a := NewMoney(1000, "")
+	b := NewMoney(250, CurrencyCNY)
+	if sum, err := a.Add(b); err != nil || sum.Amount != 1250 {
+		t.Errorf("Add = %+v, %v", sum, err)
+	}
+	if diff, err := b.Sub(a); err != nil || diff.String() != "-7.50" {
+		t.Errorf("Sub = %v, %v", diff, err)
+	}
+	if _, err := a.Add(NewMoney(1, CurrencyUSD)); err != ErrCurrencyMismatch {
+		t.Errorf("Add mismatched currency err = %v", err)
+	}
+	if cmp, err := b.Cmp(a); err != nil || cmp != -1 {
+		t.Errorf("Cmp = %d, %v", cmp, err)
+	}
+	if r := b.Ratio(3600, a); r != 900 {
+		t.Errorf("Ratio = %d, want 900", r)
+	}
+}
+
+func TestMoneyMarshal(t *testing.T) {
+	m := NewMoney(1234, CurrencyEUR)
+	data, err := json.Marshal(m)
+	if err != nil || string(data) != `{"amount":1234,"currency":"EUR","display":"12.34"}` {
+		t.Fatalf("MarshalJSON = %s, %v", data, err)
+	}
+	var got Money
+	if err := json.Unmarshal(data, &got); err != nil || !got.Equal(m) {
+		t.Errorf("UnmarshalJSON = %+v, %v", got, err)
+	}
+
+	v, _ := m.Value()
+	var scanned Money
+	if err := scanned.Scan([]byte(v.(string))); err != nil || !scanned.Equal(m) {
+		t.Errorf("Scan(%v) = %+v, %v", v, scanned, err)
+	}
+}
@@ -0,0 +1,41 @@
+package model
+
+// 站内通知类型
+const (
+	NotificationKindPaymentConfirmed     = "payment_confirmed"
+	NotificationKindRefund               = "refund"
+	NotificationKindSubscriptionExpiring = "subscription_expiring"
+	NotificationKindSubscriptionExpired  = "subscription_expired"
+	NotificationKindSubscriptionLapsed   = "subscription_lapsed"
+	NotificationKindAnnouncement         = "announcement"
+	NotificationKindRelayTrialEnded      = "relay_trial_ended"
+	NotificationKindGatewayRefund        = "gateway_refund"
+)
+
+// Notification 站内通知, UserId 为 0 表示面向全部用户的公告
+// 个人通知的已读状态记录在 ReadAt, 公告的已读状态按用户记录在 NotificationRead
+type Notification struct {
+	IdModel
+	UserId     uint   `json:"user_id" gorm:"default:0;not null;index"`
+	Kind       string `json:"kind" gorm:"size:32;default:'';not null;index"`
+	RefKey     string `json:"-" gorm:"size:128;not null;uniqueIndex"` // 防止同一事件重复通知
+	Title      string `json:"title" gorm:"size:255;default:'';not null"`
+	Content    string `json:"content" gorm:"type:text"`
+	ReadAt     int64  `json:"-" gorm:"default:0;not null"`
+	OperatorId uint   `json:"operator_id,omitempty" gorm:"default:0;not null"` // 发布公告的管理员
+	Read       bool   `json:"read" gorm:"-"`
+	TimeModel
+}
+
+type NotificationList struct {
+	Notifications []*Notification `json:"list"`
+	Pagination
+}
+
+// NotificationRead 用户已读的公告
+type NotificationRead struct {
+	IdModel
+	NotificationId uint `json:"notification_id" gorm:"not null;uniqueIndex:idx_notification_user"`
+	UserId         uint `json:"user_id" gorm:"not null;uniqueIndex:idx_notification_user"`
+	TimeModel
+}
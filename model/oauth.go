@@ -1,9 +1,12 @@
 package model
 
 import (
+	"encoding/json"
 	"errors"
 	"strconv"
 	"strings"
+
+	"github.com/lejianwen/rustdesk-api/v2/model/custom_types"
 )
 
 const OIDC_DEFAULT_SCOPES = "openid,profile,email"
@@ -47,9 +50,86 @@ type Oauth struct {
 	Issuer       string `json:"issuer"`
 	PkceEnable   *bool  `json:"pkce_enable"`
 	PkceMethod   string `json:"pkce_method"`
+	// 登录时按 IdP 分组映射用户组和套餐, GroupsClaim 为空或没有映射时不处理
+	GroupsClaim   string                `json:"groups_claim"`                                               // 分组所在的 claim, 支持 realm_access.roles 形式的嵌套路径
+	GroupMappings custom_types.AutoJson `json:"group_mappings" gorm:"type:text" swaggertype:"array,object"` // []OauthGroupMapping
 	TimeModel
 }
 
+// OauthGroupMapping IdP 分组到用户组和套餐的映射, 按顺序取第一个匹配的用户组和第一个匹配的套餐
+type OauthGroupMapping struct {
+	IdpGroup string `json:"idp_group"`
+	GroupId  uint   `json:"group_id"` // 0 为不修改用户组
+	PlanId   uint   `json:"plan_id"`  // 0 为不赠送订阅, 用户已有有效订阅时不赠送
+	Period   string `json:"period"`   // 赠送时长(ISO-8601), 为空取套餐周期
+}
+
+// GroupMappingList 分组映射
+func (oa *Oauth) GroupMappingList() []OauthGroupMapping {
+	var list []OauthGroupMapping
+	if len(oa.GroupMappings) > 0 {
+		_ = json.Unmarshal(oa.GroupMappings, &list)
+	}
+	return list
+}
+
+// SetGroupMappings 设置分组映射
+func (oa *Oauth) SetGroupMappings(list []OauthGroupMapping) {
+	if list == nil {
+		list = []OauthGroupMapping{}
+	}
+	data, _ := json.Marshal(list)
+	oa.GroupMappings = data
+}
+
+// MatchGroupMappings 用户所属分组匹配到的用户组映射和套餐映射, 没有匹配时为 nil
+func MatchGroupMappings(list []OauthGroupMapping, groups []string) (group, plan *OauthGroupMapping) {
+	in := make(map[string]bool, len(groups))
+	for _, g := range groups {
+		in[g] = true
+	}
+	for i := range list {
+		m := &list[i]
+		if !in[m.IdpGroup] {
+			continue
+		}
+		if group == nil && m.GroupId > 0 {
+			group = m
+		}
+		if plan == nil && m.PlanId > 0 {
+			plan = m
+		}
+	}
+	return
+}
+
+// ClaimGroups 从 claims 中按路径取分组, 值可以是字符串数组或以逗号/空格分隔的字符串
+func ClaimGroups(claims map[string]interface{}, path string) []string {
+	if path == "" {
+		return nil
+	}
+	var v interface{} = claims
+	for _, key := range strings.Split(path, ".") {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		v = m[key]
+	}
+	var res []string
+	switch val := v.(type) {
+	case []interface{}:
+		for _, item := range val {
+			if s, ok := item.(string); ok && s != "" {
+				res = append(res, s)
+			}
+		}
+	case string:
+		res = strings.FieldsFunc(val, func(r rune) bool { return r == ',' || r == ' ' })
+	}
+	return res
+}
+
 // Helper function to format oauth info, it's used in the update and create method
 func (oa *Oauth) FormatOauthInfo() error {
 	oauthType := strings.TrimSpace(oa.OauthType)
@@ -83,16 +163,28 @@ func (oa *Oauth) FormatOauthInfo() error {
 	if oa.PkceMethod == "" {
 		oa.PkceMethod = PKCEMethodS256
 	}
+	oa.GroupsClaim = strings.TrimSpace(oa.GroupsClaim)
+	for _, m := range oa.GroupMappingList() {
+		if m.IdpGroup == "" || (m.GroupId == 0 && m.PlanId == 0) {
+			return errors.New("invalid group mapping: idp_group and group_id or plan_id are required")
+		}
+		if m.Period != "" {
+			if _, err := ParsePeriod(m.Period); err != nil {
+				return errors.New("invalid group mapping period: " + m.Period)
+			}
+		}
+	}
 	return nil
 }
 
 type OauthUser struct {
-	OpenId        string `json:"open_id" gorm:"not null;index"`
-	Name          string `json:"name"`
-	Username      string `json:"username"`
-	Email         string `json:"email"`
-	VerifiedEmail bool   `json:"verified_email,omitempty"`
-	Picture       string `json:"picture,omitempty"`
+	OpenId        string   `json:"open_id" gorm:"not null;index"`
+	Name          string   `json:"name"`
+	Username      string   `json:"username"`
+	Email         string   `json:"email"`
+	VerifiedEmail bool     `json:"verified_email,omitempty"`
+	Picture       string   `json:"picture,omitempty"`
+	Groups        []string `json:"groups,omitempty" gorm:"-"` // IdP 分组, 仅 OIDC 配置了 groups_claim 时有值
 }
 
 func (ou *OauthUser) ToUser(user *User, overideUsername bool) {
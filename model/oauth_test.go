@@ -0,0 +1,69 @@
+package model
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestClaimGroups(t *testing.T) {
+	var claims map[string]interface{}
+	_ = json.Unmarshal([]byte(`{"groups":["ops","dev"],"realm_access":{"roles":["admin"]},"scope_groups":"a, b","n":1}`), &claims)
+	cases := []struct {
+		path string
+		want []string
+	}{
+		{"groups", []string{"ops", "dev"}},
+		{"realm_access.roles", []string{"admin"}},
+		{"scope_groups", []string{"a", "b"}},
+		{"n", nil},
+		{"missing.path", nil},
+		{"", nil},
+	}
+	for _, c := range cases {
+		got := ClaimGroups(claims, c.path)
+		if len(got) != len(c.want) {
+			t.Errorf("%s: groups = %v, want %v", c.path, got, c.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != c.want[i] {
+				t.Errorf("%s: groups = %v, want %v", c.path, got, c.want)
+			}
+		}
+	}
+}
+
+func TestMatchGroupMappings(t *testing.T) {
+	list := []OauthGroupMapping{
+		{IdpGroup: "staff", GroupId: 2},
+		{IdpGroup: "admins", GroupId: 3, PlanId: 9},
+		{IdpGroup: "staff", PlanId: 5, Period: "P1Y"},
+	}
+	g, p := MatchGroupMappings(list, []string{"staff"})
+	if g == nil || g.GroupId != 2 || p == nil || p.PlanId != 5 {
+		t.Fatalf("staff = %+v %+v", g, p)
+	}
+	g, p = MatchGroupMappings(list, []string{"staff", "admins"})
+	if g.GroupId != 2 || p.PlanId != 9 {
+		t.Fatalf("first match wins = %+v %+v", g, p)
+	}
+	if g, p = MatchGroupMappings(list, []string{"guest"}); g != nil || p != nil {
+		t.Fatalf("no match = %+v %+v", g, p)
+	}
+}
+
+func TestFormatOauthGroupMappings(t *testing.T) {
+	oa := &Oauth{OauthType: OauthTypeOidc, GroupsClaim: " groups "}
+	oa.SetGroupMappings([]OauthGroupMapping{{IdpGroup: "staff", PlanId: 1, Period: "P1M"}})
+	if err := oa.FormatOauthInfo(); err != nil || oa.GroupsClaim != "groups" {
+		t.Fatalf("valid mapping: %v %q", err, oa.GroupsClaim)
+	}
+	oa.SetGroupMappings([]OauthGroupMapping{{IdpGroup: "staff"}})
+	if err := oa.FormatOauthInfo(); err == nil {
+		t.Fatal("mapping without group or plan should fail")
+	}
+	oa.SetGroupMappings([]OauthGroupMapping{{IdpGroup: "staff", PlanId: 1, Period: "1 month"}})
+	if err := oa.FormatOauthInfo(); err == nil {
+		t.Fatal("invalid period should fail")
+	}
+}
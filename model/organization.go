@@ -0,0 +1,42 @@
+package model
+
+// 组织成员角色
+const (
+	OrgRoleOwner  = "owner"  // 所有者, 其订阅为全体成员提供席位
+	OrgRoleMember = "member" // 成员
+)
+
+// Organization 组织/团队, 所有者的一份订阅覆盖多个成员, 席位数取自套餐的 Seats
+type Organization struct {
+	IdModel
+	Name    string                `json:"name" gorm:"size:128;default:'';not null"`
+	OwnerId uint                  `json:"owner_id" gorm:"uniqueIndex;not null"` // 所有者(一个用户最多拥有一个组织)
+	Seats   int                   `json:"seats" gorm:"-"`                       // 席位数(接口计算返回, 所有者订阅无效时为 0)
+	Used    int                   `json:"used" gorm:"-"`                        // 已占用席位(接口计算返回, 含所有者)
+	Owner   *User                 `json:"owner,omitempty" gorm:"foreignKey:OwnerId"`
+	Members []*OrganizationMember `json:"members,omitempty" gorm:"foreignKey:OrgId"`
+	TimeModel
+}
+
+type OrganizationList struct {
+	Organizations []*Organization `json:"list"`
+	Pagination
+}
+
+// OrganizationMember 组织成员, 一个用户最多加入一个组织
+// 按加入顺序占用席位, 套餐席位减少后超出的成员不再享有订阅
+type OrganizationMember struct {
+	IdModel
+	OrgId     uint   `json:"org_id" gorm:"index;not null"`
+	UserId    uint   `json:"user_id" gorm:"uniqueIndex;not null"`
+	Role      string `json:"role" gorm:"size:16;default:'member';not null"`
+	InvitedBy uint   `json:"invited_by" gorm:"default:0;not null"` // 邀请人(所有者或管理员)
+	Covered   bool   `json:"covered" gorm:"-"`                     // 是否在席位内(接口计算返回)
+	User      *User  `json:"user,omitempty" gorm:"foreignKey:UserId"`
+	TimeModel
+}
+
+// SeatCovered 按加入顺序(rank 从 0 开始)判断成员是否在席位内
+func SeatCovered(rank, seats int) bool {
+	return seats > 0 && rank < seats
+}
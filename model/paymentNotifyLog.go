@@ -0,0 +1,23 @@
+package model
+
+import "github.com/lejianwen/rustdesk-api/v2/model/custom_types"
+
+// PaymentNotifyLog 支付回调原始记录, 每次收到回调写入一条(包括验签失败和重放), 用于支付争议排查
+type PaymentNotifyLog struct {
+	IdModel
+	OutTradeNo  string                `json:"out_trade_no" gorm:"size:64;default:'';not null;index"`
+	TradeNo     string                `json:"trade_no" gorm:"size:64;default:'';not null"`
+	Fingerprint string                `json:"fingerprint" gorm:"size:64;default:'';not null;index"` // 全部参数(含签名)的摘要, 相同表示完全重复的回调
+	Params      custom_types.AutoJson `json:"params" gorm:"type:text" swaggertype:"object"`         // 原始参数
+	SourceIp    string                `json:"source_ip" gorm:"size:64;default:'';not null"`
+	SignValid   bool                  `json:"sign_valid" gorm:"default:0;not null"`
+	Result      string                `json:"result" gorm:"size:32;default:'';not null;index"` // 同 rustdesk_api_payment_notify_total 的 result, 重放被拒绝时为 replay
+	Error       string                `json:"error" gorm:"type:text"`
+	ReplayOf    uint                  `json:"replay_of" gorm:"default:0;not null"` // 与之完全相同的上一条记录
+	TimeModel
+}
+
+type PaymentNotifyLogList struct {
+	PaymentNotifyLogs []*PaymentNotifyLog `json:"list"`
+	Pagination
+}
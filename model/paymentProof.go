@@ -0,0 +1,31 @@
+package model
+
+// 付款凭证审核状态
+const (
+	PaymentProofPending  = 0 // 待审核
+	PaymentProofApproved = 1 // 已通过
+	PaymentProofRejected = 2 // 已驳回
+)
+
+// PaymentProof 线下转账付款凭证, 审核通过后订单入账
+type PaymentProof struct {
+	IdModel
+	OrderId      uint   `json:"order_id" gorm:"default:0;not null;index"`
+	UserId       uint   `json:"user_id" gorm:"default:0;not null;index"`
+	FileKey      string `json:"-" gorm:"size:255;default:'';not null"` // 存储路径, 不直接对外暴露
+	FileName     string `json:"file_name" gorm:"size:255;default:'';not null"`
+	FileSize     int64  `json:"file_size" gorm:"default:0;not null"`
+	ContentType  string `json:"content_type" gorm:"size:64;default:'';not null"`
+	Note         string `json:"note" gorm:"size:500;default:'';not null"` // 用户备注, 如转账流水号
+	Status       int    `json:"status" gorm:"default:0;not null;index"`   // 0待审核 1已通过 2已驳回
+	ReviewerId   uint   `json:"reviewer_id" gorm:"default:0;not null"`
+	ReviewedAt   int64  `json:"reviewed_at" gorm:"default:0;not null"`
+	ReviewRemark string `json:"review_remark" gorm:"size:500;default:'';not null"`
+	Order        *Order `json:"order,omitempty" gorm:"foreignKey:OrderId"`
+	TimeModel
+}
+
+type PaymentProofList struct {
+	PaymentProofs []*PaymentProof `json:"list"`
+	Pagination
+}
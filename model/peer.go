@@ -16,6 +16,10 @@ type Peer struct {
 	LastOnlineIp   string `json:"last_online_ip"  gorm:"default:'';not null;"`
 	GroupId        uint   `json:"group_id"  gorm:"default:0;not null;index"`
 	Alias          string `json:"alias" gorm:"default:'';not null;index"`
+
+	// 按 last_online_ip 查询的地理位置
+	Country string `json:"country" gorm:"size:8;default:'';not null;index"`
+	City    string `json:"city" gorm:"size:100;default:'';not null"`
 	TimeModel
 }
 
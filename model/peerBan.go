@@ -0,0 +1,17 @@
+package model
+
+// PeerBan 设备封禁, 按设备ID或uuid匹配, 被封禁的设备订阅检查和 relay 放行均被拒绝
+type PeerBan struct {
+	IdModel
+	PeerId     string `json:"peer_id" gorm:"size:100;default:'';not null;index"`
+	Uuid       string `json:"uuid" gorm:"size:128;default:'';not null;index"`
+	Reason     string `json:"reason" gorm:"size:255;default:'';not null"`
+	OperatorId uint   `json:"operator_id" gorm:"default:0;not null"`
+	ExpireAt   int64  `json:"expire_at" gorm:"default:0;not null"` // 解封时间, 0 表示永久
+	TimeModel
+}
+
+type PeerBanList struct {
+	PeerBans []*PeerBan `json:"list"`
+	Pagination
+}
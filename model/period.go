@@ -0,0 +1,131 @@
+package model
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Period 日历周期(ISO-8601 时长的日期部分), 如 P1M、P90D、P1M7D
+// 按日历加减(AddDate), 与原 PeriodUnit/PeriodCount 的计算方式一致
+type Period struct {
+	Years  int
+	Months int
+	Days   int
+}
+
+// ParsePeriod 解析 ISO-8601 时长, 支持 Y/M/W/D, 不支持时间部分(T)和负数
+func ParsePeriod(s string) (Period, error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	if len(s) < 3 || s[0] != 'P' {
+		return Period{}, errors.New("invalid period")
+	}
+	var p Period
+	num := ""
+	last := -1
+	order := "YMWD"
+	for _, ch := range s[1:] {
+		if ch >= '0' && ch <= '9' {
+			num += string(ch)
+			continue
+		}
+		idx := strings.IndexRune(order, ch)
+		if idx < 0 {
+			return Period{}, errors.New("invalid period: unsupported designator " + string(ch))
+		}
+		if num == "" || idx <= last {
+			return Period{}, errors.New("invalid period format")
+		}
+		n, err := strconv.Atoi(num)
+		if err != nil || n > 100000 {
+			return Period{}, errors.New("invalid period: number out of range")
+		}
+		switch ch {
+		case 'Y':
+			p.Years = n
+		case 'M':
+			p.Months = n
+		case 'W':
+			p.Days += n * 7
+		case 'D':
+			p.Days += n
+		}
+		num = ""
+		last = idx
+	}
+	if num != "" {
+		return Period{}, errors.New("invalid period format")
+	}
+	if p.IsZero() {
+		return Period{}, errors.New("invalid period: zero")
+	}
+	return p, nil
+}
+
+// PeriodOf 将旧的周期单位和数量映射为 Period, 未知单位按月
+func PeriodOf(unit string, count int) Period {
+	switch unit {
+	case PeriodUnitDay:
+		return Period{Days: count}
+	case PeriodUnitYear:
+		return Period{Years: count}
+	}
+	return Period{Months: count}
+}
+
+func (p Period) IsZero() bool {
+	return p.Years == 0 && p.Months == 0 && p.Days == 0
+}
+
+// String 规范化的 ISO-8601 表示, 如 P1M7D
+func (p Period) String() string {
+	if p.IsZero() {
+		return "P0D"
+	}
+	var sb strings.Builder
+	sb.WriteString("P")
+	if p.Years != 0 {
+		sb.WriteString(strconv.Itoa(p.Years) + "Y")
+	}
+	if p.Months != 0 {
+		sb.WriteString(strconv.Itoa(p.Months) + "M")
+	}
+	if p.Days != 0 {
+		sb.WriteString(strconv.Itoa(p.Days) + "D")
+	}
+	return sb.String()
+}
+
+// Add 周期相加, 如 P1M + P7D = P1M7D
+func (p Period) Add(o Period) Period {
+	return Period{Years: p.Years + o.Years, Months: p.Months + o.Months, Days: p.Days + o.Days}
+}
+
+// AddTo 从 t 起加上周期
+func (p Period) AddTo(t time.Time) time.Time {
+	return t.AddDate(p.Years, p.Months, p.Days)
+}
+
+// AddToUnix 从时间戳 base 起加上周期, 返回时间戳
+func (p Period) AddToUnix(base int64) int64 {
+	return p.AddTo(time.Unix(base, 0)).Unix()
+}
+
+// Seconds 从 base 起该周期的实际秒数(按比例折算时长时使用)
+func (p Period) Seconds(base int64) int64 {
+	return p.AddToUnix(base) - base
+}
+
+// Unit 转换为旧的周期单位和数量, 只含单一单位时 ok 为 true
+func (p Period) Unit() (unit string, count int, ok bool) {
+	switch {
+	case p.Years != 0 && p.Months == 0 && p.Days == 0:
+		return PeriodUnitYear, p.Years, true
+	case p.Years == 0 && p.Months != 0 && p.Days == 0:
+		return PeriodUnitMonth, p.Months, true
+	case p.Years == 0 && p.Months == 0 && p.Days != 0:
+		return PeriodUnitDay, p.Days, true
+	}
+	return "", 0, false
+}
@@ -0,0 +1,48 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParsePeriod(t *testing.T) {
+	cases := map[string]Period{
+		"P1M":     {Months: 1},
+		"P90D":    {Days: 90},
+		"P1M7D":   {Months: 1, Days: 7},
+		"p1y":     {Years: 1},
+		"P2W":     {Days: 14},
+		"P1Y2M3D": {Years: 1, Months: 2, Days: 3},
+	}
+	for s, want := range cases {
+		got, err := ParsePeriod(s)
+		if err != nil || got != want {
+			t.Errorf("ParsePeriod(%q) = %+v, %v, want %+v", s, got, err, want)
+		}
+	}
+	for _, s := range []string{"", "P", "P0D", "1M", "PT1H", "P1D1M", "P-1D", "P1", "PM"} {
+		if _, err := ParsePeriod(s); err == nil {
+			t.Errorf("ParsePeriod(%q) expected error", s)
+		}
+	}
+}
+
+func TestPeriodAddTo(t *testing.T) {
+	base := time.Date(2024, 1, 15, 10, 0, 0, 0, time.Local)
+	p := Period{Months: 1, Days: 7}
+	if got := p.AddTo(base); !got.Equal(time.Date(2024, 2, 22, 10, 0, 0, 0, time.Local)) {
+		t.Errorf("AddTo = %v", got)
+	}
+	if got := PeriodOf(PeriodUnitMonth, 3).AddToUnix(base.Unix()); got != base.AddDate(0, 3, 0).Unix() {
+		t.Errorf("PeriodOf month AddToUnix = %d", got)
+	}
+	if s := p.String(); s != "P1M7D" {
+		t.Errorf("String = %s", s)
+	}
+	if unit, count, ok := (Period{Days: 90}).Unit(); !ok || unit != PeriodUnitDay || count != 90 {
+		t.Errorf("Unit = %s %d %v", unit, count, ok)
+	}
+	if _, _, ok := p.Unit(); ok {
+		t.Errorf("Unit of combined period should not be ok")
+	}
+}
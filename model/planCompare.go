@@ -0,0 +1,74 @@
+package model
+
+// 套餐能力字段对应的功能项, 在自定义功能项之前展示
+const (
+	FeatureSeats             = "seats"
+	FeatureMaxDevices        = "max_devices"
+	FeatureMaxSessions       = "max_sessions"
+	FeatureRelayQuotaBytes   = "relay_quota_bytes"
+	FeatureRelayQuotaMinutes = "relay_quota_minutes"
+	FeaturePremiumRelay      = "premium_relay"
+)
+
+// PlanFeatureRow 功能对比矩阵的一行, Values 与 PlanComparison.Plans 顺序一致, 套餐未设置时为 null
+type PlanFeatureRow struct {
+	Key     string        `json:"key"`
+	Name    string        `json:"name,omitempty"`
+	Builtin bool          `json:"builtin"` // 来自套餐能力字段(数量类 0 表示不限)
+	Values  []interface{} `json:"values"`
+}
+
+// PlanComparison 定价页的套餐对比
+type PlanComparison struct {
+	Plans    []*SubscriptionPlan `json:"plans"`
+	Features []*PlanFeatureRow   `json:"features"`
+}
+
+// BuildPlanComparison 生成功能对比矩阵
+// 能力字段只在至少一个套餐设置了时展示; 自定义功能项按首次出现的顺序合并, 名称取第一个设置了名称的套餐
+func BuildPlanComparison(plans []*SubscriptionPlan) *PlanComparison {
+	res := &PlanComparison{Plans: plans, Features: make([]*PlanFeatureRow, 0)}
+	builtin := []struct {
+		key   string
+		value func(p *SubscriptionPlan) interface{}
+		set   func(p *SubscriptionPlan) bool
+	}{
+		{FeatureSeats, func(p *SubscriptionPlan) interface{} { return p.Seats }, func(p *SubscriptionPlan) bool { return p.Seats > 0 }},
+		{FeatureMaxDevices, func(p *SubscriptionPlan) interface{} { return p.MaxDevices }, func(p *SubscriptionPlan) bool { return p.MaxDevices > 0 }},
+		{FeatureMaxSessions, func(p *SubscriptionPlan) interface{} { return p.MaxSessions }, func(p *SubscriptionPlan) bool { return p.MaxSessions > 0 }},
+		{FeatureRelayQuotaBytes, func(p *SubscriptionPlan) interface{} { return p.RelayQuotaBytes }, func(p *SubscriptionPlan) bool { return p.RelayQuotaBytes > 0 }},
+		{FeatureRelayQuotaMinutes, func(p *SubscriptionPlan) interface{} { return p.RelayQuotaMinutes }, func(p *SubscriptionPlan) bool { return p.RelayQuotaMinutes > 0 }},
+		{FeaturePremiumRelay, func(p *SubscriptionPlan) interface{} { return p.PremiumRelay }, func(p *SubscriptionPlan) bool { return p.PremiumRelay }},
+	}
+	for _, b := range builtin {
+		row := &PlanFeatureRow{Key: b.key, Builtin: true, Values: make([]interface{}, len(plans))}
+		show := false
+		for i, p := range plans {
+			row.Values[i] = b.value(p)
+			show = show || b.set(p)
+		}
+		if show {
+			res.Features = append(res.Features, row)
+		}
+	}
+
+	rows := make(map[string]*PlanFeatureRow)
+	for i, p := range plans {
+		for _, f := range p.FeatureList() {
+			if f.Key == "" {
+				continue
+			}
+			row, ok := rows[f.Key]
+			if !ok {
+				row = &PlanFeatureRow{Key: f.Key, Values: make([]interface{}, len(plans))}
+				rows[f.Key] = row
+				res.Features = append(res.Features, row)
+			}
+			if row.Name == "" {
+				row.Name = f.Name
+			}
+			row.Values[i] = f.Value
+		}
+	}
+	return res
+}
@@ -0,0 +1,32 @@
+package model
+
+import "testing"
+
+func TestBuildPlanComparison(t *testing.T) {
+	basic := &SubscriptionPlan{MaxDevices: 3}
+	basic.SetFeatures([]PlanFeature{{Key: "support", Value: "email"}})
+	pro := &SubscriptionPlan{MaxDevices: 0, PremiumRelay: true}
+	pro.SetFeatures([]PlanFeature{{Key: "support", Name: "Support", Value: "priority"}, {Key: "audit", Value: true}})
+
+	res := BuildPlanComparison([]*SubscriptionPlan{basic, pro})
+	var keys []string
+	for _, row := range res.Features {
+		keys = append(keys, row.Key)
+	}
+	want := []string{FeatureMaxDevices, FeaturePremiumRelay, "support", "audit"}
+	if len(keys) != len(want) {
+		t.Fatalf("rows = %v, want %v", keys, want)
+	}
+	for i := range want {
+		if keys[i] != want[i] {
+			t.Fatalf("rows = %v, want %v", keys, want)
+		}
+	}
+	support := res.Features[2]
+	if support.Name != "Support" || support.Values[0] != "email" || support.Values[1] != "priority" {
+		t.Errorf("support row = %+v", support)
+	}
+	if audit := res.Features[3]; audit.Values[0] != nil || audit.Values[1] != true {
+		t.Errorf("audit row = %+v, want null for plans without the feature", audit)
+	}
+}
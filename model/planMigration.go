@@ -0,0 +1,27 @@
+package model
+
+// 套餐迁移状态
+const (
+	PlanMigrationRunning = 0 // 进行中
+	PlanMigrationDone    = 1 // 已完成
+	PlanMigrationFailed  = 2 // 失败, 可重新发起
+)
+
+// PlanMigration 归档套餐时将有效订阅迁移到继任套餐的任务, 分批执行, 到期时间不变
+type PlanMigration struct {
+	IdModel
+	FromPlanId uint   `json:"from_plan_id" gorm:"default:0;not null;index"`
+	ToPlanId   uint   `json:"to_plan_id" gorm:"default:0;not null"`
+	OperatorId uint   `json:"operator_id" gorm:"default:0;not null"`
+	Status     int    `json:"status" gorm:"default:0;not null;index"`
+	Total      int64  `json:"total" gorm:"default:0;not null"`    // 发起时的有效订阅数
+	Migrated   int64  `json:"migrated" gorm:"default:0;not null"` // 已迁移数
+	Error      string `json:"error" gorm:"type:text"`
+	FinishedAt int64  `json:"finished_at" gorm:"default:0;not null"`
+	TimeModel
+}
+
+type PlanMigrationList struct {
+	PlanMigrations []*PlanMigration `json:"list"`
+	Pagination
+}
@@ -0,0 +1,42 @@
+package model
+
+import "github.com/lejianwen/rustdesk-api/v2/utils"
+
+// Product 一次性购买的商品(如优先支持工单、定制客户端), 与套餐共用订单和支付流程, 不影响订阅
+type Product struct {
+	IdModel
+	Code          string     `json:"code" gorm:"uniqueIndex;not null"`  // 商品编码
+	Name          string     `json:"name" gorm:"not null"`              // 商品名称
+	Description   string     `json:"description" gorm:"type:text"`      // 描述
+	Price         int64      `json:"price" gorm:"not null"`             // 价格(分,基础币种)
+	Status        StatusCode `json:"status" gorm:"default:1;index"`     // 状态: 1启用 2禁用
+	SortOrder     int        `json:"sort_order" gorm:"default:0"`       // 排序
+	Currency      string     `json:"currency,omitempty" gorm:"-"`       // 价格币种(接口计算返回)
+	AmountDisplay string     `json:"amount_display,omitempty" gorm:"-"` // 格式化的价格(接口计算返回)
+	TimeModel
+}
+
+type ProductList struct {
+	Products []*Product `json:"list"`
+	Pagination
+}
+
+// 商品订单履约状态, 套餐订单为 FulfillmentNone
+const (
+	FulfillmentNone       = 0 // 无需履约
+	FulfillmentPending    = 1 // 已支付, 待履约
+	FulfillmentProcessing = 2 // 处理中
+	FulfillmentDone       = 3 // 已履约
+	FulfillmentCancelled  = 4 // 已取消(如退款)
+)
+
+// Total 商品价格, 使用基础币种
+func (p *Product) Total() Money {
+	return NewMoney(p.Price, SupportedCurrencies[0])
+}
+
+// Localize 按请求语言填充展示价格
+func (p *Product) Localize(l *utils.Locale) {
+	p.Currency = p.Total().Currency
+	p.AmountDisplay = l.Money(p.Price, p.Currency)
+}
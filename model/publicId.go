@@ -0,0 +1,78 @@
+package model
+
+import "github.com/lejianwen/rustdesk-api/v2/lib/publicid"
+
+// encodeRef 编码关联ID, 0 表示无关联
+func encodeRef(c publicid.Codec, kind string, id uint) string {
+	if id == 0 {
+		return ""
+	}
+	return c.Encode(kind, id)
+}
+
+// Publicize 填充对外标识, hide 时清空数字ID(面向用户的接口在隐藏数字ID的编码下使用)
+func (p *SubscriptionPlan) Publicize(c publicid.Codec, hide bool) {
+	p.PublicId = encodeRef(c, publicid.KindPlan, p.Id)
+	if hide {
+		p.Id = 0
+		for _, pp := range p.Prices {
+			pp.Id, pp.PlanId = 0, 0
+		}
+	}
+}
+
+// Publicize 填充对外标识, hide 时清空数字ID, 包括关联的套餐和付款凭证
+func (o *Order) Publicize(c publicid.Codec, hide bool) {
+	o.PublicId = encodeRef(c, publicid.KindOrder, o.Id)
+	o.PlanPublicId = encodeRef(c, publicid.KindPlan, o.PlanId)
+	if o.Plan != nil {
+		o.Plan.Publicize(c, hide)
+	}
+	for _, pf := range o.Proofs {
+		pf.Publicize(hide)
+	}
+	if hide {
+		o.Id, o.UserId, o.PlanId = 0, 0, 0
+		o.User = nil
+		for _, r := range o.Refunds {
+			r.Id, r.OrderId, r.UserId = 0, 0, 0
+		}
+	}
+}
+
+// Publicize hide 时清空数字ID, 凭证通过所属订单的对外标识查找
+func (pf *PaymentProof) Publicize(hide bool) {
+	if hide {
+		pf.Id, pf.OrderId, pf.UserId, pf.ReviewerId = 0, 0, 0, 0
+	}
+}
+
+// Publicize 填充对外标识, hide 时清空数字ID, 包括关联的套餐和订单
+func (s *UserSubscription) Publicize(c publicid.Codec, hide bool) {
+	s.PublicId = encodeRef(c, publicid.KindSubscription, s.Id)
+	s.PlanPublicId = encodeRef(c, publicid.KindPlan, s.PlanId)
+	s.LastOrderPublicId = encodeRef(c, publicid.KindOrder, s.LastOrderId)
+	if s.Plan != nil {
+		s.Plan.Publicize(c, hide)
+	}
+	if s.LastOrder != nil {
+		s.LastOrder.Publicize(c, hide)
+	}
+	if s.PendingPlan != nil {
+		s.PendingPlan.Publicize(c, hide)
+	}
+	if hide {
+		s.Id, s.UserId, s.PlanId, s.LastOrderId, s.PendingPlanId = 0, 0, 0, 0, 0
+		s.User = nil
+	}
+}
+
+// Publicize 填充对外标识, hide 时清空数字ID
+func (e *SubscriptionEvent) Publicize(c publicid.Codec, hide bool) {
+	e.SubscriptionPublicId = encodeRef(c, publicid.KindSubscription, e.SubscriptionId)
+	e.PlanPublicId = encodeRef(c, publicid.KindPlan, e.PlanId)
+	e.OrderPublicId = encodeRef(c, publicid.KindOrder, e.OrderId)
+	if hide {
+		e.Id, e.UserId, e.SubscriptionId, e.PlanId, e.OrderId, e.ActorId = 0, 0, 0, 0, 0, 0
+	}
+}
@@ -0,0 +1,33 @@
+package model
+
+import "github.com/lejianwen/rustdesk-api/v2/model/custom_types"
+
+// 兑换码状态
+const (
+	RedeemCodeStatusUnused   = 0 // 未使用
+	RedeemCodeStatusUsed     = 1 // 已使用
+	RedeemCodeStatusDisabled = 2 // 已作废
+)
+
+// RedeemCode 兑换码(礼品码)
+type RedeemCode struct {
+	IdModel
+	Code      string                `json:"code" gorm:"uniqueIndex;size:64;not null"` // 兑换码
+	BatchNo   string                `json:"batch_no" gorm:"index;size:64"`            // 批次号
+	PlanId    uint                  `json:"plan_id" gorm:"index;not null"`            // 套餐ID
+	Days      int                   `json:"days" gorm:"not null"`                     // 兑换天数
+	Status    int                   `json:"status" gorm:"default:0;index"`            // 状态: 0未使用 1已使用 2已作废
+	ExpireAt  int64                 `json:"expire_at" gorm:"default:0"`               // 兑换截止时间(0为不限)
+	CreatedBy uint                  `json:"created_by" gorm:"default:0"`              // 生成者(管理员ID)
+	UsedBy    uint                  `json:"used_by" gorm:"default:0;index"`           // 使用者ID
+	UsedAt    int64                 `json:"used_at" gorm:"default:0"`                 // 使用时间
+	Plan      *SubscriptionPlan     `json:"plan,omitempty" gorm:"foreignKey:PlanId"`
+	UsedUser  *User                 `json:"used_user,omitempty" gorm:"foreignKey:UsedBy"`
+	CreatedAt custom_types.AutoTime `json:"created_at" gorm:"type:timestamp;"`
+	UpdatedAt custom_types.AutoTime `json:"updated_at" gorm:"type:timestamp;"`
+}
+
+type RedeemCodeList struct {
+	RedeemCodes []*RedeemCode `json:"list"`
+	Pagination
+}
@@ -0,0 +1,47 @@
+package model
+
+// ReferralCode 用户的推荐码, 首次查看推荐信息时生成
+type ReferralCode struct {
+	IdModel
+	UserId uint   `json:"user_id" gorm:"uniqueIndex;not null"`
+	Code   string `json:"code" gorm:"uniqueIndex;size:32;not null"`
+	TimeModel
+}
+
+// Referral 推荐关系, 被推荐用户注册时记录, 一个用户最多被推荐一次
+// 奖励天数为实际发放给推荐人的天数, 0 表示未发放(规则未开启、达到上限或无可用套餐)
+type Referral struct {
+	IdModel
+	ReferrerId     uint   `json:"referrer_id" gorm:"index;not null"`
+	RefereeId      uint   `json:"referee_id" gorm:"uniqueIndex;not null"`
+	Code           string `json:"code" gorm:"size:32;default:'';not null"`
+	SignupReward   int    `json:"signup_reward" gorm:"default:0;not null"`   // 注册奖励天数
+	FirstOrderId   uint   `json:"first_order_id" gorm:"default:0;not null"`  // 被推荐用户的首笔付费订单
+	PurchasedAt    int64  `json:"purchased_at" gorm:"default:0;not null"`    // 首购时间
+	PurchaseReward int    `json:"purchase_reward" gorm:"default:0;not null"` // 首购奖励天数
+	Referrer       *User  `json:"referrer,omitempty" gorm:"foreignKey:ReferrerId"`
+	Referee        *User  `json:"referee,omitempty" gorm:"foreignKey:RefereeId"`
+	TimeModel
+}
+
+type ReferralList struct {
+	Referrals []*Referral `json:"list"`
+	Pagination
+}
+
+// ReferralStat 推荐人的推荐效果
+type ReferralStat struct {
+	ReferrerId uint   `json:"referrer_id"`
+	Username   string `json:"username"`
+	Signups    int64  `json:"signups"`     // 推荐注册数
+	Purchases  int64  `json:"purchases"`   // 其中完成首购的人数
+	RewardDays int64  `json:"reward_days"` // 累计奖励天数
+}
+
+// ReferralSummary 当前用户的推荐信息
+type ReferralSummary struct {
+	Code       string `json:"code"`
+	Signups    int64  `json:"signups"`
+	Purchases  int64  `json:"purchases"`
+	RewardDays int64  `json:"reward_days"`
+}
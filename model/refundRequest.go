@@ -0,0 +1,32 @@
+package model
+
+// 退款申请状态
+const (
+	RefundRequestPending  = 0 // 待审批
+	RefundRequestApproved = 1 // 已批准并退款
+	RefundRequestRejected = 2 // 已驳回
+)
+
+// RefundRequest 退款申请: 客服提交, 财务审批通过后才调用支付网关退款
+// 网关退款失败时保持待审批并记录错误, 可再次审批重试
+type RefundRequest struct {
+	IdModel
+	OrderId     uint   `json:"order_id" gorm:"index;not null"`
+	UserId      uint   `json:"user_id" gorm:"index;not null"`
+	Amount      int64  `json:"amount" gorm:"not null"` // 申请金额(分), 0 表示退还审批时剩余全部金额
+	Currency    string `json:"currency" gorm:"size:3;default:'CNY'"`
+	Reason      string `json:"reason" gorm:"type:text"`
+	Status      int    `json:"status" gorm:"default:0;not null;index"`
+	RequesterId uint   `json:"requester_id" gorm:"default:0;not null"` // 申请的管理员
+	ReviewerId  uint   `json:"reviewer_id" gorm:"default:0;not null"`  // 审批的管理员
+	ReviewNote  string `json:"review_note" gorm:"type:text"`
+	ReviewedAt  int64  `json:"reviewed_at" gorm:"default:0;not null"`
+	RefundId    uint   `json:"refund_id" gorm:"default:0;not null"` // 批准后生成的退款记录
+	LastError   string `json:"last_error" gorm:"type:text"`         // 最近一次审批时网关退款失败的原因
+	TimeModel
+}
+
+type RefundRequestList struct {
+	RefundRequests []*RefundRequest `json:"list"`
+	Pagination
+}
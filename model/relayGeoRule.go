@@ -0,0 +1,32 @@
+package model
+
+// 地区限制的适用范围
+const (
+	RelayGeoScopeGroup = "group" // 用户组
+	RelayGeoScopePlan  = "plan"  // 当前有效订阅的套餐
+)
+
+// 地区限制方式
+const (
+	RelayGeoModeAllow = "allow" // 仅允许匹配的国家/网段
+	RelayGeoModeDeny  = "deny"  // 拒绝匹配的国家/网段
+)
+
+// RelayGeoRule relay 白名单消费的地区限制, 按发起用户的用户组或套餐生效
+// 同一用户命中的规则中, 任一 deny 匹配即拒绝; 存在 allow 规则时需至少匹配一条
+type RelayGeoRule struct {
+	IdModel
+	Scope     string     `json:"scope" gorm:"size:16;default:'';not null;index:idx_relay_geo_scope"`
+	ScopeId   uint       `json:"scope_id" gorm:"default:0;not null;index:idx_relay_geo_scope"` // 用户组ID或套餐ID
+	Mode      string     `json:"mode" gorm:"size:16;default:'';not null"`
+	Countries string     `json:"countries" gorm:"size:255;default:'';not null"` // ISO 国家/地区代码, 逗号分隔
+	Cidrs     string     `json:"cidrs" gorm:"type:text"`                        // IP 或 CIDR, 逗号或换行分隔
+	Status    StatusCode `json:"status" gorm:"default:1;not null;index"`
+	Remark    string     `json:"remark" gorm:"size:255;default:'';not null"`
+	TimeModel
+}
+
+type RelayGeoRuleList struct {
+	RelayGeoRules []*RelayGeoRule `json:"list"`
+	Pagination
+}
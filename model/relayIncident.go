@@ -0,0 +1,28 @@
+package model
+
+// relay 异常类型
+const (
+	RelayIncidentConsumeFailures = "consume_failures" // 同一 uuid 短时间内大量消费失败
+	RelayIncidentAllowFlood      = "allow_flood"      // 同一用户短时间内大量写入白名单
+	RelayIncidentGeoRestricted   = "geo_restricted"   // 从地区限制不允许的IP消费白名单
+)
+
+// RelayIncident relay 异常记录
+type RelayIncident struct {
+	IdModel
+	Kind      string `json:"kind" gorm:"size:32;default:'';not null;index"`
+	Uuid      string `json:"uuid" gorm:"size:128;default:'';not null;index"`
+	UserId    uint   `json:"user_id" gorm:"default:0;not null;index"`
+	PeerId    string `json:"peer_id" gorm:"size:100;default:'';not null"`
+	Count     int    `json:"count" gorm:"default:0;not null"`      // 窗口内次数
+	WindowSec int    `json:"window_sec" gorm:"default:0;not null"` // 统计窗口(秒)
+	FirstAt   int64  `json:"first_at" gorm:"default:0;not null"`   // 窗口内首次发生时间
+	LastAt    int64  `json:"last_at" gorm:"default:0;not null"`    // 触发时间
+	Detail    string `json:"detail" gorm:"type:text"`              // 失败原因分布/样本 uuid 等排查信息
+	TimeModel
+}
+
+type RelayIncidentList struct {
+	RelayIncidents []*RelayIncident `json:"list"`
+	Pagination
+}
@@ -0,0 +1,56 @@
+package model
+
+// relay 会话状态
+const (
+	RelaySessionActive = 1 // 进行中
+	RelaySessionEnded  = 2 // 已结束
+)
+
+// RelaySession hbbr 上报的 relay 会话, 用于审计和按流量/时长计费
+type RelaySession struct {
+	IdModel
+	Uuid         string `json:"uuid" gorm:"size:128;not null;uniqueIndex"`
+	PeerId       string `json:"peer_id" gorm:"size:100;default:'';not null;index"`        // 发起端设备ID
+	TargetPeerId string `json:"target_peer_id" gorm:"size:100;default:'';not null;index"` // 被控端设备ID
+	UserId       uint   `json:"user_id" gorm:"default:0;not null;index"`                  // 发起端设备归属用户
+	TargetUserId uint   `json:"target_user_id" gorm:"default:0;not null;index"`           // 被控端设备归属用户
+	RelayAddr    string `json:"relay_addr" gorm:"size:64;default:'';not null"`            // 上报的 hbbr 地址
+	StartedAt    int64  `json:"started_at" gorm:"default:0;not null;index"`
+	EndedAt      int64  `json:"ended_at" gorm:"default:0;not null"`
+	Duration     int64  `json:"duration" gorm:"default:0;not null"`   // 时长(秒)
+	BytesUp      int64  `json:"bytes_up" gorm:"default:0;not null"`   // 发起端 -> 被控端
+	BytesDown    int64  `json:"bytes_down" gorm:"default:0;not null"` // 被控端 -> 发起端
+	Status       int    `json:"status" gorm:"default:1;not null;index"`
+	TimeModel
+}
+
+type RelaySessionList struct {
+	RelaySessions []*RelaySession `json:"list"`
+	Pagination
+}
+
+// RelayUsage 按用户汇总的 relay 用量
+type RelayUsage struct {
+	UserId   uint  `json:"user_id"`
+	Sessions int64 `json:"sessions"`
+	Duration int64 `json:"duration"` // 秒
+	Bytes    int64 `json:"bytes"`
+}
+
+// RelayQuotaUsage 用户当月 relay 配额及用量, 配额为 0 表示不限
+type RelayQuotaUsage struct {
+	PeriodStart  int64 `json:"period_start"`
+	PeriodEnd    int64 `json:"period_end"`
+	QuotaBytes   int64 `json:"quota_bytes"`
+	UsedBytes    int64 `json:"used_bytes"`
+	QuotaMinutes int64 `json:"quota_minutes"`
+	UsedMinutes  int64 `json:"used_minutes"`
+	Exceeded     bool  `json:"exceeded"`
+}
+
+// SessionCheck 用户并发会话检查结果, Limit 为 0 表示不限
+type SessionCheck struct {
+	Allow  bool  `json:"allow"`
+	Limit  int64 `json:"limit"`
+	Active int64 `json:"active"`
+}
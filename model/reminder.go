@@ -0,0 +1,26 @@
+package model
+
+// 订阅提醒类型
+const (
+	ReminderKindExpiring = "expiring" // 到期前提醒
+	ReminderKindLapsed   = "lapsed"   // 到期后仍未续费的催缴提醒
+)
+
+// SubscriptionReminder 订阅提醒发送记录, RefKey 唯一, 每个订阅的每个到期时间在每个提醒点只发送一次
+type SubscriptionReminder struct {
+	IdModel
+	UserId         uint   `json:"user_id" gorm:"default:0;not null;index"`
+	SubscriptionId uint   `json:"subscription_id" gorm:"default:0;not null"`
+	Kind           string `json:"kind" gorm:"size:16;default:'';not null"`
+	Days           int    `json:"days" gorm:"default:0;not null"` // 距到期(到期前)或已过期(到期后)的天数
+	ExpireAt       int64  `json:"expire_at" gorm:"default:0;not null"`
+	RefKey         string `json:"ref_key" gorm:"size:128;not null;uniqueIndex"`
+	TimeModel
+}
+
+// ReminderOptOut 退订订阅提醒的用户, 不再发送到期和催缴的邮件、站内通知和 webhook
+type ReminderOptOut struct {
+	IdModel
+	UserId uint `json:"user_id" gorm:"uniqueIndex;not null"`
+	TimeModel
+}
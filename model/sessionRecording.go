@@ -0,0 +1,25 @@
+package model
+
+// SessionRecording 会话录像登记, 录像文件由 relay/录制组件保存, 这里只记录元数据
+// StorageKey 为本服务存储(本地或 S3)中的 key, 下载时生成签名地址; 组件自行托管时填写 Url
+type SessionRecording struct {
+	IdModel
+	SessionId    string `json:"session_id" gorm:"size:191;default:'';not null;index"` // relay uuid 或连接ID
+	StorageKey   string `json:"storage_key" gorm:"size:191;default:'';not null"`
+	Url          string `json:"url" gorm:"size:1024;default:'';not null"`
+	PeerId       string `json:"peer_id" gorm:"size:100;default:'';not null;index"`        // 发起端设备ID
+	TargetPeerId string `json:"target_peer_id" gorm:"size:100;default:'';not null;index"` // 被控端设备ID
+	UserId       uint   `json:"user_id" gorm:"default:0;not null;index"`                  // 被控端设备归属用户
+	Size         int64  `json:"size" gorm:"default:0;not null"`                           // 字节
+	Duration     int64  `json:"duration" gorm:"default:0;not null"`                       // 秒
+	Checksum     string `json:"checksum" gorm:"size:64;default:'';not null"`              // sha256 十六进制
+	RecordedAt   int64  `json:"recorded_at" gorm:"default:0;not null;index"`              // 录制开始时间(unix 秒)
+	Hold         bool   `json:"hold" gorm:"default:0;not null;"`                          // 保留, 不受保留期限清理
+	Remark       string `json:"remark" gorm:"size:255;default:'';not null"`
+	TimeModel
+}
+
+type SessionRecordingList struct {
+	SessionRecordings []*SessionRecording `json:"list"`
+	Pagination
+}
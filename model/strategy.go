@@ -0,0 +1,100 @@
+package model
+
+import "strconv"
+
+// 验证方式
+const (
+	StrategyVerifyTemporary = "temporary" // 仅一次性密码
+	StrategyVerifyPermanent = "permanent" // 仅固定密码
+	StrategyVerifyBoth      = "both"      // 两者均可
+)
+
+// 策略的适用范围
+const (
+	StrategyScopeGroup = "group" // 用户组
+	StrategyScopePlan  = "plan"  // 当前有效订阅的套餐, 优先于用户组
+)
+
+// StrategyProfile 设备策略, 通过心跳下发给用户名下的设备, 由客户端写入本地配置
+// 未勾选的限制和未设置的选项下发为空值, 客户端按空值恢复默认
+type StrategyProfile struct {
+	IdModel
+	Name                    string     `json:"name" gorm:"size:64;default:'';not null"`
+	DisableFileTransfer     bool       `json:"disable_file_transfer" gorm:"default:0;not null;"`       // 禁止文件传输
+	DisableClipboard        bool       `json:"disable_clipboard" gorm:"default:0;not null;"`           // 禁止剪贴板
+	DisableKeyboard         bool       `json:"disable_keyboard" gorm:"default:0;not null;"`            // 禁止键鼠控制
+	DisableAudio            bool       `json:"disable_audio" gorm:"default:0;not null;"`               // 禁止音频
+	DisableRecording        bool       `json:"disable_recording" gorm:"default:0;not null;"`           // 禁止录制会话
+	AutoRecordIncoming      bool       `json:"auto_record_incoming" gorm:"default:0;not null;"`        // 自动录制来访会话
+	VerificationMethod      string     `json:"verification_method" gorm:"size:16;default:'';not null"` // temporary/permanent/both, 空为客户端默认
+	TemporaryPasswordLength int        `json:"temporary_password_length" gorm:"default:0;not null"`    // 一次性密码长度 6/8/10, 0 为客户端默认
+	AuditFileTransfer       bool       `json:"audit_file_transfer" gorm:"default:0;not null;"`         // 记录文件传输审计(仅服务端使用, 不下发)
+	Status                  StatusCode `json:"status" gorm:"default:1;not null;index"`
+	Remark                  string     `json:"remark" gorm:"size:255;default:'';not null"`
+	TimeModel
+}
+
+type StrategyProfileList struct {
+	StrategyProfiles []*StrategyProfile `json:"list"`
+	Pagination
+}
+
+// StrategyAssignment 策略分配, 每个用户组或套餐只分配一个策略
+type StrategyAssignment struct {
+	IdModel
+	Scope     string           `json:"scope" gorm:"size:16;default:'';not null;uniqueIndex:idx_strategy_scope"`
+	ScopeId   uint             `json:"scope_id" gorm:"default:0;not null;uniqueIndex:idx_strategy_scope"` // 用户组ID或套餐ID
+	ProfileId uint             `json:"profile_id" gorm:"default:0;not null;index"`
+	Profile   *StrategyProfile `json:"profile,omitempty" gorm:"foreignKey:ProfileId"`
+	TimeModel
+}
+
+type StrategyAssignmentList struct {
+	StrategyAssignments []*StrategyAssignment `json:"list"`
+	Pagination
+}
+
+var strategyVerifyOptions = map[string]string{
+	StrategyVerifyTemporary: "use-temporary-password",
+	StrategyVerifyPermanent: "use-permanent-password",
+	StrategyVerifyBoth:      "use-both-passwords",
+}
+
+// ValidVerificationMethod 验证方式是否有效, 空值表示不设置
+func ValidVerificationMethod(m string) bool {
+	_, ok := strategyVerifyOptions[m]
+	return m == "" || ok
+}
+
+// ValidTemporaryPasswordLength 一次性密码长度是否有效, 0 表示不设置
+func ValidTemporaryPasswordLength(n int) bool {
+	return n == 0 || n == 6 || n == 8 || n == 10
+}
+
+func disableOption(disabled bool) string {
+	if disabled {
+		return "N"
+	}
+	return ""
+}
+
+// ConfigOptions 转换为 RustDesk 客户端配置项, 空值表示删除该项(恢复默认)
+func (p *StrategyProfile) ConfigOptions() map[string]string {
+	opts := map[string]string{
+		"enable-file-transfer":       disableOption(p.DisableFileTransfer),
+		"enable-clipboard":           disableOption(p.DisableClipboard),
+		"enable-keyboard":            disableOption(p.DisableKeyboard),
+		"enable-audio":               disableOption(p.DisableAudio),
+		"enable-record-session":      disableOption(p.DisableRecording),
+		"allow-auto-record-incoming": "",
+		"verification-method":        strategyVerifyOptions[p.VerificationMethod],
+		"temporary-password-length":  "",
+	}
+	if p.AutoRecordIncoming && !p.DisableRecording {
+		opts["allow-auto-record-incoming"] = "Y"
+	}
+	if p.TemporaryPasswordLength > 0 {
+		opts["temporary-password-length"] = strconv.Itoa(p.TemporaryPasswordLength)
+	}
+	return opts
+}
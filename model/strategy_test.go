@@ -0,0 +1,53 @@
+package model
+
+import "testing"
+
+func TestStrategyConfigOptions(t *testing.T) {
+	p := &StrategyProfile{
+		DisableFileTransfer:     true,
+		AutoRecordIncoming:      true,
+		VerificationMethod:      StrategyVerifyPermanent,
+		TemporaryPasswordLength: 8,
+	}
+	opts := p.ConfigOptions()
+	want := map[string]string{
+		"enable-file-transfer":       "N",
+		"enable-clipboard":           "",
+		"allow-auto-record-incoming": "Y",
+		"verification-method":        "use-permanent-password",
+		"temporary-password-length":  "8",
+	}
+	for k, v := range want {
+		if opts[k] != v {
+			t.Errorf("%s = %q, want %q", k, opts[k], v)
+		}
+	}
+
+	// 禁止录制时不开启自动录制
+	p = &StrategyProfile{DisableRecording: true, AutoRecordIncoming: true}
+	opts = p.ConfigOptions()
+	if opts["enable-record-session"] != "N" || opts["allow-auto-record-incoming"] != "" {
+		t.Fatalf("recording options = %q %q", opts["enable-record-session"], opts["allow-auto-record-incoming"])
+	}
+
+	// 空策略清除全部选项
+	for k, v := range (&StrategyProfile{}).ConfigOptions() {
+		if v != "" {
+			t.Errorf("empty profile %s = %q", k, v)
+		}
+	}
+}
+
+func TestStrategyValidation(t *testing.T) {
+	for _, m := range []string{"", StrategyVerifyTemporary, StrategyVerifyPermanent, StrategyVerifyBoth} {
+		if !ValidVerificationMethod(m) {
+			t.Errorf("%q should be valid", m)
+		}
+	}
+	if ValidVerificationMethod("otp") {
+		t.Error("otp should be invalid")
+	}
+	if !ValidTemporaryPasswordLength(0) || !ValidTemporaryPasswordLength(10) || ValidTemporaryPasswordLength(7) {
+		t.Error("temporary password length validation")
+	}
+}
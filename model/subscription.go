@@ -1,12 +1,11 @@
 package model
 
 import (
-	"errors"
-	"fmt"
-	"strconv"
+	"encoding/json"
 	"strings"
 
 	"github.com/lejianwen/rustdesk-api/v2/model/custom_types"
+	"github.com/lejianwen/rustdesk-api/v2/utils"
 )
 
 // 订单状态
@@ -17,11 +16,49 @@ const (
 	OrderStatusClosed   = 3 // 已关闭
 )
 
+// 支付方式
+const (
+	PayMethodOnline = "online" // 在线支付网关
+	PayMethodManual = "manual" // 线下转账, 上传凭证后由管理员审核
+)
+
+// 在线支付渠道(易支付 type 参数)
+const (
+	PayTypeGateway = "epay"   // 未指定渠道, 由网关收银台选择
+	PayTypeAlipay  = "alipay" // 支付宝
+	PayTypeWxpay   = "wxpay"  // 微信支付
+	PayTypeQQpay   = "qqpay"  // QQ 钱包
+)
+
+// PayTypes 可配置的支付渠道
+var PayTypes = []string{PayTypeAlipay, PayTypeWxpay, PayTypeQQpay}
+
+// EnabledPayTypes 过滤配置中的支付渠道: 忽略未知渠道和重复项, 保持配置顺序
+func EnabledPayTypes(configured []string) []string {
+	var res []string
+	seen := map[string]bool{}
+	for _, t := range configured {
+		t = strings.ToLower(strings.TrimSpace(t))
+		if seen[t] {
+			continue
+		}
+		for _, known := range PayTypes {
+			if t == known {
+				res = append(res, t)
+				seen[t] = true
+				break
+			}
+		}
+	}
+	return res
+}
+
 // 订阅状态
 const (
 	SubscriptionStatusActive   = 1 // 有效
 	SubscriptionStatusExpired  = 2 // 已过期
 	SubscriptionStatusCanceled = 3 // 已取消
+	SubscriptionStatusPaused   = 4 // 已暂停(剩余时长冻结)
 )
 
 // 周期单位
@@ -31,17 +68,67 @@ const (
 	PeriodUnitYear  = "year"
 )
 
+// 币种(ISO 4217)
+const (
+	CurrencyCNY = "CNY"
+	CurrencyUSD = "USD"
+	CurrencyEUR = "EUR"
+)
+
+// 套餐可见范围
+const (
+	PlanVisibilityPublic = "public" // 公开, 所有用户可见可购买
+	PlanVisibilityGroups = "groups" // 仅指定用户组可见可购买(如企业协议价)
+	PlanVisibilityHidden = "hidden" // 不在列表中展示, 持直接链接(套餐编码)可购买
+)
+
+// SupportedCurrencies 套餐可配置的币种, 第一个为基础币种(即 SubscriptionPlan.Price 的币种)
+var SupportedCurrencies = []string{CurrencyCNY, CurrencyUSD, CurrencyEUR}
+
 // SubscriptionPlan 订阅套餐
 type SubscriptionPlan struct {
 	IdModel
-	Code        string     `json:"code" gorm:"uniqueIndex;not null"`   // 套餐编码
-	Name        string     `json:"name" gorm:"not null"`               // 套餐名称
-	Description string     `json:"description" gorm:"type:text"`       // 描述
-	Price       int64      `json:"price" gorm:"not null"`              // 价格(分)
-	PeriodUnit  string     `json:"period_unit" gorm:"default:'month'"` // 周期单位: day/month/year
-	PeriodCount int        `json:"period_count" gorm:"default:1"`      // 周期数量
-	Status      StatusCode `json:"status" gorm:"default:1;index"`      // 状态: 1启用 2禁用
-	SortOrder   int        `json:"sort_order" gorm:"default:0"`        // 排序
+	Code                   string       `json:"code" gorm:"uniqueIndex;not null"`           // 套餐编码
+	Name                   string       `json:"name" gorm:"not null"`                       // 套餐名称
+	Description            string       `json:"description" gorm:"type:text"`               // 描述
+	Price                  int64        `json:"price" gorm:"not null"`                      // 价格(分,基础币种)
+	PeriodUnit             string       `json:"period_unit" gorm:"default:'month'"`         // 周期单位: day/month/year
+	PeriodCount            int          `json:"period_count" gorm:"default:1"`              // 周期数量
+	Period                 string       `json:"period" gorm:"size:32;default:''"`           // ISO-8601 周期(如 P90D、P1M7D), 非空时优先于 PeriodUnit/PeriodCount
+	BonusDaysFirstPurchase int          `json:"bonus_days_first_purchase" gorm:"default:0"` // 首购赠送天数: 用户首次付费购买该套餐时额外赠送
+	BonusEligible          bool         `json:"bonus_eligible,omitempty" gorm:"-"`          // 当前用户是否可享首购赠送(接口计算返回)
+	MaxDevices             int          `json:"max_devices" gorm:"default:0"`               // 可绑定设备数, 0 表示不限
+	MaxSessions            int          `json:"max_sessions" gorm:"default:0"`              // 同时进行的连接会话数, 0 表示不限
+	DeviceSeats            bool         `json:"device_seats" gorm:"default:0"`              // 按设备席位授权: 仅分配到席位的设备可使用订阅, 席位数为 MaxDevices
+	RelayQuotaBytes        int64        `json:"relay_quota_bytes" gorm:"default:0"`         // 每月 relay 流量配额(字节), 0 表示不限
+	RelayQuotaMinutes      int64        `json:"relay_quota_minutes" gorm:"default:0"`       // 每月 relay 时长配额(分钟), 0 表示不限
+	PremiumRelay           bool         `json:"premium_relay" gorm:"default:0"`             // 使用高级 relay
+	PremiumRelayTrialDays  int          `json:"premium_relay_trial_days" gorm:"default:0"`  // 免费套餐: 注册后试用高级 relay 的天数, 到期回落到普通 relay
+	Seats                  int          `json:"seats" gorm:"default:0"`                     // 组织席位数(含所有者), 0 为个人套餐, 大于 0 时购买者可创建组织
+	OncePerUser            bool         `json:"once_per_user" gorm:"default:0"`             // 购买限制: 每个用户只能购买一次
+	NewUsersOnly           bool         `json:"new_users_only" gorm:"default:0"`            // 购买限制: 仅限从未订阅过的新用户
+	UpgradeOnly            bool         `json:"upgrade_only" gorm:"default:0"`              // 购买限制: 需要当前有有效订阅(仅用于升级/加购)
+	Status                 StatusCode   `json:"status" gorm:"default:1;index"`              // 状态: 1启用 2禁用
+	SortOrder              int          `json:"sort_order" gorm:"default:0"`                // 排序
+	Prices                 []*PlanPrice `json:"prices,omitempty" gorm:"foreignKey:PlanId"`  // 其他币种价格
+	Currency               string       `json:"currency,omitempty" gorm:"-"`                // 展示币种(接口计算返回)
+	LocalPrice             int64        `json:"local_price,omitempty" gorm:"-"`             // 展示币种价格(接口计算返回)
+	AmountDisplay          string       `json:"amount_display,omitempty" gorm:"-"`          // 按请求语言格式化的价格(接口计算返回)
+	PublicId               string       `json:"public_id,omitempty" gorm:"-"`               // 对外标识(接口计算返回)
+
+	// Visibility 可见范围: public/groups/hidden, 为空视为 public
+	Visibility    string                `json:"visibility" gorm:"size:16;default:'public'"`
+	VisibleGroups custom_types.AutoJson `json:"visible_groups" gorm:"type:text" swaggertype:"array,integer"` // visibility 为 groups 时可见的用户组ID
+	Features      custom_types.AutoJson `json:"features" gorm:"type:text" swaggertype:"array,object"`        // 定价页展示的功能项 []PlanFeature
+	TimeModel
+}
+
+// PlanPrice 套餐多币种价格
+type PlanPrice struct {
+	IdModel
+	PlanId   uint   `json:"plan_id" gorm:"uniqueIndex:idx_plan_currency;not null"`         // 套餐ID
+	Currency string `json:"currency" gorm:"uniqueIndex:idx_plan_currency;size:3;not null"` // 币种
+	Price    int64  `json:"price" gorm:"not null"`                                         // 价格(最小货币单位,如分)
 	TimeModel
 }
 
@@ -53,23 +140,52 @@ type SubscriptionPlanList struct {
 // Order 支付订单
 type Order struct {
 	IdModel
-	UserId        uint                  `json:"user_id" gorm:"index;not null"`            // 用户ID
-	PlanId        uint                  `json:"plan_id" gorm:"index;not null"`            // 套餐ID
-	OutTradeNo    string                `json:"out_trade_no" gorm:"uniqueIndex;not null"` // 业务订单号
-	TradeNo       string                `json:"trade_no" gorm:"index"`                    // 平台订单号
-	Subject       string                `json:"subject" gorm:"not null"`                  // 订单标题
-	Amount        int64                 `json:"amount" gorm:"not null"`                   // 金额(分)
-	AmountYuan    string                `json:"amount_yuan" gorm:"not null"`              // 金额(元字符串,用于对账)
-	Status        int                   `json:"status" gorm:"default:0;index"`            // 状态: 0待支付 1已支付 2已退款 3已关闭
-	PaySubmitAt   int64                 `json:"pay_submit_at" gorm:"default:0"`           // 最近一次发起支付时间(秒)
-	PaidAt        int64                 `json:"paid_at" gorm:"default:0"`                 // 支付时间
-	RefundedAt    int64                 `json:"refunded_at" gorm:"default:0"`             // 退款时间
-	NotifyPayload string                `json:"notify_payload" gorm:"type:text"`          // 回调原始数据
-	PayURL        string                `json:"pay_url,omitempty" gorm:"-"`               // 支付跳转URL(接口计算返回)
-	User          *User                 `json:"user,omitempty" gorm:"foreignKey:UserId"`
-	Plan          *SubscriptionPlan     `json:"plan,omitempty" gorm:"foreignKey:PlanId"`
-	CreatedAt     custom_types.AutoTime `json:"created_at" gorm:"type:timestamp;index"`
-	UpdatedAt     custom_types.AutoTime `json:"updated_at" gorm:"type:timestamp;"`
+	UserId            uint                  `json:"user_id" gorm:"index;not null"`              // 用户ID
+	PlanId            uint                  `json:"plan_id" gorm:"index;not null"`              // 套餐ID
+	OutTradeNo        string                `json:"out_trade_no" gorm:"uniqueIndex;not null"`   // 业务订单号
+	TradeNo           string                `json:"trade_no" gorm:"index"`                      // 平台订单号
+	Subject           string                `json:"subject" gorm:"not null"`                    // 订单标题
+	Amount            int64                 `json:"amount" gorm:"not null"`                     // 金额(分)
+	AmountYuan        string                `json:"amount_yuan" gorm:"not null"`                // 金额(元字符串,用于对账)
+	Currency          string                `json:"currency" gorm:"size:3;default:'CNY'"`       // 币种
+	Status            int                   `json:"status" gorm:"default:0;index"`              // 状态: 0待支付 1已支付 2已退款 3已关闭
+	PayMethod         string                `json:"pay_method" gorm:"size:16;default:'online'"` // 支付方式: online/manual
+	PayType           string                `json:"pay_type" gorm:"size:16;default:''"`         // 在线支付渠道, 为空时由网关收银台选择
+	QRCode            string                `json:"-" gorm:"type:text"`                         // 扫码支付内容(API 下单返回), 重复请求时复用, 避免网关重复建单
+	PaySubmitAt       int64                 `json:"pay_submit_at" gorm:"default:0"`             // 最近一次发起支付时间(秒)
+	HoldUntil         int64                 `json:"hold_until" gorm:"default:0"`                // 管理员延长保留至(秒), 此前不会自动关闭
+	ExpireAt          int64                 `json:"expire_at,omitempty" gorm:"-"`               // 待支付订单自动关闭时间(接口计算返回)
+	PaidAt            int64                 `json:"paid_at" gorm:"default:0"`                   // 支付时间
+	RefundedAt        int64                 `json:"refunded_at" gorm:"default:0"`               // 退款时间
+	RefundedAmount    int64                 `json:"refunded_amount" gorm:"default:0"`           // 已退款金额(分)
+	BonusDays         int                   `json:"bonus_days" gorm:"default:0"`                // 首购赠送天数(入账时确定)
+	ProductId         uint                  `json:"product_id" gorm:"default:0;index"`          // 一次性商品ID, 商品订单的 PlanId 为 0
+	FulfillmentStatus int                   `json:"fulfillment_status" gorm:"default:0;index"`  // 商品履约状态
+	FulfillmentNote   string                `json:"fulfillment_note" gorm:"type:text"`          // 履约备注
+	FulfilledAt       int64                 `json:"fulfilled_at" gorm:"default:0"`              // 履约完成时间
+	NotifyPayload     string                `json:"notify_payload" gorm:"type:text"`            // 回调原始数据
+	PayURL            string                `json:"pay_url,omitempty" gorm:"-"`                 // 支付跳转URL(接口计算返回)
+	AmountDisplay     string                `json:"amount_display,omitempty" gorm:"-"`          // 按请求语言格式化的金额(接口计算返回)
+	PaidAtDisplay     string                `json:"paid_at_display,omitempty" gorm:"-"`         // 按请求时区格式化的支付时间(接口计算返回)
+	PublicId          string                `json:"public_id,omitempty" gorm:"-"`               // 对外标识(接口计算返回)
+	PlanPublicId      string                `json:"plan_public_id,omitempty" gorm:"-"`          // 套餐对外标识(接口计算返回)
+	User              *User                 `json:"user,omitempty" gorm:"foreignKey:UserId"`
+	Plan              *SubscriptionPlan     `json:"plan,omitempty" gorm:"foreignKey:PlanId"`
+	Product           *Product              `json:"product,omitempty" gorm:"foreignKey:ProductId"`
+	Refunds           []*Refund             `json:"refunds,omitempty" gorm:"foreignKey:OrderId"`
+	Proofs            []*PaymentProof       `json:"proofs,omitempty" gorm:"foreignKey:OrderId"`          // 线下转账付款凭证
+	RefundRequests    []*RefundRequest      `json:"refund_requests,omitempty" gorm:"foreignKey:OrderId"` // 退款申请及审批记录
+	Notes             []*AdminNote          `json:"notes,omitempty" gorm:"-"`                            // 内部备注(仅管理后台详情返回)
+	CreatedAt         custom_types.AutoTime `json:"created_at" gorm:"type:timestamp;index"`
+	UpdatedAt         custom_types.AutoTime `json:"updated_at" gorm:"type:timestamp;"`
+
+	// 税费(payment.tax 启用时下单确定), Amount 为实付总额(含税)
+	TaxCountry   string  `json:"tax_country" gorm:"size:8;default:''"` // 适用税率的国家/地区
+	TaxRate      float64 `json:"tax_rate" gorm:"default:0"`            // 税率(百分比)
+	TaxAmount    int64   `json:"tax_amount" gorm:"default:0"`          // 税额(分)
+	TaxInclusive bool    `json:"tax_inclusive" gorm:"default:0"`       // 价格是否含税
+
+	CreatedBy uint `json:"created_by" gorm:"default:0"` // 管理员代下单时为操作者ID, 用户下单为 0
 }
 
 type OrderList struct {
@@ -77,20 +193,110 @@ type OrderList struct {
 	Pagination
 }
 
+// Localize 按请求语言/时区填充展示字段
+func (o *Order) Localize(l *utils.Locale) {
+	o.AmountDisplay = l.Money(o.Amount, o.Total().Currency)
+	o.PaidAtDisplay = l.Time(o.PaidAt)
+	if o.Plan != nil {
+		o.Plan.Localize(l)
+	}
+}
+
+// Localize 按请求语言/时区填充展示字段
+func (s *UserSubscription) Localize(l *utils.Locale) {
+	s.StartAtDisplay = l.Time(s.StartAt)
+	s.ExpireAtDisplay = l.Time(s.ExpireAt)
+	if s.Plan != nil {
+		s.Plan.Localize(l)
+	}
+	if s.LastOrder != nil {
+		s.LastOrder.Localize(l)
+	}
+}
+
+// Total 订单金额
+func (o *Order) Total() Money {
+	return NewMoney(o.Amount, o.Currency)
+}
+
+// Refunded 已退款金额
+func (o *Order) Refunded() Money {
+	return NewMoney(o.RefundedAmount, o.Currency)
+}
+
+// Refundable 剩余可退金额
+func (o *Order) Refundable() Money {
+	return NewMoney(o.Amount-o.RefundedAmount, o.Currency)
+}
+
+// Refund 退款记录(一个订单可多次部分退款)
+type Refund struct {
+	IdModel
+	OrderId       uint                  `json:"order_id" gorm:"index;not null"` // 订单ID
+	UserId        uint                  `json:"user_id" gorm:"index;not null"`  // 用户ID
+	Amount        int64                 `json:"amount" gorm:"not null"`         // 退款金额(分)
+	AmountYuan    string                `json:"amount_yuan" gorm:"not null"`    // 退款金额(元字符串)
+	Currency      string                `json:"currency" gorm:"size:3;default:'CNY'"`
+	Reason        string                `json:"reason" gorm:"default:''"`        // 退款原因
+	OperatorId    uint                  `json:"operator_id" gorm:"default:0"`    // 操作管理员ID
+	DeductSeconds int64                 `json:"deduct_seconds" gorm:"default:0"` // 按比例扣减的订阅时长(秒)
+	CreatedAt     custom_types.AutoTime `json:"created_at" gorm:"type:timestamp;"`
+	UpdatedAt     custom_types.AutoTime `json:"updated_at" gorm:"type:timestamp;"`
+
+	// 网关推送的退款/拒付
+	Source          string `json:"source" gorm:"size:16;default:''"`       // 发起方: admin 管理员, notify 网关推送
+	GatewayRefundNo string `json:"gateway_refund_no" gorm:"size:64;index"` // 网关退款单号, 用于推送幂等
+}
+
+// Total 退款金额
+func (r *Refund) Total() Money {
+	return NewMoney(r.Amount, r.Currency)
+}
+
 // UserSubscription 用户订阅
 type UserSubscription struct {
 	IdModel
-	UserId      uint                  `json:"user_id" gorm:"uniqueIndex;not null"` // 用户ID(一用户一条)
-	PlanId      uint                  `json:"plan_id" gorm:"index;not null"`       // 当前套餐ID
-	LastOrderId uint                  `json:"last_order_id" gorm:"index"`          // 最近订单ID
-	StartAt     int64                 `json:"start_at" gorm:"not null"`            // 开始时间
-	ExpireAt    int64                 `json:"expire_at" gorm:"not null;index"`     // 过期时间
-	Status      int                   `json:"status" gorm:"default:1;index"`       // 状态: 1有效 2已过期 3已取消
-	User        *User                 `json:"user,omitempty" gorm:"foreignKey:UserId"`
-	Plan        *SubscriptionPlan     `json:"plan,omitempty" gorm:"foreignKey:PlanId"`
-	LastOrder   *Order                `json:"last_order,omitempty" gorm:"foreignKey:LastOrderId"`
-	CreatedAt   custom_types.AutoTime `json:"created_at" gorm:"type:timestamp;"`
-	UpdatedAt   custom_types.AutoTime `json:"updated_at" gorm:"type:timestamp;"`
+	UserId            uint                  `json:"user_id" gorm:"uniqueIndex;not null"`  // 用户ID(一用户一条)
+	PlanId            uint                  `json:"plan_id" gorm:"index;not null"`        // 当前套餐ID
+	LastOrderId       uint                  `json:"last_order_id" gorm:"index"`           // 最近订单ID
+	StartAt           int64                 `json:"start_at" gorm:"not null"`             // 开始时间
+	ExpireAt          int64                 `json:"expire_at" gorm:"not null;index"`      // 过期时间
+	StartAtDisplay    string                `json:"start_at_display,omitempty" gorm:"-"`  // 按请求时区格式化(接口计算返回)
+	ExpireAtDisplay   string                `json:"expire_at_display,omitempty" gorm:"-"` // 按请求时区格式化(接口计算返回)
+	PublicId          string                `json:"public_id,omitempty" gorm:"-"`         // 对外标识(接口计算返回)
+	PlanPublicId      string                `json:"plan_public_id,omitempty" gorm:"-"`
+	LastOrderPublicId string                `json:"last_order_public_id,omitempty" gorm:"-"`
+	Status            int                   `json:"status" gorm:"default:1;index"` // 状态: 1有效 2已过期 3已取消 4已暂停
+	User              *User                 `json:"user,omitempty" gorm:"foreignKey:UserId"`
+	Plan              *SubscriptionPlan     `json:"plan,omitempty" gorm:"foreignKey:PlanId"`
+	LastOrder         *Order                `json:"last_order,omitempty" gorm:"foreignKey:LastOrderId"`
+	Notes             []*AdminNote          `json:"notes,omitempty" gorm:"-"` // 内部备注(仅管理后台详情返回)
+	CreatedAt         custom_types.AutoTime `json:"created_at" gorm:"type:timestamp;"`
+	UpdatedAt         custom_types.AutoTime `json:"updated_at" gorm:"type:timestamp;"`
+
+	// 暂停期间 ExpireAt 为暂停时间, 剩余时长记录在 PauseRemaining, 恢复时顺延
+	PausedAt       int64  `json:"paused_at" gorm:"default:0"`              // 暂停时间
+	PauseUntil     int64  `json:"pause_until" gorm:"default:0"`            // 自动恢复时间, 0 为需手动恢复
+	PauseRemaining int64  `json:"pause_remaining" gorm:"default:0"`        // 冻结的剩余时长(秒)
+	PauseSource    string `json:"pause_source" gorm:"size:16;default:''"`  // 暂停发起方: admin / user
+	PauseReason    string `json:"pause_reason" gorm:"size:255;default:''"` // 暂停原因
+
+	// 预约在当前周期结束时切换的套餐(降级), 由到期任务应用
+	PendingPlanId uint              `json:"pending_plan_id" gorm:"default:0;index"`
+	PendingPlan   *SubscriptionPlan `json:"pending_plan,omitempty" gorm:"foreignKey:PendingPlanId"`
+}
+
+// ResumeExpireAt 暂停的订阅在 now 恢复后的到期时间
+func (s *UserSubscription) ResumeExpireAt(now int64) int64 {
+	return now + s.PauseRemaining
+}
+
+// GrantBatchResult 批量赠送中单个用户的结果
+type GrantBatchResult struct {
+	UserId   uint   `json:"user_id"`
+	Success  bool   `json:"success"`
+	Error    string `json:"error,omitempty"`     // 失败原因
+	ExpireAt int64  `json:"expire_at,omitempty"` // 赠送后的到期时间
 }
 
 type UserSubscriptionList struct {
@@ -98,88 +304,208 @@ type UserSubscriptionList struct {
 	Pagination
 }
 
+// SubscriptionSimulation 某一时刻的订阅有效性模拟结果(只读, 用于排查"未到期就被断开"等争议)
+type SubscriptionSimulation struct {
+	UserId         uint                         `json:"user_id"`
+	At             int64                        `json:"at"`              // 模拟时刻
+	Now            int64                        `json:"now"`             // 服务器当前时间
+	PaymentEnabled bool                         `json:"payment_enabled"` // 未启用支付时不检查订阅
+	IsAdmin        bool                         `json:"is_admin"`        // 管理员免检查
+	Entitled       bool                         `json:"entitled"`        // 按当前订阅记录, 该时刻是否允许使用
+	StoredActive   bool                         `json:"stored_active"`   // 当前订阅记录在该时刻是否有效(IsSubscriptionActive 规则)
+	StoredReason   string                       `json:"stored_reason"`   // active/no_subscription/expired/canceled
+	Subscription   *UserSubscription            `json:"subscription,omitempty"`
+	ReplayActive   bool                         `json:"replay_active"`    // 按订单/退款/兑换记录重放, 该时刻是否有效
+	ReplayExpireAt int64                        `json:"replay_expire_at"` // 重放得到的该时刻的到期时间
+	Timeline       []*SubscriptionTimelineEntry `json:"timeline"`         // 截至该时刻参与重放的记录
+	Notes          []string                     `json:"notes,omitempty"`
+}
+
+// 一致性检查问题类型
+const (
+	ConsistencyPaidNoSubscription  = "paid_no_subscription" // 已支付订单未体现在订阅中
+	ConsistencyMissingOrder        = "subscription_missing_order"
+	ConsistencyMissingPlan         = "subscription_missing_plan"
+	ConsistencyDuplicatePending    = "duplicate_pending_order" // 同一用户/套餐/币种存在多笔待支付订单
+	ConsistencyAmountMismatch      = "amount_mismatch"         // amount 与 amount_yuan 快照不一致
+	ConsistencyRefundMismatch      = "refund_mismatch"         // refunded_amount 与退款记录合计不一致
+	ConsistencyRefundExceedsAmount = "refund_exceeds_amount"
+)
+
+// ConsistencyIssue 一致性检查发现的问题
+type ConsistencyIssue struct {
+	Type     string `json:"type"`
+	RefType  string `json:"ref_type"` // order/subscription
+	RefId    uint   `json:"ref_id"`
+	UserId   uint   `json:"user_id"`
+	Detail   string `json:"detail"`
+	Fixable  bool   `json:"fixable"`       // 是否支持自动修复
+	Fix      string `json:"fix,omitempty"` // 修复动作说明
+	Fixed    bool   `json:"fixed"`         // 本次是否已修复
+	FixError string `json:"fix_error,omitempty"`
+}
+
+// ConsistencyReport 订单/订阅一致性检查报告
+type ConsistencyReport struct {
+	CheckedAt int64               `json:"checked_at"`
+	Repair    bool                `json:"repair"`
+	Summary   map[string]int      `json:"summary"` // 按问题类型计数
+	Fixed     int                 `json:"fixed"`
+	Issues    []*ConsistencyIssue `json:"issues"`
+}
+
+// SubscriptionTimelineEntry 重放时间线条目
+type SubscriptionTimelineEntry struct {
+	At       int64  `json:"at"`
+	Kind     string `json:"kind"` // order_paid/refund/redeem
+	RefId    uint   `json:"ref_id"`
+	Detail   string `json:"detail"`
+	ExpireAt int64  `json:"expire_at"` // 应用该记录后的到期时间
+}
+
+// RevenueStat 按币种汇总的收入(已扣除退款)
+type RevenueStat struct {
+	Currency string `json:"currency"`
+	Amount   int64  `json:"amount"` // 金额(分)
+	Orders   int64  `json:"orders"` // 订单数
+	Tax      int64  `json:"tax"`    // 其中的税额(分), 全额退款的订单不计, 部分退款不按比例扣减
+}
+
+// PaymentDashboard 支付与订阅统计
+type PaymentDashboard struct {
+	RevenueToday      []*RevenueStat `json:"revenue_today"`
+	Revenue7d         []*RevenueStat `json:"revenue_7d"`
+	Revenue30d        []*RevenueStat `json:"revenue_30d"`
+	OrdersByStatus    map[int]int64  `json:"orders_by_status"`
+	ActiveSubs        int64          `json:"active_subscriptions"`
+	ExpiredSubs       int64          `json:"expired_subscriptions"`
+	CanceledSubs      int64          `json:"canceled_subscriptions"`
+	PausedSubs        int64          `json:"paused_subscriptions"`
+	NewSubscribers7d  int64          `json:"new_subscribers_7d"`
+	NewSubscribers30d int64          `json:"new_subscribers_30d"`
+	Churned30d        int64          `json:"churned_30d"`    // 近30天到期或取消且未续费的订阅数
+	ChurnRate30d      float64        `json:"churn_rate_30d"` // churned / (active + churned)
+}
+
 // PriceYuan 返回元为单位的价格字符串
 func (p *SubscriptionPlan) PriceYuan() string {
-	return FenToYuan(p.Price)
+	return NewMoney(p.Price, "").String()
 }
 
-// FenToYuan 分转元(避免浮点精度问题)
-func FenToYuan(fen int64) string {
-	sign := ""
-	if fen < 0 {
-		sign = "-"
-		fen = -fen
+// Localize 按请求语言填充展示价格, 已按币种本地化(LocalizePlans)时使用展示币种价格
+func (p *SubscriptionPlan) Localize(l *utils.Locale) {
+	if p.Currency != "" {
+		p.AmountDisplay = l.Money(p.LocalPrice, p.Currency)
+		return
 	}
-	return fmt.Sprintf("%s%d.%02d", sign, fen/100, fen%100)
+	p.AmountDisplay = l.Money(p.Price, SupportedCurrencies[0])
 }
 
-// YuanToFen 元转分(字符串严格解析,避免浮点精度问题)
-func YuanToFen(yuan string) (int64, error) {
-	s := strings.TrimSpace(yuan)
-	if s == "" {
-		return 0, errors.New("invalid money")
-	}
-	// 去除正号
-	s = strings.TrimPrefix(s, "+")
-	// 不支持负数
-	if strings.HasPrefix(s, "-") {
-		return 0, errors.New("invalid money: negative")
+// PlanPeriod 返回套餐周期, Period 为空或无效时使用 PeriodUnit/PeriodCount
+func (p *SubscriptionPlan) PlanPeriod() Period {
+	if p.Period != "" {
+		if period, err := ParsePeriod(p.Period); err == nil {
+			return period
+		}
 	}
+	return PeriodOf(p.PeriodUnit, p.PeriodCount)
+}
 
-	parts := strings.SplitN(s, ".", 3)
-	if len(parts) == 0 || len(parts) > 2 {
-		return 0, errors.New("invalid money format")
+// GroupIds 可见的用户组ID
+func (p *SubscriptionPlan) GroupIds() []uint {
+	var ids []uint
+	if len(p.VisibleGroups) > 0 {
+		_ = json.Unmarshal(p.VisibleGroups, &ids)
 	}
+	return ids
+}
 
-	intPart := parts[0]
-	fracPart := ""
-	if len(parts) == 2 {
-		fracPart = parts[1]
+// SetGroupIds 设置可见的用户组ID
+func (p *SubscriptionPlan) SetGroupIds(ids []uint) {
+	if ids == nil {
+		ids = []uint{}
 	}
+	data, _ := json.Marshal(ids)
+	p.VisibleGroups = data
+}
 
-	if intPart == "" {
-		intPart = "0"
-	}
+// PlanFeature 套餐功能项, 用于定价页对比, value 可以是布尔值、数字或文字
+type PlanFeature struct {
+	Key   string      `json:"key"`
+	Name  string      `json:"name,omitempty"`
+	Value interface{} `json:"value"`
+}
 
-	// 处理小数部分
-	switch len(fracPart) {
-	case 0:
-		fracPart = "00"
-	case 1:
-		fracPart += "0"
-	case 2:
-		// OK
-	default:
-		return 0, errors.New("invalid money: too many decimal places")
+// FeatureList 套餐的自定义功能项
+func (p *SubscriptionPlan) FeatureList() []PlanFeature {
+	var list []PlanFeature
+	if len(p.Features) > 0 {
+		_ = json.Unmarshal(p.Features, &list)
 	}
+	return list
+}
 
-	// 验证是否全为数字
-	if !isAllDigits(intPart) || !isAllDigits(fracPart) {
-		return 0, errors.New("invalid money: non-digit characters")
+// SetFeatures 设置自定义功能项
+func (p *SubscriptionPlan) SetFeatures(list []PlanFeature) {
+	if list == nil {
+		list = []PlanFeature{}
 	}
+	data, _ := json.Marshal(list)
+	p.Features = data
+}
 
-	whole, err := strconv.ParseInt(intPart, 10, 64)
-	if err != nil || whole < 0 {
-		return 0, errors.New("invalid money: integer part")
+// Purchasable 指定用户组的用户能否购买(hidden 套餐持链接即可购买)
+func (p *SubscriptionPlan) Purchasable(groupId uint) bool {
+	if p.Visibility != PlanVisibilityGroups {
+		return true
 	}
-	cents, err := strconv.ParseInt(fracPart, 10, 64)
-	if err != nil || cents < 0 || cents > 99 {
-		return 0, errors.New("invalid money: decimal part")
+	for _, id := range p.GroupIds() {
+		if id == groupId {
+			return true
+		}
 	}
+	return false
+}
+
+// Listed 是否在指定用户组的套餐列表中展示
+func (p *SubscriptionPlan) Listed(groupId uint) bool {
+	return p.Visibility != PlanVisibilityHidden && p.Purchasable(groupId)
+}
 
-	// 溢出检查
-	const maxInt64 = int64(^uint64(0) >> 1)
-	if whole > (maxInt64-cents)/100 {
-		return 0, errors.New("invalid money: overflow")
+// PriceIn 返回指定币种的价格, 基础币种直接使用 Price
+func (p *SubscriptionPlan) PriceIn(currency string) (Money, bool) {
+	if currency == SupportedCurrencies[0] {
+		return NewMoney(p.Price, currency), true
 	}
-	return whole*100 + cents, nil
+	for _, pp := range p.Prices {
+		if pp != nil && pp.Currency == currency {
+			return NewMoney(pp.Price, currency), true
+		}
+	}
+	return Money{}, false
 }
 
-func isAllDigits(s string) bool {
-	for _, ch := range s {
-		if ch < '0' || ch > '9' {
-			return false
+// IsSupportedCurrency 是否为支持的币种
+func IsSupportedCurrency(currency string) bool {
+	for _, c := range SupportedCurrencies {
+		if c == currency {
+			return true
 		}
 	}
-	return true
+	return false
+}
+
+// FenToYuan 分转元(基础币种)
+//
+// Deprecated: 使用 NewMoney(fen, currency).String()
+func FenToYuan(fen int64) string {
+	return NewMoney(fen, "").String()
+}
+
+// YuanToFen 元转分(基础币种)
+//
+// Deprecated: 使用 ParseMoney(yuan, currency)
+func YuanToFen(yuan string) (int64, error) {
+	m, err := ParseMoney(yuan, "")
+	return m.Amount, err
 }
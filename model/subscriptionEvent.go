@@ -0,0 +1,73 @@
+package model
+
+import "github.com/lejianwen/rustdesk-api/v2/model/custom_types"
+
+// 订阅变更类型
+const (
+	SubscriptionEventCreated   = "created"   // 首次开通
+	SubscriptionEventRenewed   = "renewed"   // 付费续期/重新开通
+	SubscriptionEventGranted   = "granted"   // 管理员赠送或兑换码
+	SubscriptionEventCancelled = "cancelled" // 管理员取消
+	SubscriptionEventRefunded  = "refunded"  // 退款扣减时长
+	SubscriptionEventExpired   = "expired"   // 到期
+	SubscriptionEventMigrated  = "migrated"  // 套餐归档, 迁移到继任套餐
+	SubscriptionEventPaused    = "paused"    // 暂停, 冻结剩余时长
+	SubscriptionEventResumed   = "resumed"   // 恢复, 到期时间顺延
+	SubscriptionEventScheduled = "scheduled" // 预约到期后变更套餐(或取消预约)
+	SubscriptionEventChanged   = "changed"   // 到期时切换到预约的套餐
+)
+
+// 订阅变更来源
+const (
+	SubscriptionSourceNotify   = "notify"   // 支付回调/主动对账
+	SubscriptionSourceAdmin    = "admin"    // 管理后台操作
+	SubscriptionSourceJob      = "job"      // 定时任务
+	SubscriptionSourceUser     = "user"     // 用户操作(免费套餐、兑换码)
+	SubscriptionSourceReferral = "referral" // 推荐奖励
+)
+
+// SubscriptionEvent 订阅变更记录, 每次状态或到期时间变化写入一条, 与变更在同一事务内
+type SubscriptionEvent struct {
+	IdModel
+	UserId         uint                  `json:"user_id" gorm:"default:0;not null;index"`
+	SubscriptionId uint                  `json:"subscription_id" gorm:"default:0;not null;index"`
+	Type           string                `json:"type" gorm:"size:16;default:'';not null;index"`
+	Source         string                `json:"source" gorm:"size:16;default:'';not null"`
+	ActorId        uint                  `json:"actor_id" gorm:"default:0;not null"` // 操作的管理员或用户, 0 表示系统
+	PlanId         uint                  `json:"plan_id" gorm:"default:0;not null"`
+	OrderId        uint                  `json:"order_id" gorm:"default:0;not null"`
+	StatusBefore   int                   `json:"status_before" gorm:"default:0;not null"`
+	StatusAfter    int                   `json:"status_after" gorm:"default:0;not null"`
+	ExpireBefore   int64                 `json:"expire_before" gorm:"default:0;not null"`
+	ExpireAfter    int64                 `json:"expire_after" gorm:"default:0;not null"`
+	Metadata       custom_types.AutoJson `json:"metadata" gorm:"type:text" swaggertype:"object"`
+	// 对外标识(接口计算返回)
+	SubscriptionPublicId string `json:"subscription_public_id,omitempty" gorm:"-"`
+	PlanPublicId         string `json:"plan_public_id,omitempty" gorm:"-"`
+	OrderPublicId        string `json:"order_public_id,omitempty" gorm:"-"`
+	TimeModel
+}
+
+// 续期异常类型
+const (
+	SubscriptionAnomalyHorizon = "horizon" // 到期时间超出上限
+	SubscriptionAnomalyRate    = "rate"    // 短时间内续期次数过多
+)
+
+// SubscriptionAnomaly 续期异常, 用于告警(不落库; 放行的异常记录在变更记录的 metadata 中)
+type SubscriptionAnomaly struct {
+	Kind       string `json:"kind"`
+	Action     string `json:"action"` // reject/flag
+	UserId     uint   `json:"user_id"`
+	PlanId     uint   `json:"plan_id"`
+	OrderId    uint   `json:"order_id"`
+	Source     string `json:"source"`
+	ExpireAt   int64  `json:"expire_at"` // 本次续期后的到期时间
+	Recent     int64  `json:"recent"`    // 最近一分钟内的开通/续期次数(含本次)
+	DetectedAt int64  `json:"detected_at"`
+}
+
+type SubscriptionEventList struct {
+	SubscriptionEvents []*SubscriptionEvent `json:"list"`
+	Pagination
+}
@@ -0,0 +1,37 @@
+package model
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestEnabledPayTypes(t *testing.T) {
+	got := EnabledPayTypes([]string{" WXPAY", "alipay", "epay", "wxpay", "", "card"})
+	want := []string{PayTypeWxpay, PayTypeAlipay}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("EnabledPayTypes = %v, want %v", got, want)
+	}
+	if got := EnabledPayTypes(nil); len(got) != 0 {
+		t.Errorf("EnabledPayTypes(nil) = %v, want empty", got)
+	}
+}
+
+func TestPlanVisibility(t *testing.T) {
+	public := &SubscriptionPlan{}
+	hidden := &SubscriptionPlan{Visibility: PlanVisibilityHidden}
+	groups := &SubscriptionPlan{Visibility: PlanVisibilityGroups}
+	groups.SetGroupIds([]uint{2, 5})
+
+	if !public.Listed(0) || !public.Purchasable(0) {
+		t.Error("public plan should be listed and purchasable")
+	}
+	if hidden.Listed(1) || !hidden.Purchasable(1) {
+		t.Error("hidden plan should be purchasable by link but not listed")
+	}
+	if !groups.Listed(5) || !groups.Purchasable(2) {
+		t.Error("group plan should be visible to its groups")
+	}
+	if groups.Listed(3) || groups.Purchasable(3) {
+		t.Error("group plan should not be visible to other groups")
+	}
+}
@@ -23,10 +23,88 @@ type PaymentConfig struct {
 	Key       string `json:"key"`
 	NotifyURL string `json:"notify_url"`
 	ReturnURL string `json:"return_url"`
-	Timeout   int    `json:"timeout"` // 秒
+	// RedirectURL 同步跳转页确认支付结果后跳转的页面(管理后台/用户中心), 为空时跳转到 /_admin/#/my/subscription
+	RedirectURL string `json:"redirect_url"`
+	Timeout     int    `json:"timeout"` // 秒
+	// PayTypes 允许用户选择的支付渠道, 为空时不传渠道, 由网关收银台选择
+	PayTypes []string `json:"pay_types"`
+	// Sandbox 测试模式, 允许管理员模拟网关回调验证套餐/周期/权益, 上线前应关闭
+	Sandbox bool `json:"sandbox"`
 }
 
-// 支付配置 key 常量
+// SMTP 加密方式
 const (
-	SettingKeyPaymentConfig = "payment.epay.config"
+	EmailEncryptionNone     = "none"     // 明文
+	EmailEncryptionStartTLS = "starttls" // STARTTLS(通常 587 端口)
+	EmailEncryptionTLS      = "tls"      // 隐式 TLS(通常 465 端口)
 )
+
+// EmailConfig SMTP 邮件配置（用于JSON序列化）
+type EmailConfig struct {
+	Enable     bool   `json:"enable"`
+	Host       string `json:"host"`
+	Port       int    `json:"port"`
+	Username   string `json:"username"`
+	Password   string `json:"password"`
+	From       string `json:"from"`       // 发件人地址, 为空时使用 Username
+	FromName   string `json:"from_name"`  // 发件人名称
+	Encryption string `json:"encryption"` // none/starttls/tls
+}
+
+// 存储驱动
+const (
+	StorageDriverLocal = "local"
+	StorageDriverS3    = "s3"
+)
+
+// StorageConfig 文件存储配置（用于JSON序列化）
+type StorageConfig struct {
+	Driver      string `json:"driver"` // local/s3
+	LocalDir    string `json:"local_dir"`
+	UrlExpire   int    `json:"url_expire"` // 临时下载地址有效期(秒)
+	S3Endpoint  string `json:"s3_endpoint"`
+	S3Region    string `json:"s3_region"`
+	S3Bucket    string `json:"s3_bucket"`
+	S3AccessKey string `json:"s3_access_key"`
+	S3SecretKey string `json:"s3_secret_key"`
+	S3PathStyle bool   `json:"s3_path_style"`
+	S3Prefix    string `json:"s3_prefix"`
+}
+
+// 配置 key 常量
+const (
+	SettingKeyPaymentConfig  = "payment.epay.config"
+	SettingKeyEmailConfig    = "email.smtp.config"
+	SettingKeyStorageConfig  = "storage.config"
+	SettingKeyStorageSignKey = "storage.sign_key" // 本地存储下载地址签名密钥, 首次使用时生成
+)
+
+// 通用设置的值类型
+const (
+	SettingTypeString   = "string"
+	SettingTypeInt      = "int"
+	SettingTypeFloat    = "float"
+	SettingTypeBool     = "bool"
+	SettingTypeDuration = "duration" // 如 30m、72h
+)
+
+// SettingDefinition 通用设置定义, 由各模块注册后即可通过 /api/admin/settings 读写, 无需单独的接口
+type SettingDefinition struct {
+	Key         string                  `json:"key"`
+	Type        string                  `json:"type"`
+	Group       string                  `json:"group"`
+	Description string                  `json:"description"`
+	Default     interface{}             `json:"default"`
+	Secret      bool                    `json:"secret"`            // 加密保存, 读取时遮蔽
+	Options     []string                `json:"options,omitempty"` // 字符串类型的可选值
+	Min         *float64                `json:"min,omitempty"`     // 数值类型的范围
+	Max         *float64                `json:"max,omitempty"`
+	Validate    func(interface{}) error `json:"-"` // 额外校验, 参数为转换后的值
+}
+
+// SettingItem 设置项及当前值
+type SettingItem struct {
+	*SettingDefinition
+	Value     interface{} `json:"value"`
+	IsDefault bool        `json:"is_default"` // 未保存过, 使用默认值
+}
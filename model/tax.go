@@ -0,0 +1,28 @@
+package model
+
+import "math"
+
+// ComputeTax 按税率(百分比, 如 19 表示 19%)计算税额, 结果四舍五入到最小货币单位
+// inclusive 为 true 时 amount 已含税, 总额不变; 否则总额为 amount 加税额
+func ComputeTax(amount int64, rate float64, inclusive bool) (tax, total int64) {
+	if amount <= 0 || rate <= 0 {
+		return 0, amount
+	}
+	r := rate / 100
+	if inclusive {
+		net := math.Round(float64(amount) / (1 + r))
+		return amount - int64(net), amount
+	}
+	tax = int64(math.Round(float64(amount) * r))
+	return tax, amount + tax
+}
+
+// Tax 订单税额
+func (o *Order) Tax() Money {
+	return NewMoney(o.TaxAmount, o.Currency)
+}
+
+// Net 订单不含税金额
+func (o *Order) Net() Money {
+	return NewMoney(o.Amount-o.TaxAmount, o.Currency)
+}
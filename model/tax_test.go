@@ -0,0 +1,25 @@
+package model
+
+import "testing"
+
+func TestComputeTax(t *testing.T) {
+	cases := []struct {
+		amount    int64
+		rate      float64
+		inclusive bool
+		tax       int64
+		total     int64
+	}{
+		{1190, 19, true, 190, 1190},
+		{1000, 19, false, 190, 1190},
+		{999, 7.7, false, 77, 1076},
+		{1000, 0, false, 0, 1000},
+		{0, 20, false, 0, 0},
+	}
+	for _, tc := range cases {
+		tax, total := ComputeTax(tc.amount, tc.rate, tc.inclusive)
+		if tax != tc.tax || total != tc.total {
+			t.Errorf("ComputeTax(%d, %v, %v) = %d, %d; want %d, %d", tc.amount, tc.rate, tc.inclusive, tax, total, tc.tax, tc.total)
+		}
+	}
+}
@@ -4,14 +4,18 @@ type User struct {
 	IdModel
 	Username string `json:"username" gorm:"default:'';not null;uniqueIndex"`
 	Email    string `json:"email" gorm:"default:'';not null;index"`
+	// EmailVerifiedAt 邮箱验证时间, 0 表示未验证; 邮箱变更后重置
+	EmailVerifiedAt int64 `json:"email_verified_at" gorm:"default:0;not null"`
 	// Email	string     	`json:"email" `
-	Password string     `json:"-" gorm:"default:'';not null;"`
-	Nickname string     `json:"nickname" gorm:"default:'';not null;"`
-	Avatar   string     `json:"avatar" gorm:"default:'';not null;"`
-	GroupId  uint       `json:"group_id" gorm:"default:0;not null;index"`
-	IsAdmin  *bool      `json:"is_admin" gorm:"default:0;not null;"`
-	Status   StatusCode `json:"status" gorm:"default:1;not null;"`
-	Remark   string     `json:"remark" gorm:"default:'';not null;"`
+	Password string `json:"-" gorm:"default:'';not null;"`
+	Nickname string `json:"nickname" gorm:"default:'';not null;"`
+	Avatar   string `json:"avatar" gorm:"default:'';not null;"`
+	GroupId  uint   `json:"group_id" gorm:"default:0;not null;index"`
+	IsAdmin  *bool  `json:"is_admin" gorm:"default:0;not null;"`
+	// AdminRole 管理员角色: super_admin/finance/support, 为空表示超级管理员
+	AdminRole string     `json:"admin_role" gorm:"size:32;default:'';not null"`
+	Status    StatusCode `json:"status" gorm:"default:1;not null;"`
+	Remark    string     `json:"remark" gorm:"default:'';not null;"`
 	TimeModel
 }
 
@@ -30,6 +34,6 @@ type UserList struct {
 }
 
 var UserRouteNames = []string{
-	"MyTagList", "MyAddressBookList", "MyInfo", "MyAddressBookCollection", "MyPeer", "MyShareRecordList", "MyLoginLog", "MySubscription", "MyOrders",
+	"MyTagList", "MyAddressBookList", "MyInfo", "MyAddressBookCollection", "MyPeer", "MyShareRecordList", "MyLoginLog", "MySubscription", "MyOrders", "MyConnPermission",
 }
 var AdminRouteNames = []string{"*"}
@@ -2,11 +2,20 @@ package model
 
 type UserToken struct {
 	IdModel
-	UserId     uint   `json:"user_id" gorm:"default:0;not null;index"`
-	DeviceUuid string `json:"device_uuid" gorm:"default:'';omitempty;"`
-	DeviceId   string `json:"device_id" gorm:"default:'';omitempty;"`
-	Token      string `json:"token" gorm:"default:'';not null;index"`
-	ExpiredAt  int64  `json:"expired_at" gorm:"default:0;not null;"`
+	UserId       uint   `json:"user_id" gorm:"default:0;not null;index"`
+	DeviceUuid   string `json:"device_uuid" gorm:"default:'';omitempty;"`
+	DeviceId     string `json:"device_id" gorm:"default:'';omitempty;"`
+	Token        string `json:"token" gorm:"default:'';not null;index"`
+	ExpiredAt    int64  `json:"expired_at" gorm:"default:0;not null;"`
+	Client       string `json:"client" gorm:"default:'';not null;"`   //webadmin,webclient,app
+	Platform     string `json:"platform" gorm:"default:'';not null;"` //windows,linux,mac,android,ios
+	Ip           string `json:"ip" gorm:"default:'';not null;"`
+	LastActiveAt int64  `json:"last_active_at" gorm:"default:0;not null;index"`
+	// 刷新令牌只保存 sha256, 明文仅在签发和轮换时返回
+	RefreshTokenHash string `json:"-" gorm:"size:64;default:'';not null;index"`
+	RefreshExpiredAt int64  `json:"refresh_expired_at" gorm:"default:0;not null;"`
+	RefreshToken     string `json:"-" gorm:"-"`
+	User             *User  `json:"user,omitempty" gorm:"foreignKey:UserId"`
 	TimeModel
 }
 
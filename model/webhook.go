@@ -0,0 +1,90 @@
+package model
+
+import "strings"
+
+// 外发 webhook 事件
+const (
+	WebhookEventOrderPaid             = "order.paid"
+	WebhookEventOrderRefunded         = "order.refunded"
+	WebhookEventSubscriptionActivated = "subscription.activated"
+	WebhookEventSubscriptionExpired   = "subscription.expired"
+	WebhookEventRelayAbuse            = "relay.abuse_detected"
+	WebhookEventUserBanned            = "user.banned"
+	WebhookEventSubscriptionAnomaly   = "subscription.anomaly"
+	WebhookEventProductPurchased      = "product.purchased"
+	WebhookEventProductFulfilled      = "product.fulfilled"
+	WebhookEventSubscriptionExpiring  = "subscription.expiring"
+	WebhookEventSubscriptionLapsed    = "subscription.lapsed"
+)
+
+var WebhookEvents = []string{
+	WebhookEventOrderPaid,
+	WebhookEventOrderRefunded,
+	WebhookEventSubscriptionActivated,
+	WebhookEventSubscriptionExpired,
+	WebhookEventRelayAbuse,
+	WebhookEventUserBanned,
+	WebhookEventSubscriptionAnomaly,
+	WebhookEventProductPurchased,
+	WebhookEventProductFulfilled,
+	WebhookEventSubscriptionExpiring,
+	WebhookEventSubscriptionLapsed,
+}
+
+// 投递状态
+const (
+	WebhookDeliveryPending = 0 // 待投递/等待重试
+	WebhookDeliverySuccess = 1 // 成功
+	WebhookDeliveryFailed  = 2 // 重试耗尽
+)
+
+// WebhookEndpoint 外发 webhook 接收地址
+type WebhookEndpoint struct {
+	IdModel
+	Name   string     `json:"name" gorm:"default:'';not null;"`
+	Url    string     `json:"url" gorm:"default:'';not null;"`
+	Secret string     `json:"secret" gorm:"default:'';not null;"`     // 签名密钥
+	Events string     `json:"events" gorm:"default:'';not null;"`     // 订阅的事件,逗号分隔,为空表示全部
+	Status StatusCode `json:"status" gorm:"default:1;not null;index"` // 1启用 2禁用
+	TimeModel
+}
+
+type WebhookEndpointList struct {
+	WebhookEndpoints []*WebhookEndpoint `json:"list"`
+	Pagination
+}
+
+// Subscribes 是否订阅了该事件
+func (e *WebhookEndpoint) Subscribes(event string) bool {
+	if strings.TrimSpace(e.Events) == "" {
+		return true
+	}
+	for _, ev := range strings.Split(e.Events, ",") {
+		if strings.TrimSpace(ev) == event {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookDelivery 外发 webhook 投递记录
+type WebhookDelivery struct {
+	IdModel
+	EndpointId   uint   `json:"endpoint_id" gorm:"default:0;not null;index"`
+	EventId      string `json:"event_id" gorm:"default:'';not null;index"`
+	Event        string `json:"event" gorm:"default:'';not null;"`
+	Payload      string `json:"payload" gorm:"type:text"`
+	Status       int    `json:"status" gorm:"default:0;not null;index"`
+	Attempts     int    `json:"attempts" gorm:"default:0;not null;"`
+	NextRetryAt  int64  `json:"next_retry_at" gorm:"default:0;not null;index"`
+	ResponseCode int    `json:"response_code" gorm:"default:0;not null;"`
+	LastError    string `json:"last_error" gorm:"type:text"`
+	ReplayOf     uint   `json:"replay_of" gorm:"default:0;not null"` // 管理员重新投递时为原投递记录ID
+	Test         bool   `json:"test" gorm:"default:0;not null"`      // 管理员发送的测试事件
+	TimeModel
+}
+
+type WebhookDeliveryList struct {
+	WebhookDeliveries []*WebhookDelivery `json:"list"`
+	Pagination
+}
@@ -2,8 +2,10 @@ package service
 
 import (
 	"encoding/json"
+	"errors"
 	"github.com/google/uuid"
 	"github.com/lejianwen/rustdesk-api/v2/model"
+	"github.com/lejianwen/rustdesk-api/v2/utils"
 	"gorm.io/gorm"
 	"strings"
 )
@@ -51,6 +53,45 @@ func (s *AddressBookService) AddAddressBook(ab *model.AddressBook) error {
 	return DB.Create(ab).Error
 }
 
+// mergeAddressBook 将 src 合并到已存在的 dst: src 中非空的字段覆盖, 标签取并集
+func mergeAddressBook(dst, src *model.AddressBook) {
+	for _, f := range []struct{ d, s *string }{
+		{&dst.Username, &src.Username}, {&dst.Password, &src.Password}, {&dst.Hostname, &src.Hostname},
+		{&dst.Alias, &src.Alias}, {&dst.Platform, &src.Platform}, {&dst.Hash, &src.Hash},
+		{&dst.RdpPort, &src.RdpPort}, {&dst.RdpUsername, &src.RdpUsername}, {&dst.LoginName, &src.LoginName},
+	} {
+		if *f.s != "" {
+			*f.d = *f.s
+		}
+	}
+	if src.ForceAlwaysRelay {
+		dst.ForceAlwaysRelay = true
+	}
+	var tags, add []string
+	_ = json.Unmarshal(dst.Tags, &tags)
+	_ = json.Unmarshal(src.Tags, &add)
+	for _, t := range add {
+		if !utils.InArray(t, tags) {
+			tags = append(tags, t)
+		}
+	}
+	if tags == nil {
+		tags = []string{}
+	}
+	dst.Tags, _ = json.Marshal(tags)
+}
+
+// MergeAddressBook 添加地址, 同一地址簿中已存在相同 id 时合并而不是重复添加
+// 团队地址簿中多个成员添加同一设备时只保留一条
+func (s *AddressBookService) MergeAddressBook(ab *model.AddressBook) error {
+	ex := s.InfoByUserIdAndIdAndCid(ab.UserId, ab.Id, ab.CollectionId)
+	if ex.RowId == 0 {
+		return s.AddAddressBook(ab)
+	}
+	mergeAddressBook(ex, ab)
+	return s.UpdateAll(ex)
+}
+
 // UpdateAddressBook
 func (s *AddressBookService) UpdateAddressBook(abs []*model.AddressBook, userId uint) error {
 	//比较peers和数据库中的数据，如果peers中的数据在数据库中不存在，则添加，如果存在则更新，如果数据库中的数据在peers中不存在，则删除
@@ -230,6 +271,37 @@ func (s *AddressBookService) CollectionReadRules(user *model.User) (res []*model
 	return
 }
 
+// teamMemberRule 组织成员对团队地址簿的权限: 所有者完全控制, 单独设置的个人规则优先于默认成员权限
+func teamMemberRule(c *model.AddressBookCollection, role string, personal int) int {
+	if role == model.OrgRoleOwner {
+		return model.ShareAddressBookRuleRuleFullControl
+	}
+	if personal > 0 {
+		return personal
+	}
+	return c.MemberRule
+}
+
+// TeamCollectionsForUser 用户所在组织的团队地址簿(不含自己拥有的)
+func (s *AddressBookService) TeamCollectionsForUser(user *model.User) (res []*model.AddressBookCollection) {
+	m := &model.OrganizationMember{}
+	DB.Where("user_id = ?", user.Id).First(m)
+	if m.Id == 0 {
+		return
+	}
+	DB.Where("org_id = ? and user_id <> ?", m.OrgId, user.Id).Find(&res)
+	return
+}
+
+// TeamCollectionOwner 团队地址簿的所有者, 即组织所有者
+func (s *AddressBookService) TeamCollectionOwner(orgId uint) (uint, error) {
+	org := AllService.SubscriptionService.GetOrganizationById(orgId)
+	if org.Id == 0 {
+		return 0, errors.New("OrganizationNotFound")
+	}
+	return org.OwnerId, nil
+}
+
 func (s *AddressBookService) UserMaxRule(user *model.User, uid, cid uint) int {
 	// ismy?
 	if user.Id == uid {
@@ -239,6 +311,17 @@ func (s *AddressBookService) UserMaxRule(user *model.User, uid, cid uint) int {
 	personalRules := &model.AddressBookCollectionRule{}
 	tx := DB.Model(personalRules)
 	tx.Where("type = ? and collection_id = ? and to_id = ?", model.ShareAddressBookRuleTypePersonal, cid, user.Id).First(&personalRules)
+	// 团队地址簿, 组织成员不再叠加群组规则
+	if cid > 0 {
+		c := s.CollectionInfoById(cid)
+		if c.OrgId > 0 {
+			m := &model.OrganizationMember{}
+			DB.Where("user_id = ? and org_id = ?", user.Id, c.OrgId).First(m)
+			if m.Id != 0 {
+				return teamMemberRule(c, m.Role, personalRules.Rule)
+			}
+		}
+	}
 	if personalRules.Id != 0 {
 		max = personalRules.Rule
 		if max == model.ShareAddressBookRuleRuleFullControl {
@@ -275,7 +358,7 @@ func (s *AddressBookService) CreateCollection(t *model.AddressBookCollection) er
 }
 
 func (s *AddressBookService) UpdateCollection(t *model.AddressBookCollection) error {
-	return DB.Model(t).Updates(t).Error
+	return DB.Model(t).Select("user_id", "name", "org_id", "member_rule").Updates(t).Error
 }
 
 func (s *AddressBookService) DeleteCollection(t *model.AddressBookCollection) error {
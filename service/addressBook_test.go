@@ -0,0 +1,52 @@
+package service
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/lejianwen/rustdesk-api/v2/model"
+)
+
+func TestTeamMemberRule(t *testing.T) {
+	c := &model.AddressBookCollection{OrgId: 1, MemberRule: model.ShareAddressBookRuleRuleRead}
+	cases := []struct {
+		name     string
+		role     string
+		personal int
+		want     int
+	}{
+		{"owner", model.OrgRoleOwner, 0, model.ShareAddressBookRuleRuleFullControl},
+		{"member default", model.OrgRoleMember, 0, model.ShareAddressBookRuleRuleRead},
+		{"member granted", model.OrgRoleMember, model.ShareAddressBookRuleRuleReadWrite, model.ShareAddressBookRuleRuleReadWrite},
+	}
+	for _, tc := range cases {
+		if got := teamMemberRule(c, tc.role, tc.personal); got != tc.want {
+			t.Errorf("%s: rule = %d, want %d", tc.name, got, tc.want)
+		}
+	}
+	// 单独设置的只读规则优先于更高的默认权限
+	c.MemberRule = model.ShareAddressBookRuleRuleFullControl
+	if got := teamMemberRule(c, model.OrgRoleMember, model.ShareAddressBookRuleRuleRead); got != model.ShareAddressBookRuleRuleRead {
+		t.Errorf("personal override: rule = %d", got)
+	}
+}
+
+func TestMergeAddressBook(t *testing.T) {
+	dst := &model.AddressBook{Id: "123", Alias: "office", Hostname: "pc1", Tags: []byte(`["a","b"]`)}
+	src := &model.AddressBook{Id: "123", Alias: "", Hostname: "pc1-new", Hash: "h", Tags: []byte(`["b","c"]`)}
+	mergeAddressBook(dst, src)
+	if dst.Alias != "office" || dst.Hostname != "pc1-new" || dst.Hash != "h" {
+		t.Fatalf("merged = %+v", dst)
+	}
+	var tags []string
+	_ = json.Unmarshal(dst.Tags, &tags)
+	if len(tags) != 3 || tags[0] != "a" || tags[2] != "c" {
+		t.Fatalf("tags = %v", tags)
+	}
+
+	empty := &model.AddressBook{}
+	mergeAddressBook(empty, &model.AddressBook{})
+	if string(empty.Tags) != "[]" {
+		t.Fatalf("empty tags = %s", empty.Tags)
+	}
+}
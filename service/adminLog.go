@@ -0,0 +1,185 @@
+package service
+
+import (
+	"time"
+
+	"github.com/lejianwen/rustdesk-api/v2/model"
+	"github.com/lejianwen/rustdesk-api/v2/model/custom_types"
+	"github.com/lejianwen/rustdesk-api/v2/utils"
+	"gorm.io/gorm"
+)
+
+type AdminLogService struct {
+}
+
+const (
+	// refundSpikeMinCount 近24小时退款次数达到该值才可能标记异常
+	refundSpikeMinCount = 3
+	// refundSpikeRatio 近24小时退款次数超过日均的倍数时标记异常
+	refundSpikeRatio = 3
+)
+
+// Create 创建
+func (as *AdminLogService) Create(l *model.AdminLog) error {
+	return DB.Create(l).Error
+}
+
+func (as *AdminLogService) List(page, pageSize uint, where func(tx *gorm.DB)) (res *model.AdminLogList) {
+	res = &model.AdminLogList{}
+	res.Page = int64(page)
+	res.PageSize = int64(pageSize)
+	tx := DB.Model(&model.AdminLog{})
+	if where != nil {
+		where(tx)
+	}
+	tx.Count(&res.Total)
+	tx.Scopes(Paginate(page, pageSize))
+	tx.Find(&res.AdminLogs)
+	return
+}
+
+// Audit 记录涉及资金的管理员操作, before/after 为修改前后的对象(为 nil 表示不存在), 其中的密钥字段会被遮蔽
+// 审计失败只记录日志, 不影响操作本身
+func (as *AdminLogService) Audit(operatorId uint, ip, action string, targetId uint, before, after interface{}) {
+	a := &model.AdminAudit{
+		UserId:   operatorId,
+		Action:   action,
+		TargetId: targetId,
+		Before:   auditValue(before),
+		After:    auditValue(after),
+		Ip:       ip,
+	}
+	if err := DB.Create(a).Error; err != nil {
+		Logger.Error("Create admin audit failed, action: ", action, " err: ", err)
+	}
+}
+
+func auditValue(v interface{}) custom_types.AutoJson {
+	if v == nil {
+		return custom_types.AutoJson("null")
+	}
+	data, err := utils.MaskSecretJSON(v)
+	if err != nil {
+		return custom_types.AutoJson("null")
+	}
+	return custom_types.AutoJson(data)
+}
+
+// AuditList 审计记录列表
+func (as *AdminLogService) AuditList(page, pageSize uint, where func(tx *gorm.DB)) (res *model.AdminAuditList) {
+	res = &model.AdminAuditList{}
+	res.Page = int64(page)
+	res.PageSize = int64(pageSize)
+	tx := DB.Model(&model.AdminAudit{})
+	if where != nil {
+		where(tx)
+	}
+	tx.Count(&res.Total)
+	tx.Scopes(Paginate(page, pageSize))
+	tx.Find(&res.AdminAudits)
+	return
+}
+
+// adminLogActions 需要单独统计的路由(c.FullPath), 其他写操作记为 other
+var adminLogActions = map[string]string{
+	"/api/admin/order/refund":         model.AdminLogActionRefund,
+	"/api/admin/order/refund/approve": model.AdminLogActionRefund,
+	"/api/admin/order/mark_paid":      model.AdminLogActionMarkPaid,
+	"/api/admin/payment/config":       model.AdminLogActionConfig,
+	"/api/admin/email/config":         model.AdminLogActionConfig,
+	"/api/admin/storage/config":       model.AdminLogActionConfig,
+	"/api/admin/settings/update":      model.AdminLogActionConfig,
+	"/api/admin/settings/reset":       model.AdminLogActionConfig,
+}
+
+// ActionFromPath 根据路由推断操作类型, path 为完整的路由模板(c.FullPath)
+// 只有成功的请求才归类, 失败的尝试(参数错误、无权限等)记为 other, 不计入退款等统计
+func (as *AdminLogService) ActionFromPath(path string, succeeded bool) string {
+	if a, ok := adminLogActions[path]; ok && succeeded {
+		return a
+	}
+	return model.AdminLogActionOther
+}
+
+// Activity 汇总最近 days 天内每个管理员的行为并标记异常
+func (as *AdminLogService) Activity(days int) []*model.AdminActivity {
+	if days < 2 {
+		days = 2
+	}
+	now := time.Now()
+	since := now.AddDate(0, 0, -days)
+	recentSince := now.Add(-24 * time.Hour)
+
+	type actionRow struct {
+		UserId uint
+		Action string
+		Cnt    int64
+	}
+	type countRow struct {
+		UserId uint
+		Cnt    int64
+	}
+
+	var actions []actionRow
+	DB.Model(&model.AdminLog{}).Select("user_id, action, count(*) as cnt").
+		Where("created_at >= ?", since).Group("user_id, action").Scan(&actions)
+
+	var recentRefunds []countRow
+	DB.Model(&model.AdminLog{}).Select("user_id, count(*) as cnt").
+		Where("created_at >= ? AND action = ?", recentSince, model.AdminLogActionRefund).
+		Group("user_id").Scan(&recentRefunds)
+
+	var logins []countRow
+	DB.Model(&model.LoginLog{}).Select("user_id, count(*) as cnt").
+		Where("created_at >= ? AND client = ?", since, model.LoginLogClientWebAdmin).
+		Group("user_id").Scan(&logins)
+
+	// 当前管理员 + 窗口内有过操作的用户(可能已被取消管理员)
+	ids := make([]uint, 0, len(actions))
+	for _, r := range actions {
+		ids = append(ids, r.UserId)
+	}
+	var users []*model.User
+	DB.Where("is_admin = ? OR id IN (?)", true, append(ids, 0)).Order("id asc").Find(&users)
+
+	res := make([]*model.AdminActivity, 0, len(users))
+	byId := make(map[uint]*model.AdminActivity, len(users))
+	for _, u := range users {
+		a := &model.AdminActivity{UserId: u.Id, Username: u.Username, IsAdmin: AllService.UserService.IsAdmin(u), Flags: []string{}}
+		byId[u.Id] = a
+		res = append(res, a)
+	}
+	for _, r := range actions {
+		a, ok := byId[r.UserId]
+		if !ok {
+			continue
+		}
+		a.Actions += r.Cnt
+		switch r.Action {
+		case model.AdminLogActionRefund:
+			a.Refunds += r.Cnt
+		case model.AdminLogActionMarkPaid:
+			a.MarkPaid += r.Cnt
+		case model.AdminLogActionConfig:
+			a.ConfigChanges += r.Cnt
+		}
+	}
+	for _, r := range recentRefunds {
+		if a, ok := byId[r.UserId]; ok {
+			a.RefundsLast24h = r.Cnt
+		}
+	}
+	for _, r := range logins {
+		if a, ok := byId[r.UserId]; ok {
+			a.Logins = r.Cnt
+		}
+	}
+
+	for _, a := range res {
+		a.RefundDailyAvg = float64(a.Refunds-a.RefundsLast24h) / float64(days-1)
+		if a.RefundsLast24h >= refundSpikeMinCount && float64(a.RefundsLast24h) > a.RefundDailyAvg*refundSpikeRatio {
+			a.Flags = append(a.Flags, model.AdminActivityFlagRefundSpike)
+		}
+	}
+	return res
+}
@@ -0,0 +1,31 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/lejianwen/rustdesk-api/v2/model"
+)
+
+func TestActionFromPath(t *testing.T) {
+	cases := []struct {
+		path      string
+		succeeded bool
+		want      string
+	}{
+		{"/api/admin/order/refund", true, model.AdminLogActionRefund},
+		{"/api/admin/order/refund/approve", true, model.AdminLogActionRefund},
+		{"/api/admin/order/refund", false, model.AdminLogActionOther},
+		{"/api/admin/order/refund/reject", true, model.AdminLogActionOther},
+		{"/api/admin/order/refund/requests", true, model.AdminLogActionOther},
+		{"/api/admin/order/mark_paid", true, model.AdminLogActionMarkPaid},
+		{"/api/admin/payment/config", true, model.AdminLogActionConfig},
+		{"/api/admin/payment/config/full", true, model.AdminLogActionOther},
+		{"", true, model.AdminLogActionOther},
+	}
+	as := &AdminLogService{}
+	for _, c := range cases {
+		if got := as.ActionFromPath(c.path, c.succeeded); got != c.want {
+			t.Errorf("ActionFromPath(%q, %v) = %s, want %s", c.path, c.succeeded, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package service
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/lejianwen/rustdesk-api/v2/model"
+	"gorm.io/gorm"
+)
+
+// adminNoteMaxLength 单条内部备注的最大长度(字符)
+const adminNoteMaxLength = 5000
+
+// ListNotes 订单/订阅的内部备注, 按时间正序
+func (ss *SubscriptionService) ListNotes(targetType string, targetId uint) []*model.AdminNote {
+	notes := make([]*model.AdminNote, 0)
+	DB.Where("target_type = ? AND target_id = ?", targetType, targetId).
+		Preload("Admin", func(tx *gorm.DB) *gorm.DB { return tx.Select("id", "username", "nickname") }).
+		Order("id ASC").Find(&notes)
+	return notes
+}
+
+// AddNote 添加内部备注, 对象需存在
+func (ss *SubscriptionService) AddNote(targetType string, targetId, adminId uint, content string) (*model.AdminNote, error) {
+	content = strings.TrimSpace(content)
+	if content == "" || len([]rune(content)) > adminNoteMaxLength {
+		return nil, errors.New("ParamsError")
+	}
+	switch targetType {
+	case model.AdminNoteTargetOrder:
+		if ss.GetOrderById(targetId).Id == 0 {
+			return nil, errors.New("OrderNotFound")
+		}
+	case model.AdminNoteTargetSubscription:
+		if ss.GetSubscriptionById(targetId).Id == 0 {
+			return nil, errors.New("ItemNotFound")
+		}
+	default:
+		return nil, errors.New("ParamsError")
+	}
+	n := &model.AdminNote{TargetType: targetType, TargetId: targetId, AdminId: adminId, Content: content}
+	if err := DB.Create(n).Error; err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+// DeleteNote 删除内部备注, 只能删除自己撰写的备注
+func (ss *SubscriptionService) DeleteNote(targetType string, id, adminId uint) (*model.AdminNote, error) {
+	n := &model.AdminNote{}
+	DB.Where("id = ? AND target_type = ?", id, targetType).First(n)
+	if n.Id == 0 {
+		return nil, errors.New("ItemNotFound")
+	}
+	if n.AdminId != adminId {
+		return nil, errors.New("NoAccess")
+	}
+	return n, DB.Delete(n).Error
+}
@@ -0,0 +1,112 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/lejianwen/rustdesk-api/v2/model"
+)
+
+const (
+	// ApiTokenPrefix 个人访问令牌明文前缀, 用于和登录令牌区分
+	ApiTokenPrefix = "rpat_"
+	// ApiTokenMaxPerUser 每个用户最多可创建的令牌数
+	ApiTokenMaxPerUser = 20
+	// ApiTokenMaxExpireDays 有效期上限
+	ApiTokenMaxExpireDays = 3650
+
+	apiTokenTouchPeriod = time.Minute // 使用记录写库间隔
+)
+
+var (
+	ErrApiTokenScopeInvalid  = errors.New("ApiTokenScopeInvalid")
+	ErrApiTokenLimitExceeded = errors.New("ApiTokenLimitExceeded")
+	ErrApiTokenExpireInvalid = errors.New("ApiTokenExpireInvalid")
+)
+
+// ApiTokenService 用户个人访问令牌
+type ApiTokenService struct {
+}
+
+// IsApiToken 按前缀判断是否为个人访问令牌
+func IsApiToken(token string) bool {
+	return strings.HasPrefix(token, ApiTokenPrefix)
+}
+
+func hashApiToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func (ats *ApiTokenService) InfoById(id uint) *model.ApiToken {
+	t := &model.ApiToken{}
+	DB.Where("id = ?", id).First(t)
+	return t
+}
+
+// ListByUser 用户的全部令牌, 按创建时间倒序
+func (ats *ApiTokenService) ListByUser(userId uint) []*model.ApiToken {
+	res := make([]*model.ApiToken, 0)
+	DB.Where("user_id = ?", userId).Order("id desc").Find(&res)
+	return res
+}
+
+// Create 校验并生成新令牌, 返回明文; expireDays 为 0 表示不过期
+func (ats *ApiTokenService) Create(userId uint, name string, scopes []string, expireDays int) (string, *model.ApiToken, error) {
+	scopes, unknown := model.NormalizeApiTokenScopes(scopes)
+	if unknown != "" || len(scopes) == 0 {
+		return "", nil, ErrApiTokenScopeInvalid
+	}
+	if expireDays < 0 || expireDays > ApiTokenMaxExpireDays {
+		return "", nil, ErrApiTokenExpireInvalid
+	}
+	var count int64
+	DB.Model(&model.ApiToken{}).Where("user_id = ?", userId).Count(&count)
+	if count >= ApiTokenMaxPerUser {
+		return "", nil, ErrApiTokenLimitExceeded
+	}
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", nil, err
+	}
+	plain := ApiTokenPrefix + hex.EncodeToString(b)
+	t := &model.ApiToken{
+		UserId:    userId,
+		Name:      name,
+		TokenHash: hashApiToken(plain),
+		Prefix:    plain[:12],
+		Scopes:    strings.Join(scopes, ","),
+	}
+	if expireDays > 0 {
+		t.ExpireAt = time.Now().AddDate(0, 0, expireDays).Unix()
+	}
+	if err := DB.Create(t).Error; err != nil {
+		return "", nil, err
+	}
+	return plain, t, nil
+}
+
+func (ats *ApiTokenService) Delete(t *model.ApiToken) error {
+	return DB.Delete(t).Error
+}
+
+// Verify 校验令牌, 返回令牌所属用户; 令牌无效或已过期时返回空用户
+func (ats *ApiTokenService) Verify(token, ip string) (*model.User, *model.ApiToken) {
+	t := &model.ApiToken{}
+	if !IsApiToken(token) {
+		return &model.User{}, t
+	}
+	DB.Where("token_hash = ?", hashApiToken(token)).First(t)
+	now := time.Now().Unix()
+	if t.Id == 0 || t.Expired(now) {
+		return &model.User{}, t
+	}
+	if now-t.LastUsedAt >= int64(apiTokenTouchPeriod.Seconds()) {
+		DB.Model(t).Updates(map[string]interface{}{"last_used_at": now, "last_used_ip": ip})
+	}
+	return AllService.UserService.InfoById(t.UserId), t
+}
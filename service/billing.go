@@ -0,0 +1,65 @@
+package service
+
+import (
+	"github.com/lejianwen/rustdesk-api/v2/model"
+)
+
+// billingRecentOrders 计费概览中返回的最近订单数
+const billingRecentOrders = 20
+
+// UserBilling 汇总用户的计费信息, 供管理后台客户页一次获取; 用户不存在时返回 nil
+func (ss *SubscriptionService) UserBilling(userId uint) *model.UserBilling {
+	user := AllService.UserService.InfoById(userId)
+	if user.Id == 0 {
+		return nil
+	}
+	res := &model.UserBilling{
+		User:         user,
+		Entitlements: AllService.EntitlementService.List(userId),
+		RelayTier:    AllService.EntitlementService.RelayTier(userId),
+		Totals: model.UserBillingTotals{
+			Paid:     map[string]int64{},
+			Refunded: map[string]int64{},
+		},
+	}
+	if sub := ss.GetUserSubscription(userId); sub.Id != 0 {
+		res.Subscription = sub
+		res.Active = ss.IsSubscriptionActive(userId)
+	}
+
+	DB.Where("user_id = ?", userId).Preload("Plan").Preload("Product").
+		Order("id DESC").Limit(billingRecentOrders).Find(&res.RecentOrders)
+	DB.Where("user_id = ?", userId).Order("id DESC").Find(&res.Refunds)
+	DB.Where("user_id = ?", userId).Order("id DESC").Find(&res.RefundRequests)
+
+	type sum struct {
+		Currency string
+		Total    int64
+		Count    int64
+	}
+	var paid []sum
+	DB.Model(&model.Order{}).Select("currency, SUM(amount) AS total, COUNT(*) AS count").
+		Where("user_id = ? AND paid_at > 0", userId).Group("currency").Scan(&paid)
+	for _, s := range paid {
+		res.Totals.Paid[s.Currency] = s.Total
+		res.Totals.PaidOrders += s.Count
+	}
+	for _, r := range res.Refunds {
+		res.Totals.Refunded[r.Currency] += r.Amount
+	}
+
+	flags := &res.Flags
+	flags.Banned = user.Status == model.COMMON_STATUS_DISABLED
+	flags.EmailVerified = AllService.EmailVerificationService.IsVerified(user)
+	for _, r := range res.RefundRequests {
+		if r.Status == model.RefundRequestPending {
+			flags.PendingRefundRequests++
+		}
+	}
+	DB.Model(&model.RelayIncident{}).Where("user_id = ?", userId).Count(&flags.RelayIncidents)
+	if flags.RelayIncidents > 0 {
+		DB.Model(&model.RelayIncident{}).Where("user_id = ?", userId).Select("MAX(last_at)").Scan(&flags.LastRelayIncidentAt)
+	}
+	DB.Model(&model.SubscriptionEvent{}).Where("user_id = ? AND metadata LIKE ?", userId, `%"anomaly"%`).Count(&flags.FlaggedExtensions)
+	return res
+}
@@ -0,0 +1,167 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/lejianwen/rustdesk-api/v2/model"
+	"github.com/lejianwen/rustdesk-api/v2/model/custom_types"
+	"github.com/lejianwen/rustdesk-api/v2/utils"
+	"gorm.io/gorm"
+)
+
+// BulkEditMax 单次批量编辑的记录数上限
+const BulkEditMax = 1000
+
+var errBulkRollback = errors.New("BulkEditRolledBack")
+
+// applyTags 在已有标签上添加和移除标签, 保持原顺序并去重
+func applyTags(tags custom_types.AutoJson, add, remove []string) custom_types.AutoJson {
+	var cur []string
+	_ = json.Unmarshal(tags, &cur)
+	res := make([]string, 0, len(cur)+len(add))
+	for _, t := range append(cur, add...) {
+		if t == "" || utils.InArray(t, remove) || utils.InArray(t, res) {
+			continue
+		}
+		res = append(res, t)
+	}
+	b, _ := json.Marshal(res)
+	return b
+}
+
+// bulkRowIds 需要处理的记录: rowIds 加上 aliases 中的记录, 去重
+func bulkRowIds(rowIds []uint, edit *model.BulkEdit) []uint {
+	ids := append([]uint{}, rowIds...)
+	for id := range edit.Aliases {
+		ids = append(ids, id)
+	}
+	return uniqueIds(ids)
+}
+
+// runBulk 在同一事务内逐条执行 fn, 每条使用 savepoint 单独回滚
+// Atomic 时任一失败回滚全部, 成功的记录标记为已回滚
+func runBulk(ids []uint, atomic bool, fn func(tx *gorm.DB, id uint) error) ([]*model.BulkEditResult, error) {
+	if len(ids) == 0 {
+		return nil, errors.New("BulkEditEmpty")
+	}
+	if len(ids) > BulkEditMax {
+		return nil, errors.New("BulkEditTooLarge")
+	}
+	results := make([]*model.BulkEditResult, 0, len(ids))
+	failed := false
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		for _, id := range ids {
+			r := &model.BulkEditResult{RowId: id}
+			results = append(results, r)
+			err := tx.Transaction(func(stx *gorm.DB) error {
+				return fn(stx, id)
+			})
+			if err != nil {
+				r.Error = err.Error()
+				failed = true
+				continue
+			}
+			r.Success = true
+		}
+		if atomic && failed {
+			return errBulkRollback
+		}
+		return nil
+	})
+	if err == errBulkRollback {
+		markRolledBack(results)
+		return results, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+func markRolledBack(results []*model.BulkEditResult) {
+	for _, r := range results {
+		if r.Success {
+			r.Success = false
+			r.Error = errBulkRollback.Error()
+		}
+	}
+}
+
+// BulkEdit 批量修改地址簿的标签、别名, 或移动到其他地址簿
+// 目标地址簿中已存在相同设备时合并到已有记录
+func (s *AddressBookService) BulkEdit(rowIds []uint, edit *model.BulkEdit) ([]*model.BulkEditResult, error) {
+	var target *model.AddressBookCollection
+	if edit.CollectionId != nil && *edit.CollectionId > 0 {
+		target = s.CollectionInfoById(*edit.CollectionId)
+		if target.Id == 0 {
+			return nil, errors.New("ItemNotFound")
+		}
+	}
+	results, err := runBulk(bulkRowIds(rowIds, edit), edit.Atomic, func(tx *gorm.DB, id uint) error {
+		ab := &model.AddressBook{}
+		tx.Where("row_id = ?", id).First(ab)
+		if ab.RowId == 0 {
+			return errors.New("ItemNotFound")
+		}
+		if len(edit.AddTags) > 0 || len(edit.RemoveTags) > 0 {
+			ab.Tags = applyTags(ab.Tags, edit.AddTags, edit.RemoveTags)
+		}
+		if alias, ok := edit.Aliases[id]; ok {
+			ab.Alias = alias
+		}
+		if edit.CollectionId != nil && *edit.CollectionId != ab.CollectionId {
+			ab.CollectionId = *edit.CollectionId
+			if target != nil {
+				ab.UserId = target.UserId
+			}
+			ex := &model.AddressBook{}
+			tx.Where("user_id = ? and id = ? and collection_id = ? and row_id <> ?", ab.UserId, ab.Id, ab.CollectionId, ab.RowId).First(ex)
+			if ex.RowId != 0 {
+				mergeAddressBook(ex, ab)
+				if err := tx.Model(ex).Select("*").Omit("created_at").Updates(ex).Error; err != nil {
+					return err
+				}
+				return tx.Delete(ab).Error
+			}
+		}
+		return tx.Model(ab).Select("*").Omit("created_at").Updates(ab).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	Logger.Info("Address book bulk edit, rows: ", len(results), " atomic: ", edit.Atomic)
+	return results, nil
+}
+
+// BulkEdit 批量修改设备别名或移动设备分组
+func (ps *PeerService) BulkEdit(rowIds []uint, edit *model.BulkEdit) ([]*model.BulkEditResult, error) {
+	if edit.GroupId != nil && *edit.GroupId > 0 {
+		if AllService.GroupService.DeviceGroupInfoById(*edit.GroupId).Id == 0 {
+			return nil, errors.New("ItemNotFound")
+		}
+	}
+	results, err := runBulk(bulkRowIds(rowIds, edit), edit.Atomic, func(tx *gorm.DB, id uint) error {
+		peer := &model.Peer{}
+		tx.Where("row_id = ?", id).First(peer)
+		if peer.RowId == 0 {
+			return errors.New("ItemNotFound")
+		}
+		data := map[string]interface{}{}
+		if alias, ok := edit.Aliases[id]; ok {
+			data["alias"] = alias
+		}
+		if edit.GroupId != nil {
+			data["group_id"] = *edit.GroupId
+		}
+		if len(data) == 0 {
+			return nil
+		}
+		return tx.Model(peer).Updates(data).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	Logger.Info("Peer bulk edit, rows: ", len(results), " atomic: ", edit.Atomic)
+	return results, nil
+}
@@ -0,0 +1,33 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/lejianwen/rustdesk-api/v2/model"
+)
+
+func TestApplyTags(t *testing.T) {
+	got := applyTags([]byte(`["a","b","c"]`), []string{"d", "a", ""}, []string{"b"})
+	if string(got) != `["a","c","d"]` {
+		t.Fatalf("tags = %s", got)
+	}
+	if got := applyTags(nil, nil, nil); string(got) != `[]` {
+		t.Fatalf("empty tags = %s", got)
+	}
+}
+
+func TestBulkRowIds(t *testing.T) {
+	edit := &model.BulkEdit{Aliases: map[uint]string{2: "x", 5: "y"}}
+	got := bulkRowIds([]uint{1, 2, 0}, edit)
+	if len(got) != 3 || got[0] != 1 || got[1] != 2 {
+		t.Fatalf("row ids = %v", got)
+	}
+}
+
+func TestMarkRolledBack(t *testing.T) {
+	results := []*model.BulkEditResult{{RowId: 1, Success: true}, {RowId: 2, Error: "ItemNotFound"}}
+	markRolledBack(results)
+	if results[0].Success || results[0].Error != "BulkEditRolledBack" || results[1].Error != "ItemNotFound" {
+		t.Fatalf("results = %+v %+v", results[0], results[1])
+	}
+}
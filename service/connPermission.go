@@ -0,0 +1,29 @@
+package service
+
+import (
+	"github.com/lejianwen/rustdesk-api/v2/model"
+)
+
+type ConnPermissionService struct {
+}
+
+// GetByUserId 获取用户连接权限预设, 未设置时返回全部放行的默认值
+func (cps *ConnPermissionService) GetByUserId(userId uint) *model.ConnPermission {
+	p := &model.ConnPermission{}
+	DB.Where("user_id = ?", userId).First(p)
+	if p.Id == 0 {
+		p.UserId = userId
+	}
+	return p
+}
+
+// Save 保存用户连接权限预设(不存在则创建)
+func (cps *ConnPermissionService) Save(p *model.ConnPermission) error {
+	existing := &model.ConnPermission{}
+	DB.Where("user_id = ?", p.UserId).First(existing)
+	if existing.Id == 0 {
+		return DB.Create(p).Error
+	}
+	p.Id = existing.Id
+	return DB.Model(existing).Select("view_only", "disable_file_transfer", "disable_clipboard", "disable_audio", "disable_terminal").Updates(p).Error
+}
@@ -0,0 +1,133 @@
+package service
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/lejianwen/rustdesk-api/v2/model"
+	"gorm.io/gorm"
+)
+
+// connectionMatchWindow 客户端上报与 relay 会话开始时间相差在该范围内时视为同一次连接
+const connectionMatchWindow = 120
+
+// connectionDuration 按上报时长或起止时间计算时长
+func connectionDuration(startedAt, endedAt, reported int64) int64 {
+	if reported > 0 {
+		return reported
+	}
+	if startedAt > 0 && endedAt > startedAt {
+		return endedAt - startedAt
+	}
+	return 0
+}
+
+// RecordRelayConnection relay 会话开始或结束时同步连接审计记录
+func (as *AuditService) RecordRelayConnection(s *model.RelaySession) {
+	l := &model.ConnectionLog{}
+	DB.Where("ref_key = ?", "relay:"+s.Uuid).First(l)
+	l.RefKey = "relay:" + s.Uuid
+	l.Mode = model.ConnectionModeRelay
+	l.PeerId, l.UserId = s.PeerId, s.UserId
+	l.TargetPeerId, l.TargetUserId = s.TargetPeerId, s.TargetUserId
+	l.StartedAt = s.StartedAt
+	if l.Id == 0 {
+		l.Country, l.City = as.peerLocation(s.PeerId)
+	}
+	if s.Status == model.RelaySessionEnded {
+		l.EndedAt = s.EndedAt
+		l.Duration = connectionDuration(s.StartedAt, s.EndedAt, s.Duration)
+		l.BytesUp, l.BytesDown = s.BytesUp, s.BytesDown
+	}
+	if err := DB.Save(l).Error; err != nil {
+		Logger.Error("Save connection log failed: ", err)
+	}
+}
+
+// RecordClientConnection 被控端上报连接建立, 合并到同一对设备进行中的 relay 记录, 否则记为直连
+func (as *AuditService) RecordClientConnection(ac *model.AuditConn) {
+	key := fmt.Sprintf("conn:%s:%d", ac.PeerId, ac.ConnId)
+	var n int64
+	if DB.Model(&model.ConnectionLog{}).Where("ref_key = ? OR conn_key = ?", key, key).Count(&n); n > 0 {
+		return
+	}
+	now := time.Now().Unix()
+	l := &model.ConnectionLog{}
+	DB.Where("mode = ? AND peer_id = ? AND target_peer_id = ? AND conn_key = '' AND ended_at = 0 AND started_at >= ?",
+		model.ConnectionModeRelay, ac.FromPeer, ac.PeerId, now-connectionMatchWindow).
+		Order("id DESC").First(l)
+	if l.Id > 0 {
+		updates := map[string]interface{}{"conn_key": key, "ip": ac.Ip, "conn_type": ac.Type}
+		if loc := AllService.GeoIpService.Locate(ac.Ip); loc.Country != "" {
+			updates["country"], updates["city"] = loc.Country, loc.City
+		}
+		DB.Model(l).Updates(updates)
+		return
+	}
+	l = &model.ConnectionLog{
+		RefKey:       key,
+		Mode:         model.ConnectionModeDirect,
+		PeerId:       ac.FromPeer,
+		TargetPeerId: ac.PeerId,
+		Ip:           ac.Ip,
+		ConnType:     ac.Type,
+		StartedAt:    now,
+	}
+	if ac.FromPeer != "" {
+		l.UserId = AllService.PeerService.FindById(ac.FromPeer).UserId
+	}
+	l.TargetUserId = AllService.PeerService.FindById(ac.PeerId).UserId
+	if loc := AllService.GeoIpService.Locate(ac.Ip); loc.Country != "" {
+		l.Country, l.City = loc.Country, loc.City
+	} else {
+		l.Country, l.City = as.peerLocation(ac.FromPeer)
+	}
+	if err := DB.Create(l).Error; err != nil {
+		// 重复上报
+		Logger.Debug("Create connection log skipped: ", err)
+	}
+}
+
+// peerLocation 设备最近一次心跳时的地理位置
+func (as *AuditService) peerLocation(peerId string) (string, string) {
+	if peerId == "" {
+		return "", ""
+	}
+	p := AllService.PeerService.FindById(peerId)
+	return p.Country, p.City
+}
+
+// CloseClientConnection 被控端上报连接关闭; relay 记录以 hbbr 上报的结束时间为准
+func (as *AuditService) CloseClientConnection(peerId string, connId int64) {
+	key := fmt.Sprintf("conn:%s:%d", peerId, connId)
+	l := &model.ConnectionLog{}
+	DB.Where("ref_key = ? AND mode = ?", key, model.ConnectionModeDirect).First(l)
+	if l.Id == 0 || l.EndedAt > 0 {
+		return
+	}
+	now := time.Now().Unix()
+	DB.Model(l).Updates(map[string]interface{}{"ended_at": now, "duration": connectionDuration(l.StartedAt, now, 0)})
+}
+
+func (as *AuditService) ConnectionLogList(page, pageSize uint, where func(tx *gorm.DB)) (res *model.ConnectionLogList) {
+	res = &model.ConnectionLogList{}
+	res.Page = int64(page)
+	res.PageSize = int64(pageSize)
+	tx := DB.Model(&model.ConnectionLog{})
+	if where != nil {
+		where(tx)
+	}
+	tx.Count(&res.Total)
+	tx.Scopes(Paginate(page, pageSize))
+	tx.Order("id DESC").Find(&res.ConnectionLogs)
+	return
+}
+
+// ExportConnectionLogs 按筛选条件分批导出连接审计记录, 按 id 倒序
+func (as *AuditService) ExportConnectionLogs(where func(tx *gorm.DB), fn func(logs []*model.ConnectionLog) error) error {
+	tx := DB.Model(&model.ConnectionLog{})
+	if where != nil {
+		where(tx)
+	}
+	return findDescInBatches(tx, exportBatchSize, func(l *model.ConnectionLog) uint { return l.Id }, fn)
+}
@@ -0,0 +1,19 @@
+package service
+
+import "testing"
+
+func TestConnectionDuration(t *testing.T) {
+	cases := []struct {
+		start, end, reported, want int64
+	}{
+		{100, 200, 0, 100},
+		{100, 200, 30, 30},
+		{0, 200, 0, 0},
+		{200, 100, 0, 0},
+	}
+	for _, c := range cases {
+		if got := connectionDuration(c.start, c.end, c.reported); got != c.want {
+			t.Errorf("%d-%d (%d): got %d", c.start, c.end, c.reported, got)
+		}
+	}
+}
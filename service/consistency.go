@@ -0,0 +1,236 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lejianwen/rustdesk-api/v2/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const consistencyLockKey = "subscription_consistency_audit"
+
+// AuditConsistency 检查订单与订阅数据的一致性
+// repair 为 true 时对可修复的问题执行修复, repairTypes 为空表示修复全部可修复类型
+func (ss *SubscriptionService) AuditConsistency(repair bool, repairTypes []string) *model.ConsistencyReport {
+	Lock.Lock(consistencyLockKey)
+	defer Lock.UnLock(consistencyLockKey)
+
+	report := &model.ConsistencyReport{
+		CheckedAt: time.Now().Unix(),
+		Repair:    repair,
+		Summary:   make(map[string]int),
+		Issues:    make([]*model.ConsistencyIssue, 0),
+	}
+	add := func(issue *model.ConsistencyIssue, fix func() error) {
+		report.Summary[issue.Type]++
+		report.Issues = append(report.Issues, issue)
+		if !repair || !issue.Fixable || fix == nil || !consistencyTypeSelected(repairTypes, issue.Type) {
+			return
+		}
+		if err := fix(); err != nil {
+			issue.FixError = err.Error()
+			return
+		}
+		issue.Fixed = true
+		report.Fixed++
+	}
+
+	ss.auditSubscriptions(add)
+	ss.auditDuplicatePending(add)
+	ss.auditOrderAmounts(add)
+
+	if report.Fixed > 0 {
+		Logger.Warn("Consistency audit repaired ", report.Fixed, " issues")
+	}
+	return report
+}
+
+func consistencyTypeSelected(types []string, t string) bool {
+	if len(types) == 0 {
+		return true
+	}
+	for _, v := range types {
+		if v == t {
+			return true
+		}
+	}
+	return false
+}
+
+// auditSubscriptions 检查已支付订单未入订阅、订阅指向不存在的订单/套餐
+func (ss *SubscriptionService) auditSubscriptions(add func(*model.ConsistencyIssue, func() error)) {
+	// 每个用户最近一笔已支付订单应为其订阅的 last_order_id
+	type latestPaid struct {
+		UserId  uint
+		OrderId uint
+	}
+	var latest []latestPaid
+	DB.Model(&model.Order{}).Select("user_id, max(id) as order_id").
+		Where("status = ? AND product_id = 0", model.OrderStatusPaid).Group("user_id").Scan(&latest)
+	latestByUser := make(map[uint]uint, len(latest))
+	for _, l := range latest {
+		latestByUser[l.UserId] = l.OrderId
+	}
+
+	subs := make(map[uint]*model.UserSubscription)
+	var list []*model.UserSubscription
+	DB.FindInBatches(&list, 500, func(tx *gorm.DB, batch int) error {
+		for _, sub := range list {
+			subs[sub.UserId] = sub
+		}
+		return nil
+	})
+
+	for userId, orderId := range latestByUser {
+		sub := subs[userId]
+		if sub != nil && sub.LastOrderId >= orderId {
+			continue
+		}
+		detail := fmt.Sprintf("latest paid order %d is not applied to subscription", orderId)
+		if sub == nil {
+			detail = fmt.Sprintf("user has paid order %d but no subscription", orderId)
+		}
+		oid := orderId
+		add(&model.ConsistencyIssue{
+			Type: model.ConsistencyPaidNoSubscription, RefType: "order", RefId: oid, UserId: userId,
+			Detail: detail, Fixable: true, Fix: "activate or extend subscription with the order",
+		}, func() error { return ss.repairApplyOrder(oid) })
+	}
+
+	for _, sub := range subs {
+		s := sub
+		if s.LastOrderId > 0 {
+			var n int64
+			DB.Model(&model.Order{}).Where("id = ?", s.LastOrderId).Count(&n)
+			if n == 0 {
+				newId := latestByUser[s.UserId]
+				add(&model.ConsistencyIssue{
+					Type: model.ConsistencyMissingOrder, RefType: "subscription", RefId: s.Id, UserId: s.UserId,
+					Detail:  fmt.Sprintf("last_order_id %d does not exist", s.LastOrderId),
+					Fixable: true, Fix: fmt.Sprintf("set last_order_id to %d", newId),
+				}, func() error {
+					return DB.Model(s).Update("last_order_id", newId).Error
+				})
+			}
+		}
+		var n int64
+		DB.Model(&model.SubscriptionPlan{}).Where("id = ?", s.PlanId).Count(&n)
+		if n == 0 {
+			issue := &model.ConsistencyIssue{
+				Type: model.ConsistencyMissingPlan, RefType: "subscription", RefId: s.Id, UserId: s.UserId,
+				Detail: fmt.Sprintf("plan %d does not exist", s.PlanId),
+			}
+			var fix func() error
+			order := &model.Order{}
+			if oid := latestByUser[s.UserId]; oid > 0 && DB.Where("id = ?", oid).First(order).Error == nil && order.PlanId != s.PlanId {
+				planId := order.PlanId
+				issue.Fixable = true
+				issue.Fix = fmt.Sprintf("set plan_id to %d from latest paid order", planId)
+				fix = func() error { return DB.Model(s).Update("plan_id", planId).Error }
+			}
+			add(issue, fix)
+		}
+	}
+}
+
+// repairApplyOrder 将已支付订单补记到订阅, 已被补记时跳过
+func (ss *SubscriptionService) repairApplyOrder(orderId uint) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		order := &model.Order{}
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", orderId).First(order).Error; err != nil {
+			return err
+		}
+		if order.Status != model.OrderStatusPaid {
+			return errors.New("order is not paid")
+		}
+		sub := &model.UserSubscription{}
+		if tx.Where("user_id = ?", order.UserId).First(sub).Error == nil && sub.LastOrderId >= order.Id {
+			return nil
+		}
+		return ss.activateOrExtendSubscription(tx, order.UserId, order.PlanId, order.Id, time.Now().Unix(),
+			subscriptionChange{Source: model.SubscriptionSourceAdmin, Metadata: map[string]interface{}{"repair": true}})
+	})
+}
+
+// auditDuplicatePending 检查同一用户/套餐/币种/支付方式/税务地区的多笔待支付订单, 修复时保留最新一笔
+// 有待审核付款凭证的订单不会被关闭
+func (ss *SubscriptionService) auditDuplicatePending(add func(*model.ConsistencyIssue, func() error)) {
+	type dup struct {
+		UserId     uint
+		PlanId     uint
+		Currency   string
+		PayMethod  string
+		TaxCountry string
+		Cnt        int64
+		MaxId      uint
+	}
+	var dups []dup
+	DB.Model(&model.Order{}).Select("user_id, plan_id, currency, pay_method, tax_country, count(*) as cnt, max(id) as max_id").
+		Where("status = ? AND product_id = 0", model.OrderStatusPending).
+		Group("user_id, plan_id, currency, pay_method, tax_country").Having("count(*) > 1").Scan(&dups)
+	for _, d := range dups {
+		d := d
+		add(&model.ConsistencyIssue{
+			Type: model.ConsistencyDuplicatePending, RefType: "order", RefId: d.MaxId, UserId: d.UserId,
+			Detail:  fmt.Sprintf("%d pending %s orders for plan %d (%s)", d.Cnt, d.PayMethod, d.PlanId, d.Currency),
+			Fixable: true, Fix: "close all but the newest pending order, keeping orders with proofs under review",
+		}, func() error {
+			proofs := DB.Model(&model.PaymentProof{}).Select("order_id").Where("status = ?", model.PaymentProofPending)
+			return DB.Model(&model.Order{}).
+				Where("user_id = ? AND plan_id = ? AND currency = ? AND pay_method = ? AND tax_country = ? AND status = ? AND id < ?",
+					d.UserId, d.PlanId, d.Currency, d.PayMethod, d.TaxCountry, model.OrderStatusPending, d.MaxId).
+				Where("id NOT IN (?)", proofs).
+				Update("status", model.OrderStatusClosed).Error
+		})
+	}
+}
+
+// auditOrderAmounts 检查金额快照和退款合计
+func (ss *SubscriptionService) auditOrderAmounts(add func(*model.ConsistencyIssue, func() error)) {
+	refundSums := make(map[uint]int64)
+	type refundSum struct {
+		OrderId uint
+		Amount  int64
+	}
+	var sums []refundSum
+	DB.Model(&model.Refund{}).Select("order_id, sum(amount) as amount").Group("order_id").Scan(&sums)
+	for _, s := range sums {
+		refundSums[s.OrderId] = s.Amount
+	}
+
+	var orders []*model.Order
+	DB.Select("id", "user_id", "amount", "amount_yuan", "currency", "refunded_amount", "status").
+		FindInBatches(&orders, 500, func(tx *gorm.DB, batch int) error {
+			for _, o := range orders {
+				o := o
+				if expected := o.Total().String(); o.AmountYuan != expected {
+					add(&model.ConsistencyIssue{
+						Type: model.ConsistencyAmountMismatch, RefType: "order", RefId: o.Id, UserId: o.UserId,
+						Detail:  fmt.Sprintf("amount %d but amount_yuan %q", o.Amount, o.AmountYuan),
+						Fixable: true, Fix: fmt.Sprintf("set amount_yuan to %s", expected),
+					}, func() error {
+						return DB.Model(&model.Order{}).Where("id = ?", o.Id).Update("amount_yuan", expected).Error
+					})
+				}
+				if sum, ok := refundSums[o.Id]; ok && sum != o.RefundedAmount {
+					add(&model.ConsistencyIssue{
+						Type: model.ConsistencyRefundMismatch, RefType: "order", RefId: o.Id, UserId: o.UserId,
+						Detail:  fmt.Sprintf("refunded_amount %d but refund records total %d", o.RefundedAmount, sum),
+						Fixable: true, Fix: fmt.Sprintf("set refunded_amount to %d", sum),
+					}, func() error {
+						return DB.Model(&model.Order{}).Where("id = ?", o.Id).Update("refunded_amount", sum).Error
+					})
+				}
+				if o.RefundedAmount > o.Amount {
+					add(&model.ConsistencyIssue{
+						Type: model.ConsistencyRefundExceedsAmount, RefType: "order", RefId: o.Id, UserId: o.UserId,
+						Detail: fmt.Sprintf("refunded_amount %d exceeds amount %d", o.RefundedAmount, o.Amount),
+					}, nil)
+				}
+			}
+			return nil
+		})
+}
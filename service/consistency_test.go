@@ -0,0 +1,45 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/lejianwen/rustdesk-api/v2/model"
+)
+
+func TestAuditDuplicatePending(t *testing.T) {
+	newTestService(t, []interface{}{&model.Order{}, &model.PaymentProof{}})
+	orders := []*model.Order{
+		{UserId: 1, PlanId: 1, OutTradeNo: "o1", Currency: "CNY", PayMethod: model.PayMethodOnline},
+		{UserId: 1, PlanId: 1, OutTradeNo: "o2", Currency: "CNY", PayMethod: model.PayMethodOnline},
+		{UserId: 1, PlanId: 1, OutTradeNo: "m1", Currency: "CNY", PayMethod: model.PayMethodManual}, // 凭证待审核
+		{UserId: 1, PlanId: 1, OutTradeNo: "m2", Currency: "CNY", PayMethod: model.PayMethodManual},
+		{UserId: 1, PlanId: 1, OutTradeNo: "t1", Currency: "CNY", PayMethod: model.PayMethodOnline, TaxCountry: "DE"},
+	}
+	for _, o := range orders {
+		if err := DB.Create(o).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	DB.Create(&model.PaymentProof{OrderId: orders[2].Id, UserId: 1, Status: model.PaymentProofPending})
+
+	var fixes []func() error
+	(&SubscriptionService{}).auditDuplicatePending(func(_ *model.ConsistencyIssue, fix func() error) {
+		fixes = append(fixes, fix)
+	})
+	if len(fixes) != 2 {
+		t.Fatalf("got %d issues, want online and manual groups", len(fixes))
+	}
+	for _, fix := range fixes {
+		if err := fix(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := map[string]int{"o1": model.OrderStatusClosed, "o2": model.OrderStatusPending, "m1": model.OrderStatusPending, "m2": model.OrderStatusPending, "t1": model.OrderStatusPending}
+	for no, status := range want {
+		o := &model.Order{}
+		DB.Where("out_trade_no = ?", no).First(o)
+		if o.Status != status {
+			t.Errorf("order %s status = %d, want %d", no, o.Status, status)
+		}
+	}
+}
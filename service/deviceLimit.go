@@ -0,0 +1,134 @@
+package service
+
+import (
+	"errors"
+	"time"
+
+	"github.com/lejianwen/rustdesk-api/v2/model"
+	"gorm.io/gorm"
+)
+
+// 超出套餐设备数时的处理方式
+const (
+	SettingDeviceLimitAction = "device.limit_action"
+	DeviceLimitReject        = "reject"  // 拒绝登录
+	DeviceLimitApprove       = "approve" // 提交管理员审批, 批准后可登录
+)
+
+// registerDeviceLimitSettings 注册设备数限制设置
+func (s *SystemSettingService) registerDeviceLimitSettings() {
+	s.RegisterSetting(&model.SettingDefinition{
+		Key:         SettingDeviceLimitAction,
+		Type:        model.SettingTypeString,
+		Group:       "device",
+		Description: "Action when a user binds more devices than the plan allows: reject, or queue for admin approval",
+		Default:     DeviceLimitReject,
+		Options:     []string{DeviceLimitReject, DeviceLimitApprove},
+	})
+}
+
+// deviceLimitExceeded 已绑定 bound 台设备时是否还能绑定新设备, limit 为 0 表示不限
+func deviceLimitExceeded(limit int, bound int64) bool {
+	return limit > 0 && bound >= int64(limit)
+}
+
+// CheckDeviceLimit 设备绑定到用户前检查套餐设备数, 已绑定到该用户的设备和管理员批准的设备不受限制
+// 超出时按设置拒绝, 或创建审批申请并返回 DeviceApprovalPending
+func (ps *PeerService) CheckDeviceLimit(uuid, peerId string, userId uint) error {
+	if uuid == "" || userId == 0 || !AllService.Payment().IsEnabled() {
+		return nil
+	}
+	if ps.FindByUserIdAndUuid(uuid, userId).RowId > 0 {
+		return nil
+	}
+	sub := AllService.Subscription().EffectiveSubscription(userId)
+	if sub.Plan == nil || sub.Plan.MaxDevices <= 0 || !AllService.Subscription().IsSubscriptionActive(userId) {
+		return nil
+	}
+	var bound int64
+	DB.Model(&model.Peer{}).Where("user_id = ?", userId).Count(&bound)
+	if !deviceLimitExceeded(sub.Plan.MaxDevices, bound) {
+		return nil
+	}
+	a := &model.DeviceApproval{}
+	DB.Where("user_id = ? AND uuid = ?", userId, uuid).First(a)
+	if a.Status == model.DeviceApprovalApproved {
+		return nil
+	}
+	if AllService.SystemSettingService.SettingString(SettingDeviceLimitAction) != DeviceLimitApprove {
+		return errors.New("DeviceLimitReached")
+	}
+	if a.Id == 0 {
+		a = &model.DeviceApproval{UserId: userId, Uuid: uuid, PeerId: peerId, Status: model.DeviceApprovalPending}
+		if err := DB.Create(a).Error; err != nil {
+			// 并发提交时唯一索引冲突, 已有申请
+			Logger.Debug("Create device approval skipped: ", err)
+		}
+	} else if a.Status == model.DeviceApprovalRejected {
+		return errors.New("DeviceLimitReached")
+	}
+	return errors.New("DeviceApprovalPending")
+}
+
+// DeactivateDevice 用户解绑自己的设备以释放设备数, 同时注销该设备的登录
+func (ps *PeerService) DeactivateDevice(rowId, userId uint) error {
+	peer := ps.InfoByRowId(rowId)
+	if peer.RowId == 0 || peer.UserId != userId {
+		return errors.New("ItemNotFound")
+	}
+	if err := DB.Model(peer).Update("user_id", 0).Error; err != nil {
+		return err
+	}
+	// 重新绑定需再次检查设备数, 释放占用的席位
+	DB.Where("user_id = ? AND uuid = ?", userId, peer.Uuid).Delete(&model.DeviceApproval{})
+	DB.Where("user_id = ? AND uuid = ?", userId, peer.Uuid).Delete(&model.DeviceSeat{})
+	AllService.EntitlementService.Invalidate(userId)
+	if peer.Uuid == "" {
+		return nil
+	}
+	return DB.Where("user_id = ? AND device_uuid = ?", userId, peer.Uuid).Delete(&model.UserToken{}).Error
+}
+
+func (ps *PeerService) ListDeviceApprovals(page, pageSize uint, where func(tx *gorm.DB)) (res *model.DeviceApprovalList) {
+	res = &model.DeviceApprovalList{}
+	res.Page = int64(page)
+	res.PageSize = int64(pageSize)
+	tx := DB.Model(&model.DeviceApproval{})
+	if where != nil {
+		where(tx)
+	}
+	tx.Count(&res.Total)
+	tx.Scopes(Paginate(page, pageSize))
+	tx.Preload("User").Order("id DESC").Find(&res.DeviceApprovals)
+	return
+}
+
+// ReviewDeviceApproval 审批待处理的设备绑定申请
+func (ps *PeerService) ReviewDeviceApproval(id uint, approve bool, reviewerId uint) (*model.DeviceApproval, error) {
+	a := &model.DeviceApproval{}
+	DB.Where("id = ?", id).First(a)
+	if a.Id == 0 {
+		return nil, errors.New("ItemNotFound")
+	}
+	if a.Status != model.DeviceApprovalPending {
+		return nil, errors.New("DeviceApprovalReviewed")
+	}
+	status := model.DeviceApprovalRejected
+	if approve {
+		status = model.DeviceApprovalApproved
+	}
+	now := time.Now().Unix()
+	res := DB.Model(a).Where("status = ?", model.DeviceApprovalPending).Updates(map[string]interface{}{
+		"status":      status,
+		"reviewer_id": reviewerId,
+		"reviewed_at": now,
+	})
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return nil, errors.New("DeviceApprovalReviewed")
+	}
+	a.Status, a.ReviewerId, a.ReviewedAt = status, reviewerId, now
+	return a, nil
+}
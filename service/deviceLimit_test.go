@@ -0,0 +1,97 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/lejianwen/rustdesk-api/v2/model"
+)
+
+func TestDeviceLimitExceeded(t *testing.T) {
+	cases := []struct {
+		limit int
+		bound int64
+		want  bool
+	}{
+		{0, 10, false},
+		{3, 2, false},
+		{3, 3, true},
+		{3, 5, true},
+	}
+	for _, c := range cases {
+		if got := deviceLimitExceeded(c.limit, c.bound); got != c.want {
+			t.Errorf("limit %d bound %d: got %v", c.limit, c.bound, got)
+		}
+	}
+}
+
+func errString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+func TestCheckDeviceLimit(t *testing.T) {
+	plan := &model.SubscriptionPlan{IdModel: model.IdModel{Id: 1}, MaxDevices: 2}
+	s := newTestService(t, []interface{}{&model.User{}, &model.Peer{}, &model.DeviceApproval{}, &model.SystemSetting{}},
+		WithPayment(enabledPayment{}), WithSubscription(planSubscription{plan: plan}))
+	ps := &PeerService{}
+	for _, uuid := range []string{"a", "b"} {
+		if err := DB.Create(&model.Peer{Id: "id-" + uuid, Uuid: uuid, UserId: 1}).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	approvals := func(uuid string) []*model.DeviceApproval {
+		res := make([]*model.DeviceApproval, 0)
+		DB.Where("user_id = ? AND uuid = ?", 1, uuid).Find(&res)
+		return res
+	}
+
+	// 已绑定的设备和未超出设备数的用户不受限制
+	if err := ps.CheckDeviceLimit("a", "id-a", 1); err != nil {
+		t.Errorf("bound device: %v", err)
+	}
+	if err := ps.CheckDeviceLimit("c", "id-c", 2); err != nil {
+		t.Errorf("user under limit: %v", err)
+	}
+
+	// 默认拒绝, 不创建审批申请
+	if err := ps.CheckDeviceLimit("c", "id-c", 1); errString(err) != "DeviceLimitReached" {
+		t.Errorf("reject mode: %v", err)
+	}
+	if len(approvals("c")) != 0 {
+		t.Error("reject mode should not queue an approval")
+	}
+
+	// 审批模式: 创建待审批申请, 重复登录不重复创建
+	if err := s.SystemSettingService.SetSetting(SettingDeviceLimitAction, DeviceLimitApprove); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := ps.CheckDeviceLimit("c", "id-c", 1); errString(err) != "DeviceApprovalPending" {
+			t.Errorf("approve mode: %v", err)
+		}
+	}
+	list := approvals("c")
+	if len(list) != 1 || list[0].Status != model.DeviceApprovalPending || list[0].PeerId != "id-c" {
+		t.Fatalf("approvals = %+v", list)
+	}
+
+	// 已拒绝的申请直接拒绝
+	DB.Create(&model.DeviceApproval{UserId: 1, Uuid: "d", Status: model.DeviceApprovalRejected})
+	if err := ps.CheckDeviceLimit("d", "id-d", 1); errString(err) != "DeviceLimitReached" {
+		t.Errorf("rejected approval: %v", err)
+	}
+
+	// 已批准的设备放行, 改回拒绝模式后仍然有效
+	DB.Model(list[0]).Update("status", model.DeviceApprovalApproved)
+	if err := ps.CheckDeviceLimit("c", "id-c", 1); err != nil {
+		t.Errorf("approved device: %v", err)
+	}
+	if err := s.SystemSettingService.SetSetting(SettingDeviceLimitAction, DeviceLimitReject); err != nil {
+		t.Fatal(err)
+	}
+	if err := ps.CheckDeviceLimit("c", "id-c", 1); err != nil {
+		t.Errorf("approved device in reject mode: %v", err)
+	}
+}
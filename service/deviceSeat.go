@@ -0,0 +1,86 @@
+package service
+
+import (
+	"errors"
+
+	"github.com/lejianwen/rustdesk-api/v2/model"
+)
+
+// seatPlan 用户当前有效且按设备席位授权的套餐, 不需要席位时返回 nil
+func (ss *SubscriptionService) seatPlan(userId uint) *model.SubscriptionPlan {
+	if userId == 0 || !AllService.Payment().IsEnabled() {
+		return nil
+	}
+	sub := ss.EffectiveSubscription(userId)
+	if sub.Plan == nil || !sub.Plan.DeviceSeats || !ss.IsSubscriptionActive(userId) {
+		return nil
+	}
+	return sub.Plan
+}
+
+// ListSeats 用户的设备席位及分配情况
+func (ss *SubscriptionService) ListSeats(userId uint) *model.DeviceSeatSummary {
+	res := &model.DeviceSeatSummary{Seats: make([]*model.DeviceSeat, 0)}
+	if plan := ss.seatPlan(userId); plan != nil {
+		res.Enabled = true
+		res.Limit = plan.MaxDevices
+	}
+	DB.Where("user_id = ?", userId).Order("id ASC").Find(&res.Seats)
+	for _, s := range res.Seats {
+		if p := AllService.PeerService.FindByUserIdAndUuid(s.Uuid, userId); p.RowId > 0 {
+			s.Peer = p
+		}
+	}
+	return res
+}
+
+// AssignSeat 将用户自己的设备分配到席位, 已分配时直接返回
+func (ss *SubscriptionService) AssignSeat(userId uint, peerId string) (*model.DeviceSeat, error) {
+	plan := ss.seatPlan(userId)
+	if plan == nil {
+		return nil, errors.New("SeatsNotEnabled")
+	}
+	peer := AllService.PeerService.FindById(peerId)
+	if peer.RowId == 0 || peer.UserId != userId || peer.Uuid == "" {
+		return nil, errors.New("ItemNotFound")
+	}
+	seat := &model.DeviceSeat{}
+	if DB.Where("user_id = ? AND uuid = ?", userId, peer.Uuid).First(seat); seat.Id > 0 {
+		return seat, nil
+	}
+	var used int64
+	DB.Model(&model.DeviceSeat{}).Where("user_id = ?", userId).Count(&used)
+	if deviceLimitExceeded(plan.MaxDevices, used) {
+		return nil, errors.New("SeatsFull")
+	}
+	seat = &model.DeviceSeat{UserId: userId, Uuid: peer.Uuid, PeerId: peer.Id}
+	if err := DB.Create(seat).Error; err != nil {
+		return nil, err
+	}
+	return seat, nil
+}
+
+// ReleaseSeat 释放设备占用的席位
+func (ss *SubscriptionService) ReleaseSeat(userId uint, peerId string) error {
+	res := DB.Where("user_id = ? AND peer_id = ?", userId, peerId).Delete(&model.DeviceSeat{})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return errors.New("ItemNotFound")
+	}
+	return nil
+}
+
+// SeatAllowed 按设备席位授权时, uuid 对应的设备是否已分配席位; 套餐不按席位授权时总是允许
+func (ss *SubscriptionService) SeatAllowed(userId uint, uuid string) bool {
+	if ss.seatPlan(userId) == nil {
+		return true
+	}
+	if uuid == "" {
+		return false
+	}
+	var n int64
+	DB.Model(&model.DeviceSeat{}).Where("user_id = ? AND uuid = ?", userId, uuid).Count(&n)
+	return n > 0
+}
@@ -0,0 +1,392 @@
+package service
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lejianwen/rustdesk-api/v2/model"
+	"gorm.io/gorm"
+)
+
+type EmailService struct {
+}
+
+const (
+	emailDialTimeout = 10 * time.Second
+	emailSendTimeout = 30 * time.Second
+)
+
+// GetConfig 获取 SMTP 配置
+func (es *EmailService) GetConfig() *model.EmailConfig {
+	return AllService.SystemSettingService.GetEmailConfig()
+}
+
+// IsEnabled 是否已启用并配置
+func (es *EmailService) IsEnabled() bool {
+	cfg := es.GetConfig()
+	return cfg.Enable && cfg.Host != "" && es.fromAddress(cfg) != ""
+}
+
+func (es *EmailService) LogList(page, pageSize uint, where func(tx *gorm.DB)) (res *model.EmailLogList) {
+	res = &model.EmailLogList{}
+	res.Page = int64(page)
+	res.PageSize = int64(pageSize)
+	tx := DB.Model(&model.EmailLog{})
+	if where != nil {
+		where(tx)
+	}
+	tx.Count(&res.Total)
+	tx.Scopes(Paginate(page, pageSize))
+	tx.Find(&res.EmailLogs)
+	return
+}
+
+// SendTest 使用指定配置同步发送测试邮件, 用于管理员验证 SMTP 配置
+func (es *EmailService) SendTest(cfg *model.EmailConfig, to string) error {
+	subject := "RustDesk API test email"
+	body := "This is a test email from RustDesk API.\n\nIf you received it, the SMTP settings are working.\n"
+	return es.Send(cfg, to, subject, body)
+}
+
+// Send 通过 SMTP 发送纯文本邮件
+func (es *EmailService) Send(cfg *model.EmailConfig, to, subject, body string) error {
+	from := es.fromAddress(cfg)
+	if cfg.Host == "" || from == "" {
+		return errors.New("EmailNotConfigured")
+	}
+	rcpt, err := mail.ParseAddress(to)
+	if err != nil {
+		return errors.New("invalid recipient: " + to)
+	}
+	msg, err := buildEmailMessage(cfg.FromName, from, rcpt.Address, subject, body)
+	if err != nil {
+		return err
+	}
+
+	port := cfg.Port
+	if port == 0 {
+		port = 587
+	}
+	addr := net.JoinHostPort(cfg.Host, strconv.Itoa(port))
+	tlsCfg := &tls.Config{ServerName: cfg.Host}
+	dialer := &net.Dialer{Timeout: emailDialTimeout}
+
+	var conn net.Conn
+	if cfg.Encryption == model.EmailEncryptionTLS {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, tlsCfg)
+	} else {
+		conn, err = dialer.Dial("tcp", addr)
+	}
+	if err != nil {
+		return err
+	}
+	_ = conn.SetDeadline(time.Now().Add(emailSendTimeout))
+
+	c, err := smtp.NewClient(conn, cfg.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+
+	if cfg.Encryption == model.EmailEncryptionStartTLS {
+		if ok, _ := c.Extension("STARTTLS"); !ok {
+			return errors.New("smtp server does not support STARTTLS")
+		}
+		if err = c.StartTLS(tlsCfg); err != nil {
+			return err
+		}
+	}
+	if cfg.Username != "" {
+		// PlainAuth 仅允许在 TLS 连接或 localhost 上发送密码
+		if err = c.Auth(smtp.PlainAuth("", cfg.Username, cfg.Password, cfg.Host)); err != nil {
+			return err
+		}
+	}
+	if err = c.Mail(from); err != nil {
+		return err
+	}
+	if err = c.Rcpt(rcpt.Address); err != nil {
+		return err
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err = w.Write(msg); err != nil {
+		return err
+	}
+	if err = w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+func (es *EmailService) fromAddress(cfg *model.EmailConfig) string {
+	if cfg.From != "" {
+		return cfg.From
+	}
+	if strings.Contains(cfg.Username, "@") {
+		return cfg.Username
+	}
+	return ""
+}
+
+// buildEmailMessage 构建 MIME 邮件, 标题和正文均编码, 防止头部注入
+func buildEmailMessage(fromName, from, to, subject, body string) ([]byte, error) {
+	if strings.ContainsAny(from+to, "\r\n") {
+		return nil, errors.New("invalid address")
+	}
+	domain := "localhost"
+	if i := strings.LastIndex(from, "@"); i >= 0 {
+		domain = from[i+1:]
+	}
+	var buf bytes.Buffer
+	fromAddr := mail.Address{Name: fromName, Address: from}
+	buf.WriteString("From: " + fromAddr.String() + "\r\n")
+	buf.WriteString("To: " + to + "\r\n")
+	buf.WriteString("Subject: " + mime.BEncoding.Encode("UTF-8", subject) + "\r\n")
+	buf.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	buf.WriteString("Message-ID: <" + uuid.NewString() + "@" + domain + ">\r\n")
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	buf.WriteString("Content-Transfer-Encoding: base64\r\n\r\n")
+	encoded := base64.StdEncoding.EncodeToString([]byte(body))
+	for len(encoded) > 76 {
+		buf.WriteString(encoded[:76] + "\r\n")
+		encoded = encoded[76:]
+	}
+	buf.WriteString(encoded + "\r\n")
+	return buf.Bytes(), nil
+}
+
+// enqueue 记录并异步发送邮件到用户当前邮箱, refKey 已存在(已发送过)时跳过
+func (es *EmailService) enqueue(userId uint, kind, refKey, subject, body string) {
+	if !es.IsEnabled() {
+		return
+	}
+	u := AllService.UserService.InfoById(userId)
+	if u.Id == 0 || u.Email == "" {
+		return
+	}
+	es.enqueueTo(userId, u.Email, kind, refKey, subject, body)
+}
+
+// enqueueTo 发送到指定地址, 用于验证尚未生效的新邮箱
+func (es *EmailService) enqueueTo(userId uint, to, kind, refKey, subject, body string) {
+	if !es.IsEnabled() {
+		return
+	}
+	l := &model.EmailLog{
+		UserId:  userId,
+		Kind:    kind,
+		RefKey:  refKey,
+		To:      to,
+		Subject: subject,
+		Body:    body,
+		Status:  model.EmailStatusPending,
+	}
+	var exists int64
+	DB.Model(&model.EmailLog{}).Where("ref_key = ?", refKey).Count(&exists)
+	if exists > 0 {
+		return
+	}
+	if err := DB.Create(l).Error; err != nil {
+		// 唯一索引冲突说明已由其他实例发送
+		return
+	}
+	go es.deliver(l)
+}
+
+func (es *EmailService) deliver(l *model.EmailLog) {
+	updates := map[string]interface{}{"status": model.EmailStatusSent, "error": ""}
+	if err := es.Send(es.GetConfig(), l.To, l.Subject, l.Body); err != nil {
+		Logger.Warn("Send email failed, kind: ", l.Kind, " to: ", l.To, " err: ", err)
+		updates = map[string]interface{}{"status": model.EmailStatusFailed, "error": err.Error()}
+	}
+	DB.Model(l).Updates(updates)
+}
+
+// OrderPaidReceipt 发送订单支付回执
+func (es *EmailService) OrderPaidReceipt(o *model.Order) {
+	if o.Id == 0 {
+		return
+	}
+	subject, body := es.renderOrderPaid(o)
+	es.enqueue(o.UserId, model.EmailKindOrderPaid, fmt.Sprintf("order_paid:%d", o.Id), subject, body)
+}
+
+func (es *EmailService) renderOrderPaid(o *model.Order) (string, string) {
+	subject := "Payment receipt - " + o.Subject
+	body := fmt.Sprintf("Thank you for your payment.\n\nOrder: %s\nItem: %s\nAmount: %s\nPaid at: %s\n",
+		o.OutTradeNo, o.Subject, o.Total().Display(), formatEmailTime(o.PaidAt))
+	if o.TaxAmount > 0 {
+		label := "excl."
+		if o.TaxInclusive {
+			label = "incl."
+		}
+		body += fmt.Sprintf("Net: %s\nTax (%s %g%%, %s): %s\n", o.Net().Display(), o.TaxCountry, o.TaxRate, label, o.Tax().Display())
+	}
+	if o.ProductId > 0 {
+		return subject, body + "We will contact you when your purchase has been fulfilled.\n"
+	}
+	sub := AllService.Subscription().GetUserSubscription(o.UserId)
+	if sub.Id > 0 {
+		body += fmt.Sprintf("Subscription valid until: %s\n", formatEmailTime(sub.ExpireAt))
+	}
+	return subject, body
+}
+
+// RefundConfirmation 发送退款确认
+func (es *EmailService) RefundConfirmation(o *model.Order, r *model.Refund) {
+	subject, body := es.renderRefund(o, r)
+	es.enqueue(o.UserId, model.EmailKindRefund, fmt.Sprintf("refund:%d", r.Id), subject, body)
+}
+
+func (es *EmailService) renderRefund(o *model.Order, r *model.Refund) (string, string) {
+	subject := "Refund confirmation - " + o.Subject
+	body := fmt.Sprintf("A refund has been issued for your order.\n\nOrder: %s\nRefund amount: %s\nTotal refunded: %s of %s\n",
+		o.OutTradeNo, r.Total().Display(), o.Refunded().Display(), o.Total().Display())
+	if r.Reason != "" {
+		body += "Reason: " + r.Reason + "\n"
+	}
+	return subject, body
+}
+
+// rerender 按当前数据重新生成回执和退款邮件, 收件人为用户当前邮箱; 其他类型返回 false
+func (es *EmailService) rerender(l *model.EmailLog) (to, subject, body string, ok bool) {
+	var id uint
+	switch l.Kind {
+	case model.EmailKindOrderPaid:
+		if _, err := fmt.Sscanf(l.RefKey, "order_paid:%d", &id); err != nil {
+			return
+		}
+		o := &model.Order{}
+		DB.Where("id = ?", id).First(o)
+		if o.Id == 0 {
+			return
+		}
+		subject, body = es.renderOrderPaid(o)
+	case model.EmailKindRefund:
+		if _, err := fmt.Sscanf(l.RefKey, "refund:%d", &id); err != nil {
+			return
+		}
+		r := &model.Refund{}
+		DB.Where("id = ?", id).First(r)
+		o := &model.Order{}
+		DB.Where("id = ?", r.OrderId).First(o)
+		if r.Id == 0 || o.Id == 0 {
+			return
+		}
+		subject, body = es.renderRefund(o, r)
+	default:
+		return
+	}
+	u := AllService.UserService.InfoById(l.UserId)
+	if u.Id == 0 || u.Email == "" {
+		return
+	}
+	return u.Email, subject, body, true
+}
+
+// Resend 重新发送一条邮件记录并同步返回结果, 新记录关联原记录
+// rerender 时按当前数据重新生成支付回执和退款确认并发送到用户当前邮箱, 其他类型原样重发
+func (es *EmailService) Resend(id uint, rerender bool) (*model.EmailLog, error) {
+	if !es.IsEnabled() {
+		return nil, errors.New("EmailNotEnabled")
+	}
+	src := &model.EmailLog{}
+	DB.Where("id = ?", id).First(src)
+	if src.Id == 0 {
+		return nil, errors.New("ItemNotFound")
+	}
+	l := &model.EmailLog{
+		UserId:   src.UserId,
+		Kind:     src.Kind,
+		RefKey:   fmt.Sprintf("resend:%d:%s", src.Id, uuid.NewString()),
+		To:       src.To,
+		Subject:  src.Subject,
+		Body:     src.Body,
+		Status:   model.EmailStatusPending,
+		ResendOf: src.Id,
+	}
+	if rerender {
+		if to, subject, body, ok := es.rerender(src); ok {
+			l.To, l.Subject, l.Body = to, subject, body
+		}
+	}
+	if err := DB.Create(l).Error; err != nil {
+		return nil, err
+	}
+	es.deliver(l)
+	DB.Where("id = ?", l.Id).First(l)
+	return l, nil
+}
+
+// RelayAbuseAlert 通知所有设置了邮箱的管理员
+func (es *EmailService) RelayAbuseAlert(incident *model.RelayIncident) {
+	subject := fmt.Sprintf("Suspicious relay activity: %s", incident.Kind)
+	body := fmt.Sprintf("A suspicious relay pattern was detected.\n\nIncident: %d\nKind: %s\nUUID: %s\nUser ID: %d\nPeer ID: %s\nCount: %d in %d seconds\nFirst seen: %s\nDetected at: %s\nDetail: %s\n",
+		incident.Id, incident.Kind, incident.Uuid, incident.UserId, incident.PeerId, incident.Count, incident.WindowSec,
+		formatEmailTime(incident.FirstAt), formatEmailTime(incident.LastAt), incident.Detail)
+	var admins []*model.User
+	DB.Where("is_admin = ? AND email <> ''", true).Find(&admins)
+	for _, a := range admins {
+		es.enqueue(a.Id, model.EmailKindRelayAbuse, fmt.Sprintf("relay_incident:%d:%d", incident.Id, a.Id), subject, body)
+	}
+}
+
+// SubscriptionAnomalyAlert 通知所有设置了邮箱的管理员
+func (es *EmailService) SubscriptionAnomalyAlert(a *model.SubscriptionAnomaly) {
+	subject := fmt.Sprintf("Subscription extension %s: %s", a.Action, a.Kind)
+	body := fmt.Sprintf("An anomalous subscription extension was detected.\n\nKind: %s\nAction: %s\nUser ID: %d\nPlan ID: %d\nOrder ID: %d\nSource: %s\nNew expiry: %s\nExtensions in the last minute: %d\nDetected at: %s\n",
+		a.Kind, a.Action, a.UserId, a.PlanId, a.OrderId, a.Source, formatEmailTime(a.ExpireAt), a.Recent, formatEmailTime(a.DetectedAt))
+	var admins []*model.User
+	DB.Where("is_admin = ? AND email <> ''", true).Find(&admins)
+	for _, u := range admins {
+		es.enqueue(u.Id, model.EmailKindAnomaly, fmt.Sprintf("anomaly:%d:%s:%d:%d", a.UserId, a.Kind, a.DetectedAt, u.Id), subject, body)
+	}
+}
+
+// GatewayRefundAlert 网关推送的退款/拒付通知所有设置了邮箱的管理员
+func (es *EmailService) GatewayRefundAlert(o *model.Order, r *model.Refund) {
+	subject := fmt.Sprintf("Gateway refund received: %s", o.OutTradeNo)
+	body := fmt.Sprintf("The payment gateway reported a refund or chargeback.\n\nOrder: %s\nTrade No: %s\nUser ID: %d\nAmount: %s\nReason: %s\nGateway refund No: %s\nDeducted: %d seconds\n",
+		o.OutTradeNo, o.TradeNo, o.UserId, r.Total().Display(), r.Reason, r.GatewayRefundNo, r.DeductSeconds)
+	var admins []*model.User
+	DB.Where("is_admin = ? AND email <> ''", true).Find(&admins)
+	for _, a := range admins {
+		es.enqueue(a.Id, model.EmailKindGatewayRefund, fmt.Sprintf("gateway_refund:%d:%d", r.Id, a.Id), subject, body)
+	}
+}
+
+func formatEmailTime(ts int64) string {
+	if ts == 0 {
+		return "-"
+	}
+	return time.Unix(ts, 0).Format(time.DateTime)
+}
+
+// subscribeEvents 订阅需要发送邮件的事件
+func (es *EmailService) subscribeEvents(bus *EventBus) {
+	On(bus, func(e OrderPaidEvent) { es.OrderPaidReceipt(e.Order) })
+	On(bus, func(e OrderRefundedEvent) {
+		es.RefundConfirmation(e.Order, e.Refund)
+		if e.Refund.Source == model.SubscriptionSourceNotify {
+			es.GatewayRefundAlert(e.Order, e.Refund)
+		}
+	})
+	On(bus, func(e RelayAbuseEvent) { es.RelayAbuseAlert(e.Incident) })
+	On(bus, func(e SubscriptionAnomalyEvent) { es.SubscriptionAnomalyAlert(e.Anomaly) })
+}
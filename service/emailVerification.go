@@ -0,0 +1,231 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lejianwen/rustdesk-api/v2/model"
+	"gorm.io/gorm"
+)
+
+// EmailVerificationService 邮箱验证和更换邮箱确认
+type EmailVerificationService struct {
+}
+
+const (
+	emailTokenTTL        = 24 * time.Hour
+	emailTokenResendWait = time.Minute // 同一用户两次发送的最小间隔
+	emailConfirmPath     = "/api/email/confirm"
+)
+
+// IsVerified 用户当前邮箱是否已验证
+func (vs *EmailVerificationService) IsVerified(u *model.User) bool {
+	return u.Email != "" && u.EmailVerifiedAt > 0
+}
+
+// PurchaseAllowed 下单前的邮箱检查, 未开启 payment.require_verified_email 设置时始终放行
+func (vs *EmailVerificationService) PurchaseAllowed(u *model.User) bool {
+	return !AllService.SystemSettingService.SettingBool(SettingRequireVerifiedEmail) || vs.IsVerified(u)
+}
+
+func hashEmailToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// confirmURL 确认链接, 未配置 api-server 时只能在客户端粘贴令牌确认
+func (vs *EmailVerificationService) confirmURL(token string) string {
+	base := strings.TrimRight(Config.Rustdesk.ApiServer, "/")
+	if base == "" {
+		return ""
+	}
+	return base + emailConfirmPath + "?token=" + url.QueryEscape(token)
+}
+
+// checkResend 限制发送频率
+func (vs *EmailVerificationService) checkResend(userId uint) error {
+	last := &model.EmailToken{}
+	DB.Where("user_id = ?", userId).Order("id DESC").First(last)
+	if last.Id > 0 && time.Since(time.Time(last.CreatedAt)) < emailTokenResendWait {
+		return errors.New("EmailSendTooFrequent")
+	}
+	return nil
+}
+
+// issue 生成令牌, 只保存哈希
+func (vs *EmailVerificationService) issue(tx *gorm.DB, t *model.EmailToken) (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(b)
+	t.TokenHash = hashEmailToken(token)
+	t.ExpireAt = time.Now().Add(emailTokenTTL).Unix()
+	if err := tx.Create(t).Error; err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+func (vs *EmailVerificationService) send(t *model.EmailToken, token string) {
+	var subject, body string
+	switch t.Purpose {
+	case model.EmailTokenVerify:
+		subject = "Verify your email address"
+		body = "Please confirm that this is your email address.\n\n"
+	case model.EmailTokenChangeOld:
+		subject = "Confirm email address change"
+		body = fmt.Sprintf("A request was made to change your account email to %s.\nIf this was not you, ignore this email and change your password.\n\n", t.NewEmail)
+	case model.EmailTokenChangeNew:
+		subject = "Confirm your new email address"
+		body = "Please confirm that this is the new email address for your account.\n\n"
+	}
+	if u := vs.confirmURL(token); u != "" {
+		body += "Open the link below to confirm:\n" + u + "\n\n"
+	}
+	body += "Or enter this code in the client: " + token + "\n\nThe code expires at " + formatEmailTime(t.ExpireAt) + ".\n"
+	AllService.EmailService.enqueueTo(t.UserId, t.Email, model.EmailKindVerify, fmt.Sprintf("email_token:%d", t.Id), subject, body)
+}
+
+// SendVerification 向用户当前邮箱发送验证邮件
+func (vs *EmailVerificationService) SendVerification(u *model.User) error {
+	if !AllService.EmailService.IsEnabled() {
+		return errors.New("EmailNotEnabled")
+	}
+	if u.Email == "" {
+		return errors.New("EmailRequired")
+	}
+	if vs.IsVerified(u) {
+		return errors.New("EmailAlreadyVerified")
+	}
+	if err := vs.checkResend(u.Id); err != nil {
+		return err
+	}
+	t := &model.EmailToken{UserId: u.Id, Purpose: model.EmailTokenVerify, Email: u.Email}
+	token, err := vs.issue(DB, t)
+	if err != nil {
+		return err
+	}
+	vs.send(t, token)
+	return nil
+}
+
+// RequestChange 申请更换邮箱, 新邮箱总是需要确认; 旧邮箱已验证时也需要确认, 防止会话被盗后改绑
+// 返回是否需要旧邮箱确认
+func (vs *EmailVerificationService) RequestChange(u *model.User, newEmail string) (bool, error) {
+	newEmail = strings.TrimSpace(newEmail)
+	if !AllService.EmailService.IsEnabled() {
+		return false, errors.New("EmailNotEnabled")
+	}
+	if strings.EqualFold(newEmail, u.Email) {
+		return false, errors.New("EmailUnchanged")
+	}
+	if ex := AllService.UserService.InfoByEmail(newEmail); ex.Id > 0 {
+		return false, errors.New("EmailExists")
+	}
+	if err := vs.checkResend(u.Id); err != nil {
+		return false, err
+	}
+
+	requireOld := vs.IsVerified(u)
+	requestId := uuid.New().String()
+	type issued struct {
+		t     *model.EmailToken
+		token string
+	}
+	var sent []issued
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		// 之前未完成的更换申请作废
+		if err := tx.Model(&model.EmailToken{}).
+			Where("user_id = ? AND purpose IN ? AND used_at = 0", u.Id, []string{model.EmailTokenChangeOld, model.EmailTokenChangeNew}).
+			Update("expire_at", 0).Error; err != nil {
+			return err
+		}
+		targets := []*model.EmailToken{{UserId: u.Id, Purpose: model.EmailTokenChangeNew, Email: newEmail, NewEmail: newEmail, RequestId: requestId}}
+		if requireOld {
+			targets = append(targets, &model.EmailToken{UserId: u.Id, Purpose: model.EmailTokenChangeOld, Email: u.Email, NewEmail: newEmail, RequestId: requestId})
+		}
+		for _, t := range targets {
+			token, err := vs.issue(tx, t)
+			if err != nil {
+				return err
+			}
+			sent = append(sent, issued{t, token})
+		}
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+	for _, s := range sent {
+		vs.send(s.t, s.token)
+	}
+	return requireOld, nil
+}
+
+// Confirm 使用令牌确认, 返回令牌用途和是否已完成(更换邮箱需两个令牌都确认)
+func (vs *EmailVerificationService) Confirm(token string) (string, bool, error) {
+	t := &model.EmailToken{}
+	DB.Where("token_hash = ?", hashEmailToken(strings.TrimSpace(token))).First(t)
+	if t.Id == 0 || t.UsedAt > 0 {
+		return "", false, errors.New("EmailTokenInvalid")
+	}
+	if time.Now().Unix() > t.ExpireAt {
+		return "", false, errors.New("EmailTokenExpired")
+	}
+
+	done := false
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&model.EmailToken{}).Where("id = ? AND used_at = 0", t.Id).Update("used_at", time.Now().Unix())
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return errors.New("EmailTokenInvalid")
+		}
+		u := &model.User{}
+		tx.Where("id = ?", t.UserId).First(u)
+		if u.Id == 0 {
+			return errors.New("EmailTokenInvalid")
+		}
+
+		if t.Purpose == model.EmailTokenVerify {
+			// 发送后邮箱已变更, 令牌作废
+			if !strings.EqualFold(u.Email, t.Email) {
+				return errors.New("EmailTokenInvalid")
+			}
+			done = true
+			return tx.Model(u).Update("email_verified_at", time.Now().Unix()).Error
+		}
+
+		var pending int64
+		tx.Model(&model.EmailToken{}).Where("request_id = ? AND used_at = 0", t.RequestId).Count(&pending)
+		if pending > 0 {
+			return nil
+		}
+		var exists int64
+		tx.Model(&model.User{}).Where("email = ? AND id <> ?", t.NewEmail, u.Id).Count(&exists)
+		if exists > 0 {
+			return errors.New("EmailExists")
+		}
+		done = true
+		return tx.Model(u).Updates(map[string]interface{}{
+			"email":             t.NewEmail,
+			"email_verified_at": time.Now().Unix(),
+		}).Error
+	})
+	if err != nil {
+		return "", false, err
+	}
+	if done && t.Purpose != model.EmailTokenVerify {
+		Logger.Info("User email changed, user: ", t.UserId)
+	}
+	return t.Purpose, done, nil
+}
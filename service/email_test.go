@@ -0,0 +1,27 @@
+package service
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBuildEmailMessage(t *testing.T) {
+	msg, err := buildEmailMessage("RustDesk", "noreply@example.com", "user@example.com", "Receipt\r\nBcc: evil@example.com", "hello")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := string(msg)
+	if strings.Contains(s, "\r\nBcc:") {
+		t.Errorf("subject header injection not prevented:\n%s", s)
+	}
+	if !strings.Contains(s, "From: \"RustDesk\" <noreply@example.com>\r\n") || !strings.Contains(s, "To: user@example.com\r\n") {
+		t.Errorf("unexpected headers:\n%s", s)
+	}
+	if !strings.HasSuffix(s, "\r\n\r\naGVsbG8=\r\n") {
+		t.Errorf("unexpected body:\n%s", s)
+	}
+
+	if _, err := buildEmailMessage("", "a@example.com", "b@example.com\r\nBcc: c@example.com", "s", "b"); err == nil {
+		t.Errorf("expected error for address with newline")
+	}
+}
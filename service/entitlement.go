@@ -0,0 +1,139 @@
+package service
+
+import (
+	"sync"
+	"time"
+
+	"github.com/lejianwen/rustdesk-api/v2/model"
+)
+
+const (
+	// EntitlementWarnPercent 用量达到限额的该百分比时提醒
+	EntitlementWarnPercent = 80
+	entitlementCacheTTL    = 30 * time.Second
+)
+
+// EntitlementService 汇总用户的设备数、relay 流量和时长等权益用量, 结果按用户缓存 entitlementCacheTTL
+type EntitlementService struct {
+	mu    sync.Mutex
+	cache map[uint]*entitlementCacheItem
+}
+
+type entitlementCacheItem struct {
+	list []*model.Entitlement
+	at   time.Time
+}
+
+// List 获取用户有限额的权益项, 支付未启用或无有效订阅时返回空
+func (es *EntitlementService) List(userId uint) []*model.Entitlement {
+	es.mu.Lock()
+	item, ok := es.cache[userId]
+	es.mu.Unlock()
+	if ok && time.Since(item.at) < entitlementCacheTTL {
+		return item.list
+	}
+
+	list := es.collect(userId)
+	es.mu.Lock()
+	if es.cache == nil {
+		es.cache = make(map[uint]*entitlementCacheItem)
+	}
+	es.cache[userId] = &entitlementCacheItem{list: list, at: time.Now()}
+	es.mu.Unlock()
+	return list
+}
+
+func (es *EntitlementService) collect(userId uint) []*model.Entitlement {
+	list := make([]*model.Entitlement, 0)
+	if userId == 0 || !AllService.Payment().IsEnabled() {
+		return list
+	}
+	sub := AllService.Subscription().EffectiveSubscription(userId)
+	if sub.Plan == nil || !AllService.Subscription().IsSubscriptionActive(userId) {
+		return list
+	}
+
+	if sub.Plan.MaxDevices > 0 {
+		var n int64
+		DB.Model(&model.Peer{}).Where("user_id = ?", userId).Count(&n)
+		list = append(list, newEntitlement(model.EntitlementDevices, int64(sub.Plan.MaxDevices), n, 0))
+	}
+	if sub.Plan.MaxSessions > 0 {
+		list = append(list, newEntitlement(model.EntitlementSessions, int64(sub.Plan.MaxSessions), AllService.RelaySessionService.ActiveSessions(userId), 0))
+	}
+	if sub.Plan.RelayQuotaBytes > 0 || sub.Plan.RelayQuotaMinutes > 0 {
+		q := AllService.RelaySessionService.Quota(userId)
+		if q.QuotaBytes > 0 {
+			list = append(list, newEntitlement(model.EntitlementRelayBytes, q.QuotaBytes, q.UsedBytes, q.PeriodEnd))
+		}
+		if q.QuotaMinutes > 0 {
+			list = append(list, newEntitlement(model.EntitlementRelayMinutes, q.QuotaMinutes, q.UsedMinutes, q.PeriodEnd))
+		}
+	}
+	return list
+}
+
+func newEntitlement(name string, limit, used, reset int64) *model.Entitlement {
+	remaining := limit - used
+	if remaining < 0 {
+		remaining = 0
+	}
+	return &model.Entitlement{Name: name, Limit: limit, Used: used, Remaining: remaining, Reset: reset}
+}
+
+// RelayTier 用户当前的 relay 等级, 支付未启用时为 nil
+func (es *EntitlementService) RelayTier(userId uint) *model.RelayTier {
+	if userId == 0 || !AllService.Payment().IsEnabled() {
+		return nil
+	}
+	sub := AllService.Subscription().EffectiveSubscription(userId)
+	active := AllService.Subscription().IsSubscriptionActive(userId)
+	var signupAt int64
+	if sub.Plan != nil && sub.Plan.PremiumRelayTrialDays > 0 {
+		signupAt = time.Time(AllService.UserService.InfoById(userId).CreatedAt).Unix()
+	}
+	return model.ResolveRelayTier(sub.Plan, active, signupAt, time.Now().Unix())
+}
+
+// NearLimit 返回用量达到 EntitlementWarnPercent 的权益项
+func (es *EntitlementService) NearLimit(userId uint) []*model.Entitlement {
+	res := make([]*model.Entitlement, 0)
+	for _, e := range es.List(userId) {
+		if e.UsedPercent() >= EntitlementWarnPercent {
+			res = append(res, e)
+		}
+	}
+	return res
+}
+
+// Tightest 返回剩余比例最小的接近限额项, 没有时返回 nil
+func (es *EntitlementService) Tightest(userId uint) *model.Entitlement {
+	var res *model.Entitlement
+	for _, e := range es.NearLimit(userId) {
+		if res == nil || e.UsedPercent() > res.UsedPercent() {
+			res = e
+		}
+	}
+	return res
+}
+
+// Invalidate 清除用户的缓存
+func (es *EntitlementService) Invalidate(userId uint) {
+	es.mu.Lock()
+	delete(es.cache, userId)
+	es.mu.Unlock()
+}
+
+// InvalidateAll 清除全部缓存, 用于批量修改订阅后
+func (es *EntitlementService) InvalidateAll() {
+	es.mu.Lock()
+	es.cache = nil
+	es.mu.Unlock()
+}
+
+// subscribeEvents 订阅变化时清除缓存
+func (es *EntitlementService) subscribeEvents(bus *EventBus) {
+	On(bus, func(e SubscriptionActivatedEvent) { es.Invalidate(e.Subscription.UserId) })
+	On(bus, func(e SubscriptionExpiredEvent) { es.Invalidate(e.Subscription.UserId) })
+	On(bus, func(e OrderRefundedEvent) { es.Invalidate(e.Order.UserId) })
+}
@@ -0,0 +1,139 @@
+package service
+
+import (
+	"sync"
+
+	"github.com/lejianwen/rustdesk-api/v2/model"
+)
+
+// Event 进程内事件, 名称与对外 webhook 事件一致
+type Event interface {
+	EventName() string
+}
+
+// OrderPaidEvent 订单已支付(入账事务提交后)
+type OrderPaidEvent struct {
+	Order *model.Order
+}
+
+// OrderRefundedEvent 订单已退款
+type OrderRefundedEvent struct {
+	Order  *model.Order
+	Refund *model.Refund
+}
+
+// SubscriptionActivatedEvent 订阅已激活或续期
+type SubscriptionActivatedEvent struct {
+	Subscription *model.UserSubscription
+}
+
+// SubscriptionExpiredEvent 订阅已到期
+type SubscriptionExpiredEvent struct {
+	Subscription *model.UserSubscription
+}
+
+// SubscriptionExpiringEvent 订阅将在 Days 天内到期(到期提醒)
+type SubscriptionExpiringEvent struct {
+	Subscription *model.UserSubscription
+	Days         int
+}
+
+// SubscriptionLapsedEvent 订阅已到期 Days 天仍未续费(催缴提醒)
+type SubscriptionLapsedEvent struct {
+	Subscription *model.UserSubscription
+	Days         int
+}
+
+// UserBannedEvent 用户被禁用
+type UserBannedEvent struct {
+	User *model.User
+}
+
+// ProductPurchasedEvent 商品订单已支付, 等待履约
+type ProductPurchasedEvent struct {
+	Order *model.Order
+}
+
+// ProductFulfilledEvent 商品订单已履约
+type ProductFulfilledEvent struct {
+	Order *model.Order
+}
+
+// SubscriptionAnomalyEvent 订阅续期异常(被拒绝或被标记)
+type SubscriptionAnomalyEvent struct {
+	Anomaly *model.SubscriptionAnomaly
+}
+
+// RelayAbuseEvent 检测到 relay 异常
+type RelayAbuseEvent struct {
+	Incident *model.RelayIncident
+}
+
+func (OrderPaidEvent) EventName() string             { return model.WebhookEventOrderPaid }
+func (OrderRefundedEvent) EventName() string         { return model.WebhookEventOrderRefunded }
+func (SubscriptionActivatedEvent) EventName() string { return model.WebhookEventSubscriptionActivated }
+func (SubscriptionExpiredEvent) EventName() string   { return model.WebhookEventSubscriptionExpired }
+func (UserBannedEvent) EventName() string            { return model.WebhookEventUserBanned }
+func (RelayAbuseEvent) EventName() string            { return model.WebhookEventRelayAbuse }
+func (SubscriptionAnomalyEvent) EventName() string   { return model.WebhookEventSubscriptionAnomaly }
+func (ProductPurchasedEvent) EventName() string      { return model.WebhookEventProductPurchased }
+func (ProductFulfilledEvent) EventName() string      { return model.WebhookEventProductFulfilled }
+func (SubscriptionExpiringEvent) EventName() string  { return model.WebhookEventSubscriptionExpiring }
+func (SubscriptionLapsedEvent) EventName() string    { return model.WebhookEventSubscriptionLapsed }
+
+// EventBus 轻量的进程内发布/订阅
+// Publish 在调用方协程内按订阅顺序同步执行处理函数, 耗时操作应由处理函数自行异步; 处理函数 panic 会被记录并忽略
+type EventBus struct {
+	mu       sync.RWMutex
+	handlers map[string][]func(Event)
+}
+
+func NewEventBus() *EventBus {
+	return &EventBus{handlers: make(map[string][]func(Event))}
+}
+
+// Subscribe 按事件名订阅
+func (b *EventBus) Subscribe(name string, h func(Event)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers[name] = append(b.handlers[name], h)
+}
+
+// On 按事件类型订阅
+func On[T Event](b *EventBus, h func(e T)) {
+	var zero T
+	b.Subscribe(zero.EventName(), func(e Event) {
+		if v, ok := e.(T); ok {
+			h(v)
+		}
+	})
+}
+
+// Publish 发布事件, 应在业务事务提交之后调用
+func (b *EventBus) Publish(e Event) {
+	b.mu.RLock()
+	handlers := b.handlers[e.EventName()]
+	b.mu.RUnlock()
+	for _, h := range handlers {
+		b.call(h, e)
+	}
+}
+
+func (b *EventBus) call(h func(Event), e Event) {
+	defer func() {
+		if r := recover(); r != nil {
+			Logger.Error("Event handler panic, event: ", e.EventName(), " err: ", r)
+		}
+	}()
+	h(e)
+}
+
+// subscribeEvents 注册各子系统的事件处理
+func (s *Service) subscribeEvents() {
+	s.WebhookService.subscribeEvents(s.EventBus)
+	s.EmailService.subscribeEvents(s.EventBus)
+	s.EntitlementService.subscribeEvents(s.EventBus)
+	s.RelaySessionService.subscribeEvents(s.EventBus)
+	s.NotificationService.subscribeEvents(s.EventBus)
+	s.ReferralService.subscribeEvents(s.EventBus)
+}
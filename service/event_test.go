@@ -0,0 +1,26 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/lejianwen/rustdesk-api/v2/model"
+	"github.com/sirupsen/logrus"
+)
+
+func TestEventBus(t *testing.T) {
+	Logger = logrus.New()
+	bus := NewEventBus()
+
+	var got []uint
+	On(bus, func(e OrderPaidEvent) { got = append(got, e.Order.Id) })
+	On(bus, func(e OrderPaidEvent) { panic("handler failure") })
+	On(bus, func(e OrderPaidEvent) { got = append(got, e.Order.Id*10) })
+	On(bus, func(e SubscriptionExpiredEvent) { t.Error("unexpected subscription.expired handler call") })
+
+	o := &model.Order{}
+	o.Id = 3
+	bus.Publish(OrderPaidEvent{Order: o})
+	if len(got) != 2 || got[0] != 3 || got[1] != 30 {
+		t.Errorf("handlers got %v, want [3 30]", got)
+	}
+}
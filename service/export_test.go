@@ -0,0 +1,104 @@
+package service
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/lejianwen/rustdesk-api/v2/model"
+	"gorm.io/gorm"
+)
+
+// checkDescExport 导出结果需包含全部行, 不重复, 且按 id 倒序
+func checkDescExport(t *testing.T, ids []uint, want int) {
+	t.Helper()
+	if len(ids) != want {
+		t.Fatalf("exported %d rows, want %d", len(ids), want)
+	}
+	for i := 1; i < len(ids); i++ {
+		if ids[i] >= ids[i-1] {
+			t.Fatalf("row %d: id %d after %d, want strictly descending", i, ids[i], ids[i-1])
+		}
+	}
+}
+
+func TestExportOrders(t *testing.T) {
+	newTestService(t, []interface{}{&model.Order{}, &model.User{}, &model.SubscriptionPlan{}})
+	orders := make([]*model.Order, 0, 1203)
+	for i := 0; i < 1203; i++ {
+		orders = append(orders, &model.Order{UserId: uint(i%2 + 1), OutTradeNo: fmt.Sprintf("o%d", i), Amount: 100, AmountYuan: "1.00"})
+	}
+	if err := DB.CreateInBatches(orders, 200).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	var ids []uint
+	err := (&SubscriptionService{}).ExportOrders(nil, func(batch []*model.Order) error {
+		for _, o := range batch {
+			ids = append(ids, o.Id)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkDescExport(t, ids, 1203)
+
+	ids = ids[:0]
+	err = (&SubscriptionService{}).ExportOrders(func(tx *gorm.DB) { tx.Where("user_id = ?", 1) }, func(batch []*model.Order) error {
+		for _, o := range batch {
+			if o.UserId != 1 {
+				t.Fatalf("filter ignored, got user %d", o.UserId)
+			}
+			ids = append(ids, o.Id)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkDescExport(t, ids, 602)
+}
+
+func TestExportConnectionLogs(t *testing.T) {
+	newTestService(t, []interface{}{&model.ConnectionLog{}})
+	logs := make([]*model.ConnectionLog, 0, 1001)
+	for i := 0; i < 1001; i++ {
+		logs = append(logs, &model.ConnectionLog{RefKey: fmt.Sprintf("relay:%d", i), UserId: 1})
+	}
+	if err := DB.CreateInBatches(logs, 200).Error; err != nil {
+		t.Fatal(err)
+	}
+	var ids []uint
+	err := (&AuditService{}).ExportConnectionLogs(nil, func(batch []*model.ConnectionLog) error {
+		for _, l := range batch {
+			ids = append(ids, l.Id)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkDescExport(t, ids, 1001)
+}
+
+func TestExportFileTransferLogs(t *testing.T) {
+	newTestService(t, []interface{}{&model.FileTransferLog{}})
+	logs := make([]*model.FileTransferLog, 0, 501)
+	for i := 0; i < 501; i++ {
+		logs = append(logs, &model.FileTransferLog{PeerId: "p", FileName: fmt.Sprintf("f%d", i)})
+	}
+	if err := DB.CreateInBatches(logs, 200).Error; err != nil {
+		t.Fatal(err)
+	}
+	var ids []uint
+	err := (&AuditService{}).ExportFileTransferLogs(nil, func(batch []*model.FileTransferLog) error {
+		for _, l := range batch {
+			ids = append(ids, l.Id)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkDescExport(t, ids, 501)
+}
@@ -0,0 +1,145 @@
+package service
+
+import (
+	"path"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/lejianwen/rustdesk-api/v2/model"
+	"gorm.io/gorm"
+)
+
+// FileTransferReportMax 单次上报的文件数上限
+const FileTransferReportMax = 500
+
+// FileTransferReport 内部接口上报的一次文件传输
+type FileTransferReport struct {
+	PeerId       string
+	TargetPeerId string
+	Direction    string
+	Path         string
+	Size         int64
+	IsDir        bool
+	Ip           string
+	At           int64
+}
+
+// fileTransferName 从路径中取文件名, 兼容 Windows 路径
+func fileTransferName(p string) string {
+	p = strings.TrimRight(strings.ReplaceAll(p, "\\", "/"), "/")
+	if p == "" {
+		return ""
+	}
+	return path.Base(p)
+}
+
+// truncateUtf8 按字节截断, 不截断多字节字符
+func truncateUtf8(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+// fileAuditEnabled 用户当前生效的策略是否开启文件传输审计
+func (as *AuditService) fileAuditEnabled(userId uint, cache map[uint]bool) bool {
+	if userId == 0 {
+		return false
+	}
+	if v, ok := cache[userId]; ok {
+		return v
+	}
+	p, _ := AllService.StrategyService.ForUser(userId)
+	cache[userId] = p != nil && p.AuditFileTransfer
+	return cache[userId]
+}
+
+// RecordFileTransfers 记录文件传输, 返回记录和因策略未开启而跳过的数量
+// 方向无效或路径为空的记录直接丢弃
+func (as *AuditService) RecordFileTransfers(reports []*FileTransferReport) (recorded, skipped int) {
+	owners := make(map[string]uint)
+	owner := func(peerId string) uint {
+		if peerId == "" {
+			return 0
+		}
+		if uid, ok := owners[peerId]; ok {
+			return uid
+		}
+		owners[peerId] = AllService.PeerService.FindById(peerId).UserId
+		return owners[peerId]
+	}
+	enabled := make(map[uint]bool)
+	now := time.Now().Unix()
+	logs := make([]*model.FileTransferLog, 0, len(reports))
+	for _, r := range reports {
+		if r.Direction != model.FileTransferUpload && r.Direction != model.FileTransferDownload {
+			continue
+		}
+		name := fileTransferName(r.Path)
+		if name == "" {
+			continue
+		}
+		l := &model.FileTransferLog{
+			PeerId:        r.PeerId,
+			UserId:        owner(r.PeerId),
+			TargetPeerId:  r.TargetPeerId,
+			TargetUserId:  owner(r.TargetPeerId),
+			Direction:     r.Direction,
+			FileName:      truncateUtf8(name, 255),
+			Path:          truncateUtf8(r.Path, 1024),
+			Size:          r.Size,
+			IsDir:         r.IsDir,
+			Ip:            r.Ip,
+			TransferredAt: r.At,
+		}
+		if !as.fileAuditEnabled(l.UserId, enabled) && !as.fileAuditEnabled(l.TargetUserId, enabled) {
+			skipped++
+			continue
+		}
+		if l.TransferredAt <= 0 {
+			l.TransferredAt = now
+		}
+		if l.Ip != "" {
+			loc := AllService.GeoIpService.Locate(l.Ip)
+			l.Country, l.City = loc.Country, loc.City
+		} else {
+			l.Country, l.City = as.peerLocation(l.PeerId)
+		}
+		logs = append(logs, l)
+	}
+	if len(logs) == 0 {
+		return 0, skipped
+	}
+	if err := DB.CreateInBatches(logs, 100).Error; err != nil {
+		Logger.Error("Create file transfer logs failed: ", err)
+		return 0, skipped
+	}
+	return len(logs), skipped
+}
+
+func (as *AuditService) FileTransferLogList(page, pageSize uint, where func(tx *gorm.DB)) (res *model.FileTransferLogList) {
+	res = &model.FileTransferLogList{}
+	res.Page = int64(page)
+	res.PageSize = int64(pageSize)
+	tx := DB.Model(&model.FileTransferLog{})
+	if where != nil {
+		where(tx)
+	}
+	tx.Count(&res.Total)
+	tx.Scopes(Paginate(page, pageSize))
+	tx.Order("id DESC").Find(&res.FileTransferLogs)
+	return
+}
+
+// ExportFileTransferLogs 按筛选条件分批导出文件传输审计记录, 按 id 倒序
+func (as *AuditService) ExportFileTransferLogs(where func(tx *gorm.DB), fn func(logs []*model.FileTransferLog) error) error {
+	tx := DB.Model(&model.FileTransferLog{})
+	if where != nil {
+		where(tx)
+	}
+	return findDescInBatches(tx, exportBatchSize, func(l *model.FileTransferLog) uint { return l.Id }, fn)
+}
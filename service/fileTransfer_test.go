@@ -0,0 +1,28 @@
+package service
+
+import "testing"
+
+func TestFileTransferName(t *testing.T) {
+	cases := map[string]string{
+		"/home/a/report.pdf":       "report.pdf",
+		`C:\Users\a\Desktop\x.txt`: "x.txt",
+		"/var/log/":                "log",
+		"":                         "",
+		"/":                        "",
+	}
+	for in, want := range cases {
+		if got := fileTransferName(in); got != want {
+			t.Errorf("fileTransferName(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestTruncateUtf8(t *testing.T) {
+	if got := truncateUtf8("abc", 5); got != "abc" {
+		t.Fatalf("short = %q", got)
+	}
+	// "文" 占 3 字节, 截断到 5 字节时不保留半个字符
+	if got := truncateUtf8("a文件", 5); got != "a文" {
+		t.Fatalf("multibyte = %q", got)
+	}
+}
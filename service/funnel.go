@@ -0,0 +1,69 @@
+package service
+
+import (
+	"sort"
+	"strconv"
+
+	"github.com/lejianwen/rustdesk-api/v2/model"
+)
+
+// FunnelStats 按套餐和支付方式汇总漏斗计数(进程启动以来, 多实例部署时以 /metrics 聚合为准)
+func (ss *SubscriptionService) FunnelStats() []*model.FunnelStat {
+	views := map[uint]float64{}
+	rows := map[string]*model.FunnelStat{}
+	MetricCheckoutFunnel.Each(func(labels []string, v float64) {
+		stage, provider := labels[0], labels[2]
+		id, _ := strconv.ParseUint(labels[1], 10, 64)
+		planId := uint(id)
+		if stage == FunnelPlanView {
+			views[planId] += v
+			return
+		}
+		key := labels[1] + "/" + provider
+		row, ok := rows[key]
+		if !ok {
+			row = &model.FunnelStat{PlanId: planId, Provider: provider}
+			rows[key] = row
+		}
+		switch stage {
+		case FunnelCheckoutPreview:
+			row.Previews += v
+		case FunnelOrderCreated:
+			row.OrdersCreated += v
+		case FunnelPaySubmit:
+			row.PaySubmits += v
+		case FunnelNotifyReceived:
+			row.NotifyReceived += v
+		case FunnelPaid:
+			row.Paid += v
+		}
+	})
+
+	res := make([]*model.FunnelStat, 0, len(rows))
+	seen := map[uint]bool{}
+	for _, row := range rows {
+		row.PlanViews = views[row.PlanId]
+		seen[row.PlanId] = true
+		res = append(res, row)
+	}
+	// 只有展示没有后续行为的套餐
+	for planId, v := range views {
+		if !seen[planId] {
+			res = append(res, &model.FunnelStat{PlanId: planId, Provider: funnelAnyProvider, PlanViews: v})
+		}
+	}
+	names := map[uint]string{}
+	for _, row := range res {
+		if _, ok := names[row.PlanId]; !ok {
+			names[row.PlanId] = ss.GetPlanById(row.PlanId).Name
+		}
+		row.PlanName = names[row.PlanId]
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].PlanId != res[j].PlanId {
+			return res[i].PlanId < res[j].PlanId
+		}
+		return res[i].Provider < res[j].Provider
+	})
+	return res
+}
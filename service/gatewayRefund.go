@@ -0,0 +1,107 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/lejianwen/rustdesk-api/v2/model"
+)
+
+// gatewayRefundStatus 网关推送的退款/拒付交易状态
+var gatewayRefundStatus = map[string]bool{
+	"TRADE_REFUND":     true,
+	"TRADE_REFUNDED":   true,
+	"REFUND_SUCCESS":   true,
+	"TRADE_CHARGEBACK": true,
+	"CHARGEBACK":       true,
+}
+
+func isGatewayRefund(tradeStatus string) bool {
+	return gatewayRefundStatus[tradeStatus]
+}
+
+// gatewayRefundNo 推送退款的幂等键, 优先使用网关退款单号
+// 未提供时以交易号+状态+金额区分, 同一笔退款重复推送只入账一次
+func gatewayRefundNo(params map[string]string) string {
+	if no := params["refund_no"]; no != "" {
+		return no
+	}
+	return fmt.Sprintf("%s:%s:%s", params["trade_no"], params["trade_status"], params["refund_money"])
+}
+
+// gatewayRefundAmount 推送退款金额(分), 未提供 refund_money 时退还剩余全部金额
+// 超出可退金额时按可退金额入账, 资金已在网关侧退出
+func gatewayRefundAmount(refundMoney string, order *model.Order) (int64, error) {
+	refundable := order.Refundable().Amount
+	if refundMoney == "" {
+		return refundable, nil
+	}
+	m, err := model.ParseMoney(refundMoney, order.Currency)
+	if err != nil || m.Amount <= 0 {
+		return 0, errors.New("InvalidMoney")
+	}
+	if m.Amount > refundable {
+		return refundable, nil
+	}
+	return m.Amount, nil
+}
+
+// gatewayRefund 处理网关推送的退款/拒付: 校验订单与交易号, 幂等入账, 扣减订阅并通知管理员
+func (ss *SubscriptionService) gatewayRefund(params map[string]string) error {
+	outTradeNo := params["out_trade_no"]
+	tradeNo := params["trade_no"]
+	tradeStatus := params["trade_status"]
+
+	order := ss.GetOrderByOutTradeNo(outTradeNo)
+	if order.Id == 0 {
+		return errors.New("OrderNotFound")
+	}
+	lockKey := fmt.Sprintf("refund_order_%d", order.Id)
+	Lock.Lock(lockKey)
+	defer Lock.UnLock(lockKey)
+
+	order = ss.GetOrderById(order.Id)
+	if order.TradeNo != tradeNo {
+		Logger.Warn("Gateway refund trade_no mismatch, out_trade_no: ", outTradeNo, " expected: ", order.TradeNo, " got: ", tradeNo)
+		return errors.New("TradeNoMismatch")
+	}
+	if order.Status != model.OrderStatusPaid {
+		// 已全额退款或未支付, 重复推送直接忽略
+		Logger.Info("Gateway refund ignored, order: ", outTradeNo, " status: ", order.Status)
+		return nil
+	}
+	refundNo := gatewayRefundNo(params)
+	var cnt int64
+	DB.Model(&model.Refund{}).Where("order_id = ? AND gateway_refund_no = ?", order.Id, refundNo).Count(&cnt)
+	if cnt > 0 {
+		Logger.Info("Gateway refund already processed, order: ", outTradeNo, " refund_no: ", refundNo)
+		return nil
+	}
+	amount, err := gatewayRefundAmount(params["refund_money"], order)
+	if err != nil {
+		return err
+	}
+	if amount <= 0 {
+		return nil
+	}
+
+	refundMoney := model.NewMoney(amount, order.Currency)
+	refund := &model.Refund{
+		OrderId:         order.Id,
+		UserId:          order.UserId,
+		Amount:          amount,
+		AmountYuan:      refundMoney.String(),
+		Currency:        refundMoney.Currency,
+		Reason:          fmt.Sprintf("gateway %s", tradeStatus),
+		Source:          model.SubscriptionSourceNotify,
+		GatewayRefundNo: refundNo,
+	}
+	if err := ss.applyRefund(order, refund, subscriptionChange{Source: model.SubscriptionSourceNotify}); err != nil {
+		Logger.Error("Gateway refund save failed, order: ", outTradeNo, " amount: ", refundMoney.Display(), " err: ", err)
+		return err
+	}
+
+	Logger.Warn("Gateway refund applied, order: ", outTradeNo, " amount: ", refundMoney.Display(), " status: ", tradeStatus)
+	AllService.EventBus.Publish(OrderRefundedEvent{Order: ss.GetOrderById(order.Id), Refund: refund})
+	return nil
+}
@@ -0,0 +1,38 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/lejianwen/rustdesk-api/v2/model"
+)
+
+func TestGatewayRefundNo(t *testing.T) {
+	if got := gatewayRefundNo(map[string]string{"refund_no": "R1", "trade_no": "T1"}); got != "R1" {
+		t.Fatalf("got %q", got)
+	}
+	got := gatewayRefundNo(map[string]string{"trade_no": "T1", "trade_status": "TRADE_REFUND", "refund_money": "1.00"})
+	if got != "T1:TRADE_REFUND:1.00" {
+		t.Fatalf("got %q", got)
+	}
+}
+
+func TestGatewayRefundAmount(t *testing.T) {
+	order := &model.Order{Amount: 1000, RefundedAmount: 300, Currency: "CNY"}
+	cases := []struct {
+		money string
+		want  int64
+		err   bool
+	}{
+		{"", 700, false},
+		{"2.50", 250, false},
+		{"9.99", 700, false},
+		{"0", 0, true},
+		{"abc", 0, true},
+	}
+	for _, c := range cases {
+		got, err := gatewayRefundAmount(c.money, order)
+		if (err != nil) != c.err || got != c.want {
+			t.Errorf("%q: got %d, %v", c.money, got, err)
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package service
+
+import (
+	"sync/atomic"
+
+	"github.com/lejianwen/rustdesk-api/v2/lib/geoip"
+)
+
+// GeoIpService 离线 IP 地理位置查询, 未配置数据库时返回空位置
+type GeoIpService struct {
+	reader atomic.Pointer[geoip.Reader]
+}
+
+// Load 加载 geoip.db-file 配置的数据库, 加载失败时保持原数据库
+func (gs *GeoIpService) Load() {
+	path := Config.GeoIp.DbFile
+	if path == "" {
+		return
+	}
+	r, err := geoip.Open(path)
+	if err != nil {
+		Logger.Error("Load geoip database failed: ", path, " err: ", err)
+		return
+	}
+	gs.reader.Store(r)
+	Logger.Info("Geoip database loaded: ", path, " type: ", r.Type)
+}
+
+// Locate 查询 IP 所在国家和城市, 城市名称按 lang 配置选择语言
+func (gs *GeoIpService) Locate(ip string) geoip.Location {
+	return gs.reader.Load().Locate(ip, Config.Lang)
+}
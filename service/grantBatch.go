@@ -0,0 +1,82 @@
+package service
+
+import (
+	"errors"
+	"time"
+
+	"github.com/lejianwen/rustdesk-api/v2/model"
+	"gorm.io/gorm"
+)
+
+// GrantBatchMax 单次批量赠送的用户数上限
+const GrantBatchMax = 1000
+
+// uniqueIds 去重并去掉 0, 保持原顺序
+func uniqueIds(ids []uint) []uint {
+	seen := make(map[uint]bool, len(ids))
+	res := make([]uint, 0, len(ids))
+	for _, id := range ids {
+		if id == 0 || seen[id] {
+			continue
+		}
+		seen[id] = true
+		res = append(res, id)
+	}
+	return res
+}
+
+// GrantSubscriptionBatch 批量赠送订阅, 在同一事务内逐个用户赠送并返回每个用户的结果
+// 单个用户失败(用户不存在、续期防护拒绝等)只回滚该用户, 不影响其他用户
+func (ss *SubscriptionService) GrantSubscriptionBatch(userIds []uint, planId uint, period model.Period, operatorId uint) ([]*model.GrantBatchResult, error) {
+	userIds = uniqueIds(userIds)
+	if len(userIds) == 0 {
+		return nil, errors.New("GrantBatchEmpty")
+	}
+	if len(userIds) > GrantBatchMax {
+		return nil, errors.New("GrantBatchTooLarge")
+	}
+	plan := ss.GetPlanById(planId)
+	if plan.Id == 0 {
+		return nil, errors.New("PlanNotFound")
+	}
+
+	var existing []uint
+	DB.Model(&model.User{}).Where("id IN ?", userIds).Pluck("id", &existing)
+	found := make(map[uint]bool, len(existing))
+	for _, id := range existing {
+		found[id] = true
+	}
+
+	by := subscriptionChange{Source: model.SubscriptionSourceAdmin, ActorId: operatorId, Metadata: map[string]interface{}{"period": period.String(), "batch": true}}
+	results := make([]*model.GrantBatchResult, 0, len(userIds))
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		now := time.Now().Unix()
+		for _, userId := range userIds {
+			r := &model.GrantBatchResult{UserId: userId}
+			results = append(results, r)
+			if !found[userId] {
+				r.Error = "UserNotFound"
+				continue
+			}
+			// 嵌套事务使用 savepoint, 失败时只回滚该用户
+			err := tx.Transaction(func(stx *gorm.DB) error {
+				if err := ss.extendSubscriptionPeriod(stx, userId, planId, period, now, by); err != nil {
+					return err
+				}
+				return stx.Model(&model.UserSubscription{}).Where("user_id = ?", userId).Pluck("expire_at", &r.ExpireAt).Error
+			})
+			if err != nil {
+				r.Error = err.Error()
+				continue
+			}
+			r.Success = true
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	AllService.EntitlementService.InvalidateAll()
+	Logger.Info("Grant subscription batch, plan: ", planId, " users: ", len(userIds), " operator: ", operatorId)
+	return results, nil
+}
@@ -0,0 +1,17 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestUniqueIds(t *testing.T) {
+	got := uniqueIds([]uint{3, 0, 1, 3, 2, 1})
+	want := []uint{3, 1, 2}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("uniqueIds = %v, want %v", got, want)
+	}
+	if got := uniqueIds(nil); len(got) != 0 {
+		t.Errorf("uniqueIds(nil) = %v, want empty", got)
+	}
+}
@@ -0,0 +1,52 @@
+package service
+
+import "github.com/lejianwen/rustdesk-api/v2/model"
+
+// PaymentProvider 支付网关, 默认实现为 PaymentService (易支付)
+type PaymentProvider interface {
+	IsEnabled() bool
+	GetConfig() *model.PaymentConfig
+	SupportedCurrencies() []string
+	PayTypes() []string
+	CheckPayType(payType string) error
+	PaySubmitURL() string
+	BuildPayParams(outTradeNo, subject string, amount model.Money, payType string) map[string]string
+	BuildPayURL(outTradeNo string) string
+	CreateQRCode(outTradeNo, subject string, amount model.Money, payType, clientIP string) (*EpayMapiResp, error)
+	VerifyPayURL(outTradeNo, expires, sign string) error
+	Sign(params map[string]string) string
+	Verify(params map[string]string) bool
+	Query(outTradeNo string) (*EpayQueryResp, error)
+	Refund(tradeNo string, amount model.Money) (*EpayRefundResp, error)
+}
+
+// SubscriptionProvider 订阅查询, 用于接口/中间件的访问控制, 默认实现为 SubscriptionService
+type SubscriptionProvider interface {
+	IsSubscriptionActive(userId uint) bool
+	GetUserSubscription(userId uint) *model.UserSubscription
+	EffectiveSubscription(userId uint) *model.UserSubscription
+	GetPlanById(id uint) *model.SubscriptionPlan
+	GetPlanByCode(code string) *model.SubscriptionPlan
+	ListActivePlans() []*model.SubscriptionPlan
+}
+
+// RelayWhitelistStore relay 白名单存储, 默认实现为进程内的 RelayWhitelistService
+// 多实例部署时可替换为共享存储(如 Redis)实现
+type RelayWhitelistStore interface {
+	Allow(uuid string, slots int, ttlSec int)
+	AllowFor(uuid string, slots int, ttlSec int, userId uint)
+	Consume(uuid string) bool
+	Check(uuid string) bool
+	Owner(uuid string) uint
+	RecordAllow(userId uint, peerId, uuid string)
+	List(keyword string) []*RelayWhitelistEntry
+	Remove(uuid string) bool
+	Flush() int
+	Stats() map[string]interface{}
+}
+
+var (
+	_ PaymentProvider      = (*PaymentService)(nil)
+	_ SubscriptionProvider = (*SubscriptionService)(nil)
+	_ RelayWhitelistStore  = (*RelayWhitelistService)(nil)
+)
@@ -0,0 +1,232 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lejianwen/rustdesk-api/v2/model"
+	"gorm.io/gorm"
+)
+
+const (
+	// InternalKeyEnv 内部接口密钥环境变量, 整个值作为一个密钥, 与之前的版本相同
+	InternalKeyEnv = "RUSTDESK_API_INTERNAL_KEY"
+	// InternalKeysEnv 多个内部接口密钥, 逗号分隔, 每项可写为 "id:key" 指定密钥ID
+	InternalKeysEnv = "RUSTDESK_API_INTERNAL_KEYS"
+
+	internalKeyCacheTTL    = 30 * time.Second
+	internalKeyTouchPeriod = time.Minute // 使用记录写库间隔
+)
+
+// InternalKeyService 内部接口密钥集合, 包括环境变量中的密钥和数据库管理的密钥
+// 多个密钥同时有效, 轮换时先添加新密钥, 各组件切换后再移除旧密钥
+type InternalKeyService struct {
+	mu       sync.Mutex
+	dbKeys   []*model.InternalKey
+	loadedAt time.Time
+	lastUsed map[string]*InternalKeyUsage // 密钥ID -> 最近使用
+}
+
+// InternalKeyUsage 密钥使用情况
+type InternalKeyUsage struct {
+	Id         string `json:"id"`
+	Source     string `json:"source"` // env/db
+	LastUsedAt int64  `json:"last_used_at"`
+	LastUsedIp string `json:"last_used_ip"`
+}
+
+type internalKeyEntry struct {
+	id     string
+	hash   [32]byte
+	source string
+	dbKey  *model.InternalKey
+}
+
+func envInternalKey(id, key string) *internalKeyEntry {
+	sum := sha256.Sum256([]byte(key))
+	if id == "" {
+		id = hex.EncodeToString(sum[:])[:8]
+	}
+	return &internalKeyEntry{id: "env:" + id, hash: sum, source: "env"}
+}
+
+// envInternalKeys 解析环境变量中的密钥, 未指定ID时使用密钥 sha256 前 8 位作为ID
+// RUSTDESK_API_INTERNAL_KEY 不做拆分, 密钥中可以包含 , 和 :
+func envInternalKeys() []*internalKeyEntry {
+	res := make([]*internalKeyEntry, 0)
+	if key := os.Getenv(InternalKeyEnv); key != "" {
+		res = append(res, envInternalKey("", key))
+	}
+	for _, item := range strings.Split(os.Getenv(InternalKeysEnv), ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		id, key := "", item
+		if i := strings.Index(item, ":"); i > 0 {
+			id, key = item[:i], item[i+1:]
+		}
+		res = append(res, envInternalKey(id, key))
+	}
+	return res
+}
+
+func (ks *InternalKeyService) loadDbKeys() []*model.InternalKey {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	if ks.dbKeys != nil && time.Since(ks.loadedAt) < internalKeyCacheTTL {
+		return ks.dbKeys
+	}
+	keys := make([]*model.InternalKey, 0)
+	DB.Where("status = ?", model.COMMON_STATUS_ENABLE).Find(&keys)
+	ks.dbKeys = keys
+	ks.loadedAt = time.Now()
+	return keys
+}
+
+// Reload 使缓存失效, 增删改数据库密钥后调用
+func (ks *InternalKeyService) Reload() {
+	ks.mu.Lock()
+	ks.dbKeys = nil
+	ks.mu.Unlock()
+}
+
+func (ks *InternalKeyService) entries() []*internalKeyEntry {
+	res := envInternalKeys()
+	now := time.Now().Unix()
+	for _, k := range ks.loadDbKeys() {
+		if k.ExpireAt > 0 && k.ExpireAt <= now {
+			continue
+		}
+		b, err := hex.DecodeString(k.KeyHash)
+		if err != nil || len(b) != sha256.Size {
+			continue
+		}
+		var hash [32]byte
+		copy(hash[:], b)
+		res = append(res, &internalKeyEntry{id: "db:" + k.Name, hash: hash, source: "db", dbKey: k})
+	}
+	return res
+}
+
+// Configured 是否配置了任一内部密钥
+func (ks *InternalKeyService) Configured() bool {
+	return len(ks.entries()) > 0
+}
+
+// Verify 校验密钥, 返回匹配的密钥ID
+func (ks *InternalKeyService) Verify(key, ip string) (string, bool) {
+	if key == "" {
+		return "", false
+	}
+	sum := sha256.Sum256([]byte(key))
+	for _, e := range ks.entries() {
+		if subtle.ConstantTimeCompare(sum[:], e.hash[:]) == 1 {
+			ks.touch(e, ip)
+			return e.id, true
+		}
+	}
+	return "", false
+}
+
+// touch 记录使用情况, 密钥首次被使用时记录日志; 数据库密钥按 internalKeyTouchPeriod 写库
+func (ks *InternalKeyService) touch(e *internalKeyEntry, ip string) {
+	now := time.Now()
+	ks.mu.Lock()
+	if ks.lastUsed == nil {
+		ks.lastUsed = make(map[string]*InternalKeyUsage)
+	}
+	u, ok := ks.lastUsed[e.id]
+	if !ok {
+		u = &InternalKeyUsage{Id: e.id, Source: e.source}
+		ks.lastUsed[e.id] = u
+	}
+	prev := u.LastUsedAt
+	u.LastUsedAt = now.Unix()
+	u.LastUsedIp = ip
+	ks.mu.Unlock()
+
+	if !ok {
+		Logger.Info("Internal API: key ", e.id, " in use from ", ip)
+	}
+	if e.dbKey != nil && now.Unix()-prev >= int64(internalKeyTouchPeriod.Seconds()) {
+		DB.Model(&model.InternalKey{}).Where("id = ?", e.dbKey.Id).
+			Updates(map[string]interface{}{"last_used_at": now.Unix(), "last_used_ip": ip})
+	}
+}
+
+// EnvUsage 环境变量密钥及其在本进程内的最近使用情况
+func (ks *InternalKeyService) EnvUsage() []*InternalKeyUsage {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	res := make([]*InternalKeyUsage, 0)
+	for _, e := range envInternalKeys() {
+		u := &InternalKeyUsage{Id: e.id, Source: e.source}
+		if used, ok := ks.lastUsed[e.id]; ok {
+			*u = *used
+		}
+		res = append(res, u)
+	}
+	return res
+}
+
+func (ks *InternalKeyService) InfoById(id uint) *model.InternalKey {
+	k := &model.InternalKey{}
+	DB.Where("id = ?", id).First(k)
+	return k
+}
+
+func (ks *InternalKeyService) List(page, pageSize uint, where func(tx *gorm.DB)) (res *model.InternalKeyList) {
+	res = &model.InternalKeyList{}
+	res.Page = int64(page)
+	res.PageSize = int64(pageSize)
+	tx := DB.Model(&model.InternalKey{})
+	if where != nil {
+		where(tx)
+	}
+	tx.Count(&res.Total)
+	tx.Scopes(Paginate(page, pageSize))
+	tx.Find(&res.InternalKeys)
+	return
+}
+
+// Create 生成并保存新密钥, 返回明文
+func (ks *InternalKeyService) Create(k *model.InternalKey) (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	plain := "rik_" + hex.EncodeToString(b)
+	sum := sha256.Sum256([]byte(plain))
+	k.KeyHash = hex.EncodeToString(sum[:])
+	k.Prefix = plain[:12]
+	if k.Status == 0 {
+		k.Status = model.COMMON_STATUS_ENABLE
+	}
+	if err := DB.Create(k).Error; err != nil {
+		return "", err
+	}
+	ks.Reload()
+	return plain, nil
+}
+
+// Update 更新状态和过期时间
+func (ks *InternalKeyService) Update(k *model.InternalKey) error {
+	err := DB.Model(k).Select("status", "expire_at").Updates(k).Error
+	ks.Reload()
+	return err
+}
+
+func (ks *InternalKeyService) Delete(k *model.InternalKey) error {
+	err := DB.Delete(k).Error
+	ks.Reload()
+	return err
+}
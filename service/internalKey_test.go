@@ -0,0 +1,28 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+)
+
+func TestEnvInternalKeys(t *testing.T) {
+	// 旧变量整体作为一个密钥, 不按 , 和 : 拆分
+	t.Setenv(InternalKeyEnv, "legacy:a,b")
+	t.Setenv(InternalKeysEnv, " old-secret , k2:new:secret ,,")
+	keys := envInternalKeys()
+	if len(keys) != 3 {
+		t.Fatalf("got %d keys, want 3", len(keys))
+	}
+	legacy := sha256.Sum256([]byte("legacy:a,b"))
+	if keys[0].id != "env:"+hex.EncodeToString(legacy[:])[:8] || keys[0].hash != legacy {
+		t.Errorf("legacy key should be used as is, got %s", keys[0].id)
+	}
+	old := sha256.Sum256([]byte("old-secret"))
+	if keys[1].id != "env:"+hex.EncodeToString(old[:])[:8] || keys[1].hash != old {
+		t.Errorf("key without id should use hash prefix, got %s", keys[1].id)
+	}
+	if keys[2].id != "env:k2" || keys[2].hash != sha256.Sum256([]byte("new:secret")) {
+		t.Errorf("id = %s, want env:k2 with key new:secret", keys[2].id)
+	}
+}
@@ -212,6 +212,9 @@ func (ls *LdapService) mapToLocalUser(cfg *config.Ldap, lu *LdapUser) (*model.Us
 		// If needed, you can set a random password here.
 		newUser.IsAdmin = &isAdmin
 		newUser.GroupId = 1
+		if groupId := ls.mappedGroupId(cfg, lu); groupId > 0 {
+			newUser.GroupId = groupId
+		}
 		if err := DB.Create(newUser).Error; err != nil {
 			return nil, errors.Join(ErrLdapCreateUserFailed, err)
 		}
@@ -235,6 +238,9 @@ func (ls *LdapService) mapToLocalUser(cfg *config.Ldap, lu *LdapUser) (*model.Us
 		}
 	}
 
+	// Group mappings are applied on every login, regardless of Ldap.Sync
+	ls.applyGroup(localUser, ls.mappedGroupId(cfg, lu))
+
 	return localUser, nil
 }
 
@@ -0,0 +1,122 @@
+package service
+
+import (
+	"strings"
+	"time"
+
+	"github.com/lejianwen/rustdesk-api/v2/config"
+	"github.com/lejianwen/rustdesk-api/v2/model"
+)
+
+// ldapSyncPageSize 同步时分页搜索, 避免 AD 默认 1000 条的限制
+const ldapSyncPageSize = 500
+
+// matchLdapGroupMapping 按配置顺序返回用户所属的第一个映射, 比较 DN 时忽略大小写
+func matchLdapGroupMapping(mappings []config.LdapGroupMapping, memberOf []string) *config.LdapGroupMapping {
+	for i := range mappings {
+		for _, g := range memberOf {
+			if strings.EqualFold(strings.TrimSpace(g), strings.TrimSpace(mappings[i].Group)) {
+				return &mappings[i]
+			}
+		}
+	}
+	return nil
+}
+
+// mappedGroupId LDAP 用户映射到的本地用户组, 未匹配时返回 0
+// 没有 memberOf 属性时(如未启用 memberOf overlay 的 OpenLDAP)逐个反查组成员
+func (ls *LdapService) mappedGroupId(cfg *config.Ldap, lu *LdapUser) uint {
+	mappings := cfg.Group.Mappings
+	if len(mappings) == 0 {
+		return 0
+	}
+	if len(lu.MemberOf) > 0 {
+		if m := matchLdapGroupMapping(mappings, lu.MemberOf); m != nil {
+			return m.GroupId
+		}
+		return 0
+	}
+	for _, m := range mappings {
+		if ls.isUserInGroup(cfg, lu, m.Group) {
+			return m.GroupId
+		}
+	}
+	return 0
+}
+
+// applyGroup 更新本地用户的用户组, 未匹配任何映射时保持不变
+func (ls *LdapService) applyGroup(user *model.User, groupId uint) bool {
+	if groupId == 0 || user.Id == 0 || user.GroupId == groupId {
+		return false
+	}
+	if AllService.GroupService.InfoById(groupId).Id == 0 {
+		Logger.Warn("Ldap group mapping to missing group: ", groupId)
+		return false
+	}
+	if err := DB.Model(user).Update("group_id", groupId).Error; err != nil {
+		Logger.Error("Ldap group mapping update group failed: ", err)
+		return false
+	}
+	Logger.Info("Ldap group mapping, user: ", user.Id, " group: ", user.GroupId, " -> ", groupId)
+	user.GroupId = groupId
+	AllService.EntitlementService.Invalidate(user.Id)
+	return true
+}
+
+// SyncGroups 同步所有已存在于本地的 LDAP 用户的用户组, 返回更新的用户数
+func (ls *LdapService) SyncGroups() (int, error) {
+	cfg := &Config.Ldap
+	if !cfg.Enable {
+		return 0, ErrLdapNotEnabled
+	}
+	if len(cfg.Group.Mappings) == 0 {
+		return 0, nil
+	}
+	conn, err := ls.connectAndBindAdmin(cfg)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+	sr, err := conn.SearchWithPaging(ls.buildUserSearchRequest(cfg, ""), ldapSyncPageSize)
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, entry := range sr.Entries {
+		lu := ls.userResultToLdapUser(cfg, entry)
+		if lu.Username == "" {
+			continue
+		}
+		user := AllService.UserService.InfoByUsername(lu.Username)
+		if user.Id == 0 {
+			continue
+		}
+		if ls.applyGroup(user, ls.mappedGroupId(cfg, lu)) {
+			n++
+		}
+	}
+	return n, nil
+}
+
+// StartGroupSyncJob 按 ldap.group.sync-interval 定时同步用户组, 间隔为 0 时只在登录时同步
+func (ls *LdapService) StartGroupSyncJob() {
+	interval := Config.Ldap.Group.SyncInterval
+	if !Config.Ldap.Enable || interval <= 0 || len(Config.Ldap.Group.Mappings) == 0 {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			n, err := ls.SyncGroups()
+			if err != nil {
+				Logger.Error("Sync ldap groups failed: ", err)
+				continue
+			}
+			if n > 0 {
+				Logger.Info("Sync ldap groups, updated users: ", n)
+			}
+		}
+	}()
+}
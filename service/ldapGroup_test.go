@@ -0,0 +1,34 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/lejianwen/rustdesk-api/v2/config"
+)
+
+func TestMatchLdapGroupMapping(t *testing.T) {
+	mappings := []config.LdapGroupMapping{
+		{Group: "cn=ops,ou=groups,dc=example,dc=com", GroupId: 2},
+		{Group: "cn=dev,ou=groups,dc=example,dc=com", GroupId: 3},
+	}
+	cases := []struct {
+		name     string
+		memberOf []string
+		groupId  uint
+	}{
+		{"none", nil, 0},
+		{"unmapped", []string{"cn=sales,ou=groups,dc=example,dc=com"}, 0},
+		{"single", []string{"cn=dev,ou=groups,dc=example,dc=com"}, 3},
+		{"case insensitive", []string{"CN=Dev,OU=Groups,DC=example,DC=com"}, 3},
+		{"config order wins", []string{"cn=dev,ou=groups,dc=example,dc=com", "cn=ops,ou=groups,dc=example,dc=com"}, 2},
+	}
+	for _, c := range cases {
+		var got uint
+		if m := matchLdapGroupMapping(mappings, c.memberOf); m != nil {
+			got = m.GroupId
+		}
+		if got != c.groupId {
+			t.Errorf("%s: group = %d, want %d", c.name, got, c.groupId)
+		}
+	}
+}
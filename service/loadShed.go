@@ -0,0 +1,147 @@
+package service
+
+import (
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lejianwen/rustdesk-api/v2/lib/shed"
+)
+
+// LoadShedService 内部接口过载保护: 自适应并发限制, 以及降级时使用的最近决策
+type LoadShedService struct {
+	once    sync.Once
+	limiter *shed.Limiter
+
+	mu        sync.Mutex
+	decisions map[string]*shedDecision
+}
+
+type shedDecision struct {
+	value gin.H
+	at    time.Time
+}
+
+const (
+	shedOverloadedKey       = "internal_overloaded"
+	shedDefaultRetryAfter   = 2
+	shedDefaultDecisionTTL  = 10 * time.Minute
+	shedMaxDecisions        = 100000
+	shedDecisionPurgeTarget = shedMaxDecisions / 2
+)
+
+// Enabled 是否开启过载保护
+func (ls *LoadShedService) Enabled() bool {
+	return Config != nil && Config.Internal.Shed.Enable
+}
+
+func (ls *LoadShedService) getLimiter() *shed.Limiter {
+	ls.once.Do(func() {
+		cfg := Config.Internal.Shed
+		ls.limiter = shed.New(shed.Options{
+			MinLimit:      cfg.MinLimit,
+			MaxLimit:      cfg.MaxLimit,
+			TargetLatency: cfg.TargetLatency,
+			LowShare:      cfg.LowShare,
+		})
+	})
+	return ls.limiter
+}
+
+// Acquire 获取并发槽位, 失败时记录指标
+func (ls *LoadShedService) Acquire(route string, p shed.Priority) bool {
+	l := ls.getLimiter()
+	ok := l.Acquire(p)
+	if !ok {
+		MetricInternalShed.Inc(route, "over_limit")
+	}
+	ls.observe(l)
+	return ok
+}
+
+// Release 归还槽位
+func (ls *LoadShedService) Release(latency time.Duration, failed bool) {
+	l := ls.getLimiter()
+	l.Release(latency, failed)
+	ls.observe(l)
+}
+
+func (ls *LoadShedService) observe(l *shed.Limiter) {
+	limit, inflight := l.Stats()
+	MetricInternalLimit.Set(float64(limit))
+	MetricInternalInflight.Set(float64(inflight))
+}
+
+// MarkOverloaded 标记请求已超出并发上限, 由处理函数决定使用最近的决策还是拒绝
+func (ls *LoadShedService) MarkOverloaded(c *gin.Context) {
+	c.Set(shedOverloadedKey, true)
+}
+
+// Overloaded 请求是否已超出并发上限
+func (ls *LoadShedService) Overloaded(c *gin.Context) bool {
+	return c.GetBool(shedOverloadedKey)
+}
+
+// RetryAfter 拒绝时建议的重试间隔(秒)
+func (ls *LoadShedService) RetryAfter() int {
+	if Config != nil && Config.Internal.Shed.RetryAfter > 0 {
+		return Config.Internal.Shed.RetryAfter
+	}
+	return shedDefaultRetryAfter
+}
+
+func (ls *LoadShedService) decisionTTL() time.Duration {
+	if Config != nil && Config.Internal.Shed.DecisionTTL > 0 {
+		return Config.Internal.Shed.DecisionTTL
+	}
+	return shedDefaultDecisionTTL
+}
+
+// Remember 保存正常处理时的决策, 过载时可直接返回; 未开启时不保存
+func (ls *LoadShedService) Remember(key string, value gin.H) {
+	if !ls.Enabled() {
+		return
+	}
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+	if ls.decisions == nil {
+		ls.decisions = make(map[string]*shedDecision)
+	}
+	if len(ls.decisions) >= shedMaxDecisions {
+		ls.purgeLocked()
+	}
+	ls.decisions[key] = &shedDecision{value: value, at: time.Now()}
+}
+
+// purgeLocked 删除过期的决策, 仍然过多时随机删除到一半
+func (ls *LoadShedService) purgeLocked() {
+	ttl := ls.decisionTTL()
+	for k, d := range ls.decisions {
+		if time.Since(d.at) > ttl {
+			delete(ls.decisions, k)
+		}
+	}
+	for k := range ls.decisions {
+		if len(ls.decisions) <= shedDecisionPurgeTarget {
+			break
+		}
+		delete(ls.decisions, k)
+	}
+}
+
+// Recall 最近的决策, 超过 decision-ttl 时视为不存在; 返回副本并附带决策时间
+func (ls *LoadShedService) Recall(key string) (gin.H, bool) {
+	ls.mu.Lock()
+	d, ok := ls.decisions[key]
+	ls.mu.Unlock()
+	if !ok || time.Since(d.at) > ls.decisionTTL() {
+		return nil, false
+	}
+	res := make(gin.H, len(d.value)+2)
+	for k, v := range d.value {
+		res[k] = v
+	}
+	res["cached"] = true
+	res["decided_at"] = d.at.Unix()
+	return res, true
+}
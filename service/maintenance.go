@@ -0,0 +1,96 @@
+package service
+
+import (
+	"sync"
+	"time"
+
+	"github.com/lejianwen/rustdesk-api/v2/model"
+	"gorm.io/gorm"
+)
+
+const maintenanceCacheTTL = 15 * time.Second
+
+// MaintenanceService 维护窗口, 窗口期间订阅校验只告警不拒绝
+type MaintenanceService struct {
+	mu       sync.Mutex
+	windows  []*model.MaintenanceWindow // 未结束的启用窗口
+	loadedAt time.Time
+}
+
+func (ms *MaintenanceService) InfoById(id uint) *model.MaintenanceWindow {
+	m := &model.MaintenanceWindow{}
+	DB.Where("id = ?", id).First(m)
+	return m
+}
+
+func (ms *MaintenanceService) List(page, pageSize uint, where func(tx *gorm.DB)) (res *model.MaintenanceWindowList) {
+	res = &model.MaintenanceWindowList{}
+	res.Page = int64(page)
+	res.PageSize = int64(pageSize)
+	tx := DB.Model(&model.MaintenanceWindow{})
+	if where != nil {
+		where(tx)
+	}
+	tx.Count(&res.Total)
+	tx.Scopes(Paginate(page, pageSize))
+	tx.Find(&res.MaintenanceWindows)
+	return
+}
+
+func (ms *MaintenanceService) Create(m *model.MaintenanceWindow) error {
+	err := DB.Create(m).Error
+	ms.Reload()
+	return err
+}
+
+func (ms *MaintenanceService) Update(m *model.MaintenanceWindow) error {
+	err := DB.Model(m).Select("title", "reason", "start_at", "end_at", "mode", "status").Updates(m).Error
+	ms.Reload()
+	return err
+}
+
+func (ms *MaintenanceService) Delete(m *model.MaintenanceWindow) error {
+	err := DB.Delete(m).Error
+	ms.Reload()
+	return err
+}
+
+// Reload 使缓存失效
+func (ms *MaintenanceService) Reload() {
+	ms.mu.Lock()
+	ms.windows = nil
+	ms.mu.Unlock()
+}
+
+// Active 返回当前生效的维护窗口, 没有时返回 nil
+func (ms *MaintenanceService) Active() *model.MaintenanceWindow {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	now := time.Now()
+	if ms.windows == nil || now.Sub(ms.loadedAt) > maintenanceCacheTTL {
+		windows := make([]*model.MaintenanceWindow, 0)
+		DB.Where("status = ? AND end_at > ?", model.COMMON_STATUS_ENABLE, now.Unix()).Order("start_at asc").Find(&windows)
+		ms.windows = windows
+		ms.loadedAt = now
+	}
+	for _, m := range ms.windows {
+		if m.ActiveAt(now.Unix()) {
+			return m
+		}
+	}
+	return nil
+}
+
+// Relax 在校验未通过时调用, 处于维护窗口时返回该窗口表示放行, 否则返回 nil
+// warn 模式下记录被放行的对象, 便于维护结束后核对
+func (ms *MaintenanceService) Relax(what string, userId uint) *model.MaintenanceWindow {
+	m := ms.Active()
+	if m == nil {
+		return nil
+	}
+	if m.Mode != model.MaintenanceModeBypass {
+		Logger.Warn("Maintenance window ", m.Id, ": ", what, " allowed, user_id: ", userId)
+	}
+	return m
+}
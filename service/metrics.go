@@ -0,0 +1,103 @@
+package service
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/lejianwen/rustdesk-api/v2/lib/metrics"
+	"github.com/lejianwen/rustdesk-api/v2/model"
+)
+
+// 业务指标, 由 /metrics 输出
+var (
+	MetricHTTPRequests = metrics.Default.NewCounterVec("rustdesk_api_http_requests_total",
+		"HTTP requests by method, route and status.", "method", "path", "status")
+	MetricHTTPDuration = metrics.Default.NewHistogramVec("rustdesk_api_http_request_duration_seconds",
+		"HTTP request latency by method and route.", nil, "method", "path")
+	MetricPaymentNotify = metrics.Default.NewCounterVec("rustdesk_api_payment_notify_total",
+		"Payment notify callbacks by outcome.", "result")
+	MetricRelayAllow = metrics.Default.NewCounterVec("rustdesk_api_relay_allow_total",
+		"Relay whitelist allow requests by result.", "result")
+	MetricRelayConsume = metrics.Default.NewCounterVec("rustdesk_api_relay_consume_total",
+		"Relay whitelist consume requests by result (hit/miss/quota_exceeded/geo_restricted).", "result")
+	MetricOrdersCreated = metrics.Default.NewCounterVec("rustdesk_api_orders_created_total",
+		"Orders created by pay method.", "pay_method")
+	MetricOrdersRejected = metrics.Default.NewCounterVec("rustdesk_api_orders_rejected_total",
+		"Order creations rejected by the order limit by reason (interval/pending).", "reason")
+	MetricSubscriptionCheck = metrics.Default.NewHistogramVec("rustdesk_api_subscription_check_duration_seconds",
+		"Subscription check latency by source and result.",
+		[]float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1}, "source", "result")
+	MetricRateLimited = metrics.Default.NewCounterVec("rustdesk_api_rate_limited_total",
+		"Requests rejected by the rate limiter by scope and dimension (ip/user).", "scope", "by")
+	MetricInternalShed = metrics.Default.NewCounterVec("rustdesk_api_internal_shed_total",
+		"Internal API requests over the concurrency limit by route and outcome (over_limit/cached/rejected).", "route", "result")
+	MetricInternalLimit = metrics.Default.NewGaugeVec("rustdesk_api_internal_concurrency_limit",
+		"Current adaptive concurrency limit of the internal API.")
+	MetricInternalInflight = metrics.Default.NewGaugeVec("rustdesk_api_internal_inflight",
+		"Internal API requests in flight under the concurrency limiter.")
+	MetricSubscriptionAnomaly = metrics.Default.NewCounterVec("rustdesk_api_subscription_anomaly_total",
+		"Subscription extensions caught by the extension guard by kind (horizon/rate) and action (reject/flag).", "kind", "action")
+	MetricCheckoutFunnel = metrics.Default.NewCounterVec("rustdesk_api_checkout_funnel_total",
+		"Checkout funnel events by stage, plan id and provider (online/manual/free).", "stage", "plan", "provider")
+)
+
+// 下单转化漏斗阶段, 按顺序
+const (
+	FunnelPlanView        = "plan_view"        // 套餐列表展示(每个套餐计一次)
+	FunnelCheckoutPreview = "checkout_preview" // 下单预览
+	FunnelOrderCreated    = "order_created"    // 创建订单
+	FunnelPaySubmit       = "pay_submit"       // 提交到支付网关
+	FunnelNotifyReceived  = "notify_received"  // 收到验签通过的支付回调
+	FunnelPaid            = "paid"             // 入账
+)
+
+// funnelAnyProvider 与支付方式无关的阶段使用的 provider 标签
+const funnelAnyProvider = "any"
+
+// ObserveFunnel 记录一次漏斗事件
+func ObserveFunnel(stage string, planId uint, provider string) {
+	if provider == "" {
+		provider = funnelAnyProvider
+	}
+	MetricCheckoutFunnel.Inc(stage, strconv.FormatUint(uint64(planId), 10), provider)
+}
+
+// orderProvider 订单的支付方式, 免费订单为 free
+func orderProvider(o *model.Order) string {
+	if o.Amount == 0 {
+		return "free"
+	}
+	if o.PayMethod == "" {
+		return model.PayMethodOnline
+	}
+	return o.PayMethod
+}
+
+// ObserveSubscriptionCheck 记录一次订阅检查的耗时
+func ObserveSubscriptionCheck(source string, active bool, start time.Time) {
+	result := "inactive"
+	if active {
+		result = "active"
+	}
+	MetricSubscriptionCheck.Observe(time.Since(start).Seconds(), source, result)
+}
+
+// notifyResult 支付回调结果分类
+func notifyResult(err error) string {
+	if err == nil {
+		return "success"
+	}
+	switch err.Error() {
+	case "SignVerifyFailed":
+		return "sign_fail"
+	case "AmountMismatch":
+		return "amount_mismatch"
+	case "ParamsError", "PidMismatch", "InvalidMoney", "TradeNoMismatch":
+		return "invalid"
+	case "OrderNotFound":
+		return "order_not_found"
+	case "NotifyReplay":
+		return "replay"
+	}
+	return "error"
+}
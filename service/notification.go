@@ -0,0 +1,238 @@
+package service
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lejianwen/rustdesk-api/v2/model"
+	"gorm.io/gorm"
+)
+
+// NotificationService 站内通知: 支付确认、到期提醒和管理员公告, 供未配置邮箱的用户在客户端/门户查看
+type NotificationService struct {
+}
+
+const notificationReminderInterval = time.Hour
+
+// Notify 发送个人通知, 同一 refKey 只通知一次
+func (ns *NotificationService) Notify(userId uint, kind, refKey, title, content string) {
+	if userId == 0 {
+		return
+	}
+	var exists int64
+	DB.Model(&model.Notification{}).Where("ref_key = ?", refKey).Count(&exists)
+	if exists > 0 {
+		return
+	}
+	n := &model.Notification{UserId: userId, Kind: kind, RefKey: refKey, Title: title, Content: content}
+	if err := DB.Create(n).Error; err != nil {
+		// 唯一索引冲突说明已由其他实例写入
+		Logger.Debug("Create notification skipped, ref: ", refKey, " err: ", err)
+	}
+}
+
+// Announce 发布面向全部用户的公告
+func (ns *NotificationService) Announce(title, content string, operatorId uint) (*model.Notification, error) {
+	n := &model.Notification{
+		Kind:       model.NotificationKindAnnouncement,
+		RefKey:     "announcement:" + uuid.New().String(),
+		Title:      title,
+		Content:    content,
+		OperatorId: operatorId,
+	}
+	return n, DB.Create(n).Error
+}
+
+func (ns *NotificationService) InfoById(id uint) *model.Notification {
+	n := &model.Notification{}
+	DB.Where("id = ?", id).First(n)
+	return n
+}
+
+// Delete 删除通知及其已读记录
+func (ns *NotificationService) Delete(n *model.Notification) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("notification_id = ?", n.Id).Delete(&model.NotificationRead{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(n).Error
+	})
+}
+
+// List 通知列表(管理后台)
+func (ns *NotificationService) List(page, pageSize uint, where func(tx *gorm.DB)) (res *model.NotificationList) {
+	res = &model.NotificationList{}
+	res.Page = int64(page)
+	res.PageSize = int64(pageSize)
+	tx := DB.Model(&model.Notification{})
+	if where != nil {
+		where(tx)
+	}
+	tx.Count(&res.Total)
+	tx.Scopes(Paginate(page, pageSize))
+	tx.Find(&res.Notifications)
+	return
+}
+
+// whereUser 用户可见的通知: 个人通知和公告, unreadOnly 时只包含未读
+func (ns *NotificationService) whereUser(tx *gorm.DB, userId uint, unreadOnly bool) {
+	if !unreadOnly {
+		tx.Where("user_id IN ?", []uint{userId, 0})
+		return
+	}
+	read := DB.Model(&model.NotificationRead{}).Select("notification_id").Where("user_id = ?", userId)
+	tx.Where("(user_id = ? AND read_at = 0) OR (user_id = 0 AND id NOT IN (?))", userId, read)
+}
+
+// ListForUser 用户的通知列表, 按时间倒序, 并填充已读状态
+func (ns *NotificationService) ListForUser(userId uint, page, pageSize uint, unreadOnly bool) *model.NotificationList {
+	res := ns.List(page, pageSize, func(tx *gorm.DB) {
+		ns.whereUser(tx, userId, unreadOnly)
+		tx.Order("id DESC")
+	})
+	var announcementIds []uint
+	for _, n := range res.Notifications {
+		if n.UserId == 0 {
+			announcementIds = append(announcementIds, n.Id)
+		} else {
+			n.Read = n.ReadAt > 0
+		}
+	}
+	if len(announcementIds) > 0 {
+		var readIds []uint
+		DB.Model(&model.NotificationRead{}).Where("user_id = ? AND notification_id IN ?", userId, announcementIds).
+			Pluck("notification_id", &readIds)
+		read := make(map[uint]bool, len(readIds))
+		for _, id := range readIds {
+			read[id] = true
+		}
+		for _, n := range res.Notifications {
+			if n.UserId == 0 {
+				n.Read = read[n.Id]
+			}
+		}
+	}
+	return res
+}
+
+// UnreadCount 未读数量
+func (ns *NotificationService) UnreadCount(userId uint) int64 {
+	var total int64
+	tx := DB.Model(&model.Notification{})
+	ns.whereUser(tx, userId, true)
+	tx.Count(&total)
+	return total
+}
+
+// MarkRead 将指定通知标记为已读, 忽略不属于该用户的通知
+func (ns *NotificationService) MarkRead(userId uint, ids []uint) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	if err := DB.Model(&model.Notification{}).
+		Where("user_id = ? AND id IN ? AND read_at = 0", userId, ids).
+		Update("read_at", time.Now().Unix()).Error; err != nil {
+		return err
+	}
+	var announcementIds []uint
+	DB.Model(&model.Notification{}).Where("user_id = 0 AND id IN ?", ids).Pluck("id", &announcementIds)
+	return ns.markAnnouncementsRead(userId, announcementIds)
+}
+
+// MarkAllRead 将全部通知标记为已读
+func (ns *NotificationService) MarkAllRead(userId uint) error {
+	if err := DB.Model(&model.Notification{}).
+		Where("user_id = ? AND read_at = 0", userId).
+		Update("read_at", time.Now().Unix()).Error; err != nil {
+		return err
+	}
+	var announcementIds []uint
+	tx := DB.Model(&model.Notification{}).Where("user_id = 0")
+	ns.whereUser(tx, userId, true)
+	tx.Pluck("id", &announcementIds)
+	return ns.markAnnouncementsRead(userId, announcementIds)
+}
+
+func (ns *NotificationService) markAnnouncementsRead(userId uint, ids []uint) error {
+	for _, id := range ids {
+		r := &model.NotificationRead{NotificationId: id, UserId: userId}
+		if err := DB.Where("notification_id = ? AND user_id = ?", id, userId).FirstOrCreate(r).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// subscribeEvents 订阅需要站内通知的事件
+func (ns *NotificationService) subscribeEvents(bus *EventBus) {
+	On(bus, func(e OrderPaidEvent) {
+		o := e.Order
+		ns.Notify(o.UserId, model.NotificationKindPaymentConfirmed, fmt.Sprintf("order_paid:%d", o.Id),
+			"Payment confirmed",
+			fmt.Sprintf("Your payment of %s for %s (order %s) has been received.", o.Total().Display(), o.Subject, o.OutTradeNo))
+	})
+	On(bus, func(e OrderRefundedEvent) {
+		o, r := e.Order, e.Refund
+		ns.Notify(o.UserId, model.NotificationKindRefund, fmt.Sprintf("refund:%d", r.Id),
+			"Refund issued",
+			fmt.Sprintf("A refund of %s has been issued for order %s.", r.Total().Display(), o.OutTradeNo))
+		if r.Source != model.SubscriptionSourceNotify {
+			return
+		}
+		var adminIds []uint
+		DB.Model(&model.User{}).Where("is_admin = ?", true).Pluck("id", &adminIds)
+		for _, id := range adminIds {
+			ns.Notify(id, model.NotificationKindGatewayRefund, fmt.Sprintf("gateway_refund:%d:%d", r.Id, id),
+				"Gateway refund received",
+				fmt.Sprintf("The payment gateway reported a refund of %s for order %s (user %d).", r.Total().Display(), o.OutTradeNo, o.UserId))
+		}
+	})
+	On(bus, func(e SubscriptionExpiredEvent) {
+		sub := e.Subscription
+		ns.Notify(sub.UserId, model.NotificationKindSubscriptionExpired, fmt.Sprintf("expired:%d:%d", sub.Id, sub.ExpireAt),
+			"Subscription expired",
+			"Your subscription has expired. Renew to restore full access.")
+	})
+}
+
+// relayTrialNoticeWindow 试用结束后在该时间内补发提醒, 避免任务停机期间漏发
+const relayTrialNoticeWindow = 7 * 24 * time.Hour
+
+// SendRelayTrialEnded 免费套餐的高级 relay 试用结束后, 向仍在使用该套餐的用户发送一次升级提醒
+func (ns *NotificationService) SendRelayTrialEnded() int {
+	var plans []*model.SubscriptionPlan
+	DB.Where("price = 0 AND premium_relay = ? AND premium_relay_trial_days > 0", false).Find(&plans)
+	now := time.Now()
+	n := 0
+	for _, plan := range plans {
+		endedBefore := now.Add(-time.Duration(plan.PremiumRelayTrialDays) * 24 * time.Hour)
+		var userIds []uint
+		signedUp := DB.Model(&model.User{}).Select("id").
+			Where("created_at <= ? AND created_at > ?", endedBefore, endedBefore.Add(-relayTrialNoticeWindow))
+		DB.Model(&model.UserSubscription{}).
+			Where("plan_id = ? AND status = ? AND expire_at > ? AND user_id IN (?)",
+				plan.Id, model.SubscriptionStatusActive, now.Unix(), signedUp).
+			Pluck("user_id", &userIds)
+		for _, userId := range userIds {
+			ns.Notify(userId, model.NotificationKindRelayTrialEnded, fmt.Sprintf("relay_trial_ended:%d", userId),
+				"Your premium relay trial has ended",
+				fmt.Sprintf("Your %d-day premium relay trial has ended and connections now use the standard relay. Upgrade your plan to get premium relay back.",
+					plan.PremiumRelayTrialDays))
+			n++
+		}
+	}
+	return n
+}
+
+// StartRelayTrialJob 启动高级 relay 试用结束提醒任务, 到期提醒见 ReminderService
+func (ns *NotificationService) StartRelayTrialJob() {
+	go func() {
+		ticker := time.NewTicker(notificationReminderInterval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			ns.SendRelayTrialEnded()
+		}
+	}()
+}
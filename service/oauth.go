@@ -232,7 +232,9 @@ func getHTTPClientWithProxy() *http.Client {
 	}
 	return http.DefaultClient
 }
-func (os *OauthService) callbackBase(oauthConfig *oauth2.Config, provider *oidc.Provider, code string, verifier string, nonce string, userData interface{}) (err error, client *http.Client) {
+
+// callbackBase idClaims 不为 nil 时写入 ID Token 的 claims
+func (os *OauthService) callbackBase(oauthConfig *oauth2.Config, provider *oidc.Provider, code string, verifier string, nonce string, userData interface{}, idClaims map[string]interface{}) (err error, client *http.Client) {
 
 	// 设置代理客户端
 	httpClient := getHTTPClientWithProxy()
@@ -275,6 +277,11 @@ func (os *OauthService) callbackBase(oauthConfig *oauth2.Config, provider *oidc.
 				return errors.New("NonceDoesNotMatch"), nil
 			}
 		}
+		if idClaims != nil {
+			if err2 = idToken.Claims(&idClaims); err2 != nil {
+				Logger.Warn("Failed to parse ID Token claims: ", err2)
+			}
+		}
 	}
 
 	// 获取用户信息
@@ -302,7 +309,7 @@ func (os *OauthService) callbackBase(oauthConfig *oauth2.Config, provider *oidc.
 // githubCallback github回调
 func (os *OauthService) githubCallback(oauthConfig *oauth2.Config, provider *oidc.Provider, code, verifier, nonce string) (error, *model.OauthUser) {
 	var user = &model.GithubUser{}
-	err, client := os.callbackBase(oauthConfig, provider, code, verifier, nonce, user)
+	err, client := os.callbackBase(oauthConfig, provider, code, verifier, nonce, user, nil)
 	if err != nil {
 		return err, nil
 	}
@@ -316,7 +323,7 @@ func (os *OauthService) githubCallback(oauthConfig *oauth2.Config, provider *oid
 // linuxdoCallback linux.do回调
 func (os *OauthService) linuxdoCallback(oauthConfig *oauth2.Config, provider *oidc.Provider, code, verifier, nonce string) (error, *model.OauthUser) {
 	var user = &model.LinuxdoUser{}
-	err, _ := os.callbackBase(oauthConfig, provider, code, verifier, nonce, user)
+	err, _ := os.callbackBase(oauthConfig, provider, code, verifier, nonce, user, nil)
 	if err != nil {
 		return err, nil
 	}
@@ -324,12 +331,70 @@ func (os *OauthService) linuxdoCallback(oauthConfig *oauth2.Config, provider *oi
 }
 
 // oidcCallback oidc回调, 通过code获取用户信息
-func (os *OauthService) oidcCallback(oauthConfig *oauth2.Config, provider *oidc.Provider, code, verifier, nonce string) (error, *model.OauthUser) {
-	var user = &model.OidcUser{}
-	if err, _ := os.callbackBase(oauthConfig, provider, code, verifier, nonce, user); err != nil {
+// groupsClaim 不为空时从 userinfo 和 ID Token 中读取分组, userinfo 优先
+func (os *OauthService) oidcCallback(oauthConfig *oauth2.Config, provider *oidc.Provider, code, verifier, nonce, groupsClaim string) (error, *model.OauthUser) {
+	if groupsClaim == "" {
+		var user = &model.OidcUser{}
+		if err, _ := os.callbackBase(oauthConfig, provider, code, verifier, nonce, user, nil); err != nil {
+			return err, nil
+		}
+		return nil, user.ToOauthUser()
+	}
+	raw := map[string]interface{}{}
+	idClaims := map[string]interface{}{}
+	if err, _ := os.callbackBase(oauthConfig, provider, code, verifier, nonce, &raw, idClaims); err != nil {
 		return err, nil
 	}
-	return nil, user.ToOauthUser()
+	var user = &model.OidcUser{}
+	data, _ := json.Marshal(raw)
+	if err := json.Unmarshal(data, user); err != nil {
+		Logger.Warn("failed decoding user info: ", err)
+		return errors.New("DecodeOauthUserInfoError"), nil
+	}
+	oauthUser := user.ToOauthUser()
+	oauthUser.Groups = model.ClaimGroups(raw, groupsClaim)
+	if oauthUser.Groups == nil {
+		oauthUser.Groups = model.ClaimGroups(idClaims, groupsClaim)
+	}
+	return nil, oauthUser
+}
+
+// ApplyGroupMappings 登录时按 IdP 分组设置用户组, 用户没有有效订阅时赠送映射的套餐
+func (os *OauthService) ApplyGroupMappings(op string, user *model.User, groups []string) {
+	info := os.InfoByOp(op)
+	if info.GroupsClaim == "" || user == nil || user.Id == 0 {
+		return
+	}
+	gm, pm := model.MatchGroupMappings(info.GroupMappingList(), groups)
+	if gm != nil && gm.GroupId != user.GroupId {
+		if AllService.GroupService.InfoById(gm.GroupId).Id == 0 {
+			Logger.Warn("Oauth group mapping to missing group: ", gm.GroupId, " op: ", op)
+		} else if err := DB.Model(user).Update("group_id", gm.GroupId).Error; err != nil {
+			Logger.Error("Oauth group mapping update group failed: ", err)
+		} else {
+			Logger.Info("Oauth group mapping, user: ", user.Id, " group: ", user.GroupId, " -> ", gm.GroupId, " idp group: ", gm.IdpGroup)
+			user.GroupId = gm.GroupId
+			AllService.EntitlementService.Invalidate(user.Id)
+		}
+	}
+	if pm != nil && !AllService.Subscription().IsSubscriptionActive(user.Id) {
+		plan := AllService.SubscriptionService.GetPlanById(pm.PlanId)
+		if plan.Id == 0 {
+			Logger.Warn("Oauth group mapping to missing plan: ", pm.PlanId, " op: ", op)
+			return
+		}
+		period := plan.PlanPeriod()
+		if pm.Period != "" {
+			if p, err := model.ParsePeriod(pm.Period); err == nil {
+				period = p
+			}
+		}
+		if err := AllService.SubscriptionService.GrantSubscription(user.Id, plan.Id, period, 0); err != nil {
+			Logger.Error("Oauth group mapping grant plan failed: ", err)
+			return
+		}
+		Logger.Info("Oauth group mapping, user: ", user.Id, " granted plan: ", plan.Id, " period: ", period.String(), " idp group: ", pm.IdpGroup)
+	}
 }
 
 // Callback: Get user information by code and op(Oauth provider)
@@ -346,7 +411,7 @@ func (os *OauthService) Callback(code, verifier, op, nonce string) (err error, o
 	case model.OauthTypeLinuxdo:
 		err, oauthUser = os.linuxdoCallback(oauthConfig, provider, code, verifier, nonce)
 	case model.OauthTypeOidc, model.OauthTypeGoogle:
-		err, oauthUser = os.oidcCallback(oauthConfig, provider, code, verifier, nonce)
+		err, oauthUser = os.oidcCallback(oauthConfig, provider, code, verifier, nonce, oauthInfo.GroupsClaim)
 	default:
 		return errors.New("unsupported OAuth type"), nil
 	}
@@ -0,0 +1,71 @@
+package service
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/lejianwen/rustdesk-api/v2/model"
+)
+
+// AdminOrderParams 管理员代用户下单的参数
+type AdminOrderParams struct {
+	UserId   uint
+	PlanId   uint
+	Currency string // 为空时使用套餐基础币种
+	Amount   *int64 // 金额(分), 为空时按套餐价格
+	Subject  string // 为空时使用套餐名称
+	Paid     bool   // 立即标记为已支付并激活订阅
+	TradeNo  string // 线下收款流水号(Paid 时可选)
+	Remark   string
+}
+
+// CreateAdminOrder 管理员代用户创建线下订单(如对公转账的企业合同), 可覆盖金额
+// 不校验套餐状态和购买限制; Paid 时与手动入账共用流程激活订阅, 否则为待支付的线下订单, 可稍后手动入账
+func (ss *SubscriptionService) CreateAdminOrder(p *AdminOrderParams, operatorId uint) (*model.Order, error) {
+	u := AllService.UserService.InfoById(p.UserId)
+	if u.Id == 0 {
+		return nil, errors.New("UserNotFound")
+	}
+	plan := ss.GetPlanById(p.PlanId)
+	if plan.Id == 0 {
+		return nil, errors.New("PlanNotFound")
+	}
+	currency := strings.ToUpper(strings.TrimSpace(p.Currency))
+	if currency != "" && !model.IsSupportedCurrency(currency) {
+		return nil, errors.New("CurrencyNotSupported")
+	}
+	price := ss.PlanPrice(plan, currency)
+	if p.Amount != nil {
+		if *p.Amount < 0 {
+			return nil, errors.New("InvalidMoney")
+		}
+		price = model.NewMoney(*p.Amount, price.Currency)
+	}
+	subject := strings.TrimSpace(p.Subject)
+	if subject == "" {
+		subject = plan.Name
+	}
+	order := &model.Order{
+		UserId:     p.UserId,
+		PlanId:     plan.Id,
+		OutTradeNo: ss.GenerateOutTradeNo(),
+		Subject:    subject,
+		Amount:     price.Amount,
+		AmountYuan: price.String(),
+		Currency:   price.Currency,
+		Status:     model.OrderStatusPending,
+		PayMethod:  model.PayMethodManual,
+		CreatedBy:  operatorId,
+	}
+	if err := DB.Create(order).Error; err != nil {
+		Logger.Error("Create admin order failed: ", err)
+		return nil, err
+	}
+	MetricOrdersCreated.Inc(model.PayMethodManual)
+	if p.Paid {
+		if err := ss.MarkOrderPaid(order.Id, strings.TrimSpace(p.TradeNo), operatorId, p.Remark); err != nil {
+			return nil, err
+		}
+	}
+	return ss.GetOrderById(order.Id), nil
+}
@@ -0,0 +1,126 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lejianwen/rustdesk-api/v2/config"
+	"github.com/lejianwen/rustdesk-api/v2/model"
+	"gorm.io/gorm"
+)
+
+// 下单限制设置
+const (
+	SettingOrderMaxPending  = "payment.order_limit.max_pending"
+	SettingOrderMinInterval = "payment.order_limit.min_interval"
+)
+
+const (
+	defaultOrderMaxPending  = 5
+	defaultOrderMinInterval = 5 * time.Second
+)
+
+// registerOrderLimitSettings 注册下单限制设置, 默认值取自 payment.order-limit
+func (s *SystemSettingService) registerOrderLimitSettings(cfg config.OrderLimit) {
+	maxPending := int64(cfg.MaxPending)
+	if maxPending <= 0 {
+		maxPending = defaultOrderMaxPending
+	}
+	minInterval := cfg.MinInterval
+	if minInterval <= 0 {
+		minInterval = defaultOrderMinInterval
+	}
+	zero := float64(0)
+	s.RegisterSetting(&model.SettingDefinition{
+		Key:         SettingOrderMaxPending,
+		Type:        model.SettingTypeInt,
+		Group:       "payment",
+		Description: "Maximum pending orders per user, 0 disables the check",
+		Default:     maxPending,
+		Min:         &zero,
+	})
+	s.RegisterSetting(&model.SettingDefinition{
+		Key:         SettingOrderMinInterval,
+		Type:        model.SettingTypeDuration,
+		Group:       "payment",
+		Description: "Minimum interval between two orders of the same user, 0 disables the check",
+		Default:     minInterval,
+		Min:         &zero,
+	})
+}
+
+// orderDupKey 重复订单的判断依据: 同一套餐/商品、支付方式和币种
+func orderDupKey(o *model.Order) string {
+	return fmt.Sprintf("%d:%d:%s:%s", o.PlanId, o.ProductId, o.PayMethod, o.Currency)
+}
+
+// duplicatePendingOrders 从按 id 倒序的待支付订单中找出可关闭的旧重复订单, 每组保留最新一笔
+// keep 中的订单(已上传凭证、管理员延长保留)不会被关闭, 也不占用保留名额
+func duplicatePendingOrders(orders []*model.Order, keep map[uint]bool) []uint {
+	seen := map[string]bool{}
+	var res []uint
+	for _, o := range orders {
+		if keep[o.Id] {
+			continue
+		}
+		key := orderDupKey(o)
+		if seen[key] {
+			res = append(res, o.Id)
+			continue
+		}
+		seen[key] = true
+	}
+	return res
+}
+
+// checkOrderLimit 创建新订单前检查(不含复用已有订单): 最小下单间隔和待支付订单上限
+// 超过上限时先关闭旧的重复订单, 仍超过时拒绝
+func (ss *SubscriptionService) checkOrderLimit(db *gorm.DB, userId uint) error {
+	settings := AllService.SystemSettingService
+	now := time.Now()
+	if interval := settings.SettingDuration(SettingOrderMinInterval); interval > 0 {
+		var recent int64
+		db.Model(&model.Order{}).Where("user_id = ? AND created_at > ?", userId, now.Add(-interval)).Count(&recent)
+		if recent > 0 {
+			MetricOrdersRejected.Inc("interval")
+			return errors.New("OrderTooFrequent")
+		}
+	}
+	maxPending := settings.SettingInt(SettingOrderMaxPending)
+	if maxPending <= 0 {
+		return nil
+	}
+	var pending []*model.Order
+	db.Where("user_id = ? AND status = ?", userId, model.OrderStatusPending).Order("id DESC").Find(&pending)
+	if int64(len(pending)) < maxPending {
+		return nil
+	}
+
+	ids := make([]uint, 0, len(pending))
+	keep := map[uint]bool{}
+	for _, o := range pending {
+		ids = append(ids, o.Id)
+		if o.HoldUntil > now.Unix() {
+			keep[o.Id] = true
+		}
+	}
+	var proofOrderIds []uint
+	db.Model(&model.PaymentProof{}).Where("order_id IN ?", ids).Pluck("order_id", &proofOrderIds)
+	for _, id := range proofOrderIds {
+		keep[id] = true
+	}
+	if dup := duplicatePendingOrders(pending, keep); len(dup) > 0 {
+		res := db.Model(&model.Order{}).Where("id IN ? AND status = ?", dup, model.OrderStatusPending).
+			Update("status", model.OrderStatusClosed)
+		if res.Error != nil {
+			return res.Error
+		}
+		Logger.Info(fmt.Sprintf("Closed %d duplicate pending orders of user %d", res.RowsAffected, userId))
+		if int64(len(pending))-res.RowsAffected < maxPending {
+			return nil
+		}
+	}
+	MetricOrdersRejected.Inc("pending")
+	return errors.New("TooManyPendingOrders")
+}
@@ -0,0 +1,36 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/lejianwen/rustdesk-api/v2/model"
+)
+
+func TestDuplicatePendingOrders(t *testing.T) {
+	order := func(id, planId, productId uint, method, currency string) *model.Order {
+		o := &model.Order{PlanId: planId, ProductId: productId, PayMethod: method, Currency: currency}
+		o.Id = id
+		return o
+	}
+	// 按 id 倒序
+	orders := []*model.Order{
+		order(9, 1, 0, model.PayMethodOnline, "CNY"),
+		order(8, 1, 0, model.PayMethodOnline, "USD"),
+		order(7, 1, 0, model.PayMethodManual, "CNY"),
+		order(6, 1, 0, model.PayMethodOnline, "CNY"),
+		order(5, 0, 3, model.PayMethodOnline, "CNY"),
+		order(4, 1, 0, model.PayMethodManual, "CNY"),
+		order(3, 0, 3, model.PayMethodOnline, "CNY"),
+		order(2, 1, 0, model.PayMethodOnline, "CNY"),
+	}
+	got := duplicatePendingOrders(orders, map[uint]bool{4: true, 9: true})
+	// 9 保留但不占名额, 6 成为该组最新的一笔; 4 已上传凭证不关闭
+	want := []uint{3, 2}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("duplicatePendingOrders = %v, want %v", got, want)
+	}
+	if got := duplicatePendingOrders(orders, nil); !reflect.DeepEqual(got, []uint{6, 4, 3, 2}) {
+		t.Errorf("duplicatePendingOrders without keep = %v", got)
+	}
+}
@@ -0,0 +1,50 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/lejianwen/rustdesk-api/v2/model"
+)
+
+func TestReissueOrder(t *testing.T) {
+	s := newTestService(t, []interface{}{&model.Order{}})
+	ss := s.SubscriptionService
+	orders := []*model.Order{
+		{UserId: 1, ProductId: 7, OutTradeNo: "p1", Amount: 990, AmountYuan: "9.90", Currency: "CNY", PayMethod: model.PayMethodOnline, PaySubmitAt: 100},
+		{UserId: 1, ProductId: 8, OutTradeNo: "p2", Amount: 500, AmountYuan: "5.00", Currency: "CNY", PayMethod: model.PayMethodOnline},
+		{UserId: 1, PlanId: 3, OutTradeNo: "u1", Amount: 1200, AmountYuan: "12.00", Currency: "USD", PayMethod: model.PayMethodOnline, PayType: "alipay", TaxCountry: "DE", TaxRate: 19, TaxAmount: 192},
+		{UserId: 1, PlanId: 3, OutTradeNo: "m1", Amount: 8000, AmountYuan: "80.00", Currency: "CNY", PayMethod: model.PayMethodManual},
+	}
+	for _, o := range orders {
+		if err := DB.Create(o).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	product, err := ss.ReissueOrder(DB, orders[0], 200)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if product.ProductId != 7 || product.PlanId != 0 || product.Amount != 990 || product.PayMethod != model.PayMethodOnline ||
+		product.PaySubmitAt != 200 || product.OutTradeNo == "p1" || product.Status != model.OrderStatusPending {
+		t.Errorf("reissued product order = %+v", product)
+	}
+
+	foreign, err := ss.ReissueOrder(DB, orders[2], 200)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if foreign.Currency != "USD" || foreign.Amount != 1200 || foreign.AmountYuan != "12.00" || foreign.PlanId != 3 ||
+		foreign.PayType != "alipay" || foreign.TaxCountry != "DE" || foreign.TaxAmount != 192 {
+		t.Errorf("reissued foreign currency order = %+v", foreign)
+	}
+
+	// 只关闭被重新下单的订单
+	want := map[string]int{"p1": model.OrderStatusClosed, "p2": model.OrderStatusPending, "u1": model.OrderStatusClosed, "m1": model.OrderStatusPending}
+	for no, status := range want {
+		o := ss.GetOrderByOutTradeNo(no)
+		if o.Status != status {
+			t.Errorf("order %s status = %d, want %d", no, o.Status, status)
+		}
+	}
+}
@@ -0,0 +1,218 @@
+package service
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/lejianwen/rustdesk-api/v2/model"
+	"gorm.io/gorm"
+)
+
+// ========== 组织/团队订阅 ==========
+
+func (ss *SubscriptionService) GetOrganizationById(id uint) *model.Organization {
+	org := &model.Organization{}
+	DB.Where("id = ?", id).Preload("Owner").First(org)
+	return org
+}
+
+// GetUserOrganization 用户所在的组织(所有者或成员), 未加入时 Id 为 0
+func (ss *SubscriptionService) GetUserOrganization(userId uint) *model.Organization {
+	m := &model.OrganizationMember{}
+	DB.Where("user_id = ?", userId).First(m)
+	if m.Id == 0 {
+		return &model.Organization{}
+	}
+	return ss.GetOrganizationById(m.OrgId)
+}
+
+// OrganizationDetail 组织详情, 包含成员和席位占用
+func (ss *SubscriptionService) OrganizationDetail(org *model.Organization) *model.Organization {
+	DB.Where("org_id = ?", org.Id).Preload("User").Order("id ASC").Find(&org.Members)
+	for _, m := range org.Members {
+		if m.User != nil {
+			m.User = &model.User{IdModel: m.User.IdModel, Username: m.User.Username, Nickname: m.User.Nickname, Email: m.User.Email}
+		}
+	}
+	ss.fillSeats(org)
+	return org
+}
+
+// fillSeats 计算席位数和占用, 席位数取所有者当前有效订阅的套餐
+func (ss *SubscriptionService) fillSeats(org *model.Organization) {
+	org.Seats = ss.orgSeats(org.OwnerId)
+	if org.Members == nil {
+		var n int64
+		DB.Model(&model.OrganizationMember{}).Where("org_id = ?", org.Id).Count(&n)
+		org.Used = int(n)
+		return
+	}
+	org.Used = len(org.Members)
+	for i, m := range org.Members {
+		m.Covered = model.SeatCovered(i, org.Seats)
+	}
+}
+
+// orgSeats 所有者个人订阅有效时的席位数
+func (ss *SubscriptionService) orgSeats(ownerId uint) int {
+	sub := ss.GetUserSubscription(ownerId)
+	if active, _ := subscriptionActiveAt(sub, time.Now().Unix()); !active || sub.Plan == nil {
+		return 0
+	}
+	return sub.Plan.Seats
+}
+
+// ListOrganizations 组织列表(管理员)
+func (ss *SubscriptionService) ListOrganizations(page, pageSize uint, where func(tx *gorm.DB)) (res *model.OrganizationList) {
+	res = &model.OrganizationList{}
+	res.Page = int64(page)
+	res.PageSize = int64(pageSize)
+	tx := DB.Model(&model.Organization{})
+	if where != nil {
+		where(tx)
+	}
+	tx.Count(&res.Total)
+	tx.Scopes(Paginate(page, pageSize))
+	tx.Preload("Owner").Order("id DESC").Find(&res.Organizations)
+	for _, org := range res.Organizations {
+		ss.fillSeats(org)
+	}
+	return
+}
+
+// CreateOrganization 创建组织, 创建者成为所有者并占用第一个席位
+// requirePlan 为 true 时(用户自助创建)要求所有者的有效订阅是多席位套餐
+func (ss *SubscriptionService) CreateOrganization(ownerId uint, name string, requirePlan bool) (*model.Organization, error) {
+	name = strings.TrimSpace(name)
+	if AllService.UserService.InfoById(ownerId).Id == 0 {
+		return nil, errors.New("UserNotFound")
+	}
+	if requirePlan && ss.orgSeats(ownerId) <= 0 {
+		return nil, errors.New("OrgPlanRequired")
+	}
+	org := &model.Organization{Name: name, OwnerId: ownerId}
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		var n int64
+		tx.Model(&model.OrganizationMember{}).Where("user_id = ?", ownerId).Count(&n)
+		if n > 0 {
+			return errors.New("UserInOrganization")
+		}
+		if err := tx.Create(org).Error; err != nil {
+			return err
+		}
+		return tx.Create(&model.OrganizationMember{OrgId: org.Id, UserId: ownerId, Role: model.OrgRoleOwner, InvitedBy: ownerId}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	AllService.EntitlementService.Invalidate(ownerId)
+	return org, nil
+}
+
+// DeleteOrganization 解散组织, 成员失去组织提供的订阅
+func (ss *SubscriptionService) DeleteOrganization(id uint) error {
+	var userIds []uint
+	DB.Model(&model.OrganizationMember{}).Where("org_id = ?", id).Pluck("user_id", &userIds)
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("org_id = ?", id).Delete(&model.OrganizationMember{}).Error; err != nil {
+			return err
+		}
+		// 团队地址簿转为所有者的个人地址簿
+		if err := tx.Model(&model.AddressBookCollection{}).Where("org_id = ?", id).Update("org_id", 0).Error; err != nil {
+			return err
+		}
+		return tx.Where("id = ?", id).Delete(&model.Organization{}).Error
+	})
+	if err != nil {
+		return err
+	}
+	for _, uid := range userIds {
+		AllService.EntitlementService.Invalidate(uid)
+	}
+	return nil
+}
+
+// AddOrganizationMember 添加成员, 席位已满或用户已加入其他组织时拒绝
+func (ss *SubscriptionService) AddOrganizationMember(orgId, userId, invitedBy uint) error {
+	org := ss.GetOrganizationById(orgId)
+	if org.Id == 0 {
+		return errors.New("OrganizationNotFound")
+	}
+	if AllService.UserService.InfoById(userId).Id == 0 {
+		return errors.New("UserNotFound")
+	}
+	seats := ss.orgSeats(org.OwnerId)
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		var n int64
+		tx.Model(&model.OrganizationMember{}).Where("user_id = ?", userId).Count(&n)
+		if n > 0 {
+			return errors.New("UserInOrganization")
+		}
+		tx.Model(&model.OrganizationMember{}).Where("org_id = ?", orgId).Count(&n)
+		if int(n) >= seats {
+			return errors.New("OrgSeatsFull")
+		}
+		return tx.Create(&model.OrganizationMember{OrgId: orgId, UserId: userId, Role: model.OrgRoleMember, InvitedBy: invitedBy}).Error
+	})
+	if err != nil {
+		return err
+	}
+	AllService.EntitlementService.Invalidate(userId)
+	Logger.Info("Organization member added, org: ", orgId, " user: ", userId, " by: ", invitedBy)
+	return nil
+}
+
+// RemoveOrganizationMember 移除成员(也用于成员主动退出), 所有者不能移除, 需解散组织
+func (ss *SubscriptionService) RemoveOrganizationMember(orgId, userId uint) error {
+	m := &model.OrganizationMember{}
+	DB.Where("org_id = ? AND user_id = ?", orgId, userId).First(m)
+	if m.Id == 0 {
+		return errors.New("OrgMemberNotFound")
+	}
+	if m.Role == model.OrgRoleOwner {
+		return errors.New("OrgOwnerCannotLeave")
+	}
+	if err := DB.Delete(m).Error; err != nil {
+		return err
+	}
+	AllService.EntitlementService.Invalidate(userId)
+	return nil
+}
+
+// orgSubscription 用户占用组织席位时返回所有者的订阅, 否则 Id 为 0
+func (ss *SubscriptionService) orgSubscription(userId uint) *model.UserSubscription {
+	m := &model.OrganizationMember{}
+	DB.Where("user_id = ?", userId).First(m)
+	if m.Id == 0 || m.Role == model.OrgRoleOwner {
+		return &model.UserSubscription{}
+	}
+	org := &model.Organization{}
+	DB.Where("id = ?", m.OrgId).First(org)
+	if org.Id == 0 {
+		return &model.UserSubscription{}
+	}
+	seats := ss.orgSeats(org.OwnerId)
+	var rank int64
+	DB.Model(&model.OrganizationMember{}).Where("org_id = ? AND id < ?", m.OrgId, m.Id).Count(&rank)
+	if !model.SeatCovered(int(rank), seats) {
+		return &model.UserSubscription{}
+	}
+	return ss.GetUserSubscription(org.OwnerId)
+}
+
+// EffectiveSubscription 用户实际生效的订阅: 个人订阅有效时为个人订阅, 否则为占用席位的组织所有者的订阅
+// 都无效时返回个人订阅记录(可能 Id 为 0)
+func (ss *SubscriptionService) EffectiveSubscription(userId uint) *model.UserSubscription {
+	sub := ss.GetUserSubscription(userId)
+	now := time.Now().Unix()
+	if active, _ := subscriptionActiveAt(sub, now); active {
+		return sub
+	}
+	if org := ss.orgSubscription(userId); org.Id > 0 {
+		if active, _ := subscriptionActiveAt(org, now); active {
+			return org
+		}
+	}
+	return sub
+}
@@ -1,7 +1,9 @@
 package service
 
 import (
+	"crypto/hmac"
 	"crypto/md5"
+	"crypto/sha256"
 	"crypto/subtle"
 	"encoding/hex"
 	"encoding/json"
@@ -11,9 +13,11 @@ import (
 	"net/http"
 	"net/url"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/lejianwen/rustdesk-api/v2/lib/trace"
 	"github.com/lejianwen/rustdesk-api/v2/model"
 )
 
@@ -34,6 +38,16 @@ type EpayQueryResp struct {
 	Status     int    `json:"status"` // 1=成功 0=失败/处理中
 }
 
+// EpayMapiResp API 下单(mapi.php)响应, 按渠道返回 payurl/qrcode/urlscheme 之一
+type EpayMapiResp struct {
+	Code      int    `json:"code"`
+	Msg       string `json:"msg"`
+	TradeNo   string `json:"trade_no"`
+	PayURL    string `json:"payurl"`
+	QRCode    string `json:"qrcode"`
+	URLScheme string `json:"urlscheme"`
+}
+
 type EpayRefundResp struct {
 	Code int    `json:"code"`
 	Msg  string `json:"msg"`
@@ -90,6 +104,39 @@ func (ps *PaymentService) Verify(params map[string]string) bool {
 	return subtle.ConstantTimeCompare([]byte(strings.ToLower(got)), []byte(strings.ToLower(expected))) == 1
 }
 
+// SupportedCurrencies 支付网关支持的币种(payment.epay.currencies, 默认仅基础币种)
+func (ps *PaymentService) SupportedCurrencies() []string {
+	var res []string
+	for _, c := range Config.Payment.EasyPay.Currencies {
+		c = strings.ToUpper(strings.TrimSpace(c))
+		if model.IsSupportedCurrency(c) {
+			res = append(res, c)
+		}
+	}
+	if len(res) == 0 {
+		res = []string{model.SupportedCurrencies[0]}
+	}
+	return res
+}
+
+// PayTypes 允许用户选择的支付渠道, 为空时由网关收银台选择
+func (ps *PaymentService) PayTypes() []string {
+	return model.EnabledPayTypes(ps.getConfig().PayTypes)
+}
+
+// CheckPayType 校验用户选择的支付渠道, 为空表示不指定
+func (ps *PaymentService) CheckPayType(payType string) error {
+	if payType == "" {
+		return nil
+	}
+	for _, t := range ps.PayTypes() {
+		if t == payType {
+			return nil
+		}
+	}
+	return errors.New("PayTypeNotEnabled")
+}
+
 // PaySubmitURL 获取 EasyPay 提交地址
 func (ps *PaymentService) PaySubmitURL() string {
 	cfg := ps.getConfig()
@@ -97,38 +144,124 @@ func (ps *PaymentService) PaySubmitURL() string {
 }
 
 // BuildPayParams 构建提交到 EasyPay 的表单参数
-func (ps *PaymentService) BuildPayParams(outTradeNo, subject, moneyYuan string) map[string]string {
+// 非基础币种时附带 currency 参数(需网关支持, 见 payment.epay.currencies)
+// payType 为订单上的支付渠道, 为空时由网关收银台选择
+func (ps *PaymentService) BuildPayParams(outTradeNo, subject string, amount model.Money, payType string) map[string]string {
+	params := ps.payParams(outTradeNo, subject, amount, payType)
+	params["sign"] = ps.Sign(params)
+	return params
+}
+
+// payParams 未签名的下单参数, 页面跳转和 API 下单共用
+func (ps *PaymentService) payParams(outTradeNo, subject string, amount model.Money, payType string) map[string]string {
 	cfg := ps.getConfig()
+	if payType == "" {
+		payType = model.PayTypeGateway
+	}
 
 	params := map[string]string{
 		"pid":          cfg.Pid,
-		"type":         "epay",
+		"type":         payType,
 		"out_trade_no": outTradeNo,
 		"name":         subject,
-		"money":        moneyYuan,
+		"money":        amount.String(),
 		"sign_type":    "MD5",
 	}
+	if amount.Currency != model.SupportedCurrencies[0] {
+		params["currency"] = amount.Currency
+	}
 	if cfg.NotifyURL != "" {
 		params["notify_url"] = cfg.NotifyURL
 	}
 	if cfg.ReturnURL != "" {
 		params["return_url"] = cfg.ReturnURL
 	}
+	return params
+}
 
-	// 生成签名
-	sign := ps.Sign(params)
-	params["sign"] = sign
+// CreateQRCode 通过 API 下单(mapi.php)获取扫码支付内容, 供无浏览器的桌面客户端展示二维码
+// payType 必须是具体渠道, 网关不支持收银台模式
+func (ps *PaymentService) CreateQRCode(outTradeNo, subject string, amount model.Money, payType, clientIP string) (*EpayMapiResp, error) {
+	cfg := ps.getConfig()
+	params := ps.payParams(outTradeNo, subject, amount, payType)
+	params["clientip"] = clientIP
+	params["device"] = "pc"
+	params["sign"] = ps.Sign(params)
 
-	return params
+	data := url.Values{}
+	for k, v := range params {
+		data.Set(k, v)
+	}
+	resp, err := ps.getHTTPClient().PostForm(strings.TrimRight(cfg.BaseURL, "/")+"/mapi.php", data)
+	if err != nil {
+		Logger.Error("Payment mapi request failed: ", err)
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		Logger.Error("Payment mapi read body failed: ", err)
+		return nil, err
+	}
+
+	var result EpayMapiResp
+	if err := json.Unmarshal(body, &result); err != nil {
+		Logger.Error("Payment mapi parse response failed: ", err, " body: ", string(body))
+		return nil, err
+	}
+	if result.Code != 1 {
+		return &result, errors.New(result.Msg)
+	}
+	return &result, nil
 }
 
 // BuildPayURL 构建支付跳转URL（返回本服务的中转页面，用于以 POST 方式提交到网关）
+// URL 带过期时间和 HMAC 签名，防止篡改参数或枚举订单号
 func (ps *PaymentService) BuildPayURL(outTradeNo string) string {
+	expires := time.Now().Add(AllService.SubscriptionService.OrderExpireAfterFor(model.PayMethodOnline)).Unix()
 	q := url.Values{}
 	q.Set("out_trade_no", outTradeNo)
+	q.Set("expires", strconv.FormatInt(expires, 10))
+	sign := ps.payURLSign(outTradeNo, expires)
+	if sign == "" {
+		Logger.Error("Payment key not configured, pay url of ", outTradeNo, " can not be signed")
+	}
+	q.Set("sign", sign)
 	return "/api/payment/submit?" + q.Encode()
 }
 
+// VerifyPayURL 校验支付跳转URL的签名和过期时间
+func (ps *PaymentService) VerifyPayURL(outTradeNo, expires, sign string) error {
+	exp, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || sign == "" {
+		return errors.New("PayURLInvalid")
+	}
+	expected := ps.payURLSign(outTradeNo, exp)
+	if expected == "" || !hmac.Equal([]byte(strings.ToLower(sign)), []byte(expected)) {
+		return errors.New("PayURLInvalid")
+	}
+	if time.Now().Unix() > exp {
+		return errors.New("PayURLExpired")
+	}
+	return nil
+}
+
+// payURLSign 支付跳转URL签名: HMAC-SHA256(商户密钥, "pay_url|out_trade_no|expires")
+// 未配置商户密钥时返回空, 空密钥签名可被任意伪造, 此时生成的URL都无法通过校验
+func (ps *PaymentService) payURLSign(outTradeNo string, expires int64) string {
+	return payURLSignWith(ps.getConfig().Key, outTradeNo, expires)
+}
+
+func payURLSignWith(key, outTradeNo string, expires int64) string {
+	if key == "" {
+		return ""
+	}
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write([]byte(fmt.Sprintf("pay_url|%s|%d", outTradeNo, expires)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
 // Query 查询订单状态
 func (ps *PaymentService) Query(outTradeNo string) (*EpayQueryResp, error) {
 	cfg := ps.getConfig()
@@ -165,14 +298,14 @@ func (ps *PaymentService) Query(outTradeNo string) (*EpayQueryResp, error) {
 }
 
 // Refund 发起退款
-func (ps *PaymentService) Refund(tradeNo, moneyYuan string) (*EpayRefundResp, error) {
+func (ps *PaymentService) Refund(tradeNo string, amount model.Money) (*EpayRefundResp, error) {
 	cfg := ps.getConfig()
 
 	data := url.Values{}
 	data.Set("pid", cfg.Pid)
 	data.Set("key", cfg.Key)
 	data.Set("trade_no", tradeNo)
-	data.Set("money", moneyYuan)
+	data.Set("money", amount.String())
 
 	reqURL := cfg.BaseURL + "/api.php"
 
@@ -211,22 +344,26 @@ func (ps *PaymentService) getHTTPClient() *http.Client {
 		timeout = 15 * time.Second
 	}
 
+	// 启用追踪时为网关请求记录 client span
+	var transport http.RoundTripper
 	if Config.Proxy.Enable && Config.Proxy.Host != "" {
 		proxyURL, err := url.Parse(Config.Proxy.Host)
 		if err != nil {
 			Logger.Warn("Invalid proxy URL: ", err)
-			return &http.Client{Timeout: timeout}
-		}
-		transport := &http.Transport{
-			Proxy: http.ProxyURL(proxyURL),
-		}
-		return &http.Client{
-			Transport: transport,
-			Timeout:   timeout,
+		} else {
+			transport = &http.Transport{
+				Proxy: http.ProxyURL(proxyURL),
+			}
 		}
 	}
+	if trace.Enabled() {
+		transport = trace.Transport(transport)
+	}
 
-	return &http.Client{Timeout: timeout}
+	return &http.Client{
+		Transport: transport,
+		Timeout:   timeout,
+	}
 }
 
 // IsEnabled 检查支付功能是否启用
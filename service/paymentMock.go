@@ -0,0 +1,153 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lejianwen/rustdesk-api/v2/model"
+)
+
+// PaymentProviderMock 内置模拟网关, 见 payment.provider
+const PaymentProviderMock = "mock"
+
+// MockCheckoutPath 模拟网关的收银台页面
+const MockCheckoutPath = "/api/payment/mock/checkout"
+
+// MockPaymentService 内置的模拟支付网关, 仅用于开发环境端到端测试 下单 -> 回调 -> 开通
+// 收银台页面打开即视为支付成功, 立即向 notify_url 发送签名后的回调; 签名、查单和退款复用易支付协议
+type MockPaymentService struct {
+	PaymentService
+	paid sync.Map // out_trade_no -> 回调参数, 供查单使用
+}
+
+func NewMockPaymentService() *MockPaymentService {
+	return &MockPaymentService{}
+}
+
+// IsEnabled 模拟网关总是可用, 不需要配置商户信息
+func (m *MockPaymentService) IsEnabled() bool {
+	return true
+}
+
+// PaySubmitURL 下单表单提交到本服务的模拟收银台
+func (m *MockPaymentService) PaySubmitURL() string {
+	return MockCheckoutPath
+}
+
+// CreateQRCode 扫码内容为模拟收银台链接, 用浏览器打开即完成支付
+func (m *MockPaymentService) CreateQRCode(outTradeNo, subject string, amount model.Money, payType, clientIP string) (*EpayMapiResp, error) {
+	params := m.BuildPayParams(outTradeNo, subject, amount, payType)
+	q := url.Values{}
+	for k, v := range params {
+		q.Set(k, v)
+	}
+	return &EpayMapiResp{
+		Code:    1,
+		TradeNo: mockTradeNo(outTradeNo),
+		QRCode:  MockCheckoutPath + "?" + q.Encode(),
+	}, nil
+}
+
+// Query 已在模拟收银台支付的订单返回成功, 对账可以补上回调失败的订单
+func (m *MockPaymentService) Query(outTradeNo string) (*EpayQueryResp, error) {
+	v, ok := m.paid.Load(outTradeNo)
+	if !ok {
+		return &EpayQueryResp{Code: 1, OutTradeNo: outTradeNo}, nil
+	}
+	notify := v.(map[string]string)
+	return &EpayQueryResp{
+		Code:       1,
+		TradeNo:    notify["trade_no"],
+		OutTradeNo: outTradeNo,
+		Type:       notify["type"],
+		Pid:        notify["pid"],
+		Name:       notify["name"],
+		Money:      notify["money"],
+		Status:     1,
+	}, nil
+}
+
+// Refund 模拟网关退款总是成功
+func (m *MockPaymentService) Refund(tradeNo string, amount model.Money) (*EpayRefundResp, error) {
+	Logger.Info("Mock payment refund, trade_no: ", tradeNo, " money: ", amount.String())
+	return &EpayRefundResp{Code: 1, Msg: "success"}, nil
+}
+
+// Checkout 模拟收银台: 校验下单参数签名后立即发送支付成功回调, 返回带签名参数的同步跳转地址
+// 未配置 notify_url 时直接在进程内处理回调
+func (m *MockPaymentService) Checkout(ctx context.Context, params map[string]string) (string, error) {
+	if !m.Verify(params) {
+		return "", errors.New("SignVerifyFailed")
+	}
+	outTradeNo := params["out_trade_no"]
+	if outTradeNo == "" || params["money"] == "" {
+		return "", errors.New("ParamsError")
+	}
+
+	notify := map[string]string{
+		"pid":          params["pid"],
+		"trade_no":     mockTradeNo(outTradeNo),
+		"out_trade_no": outTradeNo,
+		"type":         params["type"],
+		"name":         params["name"],
+		"money":        params["money"],
+		"trade_status": "TRADE_SUCCESS",
+		"sign_type":    "MD5",
+	}
+	notify["sign"] = m.Sign(notify)
+	m.paid.Store(outTradeNo, notify)
+
+	q := url.Values{}
+	for k, v := range notify {
+		q.Set(k, v)
+	}
+	if notifyURL := params["notify_url"]; notifyURL != "" {
+		if err := m.sendNotify(notifyURL, q); err != nil {
+			Logger.Warn("Mock payment notify failed, out_trade_no: ", outTradeNo, " err: ", err)
+			return "", errors.New("NotifyFailed")
+		}
+	} else if err := AllService.SubscriptionService.HandleNotify(ctx, notify, PaymentProviderMock); err != nil {
+		return "", err
+	}
+
+	returnURL := params["return_url"]
+	if returnURL == "" {
+		return "", nil
+	}
+	sep := "?"
+	if strings.Contains(returnURL, "?") {
+		sep = "&"
+	}
+	return returnURL + sep + q.Encode(), nil
+}
+
+// sendNotify 按易支付的方式以 GET 请求回调地址, 响应必须为 success
+func (m *MockPaymentService) sendNotify(notifyURL string, q url.Values) error {
+	sep := "?"
+	if strings.Contains(notifyURL, "?") {
+		sep = "&"
+	}
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Get(notifyURL + sep + q.Encode())
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	if strings.TrimSpace(string(body)) != "success" {
+		return fmt.Errorf("notify response %d: %s", resp.StatusCode, body)
+	}
+	return nil
+}
+
+// mockTradeNo 模拟网关交易号, 同一订单保持不变
+func mockTradeNo(outTradeNo string) string {
+	return "MOCK" + outTradeNo
+}
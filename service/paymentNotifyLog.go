@@ -0,0 +1,79 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+
+	"github.com/lejianwen/rustdesk-api/v2/model"
+	"github.com/lejianwen/rustdesk-api/v2/model/custom_types"
+	"gorm.io/gorm"
+)
+
+// notifyFingerprint 回调参数摘要: 全部参数(含签名)按 key 排序后计算 SHA-256
+func notifyFingerprint(params map[string]string) string {
+	keys := make([]string, 0, len(params))
+	for k := range params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	h := sha256.New()
+	for _, k := range keys {
+		h.Write([]byte(k))
+		h.Write([]byte{0})
+		h.Write([]byte(params[k]))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// replayDecision 完全重复的回调的处理方式
+// 上一次成功: 直接返回成功(幂等), 不再处理; 上一次为临时错误: 网关重试, 正常处理
+// 其他结果(验签失败、金额不符、订单不存在、已被拒绝的重放): 拒绝, 结果记为 replay
+func replayDecision(prevResult string) (process bool, err error) {
+	switch prevResult {
+	case "", "error":
+		return true, nil
+	case "success":
+		return false, nil
+	}
+	return false, errNotifyReplay
+}
+
+// recordNotify 写入回调记录, 失败只记录日志, 不影响回调处理结果
+func (ss *SubscriptionService) recordNotify(ctx context.Context, params map[string]string, fingerprint, sourceIP, result string, signValid bool, err error, replayOf uint) {
+	raw, _ := json.Marshal(params)
+	l := &model.PaymentNotifyLog{
+		OutTradeNo:  params["out_trade_no"],
+		TradeNo:     params["trade_no"],
+		Fingerprint: fingerprint,
+		Params:      custom_types.AutoJson(raw),
+		SourceIp:    sourceIP,
+		SignValid:   signValid,
+		Result:      result,
+		ReplayOf:    replayOf,
+	}
+	if err != nil {
+		l.Error = err.Error()
+	}
+	if e := DB.WithContext(ctx).Create(l).Error; e != nil {
+		Logger.Error("Create payment notify log failed: ", e)
+	}
+}
+
+// ListNotifyLogs 支付回调记录, 按时间倒序
+func (ss *SubscriptionService) ListNotifyLogs(page, pageSize uint, where func(tx *gorm.DB)) (res *model.PaymentNotifyLogList) {
+	res = &model.PaymentNotifyLogList{}
+	res.Page = int64(page)
+	res.PageSize = int64(pageSize)
+	tx := DB.Model(&model.PaymentNotifyLog{})
+	if where != nil {
+		where(tx)
+	}
+	tx.Count(&res.Total)
+	tx.Scopes(Paginate(page, pageSize))
+	tx.Order("id DESC").Find(&res.PaymentNotifyLogs)
+	return
+}
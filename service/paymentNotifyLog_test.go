@@ -0,0 +1,41 @@
+package service
+
+import "testing"
+
+func TestNotifyFingerprint(t *testing.T) {
+	a := notifyFingerprint(map[string]string{"out_trade_no": "1", "money": "9.90", "sign": "abc"})
+	b := notifyFingerprint(map[string]string{"sign": "abc", "money": "9.90", "out_trade_no": "1"})
+	if a != b {
+		t.Errorf("fingerprint depends on map order")
+	}
+	// 拼接边界不同的参数不能得到相同摘要
+	if notifyFingerprint(map[string]string{"a": "bc"}) == notifyFingerprint(map[string]string{"ab": "c"}) {
+		t.Errorf("fingerprint collision on key/value boundary")
+	}
+}
+
+func TestReplayDecision(t *testing.T) {
+	cases := []struct {
+		prev    string
+		process bool
+		reject  bool
+	}{
+		{"", true, false},
+		{"success", false, false},
+		{"error", true, false},
+		{"sign_fail", false, true},
+		{"amount_mismatch", false, true},
+		{"invalid", false, true},
+		{"order_not_found", false, true},
+		{"replay", false, true},
+	}
+	for _, c := range cases {
+		process, err := replayDecision(c.prev)
+		if process != c.process || (err != nil) != c.reject {
+			t.Errorf("replayDecision(%q) = %v, %v", c.prev, process, err)
+		}
+		if c.reject && notifyResult(err) != "replay" {
+			t.Errorf("rejected replay of %q recorded as %s", c.prev, notifyResult(err))
+		}
+	}
+}
@@ -0,0 +1,227 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lejianwen/rustdesk-api/v2/model"
+	"gorm.io/gorm"
+)
+
+// PaymentProofService 线下转账: 付款凭证上传与审核
+type PaymentProofService struct {
+}
+
+const defaultProofMaxSize = 5 << 20
+
+var defaultProofTypes = []string{"image/jpeg", "image/png", "application/pdf"}
+
+var proofExts = map[string]string{
+	"image/jpeg":      ".jpg",
+	"image/png":       ".png",
+	"image/gif":       ".gif",
+	"image/webp":      ".webp",
+	"application/pdf": ".pdf",
+}
+
+// IsEnabled 是否启用线下转账
+func (ps *PaymentProofService) IsEnabled() bool {
+	return Config.Payment.Manual.Enable
+}
+
+// Instructions 收款说明, 可在管理后台设置中覆盖配置文件
+func (ps *PaymentProofService) Instructions() string {
+	return AllService.SystemSettingService.SettingString(SettingManualInstructions)
+}
+
+func (ps *PaymentProofService) maxSize() int64 {
+	if Config.Payment.Manual.MaxSize > 0 {
+		return Config.Payment.Manual.MaxSize
+	}
+	return defaultProofMaxSize
+}
+
+// detectType 按文件内容识别类型, 不信任客户端提交的 Content-Type
+func (ps *PaymentProofService) detectType(head []byte) (string, bool) {
+	ct := http.DetectContentType(head)
+	if i := strings.Index(ct, ";"); i >= 0 {
+		ct = ct[:i]
+	}
+	allowed := Config.Payment.Manual.AllowedTypes
+	if len(allowed) == 0 {
+		allowed = defaultProofTypes
+	}
+	for _, t := range allowed {
+		if strings.EqualFold(strings.TrimSpace(t), ct) {
+			return ct, true
+		}
+	}
+	return ct, false
+}
+
+// Upload 上传订单付款凭证, 同一订单之前待审核的凭证被新凭证取代
+func (ps *PaymentProofService) Upload(ctx context.Context, userId uint, outTradeNo string, fh *multipart.FileHeader, note string) (*model.PaymentProof, error) {
+	if !ps.IsEnabled() {
+		return nil, errors.New("ManualPaymentDisabled")
+	}
+	order := &model.Order{}
+	DB.Where("out_trade_no = ? AND user_id = ?", outTradeNo, userId).First(order)
+	if order.Id == 0 {
+		return nil, errors.New("OrderNotFound")
+	}
+	if order.Status != model.OrderStatusPending {
+		return nil, errors.New("OrderNotPending")
+	}
+	if order.PayMethod != model.PayMethodManual {
+		return nil, errors.New("OrderNotManual")
+	}
+	if fh.Size <= 0 {
+		return nil, errors.New("ProofFileRequired")
+	}
+	if fh.Size > ps.maxSize() {
+		return nil, errors.New("ProofFileTooLarge")
+	}
+
+	f, err := fh.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	head := make([]byte, 512)
+	n, err := io.ReadFull(f, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return nil, err
+	}
+	head = head[:n]
+	ct, ok := ps.detectType(head)
+	if !ok {
+		return nil, errors.New("ProofFileTypeNotAllowed")
+	}
+
+	key := AllService.StorageService.NewKey("receipts", strconv.FormatUint(uint64(order.Id), 10), proofExts[ct])
+	if err := AllService.StorageService.Put(ctx, key, io.MultiReader(bytes.NewReader(head), f), fh.Size, ct); err != nil {
+		Logger.Error("Save payment proof failed: ", err)
+		return nil, err
+	}
+
+	proof := &model.PaymentProof{
+		OrderId:     order.Id,
+		UserId:      userId,
+		FileKey:     key,
+		FileName:    filepath.Base(fh.Filename),
+		FileSize:    fh.Size,
+		ContentType: ct,
+		Note:        note,
+		Status:      model.PaymentProofPending,
+	}
+	err = DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&model.PaymentProof{}).
+			Where("order_id = ? AND status = ?", order.Id, model.PaymentProofPending).
+			Updates(map[string]interface{}{
+				"status":        model.PaymentProofRejected,
+				"review_remark": "superseded",
+				"reviewed_at":   time.Now().Unix(),
+			}).Error; err != nil {
+			return err
+		}
+		return tx.Create(proof).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	Logger.Info("Payment proof uploaded, order: ", order.OutTradeNo, " proof: ", proof.Id)
+	return proof, nil
+}
+
+func (ps *PaymentProofService) InfoById(id uint) *model.PaymentProof {
+	p := &model.PaymentProof{}
+	DB.Where("id = ?", id).First(p)
+	return p
+}
+
+func (ps *PaymentProofService) List(page, pageSize uint, where func(tx *gorm.DB)) (res *model.PaymentProofList) {
+	res = &model.PaymentProofList{}
+	res.Page = int64(page)
+	res.PageSize = int64(pageSize)
+	tx := DB.Model(&model.PaymentProof{})
+	if where != nil {
+		where(tx)
+	}
+	tx.Count(&res.Total)
+	tx.Scopes(Paginate(page, pageSize))
+	tx.Preload("Order").Preload("Order.User").Find(&res.PaymentProofs)
+	return
+}
+
+// ListByOrder 订单的全部凭证
+func (ps *PaymentProofService) ListByOrder(orderId uint) []*model.PaymentProof {
+	var proofs []*model.PaymentProof
+	DB.Where("order_id = ?", orderId).Order("id ASC").Find(&proofs)
+	return proofs
+}
+
+// OpenFile 读取凭证文件
+func (ps *PaymentProofService) OpenFile(ctx context.Context, p *model.PaymentProof) (io.ReadCloser, error) {
+	return AllService.StorageService.Open(ctx, p.FileKey)
+}
+
+// FileURL 凭证文件的临时下载地址
+func (ps *PaymentProofService) FileURL(p *model.PaymentProof) (string, int64, error) {
+	return AllService.StorageService.SignedURL(p.FileKey)
+}
+
+// review 将待审核凭证标记为已审核, 条件更新保证同一凭证只被处理一次
+func (ps *PaymentProofService) review(id uint, status int, operatorId uint, remark string) (*model.PaymentProof, error) {
+	p := ps.InfoById(id)
+	if p.Id == 0 {
+		return nil, errors.New("PaymentProofNotFound")
+	}
+	res := DB.Model(&model.PaymentProof{}).
+		Where("id = ? AND status = ?", id, model.PaymentProofPending).
+		Updates(map[string]interface{}{
+			"status":        status,
+			"reviewer_id":   operatorId,
+			"reviewed_at":   time.Now().Unix(),
+			"review_remark": remark,
+		})
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return nil, errors.New("PaymentProofReviewed")
+	}
+	return p, nil
+}
+
+// Approve 审核通过, 订单入账并激活订阅
+func (ps *PaymentProofService) Approve(id, operatorId uint, remark string) error {
+	p, err := ps.review(id, model.PaymentProofApproved, operatorId, remark)
+	if err != nil {
+		return err
+	}
+	if err := AllService.SubscriptionService.MarkOrderPaid(p.OrderId, fmt.Sprintf("proof-%d", p.Id), operatorId, remark); err != nil {
+		// 入账失败时恢复为待审核, 便于处理后重试
+		DB.Model(&model.PaymentProof{}).Where("id = ?", p.Id).Updates(map[string]interface{}{
+			"status":      model.PaymentProofPending,
+			"reviewer_id": 0,
+			"reviewed_at": 0,
+		})
+		return err
+	}
+	return nil
+}
+
+// Reject 驳回凭证, 订单保持待支付, 用户可重新上传
+func (ps *PaymentProofService) Reject(id, operatorId uint, remark string) error {
+	_, err := ps.review(id, model.PaymentProofRejected, operatorId, remark)
+	return err
+}
@@ -0,0 +1,152 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/lejianwen/rustdesk-api/v2/lib/trace"
+	"github.com/lejianwen/rustdesk-api/v2/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// pollQueryInterval 轮询时同一订单向网关查单的最小间隔, 回调丢失时也能及时入账
+const pollQueryInterval = 10 * time.Second
+
+var pollQueried sync.Map // out_trade_no -> time.Time
+
+// pollOrder 用户轮询待支付订单时按间隔向网关查单, 网关确认支付成功则入账并重新读取订单
+func (ss *SubscriptionService) pollOrder(order *model.Order, meta map[string]interface{}) {
+	if order.Status != model.OrderStatusPending {
+		pollQueried.Delete(order.OutTradeNo)
+		return
+	}
+	if order.PaySubmitAt == 0 || !AllService.Payment().IsEnabled() {
+		return
+	}
+	now := time.Now()
+	if last, ok := pollQueried.Load(order.OutTradeNo); ok && now.Sub(last.(time.Time)) < pollQueryInterval {
+		return
+	}
+	pollQueried.Store(order.OutTradeNo, now)
+	if ss.reconcileOrder(order, subscriptionChange{Source: model.SubscriptionSourceNotify, Metadata: meta}) {
+		pollQueried.Delete(order.OutTradeNo)
+		DB.Where("id = ?", order.Id).First(order)
+	}
+}
+
+// qrCodePayType 扫码支付渠道: 使用订单或请求中的渠道, 都未指定时使用第一个启用的渠道
+func (ss *SubscriptionService) qrCodePayType(order *model.Order, payType string) (string, error) {
+	if payType == "" {
+		payType = order.PayType
+	}
+	if payType == "" {
+		types := AllService.Payment().PayTypes()
+		if len(types) == 0 {
+			return "", errors.New("PayTypeRequired")
+		}
+		return types[0], nil
+	}
+	if err := AllService.Payment().CheckPayType(payType); err != nil {
+		return "", err
+	}
+	return payType, nil
+}
+
+// QRCodePay 为待支付的在线订单获取扫码支付内容
+// 同一订单和渠道重复请求时复用已获取的内容; 订单已通过页面跳转发起过支付或更换了渠道时, 关闭旧订单并重新下单
+func (ss *SubscriptionService) QRCodePay(ctx context.Context, userId uint, outTradeNo, payType, clientIP string) (res *model.QRCodePayment, err error) {
+	ctx, span := trace.Start(ctx, "subscription.qrcode_pay", trace.KindInternal)
+	defer func() { span.SetError(err); span.End() }()
+
+	var order *model.Order
+	expired := false
+	err = DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		cur := &model.Order{}
+		tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("out_trade_no = ?", outTradeNo).First(cur)
+		if cur.Id == 0 || cur.UserId != userId {
+			return errors.New("OrderNotFound")
+		}
+		if cur.Status != model.OrderStatusPending || cur.PayMethod != model.PayMethodOnline || cur.Amount <= 0 {
+			return errors.New("OrderNotPayable")
+		}
+		// 超时未支付的订单直接关闭
+		if ss.IsOrderExpired(cur) {
+			expired = true
+			return tx.Model(cur).Update("status", model.OrderStatusClosed).Error
+		}
+		pt, err := ss.qrCodePayType(cur, payType)
+		if err != nil {
+			return err
+		}
+		if cur.QRCode != "" && cur.PayType == pt {
+			order = cur
+			return nil
+		}
+		if cur.PaySubmitAt > 0 {
+			next, err := ss.ReissueOrder(tx, cur, 0)
+			if err != nil {
+				return err
+			}
+			cur = next
+		}
+
+		resp, err := AllService.Payment().CreateQRCode(cur.OutTradeNo, cur.Subject, cur.Total(), pt, clientIP)
+		if err != nil {
+			Logger.Error("Create qrcode failed, order: ", cur.OutTradeNo, " err: ", err)
+			return errors.New("QRCodeCreateFailed")
+		}
+		code := resp.QRCode
+		if code == "" {
+			code = resp.PayURL
+		}
+		if code == "" {
+			return errors.New("QRCodeCreateFailed")
+		}
+		cur.PayType = pt
+		cur.QRCode = code
+		cur.PaySubmitAt = time.Now().Unix()
+		if err := tx.Model(cur).Updates(map[string]interface{}{
+			"pay_type":      cur.PayType,
+			"qr_code":       cur.QRCode,
+			"pay_submit_at": cur.PaySubmitAt,
+		}).Error; err != nil {
+			return err
+		}
+		order = cur
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if expired {
+		return nil, errors.New("OrderExpired")
+	}
+	ObserveFunnel(FunnelPaySubmit, order.PlanId, model.PayMethodOnline)
+	return &model.QRCodePayment{
+		OutTradeNo: order.OutTradeNo,
+		PayType:    order.PayType,
+		QRCode:     order.QRCode,
+		Amount:     order.Amount,
+		Currency:   order.Currency,
+		ExpireAt:   ss.OrderExpireAt(order),
+	}, nil
+}
+
+// OrderPayStatus 查询订单支付状态, 待支付且已发起支付时按间隔向网关查单
+func (ss *SubscriptionService) OrderPayStatus(userId uint, outTradeNo string) (*model.OrderPayStatus, error) {
+	order := &model.Order{}
+	DB.Where("out_trade_no = ? AND user_id = ?", outTradeNo, userId).First(order)
+	if order.Id == 0 {
+		return nil, errors.New("OrderNotFound")
+	}
+	ss.pollOrder(order, map[string]interface{}{"poll": true})
+	return &model.OrderPayStatus{
+		OutTradeNo: order.OutTradeNo,
+		Status:     order.Status,
+		Paid:       order.PaidAt > 0,
+		PaidAt:     order.PaidAt,
+	}, nil
+}
@@ -0,0 +1,37 @@
+package service
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/lejianwen/rustdesk-api/v2/model"
+)
+
+// defaultReturnRedirect 未配置 payment.epay.redirect-url 时, 确认支付结果后跳转的页面
+const defaultReturnRedirect = "/_admin/#/my/subscription"
+
+// ConfirmReturn 处理网关同步跳转(return_url): 验签后返回订单的真实状态
+// 跳转参数可被用户伪造, 只用于定位订单; 订单待支付时向网关查单确认, 不会仅凭跳转参数入账
+func (ss *SubscriptionService) ConfirmReturn(params map[string]string) (*model.Order, error) {
+	if !AllService.Payment().Verify(params) {
+		Logger.Warn("Payment return sign verify failed, out_trade_no: ", params["out_trade_no"])
+		return nil, errors.New("SignVerifyFailed")
+	}
+	if pid := params["pid"]; pid != "" && pid != AllService.Payment().GetConfig().Pid {
+		return nil, errors.New("PidMismatch")
+	}
+	order := ss.GetOrderByOutTradeNo(params["out_trade_no"])
+	if order.Id == 0 {
+		return nil, errors.New("OrderNotFound")
+	}
+	ss.pollOrder(order, map[string]interface{}{"return": true})
+	return order, nil
+}
+
+// ReturnRedirectURL 确认支付结果后跳转的地址
+func (ss *SubscriptionService) ReturnRedirectURL() string {
+	if u := strings.TrimSpace(AllService.Payment().GetConfig().RedirectURL); u != "" {
+		return u
+	}
+	return defaultReturnRedirect
+}
@@ -0,0 +1,51 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lejianwen/rustdesk-api/v2/model"
+)
+
+// sandboxSourceIP 模拟回调写入回调记录的来源, 便于与真实网关回调区分
+const sandboxSourceIP = "sandbox"
+
+// SimulatePaid 测试模式下模拟网关支付成功回调
+// 使用当前商户密钥签名后走完整的 HandleNotify 流程(验签、回调记录、入账、激活订阅、事件), 仅跳过真实网关
+func (ss *SubscriptionService) SimulatePaid(ctx context.Context, orderId, operatorId uint) error {
+	cfg := AllService.Payment().GetConfig()
+	if !cfg.Sandbox {
+		return errors.New("PaymentSandboxDisabled")
+	}
+	order := ss.GetOrderById(orderId)
+	if order.Id == 0 {
+		return errors.New("OrderNotFound")
+	}
+	if order.Status != model.OrderStatusPending {
+		return errors.New("OrderNotPending")
+	}
+
+	payType := order.PayType
+	if payType == "" {
+		payType = model.PayTypeGateway
+	}
+	params := map[string]string{
+		"pid":          cfg.Pid,
+		"trade_no":     fmt.Sprintf("SANDBOX%d%d", time.Now().UnixNano(), order.Id),
+		"out_trade_no": order.OutTradeNo,
+		"type":         payType,
+		"name":         order.Subject,
+		"money":        order.Total().String(),
+		"trade_status": "TRADE_SUCCESS",
+		"sign_type":    "MD5",
+	}
+	params["sign"] = AllService.Payment().Sign(params)
+
+	if err := ss.HandleNotify(ctx, params, sandboxSourceIP); err != nil {
+		return err
+	}
+	Logger.Warn("Sandbox payment simulated, order: ", order.OutTradeNo, " operator: ", operatorId)
+	return nil
+}
@@ -0,0 +1,13 @@
+package service
+
+import "testing"
+
+func TestPayURLSignWith(t *testing.T) {
+	if s := payURLSignWith("", "T1", 100); s != "" {
+		t.Errorf("empty key should not sign, got %s", s)
+	}
+	a := payURLSignWith("secret", "T1", 100)
+	if a == "" || a == payURLSignWith("secret", "T2", 100) || a == payURLSignWith("secret", "T1", 101) || a == payURLSignWith("other", "T1", 100) {
+		t.Errorf("sign should depend on key, order and expires")
+	}
+}
@@ -1,6 +1,9 @@
 package service
 
 import (
+	"errors"
+	"time"
+
 	"github.com/lejianwen/rustdesk-api/v2/model"
 	"gorm.io/gorm"
 )
@@ -151,3 +154,72 @@ func (ps *PeerService) BatchDelete(ids []uint) error {
 func (ps *PeerService) Update(u *model.Peer) error {
 	return DB.Model(u).Updates(u).Error
 }
+
+// peerCleanupInterval 过期设备自动清理间隔
+const peerCleanupInterval = 24 * time.Hour
+
+// StaleScope 过期设备筛选条件
+// orphan: 未绑定用户的设备; inactiveDays: 超过N天未在线的设备; 同时指定时需同时满足
+func (ps *PeerService) StaleScope(orphan bool, inactiveDays int) func(tx *gorm.DB) {
+	return func(tx *gorm.DB) {
+		if orphan {
+			tx.Where("user_id = ?", 0)
+		}
+		if inactiveDays > 0 {
+			lt := time.Now().AddDate(0, 0, -inactiveDays).Unix()
+			tx.Where("last_online_time < ?", lt)
+		}
+	}
+}
+
+// ListStale 过期设备列表
+func (ps *PeerService) ListStale(page, pageSize uint, orphan bool, inactiveDays int) *model.PeerList {
+	return ps.List(page, pageSize, func(tx *gorm.DB) {
+		ps.StaleScope(orphan, inactiveDays)(tx)
+		tx.Order("last_online_time ASC")
+	})
+}
+
+// CleanupStale 清理过期设备, 返回清理数量
+func (ps *PeerService) CleanupStale(orphan bool, inactiveDays int) (int, error) {
+	if !orphan && inactiveDays <= 0 {
+		return 0, errors.New("ParamsError")
+	}
+	var ids []uint
+	tx := DB.Model(&model.Peer{})
+	ps.StaleScope(orphan, inactiveDays)(tx)
+	if err := tx.Pluck("row_id", &ids).Error; err != nil {
+		return 0, err
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	if err := ps.BatchDelete(ids); err != nil {
+		return 0, err
+	}
+	return len(ids), nil
+}
+
+// StartCleanupJob 启动过期设备自动清理任务(按 app.peer-retention-days 配置, 0 为不清理)
+func (ps *PeerService) StartCleanupJob() {
+	days := Config.App.PeerRetentionDays
+	if days <= 0 {
+		return
+	}
+	orphanOnly := Config.App.PeerRetentionOrphanOnly
+	go func() {
+		ticker := time.NewTicker(peerCleanupInterval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			n, err := ps.CleanupStale(orphanOnly, days)
+			if err != nil {
+				Logger.Error("Cleanup stale peers failed: ", err)
+				continue
+			}
+			if n > 0 {
+				Logger.Info("Cleanup stale peers: ", n)
+			}
+		}
+	}()
+}
@@ -0,0 +1,141 @@
+package service
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lejianwen/rustdesk-api/v2/model"
+	"gorm.io/gorm"
+)
+
+const peerBanCacheTTL = 30 * time.Second
+
+// peerKickCmd 通知 hbbs 断开被封禁设备的现有连接, 通过 hbbs 管理端口发送
+// 官方 hbbs 没有该命令, 需要自行添加该命令的 hbbs 构建; 否则只能拒绝新连接, 见 README
+const peerKickCmd = "kick-peer"
+
+// PeerBanService 设备封禁名单, 内部接口每次连接都会查询, 使用内存缓存
+type PeerBanService struct {
+	mu       sync.Mutex
+	bans     []*model.PeerBan
+	loadedAt time.Time
+}
+
+// peerBanMatch 查找匹配设备ID或uuid且未过期的封禁
+func peerBanMatch(bans []*model.PeerBan, peerId, uuid string, now int64) *model.PeerBan {
+	for _, b := range bans {
+		if b.ExpireAt > 0 && b.ExpireAt <= now {
+			continue
+		}
+		if (peerId != "" && b.PeerId == peerId) || (uuid != "" && b.Uuid == uuid) {
+			return b
+		}
+	}
+	return nil
+}
+
+func (bs *PeerBanService) activeBans() []*model.PeerBan {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+
+	if bs.bans != nil && time.Since(bs.loadedAt) < peerBanCacheTTL {
+		return bs.bans
+	}
+	bans := make([]*model.PeerBan, 0)
+	now := time.Now().Unix()
+	DB.Where("expire_at = 0 OR expire_at > ?", now).Find(&bans)
+	bs.bans = bans
+	bs.loadedAt = time.Now()
+	return bans
+}
+
+// Reload 使缓存失效, 封禁/解封后调用
+func (bs *PeerBanService) Reload() {
+	bs.mu.Lock()
+	bs.bans = nil
+	bs.mu.Unlock()
+}
+
+// Banned 返回设备当前生效的封禁, 未封禁时返回 nil
+func (bs *PeerBanService) Banned(peerId, uuid string) *model.PeerBan {
+	if peerId == "" && uuid == "" {
+		return nil
+	}
+	return peerBanMatch(bs.activeBans(), peerId, uuid, time.Now().Unix())
+}
+
+// ActiveBans 当前生效的封禁, 供 hbbs 启动或漏收通知后同步
+func (bs *PeerBanService) ActiveBans() []*model.PeerBan {
+	return bs.activeBans()
+}
+
+// Ban 封禁设备并通知 hbbs 断开现有连接, 只提供设备ID或uuid之一时按设备记录补全另一项
+func (bs *PeerBanService) Ban(b *model.PeerBan) error {
+	if b.PeerId == "" && b.Uuid == "" {
+		return errors.New("ParamsError")
+	}
+	if b.PeerId == "" {
+		b.PeerId = AllService.PeerService.FindByUuid(b.Uuid).Id
+	} else if b.Uuid == "" {
+		b.Uuid = AllService.PeerService.FindById(b.PeerId).Uuid
+	}
+	if ex := bs.Banned(b.PeerId, b.Uuid); ex != nil {
+		return errors.New("PeerAlreadyBanned")
+	}
+	if err := DB.Create(b).Error; err != nil {
+		return err
+	}
+	bs.Reload()
+	if b.PeerId != "" {
+		go bs.kick(b.PeerId)
+	}
+	return nil
+}
+
+// peerKickAccepted hbbs 是否接受了 kick-peer 命令, 官方 hbbs 对未知命令返回空内容
+func peerKickAccepted(res string) bool {
+	return strings.TrimSpace(res) != ""
+}
+
+// kick 通知 hbbs 断开设备的现有会话, 失败时仅记录日志, 新连接仍会被内部接口拒绝
+func (bs *PeerBanService) kick(peerId string) {
+	res, err := AllService.ServerCmdService.SendCmd(Config.Admin.IdServerPort-1, peerKickCmd, peerId)
+	if err != nil {
+		Logger.Warn("Notify hbbs to drop banned peer failed, peer: ", peerId, " err: ", err)
+		return
+	}
+	if !peerKickAccepted(res) {
+		Logger.Warn("hbbs did not accept ", peerKickCmd, ", existing sessions of banned peer ", peerId, " are kept until they reconnect")
+		return
+	}
+	Logger.Info("Notify hbbs to drop banned peer: ", peerId, " res: ", res)
+}
+
+// Unban 解除封禁
+func (bs *PeerBanService) Unban(b *model.PeerBan) error {
+	err := DB.Delete(b).Error
+	bs.Reload()
+	return err
+}
+
+func (bs *PeerBanService) InfoById(id uint) *model.PeerBan {
+	b := &model.PeerBan{}
+	DB.Where("id = ?", id).First(b)
+	return b
+}
+
+func (bs *PeerBanService) List(page, pageSize uint, where func(tx *gorm.DB)) (res *model.PeerBanList) {
+	res = &model.PeerBanList{}
+	res.Page = int64(page)
+	res.PageSize = int64(pageSize)
+	tx := DB.Model(&model.PeerBan{})
+	if where != nil {
+		where(tx)
+	}
+	tx.Count(&res.Total)
+	tx.Scopes(Paginate(page, pageSize))
+	tx.Find(&res.PeerBans)
+	return
+}
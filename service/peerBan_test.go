@@ -0,0 +1,50 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/lejianwen/rustdesk-api/v2/model"
+)
+
+func TestPeerBanMatch(t *testing.T) {
+	bans := []*model.PeerBan{
+		{IdModel: model.IdModel{Id: 1}, PeerId: "111", Uuid: "u1"},
+		{IdModel: model.IdModel{Id: 2}, PeerId: "222", ExpireAt: 100},
+		{IdModel: model.IdModel{Id: 3}, Uuid: "u3"},
+	}
+	cases := []struct {
+		peerId, uuid string
+		now          int64
+		want         uint
+	}{
+		{"111", "", 50, 1},
+		{"", "u1", 50, 1},
+		{"222", "", 50, 2},
+		{"222", "", 100, 0}, // 已到解封时间
+		{"333", "u3", 50, 3},
+		{"333", "", 50, 0},
+		{"", "", 50, 0}, // 未提供标识不匹配空字段
+	}
+	for _, c := range cases {
+		got := peerBanMatch(bans, c.peerId, c.uuid, c.now)
+		var id uint
+		if got != nil {
+			id = got.Id
+		}
+		if id != c.want {
+			t.Errorf("match(%q, %q, %d) = %d, want %d", c.peerId, c.uuid, c.now, id, c.want)
+		}
+	}
+}
+
+func TestPeerKickAccepted(t *testing.T) {
+	for res, want := range map[string]bool{
+		"":         false, // 官方 hbbs 未知命令
+		"\n":       false,
+		"kicked\n": true,
+	} {
+		if got := peerKickAccepted(res); got != want {
+			t.Errorf("peerKickAccepted(%q) = %v, want %v", res, got, want)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package service
+
+import (
+	"errors"
+	"time"
+
+	"github.com/lejianwen/rustdesk-api/v2/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// planMigrationBatch 每批迁移的订阅数
+const planMigrationBatch = 200
+
+// activeSubscribers 套餐的有效订阅, 包括暂停中的订阅(恢复后仍使用该套餐)
+func (ss *SubscriptionService) activeSubscribers(tx *gorm.DB, planId uint, now int64) *gorm.DB {
+	return tx.Model(&model.UserSubscription{}).
+		Where("plan_id = ? AND ((status = ? AND expire_at > ?) OR status = ?)", planId, model.SubscriptionStatusActive, now, model.SubscriptionStatusPaused)
+}
+
+// CountActiveSubscribers 套餐的有效订阅数
+func (ss *SubscriptionService) CountActiveSubscribers(planId uint) int64 {
+	var n int64
+	ss.activeSubscribers(DB, planId, time.Now().Unix()).Count(&n)
+	return n
+}
+
+// ArchivePlan 归档(禁用)套餐; 有有效订阅时必须指定继任套餐, 并在后台分批迁移这些订阅
+// 返回迁移任务, 没有需要迁移的订阅时为 nil
+func (ss *SubscriptionService) ArchivePlan(id, successorId, operatorId uint) (*model.PlanMigration, error) {
+	plan := ss.GetPlanById(id)
+	if plan.Id == 0 {
+		return nil, errors.New("PlanNotFound")
+	}
+	total := ss.CountActiveSubscribers(id)
+	if total > 0 || successorId > 0 {
+		if successorId == 0 {
+			return nil, errors.New("PlanSuccessorRequired")
+		}
+		successor := ss.GetPlanById(successorId)
+		if successor.Id == 0 || successor.Id == id {
+			return nil, errors.New("PlanSuccessorInvalid")
+		}
+		if successor.Status != model.COMMON_STATUS_ENABLE {
+			return nil, errors.New("PlanDisabled")
+		}
+	}
+	var running int64
+	DB.Model(&model.PlanMigration{}).Where("from_plan_id = ? AND status = ?", id, model.PlanMigrationRunning).Count(&running)
+	if running > 0 {
+		return nil, errors.New("PlanMigrationRunning")
+	}
+	if err := ss.DeletePlan(id); err != nil {
+		return nil, err
+	}
+	if total == 0 {
+		return nil, nil
+	}
+	m := &model.PlanMigration{
+		FromPlanId: id,
+		ToPlanId:   successorId,
+		OperatorId: operatorId,
+		Status:     model.PlanMigrationRunning,
+		Total:      total,
+	}
+	if err := DB.Create(m).Error; err != nil {
+		return nil, err
+	}
+	go ss.runPlanMigration(m)
+	return m, nil
+}
+
+// runPlanMigration 分批迁移, 每批一个事务; 中断后可由 ResumePlanMigrations 继续
+func (ss *SubscriptionService) runPlanMigration(m *model.PlanMigration) {
+	Logger.Info("Plan migration start, id: ", m.Id, " from: ", m.FromPlanId, " to: ", m.ToPlanId)
+	for {
+		n, err := ss.migratePlanBatch(m)
+		if err != nil {
+			Logger.Error("Plan migration failed, id: ", m.Id, " err: ", err)
+			DB.Model(m).Updates(map[string]interface{}{
+				"status":      model.PlanMigrationFailed,
+				"error":       err.Error(),
+				"finished_at": time.Now().Unix(),
+			})
+			return
+		}
+		if n == 0 {
+			break
+		}
+	}
+	DB.Model(m).Updates(map[string]interface{}{
+		"status":      model.PlanMigrationDone,
+		"finished_at": time.Now().Unix(),
+	})
+	Logger.Info("Plan migration done, id: ", m.Id, " migrated: ", m.Migrated)
+}
+
+// migratePlanBatch 迁移一批订阅, 只修改套餐, 到期时间不变, 每个订阅写入一条变更记录
+func (ss *SubscriptionService) migratePlanBatch(m *model.PlanMigration) (int, error) {
+	n := 0
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		var subs []*model.UserSubscription
+		if err := ss.activeSubscribers(tx, m.FromPlanId, time.Now().Unix()).
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Order("id ASC").Limit(planMigrationBatch).Find(&subs).Error; err != nil {
+			return err
+		}
+		by := subscriptionChange{
+			Source:   model.SubscriptionSourceAdmin,
+			ActorId:  m.OperatorId,
+			Metadata: map[string]interface{}{"migration_id": m.Id, "from_plan_id": m.FromPlanId},
+		}
+		for _, sub := range subs {
+			before := *sub
+			if err := tx.Model(sub).Update("plan_id", m.ToPlanId).Error; err != nil {
+				return err
+			}
+			if err := ss.recordSubscriptionEvent(tx, model.SubscriptionEventMigrated, by, &before, sub, 0); err != nil {
+				return err
+			}
+		}
+		n = len(subs)
+		if n == 0 {
+			return nil
+		}
+		return tx.Model(m).Update("migrated", gorm.Expr("migrated + ?", n)).Error
+	})
+	if err != nil {
+		return 0, err
+	}
+	m.Migrated += int64(n)
+	if n > 0 {
+		AllService.EntitlementService.InvalidateAll()
+	}
+	return n, nil
+}
+
+// ResumePlanMigrations 启动时继续未完成的迁移任务
+func (ss *SubscriptionService) ResumePlanMigrations() {
+	var list []*model.PlanMigration
+	DB.Where("status = ?", model.PlanMigrationRunning).Find(&list)
+	for _, m := range list {
+		go ss.runPlanMigration(m)
+	}
+}
+
+func (ss *SubscriptionService) GetPlanMigrationById(id uint) *model.PlanMigration {
+	m := &model.PlanMigration{}
+	DB.Where("id = ?", id).First(m)
+	return m
+}
+
+// ListPlanMigrations 迁移任务列表
+func (ss *SubscriptionService) ListPlanMigrations(page, pageSize uint, where func(tx *gorm.DB)) (res *model.PlanMigrationList) {
+	res = &model.PlanMigrationList{}
+	res.Page = int64(page)
+	res.PageSize = int64(pageSize)
+	tx := DB.Model(&model.PlanMigration{})
+	if where != nil {
+		where(tx)
+	}
+	tx.Count(&res.Total)
+	tx.Scopes(Paginate(page, pageSize))
+	tx.Order("id DESC").Find(&res.PlanMigrations)
+	return
+}
@@ -0,0 +1,59 @@
+package service
+
+import (
+	"errors"
+	"time"
+
+	"github.com/lejianwen/rustdesk-api/v2/model"
+	"gorm.io/gorm"
+)
+
+// purchaseHistory 判断套餐购买限制所需的用户历史
+type purchaseHistory struct {
+	BoughtPlan      bool // 已购买过该套餐(含免费套餐和已退款订单)
+	EverSubscribed  bool // 曾有过订阅(任意套餐、任意来源)
+	ActiveSubscribe bool // 当前个人订阅有效
+}
+
+// checkPlanRestrictions 按套餐的购买限制检查, 返回可翻译的错误
+func checkPlanRestrictions(plan *model.SubscriptionPlan, h purchaseHistory) error {
+	switch {
+	case plan.OncePerUser && h.BoughtPlan:
+		return errors.New("PlanOncePerUser")
+	case plan.NewUsersOnly && h.EverSubscribed:
+		return errors.New("PlanNewUsersOnly")
+	case plan.UpgradeOnly && !h.ActiveSubscribe:
+		return errors.New("PlanUpgradeOnly")
+	}
+	return nil
+}
+
+// CheckPlanRestrictions 检查用户是否可以购买套餐, 没有购买限制的套餐不查询数据库
+// 仅指定用户组可见的套餐对其他用户按套餐不存在处理, 不暴露套餐的存在
+func (ss *SubscriptionService) CheckPlanRestrictions(db *gorm.DB, userId uint, plan *model.SubscriptionPlan) error {
+	if plan.Visibility == model.PlanVisibilityGroups && !plan.Purchasable(AllService.UserService.InfoById(userId).GroupId) {
+		return errors.New("PlanNotFound")
+	}
+	if !plan.OncePerUser && !plan.NewUsersOnly && !plan.UpgradeOnly {
+		return nil
+	}
+	h := purchaseHistory{}
+	if plan.OncePerUser {
+		var n int64
+		db.Model(&model.Order{}).
+			Where("user_id = ? AND plan_id = ? AND status IN ?", userId, plan.Id, []int{model.OrderStatusPaid, model.OrderStatusRefunded}).
+			Count(&n)
+		h.BoughtPlan = n > 0
+	}
+	sub := &model.UserSubscription{}
+	db.Where("user_id = ?", userId).First(sub)
+	if plan.NewUsersOnly {
+		var n int64
+		db.Model(&model.Order{}).
+			Where("user_id = ? AND status IN ?", userId, []int{model.OrderStatusPaid, model.OrderStatusRefunded}).
+			Count(&n)
+		h.EverSubscribed = sub.Id > 0 || n > 0
+	}
+	h.ActiveSubscribe, _ = subscriptionActiveAt(sub, time.Now().Unix())
+	return checkPlanRestrictions(plan, h)
+}
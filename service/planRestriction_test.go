@@ -0,0 +1,34 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/lejianwen/rustdesk-api/v2/model"
+)
+
+func TestCheckPlanRestrictions(t *testing.T) {
+	cases := []struct {
+		name string
+		plan model.SubscriptionPlan
+		h    purchaseHistory
+		want string
+	}{
+		{"unrestricted", model.SubscriptionPlan{}, purchaseHistory{BoughtPlan: true, EverSubscribed: true}, ""},
+		{"once first", model.SubscriptionPlan{OncePerUser: true}, purchaseHistory{}, ""},
+		{"once again", model.SubscriptionPlan{OncePerUser: true}, purchaseHistory{BoughtPlan: true}, "PlanOncePerUser"},
+		{"new user", model.SubscriptionPlan{NewUsersOnly: true}, purchaseHistory{}, ""},
+		{"returning user", model.SubscriptionPlan{NewUsersOnly: true}, purchaseHistory{EverSubscribed: true}, "PlanNewUsersOnly"},
+		{"upgrade active", model.SubscriptionPlan{UpgradeOnly: true}, purchaseHistory{ActiveSubscribe: true, EverSubscribed: true}, ""},
+		{"upgrade inactive", model.SubscriptionPlan{UpgradeOnly: true}, purchaseHistory{EverSubscribed: true}, "PlanUpgradeOnly"},
+	}
+	for _, tc := range cases {
+		err := checkPlanRestrictions(&tc.plan, tc.h)
+		got := ""
+		if err != nil {
+			got = err.Error()
+		}
+		if got != tc.want {
+			t.Errorf("%s: got %q, want %q", tc.name, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/lejianwen/rustdesk-api/v2/lib/trace"
+	"github.com/lejianwen/rustdesk-api/v2/model"
+	"gorm.io/gorm"
+)
+
+// ========== 一次性商品 ==========
+
+func (ss *SubscriptionService) GetProductById(id uint) *model.Product {
+	p := &model.Product{}
+	DB.Where("id = ?", id).First(p)
+	return p
+}
+
+func (ss *SubscriptionService) GetProductByCode(code string) *model.Product {
+	p := &model.Product{}
+	DB.Where("code = ?", code).First(p)
+	return p
+}
+
+// ListProducts 商品列表
+func (ss *SubscriptionService) ListProducts(page, pageSize uint, where func(tx *gorm.DB)) (res *model.ProductList) {
+	res = &model.ProductList{}
+	res.Page = int64(page)
+	res.PageSize = int64(pageSize)
+	tx := DB.Model(&model.Product{})
+	if where != nil {
+		where(tx)
+	}
+	tx.Count(&res.Total)
+	tx.Scopes(Paginate(page, pageSize))
+	tx.Order("sort_order ASC, id ASC").Find(&res.Products)
+	return
+}
+
+// ListEnabledProducts 可购买的商品
+func (ss *SubscriptionService) ListEnabledProducts() []*model.Product {
+	var list []*model.Product
+	DB.Where("status = ?", model.COMMON_STATUS_ENABLE).Order("sort_order ASC, id ASC").Find(&list)
+	return list
+}
+
+func (ss *SubscriptionService) CreateProduct(p *model.Product) error {
+	return DB.Create(p).Error
+}
+
+func (ss *SubscriptionService) UpdateProduct(p *model.Product) error {
+	return DB.Save(p).Error
+}
+
+// DeleteProduct 删除商品(软删除: 禁用), 已有订单仍可履约
+func (ss *SubscriptionService) DeleteProduct(id uint) error {
+	return DB.Model(&model.Product{}).Where("id = ?", id).Update("status", model.COMMON_STATUS_DISABLED).Error
+}
+
+// CreateProductOrder 创建商品订单并返回支付URL; 商品订单不复用待支付订单, 支付后进入待履约, 不影响订阅
+// country 同 CreateOrder, 用于计算税费
+func (ss *SubscriptionService) CreateProductOrder(ctx context.Context, userId, productId uint, payType, country string) (outTradeNo, payURL string, err error) {
+	ctx, span := trace.Start(ctx, "subscription.create_product_order", trace.KindInternal)
+	defer func() { span.SetError(err); span.End() }()
+
+	p := ss.GetProductById(productId)
+	if p.Id == 0 {
+		return "", "", errors.New("ProductNotFound")
+	}
+	if p.Status != model.COMMON_STATUS_ENABLE {
+		return "", "", errors.New("ProductDisabled")
+	}
+	price := p.Total()
+	if price.Amount <= 0 {
+		return "", "", errors.New("ProductNotFound")
+	}
+	if err := AllService.Payment().CheckPayType(payType); err != nil {
+		return "", "", err
+	}
+	if err := ss.checkOrderLimit(DB.WithContext(ctx), userId); err != nil {
+		return "", "", err
+	}
+	outTradeNo = ss.GenerateOutTradeNo()
+	order := &model.Order{
+		UserId:     userId,
+		ProductId:  p.Id,
+		OutTradeNo: outTradeNo,
+		Subject:    p.Name,
+		Amount:     price.Amount,
+		AmountYuan: price.String(),
+		Currency:   price.Currency,
+		Status:     model.OrderStatusPending,
+		PayMethod:  model.PayMethodOnline,
+		PayType:    payType,
+	}
+	applyTax(order, country)
+	if err := DB.WithContext(ctx).Create(order).Error; err != nil {
+		Logger.Error("Create product order failed: ", err)
+		return "", "", err
+	}
+	MetricOrdersCreated.Inc(model.PayMethodOnline)
+	return outTradeNo, AllService.Payment().BuildPayURL(outTradeNo), nil
+}
+
+// UpdateFulfillment 更新商品订单的履约状态, 变为已履约时发布 product.fulfilled 事件
+func (ss *SubscriptionService) UpdateFulfillment(orderId uint, status int, note string) (*model.Order, error) {
+	order := ss.GetOrderById(orderId)
+	if order.Id == 0 {
+		return nil, errors.New("OrderNotFound")
+	}
+	if order.ProductId == 0 {
+		return nil, errors.New("OrderNotProduct")
+	}
+	if order.FulfillmentStatus == model.FulfillmentNone {
+		// 未支付的商品订单
+		return nil, errors.New("OrderNotPaid")
+	}
+	updates := map[string]interface{}{
+		"fulfillment_status": status,
+		"fulfillment_note":   note,
+	}
+	fulfilled := status == model.FulfillmentDone && order.FulfillmentStatus != model.FulfillmentDone
+	if fulfilled {
+		updates["fulfilled_at"] = time.Now().Unix()
+	}
+	if err := DB.Model(order).Updates(updates).Error; err != nil {
+		return nil, err
+	}
+	order = ss.GetOrderById(orderId)
+	if fulfilled {
+		AllService.EventBus.Publish(ProductFulfilledEvent{Order: order})
+	}
+	return order, nil
+}
@@ -0,0 +1,24 @@
+package service
+
+import (
+	"github.com/lejianwen/rustdesk-api/v2/lib/publicid"
+)
+
+// newPublicIds 按配置创建对外标识编码, 配置无效时回退为数字ID
+func newPublicIds() publicid.Codec {
+	if Config == nil {
+		return publicid.Numeric{}
+	}
+	c, err := publicid.New(Config.App.PublicId, Config.App.PublicIdSecret)
+	if err != nil {
+		Logger.Error("Invalid app.public-id, fallback to numeric: ", err)
+		return publicid.Numeric{}
+	}
+	return c
+}
+
+// ResolvePublicId 将请求中的对外标识解析为主键
+// 隐藏数字ID时只接受编码后的标识, 否则只接受数字
+func (s *Service) ResolvePublicId(kind string, ref publicid.Ref) (uint, error) {
+	return s.PublicIds().Decode(kind, string(ref))
+}
@@ -0,0 +1,185 @@
+package service
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/lejianwen/rustdesk-api/v2/model"
+)
+
+const (
+	PushTypePeerOnline          = "peer.online"
+	PushTypePeerOffline         = "peer.offline"
+	PushTypeSubscriptionChanged = "subscription.changed"
+)
+
+// peerOfflineAfter 超过该时间未收到心跳视为离线
+const peerOfflineAfter = 90 * time.Second
+
+// pushScanInterval 离线扫描间隔
+const pushScanInterval = 15 * time.Second
+
+// pushBuffer 每个连接待发送消息的缓冲, 满时丢弃新消息, 客户端可通过接口重新拉取
+const pushBuffer = 32
+
+// PushMessage 推送给前端的消息
+type PushMessage struct {
+	Type string      `json:"type"`
+	Data interface{} `json:"data"`
+}
+
+// PushClient 一个 WebSocket 订阅者, 管理员接收所有用户的事件
+type PushClient struct {
+	UserId  uint
+	TokenId uint // 建立连接使用的登录会话, 会话撤销时断开
+	Admin   bool
+	Send    chan []byte
+}
+
+type onlinePeer struct {
+	userId   uint
+	peerId   string
+	lastSeen time.Time
+}
+
+// PushService 实时推送: 设备上下线和订阅状态变更
+type PushService struct {
+	mu      sync.RWMutex
+	clients map[*PushClient]struct{}
+
+	peerMu sync.Mutex
+	peers  map[uint]*onlinePeer
+}
+
+// Subscribe 注册订阅者, 连接断开时须调用 Unsubscribe
+func (ps *PushService) Subscribe(userId, tokenId uint, admin bool) *PushClient {
+	c := &PushClient{UserId: userId, TokenId: tokenId, Admin: admin, Send: make(chan []byte, pushBuffer)}
+	ps.mu.Lock()
+	if ps.clients == nil {
+		ps.clients = make(map[*PushClient]struct{})
+	}
+	ps.clients[c] = struct{}{}
+	ps.mu.Unlock()
+	return c
+}
+
+// Unsubscribe 注销订阅者并关闭发送通道
+func (ps *PushService) Unsubscribe(c *PushClient) {
+	ps.mu.Lock()
+	if _, ok := ps.clients[c]; ok {
+		delete(ps.clients, c)
+		close(c.Send)
+	}
+	ps.mu.Unlock()
+}
+
+// drop 注销匹配的订阅者, 关闭发送通道后连接随之断开
+func (ps *PushService) drop(match func(c *PushClient) bool) {
+	ps.mu.Lock()
+	for c := range ps.clients {
+		if match(c) {
+			delete(ps.clients, c)
+			close(c.Send)
+		}
+	}
+	ps.mu.Unlock()
+}
+
+// DropUser 断开用户的全部连接, 用于修改密码、禁用等作废全部会话的场景
+func (ps *PushService) DropUser(userId uint) {
+	ps.drop(func(c *PushClient) bool { return c.UserId == userId })
+}
+
+// DropSession 断开使用该登录会话建立的连接
+func (ps *PushService) DropSession(tokenId uint) {
+	ps.drop(func(c *PushClient) bool { return c.TokenId == tokenId })
+}
+
+// Publish 推送给所属用户和所有管理员, userId 为 0 时只推送给管理员
+func (ps *PushService) Publish(userId uint, typ string, data interface{}) {
+	ps.mu.RLock()
+	defer ps.mu.RUnlock()
+	if len(ps.clients) == 0 {
+		return
+	}
+	msg, err := json.Marshal(&PushMessage{Type: typ, Data: data})
+	if err != nil {
+		return
+	}
+	for c := range ps.clients {
+		if !c.Admin && (userId == 0 || c.UserId != userId) {
+			continue
+		}
+		select {
+		case c.Send <- msg:
+		default:
+		}
+	}
+}
+
+// PeerSeen 收到设备心跳, 由离线转为在线时推送
+func (ps *PushService) PeerSeen(peer *model.Peer) {
+	ps.peerMu.Lock()
+	if ps.peers == nil {
+		ps.peers = make(map[uint]*onlinePeer)
+	}
+	p, ok := ps.peers[peer.RowId]
+	if ok {
+		p.lastSeen = time.Now()
+		p.userId = peer.UserId
+		ps.peerMu.Unlock()
+		return
+	}
+	ps.peers[peer.RowId] = &onlinePeer{userId: peer.UserId, peerId: peer.Id, lastSeen: time.Now()}
+	ps.peerMu.Unlock()
+	ps.Publish(peer.UserId, PushTypePeerOnline, peerStatusData(peer.RowId, peer.Id, true))
+}
+
+// scanOffline 移除超时未心跳的设备并推送离线
+func (ps *PushService) scanOffline(now time.Time) {
+	ps.peerMu.Lock()
+	var offline []*onlinePeer
+	var rowIds []uint
+	for rowId, p := range ps.peers {
+		if now.Sub(p.lastSeen) > peerOfflineAfter {
+			offline = append(offline, p)
+			rowIds = append(rowIds, rowId)
+			delete(ps.peers, rowId)
+		}
+	}
+	ps.peerMu.Unlock()
+	for i, p := range offline {
+		ps.Publish(p.userId, PushTypePeerOffline, peerStatusData(rowIds[i], p.peerId, false))
+	}
+}
+
+func peerStatusData(rowId uint, peerId string, online bool) map[string]interface{} {
+	return map[string]interface{}{
+		"row_id":  rowId,
+		"peer_id": peerId,
+		"online":  online,
+	}
+}
+
+// SubscriptionChanged 订阅状态变更推送, 前端收到后重新拉取订阅详情
+func (ps *PushService) SubscriptionChanged(typ string, sub *model.UserSubscription) {
+	ps.Publish(sub.UserId, PushTypeSubscriptionChanged, map[string]interface{}{
+		"user_id":   sub.UserId,
+		"event":     typ,
+		"status":    sub.Status,
+		"expire_at": sub.ExpireAt,
+	})
+}
+
+// StartOfflineJob 启动设备离线检测任务
+func (ps *PushService) StartOfflineJob() {
+	go func() {
+		ticker := time.NewTicker(pushScanInterval)
+		defer ticker.Stop()
+
+		for now := range ticker.C {
+			ps.scanOffline(now)
+		}
+	}()
+}
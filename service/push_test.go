@@ -0,0 +1,120 @@
+package service
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/lejianwen/rustdesk-api/v2/model"
+)
+
+func recvPush(t *testing.T, c *PushClient) *PushMessage {
+	t.Helper()
+	select {
+	case b := <-c.Send:
+		m := &PushMessage{}
+		if err := json.Unmarshal(b, m); err != nil {
+			t.Fatal(err)
+		}
+		return m
+	default:
+		return nil
+	}
+}
+
+func TestPushRouting(t *testing.T) {
+	ps := &PushService{}
+	owner := ps.Subscribe(1, 0, false)
+	other := ps.Subscribe(2, 0, false)
+	admin := ps.Subscribe(3, 0, true)
+	defer ps.Unsubscribe(owner)
+	defer ps.Unsubscribe(other)
+	defer ps.Unsubscribe(admin)
+
+	ps.SubscriptionChanged(model.SubscriptionEventChanged, &model.UserSubscription{UserId: 1})
+	if m := recvPush(t, owner); m == nil || m.Type != PushTypeSubscriptionChanged {
+		t.Fatalf("owner got %v", m)
+	}
+	if m := recvPush(t, admin); m == nil {
+		t.Fatal("admin should receive all events")
+	}
+	if m := recvPush(t, other); m != nil {
+		t.Fatalf("other user got %v", m)
+	}
+
+	// 未绑定用户的设备只推送给管理员
+	ps.PeerSeen(&model.Peer{RowId: 10, Id: "123"})
+	if m := recvPush(t, admin); m == nil || m.Type != PushTypePeerOnline {
+		t.Fatalf("admin got %v", m)
+	}
+	if recvPush(t, owner) != nil || recvPush(t, other) != nil {
+		t.Fatal("unbound peer should only reach admins")
+	}
+}
+
+func TestPushPeerTransitions(t *testing.T) {
+	ps := &PushService{}
+	c := ps.Subscribe(1, 0, false)
+	defer ps.Unsubscribe(c)
+
+	peer := &model.Peer{RowId: 5, Id: "abc", UserId: 1}
+	ps.PeerSeen(peer)
+	ps.PeerSeen(peer)
+	if m := recvPush(t, c); m == nil || m.Type != PushTypePeerOnline {
+		t.Fatalf("first heartbeat got %v", m)
+	}
+	if m := recvPush(t, c); m != nil {
+		t.Fatalf("repeated heartbeat should not push, got %v", m)
+	}
+
+	ps.scanOffline(time.Now())
+	if m := recvPush(t, c); m != nil {
+		t.Fatalf("fresh peer should stay online, got %v", m)
+	}
+	ps.scanOffline(time.Now().Add(peerOfflineAfter + time.Second))
+	if m := recvPush(t, c); m == nil || m.Type != PushTypePeerOffline {
+		t.Fatalf("stale peer got %v", m)
+	}
+	ps.PeerSeen(peer)
+	if m := recvPush(t, c); m == nil || m.Type != PushTypePeerOnline {
+		t.Fatalf("peer back online got %v", m)
+	}
+}
+
+func TestPushUnsubscribe(t *testing.T) {
+	ps := &PushService{}
+	c := ps.Subscribe(1, 0, false)
+	ps.Unsubscribe(c)
+	ps.Unsubscribe(c)
+	if _, ok := <-c.Send; ok {
+		t.Fatal("send channel should be closed")
+	}
+	ps.Publish(1, PushTypePeerOnline, nil)
+}
+
+func TestPushDrop(t *testing.T) {
+	ps := &PushService{}
+	a := ps.Subscribe(1, 10, false)
+	b := ps.Subscribe(1, 11, false)
+	other := ps.Subscribe(2, 20, false)
+	defer ps.Unsubscribe(other)
+
+	ps.DropSession(10)
+	if _, ok := <-a.Send; ok {
+		t.Fatal("revoked session should be closed")
+	}
+	ps.Publish(1, PushTypePeerOnline, nil)
+	if recvPush(t, b) == nil {
+		t.Fatal("other session of the user should stay subscribed")
+	}
+
+	ps.DropUser(1)
+	if _, ok := <-b.Send; ok {
+		t.Fatal("all sessions of the user should be closed")
+	}
+	ps.Unsubscribe(b)
+	ps.Publish(2, PushTypePeerOnline, nil)
+	if recvPush(t, other) == nil {
+		t.Fatal("other users should stay subscribed")
+	}
+}
@@ -0,0 +1,174 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/lejianwen/rustdesk-api/v2/lib/storage"
+	"github.com/lejianwen/rustdesk-api/v2/model"
+	"gorm.io/gorm"
+)
+
+// SettingRecordingRetentionDays 录像保留天数, 0 为永久保留
+const SettingRecordingRetentionDays = "recording.retention_days"
+
+const recordingCleanupInterval = time.Hour
+
+var recordingChecksumRe = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// RecordingService 会话录像登记和保留期限清理
+type RecordingService struct {
+}
+
+func (s *SystemSettingService) registerRecordingSettings() {
+	s.RegisterSetting(&model.SettingDefinition{
+		Key:         SettingRecordingRetentionDays,
+		Type:        model.SettingTypeInt,
+		Group:       "recording",
+		Description: "Days to keep session recordings before they are deleted, 0 keeps them forever",
+		Default:     int64(0),
+	})
+}
+
+// recordingExpired 录像是否超过保留期限, 标记保留的录像不过期
+func recordingExpired(r *model.SessionRecording, days int64, now int64) bool {
+	if r.Hold || days <= 0 {
+		return false
+	}
+	return r.RecordedAt < now-days*86400
+}
+
+// ValidateRecording 校验并规范化上报的录像信息
+func (rs *RecordingService) ValidateRecording(r *model.SessionRecording) error {
+	if r.SessionId == "" {
+		return errors.New("session_id is required")
+	}
+	if r.StorageKey == "" && r.Url == "" {
+		return errors.New("storage_key or url is required")
+	}
+	if r.StorageKey != "" {
+		key, err := storage.CleanKey(r.StorageKey)
+		if err != nil {
+			return err
+		}
+		r.StorageKey = key
+	}
+	if r.Url != "" && !strings.HasPrefix(r.Url, "https://") && !strings.HasPrefix(r.Url, "http://") {
+		return errors.New("url must be http(s)")
+	}
+	r.Checksum = strings.ToLower(r.Checksum)
+	if r.Checksum != "" && !recordingChecksumRe.MatchString(r.Checksum) {
+		return errors.New("checksum must be sha256 hex")
+	}
+	if r.Size < 0 || r.Duration < 0 {
+		return errors.New("size and duration must not be negative")
+	}
+	return nil
+}
+
+// Register 登记录像, 同一会话的同一文件重复上报时更新大小、时长和校验值
+func (rs *RecordingService) Register(r *model.SessionRecording) (*model.SessionRecording, error) {
+	if err := rs.ValidateRecording(r); err != nil {
+		return nil, err
+	}
+	if r.RecordedAt <= 0 {
+		r.RecordedAt = time.Now().Unix()
+	}
+	if r.UserId == 0 && r.TargetPeerId != "" {
+		r.UserId = AllService.PeerService.FindById(r.TargetPeerId).UserId
+	}
+	ex := &model.SessionRecording{}
+	DB.Where("session_id = ? AND storage_key = ? AND url = ?", r.SessionId, r.StorageKey, r.Url).First(ex)
+	if ex.Id > 0 {
+		err := DB.Model(ex).Updates(map[string]interface{}{"size": r.Size, "duration": r.Duration, "checksum": r.Checksum}).Error
+		return ex, err
+	}
+	return r, DB.Create(r).Error
+}
+
+func (rs *RecordingService) InfoById(id uint) *model.SessionRecording {
+	r := &model.SessionRecording{}
+	DB.Where("id = ?", id).First(r)
+	return r
+}
+
+func (rs *RecordingService) List(page, pageSize uint, where func(tx *gorm.DB)) (res *model.SessionRecordingList) {
+	res = &model.SessionRecordingList{}
+	res.Page = int64(page)
+	res.PageSize = int64(pageSize)
+	tx := DB.Model(&model.SessionRecording{})
+	if where != nil {
+		where(tx)
+	}
+	tx.Count(&res.Total)
+	tx.Scopes(Paginate(page, pageSize))
+	tx.Order("id DESC").Find(&res.SessionRecordings)
+	return
+}
+
+// DownloadURL 录像下载地址, 存储中的录像返回签名地址和过期时间, 外部地址原样返回
+func (rs *RecordingService) DownloadURL(r *model.SessionRecording) (string, int64, error) {
+	if r.StorageKey == "" {
+		return r.Url, 0, nil
+	}
+	return AllService.StorageService.SignedURL(r.StorageKey)
+}
+
+// SetHold 设置或取消保留
+func (rs *RecordingService) SetHold(r *model.SessionRecording, hold bool, remark string) error {
+	return DB.Model(r).Updates(map[string]interface{}{"hold": hold, "remark": remark}).Error
+}
+
+// Delete 删除登记记录和存储中的文件, 外部地址的文件由录制组件自行清理
+func (rs *RecordingService) Delete(r *model.SessionRecording) error {
+	if r.StorageKey != "" {
+		if err := AllService.StorageService.Delete(context.Background(), r.StorageKey); err != nil {
+			return err
+		}
+	}
+	return DB.Delete(r).Error
+}
+
+// Cleanup 删除超过保留期限的录像, 返回删除数量
+func (rs *RecordingService) Cleanup() (int, error) {
+	days := AllService.SystemSettingService.SettingInt(SettingRecordingRetentionDays)
+	if days <= 0 {
+		return 0, nil
+	}
+	now := time.Now().Unix()
+	var list []*model.SessionRecording
+	DB.Where("hold = ? AND recorded_at < ?", false, now-days*86400).Limit(500).Find(&list)
+	n := 0
+	for _, r := range list {
+		if !recordingExpired(r, days, now) {
+			continue
+		}
+		if err := rs.Delete(r); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
+
+// StartCleanupJob 定时按保留期限清理录像
+func (rs *RecordingService) StartCleanupJob() {
+	go func() {
+		ticker := time.NewTicker(recordingCleanupInterval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			n, err := rs.Cleanup()
+			if err != nil {
+				Logger.Error("Cleanup session recordings failed: ", err)
+				continue
+			}
+			if n > 0 {
+				Logger.Info("Cleanup session recordings: ", n)
+			}
+		}
+	}()
+}
@@ -0,0 +1,50 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/lejianwen/rustdesk-api/v2/model"
+)
+
+func TestRecordingExpired(t *testing.T) {
+	now := int64(100 * 86400)
+	old := &model.SessionRecording{RecordedAt: now - 31*86400}
+	fresh := &model.SessionRecording{RecordedAt: now - 29*86400}
+	held := &model.SessionRecording{RecordedAt: now - 365*86400, Hold: true}
+	if !recordingExpired(old, 30, now) {
+		t.Error("old recording should expire")
+	}
+	if recordingExpired(fresh, 30, now) {
+		t.Error("fresh recording should not expire")
+	}
+	if recordingExpired(held, 30, now) {
+		t.Error("held recording should not expire")
+	}
+	if recordingExpired(old, 0, now) {
+		t.Error("retention 0 keeps recordings forever")
+	}
+}
+
+func TestValidateRecording(t *testing.T) {
+	rs := &RecordingService{}
+	r := &model.SessionRecording{SessionId: "abc", StorageKey: `recordings\2026\a.webm`, Checksum: "E3B0C44298FC1C149AFBF4C8996FB92427AE41E4649B934CA495991B7852B855"}
+	if err := rs.ValidateRecording(r); err != nil {
+		t.Fatal(err)
+	}
+	if r.StorageKey != "recordings/2026/a.webm" || r.Checksum[0] != 'e' {
+		t.Fatalf("normalized = %q %q", r.StorageKey, r.Checksum)
+	}
+	bad := []*model.SessionRecording{
+		{StorageKey: "a.webm"},
+		{SessionId: "abc"},
+		{SessionId: "abc", StorageKey: "../etc/passwd"},
+		{SessionId: "abc", Url: "ftp://host/a.webm"},
+		{SessionId: "abc", Url: "https://host/a.webm", Checksum: "md5"},
+		{SessionId: "abc", Url: "https://host/a.webm", Size: -1},
+	}
+	for i, r := range bad {
+		if err := rs.ValidateRecording(r); err == nil {
+			t.Errorf("case %d should fail", i)
+		}
+	}
+}
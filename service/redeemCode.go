@@ -0,0 +1,135 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lejianwen/rustdesk-api/v2/model"
+	"github.com/lejianwen/rustdesk-api/v2/utils"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type RedeemCodeService struct{}
+
+const (
+	redeemCodeLength      = 16
+	redeemCodeMaxPerBatch = 1000
+)
+
+// NormalizeCode 规范化兑换码(去除分隔符/空白, 转大写)
+func (rs *RedeemCodeService) NormalizeCode(code string) string {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	code = strings.ReplaceAll(code, "-", "")
+	return strings.ReplaceAll(code, " ", "")
+}
+
+// Generate 批量生成兑换码
+func (rs *RedeemCodeService) Generate(planId uint, days, count int, expireAt int64, operatorId uint) ([]*model.RedeemCode, error) {
+	if days <= 0 || count <= 0 || count > redeemCodeMaxPerBatch {
+		return nil, errors.New("ParamsError")
+	}
+	plan := AllService.Subscription().GetPlanById(planId)
+	if plan.Id == 0 {
+		return nil, errors.New("PlanNotFound")
+	}
+
+	batchNo := fmt.Sprintf("B%s%s", time.Now().Format("20060102150405"), strings.ToUpper(utils.RandomString(4)))
+	codes := make([]*model.RedeemCode, 0, count)
+	seen := make(map[string]struct{}, count)
+	for len(codes) < count {
+		code := strings.ToUpper(utils.RandomString(redeemCodeLength))
+		if code == "" {
+			return nil, errors.New("GenerateCodeFailed")
+		}
+		if _, ok := seen[code]; ok {
+			continue
+		}
+		seen[code] = struct{}{}
+		codes = append(codes, &model.RedeemCode{
+			Code:      code,
+			BatchNo:   batchNo,
+			PlanId:    planId,
+			Days:      days,
+			Status:    model.RedeemCodeStatusUnused,
+			ExpireAt:  expireAt,
+			CreatedBy: operatorId,
+		})
+	}
+	if err := DB.CreateInBatches(codes, 100).Error; err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// List 兑换码列表(分页)
+func (rs *RedeemCodeService) List(page, pageSize uint, where func(tx *gorm.DB)) *model.RedeemCodeList {
+	res := &model.RedeemCodeList{}
+	res.Page = int64(page)
+	res.PageSize = int64(pageSize)
+	tx := DB.Model(&model.RedeemCode{})
+	if where != nil {
+		where(tx)
+	}
+	tx.Count(&res.Total)
+	tx.Scopes(Paginate(page, pageSize)).Preload("Plan").Preload("UsedUser").Order("id DESC").Find(&res.RedeemCodes)
+	return res
+}
+
+// Disable 作废未使用的兑换码
+func (rs *RedeemCodeService) Disable(ids []uint) error {
+	return DB.Model(&model.RedeemCode{}).
+		Where("id IN ? AND status = ?", ids, model.RedeemCodeStatusUnused).
+		Update("status", model.RedeemCodeStatusDisabled).Error
+}
+
+// Redeem 用户兑换, 激活或续期订阅(一次性使用)
+func (rs *RedeemCodeService) Redeem(userId uint, code string) (*model.RedeemCode, error) {
+	code = rs.NormalizeCode(code)
+	if code == "" {
+		return nil, errors.New("RedeemCodeInvalid")
+	}
+
+	rc := &model.RedeemCode{}
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("code = ?", code).First(rc).Error; err != nil {
+			return errors.New("RedeemCodeInvalid")
+		}
+		if rc.Status != model.RedeemCodeStatusUnused {
+			return errors.New("RedeemCodeUsed")
+		}
+		now := time.Now().Unix()
+		if rc.ExpireAt > 0 && rc.ExpireAt < now {
+			return errors.New("RedeemCodeExpired")
+		}
+
+		// 条件更新, 防止并发重复兑换
+		res := tx.Model(&model.RedeemCode{}).
+			Where("id = ? AND status = ?", rc.Id, model.RedeemCodeStatusUnused).
+			Updates(map[string]interface{}{
+				"status":  model.RedeemCodeStatusUsed,
+				"used_by": userId,
+				"used_at": now,
+			})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return errors.New("RedeemCodeUsed")
+		}
+
+		return AllService.SubscriptionService.extendSubscriptionPeriod(tx, userId, rc.PlanId, model.Period{Days: rc.Days}, now, subscriptionChange{
+			Source:   model.SubscriptionSourceUser,
+			ActorId:  userId,
+			Metadata: map[string]interface{}{"redeem_code_id": rc.Id},
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	Logger.Info("Redeem code success, code id: ", rc.Id, " user: ", userId)
+	return rc, nil
+}
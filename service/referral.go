@@ -0,0 +1,268 @@
+package service
+
+import (
+	"strings"
+	"time"
+
+	"github.com/lejianwen/rustdesk-api/v2/model"
+	"github.com/lejianwen/rustdesk-api/v2/utils"
+	"gorm.io/gorm"
+)
+
+// 推荐奖励规则, 可在管理后台设置中调整
+const (
+	SettingReferralEnable       = "referral.enable"
+	SettingReferralSignupDays   = "referral.signup_days"
+	SettingReferralPurchaseDays = "referral.purchase_days"
+	SettingReferralPlanId       = "referral.plan_id"
+	SettingReferralMaxRewards   = "referral.max_rewards"
+)
+
+const referralCodeLength = 8
+
+type ReferralService struct {
+}
+
+// registerReferralSettings 注册推荐奖励设置
+func (s *SystemSettingService) registerReferralSettings() {
+	zero := float64(0)
+	s.RegisterSetting(&model.SettingDefinition{
+		Key:         SettingReferralEnable,
+		Type:        model.SettingTypeBool,
+		Group:       "referral",
+		Description: "Enable the referral program",
+		Default:     false,
+	})
+	s.RegisterSetting(&model.SettingDefinition{
+		Key:         SettingReferralSignupDays,
+		Type:        model.SettingTypeInt,
+		Group:       "referral",
+		Description: "Days granted to the referrer when a referred user signs up, 0 disables",
+		Default:     int64(0),
+		Min:         &zero,
+	})
+	s.RegisterSetting(&model.SettingDefinition{
+		Key:         SettingReferralPurchaseDays,
+		Type:        model.SettingTypeInt,
+		Group:       "referral",
+		Description: "Days granted to the referrer on the first paid order of a referred user, 0 disables",
+		Default:     int64(7),
+		Min:         &zero,
+	})
+	s.RegisterSetting(&model.SettingDefinition{
+		Key:         SettingReferralPlanId,
+		Type:        model.SettingTypeInt,
+		Group:       "referral",
+		Description: "Plan granted when the referrer has no subscription, 0 skips the reward",
+		Default:     int64(0),
+		Min:         &zero,
+	})
+	s.RegisterSetting(&model.SettingDefinition{
+		Key:         SettingReferralMaxRewards,
+		Type:        model.SettingTypeInt,
+		Group:       "referral",
+		Description: "Maximum rewarded referrals per referrer, 0 for unlimited",
+		Default:     int64(0),
+		Min:         &zero,
+	})
+}
+
+func (rs *ReferralService) subscribeEvents(bus *EventBus) {
+	On(bus, func(e OrderPaidEvent) { rs.onOrderPaid(e.Order) })
+}
+
+// IsEnabled 是否开启推荐计划
+func (rs *ReferralService) IsEnabled() bool {
+	return AllService.SystemSettingService.SettingBool(SettingReferralEnable)
+}
+
+// NormalizeCode 规范化推荐码
+func (rs *ReferralService) NormalizeCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// GetOrCreateCode 获取用户的推荐码, 不存在时生成
+func (rs *ReferralService) GetOrCreateCode(userId uint) (*model.ReferralCode, error) {
+	rc := &model.ReferralCode{}
+	DB.Where("user_id = ?", userId).First(rc)
+	if rc.Id > 0 {
+		return rc, nil
+	}
+	// 唯一索引冲突时重试
+	var err error
+	for i := 0; i < 3; i++ {
+		rc = &model.ReferralCode{UserId: userId, Code: strings.ToUpper(utils.RandomString(referralCodeLength))}
+		if err = DB.Create(rc).Error; err == nil {
+			return rc, nil
+		}
+		// 并发创建时另一请求已生成
+		exist := &model.ReferralCode{}
+		DB.Where("user_id = ?", userId).First(exist)
+		if exist.Id > 0 {
+			return exist, nil
+		}
+	}
+	return nil, err
+}
+
+// Summary 用户的推荐码和推荐效果
+func (rs *ReferralService) Summary(userId uint) (*model.ReferralSummary, error) {
+	rc, err := rs.GetOrCreateCode(userId)
+	if err != nil {
+		return nil, err
+	}
+	res := &model.ReferralSummary{Code: rc.Code}
+	stats := rs.stats(func(tx *gorm.DB) { tx.Where("referrer_id = ?", userId) })
+	if len(stats) > 0 {
+		res.Signups = stats[0].Signups
+		res.Purchases = stats[0].Purchases
+		res.RewardDays = stats[0].RewardDays
+	}
+	return res, nil
+}
+
+// Attribute 记录新注册用户的推荐关系并发放注册奖励, 推荐码无效或未开启时忽略
+func (rs *ReferralService) Attribute(refereeId uint, code string) {
+	code = rs.NormalizeCode(code)
+	if code == "" || !rs.IsEnabled() {
+		return
+	}
+	rc := &model.ReferralCode{}
+	DB.Where("code = ?", code).First(rc)
+	if rc.Id == 0 || rc.UserId == refereeId {
+		return
+	}
+	r := &model.Referral{ReferrerId: rc.UserId, RefereeId: refereeId, Code: rc.Code}
+	if err := DB.Create(r).Error; err != nil {
+		Logger.Error("Referral attribute failed, referee: ", refereeId, " err: ", err)
+		return
+	}
+	days := int(AllService.SystemSettingService.SettingInt(SettingReferralSignupDays))
+	rs.grantReward(r, days, "signup_reward")
+}
+
+// onOrderPaid 被推荐用户首笔付费订单, 向推荐人发放首购奖励
+func (rs *ReferralService) onOrderPaid(order *model.Order) {
+	if order == nil || order.Amount <= 0 || !rs.IsEnabled() {
+		return
+	}
+	r := &model.Referral{}
+	DB.Where("referee_id = ? AND first_order_id = 0", order.UserId).First(r)
+	if r.Id == 0 {
+		return
+	}
+	// 条件更新, 回调重复或并发时只处理一次
+	res := DB.Model(&model.Referral{}).Where("id = ? AND first_order_id = 0", r.Id).
+		Updates(map[string]interface{}{"first_order_id": order.Id, "purchased_at": order.PaidAt})
+	if res.Error != nil || res.RowsAffected == 0 {
+		return
+	}
+	days := int(AllService.SystemSettingService.SettingInt(SettingReferralPurchaseDays))
+	rs.grantReward(r, days, "purchase_reward")
+}
+
+// grantReward 发放奖励并记录到 column, 失败时只记录日志, 不影响推荐关系
+func (rs *ReferralService) grantReward(r *model.Referral, days int, column string) {
+	var granted int
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if granted, err = rs.reward(tx, r, days); err != nil || granted == 0 {
+			return err
+		}
+		return tx.Model(&model.Referral{}).Where("id = ?", r.Id).Update(column, granted).Error
+	})
+	if err != nil {
+		Logger.Error("Referral reward failed, referral: ", r.Id, " err: ", err)
+		return
+	}
+	if granted > 0 {
+		AllService.EntitlementService.Invalidate(r.ReferrerId)
+	}
+}
+
+// reward 为推荐人续期 days 天(事务内调用), 返回实际发放的天数
+// 续期推荐人当前订阅的套餐, 没有订阅时使用设置的奖励套餐; 达到奖励上限时不发放
+func (rs *ReferralService) reward(tx *gorm.DB, r *model.Referral, days int) (int, error) {
+	if days <= 0 {
+		return 0, nil
+	}
+	settings := AllService.SystemSettingService
+	if max := settings.SettingInt(SettingReferralMaxRewards); max > 0 {
+		var rewarded int64
+		tx.Model(&model.Referral{}).
+			Where("referrer_id = ? AND id <> ? AND (signup_reward > 0 OR purchase_reward > 0)", r.ReferrerId, r.Id).
+			Count(&rewarded)
+		if r.SignupReward == 0 && rewarded >= max {
+			return 0, nil
+		}
+	}
+	sub := &model.UserSubscription{}
+	tx.Where("user_id = ?", r.ReferrerId).First(sub)
+	planId := referralRewardPlan(sub, uint(settings.SettingInt(SettingReferralPlanId)))
+	if planId == 0 {
+		return 0, nil
+	}
+	err := AllService.SubscriptionService.extendSubscriptionPeriod(tx, r.ReferrerId, planId, model.Period{Days: days}, time.Now().Unix(), subscriptionChange{
+		Source:   model.SubscriptionSourceReferral,
+		ActorId:  r.RefereeId,
+		Metadata: map[string]interface{}{"referral_id": r.Id},
+	})
+	if err != nil {
+		return 0, err
+	}
+	return days, nil
+}
+
+// referralRewardPlan 奖励续期的套餐: 推荐人已有订阅(含已到期)时沿用其套餐, 否则使用设置的奖励套餐
+func referralRewardPlan(sub *model.UserSubscription, fallback uint) uint {
+	if sub != nil && sub.Id > 0 && sub.PlanId > 0 && sub.Status != model.SubscriptionStatusCanceled {
+		return sub.PlanId
+	}
+	return fallback
+}
+
+// List 推荐记录(管理员)
+func (rs *ReferralService) List(page, pageSize uint, where func(tx *gorm.DB)) *model.ReferralList {
+	res := &model.ReferralList{}
+	res.Page = int64(page)
+	res.PageSize = int64(pageSize)
+	tx := DB.Model(&model.Referral{})
+	if where != nil {
+		where(tx)
+	}
+	tx.Count(&res.Total)
+	tx.Scopes(Paginate(page, pageSize))
+	tx.Preload("Referrer").Preload("Referee").Order("id DESC").Find(&res.Referrals)
+	return res
+}
+
+// Report 按推荐人统计推荐效果, 按推荐注册数倒序
+func (rs *ReferralService) Report(limit int) []*model.ReferralStat {
+	return rs.stats(func(tx *gorm.DB) { tx.Order("signups DESC").Limit(limit) })
+}
+
+func (rs *ReferralService) stats(where func(tx *gorm.DB)) []*model.ReferralStat {
+	stats := make([]*model.ReferralStat, 0)
+	tx := DB.Model(&model.Referral{}).
+		Select("referrer_id, count(*) as signups, sum(CASE WHEN first_order_id > 0 THEN 1 ELSE 0 END) as purchases, sum(signup_reward + purchase_reward) as reward_days").
+		Group("referrer_id")
+	where(tx)
+	tx.Scan(&stats)
+	if len(stats) == 0 {
+		return stats
+	}
+	ids := make([]uint, 0, len(stats))
+	for _, s := range stats {
+		ids = append(ids, s.ReferrerId)
+	}
+	var users []*model.User
+	DB.Select("id, username").Where("id IN ?", ids).Find(&users)
+	names := make(map[uint]string, len(users))
+	for _, u := range users {
+		names[u.Id] = u.Username
+	}
+	for _, s := range stats {
+		s.Username = names[s.ReferrerId]
+	}
+	return stats
+}
@@ -0,0 +1,27 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/lejianwen/rustdesk-api/v2/model"
+)
+
+func TestReferralRewardPlan(t *testing.T) {
+	cases := []struct {
+		name     string
+		sub      *model.UserSubscription
+		fallback uint
+		want     uint
+	}{
+		{"no subscription", &model.UserSubscription{}, 3, 3},
+		{"no subscription no fallback", &model.UserSubscription{}, 0, 0},
+		{"active", &model.UserSubscription{IdModel: model.IdModel{Id: 1}, PlanId: 2, Status: model.SubscriptionStatusActive}, 3, 2},
+		{"expired keeps plan", &model.UserSubscription{IdModel: model.IdModel{Id: 1}, PlanId: 2, Status: model.SubscriptionStatusExpired}, 3, 2},
+		{"canceled uses fallback", &model.UserSubscription{IdModel: model.IdModel{Id: 1}, PlanId: 2, Status: model.SubscriptionStatusCanceled}, 3, 3},
+	}
+	for _, tc := range cases {
+		if got := referralRewardPlan(tc.sub, tc.fallback); got != tc.want {
+			t.Errorf("%s: got %d, want %d", tc.name, got, tc.want)
+		}
+	}
+}
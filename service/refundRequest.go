@@ -0,0 +1,170 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lejianwen/rustdesk-api/v2/model"
+	"gorm.io/gorm"
+)
+
+// RequestRefund 提交退款申请, 金额规则与 RefundOrder 一致; 同一订单同时只能有一个待审批申请
+func (ss *SubscriptionService) RequestRefund(orderId uint, amount int64, reason string, requesterId uint) (*model.RefundRequest, error) {
+	lockKey := fmt.Sprintf("refund_order_%d", orderId)
+	Lock.Lock(lockKey)
+	defer Lock.UnLock(lockKey)
+
+	order := ss.GetOrderById(orderId)
+	if order.Id == 0 {
+		return nil, errors.New("OrderNotFound")
+	}
+	if order.Status != model.OrderStatusPaid {
+		return nil, errors.New("OrderNotPaid")
+	}
+	if order.TradeNo == "" {
+		return nil, errors.New("TradeNoEmpty")
+	}
+	if amount < 0 || amount > order.Refundable().Amount {
+		return nil, errors.New("RefundAmountInvalid")
+	}
+	var pending int64
+	DB.Model(&model.RefundRequest{}).Where("order_id = ? AND status = ?", orderId, model.RefundRequestPending).Count(&pending)
+	if pending > 0 {
+		return nil, errors.New("RefundRequestExists")
+	}
+	r := &model.RefundRequest{
+		OrderId:     order.Id,
+		UserId:      order.UserId,
+		Amount:      amount,
+		Currency:    order.Currency,
+		Reason:      reason,
+		Status:      model.RefundRequestPending,
+		RequesterId: requesterId,
+	}
+	if err := DB.Create(r).Error; err != nil {
+		return nil, err
+	}
+	Logger.Info("Refund requested, order: ", order.OutTradeNo, " amount: ", amount, " requester: ", requesterId)
+	return r, nil
+}
+
+func (ss *SubscriptionService) GetRefundRequestById(id uint) *model.RefundRequest {
+	r := &model.RefundRequest{}
+	DB.Where("id = ?", id).First(r)
+	return r
+}
+
+// RefundDirect 超级管理员直接退款: 提交申请并立即以本人身份批准, 保留申请记录
+// 兼容拆分审批流程前 /order/refund 立即退款的行为, 单管理员部署也能退款
+func (ss *SubscriptionService) RefundDirect(orderId uint, amount int64, reason string, adminId uint) (*model.RefundRequest, *model.Refund, error) {
+	r, err := ss.RequestRefund(orderId, amount, reason, adminId)
+	if err != nil {
+		return nil, nil, err
+	}
+	req, refund, err := ss.approveRefund(r.Id, adminId, "direct refund by super admin", true)
+	if err != nil {
+		// 失败时不保留待审批申请, 避免再次退款时提示已有申请
+		DB.Model(r).Updates(map[string]interface{}{
+			"status":      model.RefundRequestRejected,
+			"reviewer_id": adminId,
+			"review_note": "direct refund failed",
+			"reviewed_at": time.Now().Unix(),
+		})
+		return nil, nil, err
+	}
+	return req, refund, nil
+}
+
+// pendingRefundRequest 待审批的申请, 除超级管理员直接退款外申请人不能审批自己的申请
+func (ss *SubscriptionService) pendingRefundRequest(id, reviewerId uint, allowSelf bool) (*model.RefundRequest, error) {
+	r := ss.GetRefundRequestById(id)
+	if r.Id == 0 {
+		return nil, errors.New("ItemNotFound")
+	}
+	if r.Status != model.RefundRequestPending {
+		return nil, errors.New("RefundRequestNotPending")
+	}
+	if r.RequesterId == reviewerId && !allowSelf {
+		return nil, errors.New("RefundSelfApprove")
+	}
+	return r, nil
+}
+
+// ApproveRefund 批准退款申请并调用支付网关退款
+// 网关或入账失败时申请保持待审批并记录错误, 可重试或驳回
+func (ss *SubscriptionService) ApproveRefund(id, reviewerId uint, note string) (*model.RefundRequest, *model.Refund, error) {
+	return ss.approveRefund(id, reviewerId, note, false)
+}
+
+func (ss *SubscriptionService) approveRefund(id, reviewerId uint, note string, allowSelf bool) (*model.RefundRequest, *model.Refund, error) {
+	lockKey := fmt.Sprintf("refund_request_%d", id)
+	Lock.Lock(lockKey)
+	defer Lock.UnLock(lockKey)
+
+	r, err := ss.pendingRefundRequest(id, reviewerId, allowSelf)
+	if err != nil {
+		return nil, nil, err
+	}
+	refund, err := ss.RefundOrder(r.OrderId, r.Amount, r.Reason, reviewerId)
+	if err != nil {
+		DB.Model(r).Update("last_error", err.Error())
+		return nil, nil, err
+	}
+	updates := map[string]interface{}{
+		"status":      model.RefundRequestApproved,
+		"reviewer_id": reviewerId,
+		"review_note": note,
+		"reviewed_at": time.Now().Unix(),
+		"refund_id":   refund.Id,
+		"last_error":  "",
+	}
+	if err := DB.Model(r).Updates(updates).Error; err != nil {
+		// 已退款, 仅申请状态未更新
+		Logger.Error("Update refund request failed after refund, request: ", r.Id, " refund: ", refund.Id, " err: ", err)
+	}
+	return ss.GetRefundRequestById(r.Id), refund, nil
+}
+
+// RejectRefund 驳回退款申请
+func (ss *SubscriptionService) RejectRefund(id, reviewerId uint, note string) (*model.RefundRequest, error) {
+	lockKey := fmt.Sprintf("refund_request_%d", id)
+	Lock.Lock(lockKey)
+	defer Lock.UnLock(lockKey)
+
+	r, err := ss.pendingRefundRequest(id, reviewerId, false)
+	if err != nil {
+		return nil, err
+	}
+	if err := DB.Model(r).Updates(map[string]interface{}{
+		"status":      model.RefundRequestRejected,
+		"reviewer_id": reviewerId,
+		"review_note": note,
+		"reviewed_at": time.Now().Unix(),
+	}).Error; err != nil {
+		return nil, err
+	}
+	return ss.GetRefundRequestById(r.Id), nil
+}
+
+// ListRefundRequests 退款申请列表
+func (ss *SubscriptionService) ListRefundRequests(page, pageSize uint, where func(tx *gorm.DB)) (res *model.RefundRequestList) {
+	res = &model.RefundRequestList{}
+	res.Page = int64(page)
+	res.PageSize = int64(pageSize)
+	tx := DB.Model(&model.RefundRequest{})
+	if where != nil {
+		where(tx)
+	}
+	tx.Count(&res.Total)
+	tx.Scopes(Paginate(page, pageSize))
+	tx.Order("id DESC").Find(&res.RefundRequests)
+	return
+}
+
+// ListOrderRefundRequests 订单的退款申请
+func (ss *SubscriptionService) ListOrderRefundRequests(orderId uint) []*model.RefundRequest {
+	var list []*model.RefundRequest
+	DB.Where("order_id = ?", orderId).Order("id ASC").Find(&list)
+	return list
+}
@@ -0,0 +1,239 @@
+package service
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/lejianwen/rustdesk-api/v2/model"
+	"gorm.io/gorm"
+)
+
+// relayQuotaCacheTTL 配额判断结果缓存时间, 避免每次 allow/consume 都汇总会话
+const relayQuotaCacheTTL = 30 * time.Second
+
+// activeSessionMaxAge 超过该时长仍未上报结束的会话视为结束上报丢失, 不计入并发数
+const activeSessionMaxAge = 24 * time.Hour
+
+type RelaySessionService struct {
+	mu         sync.Mutex
+	quotaCache map[uint]relayQuotaCacheItem
+}
+
+type relayQuotaCacheItem struct {
+	exceeded bool
+	at       time.Time
+}
+
+// RelaySessionReport hbbr 上报的会话信息
+type RelaySessionReport struct {
+	Uuid         string
+	PeerId       string
+	TargetPeerId string
+	RelayAddr    string
+	At           int64 // 开始或结束时间(秒), 0 表示当前时间
+	Duration     int64
+	BytesUp      int64
+	BytesDown    int64
+}
+
+func (rs *RelaySessionService) InfoByUuid(uuid string) *model.RelaySession {
+	s := &model.RelaySession{}
+	DB.Where("uuid = ?", uuid).First(s)
+	return s
+}
+
+func (rs *RelaySessionService) List(page, pageSize uint, where func(tx *gorm.DB)) (res *model.RelaySessionList) {
+	res = &model.RelaySessionList{}
+	res.Page = int64(page)
+	res.PageSize = int64(pageSize)
+	tx := DB.Model(&model.RelaySession{})
+	if where != nil {
+		where(tx)
+	}
+	tx.Count(&res.Total)
+	tx.Scopes(Paginate(page, pageSize))
+	tx.Find(&res.RelaySessions)
+	return
+}
+
+// Start 记录会话开始, 同一 uuid 重复上报时忽略
+func (rs *RelaySessionService) Start(r *RelaySessionReport) (*model.RelaySession, error) {
+	if ex := rs.InfoByUuid(r.Uuid); ex.Id > 0 {
+		return ex, nil
+	}
+	s := &model.RelaySession{
+		Uuid:         r.Uuid,
+		PeerId:       r.PeerId,
+		TargetPeerId: r.TargetPeerId,
+		RelayAddr:    r.RelayAddr,
+		StartedAt:    r.At,
+		Status:       model.RelaySessionActive,
+	}
+	if s.StartedAt == 0 {
+		s.StartedAt = time.Now().Unix()
+	}
+	rs.resolveUsers(s)
+	if err := DB.Create(s).Error; err != nil {
+		return s, err
+	}
+	AllService.AuditService.RecordRelayConnection(s)
+	return s, nil
+}
+
+// End 记录会话结束及流量; 未收到开始上报时按结束上报补建记录
+func (rs *RelaySessionService) End(r *RelaySessionReport) (*model.RelaySession, error) {
+	if r.Duration < 0 || r.BytesUp < 0 || r.BytesDown < 0 {
+		return nil, errors.New("invalid usage")
+	}
+	endedAt := r.At
+	if endedAt == 0 {
+		endedAt = time.Now().Unix()
+	}
+	s := rs.InfoByUuid(r.Uuid)
+	if s.Id == 0 {
+		s = &model.RelaySession{
+			Uuid:         r.Uuid,
+			PeerId:       r.PeerId,
+			TargetPeerId: r.TargetPeerId,
+			RelayAddr:    r.RelayAddr,
+			StartedAt:    endedAt - r.Duration,
+		}
+		rs.resolveUsers(s)
+	} else if s.Status == model.RelaySessionEnded {
+		return s, nil
+	}
+	s.EndedAt = endedAt
+	s.Duration = r.Duration
+	if s.Duration == 0 && s.StartedAt > 0 && endedAt > s.StartedAt {
+		s.Duration = endedAt - s.StartedAt
+	}
+	s.BytesUp = r.BytesUp
+	s.BytesDown = r.BytesDown
+	s.Status = model.RelaySessionEnded
+	if err := DB.Save(s).Error; err != nil {
+		return s, err
+	}
+	AllService.AuditService.RecordRelayConnection(s)
+	return s, nil
+}
+
+// resolveUsers 根据设备ID解析归属用户
+func (rs *RelaySessionService) resolveUsers(s *model.RelaySession) {
+	if s.PeerId != "" {
+		s.UserId = AllService.PeerService.FindById(s.PeerId).UserId
+	}
+	if s.TargetPeerId != "" {
+		s.TargetUserId = AllService.PeerService.FindById(s.TargetPeerId).UserId
+	}
+}
+
+// Usage 按发起端用户汇总 [since, until) 内已结束会话的用量, userId 为 0 表示全部用户
+func (rs *RelaySessionService) Usage(userId uint, since, until int64) []*model.RelayUsage {
+	res := make([]*model.RelayUsage, 0)
+	tx := DB.Model(&model.RelaySession{}).
+		Select("user_id, count(*) as sessions, sum(duration) as duration, sum(bytes_up + bytes_down) as bytes").
+		Where("status = ? AND started_at >= ? AND started_at < ?", model.RelaySessionEnded, since, until)
+	if userId > 0 {
+		tx = tx.Where("user_id = ?", userId)
+	}
+	tx.Group("user_id").Order("bytes desc").Scan(&res)
+	return res
+}
+
+// relayQuotaPeriod 配额统计周期: 服务器时区的自然月
+func relayQuotaPeriod(now time.Time) (time.Time, time.Time) {
+	start := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	return start, start.AddDate(0, 1, 0)
+}
+
+// Quota 获取用户当月 relay 配额及用量, 配额取当前有效订阅的套餐
+func (rs *RelaySessionService) Quota(userId uint) *model.RelayQuotaUsage {
+	start, end := relayQuotaPeriod(time.Now())
+	q := &model.RelayQuotaUsage{PeriodStart: start.Unix(), PeriodEnd: end.Unix()}
+	if sub := AllService.Subscription().EffectiveSubscription(userId); sub.Plan != nil && AllService.Subscription().IsSubscriptionActive(userId) {
+		q.QuotaBytes = sub.Plan.RelayQuotaBytes
+		q.QuotaMinutes = sub.Plan.RelayQuotaMinutes
+	}
+	if usage := rs.Usage(userId, q.PeriodStart, q.PeriodEnd); len(usage) > 0 {
+		q.UsedBytes = usage[0].Bytes
+		q.UsedMinutes = (usage[0].Duration + 59) / 60
+	}
+	q.Exceeded = (q.QuotaBytes > 0 && q.UsedBytes >= q.QuotaBytes) ||
+		(q.QuotaMinutes > 0 && q.UsedMinutes >= q.QuotaMinutes)
+	return q
+}
+
+// QuotaExceeded 用户当月 relay 配额是否已用尽, 支付未启用或未识别用户时不限制
+func (rs *RelaySessionService) QuotaExceeded(userId uint) bool {
+	if userId == 0 || !AllService.Payment().IsEnabled() {
+		return false
+	}
+	rs.mu.Lock()
+	item, ok := rs.quotaCache[userId]
+	rs.mu.Unlock()
+	if ok && time.Since(item.at) < relayQuotaCacheTTL {
+		return item.exceeded
+	}
+
+	exceeded := rs.Quota(userId).Exceeded
+	// 维护窗口期间不按配额拒绝
+	if exceeded && AllService.MaintenanceService.Relax("relay quota", userId) != nil {
+		exceeded = false
+	}
+	rs.mu.Lock()
+	if rs.quotaCache == nil {
+		rs.quotaCache = make(map[uint]relayQuotaCacheItem)
+	}
+	rs.quotaCache[userId] = relayQuotaCacheItem{exceeded: exceeded, at: time.Now()}
+	rs.mu.Unlock()
+	return exceeded
+}
+
+// ActiveSessions 用户作为发起端进行中的会话数
+func (rs *RelaySessionService) ActiveSessions(userId uint) int64 {
+	var n int64
+	DB.Model(&model.RelaySession{}).
+		Where("user_id = ? AND status = ? AND started_at > ?", userId, model.RelaySessionActive, time.Now().Add(-activeSessionMaxAge).Unix()).
+		Count(&n)
+	return n
+}
+
+// sessionAllowed 已有 active 个会话时是否允许再建立一个, limit 为 0 表示不限
+func sessionAllowed(limit, active int64) bool {
+	return limit <= 0 || active < limit
+}
+
+// SessionCheck 按套餐的并发会话数检查用户能否建立新连接, 支付未启用、未识别用户或无有效订阅时不限制
+func (rs *RelaySessionService) SessionCheck(userId uint) *model.SessionCheck {
+	res := &model.SessionCheck{Allow: true}
+	if userId == 0 || !AllService.Payment().IsEnabled() {
+		return res
+	}
+	sub := AllService.Subscription().EffectiveSubscription(userId)
+	if sub.Plan == nil || sub.Plan.MaxSessions <= 0 || !AllService.Subscription().IsSubscriptionActive(userId) {
+		return res
+	}
+	res.Limit = int64(sub.Plan.MaxSessions)
+	res.Active = rs.ActiveSessions(userId)
+	res.Allow = sessionAllowed(res.Limit, res.Active)
+	// 维护窗口期间不按并发数拒绝
+	if !res.Allow && AllService.MaintenanceService.Relax("concurrent sessions", userId) != nil {
+		res.Allow = true
+	}
+	return res
+}
+
+// invalidateQuota 清除用户的配额判断缓存
+func (rs *RelaySessionService) invalidateQuota(userId uint) {
+	rs.mu.Lock()
+	delete(rs.quotaCache, userId)
+	rs.mu.Unlock()
+}
+
+// subscribeEvents 订阅变化时清除配额缓存
+func (rs *RelaySessionService) subscribeEvents(bus *EventBus) {
+	On(bus, func(e SubscriptionActivatedEvent) { rs.invalidateQuota(e.Subscription.UserId) })
+	On(bus, func(e SubscriptionExpiredEvent) { rs.invalidateQuota(e.Subscription.UserId) })
+	On(bus, func(e OrderRefundedEvent) { rs.invalidateQuota(e.Order.UserId) })
+}
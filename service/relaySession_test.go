@@ -0,0 +1,57 @@
+package service
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/lejianwen/rustdesk-api/v2/model"
+)
+
+func TestSessionCheck(t *testing.T) {
+	plan := &model.SubscriptionPlan{IdModel: model.IdModel{Id: 1}, MaxSessions: 2}
+	s := newTestService(t, []interface{}{&model.RelaySession{}, &model.MaintenanceWindow{}},
+		WithPayment(enabledPayment{}), WithSubscription(planSubscription{plan: plan}))
+
+	now := time.Now()
+	seed := []struct {
+		userId    uint
+		status    int
+		startedAt time.Time
+	}{
+		{1, model.RelaySessionActive, now.Add(-time.Minute)},
+		{1, model.RelaySessionActive, now.Add(-time.Hour)},
+		{1, model.RelaySessionEnded, now.Add(-time.Minute)},                        // 已结束
+		{1, model.RelaySessionActive, now.Add(-activeSessionMaxAge - time.Minute)}, // 未上报结束的旧会话
+		{2, model.RelaySessionActive, now.Add(-time.Minute)},
+	}
+	for i, r := range seed {
+		if err := DB.Create(&model.RelaySession{Uuid: fmt.Sprintf("s%d", i), UserId: r.userId, Status: r.status, StartedAt: r.startedAt.Unix()}).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rs := s.RelaySessionService
+	if res := rs.SessionCheck(1); res.Allow || res.Limit != 2 || res.Active != 2 {
+		t.Errorf("user at limit: %+v", res)
+	}
+	if res := rs.SessionCheck(2); !res.Allow || res.Active != 1 {
+		t.Errorf("user under limit: %+v", res)
+	}
+	if res := rs.SessionCheck(0); !res.Allow || res.Limit != 0 {
+		t.Errorf("unknown user should not be limited: %+v", res)
+	}
+
+	// 维护窗口期间超出并发数也放行
+	if err := s.MaintenanceService.Create(&model.MaintenanceWindow{
+		StartAt: now.Add(-time.Minute).Unix(),
+		EndAt:   now.Add(time.Hour).Unix(),
+		Mode:    model.MaintenanceModeWarn,
+		Status:  model.COMMON_STATUS_ENABLE,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if res := rs.SessionCheck(1); !res.Allow || res.Active != 2 {
+		t.Errorf("maintenance window should relax the limit: %+v", res)
+	}
+}
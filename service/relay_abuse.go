@@ -0,0 +1,200 @@
+package service
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lejianwen/rustdesk-api/v2/model"
+	"gorm.io/gorm"
+)
+
+const (
+	relayAbuseConsumeFailures = 20               // 同一 uuid 窗口内消费失败次数阈值
+	relayAbuseConsumeWindow   = time.Minute      // 消费失败统计窗口
+	relayAbuseAllowsPerUser   = 200              // 同一用户窗口内写入白名单次数阈值
+	relayAbuseAllowWindow     = time.Minute      // 写入统计窗口
+	relayAbuseCooldown        = 10 * time.Minute // 同一对象触发后的静默期, 避免重复告警
+	relayAbuseSampleSize      = 10               // 记录的样本 uuid 数量
+)
+
+// relayAbuseDetector 统计 relay 白名单的异常使用模式
+type relayAbuseDetector struct {
+	mu          sync.Mutex
+	consumeFail map[string]*relayAbuseCounter // uuid -> 消费失败
+	userAllows  map[uint]*relayAbuseCounter   // user_id -> 写入
+	reportedAt  map[string]time.Time          // 告警键 -> 上次触发时间
+}
+
+type relayAbuseCounter struct {
+	count   int
+	firstAt time.Time
+	peerId  string
+	reasons map[string]int
+	samples []string
+}
+
+// hit 计数, 超出窗口时重新开始
+func (ct *relayAbuseCounter) hit(now time.Time, window time.Duration) {
+	if ct.count == 0 || now.Sub(ct.firstAt) > window {
+		ct.count = 0
+		ct.firstAt = now
+		ct.reasons = nil
+		ct.samples = nil
+	}
+	ct.count++
+}
+
+func (s *RelayWhitelistService) abuse() *relayAbuseDetector {
+	s.abuseOnce.Do(func() {
+		s.detector = &relayAbuseDetector{
+			consumeFail: make(map[string]*relayAbuseCounter),
+			userAllows:  make(map[uint]*relayAbuseCounter),
+			reportedAt:  make(map[string]time.Time),
+		}
+	})
+	return s.detector
+}
+
+// recordConsumeFailure 记录一次消费失败, reason: not_found/expired/no_slots
+func (s *RelayWhitelistService) recordConsumeFailure(uuid, reason string) {
+	d := s.abuse()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := time.Now()
+	ct, ok := d.consumeFail[uuid]
+	if !ok {
+		ct = &relayAbuseCounter{}
+		d.consumeFail[uuid] = ct
+	}
+	ct.hit(now, relayAbuseConsumeWindow)
+	if ct.reasons == nil {
+		ct.reasons = make(map[string]int)
+	}
+	ct.reasons[reason]++
+	if ct.count < relayAbuseConsumeFailures || !d.shouldReport("consume:"+uuid, now) {
+		return
+	}
+	incident := &model.RelayIncident{
+		Kind:      model.RelayIncidentConsumeFailures,
+		Uuid:      uuid,
+		Count:     ct.count,
+		WindowSec: int(relayAbuseConsumeWindow.Seconds()),
+		FirstAt:   ct.firstAt.Unix(),
+		LastAt:    now.Unix(),
+		Detail:    "reasons: " + formatAbuseReasons(ct.reasons),
+	}
+	go s.reportIncident(incident)
+}
+
+// RecordAllow 记录用户写入白名单, 用于识别单个用户短时间内大量发起 relay
+// 由调用方在能识别发起用户时调用, userId 为 0 时忽略
+func (s *RelayWhitelistService) RecordAllow(userId uint, peerId, uuid string) {
+	if userId == 0 {
+		return
+	}
+	d := s.abuse()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := time.Now()
+	ct, ok := d.userAllows[userId]
+	if !ok {
+		ct = &relayAbuseCounter{}
+		d.userAllows[userId] = ct
+	}
+	ct.hit(now, relayAbuseAllowWindow)
+	ct.peerId = peerId
+	if len(ct.samples) < relayAbuseSampleSize {
+		ct.samples = append(ct.samples, uuid)
+	}
+	if ct.count < relayAbuseAllowsPerUser || !d.shouldReport(fmt.Sprintf("allow:%d", userId), now) {
+		return
+	}
+	incident := &model.RelayIncident{
+		Kind:      model.RelayIncidentAllowFlood,
+		Uuid:      uuid,
+		UserId:    userId,
+		PeerId:    peerId,
+		Count:     ct.count,
+		WindowSec: int(relayAbuseAllowWindow.Seconds()),
+		FirstAt:   ct.firstAt.Unix(),
+		LastAt:    now.Unix(),
+		Detail:    "sample uuids: " + strings.Join(ct.samples, ","),
+	}
+	go s.reportIncident(incident)
+}
+
+// shouldReport 静默期内同一告警键只触发一次, 调用方需持有锁
+func (d *relayAbuseDetector) shouldReport(key string, now time.Time) bool {
+	if last, ok := d.reportedAt[key]; ok && now.Sub(last) < relayAbuseCooldown {
+		return false
+	}
+	d.reportedAt[key] = now
+	return true
+}
+
+// cleanupAbuse 清理过期的计数
+func (s *RelayWhitelistService) cleanupAbuse() {
+	d := s.abuse()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := time.Now()
+	for k, ct := range d.consumeFail {
+		if now.Sub(ct.firstAt) > relayAbuseConsumeWindow {
+			delete(d.consumeFail, k)
+		}
+	}
+	for k, ct := range d.userAllows {
+		if now.Sub(ct.firstAt) > relayAbuseAllowWindow {
+			delete(d.userAllows, k)
+		}
+	}
+	for k, t := range d.reportedAt {
+		if now.Sub(t) > relayAbuseCooldown {
+			delete(d.reportedAt, k)
+		}
+	}
+}
+
+func formatAbuseReasons(reasons map[string]int) string {
+	keys := make([]string, 0, len(reasons))
+	for k := range reasons {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		parts = append(parts, fmt.Sprintf("%s=%d", k, reasons[k]))
+	}
+	return strings.Join(parts, " ")
+}
+
+// reportIncident 记录异常并发布事件, 由 webhook 和邮件通知管理员
+func (s *RelayWhitelistService) reportIncident(incident *model.RelayIncident) {
+	Logger.Warnf("RelayWhitelist: suspicious relay pattern kind=%s uuid=%s user_id=%d count=%d window=%ds %s",
+		incident.Kind, incident.Uuid, incident.UserId, incident.Count, incident.WindowSec, incident.Detail)
+	if err := DB.Create(incident).Error; err != nil {
+		Logger.Error("RelayWhitelist: save incident failed: ", err)
+		return
+	}
+	AllService.EventBus.Publish(RelayAbuseEvent{Incident: incident})
+}
+
+func (s *RelayWhitelistService) IncidentList(page, pageSize uint, where func(tx *gorm.DB)) (res *model.RelayIncidentList) {
+	res = &model.RelayIncidentList{}
+	res.Page = int64(page)
+	res.PageSize = int64(pageSize)
+	tx := DB.Model(&model.RelayIncident{})
+	if where != nil {
+		where(tx)
+	}
+	tx.Count(&res.Total)
+	tx.Scopes(Paginate(page, pageSize))
+	tx.Find(&res.RelayIncidents)
+	return
+}
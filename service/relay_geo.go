@@ -0,0 +1,243 @@
+package service
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lejianwen/rustdesk-api/v2/model"
+	"gorm.io/gorm"
+)
+
+const relayGeoCacheTTL = time.Minute
+
+// RelayGeoService relay 白名单消费的地区限制
+type RelayGeoService struct {
+	mu       sync.Mutex
+	rules    []*model.RelayGeoRule
+	loadedAt time.Time
+}
+
+// splitGeoList 按逗号、空白和换行拆分国家或网段列表
+func splitGeoList(s string) []string {
+	return strings.FieldsFunc(s, func(r rune) bool {
+		return r == ',' || r == '\n' || r == '\r' || r == ' ' || r == '\t'
+	})
+}
+
+// parseGeoCidr 解析 CIDR, 单个 IP 视为 /32 或 /128
+func parseGeoCidr(s string) (*net.IPNet, error) {
+	if !strings.Contains(s, "/") {
+		ip := net.ParseIP(s)
+		if ip == nil {
+			return nil, fmt.Errorf("invalid ip: %s", s)
+		}
+		bits := 128
+		if ip.To4() != nil {
+			ip, bits = ip.To4(), 32
+		}
+		return &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}, nil
+	}
+	_, n, err := net.ParseCIDR(s)
+	return n, err
+}
+
+// geoRuleMatches 国家代码或网段任一匹配
+func geoRuleMatches(rule *model.RelayGeoRule, ip net.IP, country string) bool {
+	if country != "" {
+		for _, c := range splitGeoList(rule.Countries) {
+			if strings.EqualFold(c, country) {
+				return true
+			}
+		}
+	}
+	for _, s := range splitGeoList(rule.Cidrs) {
+		if n, err := parseGeoCidr(s); err == nil && n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// relayGeoAllowed 判断 IP 是否满足规则, 拒绝时返回导致拒绝的规则
+// 任一 deny 规则匹配即拒绝; 存在 allow 规则时需至少匹配一条
+func relayGeoAllowed(rules []*model.RelayGeoRule, ip net.IP, country string) (bool, *model.RelayGeoRule) {
+	var firstAllow *model.RelayGeoRule
+	allowed := true
+	for _, r := range rules {
+		switch r.Mode {
+		case model.RelayGeoModeDeny:
+			if geoRuleMatches(r, ip, country) {
+				return false, r
+			}
+		case model.RelayGeoModeAllow:
+			if firstAllow == nil {
+				firstAllow = r
+				allowed = false
+			}
+			if geoRuleMatches(r, ip, country) {
+				allowed = true
+			}
+		}
+	}
+	if !allowed {
+		return false, firstAllow
+	}
+	return true, nil
+}
+
+// ValidateRule 校验规则内容, 返回可翻译的错误
+func (gs *RelayGeoService) ValidateRule(r *model.RelayGeoRule) error {
+	if r.Scope != model.RelayGeoScopeGroup && r.Scope != model.RelayGeoScopePlan {
+		return fmt.Errorf("invalid scope: %s", r.Scope)
+	}
+	if r.Mode != model.RelayGeoModeAllow && r.Mode != model.RelayGeoModeDeny {
+		return fmt.Errorf("invalid mode: %s", r.Mode)
+	}
+	countries := splitGeoList(r.Countries)
+	for i, c := range countries {
+		if len(c) != 2 {
+			return fmt.Errorf("invalid country: %s", c)
+		}
+		countries[i] = strings.ToUpper(c)
+	}
+	r.Countries = strings.Join(countries, ",")
+	cidrs := splitGeoList(r.Cidrs)
+	for _, s := range cidrs {
+		if _, err := parseGeoCidr(s); err != nil {
+			return err
+		}
+	}
+	r.Cidrs = strings.Join(cidrs, "\n")
+	if len(countries) == 0 && len(cidrs) == 0 {
+		return fmt.Errorf("countries or cidrs is required")
+	}
+	return nil
+}
+
+func (gs *RelayGeoService) enabledRules() []*model.RelayGeoRule {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+
+	if gs.rules != nil && time.Since(gs.loadedAt) < relayGeoCacheTTL {
+		return gs.rules
+	}
+	rules := make([]*model.RelayGeoRule, 0)
+	DB.Where("status = ?", model.COMMON_STATUS_ENABLE).Order("id asc").Find(&rules)
+	gs.rules = rules
+	gs.loadedAt = time.Now()
+	return rules
+}
+
+// Reload 使缓存失效, 增删改规则后调用
+func (gs *RelayGeoService) Reload() {
+	gs.mu.Lock()
+	gs.rules = nil
+	gs.mu.Unlock()
+}
+
+// userRules 用户所在用户组和当前有效套餐的规则, 没有任何规则时不查询用户
+func (gs *RelayGeoService) userRules(userId uint) []*model.RelayGeoRule {
+	all := gs.enabledRules()
+	if len(all) == 0 {
+		return nil
+	}
+	var groupId, planId uint
+	hasPlanRules := false
+	for _, r := range all {
+		if r.Scope == model.RelayGeoScopePlan {
+			hasPlanRules = true
+			break
+		}
+	}
+	groupId = AllService.UserService.InfoById(userId).GroupId
+	if hasPlanRules && AllService.Subscription().IsSubscriptionActive(userId) {
+		if sub := AllService.Subscription().EffectiveSubscription(userId); sub.Plan != nil {
+			planId = sub.Plan.Id
+		}
+	}
+	res := make([]*model.RelayGeoRule, 0)
+	for _, r := range all {
+		if (r.Scope == model.RelayGeoScopeGroup && r.ScopeId == groupId && groupId > 0) ||
+			(r.Scope == model.RelayGeoScopePlan && r.ScopeId == planId && planId > 0) {
+			res = append(res, r)
+		}
+	}
+	return res
+}
+
+// Allowed 检查用户从 ip 消费 relay 白名单是否满足地区限制, 拒绝时记录 relay 异常
+// ip 为空或无法解析时不限制(旧版 hbbr 不上报 IP)
+func (gs *RelayGeoService) Allowed(userId uint, uuid, ip string) bool {
+	addr := net.ParseIP(ip)
+	if userId == 0 || addr == nil {
+		return true
+	}
+	rules := gs.userRules(userId)
+	if len(rules) == 0 {
+		return true
+	}
+	loc := AllService.GeoIpService.Locate(ip)
+	ok, rule := relayGeoAllowed(rules, addr, loc.Country)
+	if ok {
+		return true
+	}
+	s := AllService.RelayWhitelistService
+	d := s.abuse()
+	d.mu.Lock()
+	report := d.shouldReport(fmt.Sprintf("geo:%d:%s", userId, ip), time.Now())
+	d.mu.Unlock()
+	if report {
+		now := time.Now().Unix()
+		go s.reportIncident(&model.RelayIncident{
+			Kind:    model.RelayIncidentGeoRestricted,
+			Uuid:    uuid,
+			UserId:  userId,
+			Count:   1,
+			FirstAt: now,
+			LastAt:  now,
+			Detail:  fmt.Sprintf("ip: %s country: %s rule: %d(%s %s:%d)", ip, loc.Country, rule.Id, rule.Mode, rule.Scope, rule.ScopeId),
+		})
+	}
+	return false
+}
+
+func (gs *RelayGeoService) InfoById(id uint) *model.RelayGeoRule {
+	r := &model.RelayGeoRule{}
+	DB.Where("id = ?", id).First(r)
+	return r
+}
+
+func (gs *RelayGeoService) List(page, pageSize uint, where func(tx *gorm.DB)) (res *model.RelayGeoRuleList) {
+	res = &model.RelayGeoRuleList{}
+	res.Page = int64(page)
+	res.PageSize = int64(pageSize)
+	tx := DB.Model(&model.RelayGeoRule{})
+	if where != nil {
+		where(tx)
+	}
+	tx.Count(&res.Total)
+	tx.Scopes(Paginate(page, pageSize))
+	tx.Find(&res.RelayGeoRules)
+	return
+}
+
+func (gs *RelayGeoService) Create(r *model.RelayGeoRule) error {
+	err := DB.Create(r).Error
+	gs.Reload()
+	return err
+}
+
+func (gs *RelayGeoService) Update(r *model.RelayGeoRule) error {
+	err := DB.Model(r).Select("scope", "scope_id", "mode", "countries", "cidrs", "status", "remark").Updates(r).Error
+	gs.Reload()
+	return err
+}
+
+func (gs *RelayGeoService) Delete(r *model.RelayGeoRule) error {
+	err := DB.Delete(r).Error
+	gs.Reload()
+	return err
+}
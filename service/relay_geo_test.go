@@ -0,0 +1,66 @@
+package service
+
+import (
+	"net"
+	"testing"
+
+	"github.com/lejianwen/rustdesk-api/v2/model"
+)
+
+func TestRelayGeoAllowed(t *testing.T) {
+	denyRu := &model.RelayGeoRule{IdModel: model.IdModel{Id: 1}, Mode: model.RelayGeoModeDeny, Countries: "RU"}
+	allowCn := &model.RelayGeoRule{IdModel: model.IdModel{Id: 2}, Mode: model.RelayGeoModeAllow, Countries: "CN,HK"}
+	allowOffice := &model.RelayGeoRule{IdModel: model.IdModel{Id: 3}, Mode: model.RelayGeoModeAllow, Cidrs: "203.0.113.0/24\n198.51.100.7"}
+
+	cases := []struct {
+		name    string
+		rules   []*model.RelayGeoRule
+		ip      string
+		country string
+		allowed bool
+		ruleId  uint
+	}{
+		{"no rules", nil, "1.1.1.1", "US", true, 0},
+		{"deny match", []*model.RelayGeoRule{denyRu}, "5.5.5.5", "RU", false, 1},
+		{"deny miss", []*model.RelayGeoRule{denyRu}, "1.1.1.1", "US", true, 0},
+		{"allow country", []*model.RelayGeoRule{allowCn}, "1.1.1.1", "hk", true, 0},
+		{"allow miss", []*model.RelayGeoRule{allowCn}, "1.1.1.1", "US", false, 2},
+		{"allow unknown country", []*model.RelayGeoRule{allowCn}, "1.1.1.1", "", false, 2},
+		{"allow cidr", []*model.RelayGeoRule{allowCn, allowOffice}, "203.0.113.9", "US", true, 0},
+		{"allow single ip", []*model.RelayGeoRule{allowOffice}, "198.51.100.7", "", true, 0},
+		{"deny wins", []*model.RelayGeoRule{allowCn, denyRu}, "5.5.5.5", "RU", false, 1},
+	}
+	for _, c := range cases {
+		ok, rule := relayGeoAllowed(c.rules, net.ParseIP(c.ip), c.country)
+		if ok != c.allowed {
+			t.Errorf("%s: allowed = %v", c.name, ok)
+			continue
+		}
+		if !ok && rule.Id != c.ruleId {
+			t.Errorf("%s: rule = %d, want %d", c.name, rule.Id, c.ruleId)
+		}
+	}
+}
+
+func TestRelayGeoValidateRule(t *testing.T) {
+	gs := &RelayGeoService{}
+	r := &model.RelayGeoRule{Scope: model.RelayGeoScopeGroup, Mode: model.RelayGeoModeAllow, Countries: "cn, hk", Cidrs: "10.0.0.0/8,192.168.1.1"}
+	if err := gs.ValidateRule(r); err != nil {
+		t.Fatal(err)
+	}
+	if r.Countries != "CN,HK" || r.Cidrs != "10.0.0.0/8\n192.168.1.1" {
+		t.Fatalf("normalized = %q %q", r.Countries, r.Cidrs)
+	}
+	bad := []*model.RelayGeoRule{
+		{Scope: "user", Mode: model.RelayGeoModeAllow, Countries: "CN"},
+		{Scope: model.RelayGeoScopePlan, Mode: "block", Countries: "CN"},
+		{Scope: model.RelayGeoScopePlan, Mode: model.RelayGeoModeDeny, Countries: "China"},
+		{Scope: model.RelayGeoScopePlan, Mode: model.RelayGeoModeDeny, Cidrs: "10.0.0.0/33"},
+		{Scope: model.RelayGeoScopePlan, Mode: model.RelayGeoModeDeny},
+	}
+	for i, r := range bad {
+		if err := gs.ValidateRule(r); err == nil {
+			t.Errorf("case %d should fail", i)
+		}
+	}
+}
@@ -1,20 +1,48 @@
 package service
 
 import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 )
 
+const (
+	defaultRelayWhitelistFile  = "./runtime/relay_whitelist.json"
+	relayWhitelistSaveInterval = time.Second
+)
+
 // RelayWhitelistService 管理 relay uuid 白名单
 // 用于 hbbs 写入允许的 uuid，hbbr 消费验证
+// 启用持久化后, 条目变更时异步快照到文件, 启动时重新加载, 避免 API 重启导致协商中的 relay 被拒绝
 type RelayWhitelistService struct {
 	mu    sync.RWMutex
 	items map[string]*whitelistItem
+
+	file  string // 快照文件, 为空表示不持久化
+	dirty bool   // 自上次快照后是否有变更
+
+	saveErr error // 最近一次快照失败原因, 成功后清空
+
+	abuseOnce sync.Once
+	detector  *relayAbuseDetector // 异常使用检测
+}
+
+// relayWhitelistSnapshot 快照文件中的条目
+type relayWhitelistSnapshot struct {
+	UUID     string `json:"uuid"`
+	Slots    int    `json:"slots"`
+	ExpireAt int64  `json:"expire_at"` // unix 毫秒
+	UserId   uint   `json:"user_id,omitempty"`
 }
 
 type whitelistItem struct {
 	slots    int       // 剩余可用次数
 	expireAt time.Time // 过期时间
+	userId   uint      // 发起用户, 0 表示未知
 }
 
 // NewRelayWhitelistService 创建白名单服务实例
@@ -32,6 +60,11 @@ func NewRelayWhitelistService() *RelayWhitelistService {
 // slots: 允许消费次数 (通常为 2，因为 relay 需要两端各连接一次)
 // ttlSec: 过期时间(秒)
 func (s *RelayWhitelistService) Allow(uuid string, slots int, ttlSec int) {
+	s.AllowFor(uuid, slots, ttlSec, 0)
+}
+
+// AllowFor 写入白名单并记录发起用户, 用于消费时按用户校验 relay 配额
+func (s *RelayWhitelistService) AllowFor(uuid string, slots int, ttlSec int, userId uint) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -45,7 +78,9 @@ func (s *RelayWhitelistService) Allow(uuid string, slots int, ttlSec int) {
 	s.items[uuid] = &whitelistItem{
 		slots:    slots,
 		expireAt: time.Now().Add(time.Duration(ttlSec) * time.Second),
+		userId:   userId,
 	}
+	s.dirty = true
 	Logger.Debugf("RelayWhitelist: allow uuid=%s slots=%d ttl=%ds", uuid, slots, ttlSec)
 }
 
@@ -58,6 +93,7 @@ func (s *RelayWhitelistService) Consume(uuid string) bool {
 	item, exists := s.items[uuid]
 	if !exists {
 		Logger.Debugf("RelayWhitelist: consume uuid=%s not found", uuid)
+		s.recordConsumeFailure(uuid, "not_found")
 		return false
 	}
 
@@ -65,6 +101,7 @@ func (s *RelayWhitelistService) Consume(uuid string) bool {
 	if time.Now().After(item.expireAt) {
 		delete(s.items, uuid)
 		Logger.Debugf("RelayWhitelist: consume uuid=%s expired", uuid)
+		s.recordConsumeFailure(uuid, "expired")
 		return false
 	}
 
@@ -72,11 +109,13 @@ func (s *RelayWhitelistService) Consume(uuid string) bool {
 	if item.slots <= 0 {
 		delete(s.items, uuid)
 		Logger.Debugf("RelayWhitelist: consume uuid=%s no slots left", uuid)
+		s.recordConsumeFailure(uuid, "no_slots")
 		return false
 	}
 
 	// 扣减次数
 	item.slots--
+	s.dirty = true
 	Logger.Debugf("RelayWhitelist: consume uuid=%s success, remaining=%d", uuid, item.slots)
 
 	// 如果次数用完，删除条目
@@ -87,6 +126,17 @@ func (s *RelayWhitelistService) Consume(uuid string) bool {
 	return true
 }
 
+// Owner 返回条目的发起用户, 不存在或未知时返回 0
+func (s *RelayWhitelistService) Owner(uuid string) uint {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if item, exists := s.items[uuid]; exists {
+		return item.userId
+	}
+	return 0
+}
+
 // Check 检查 uuid 是否在白名单中（不消费）
 func (s *RelayWhitelistService) Check(uuid string) bool {
 	s.mu.RLock()
@@ -111,6 +161,7 @@ func (s *RelayWhitelistService) cleanupLoop() {
 
 	for range ticker.C {
 		s.cleanup()
+		s.cleanupAbuse()
 	}
 }
 
@@ -122,8 +173,199 @@ func (s *RelayWhitelistService) cleanup() {
 	for uuid, item := range s.items {
 		if now.After(item.expireAt) || item.slots <= 0 {
 			delete(s.items, uuid)
+			s.dirty = true
+		}
+	}
+}
+
+// StartPersistence 从快照文件恢复白名单并启动定时快照, file 为空时使用默认路径
+func (s *RelayWhitelistService) StartPersistence(file string) {
+	if file == "" {
+		file = defaultRelayWhitelistFile
+	}
+	s.mu.Lock()
+	s.file = file
+	s.mu.Unlock()
+
+	n, err := s.Load()
+	if err != nil {
+		Logger.Warn("RelayWhitelist: load snapshot failed: ", err)
+	} else if n > 0 {
+		Logger.Info("RelayWhitelist: restored ", n, " entries from ", file)
+	}
+
+	go func() {
+		ticker := time.NewTicker(relayWhitelistSaveInterval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			if err := s.Save(false); err != nil {
+				Logger.Warn("RelayWhitelist: save snapshot failed: ", err)
+			}
+		}
+	}()
+}
+
+// Load 从快照文件加载未过期的条目(已存在的 uuid 不覆盖), 返回加载数量
+func (s *RelayWhitelistService) Load() (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.file == "" {
+		return 0, nil
+	}
+	data, err := os.ReadFile(s.file)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	var entries []relayWhitelistSnapshot
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return 0, err
+	}
+	now := time.Now()
+	n := 0
+	for _, e := range entries {
+		expireAt := time.UnixMilli(e.ExpireAt)
+		if e.UUID == "" || e.Slots <= 0 || !expireAt.After(now) {
+			continue
+		}
+		if _, exists := s.items[e.UUID]; exists {
+			continue
+		}
+		s.items[e.UUID] = &whitelistItem{slots: e.Slots, expireAt: expireAt, userId: e.UserId}
+		n++
+	}
+	return n, nil
+}
+
+// Save 将当前条目快照到文件(先写临时文件再重命名), force 为 false 时仅在有变更时写入
+// 在关闭服务时应以 force=true 调用一次
+func (s *RelayWhitelistService) Save(force bool) error {
+	s.mu.Lock()
+	if s.file == "" || (!force && !s.dirty) {
+		s.mu.Unlock()
+		return nil
+	}
+	now := time.Now()
+	entries := make([]relayWhitelistSnapshot, 0, len(s.items))
+	for uuid, item := range s.items {
+		if item.slots <= 0 || now.After(item.expireAt) {
+			continue
+		}
+		entries = append(entries, relayWhitelistSnapshot{UUID: uuid, Slots: item.slots, ExpireAt: item.expireAt.UnixMilli(), UserId: item.userId})
+	}
+	file := s.file
+	s.dirty = false
+	s.mu.Unlock()
+
+	data, err := json.Marshal(entries)
+	if err == nil {
+		err = writeFileAtomic(file, data)
+	}
+	s.mu.Lock()
+	s.saveErr = err
+	if err != nil {
+		s.dirty = true
+	}
+	s.mu.Unlock()
+	return err
+}
+
+// PersistenceStatus 返回快照文件和最近一次快照失败原因, 未启用持久化时均为空
+func (s *RelayWhitelistService) PersistenceStatus() (string, string) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.saveErr != nil {
+		return s.file, s.saveErr.Error()
+	}
+	return s.file, ""
+}
+
+func writeFileAtomic(file string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(file), filepath.Base(file)+".tmp*")
+	if err != nil {
+		return err
+	}
+	if _, err = tmp.Write(data); err == nil {
+		err = tmp.Sync()
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), file)
+}
+
+// RelayWhitelistEntry 白名单条目(管理接口展示)
+type RelayWhitelistEntry struct {
+	UUID     string `json:"uuid"`
+	Slots    int    `json:"slots"`     // 剩余可用次数
+	ExpireAt int64  `json:"expire_at"` // 过期时间(秒)
+	TTL      int64  `json:"ttl"`       // 剩余秒数
+}
+
+// List 返回未过期的条目, 按过期时间升序; keyword 非空时按 uuid 包含匹配
+func (s *RelayWhitelistService) List(keyword string) []*RelayWhitelistEntry {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	now := time.Now()
+	res := make([]*RelayWhitelistEntry, 0, len(s.items))
+	for uuid, item := range s.items {
+		if item.slots <= 0 || now.After(item.expireAt) {
+			continue
+		}
+		if keyword != "" && !strings.Contains(uuid, keyword) {
+			continue
 		}
+		res = append(res, &RelayWhitelistEntry{
+			UUID:     uuid,
+			Slots:    item.slots,
+			ExpireAt: item.expireAt.Unix(),
+			TTL:      int64(item.expireAt.Sub(now).Seconds()),
+		})
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].ExpireAt == res[j].ExpireAt {
+			return res[i].UUID < res[j].UUID
+		}
+		return res[i].ExpireAt < res[j].ExpireAt
+	})
+	return res
+}
+
+// Remove 删除指定条目, 返回是否存在
+func (s *RelayWhitelistService) Remove(uuid string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.items[uuid]; !exists {
+		return false
 	}
+	delete(s.items, uuid)
+	s.dirty = true
+	return true
+}
+
+// Flush 清空全部条目, 返回清除数量
+func (s *RelayWhitelistService) Flush() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := len(s.items)
+	s.items = make(map[string]*whitelistItem)
+	s.dirty = true
+	return n
 }
 
 // Stats 返回当前白名单统计信息
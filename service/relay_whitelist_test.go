@@ -0,0 +1,33 @@
+package service
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestRelayWhitelistSnapshot(t *testing.T) {
+	Logger = logrus.New()
+	file := filepath.Join(t.TempDir(), "relay_whitelist.json")
+
+	s := &RelayWhitelistService{items: make(map[string]*whitelistItem), file: file}
+	s.Allow("a", 2, 60)
+	s.Allow("b", 1, 60)
+	s.Consume("b")
+	if err := s.Save(false); err != nil {
+		t.Fatal(err)
+	}
+
+	restored := &RelayWhitelistService{items: make(map[string]*whitelistItem), file: file}
+	n, err := restored.Load()
+	if err != nil || n != 1 {
+		t.Fatalf("Load = %d, %v, want 1 entry", n, err)
+	}
+	if !restored.Consume("a") || !restored.Consume("a") || restored.Consume("a") {
+		t.Errorf("restored entry should allow exactly 2 consumes")
+	}
+	if restored.Check("b") {
+		t.Errorf("fully consumed entry should not be restored")
+	}
+}
@@ -0,0 +1,224 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lejianwen/rustdesk-api/v2/model"
+)
+
+// ReminderService 订阅到期提醒和到期未续费的催缴提醒, 通过邮件、站内通知和 webhook 发送
+type ReminderService struct {
+}
+
+// 提醒设置, 可在管理后台设置中调整; 模板支持 {username} {plan} {expire_at} {days} 占位符
+const (
+	SettingReminderBeforeDays      = "reminder.before_days"
+	SettingReminderAfterDays       = "reminder.after_days"
+	SettingReminderExpiringSubject = "reminder.expiring.subject"
+	SettingReminderExpiringBody    = "reminder.expiring.body"
+	SettingReminderLapsedSubject   = "reminder.lapsed.subject"
+	SettingReminderLapsedBody      = "reminder.lapsed.body"
+)
+
+const (
+	reminderInterval = time.Hour
+	reminderMaxDays  = 365
+)
+
+// registerReminderSettings 注册提醒设置, 默认到期前 7/3/1 天和到期后 1/3/7 天各提醒一次
+func (s *SystemSettingService) registerReminderSettings() {
+	validDays := func(v interface{}) error {
+		_, err := parseReminderDays(v.(string))
+		return err
+	}
+	s.RegisterSetting(&model.SettingDefinition{
+		Key:         SettingReminderBeforeDays,
+		Type:        model.SettingTypeString,
+		Group:       "reminder",
+		Description: "Days before expiry to send reminders, comma separated, empty disables",
+		Default:     "7,3,1",
+		Validate:    validDays,
+	})
+	s.RegisterSetting(&model.SettingDefinition{
+		Key:         SettingReminderAfterDays,
+		Type:        model.SettingTypeString,
+		Group:       "reminder",
+		Description: "Days after expiry to remind users who have not renewed, comma separated, empty disables",
+		Default:     "1,3,7",
+		Validate:    validDays,
+	})
+	s.RegisterSetting(&model.SettingDefinition{
+		Key:         SettingReminderExpiringSubject,
+		Type:        model.SettingTypeString,
+		Group:       "reminder",
+		Description: "Subject of the expiry reminder",
+		Default:     "Your subscription expires in {days} day(s)",
+	})
+	s.RegisterSetting(&model.SettingDefinition{
+		Key:         SettingReminderExpiringBody,
+		Type:        model.SettingTypeString,
+		Group:       "reminder",
+		Description: "Body of the expiry reminder",
+		Default:     "Your subscription {plan} will expire at {expire_at}.\n\nRenew before then to keep your service uninterrupted.\n",
+	})
+	s.RegisterSetting(&model.SettingDefinition{
+		Key:         SettingReminderLapsedSubject,
+		Type:        model.SettingTypeString,
+		Group:       "reminder",
+		Description: "Subject of the reminder sent after expiry without renewal",
+		Default:     "Your subscription expired {days} day(s) ago",
+	})
+	s.RegisterSetting(&model.SettingDefinition{
+		Key:         SettingReminderLapsedBody,
+		Type:        model.SettingTypeString,
+		Group:       "reminder",
+		Description: "Body of the reminder sent after expiry without renewal",
+		Default:     "Your subscription {plan} expired at {expire_at} and has not been renewed.\n\nRenew now to restore full access.\n",
+	})
+}
+
+// parseReminderDays 解析逗号分隔的提醒天数, 去重并按从大到小排序
+func parseReminderDays(s string) ([]int, error) {
+	days := make([]int, 0)
+	seen := map[int]bool{}
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		d, err := strconv.Atoi(part)
+		if err != nil || d <= 0 || d > reminderMaxDays {
+			return nil, errors.New("invalid reminder day: " + part)
+		}
+		if !seen[d] {
+			seen[d] = true
+			days = append(days, d)
+		}
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(days)))
+	return days, nil
+}
+
+// renderReminder 替换模板中的占位符
+func renderReminder(tpl string, vars map[string]string) string {
+	pairs := make([]string, 0, len(vars)*2)
+	for k, v := range vars {
+		pairs = append(pairs, "{"+k+"}", v)
+	}
+	return strings.NewReplacer(pairs...).Replace(tpl)
+}
+
+func (rs *ReminderService) days(key string) []int {
+	days, _ := parseReminderDays(AllService.SystemSettingService.SettingString(key))
+	return days
+}
+
+// OptedOut 用户是否退订了订阅提醒
+func (rs *ReminderService) OptedOut(userId uint) bool {
+	var n int64
+	DB.Model(&model.ReminderOptOut{}).Where("user_id = ?", userId).Count(&n)
+	return n > 0
+}
+
+// SetOptOut 设置用户是否退订订阅提醒
+func (rs *ReminderService) SetOptOut(userId uint, optOut bool) error {
+	if !optOut {
+		return DB.Where("user_id = ?", userId).Delete(&model.ReminderOptOut{}).Error
+	}
+	if rs.OptedOut(userId) {
+		return nil
+	}
+	return DB.Create(&model.ReminderOptOut{UserId: userId}).Error
+}
+
+// SendReminders 发送到期前提醒和到期后催缴提醒, 返回本次发送的数量
+func (rs *ReminderService) SendReminders() int {
+	n := 0
+	for _, days := range rs.days(SettingReminderBeforeDays) {
+		for _, sub := range AllService.SubscriptionService.ExpiringSubscriptions(days) {
+			if rs.send(sub, model.ReminderKindExpiring, days) {
+				n++
+			}
+		}
+	}
+	for _, days := range rs.days(SettingReminderAfterDays) {
+		for _, sub := range AllService.SubscriptionService.LapsedSubscriptions(days) {
+			if rs.send(sub, model.ReminderKindLapsed, days) {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+// send 记录并发送一条提醒, 已发送过或用户已退订时返回 false
+func (rs *ReminderService) send(sub *model.UserSubscription, kind string, days int) bool {
+	if rs.OptedOut(sub.UserId) {
+		return false
+	}
+	prefix := "expiry"
+	if kind == model.ReminderKindLapsed {
+		prefix = "lapsed"
+	}
+	refKey := fmt.Sprintf("%s:%d:%d:%d", prefix, sub.Id, sub.ExpireAt, days)
+	r := &model.SubscriptionReminder{
+		UserId:         sub.UserId,
+		SubscriptionId: sub.Id,
+		Kind:           kind,
+		Days:           days,
+		ExpireAt:       sub.ExpireAt,
+		RefKey:         refKey,
+	}
+	var exists int64
+	DB.Model(&model.SubscriptionReminder{}).Where("ref_key = ?", refKey).Count(&exists)
+	if exists > 0 {
+		return false
+	}
+	if err := DB.Create(r).Error; err != nil {
+		// 唯一索引冲突说明已由其他实例发送
+		return false
+	}
+
+	planName := ""
+	if sub.Plan != nil {
+		planName = sub.Plan.Name
+	}
+	vars := map[string]string{
+		"username":  AllService.UserService.InfoById(sub.UserId).Username,
+		"plan":      planName,
+		"expire_at": formatEmailTime(sub.ExpireAt),
+		"days":      strconv.Itoa(days),
+	}
+	settings := AllService.SystemSettingService
+	if kind == model.ReminderKindLapsed {
+		subject := renderReminder(settings.SettingString(SettingReminderLapsedSubject), vars)
+		body := renderReminder(settings.SettingString(SettingReminderLapsedBody), vars)
+		AllService.EmailService.enqueue(sub.UserId, model.EmailKindLapsedReminder, refKey, subject, body)
+		AllService.NotificationService.Notify(sub.UserId, model.NotificationKindSubscriptionLapsed, refKey, subject, strings.TrimSpace(body))
+		AllService.EventBus.Publish(SubscriptionLapsedEvent{Subscription: sub, Days: days})
+		return true
+	}
+	subject := renderReminder(settings.SettingString(SettingReminderExpiringSubject), vars)
+	body := renderReminder(settings.SettingString(SettingReminderExpiringBody), vars)
+	AllService.EmailService.enqueue(sub.UserId, model.EmailKindExpiryReminder, refKey, subject, body)
+	AllService.NotificationService.Notify(sub.UserId, model.NotificationKindSubscriptionExpiring, refKey, subject, strings.TrimSpace(body))
+	AllService.EventBus.Publish(SubscriptionExpiringEvent{Subscription: sub, Days: days})
+	return true
+}
+
+// StartReminderJob 启动订阅提醒任务
+func (rs *ReminderService) StartReminderJob() {
+	go func() {
+		ticker := time.NewTicker(reminderInterval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			rs.SendReminders()
+		}
+	}()
+}
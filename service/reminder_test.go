@@ -0,0 +1,32 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseReminderDays(t *testing.T) {
+	days, err := parseReminderDays(" 1, 7,3,7 ")
+	if err != nil || !reflect.DeepEqual(days, []int{7, 3, 1}) {
+		t.Fatalf("got %v, %v", days, err)
+	}
+	if days, err := parseReminderDays(""); err != nil || len(days) != 0 {
+		t.Fatalf("empty: got %v, %v", days, err)
+	}
+	for _, bad := range []string{"0", "-1", "x", "400"} {
+		if _, err := parseReminderDays(bad); err == nil {
+			t.Errorf("%q: expected error", bad)
+		}
+	}
+}
+
+func TestRenderReminder(t *testing.T) {
+	got := renderReminder("Hi {username}, {plan} expires in {days} day(s) {unknown}", map[string]string{
+		"username": "alice",
+		"plan":     "Pro",
+		"days":     "3",
+	})
+	if want := "Hi alice, Pro expires in 3 day(s) {unknown}"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
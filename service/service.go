@@ -4,6 +4,7 @@ import (
 	"github.com/lejianwen/rustdesk-api/v2/config"
 	"github.com/lejianwen/rustdesk-api/v2/lib/jwt"
 	"github.com/lejianwen/rustdesk-api/v2/lib/lock"
+	"github.com/lejianwen/rustdesk-api/v2/lib/publicid"
 	"github.com/lejianwen/rustdesk-api/v2/model"
 	log "github.com/sirupsen/logrus"
 	"gorm.io/gorm"
@@ -28,6 +29,37 @@ type Service struct {
 	*SubscriptionService
 	*SystemSettingService
 	*RelayWhitelistService
+	*ConnPermissionService
+	*RedeemCodeService
+	*AdminLogService
+	*WebhookService
+	*EmailService
+	*StatusService
+	*RelaySessionService
+	*EntitlementService
+	*InternalKeyService
+	*EventBus
+	*MaintenanceService
+	*PaymentProofService
+	*StorageService
+	*NotificationService
+	*EmailVerificationService
+	*LoadShedService
+	*ReferralService
+	*ReminderService
+	*PushService
+	*GeoIpService
+	*RelayGeoService
+	*PeerBanService
+	*StrategyService
+	*RecordingService
+	*ApiTokenService
+
+	// 通过 Option 替换的实现, 为空时使用上面的默认实现
+	payment        PaymentProvider
+	subscription   SubscriptionProvider
+	relayWhitelist RelayWhitelistStore
+	publicIds      publicid.Codec
 }
 
 type Dependencies struct {
@@ -46,19 +78,103 @@ var Lock lock.Locker
 
 var AllService *Service
 
-func New(c *config.Config, g *gorm.DB, l *log.Logger, j *jwt.Jwt, lo lock.Locker) *Service {
-	Config = c
-	DB = g
-	Logger = l
-	Jwt = j
-	Lock = lo
-	AllService = &Service{
+// Option 替换 NewService 创建的默认实现, 用于测试注入 mock 或切换实现
+type Option func(s *Service)
+
+// WithPayment 替换支付网关实现
+func WithPayment(p PaymentProvider) Option {
+	return func(s *Service) { s.payment = p }
+}
+
+// WithSubscription 替换订阅查询实现
+func WithSubscription(p SubscriptionProvider) Option {
+	return func(s *Service) { s.subscription = p }
+}
+
+// WithPublicIds 替换对外标识编码
+func WithPublicIds(c publicid.Codec) Option {
+	return func(s *Service) { s.publicIds = c }
+}
+
+// WithRelayWhitelist 替换 relay 白名单存储
+func WithRelayWhitelist(p RelayWhitelistStore) Option {
+	return func(s *Service) { s.relayWhitelist = p }
+}
+
+// NewService 根据依赖创建服务容器, 并设置包级依赖和 AllService 以兼容现有调用
+func NewService(deps *Dependencies, opts ...Option) *Service {
+	Config = deps.Config
+	DB = deps.DB
+	Logger = deps.Logger
+	Jwt = deps.Jwt
+	if deps.Lock != nil {
+		Lock = *deps.Lock
+	}
+	s := &Service{
 		SystemSettingService: &SystemSettingService{
 			cache: make(map[string]*cacheItem),
 		},
 		RelayWhitelistService: NewRelayWhitelistService(),
+		StatusService:         &StatusService{},
+		RelaySessionService:   &RelaySessionService{},
+		EntitlementService:    &EntitlementService{},
+		InternalKeyService:    &InternalKeyService{},
+		EventBus:              NewEventBus(),
+		MaintenanceService:    &MaintenanceService{},
+		StorageService:        &StorageService{},
+		LoadShedService:       &LoadShedService{},
+		PushService:           &PushService{},
+		GeoIpService:          &GeoIpService{},
+		RelayGeoService:       &RelayGeoService{},
+		PeerBanService:        &PeerBanService{},
+		StrategyService:       &StrategyService{},
+		RecordingService:      &RecordingService{},
+		ApiTokenService:       &ApiTokenService{},
+	}
+	for _, opt := range opts {
+		opt(s)
 	}
-	return AllService
+	if s.publicIds == nil {
+		s.publicIds = newPublicIds()
+	}
+	s.SystemSettingService.registerBuiltinSettings()
+	s.subscribeEvents()
+	AllService = s
+	return s
+}
+
+// New 兼容旧的创建方式
+func New(c *config.Config, g *gorm.DB, l *log.Logger, j *jwt.Jwt, lo lock.Locker) *Service {
+	return NewService(&Dependencies{Config: c, DB: g, Logger: l, Jwt: j, Lock: &lo})
+}
+
+// Payment 支付网关, 未替换时为 PaymentService
+func (s *Service) Payment() PaymentProvider {
+	if s.payment != nil {
+		return s.payment
+	}
+	return s.PaymentService
+}
+
+// Subscription 订阅查询, 未替换时为 SubscriptionService
+func (s *Service) Subscription() SubscriptionProvider {
+	if s.subscription != nil {
+		return s.subscription
+	}
+	return s.SubscriptionService
+}
+
+// PublicIds 订单、套餐和订阅的对外标识编码, 未替换时按配置 app.public-id 创建
+func (s *Service) PublicIds() publicid.Codec {
+	return s.publicIds
+}
+
+// RelayWhitelist relay 白名单存储, 未替换时为 RelayWhitelistService
+func (s *Service) RelayWhitelist() RelayWhitelistStore {
+	if s.relayWhitelist != nil {
+		return s.relayWhitelist
+	}
+	return s.RelayWhitelistService
 }
 
 func Paginate(page, pageSize uint) func(db *gorm.DB) *gorm.DB {
@@ -74,6 +190,36 @@ func Paginate(page, pageSize uint) func(db *gorm.DB) *gorm.DB {
 	}
 }
 
+// exportBatchSize 导出时每批查询的行数
+const exportBatchSize = 500
+
+// findDescInBatches 按主键倒序分批查询, 每批回调一次
+// FindInBatches 按 id > 上一批最后一行翻页, 只适用于正序, 倒序时超过一批会重复和漏行, 这里按 id < 上一批最后一行翻页
+func findDescInBatches[T any](tx *gorm.DB, size int, idOf func(*T) uint, fn func([]*T) error) error {
+	base := tx.Session(&gorm.Session{})
+	var last uint
+	for {
+		batch := make([]*T, 0, size)
+		q := base
+		if last > 0 {
+			q = q.Where("id < ?", last)
+		}
+		if err := q.Order("id DESC").Limit(size).Find(&batch).Error; err != nil {
+			return err
+		}
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := fn(batch); err != nil {
+			return err
+		}
+		if len(batch) < size {
+			return nil
+		}
+		last = idOf(batch[len(batch)-1])
+	}
+}
+
 func CommonEnable() func(db *gorm.DB) *gorm.DB {
 	return func(db *gorm.DB) *gorm.DB {
 		return db.Where("status = ?", model.COMMON_STATUS_ENABLE)
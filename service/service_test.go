@@ -0,0 +1,80 @@
+package service
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"github.com/lejianwen/rustdesk-api/v2/config"
+	"github.com/lejianwen/rustdesk-api/v2/model"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// newTestService 使用临时 sqlite 库创建服务容器, 测试结束后恢复 AllService 和 DB
+func newTestService(t *testing.T, models []interface{}, opts ...Option) *Service {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{
+		DisableForeignKeyConstraintWhenMigrating: true,
+		Logger:                                   logger.Discard,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(models...); err != nil {
+		t.Fatal(err)
+	}
+	oldService, oldDB, oldConfig, oldLogger := AllService, DB, Config, Logger
+	t.Cleanup(func() { AllService, DB, Config, Logger = oldService, oldDB, oldConfig, oldLogger })
+	return NewService(&Dependencies{Config: &config.Config{}, DB: db, Logger: logrus.New()}, opts...)
+}
+
+// disabledPayment 支付未启用的 mock
+type disabledPayment struct {
+	PaymentProvider
+}
+
+func (disabledPayment) IsEnabled() bool { return false }
+
+// enabledPayment 支付已启用的 mock
+type enabledPayment struct {
+	PaymentProvider
+}
+
+func (enabledPayment) IsEnabled() bool { return true }
+
+// planSubscription 所有用户都订阅了同一套餐的 mock
+type planSubscription struct {
+	SubscriptionProvider
+	plan *model.SubscriptionPlan
+}
+
+func (s planSubscription) IsSubscriptionActive(userId uint) bool { return true }
+
+func (s planSubscription) EffectiveSubscription(userId uint) *model.UserSubscription {
+	return &model.UserSubscription{UserId: userId, PlanId: s.plan.Id, Plan: s.plan}
+}
+
+func TestNewServiceWithOptions(t *testing.T) {
+	old := AllService
+	defer func() { AllService = old }()
+
+	s := NewService(&Dependencies{Logger: logrus.New()}, WithPayment(disabledPayment{}))
+	if AllService != s {
+		t.Fatal("NewService should set AllService")
+	}
+	if s.Payment().IsEnabled() {
+		t.Error("Payment() should return the injected provider")
+	}
+	if _, ok := s.RelayWhitelist().(*RelayWhitelistService); !ok {
+		t.Error("RelayWhitelist() should default to RelayWhitelistService")
+	}
+	// 支付未启用时不查询数据库, 直接返回无限额
+	if list := s.EntitlementService.List(1); len(list) != 0 {
+		t.Errorf("entitlements = %v, want none when payment disabled", list)
+	}
+	if s.RelaySessionService.QuotaExceeded(1) {
+		t.Error("quota should not be enforced when payment disabled")
+	}
+}
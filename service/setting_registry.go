@@ -0,0 +1,288 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/lejianwen/rustdesk-api/v2/config"
+	"github.com/lejianwen/rustdesk-api/v2/model"
+	"github.com/lejianwen/rustdesk-api/v2/utils"
+)
+
+// 内置的通用设置
+const (
+	SettingRequireVerifiedEmail = "payment.require_verified_email"
+	SettingManualInstructions   = "payment.manual.instructions"
+)
+
+// registerBuiltinSettings 注册内置设置, 默认值取自配置文件
+func (s *SystemSettingService) registerBuiltinSettings() {
+	cfg := Config
+	if cfg == nil {
+		cfg = &config.Config{}
+	}
+	s.RegisterSetting(&model.SettingDefinition{
+		Key:         SettingRequireVerifiedEmail,
+		Type:        model.SettingTypeBool,
+		Group:       "payment",
+		Description: "Require a verified email address before purchase",
+		Default:     cfg.Payment.RequireVerifiedEmail,
+	})
+	s.RegisterSetting(&model.SettingDefinition{
+		Key:         SettingManualInstructions,
+		Type:        model.SettingTypeString,
+		Group:       "payment",
+		Description: "Bank transfer instructions shown for manual orders",
+		Default:     cfg.Payment.Manual.Instructions,
+	})
+	s.registerExtensionGuardSettings(cfg.Payment.ExtensionGuard)
+	s.registerOrderLimitSettings(cfg.Payment.OrderLimit)
+	s.registerReferralSettings()
+	s.registerReminderSettings()
+	s.registerPauseSettings()
+	s.registerDeviceLimitSettings()
+	s.registerRecordingSettings()
+}
+
+// RegisterSetting 注册设置定义, key 重复时 panic
+func (s *SystemSettingService) RegisterSetting(def *model.SettingDefinition) {
+	s.defsLock.Lock()
+	defer s.defsLock.Unlock()
+	if s.defs == nil {
+		s.defs = make(map[string]*model.SettingDefinition)
+	}
+	if _, ok := s.defs[def.Key]; ok {
+		panic("setting: duplicate definition " + def.Key)
+	}
+	if _, ok := secretSettingFields[def.Key]; ok {
+		panic("setting: " + def.Key + " is reserved")
+	}
+	s.defs[def.Key] = def
+}
+
+// Definition 获取设置定义, 未注册时返回 nil
+func (s *SystemSettingService) Definition(key string) *model.SettingDefinition {
+	s.defsLock.RLock()
+	defer s.defsLock.RUnlock()
+	return s.defs[key]
+}
+
+// Definitions 全部设置定义, 按 key 排序
+func (s *SystemSettingService) Definitions() []*model.SettingDefinition {
+	s.defsLock.RLock()
+	defer s.defsLock.RUnlock()
+	res := make([]*model.SettingDefinition, 0, len(s.defs))
+	for _, d := range s.defs {
+		res = append(res, d)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Key < res[j].Key })
+	return res
+}
+
+// Setting 获取设置的当前值, 未保存或保存的值无效时返回默认值
+func (s *SystemSettingService) Setting(key string) (value interface{}, isDefault bool) {
+	def := s.Definition(key)
+	if def == nil {
+		return nil, true
+	}
+	raw := s.Get(key)
+	if raw == "" {
+		return def.Default, true
+	}
+	var v interface{}
+	if err := json.Unmarshal([]byte(raw), &v); err != nil {
+		Logger.Error("Parse setting failed, key: ", key, " err: ", err)
+		return def.Default, true
+	}
+	cv, err := coerceSetting(def, v)
+	if err != nil {
+		Logger.Error("Invalid setting value, key: ", key, " err: ", err)
+		return def.Default, true
+	}
+	return cv, false
+}
+
+// SettingBool 布尔设置
+func (s *SystemSettingService) SettingBool(key string) bool {
+	v, _ := s.Setting(key)
+	b, _ := v.(bool)
+	return b
+}
+
+// SettingString 字符串设置
+func (s *SystemSettingService) SettingString(key string) string {
+	v, _ := s.Setting(key)
+	str, _ := v.(string)
+	return str
+}
+
+// SettingInt 整数设置
+func (s *SystemSettingService) SettingInt(key string) int64 {
+	v, _ := s.Setting(key)
+	i, _ := v.(int64)
+	return i
+}
+
+// SettingDuration 时长设置
+func (s *SystemSettingService) SettingDuration(key string) time.Duration {
+	v, _ := s.Setting(key)
+	switch d := v.(type) {
+	case time.Duration:
+		return d
+	case string:
+		dd, _ := time.ParseDuration(d)
+		return dd
+	}
+	return 0
+}
+
+// SetSetting 校验并保存设置, value 一般来自 JSON 请求
+func (s *SystemSettingService) SetSetting(key string, value interface{}) error {
+	def := s.Definition(key)
+	if def == nil {
+		return errors.New("SettingNotFound")
+	}
+	v, err := coerceSetting(def, value)
+	if err != nil {
+		return err
+	}
+	if def.Validate != nil {
+		if err := def.Validate(v); err != nil {
+			return err
+		}
+	}
+	if d, ok := v.(time.Duration); ok {
+		v = d.String()
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return s.Set(key, string(data))
+}
+
+// ResetSetting 删除已保存的值, 恢复默认值
+func (s *SystemSettingService) ResetSetting(key string) error {
+	if s.Definition(key) == nil {
+		return errors.New("SettingNotFound")
+	}
+	return s.Delete(key)
+}
+
+// ListSettings 全部设置及当前值, group 非空时只返回该分组; 密钥类设置的值被遮蔽
+func (s *SystemSettingService) ListSettings(group string) []*model.SettingItem {
+	defs := s.Definitions()
+	res := make([]*model.SettingItem, 0, len(defs))
+	for _, def := range defs {
+		if group != "" && def.Group != group {
+			continue
+		}
+		res = append(res, s.settingItem(def))
+	}
+	return res
+}
+
+// SettingItem 单个设置及当前值, 未注册时返回 nil
+func (s *SystemSettingService) SettingItem(key string) *model.SettingItem {
+	def := s.Definition(key)
+	if def == nil {
+		return nil
+	}
+	return s.settingItem(def)
+}
+
+func (s *SystemSettingService) settingItem(def *model.SettingDefinition) *model.SettingItem {
+	v, isDefault := s.Setting(def.Key)
+	if d, ok := v.(time.Duration); ok {
+		v = d.String()
+	}
+	if str, ok := v.(string); ok && def.Secret {
+		v = utils.MaskString(str)
+	}
+	return &model.SettingItem{SettingDefinition: def, Value: v, IsDefault: isDefault}
+}
+
+// coerceSetting 将 JSON 解析出的值转换为定义的类型并校验可选值和范围
+// 转换后: string -> string, int -> int64, float -> float64, bool -> bool, duration -> time.Duration
+func coerceSetting(def *model.SettingDefinition, value interface{}) (interface{}, error) {
+	invalid := fmt.Errorf("SettingValueInvalid")
+	var num float64
+	var res interface{}
+	switch def.Type {
+	case model.SettingTypeString:
+		str, ok := value.(string)
+		if !ok {
+			return nil, invalid
+		}
+		if len(def.Options) > 0 {
+			found := false
+			for _, o := range def.Options {
+				if o == str {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return nil, invalid
+			}
+		}
+		return str, nil
+	case model.SettingTypeBool:
+		switch b := value.(type) {
+		case bool:
+			return b, nil
+		case string:
+			pb, err := strconv.ParseBool(b)
+			if err != nil {
+				return nil, invalid
+			}
+			return pb, nil
+		}
+		return nil, invalid
+	case model.SettingTypeInt, model.SettingTypeFloat:
+		switch n := value.(type) {
+		case float64:
+			num = n
+		case int:
+			num = float64(n)
+		case int64:
+			num = float64(n)
+		case string:
+			f, err := strconv.ParseFloat(n, 64)
+			if err != nil {
+				return nil, invalid
+			}
+			num = f
+		default:
+			return nil, invalid
+		}
+		if def.Type == model.SettingTypeInt {
+			if num != float64(int64(num)) {
+				return nil, invalid
+			}
+			res = int64(num)
+		} else {
+			res = num
+		}
+	case model.SettingTypeDuration:
+		str, ok := value.(string)
+		if !ok {
+			return nil, invalid
+		}
+		d, err := time.ParseDuration(str)
+		if err != nil {
+			return nil, invalid
+		}
+		num, res = d.Seconds(), d
+	default:
+		return nil, invalid
+	}
+	if (def.Min != nil && num < *def.Min) || (def.Max != nil && num > *def.Max) {
+		return nil, invalid
+	}
+	return res, nil
+}
@@ -0,0 +1,43 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lejianwen/rustdesk-api/v2/model"
+)
+
+func TestCoerceSetting(t *testing.T) {
+	one, ten := 1.0, 10.0
+	cases := []struct {
+		def   *model.SettingDefinition
+		in    interface{}
+		want  interface{}
+		valid bool
+	}{
+		{&model.SettingDefinition{Type: model.SettingTypeBool}, true, true, true},
+		{&model.SettingDefinition{Type: model.SettingTypeBool}, "false", false, true},
+		{&model.SettingDefinition{Type: model.SettingTypeBool}, 1.0, nil, false},
+		{&model.SettingDefinition{Type: model.SettingTypeInt, Min: &one, Max: &ten}, 5.0, int64(5), true},
+		{&model.SettingDefinition{Type: model.SettingTypeInt, Min: &one, Max: &ten}, "7", int64(7), true},
+		{&model.SettingDefinition{Type: model.SettingTypeInt}, 1.5, nil, false},
+		{&model.SettingDefinition{Type: model.SettingTypeInt, Min: &one, Max: &ten}, 11.0, nil, false},
+		{&model.SettingDefinition{Type: model.SettingTypeFloat, Max: &one}, 0.5, 0.5, true},
+		{&model.SettingDefinition{Type: model.SettingTypeString, Options: []string{"a", "b"}}, "b", "b", true},
+		{&model.SettingDefinition{Type: model.SettingTypeString, Options: []string{"a", "b"}}, "c", nil, false},
+		{&model.SettingDefinition{Type: model.SettingTypeString}, 3.0, nil, false},
+		{&model.SettingDefinition{Type: model.SettingTypeDuration}, "90m", 90 * time.Minute, true},
+		{&model.SettingDefinition{Type: model.SettingTypeDuration, Max: &ten}, "1m", nil, false},
+		{&model.SettingDefinition{Type: "unknown"}, "x", nil, false},
+	}
+	for i, c := range cases {
+		got, err := coerceSetting(c.def, c.in)
+		if (err == nil) != c.valid {
+			t.Errorf("case %d: err = %v, want valid %v", i, err, c.valid)
+			continue
+		}
+		if c.valid && got != c.want {
+			t.Errorf("case %d: got %#v, want %#v", i, got, c.want)
+		}
+	}
+}
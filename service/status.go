@@ -0,0 +1,216 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"runtime"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/lejianwen/rustdesk-api/v2/model"
+)
+
+// 组件状态
+const (
+	StatusOperational = "operational"
+	StatusDegraded    = "degraded"
+	StatusDown        = "down"
+	StatusDisabled    = "disabled"
+)
+
+const (
+	statusCacheTTL     = 15 * time.Second
+	statusProbeTimeout = 5 * time.Second
+)
+
+// StatusService 对外状态页数据, 只包含粗粒度的组件状态, 结果缓存 statusCacheTTL
+type StatusService struct {
+	mu       sync.Mutex
+	cached   *SystemStatus
+	cachedAt time.Time
+}
+
+type ComponentStatus struct {
+	Name   string `json:"name"`
+	Status string `json:"status"` // operational/degraded/down/disabled
+}
+
+type SystemStatus struct {
+	Status     string             `json:"status"` // 整体状态: operational/degraded/down
+	Components []*ComponentStatus `json:"components"`
+	Relay      struct {
+		WhitelistEntries int `json:"whitelist_entries"`
+	} `json:"relay"`
+	Maintenance *model.MaintenanceWindow `json:"maintenance,omitempty"` // 当前维护窗口
+	UpdatedAt   int64                    `json:"updated_at"`
+}
+
+// Status 获取状态, 缓存期内直接返回缓存结果
+func (s *StatusService) Status() *SystemStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.cached != nil && time.Since(s.cachedAt) < statusCacheTTL {
+		return s.cached
+	}
+	s.cached = s.collect()
+	s.cachedAt = time.Now()
+	return s.cached
+}
+
+func (s *StatusService) collect() *SystemStatus {
+	res := &SystemStatus{UpdatedAt: time.Now().Unix()}
+	res.Components = []*ComponentStatus{
+		{Name: "api", Status: StatusOperational},
+		{Name: "database", Status: s.databaseStatus()},
+		{Name: "payment_gateway", Status: s.paymentStatus()},
+	}
+	res.Relay.WhitelistEntries = len(AllService.RelayWhitelist().List(""))
+	res.Maintenance = AllService.MaintenanceService.Active()
+
+	res.Status = StatusOperational
+	for _, c := range res.Components {
+		switch {
+		case c.Name == "database" && c.Status == StatusDown:
+			res.Status = StatusDown
+		case c.Status == StatusDown || c.Status == StatusDegraded:
+			if res.Status == StatusOperational {
+				res.Status = StatusDegraded
+			}
+		}
+	}
+	return res
+}
+
+func (s *StatusService) databaseStatus() string {
+	return s.probeDatabase().Status
+}
+
+func (s *StatusService) paymentStatus() string {
+	return s.probePayment().Status
+}
+
+// HealthCheck 单项检查结果
+type HealthCheck struct {
+	Status    string `json:"status"`
+	LatencyMs int64  `json:"latency_ms"`
+	Error     string `json:"error,omitempty"`
+}
+
+// probeDatabase 探测数据库连接
+func (s *StatusService) probeDatabase() *HealthCheck {
+	res := &HealthCheck{Status: StatusOperational}
+	start := time.Now()
+	defer func() { res.LatencyMs = time.Since(start).Milliseconds() }()
+
+	sqlDB, err := DB.DB()
+	if err == nil {
+		ctx, cancel := context.WithTimeout(context.Background(), statusProbeTimeout)
+		defer cancel()
+		err = sqlDB.PingContext(ctx)
+	}
+	if err != nil {
+		res.Status = StatusDown
+		res.Error = err.Error()
+	}
+	return res
+}
+
+// probePayment 探测支付网关是否可达, 网关返回 5xx 视为降级, 无法连接视为不可用
+func (s *StatusService) probePayment() *HealthCheck {
+	if !AllService.Payment().IsEnabled() {
+		return &HealthCheck{Status: StatusDisabled}
+	}
+	res := &HealthCheck{Status: StatusOperational}
+	start := time.Now()
+	defer func() { res.LatencyMs = time.Since(start).Milliseconds() }()
+
+	cfg := AllService.Payment().GetConfig()
+	ctx, cancel := context.WithTimeout(context.Background(), statusProbeTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, cfg.BaseURL, nil)
+	if err != nil {
+		res.Status, res.Error = StatusDown, err.Error()
+		return res
+	}
+	resp, err := AllService.PaymentService.getHTTPClient().Do(req)
+	if err != nil {
+		res.Status, res.Error = StatusDown, err.Error()
+		return res
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 500 {
+		res.Status, res.Error = StatusDegraded, resp.Status
+	}
+	return res
+}
+
+// startedAt 进程启动时间
+var startedAt = time.Now()
+
+// HealthReport 内部健康检查结果, 包含错误详情, 仅供内部接口返回
+type HealthReport struct {
+	Status    string `json:"status"` // operational/degraded/down
+	Version   string `json:"version"`
+	GoVersion string `json:"go_version"`
+	Revision  string `json:"revision,omitempty"` // 构建时的 vcs 版本
+	Uptime    int64  `json:"uptime"`             // 秒
+	Database  struct {
+		HealthCheck
+		Dialect string `json:"dialect"`
+	} `json:"database"`
+	PaymentGateway *HealthCheck `json:"payment_gateway"`
+	RelayWhitelist struct {
+		Status      string `json:"status"`
+		Backend     string `json:"backend"` // memory/custom
+		Entries     int    `json:"entries"`
+		Persistence string `json:"persistence,omitempty"` // 快照文件
+		Error       string `json:"error,omitempty"`       // 最近一次快照失败原因
+	} `json:"relay_whitelist"`
+	CheckedAt int64 `json:"checked_at"`
+}
+
+// Health 实时检查各依赖(不使用缓存), 数据库不可用为 down, 其他依赖异常为 degraded
+func (s *StatusService) Health() *HealthReport {
+	res := &HealthReport{
+		Version:   AllService.AppService.GetAppVersion(),
+		GoVersion: runtime.Version(),
+		Uptime:    int64(time.Since(startedAt).Seconds()),
+		CheckedAt: time.Now().Unix(),
+	}
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, st := range info.Settings {
+			if st.Key == "vcs.revision" {
+				res.Revision = st.Value
+			}
+		}
+	}
+
+	res.Database.HealthCheck = *s.probeDatabase()
+	res.Database.Dialect = DB.Dialector.Name()
+	res.PaymentGateway = s.probePayment()
+
+	wl := &res.RelayWhitelist
+	wl.Status = StatusOperational
+	wl.Backend = "custom"
+	if store, ok := AllService.RelayWhitelist().(*RelayWhitelistService); ok {
+		wl.Backend = "memory"
+		wl.Persistence, wl.Error = store.PersistenceStatus()
+		if wl.Error != "" {
+			wl.Status = StatusDegraded
+		}
+	}
+	if n, ok := AllService.RelayWhitelist().Stats()["count"].(int); ok {
+		wl.Entries = n
+	}
+
+	res.Status = StatusOperational
+	switch {
+	case res.Database.Status == StatusDown:
+		res.Status = StatusDown
+	case res.PaymentGateway.Status == StatusDown || res.PaymentGateway.Status == StatusDegraded || wl.Status != StatusOperational:
+		res.Status = StatusDegraded
+	}
+	return res
+}
@@ -0,0 +1,192 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/lejianwen/rustdesk-api/v2/lib/storage"
+	"github.com/lejianwen/rustdesk-api/v2/model"
+)
+
+const (
+	defaultStorageDir       = "./runtime/storage"
+	defaultStorageUrlExpire = 10 * time.Minute
+	storageFileURL          = "/api/storage/file"
+)
+
+// StorageService 文件存储: 付款凭证、发票、备份和导出文件, 按系统设置选择本地或 S3 兼容存储
+type StorageService struct {
+	mu     sync.Mutex
+	driver storage.Storage
+	raw    string // 创建 driver 时的配置, 配置变化后重新创建
+}
+
+// normalize 补全默认值
+func (ss *StorageService) normalize(cfg *model.StorageConfig) *model.StorageConfig {
+	c := *cfg
+	if c.Driver == "" {
+		c.Driver = model.StorageDriverLocal
+	}
+	if c.LocalDir == "" {
+		c.LocalDir = defaultStorageDir
+	}
+	if c.UrlExpire <= 0 {
+		c.UrlExpire = int(defaultStorageUrlExpire.Seconds())
+	}
+	return &c
+}
+
+// GetConfig 当前生效的存储配置
+func (ss *StorageService) GetConfig() *model.StorageConfig {
+	return ss.normalize(AllService.SystemSettingService.GetStorageConfig())
+}
+
+// signKey 本地下载地址签名密钥, 不存在时生成并保存
+func (ss *StorageService) signKey() []byte {
+	key := AllService.SystemSettingService.Get(model.SettingKeyStorageSignKey)
+	if key == "" {
+		b := make([]byte, 32)
+		_, _ = rand.Read(b)
+		key = hex.EncodeToString(b)
+		if err := AllService.SystemSettingService.Set(model.SettingKeyStorageSignKey, key); err != nil {
+			Logger.Error("Save storage sign key failed: ", err)
+		}
+	}
+	return []byte(key)
+}
+
+func (ss *StorageService) build(cfg *model.StorageConfig) (storage.Storage, error) {
+	switch cfg.Driver {
+	case model.StorageDriverLocal:
+		return &storage.Local{Dir: cfg.LocalDir, URL: storageFileURL, Secret: ss.signKey()}, nil
+	case model.StorageDriverS3:
+		if cfg.S3Endpoint == "" || cfg.S3Bucket == "" {
+			return nil, errors.New("StorageConfigInvalid")
+		}
+		return &storage.S3{
+			Endpoint:  cfg.S3Endpoint,
+			Region:    cfg.S3Region,
+			Bucket:    cfg.S3Bucket,
+			AccessKey: cfg.S3AccessKey,
+			SecretKey: cfg.S3SecretKey,
+			PathStyle: cfg.S3PathStyle,
+			Prefix:    cfg.S3Prefix,
+			Client:    &http.Client{Timeout: 60 * time.Second},
+		}, nil
+	}
+	return nil, errors.New("StorageConfigInvalid")
+}
+
+// Storage 当前存储后端
+func (ss *StorageService) Storage() (storage.Storage, error) {
+	cfg := ss.GetConfig()
+	data, _ := json.Marshal(cfg)
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	if ss.driver != nil && ss.raw == string(data) {
+		return ss.driver, nil
+	}
+	d, err := ss.build(cfg)
+	if err != nil {
+		return nil, err
+	}
+	ss.driver, ss.raw = d, string(data)
+	return d, nil
+}
+
+// Put 保存文件
+func (ss *StorageService) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	st, err := ss.Storage()
+	if err != nil {
+		return err
+	}
+	return st.Put(ctx, key, r, size, contentType)
+}
+
+// Open 读取文件
+func (ss *StorageService) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	st, err := ss.Storage()
+	if err != nil {
+		return nil, err
+	}
+	return st.Open(ctx, key)
+}
+
+// Delete 删除文件
+func (ss *StorageService) Delete(ctx context.Context, key string) error {
+	st, err := ss.Storage()
+	if err != nil {
+		return err
+	}
+	return st.Delete(ctx, key)
+}
+
+// SignedURL 生成临时下载地址, 返回地址和过期时间戳
+// 本地存储返回本服务的相对路径, S3 返回预签名地址
+func (ss *StorageService) SignedURL(key string) (string, int64, error) {
+	st, err := ss.Storage()
+	if err != nil {
+		return "", 0, err
+	}
+	expire := time.Duration(ss.GetConfig().UrlExpire) * time.Second
+	u, err := st.SignedURL(key, expire)
+	if err != nil {
+		return "", 0, err
+	}
+	return u, time.Now().Add(expire).Unix(), nil
+}
+
+// VerifyLocalURL 校验本地存储下载地址, 非本地存储时始终失败
+func (ss *StorageService) VerifyLocalURL(key, expires, sign string) bool {
+	st, err := ss.Storage()
+	if err != nil {
+		return false
+	}
+	l, ok := st.(*storage.Local)
+	return ok && l.Verify(key, expires, sign)
+}
+
+// NewKey 生成不重复的对象 key: dir/YYYYMM/name_随机串ext
+func (ss *StorageService) NewKey(dir, name, ext string) string {
+	rnd := make([]byte, 8)
+	_, _ = rand.Read(rnd)
+	if name != "" {
+		name += "_"
+	}
+	return dir + "/" + time.Now().Format("200601") + "/" + name + hex.EncodeToString(rnd) + ext
+}
+
+// Test 使用给定配置写入、读取并删除一个测试文件
+func (ss *StorageService) Test(ctx context.Context, cfg *model.StorageConfig) error {
+	st, err := ss.build(ss.normalize(cfg))
+	if err != nil {
+		return err
+	}
+	key := ss.NewKey("storage-test", "", ".txt")
+	content := []byte("rustdesk-api storage test " + time.Now().Format(time.RFC3339))
+	if err := st.Put(ctx, key, bytes.NewReader(content), int64(len(content)), "text/plain"); err != nil {
+		return err
+	}
+	defer st.Delete(ctx, key)
+	rc, err := st.Open(ctx, key)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	got, err := io.ReadAll(io.LimitReader(rc, int64(len(content))+1))
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(got, content) {
+		return errors.New("content mismatch")
+	}
+	return nil
+}
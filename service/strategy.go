@@ -0,0 +1,196 @@
+package service
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/lejianwen/rustdesk-api/v2/model"
+	"gorm.io/gorm"
+)
+
+const strategyCacheTTL = time.Minute
+
+// StrategyService 设备策略, 按用户的套餐或用户组解析, 心跳时下发
+type StrategyService struct {
+	mu          sync.Mutex
+	assignments []*model.StrategyAssignment
+	loadedAt    time.Time
+	users       map[uint]*strategyCacheItem
+}
+
+type strategyCacheItem struct {
+	profile    *model.StrategyProfile
+	modifiedAt int64
+	at         time.Time
+}
+
+// resolveStrategy 套餐的策略优先于用户组, 已禁用的策略忽略
+func resolveStrategy(assignments []*model.StrategyAssignment, groupId, planId uint) *model.StrategyAssignment {
+	var group *model.StrategyAssignment
+	for _, a := range assignments {
+		if a.Profile == nil || a.Profile.Status != model.COMMON_STATUS_ENABLE {
+			continue
+		}
+		if a.Scope == model.StrategyScopePlan && planId > 0 && a.ScopeId == planId {
+			return a
+		}
+		if a.Scope == model.StrategyScopeGroup && groupId > 0 && a.ScopeId == groupId {
+			group = a
+		}
+	}
+	return group
+}
+
+// strategyModifiedAt 策略或分配任一变更都需要重新下发
+func strategyModifiedAt(a *model.StrategyAssignment) int64 {
+	m := time.Time(a.UpdatedAt).Unix()
+	if p := time.Time(a.Profile.UpdatedAt).Unix(); p > m {
+		m = p
+	}
+	return m
+}
+
+func (ss *StrategyService) loadAssignments() []*model.StrategyAssignment {
+	if ss.assignments != nil && time.Since(ss.loadedAt) < strategyCacheTTL {
+		return ss.assignments
+	}
+	list := make([]*model.StrategyAssignment, 0)
+	DB.Preload("Profile").Find(&list)
+	ss.assignments = list
+	ss.loadedAt = time.Now()
+	return list
+}
+
+// ForUser 用户当前生效的策略及其修改时间, 没有策略时返回 nil, 0
+func (ss *StrategyService) ForUser(userId uint) (*model.StrategyProfile, int64) {
+	if userId == 0 {
+		return nil, 0
+	}
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	if item, ok := ss.users[userId]; ok && time.Since(item.at) < strategyCacheTTL {
+		return item.profile, item.modifiedAt
+	}
+	item := &strategyCacheItem{at: time.Now()}
+	if all := ss.loadAssignments(); len(all) > 0 {
+		groupId := AllService.UserService.InfoById(userId).GroupId
+		var planId uint
+		if AllService.Subscription().IsSubscriptionActive(userId) {
+			if sub := AllService.Subscription().EffectiveSubscription(userId); sub.Plan != nil {
+				planId = sub.Plan.Id
+			}
+		}
+		if a := resolveStrategy(all, groupId, planId); a != nil {
+			item.profile, item.modifiedAt = a.Profile, strategyModifiedAt(a)
+		}
+	}
+	if ss.users == nil {
+		ss.users = make(map[uint]*strategyCacheItem)
+	}
+	ss.users[userId] = item
+	return item.profile, item.modifiedAt
+}
+
+// Reload 使缓存失效, 策略或分配变更后调用
+func (ss *StrategyService) Reload() {
+	ss.mu.Lock()
+	ss.assignments = nil
+	ss.users = nil
+	ss.mu.Unlock()
+}
+
+// ValidateProfile 校验策略内容, 返回可翻译的错误
+func (ss *StrategyService) ValidateProfile(p *model.StrategyProfile) error {
+	if !model.ValidVerificationMethod(p.VerificationMethod) || !model.ValidTemporaryPasswordLength(p.TemporaryPasswordLength) {
+		return errors.New("ParamsError")
+	}
+	return nil
+}
+
+func (ss *StrategyService) ProfileById(id uint) *model.StrategyProfile {
+	p := &model.StrategyProfile{}
+	DB.Where("id = ?", id).First(p)
+	return p
+}
+
+func (ss *StrategyService) ProfileList(page, pageSize uint, where func(tx *gorm.DB)) (res *model.StrategyProfileList) {
+	res = &model.StrategyProfileList{}
+	res.Page = int64(page)
+	res.PageSize = int64(pageSize)
+	tx := DB.Model(&model.StrategyProfile{})
+	if where != nil {
+		where(tx)
+	}
+	tx.Count(&res.Total)
+	tx.Scopes(Paginate(page, pageSize))
+	tx.Find(&res.StrategyProfiles)
+	return
+}
+
+func (ss *StrategyService) CreateProfile(p *model.StrategyProfile) error {
+	err := DB.Create(p).Error
+	ss.Reload()
+	return err
+}
+
+func (ss *StrategyService) UpdateProfile(p *model.StrategyProfile) error {
+	err := DB.Model(p).Select("name", "disable_file_transfer", "disable_clipboard", "disable_keyboard", "disable_audio",
+		"disable_recording", "auto_record_incoming", "verification_method", "temporary_password_length", "audit_file_transfer", "status", "remark").Updates(p).Error
+	ss.Reload()
+	return err
+}
+
+// DeleteProfile 删除策略及其分配
+func (ss *StrategyService) DeleteProfile(p *model.StrategyProfile) error {
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("profile_id = ?", p.Id).Delete(&model.StrategyAssignment{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(p).Error
+	})
+	ss.Reload()
+	return err
+}
+
+func (ss *StrategyService) AssignmentList(page, pageSize uint, where func(tx *gorm.DB)) (res *model.StrategyAssignmentList) {
+	res = &model.StrategyAssignmentList{}
+	res.Page = int64(page)
+	res.PageSize = int64(pageSize)
+	tx := DB.Model(&model.StrategyAssignment{})
+	if where != nil {
+		where(tx)
+	}
+	tx.Count(&res.Total)
+	tx.Scopes(Paginate(page, pageSize))
+	tx.Preload("Profile").Find(&res.StrategyAssignments)
+	return
+}
+
+// Assign 为用户组或套餐分配策略, 已有分配时替换
+func (ss *StrategyService) Assign(scope string, scopeId, profileId uint) (*model.StrategyAssignment, error) {
+	if scope != model.StrategyScopeGroup && scope != model.StrategyScopePlan {
+		return nil, errors.New("ParamsError")
+	}
+	if ss.ProfileById(profileId).Id == 0 {
+		return nil, errors.New("ItemNotFound")
+	}
+	a := &model.StrategyAssignment{}
+	DB.Where("scope = ? AND scope_id = ?", scope, scopeId).First(a)
+	a.Scope, a.ScopeId, a.ProfileId = scope, scopeId, profileId
+	err := DB.Save(a).Error
+	ss.Reload()
+	return a, err
+}
+
+func (ss *StrategyService) Unassign(id uint) error {
+	a := &model.StrategyAssignment{}
+	DB.Where("id = ?", id).First(a)
+	if a.Id == 0 {
+		return errors.New("ItemNotFound")
+	}
+	err := DB.Delete(a).Error
+	ss.Reload()
+	return err
+}
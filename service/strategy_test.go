@@ -0,0 +1,38 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/lejianwen/rustdesk-api/v2/model"
+)
+
+func TestResolveStrategy(t *testing.T) {
+	enabled := &model.StrategyProfile{Status: model.COMMON_STATUS_ENABLE}
+	disabled := &model.StrategyProfile{Status: model.COMMON_STATUS_DISABLED}
+	group := &model.StrategyAssignment{IdModel: model.IdModel{Id: 1}, Scope: model.StrategyScopeGroup, ScopeId: 1, Profile: enabled}
+	plan := &model.StrategyAssignment{IdModel: model.IdModel{Id: 2}, Scope: model.StrategyScopePlan, ScopeId: 5, Profile: enabled}
+	off := &model.StrategyAssignment{IdModel: model.IdModel{Id: 3}, Scope: model.StrategyScopePlan, ScopeId: 6, Profile: disabled}
+	all := []*model.StrategyAssignment{group, plan, off}
+
+	cases := []struct {
+		groupId, planId uint
+		want            uint
+	}{
+		{1, 0, 1},
+		{1, 5, 2}, // 套餐优先
+		{2, 5, 2},
+		{1, 6, 1}, // 套餐策略已禁用时回退到用户组
+		{2, 6, 0},
+		{0, 0, 0},
+	}
+	for _, c := range cases {
+		got := resolveStrategy(all, c.groupId, c.planId)
+		var id uint
+		if got != nil {
+			id = got.Id
+		}
+		if id != c.want {
+			t.Errorf("resolve(group=%d, plan=%d) = %d, want %d", c.groupId, c.planId, id, c.want)
+		}
+	}
+}
@@ -1,11 +1,15 @@
 package service
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"sort"
+	"strings"
 	"time"
 
+	"github.com/lejianwen/rustdesk-api/v2/lib/trace"
 	"github.com/lejianwen/rustdesk-api/v2/model"
 	"github.com/lejianwen/rustdesk-api/v2/utils"
 	"gorm.io/gorm"
@@ -18,6 +22,18 @@ const (
 	// pendingOrderStaleAfter 待支付订单超过该时长后视为“过期”，将关闭并重新生成订单号再发起支付。
 	// 目的：避免部分支付网关对相同 out_trade_no 的重复提交报唯一约束冲突（例如 idx_orders_client_merchant_order）。
 	pendingOrderStaleAfter = 30 * time.Minute
+
+	// defaultOrderExpireAfter 待支付订单默认超时关闭时长(未配置 payment.order-expire 时使用)
+	defaultOrderExpireAfter = 2 * time.Hour
+	// orderExpireCheckInterval 超时订单扫描间隔
+	orderExpireCheckInterval = time.Minute
+	// subscriptionExpireCheckInterval 到期订阅扫描间隔
+	subscriptionExpireCheckInterval = time.Minute
+
+	// defaultReconcileInterval 主动对账默认间隔(未配置 payment.reconcile-interval 时使用)
+	defaultReconcileInterval = 5 * time.Minute
+	// reconcileLookback 主动对账只查询该时长内发起过支付的订单
+	reconcileLookback = 24 * time.Hour
 )
 
 // ========== 套餐管理 ==========
@@ -25,7 +41,7 @@ const (
 // GetPlanById 根据ID获取套餐
 func (ss *SubscriptionService) GetPlanById(id uint) *model.SubscriptionPlan {
 	plan := &model.SubscriptionPlan{}
-	DB.Where("id = ?", id).First(plan)
+	DB.Preload("Prices").Where("id = ?", id).First(plan)
 	return plan
 }
 
@@ -39,7 +55,19 @@ func (ss *SubscriptionService) GetPlanByCode(code string) *model.SubscriptionPla
 // ListActivePlans 获取启用的套餐列表
 func (ss *SubscriptionService) ListActivePlans() []*model.SubscriptionPlan {
 	var plans []*model.SubscriptionPlan
-	DB.Where("status = ?", model.COMMON_STATUS_ENABLE).Order("sort_order ASC, id ASC").Find(&plans)
+	DB.Preload("Prices").Where("status = ?", model.COMMON_STATUS_ENABLE).Order("sort_order ASC, id ASC").Find(&plans)
+	return plans
+}
+
+// ListPlansForUser 用户可见的启用套餐, 不含隐藏套餐和其他用户组的专属套餐
+func (ss *SubscriptionService) ListPlansForUser(userId uint) []*model.SubscriptionPlan {
+	groupId := AllService.UserService.InfoById(userId).GroupId
+	plans := make([]*model.SubscriptionPlan, 0)
+	for _, plan := range AllService.Subscription().ListActivePlans() {
+		if plan.Listed(groupId) {
+			plans = append(plans, plan)
+		}
+	}
 	return plans
 }
 
@@ -53,18 +81,104 @@ func (ss *SubscriptionService) ListPlans(page, pageSize uint, where func(tx *gor
 		where(tx)
 	}
 	tx.Count(&res.Total)
-	tx.Scopes(Paginate(page, pageSize)).Order("sort_order ASC, id ASC").Find(&res.Plans)
+	tx.Scopes(Paginate(page, pageSize)).Preload("Prices").Order("sort_order ASC, id ASC").Find(&res.Plans)
 	return res
 }
 
-// CreatePlan 创建套餐
+// CreatePlan 创建套餐(连同多币种价格)
 func (ss *SubscriptionService) CreatePlan(plan *model.SubscriptionPlan) error {
 	return DB.Create(plan).Error
 }
 
-// UpdatePlan 更新套餐
+// UpdatePlan 更新套餐, 多币种价格以 plan.Prices 整体替换
 func (ss *SubscriptionService) UpdatePlan(plan *model.SubscriptionPlan) error {
-	return DB.Save(plan).Error
+	return DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit(clause.Associations).Save(plan).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("plan_id = ?", plan.Id).Delete(&model.PlanPrice{}).Error; err != nil {
+			return err
+		}
+		for _, pp := range plan.Prices {
+			pp.Id = 0
+			pp.PlanId = plan.Id
+		}
+		if len(plan.Prices) > 0 {
+			return tx.Create(&plan.Prices).Error
+		}
+		return nil
+	})
+}
+
+// ResolveCurrency 确定下单/展示使用的币种
+// 优先使用用户指定的币种, 其次按 Accept-Language 推断, 最后回退到基础币种; 仅返回支付网关支持的币种
+func (ss *SubscriptionService) ResolveCurrency(preferred, acceptLanguage string) string {
+	supported := AllService.Payment().SupportedCurrencies()
+	ok := func(c string) bool {
+		for _, s := range supported {
+			if s == c {
+				return true
+			}
+		}
+		return false
+	}
+	if c := strings.ToUpper(strings.TrimSpace(preferred)); c != "" && model.IsSupportedCurrency(c) && ok(c) {
+		return c
+	}
+	if c := currencyFromAcceptLanguage(acceptLanguage); c != "" && ok(c) {
+		return c
+	}
+	if ok(model.SupportedCurrencies[0]) || len(supported) == 0 {
+		return model.SupportedCurrencies[0]
+	}
+	return supported[0]
+}
+
+// PlanPrice 返回套餐在指定币种下的价格, 套餐未配置该币种时回退到基础币种
+func (ss *SubscriptionService) PlanPrice(plan *model.SubscriptionPlan, currency string) model.Money {
+	if price, ok := plan.PriceIn(currency); ok {
+		return price
+	}
+	return model.NewMoney(plan.Price, model.SupportedCurrencies[0])
+}
+
+// LocalizePlans 按币种填充套餐的展示价格
+func (ss *SubscriptionService) LocalizePlans(plans []*model.SubscriptionPlan, currency string) {
+	for _, plan := range plans {
+		price := ss.PlanPrice(plan, currency)
+		plan.Currency, plan.LocalPrice = price.Currency, price.Amount
+	}
+}
+
+// euroLanguages 默认使用欧元的语言
+var euroLanguages = map[string]bool{
+	"de": true, "fr": true, "it": true, "es": true, "nl": true, "pt": true, "fi": true,
+	"el": true, "sk": true, "sl": true, "et": true, "lv": true, "lt": true, "ga": true, "mt": true,
+}
+
+// currencyFromAcceptLanguage 根据 Accept-Language 的首选语言推断币种, 无法推断时返回空
+func currencyFromAcceptLanguage(acceptLanguage string) string {
+	tag := strings.TrimSpace(strings.Split(strings.Split(acceptLanguage, ",")[0], ";")[0])
+	if tag == "" {
+		return ""
+	}
+	parts := strings.Split(strings.ReplaceAll(strings.ToLower(tag), "_", "-"), "-")
+	lang := parts[0]
+	region := ""
+	if len(parts) > 1 {
+		region = parts[len(parts)-1]
+	}
+	switch {
+	case lang == "zh":
+		return model.CurrencyCNY
+	case region == "us":
+		return model.CurrencyUSD
+	case euroLanguages[lang], region == "de", region == "fr", region == "ie":
+		return model.CurrencyEUR
+	case lang == "en":
+		return model.CurrencyUSD
+	}
+	return ""
 }
 
 // DeletePlan 删除套餐(软删除:禁用)
@@ -75,13 +189,52 @@ func (ss *SubscriptionService) DeletePlan(id uint) error {
 // ========== 订单管理 ==========
 
 // GenerateOutTradeNo 生成业务订单号
-func (ss *SubscriptionService) GenerateOutTradeNo(userId uint) string {
-	// 格式: RD + 日期 + 用户ID + 随机数
-	return fmt.Sprintf("RD%s%d%s", time.Now().Format("20060102150405"), userId, utils.RandomString(6))
+// 格式: RD + 时间 + 随机数, 不包含用户ID等自增编号, 避免推算用户量
+func (ss *SubscriptionService) GenerateOutTradeNo() string {
+	return fmt.Sprintf("RD%s%s", time.Now().Format("20060102150405"), utils.RandomString(10))
+}
+
+// ReissueOrder 关闭待支付订单并以新订单号重新下单, 避免网关侧同一订单号重复建单
+// 只关闭该订单本身; 新订单复制套餐/商品、金额、币种、支付方式和税费, paySubmitAt 为新订单的发起支付时间
+func (ss *SubscriptionService) ReissueOrder(tx *gorm.DB, cur *model.Order, paySubmitAt int64) (*model.Order, error) {
+	if err := tx.Model(cur).Update("status", model.OrderStatusClosed).Error; err != nil {
+		return nil, err
+	}
+	cur.Status = model.OrderStatusClosed
+	next := &model.Order{
+		UserId:      cur.UserId,
+		PlanId:      cur.PlanId,
+		ProductId:   cur.ProductId,
+		OutTradeNo:  ss.GenerateOutTradeNo(),
+		Subject:     cur.Subject,
+		Amount:      cur.Amount,
+		AmountYuan:  cur.AmountYuan,
+		Currency:    cur.Currency,
+		Status:      model.OrderStatusPending,
+		PayMethod:   cur.PayMethod,
+		PayType:     cur.PayType,
+		PaySubmitAt: paySubmitAt,
+		CreatedBy:   cur.CreatedBy,
+
+		TaxCountry:   cur.TaxCountry,
+		TaxRate:      cur.TaxRate,
+		TaxAmount:    cur.TaxAmount,
+		TaxInclusive: cur.TaxInclusive,
+	}
+	if err := tx.Create(next).Error; err != nil {
+		return nil, err
+	}
+	return next, nil
 }
 
-// CreateOrder 创建订单并返回支付URL
-func (ss *SubscriptionService) CreateOrder(userId, planId uint) (outTradeNo, payURL string, err error) {
+// CreateOrder 创建订单并返回支付URL, currency 为 ResolveCurrency 确定的币种
+// payType 为用户选择的支付渠道, 需在支付配置中启用, 为空时由网关收银台选择
+// country 为 ResolveTaxCountry 确定的计税国家/地区, 启用税费时用于计算税额
+func (ss *SubscriptionService) CreateOrder(ctx context.Context, userId, planId uint, currency, payType, country string) (outTradeNo, payURL string, err error) {
+	ctx, span := trace.Start(ctx, "subscription.create_order", trace.KindInternal)
+	defer func() { span.SetError(err); span.End() }()
+	db := DB.WithContext(ctx)
+
 	// 1. 检查套餐
 	plan := ss.GetPlanById(planId)
 	if plan.Id == 0 {
@@ -90,21 +243,31 @@ func (ss *SubscriptionService) CreateOrder(userId, planId uint) (outTradeNo, pay
 	if plan.Status != model.COMMON_STATUS_ENABLE {
 		return "", "", errors.New("PlanDisabled")
 	}
+	if err := ss.CheckPlanRestrictions(db, userId, plan); err != nil {
+		return "", "", err
+	}
+	price := ss.PlanPrice(plan, currency)
+	if !price.IsZero() {
+		if err := AllService.Payment().CheckPayType(payType); err != nil {
+			return "", "", err
+		}
+	}
 
 	// 免费套餐：直接创建已支付订单并激活订阅
-	if plan.Price == 0 {
-		outTradeNo = ss.GenerateOutTradeNo(userId)
-		amountYuan := model.FenToYuan(plan.Price)
+	if price.IsZero() {
+		outTradeNo = ss.GenerateOutTradeNo()
 		now := time.Now().Unix()
+		var freeOrderId uint
 
-		err = DB.Transaction(func(tx *gorm.DB) error {
+		err = db.Transaction(func(tx *gorm.DB) error {
 			order := &model.Order{
 				UserId:     userId,
 				PlanId:     planId,
 				OutTradeNo: outTradeNo,
 				Subject:    plan.Name,
-				Amount:     plan.Price,
-				AmountYuan: amountYuan,
+				Amount:     price.Amount,
+				AmountYuan: price.String(),
+				Currency:   price.Currency,
 				Status:     model.OrderStatusPaid,
 				PaidAt:     now,
 			}
@@ -112,11 +275,19 @@ func (ss *SubscriptionService) CreateOrder(userId, planId uint) (outTradeNo, pay
 				Logger.Error("Create free order failed: ", err)
 				return err
 			}
-			return ss.activateOrExtendSubscription(tx, order.UserId, order.PlanId, order.Id, now)
+			MetricOrdersCreated.Inc("free")
+			ObserveFunnel(FunnelOrderCreated, planId, "free")
+			by := subscriptionChange{Source: model.SubscriptionSourceUser, ActorId: userId, Metadata: map[string]interface{}{"free": true}}
+			if err := ss.activateOrExtendSubscription(tx, order.UserId, order.PlanId, order.Id, now, by); err != nil {
+				return err
+			}
+			freeOrderId = order.Id
+			return nil
 		})
 		if err != nil {
 			return "", "", err
 		}
+		ss.dispatchPaidEvents(freeOrderId)
 		return outTradeNo, "", nil
 	}
 
@@ -124,29 +295,38 @@ func (ss *SubscriptionService) CreateOrder(userId, planId uint) (outTradeNo, pay
 	// 注意：若订单已发起过支付（或太久未支付），继续复用同一个 out_trade_no 可能导致网关侧重复建单报错；
 	// 此时应关闭旧订单并重新生成 out_trade_no 发起支付。
 	existing := &model.Order{}
-	if err := DB.Where("user_id = ? AND plan_id = ? AND status = ?", userId, planId, model.OrderStatusPending).
+	if err := db.Where("user_id = ? AND plan_id = ? AND status = ? AND currency = ? AND pay_method = ? AND tax_country = ?", userId, planId, model.OrderStatusPending, price.Currency, model.PayMethodOnline, taxCountry(country)).
 		Order("id DESC").
 		First(existing).Error; err == nil && existing.Id != 0 {
 		createdAt := time.Time(existing.CreatedAt)
 		isStale := !createdAt.IsZero() && time.Since(createdAt) > pendingOrderStaleAfter
 
 		if existing.PaySubmitAt == 0 && !isStale {
-			payURL = AllService.PaymentService.BuildPayURL(existing.OutTradeNo)
+			// 尚未发起支付, 可直接更换渠道
+			if existing.PayType != payType {
+				if err := db.Model(existing).Update("pay_type", payType).Error; err != nil {
+					return "", "", err
+				}
+			}
+			payURL = AllService.Payment().BuildPayURL(existing.OutTradeNo)
 			return existing.OutTradeNo, payURL, nil
 		}
 
 		// 关闭该套餐下所有待支付订单，避免用户从订单列表“立即支付”时继续命中旧单
-		if err := DB.Model(&model.Order{}).
-			Where("user_id = ? AND plan_id = ? AND status = ?", userId, planId, model.OrderStatusPending).
+		if err := db.Model(&model.Order{}).
+			Where("user_id = ? AND plan_id = ? AND status = ? AND pay_method = ?", userId, planId, model.OrderStatusPending, model.PayMethodOnline).
 			Update("status", model.OrderStatusClosed).Error; err != nil {
 			Logger.Error("Close pending orders failed: ", err)
 			return "", "", err
 		}
 	}
 
+	if err := ss.checkOrderLimit(db, userId); err != nil {
+		return "", "", err
+	}
+
 	// 2. 生成订单号
-	outTradeNo = ss.GenerateOutTradeNo(userId)
-	amountYuan := model.FenToYuan(plan.Price)
+	outTradeNo = ss.GenerateOutTradeNo()
 
 	// 3. 创建订单
 	order := &model.Order{
@@ -154,21 +334,85 @@ func (ss *SubscriptionService) CreateOrder(userId, planId uint) (outTradeNo, pay
 		PlanId:     planId,
 		OutTradeNo: outTradeNo,
 		Subject:    plan.Name,
-		Amount:     plan.Price,
-		AmountYuan: amountYuan,
+		Amount:     price.Amount,
+		AmountYuan: price.String(),
+		Currency:   price.Currency,
 		Status:     model.OrderStatusPending,
+		PayMethod:  model.PayMethodOnline,
+		PayType:    payType,
 	}
-	if err := DB.Create(order).Error; err != nil {
+	applyTax(order, country)
+	if err := db.Create(order).Error; err != nil {
 		Logger.Error("Create order failed: ", err)
 		return "", "", err
 	}
+	MetricOrdersCreated.Inc(model.PayMethodOnline)
+	ObserveFunnel(FunnelOrderCreated, planId, model.PayMethodOnline)
 
 	// 4. 构建支付URL
-	payURL = AllService.PaymentService.BuildPayURL(outTradeNo)
+	payURL = AllService.Payment().BuildPayURL(outTradeNo)
 
 	return outTradeNo, payURL, nil
 }
 
+// CreateManualOrder 创建线下转账订单, 用户上传付款凭证后由管理员审核入账
+// 免费套餐按普通下单直接激活; 同一套餐已有待支付的线下订单时复用
+func (ss *SubscriptionService) CreateManualOrder(ctx context.Context, userId, planId uint, currency, country string) (outTradeNo string, err error) {
+	ctx, span := trace.Start(ctx, "subscription.create_manual_order", trace.KindInternal)
+	defer func() { span.SetError(err); span.End() }()
+	db := DB.WithContext(ctx)
+
+	if !AllService.PaymentProofService.IsEnabled() {
+		return "", errors.New("ManualPaymentDisabled")
+	}
+	plan := ss.GetPlanById(planId)
+	if plan.Id == 0 {
+		return "", errors.New("PlanNotFound")
+	}
+	if plan.Status != model.COMMON_STATUS_ENABLE {
+		return "", errors.New("PlanDisabled")
+	}
+	if err := ss.CheckPlanRestrictions(db, userId, plan); err != nil {
+		return "", err
+	}
+	price := ss.PlanPrice(plan, currency)
+	if price.IsZero() {
+		outTradeNo, _, err = ss.CreateOrder(ctx, userId, planId, currency, "", country)
+		return outTradeNo, err
+	}
+
+	existing := &model.Order{}
+	db.Where("user_id = ? AND plan_id = ? AND status = ? AND currency = ? AND pay_method = ? AND tax_country = ?",
+		userId, planId, model.OrderStatusPending, price.Currency, model.PayMethodManual, taxCountry(country)).
+		Order("id DESC").First(existing)
+	if existing.Id != 0 {
+		return existing.OutTradeNo, nil
+	}
+	if err := ss.checkOrderLimit(db, userId); err != nil {
+		return "", err
+	}
+
+	order := &model.Order{
+		UserId:     userId,
+		PlanId:     planId,
+		OutTradeNo: ss.GenerateOutTradeNo(),
+		Subject:    plan.Name,
+		Amount:     price.Amount,
+		AmountYuan: price.String(),
+		Currency:   price.Currency,
+		Status:     model.OrderStatusPending,
+		PayMethod:  model.PayMethodManual,
+	}
+	applyTax(order, country)
+	if err := db.Create(order).Error; err != nil {
+		Logger.Error("Create manual order failed: ", err)
+		return "", err
+	}
+	MetricOrdersCreated.Inc(model.PayMethodManual)
+	ObserveFunnel(FunnelOrderCreated, planId, model.PayMethodManual)
+	return order.OutTradeNo, nil
+}
+
 // GetOrderByOutTradeNo 根据业务订单号获取订单
 func (ss *SubscriptionService) GetOrderByOutTradeNo(outTradeNo string) *model.Order {
 	order := &model.Order{}
@@ -197,6 +441,18 @@ func (ss *SubscriptionService) ListOrders(page, pageSize uint, where func(tx *go
 	return res
 }
 
+// ExportOrders 按条件分批导出订单(预加载用户和套餐), 按 id 倒序, 每批回调一次
+func (ss *SubscriptionService) ExportOrders(where func(tx *gorm.DB), fn func(orders []*model.Order) error) error {
+	tx := DB.Model(&model.Order{})
+	if where != nil {
+		where(tx)
+	}
+	return findDescInBatches(tx.Preload("User").Preload("Plan"), exportBatchSize,
+		func(o *model.Order) uint { return o.Id }, fn)
+}
+
+var errNotifyReplay = errors.New("NotifyReplay")
+
 // ListUserOrders 获取用户订单列表
 func (ss *SubscriptionService) ListUserOrders(userId uint, page, pageSize uint) *model.OrderList {
 	return ss.ListOrders(page, pageSize, func(tx *gorm.DB) {
@@ -204,98 +460,160 @@ func (ss *SubscriptionService) ListUserOrders(userId uint, page, pageSize uint)
 	})
 }
 
-// HandleNotify 处理支付回调
-func (ss *SubscriptionService) HandleNotify(params map[string]string) error {
+// HandleNotify 处理支付回调, 每次回调(含原始参数和来源 IP)都写入回调记录
+// 与之前完全相同的回调按上一次的结果处理, 见 replayDecision
+func (ss *SubscriptionService) HandleNotify(ctx context.Context, params map[string]string, sourceIP string) error {
+	ctx, span := trace.Start(ctx, "subscription.handle_notify", trace.KindInternal)
+	defer span.End()
+	span.SetAttr("out_trade_no", params["out_trade_no"])
+
+	fingerprint := notifyFingerprint(params)
+	prev := &model.PaymentNotifyLog{}
+	DB.WithContext(ctx).Where("fingerprint = ?", fingerprint).Order("id DESC").First(prev)
+	signValid := AllService.Payment().Verify(params)
+	process, err := replayDecision(prev.Result)
+	if process {
+		err = ss.handleNotify(ctx, params, signValid)
+	} else if err != nil {
+		Logger.Warn("Payment notify replay rejected, out_trade_no: ", params["out_trade_no"], " previous: ", prev.Id, " ip: ", sourceIP)
+	}
+	result := notifyResult(err)
+	ss.recordNotify(ctx, params, fingerprint, sourceIP, result, signValid, err, prev.Id)
+	MetricPaymentNotify.Inc(result)
+	span.SetAttr("notify.result", result)
+	span.SetError(err)
+	return err
+}
+
+// handleNotify signValid 为 HandleNotify 已完成的验签结果, 避免重复验签
+func (ss *SubscriptionService) handleNotify(ctx context.Context, params map[string]string, signValid bool) error {
 	outTradeNo := params["out_trade_no"]
 	tradeNo := params["trade_no"]
 	money := params["money"]
 	pid := params["pid"]
 
 	// 1. 验签
-	if !AllService.PaymentService.Verify(params) {
+	if !signValid {
 		// 仅记录关键字段,避免泄露敏感信息
 		Logger.Warn("Payment notify sign verify failed, out_trade_no: ", outTradeNo, " trade_no: ", tradeNo, " pid: ", pid)
 		return errors.New("SignVerifyFailed")
 	}
 
-	// 2. 参数校验
-	if outTradeNo == "" || tradeNo == "" || money == "" {
+	// 2. 参数校验(退款推送可不带 money)
+	tradeStatus := params["trade_status"]
+	if outTradeNo == "" || tradeNo == "" || (money == "" && !isGatewayRefund(tradeStatus)) {
 		Logger.Warn("Payment notify missing params, out_trade_no: ", outTradeNo, " trade_no: ", tradeNo, " money: ", money)
 		return errors.New("ParamsError")
 	}
 
 	// 3. 校验pid是否匹配
-	cfg := AllService.PaymentService.GetConfig()
+	cfg := AllService.Payment().GetConfig()
 	if pid != "" && pid != cfg.Pid {
 		Logger.Warn("Payment notify pid mismatch, out_trade_no: ", outTradeNo, " expected: ", cfg.Pid, " got: ", pid)
 		return errors.New("PidMismatch")
 	}
 
-	// 4. 检查交易状态
-	tradeStatus := params["trade_status"]
+	// 4. 检查交易状态, 网关推送的退款/拒付单独入账
+	if isGatewayRefund(tradeStatus) {
+		return ss.gatewayRefund(params)
+	}
 	if tradeStatus != "TRADE_SUCCESS" {
 		Logger.Info("Payment notify trade_status is not TRADE_SUCCESS: ", tradeStatus)
 		return nil // 非成功状态,忽略
 	}
 
-	// 5. 使用事务处理
-	return DB.Transaction(func(tx *gorm.DB) error {
-		// 5.1 查询订单(加行锁)
+	// 5. 入账
+	if o := ss.GetOrderByOutTradeNo(outTradeNo); o.Id > 0 {
+		ObserveFunnel(FunnelNotifyReceived, o.PlanId, orderProvider(o))
+	}
+	return ss.payOrder(ctx, outTradeNo, tradeNo, money, params, subscriptionChange{Source: model.SubscriptionSourceNotify})
+}
+
+// payOrder 订单支付成功入账(回调/主动对账共用)
+// 在同一事务内完成: 加锁查询订单 -> 幂等检查 -> 校验金额 -> 更新订单 -> 激活/续期订阅
+func (ss *SubscriptionService) payOrder(ctx context.Context, outTradeNo, tradeNo, money string, payload interface{}, by subscriptionChange) error {
+	var paid *model.Order
+	err := DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// 1. 查询订单(加行锁)
 		order := &model.Order{}
 		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
 			Where("out_trade_no = ?", outTradeNo).First(order).Error; err != nil {
-			Logger.Error("Payment notify order not found: ", outTradeNo)
+			Logger.Error("Pay order not found: ", outTradeNo)
 			return errors.New("OrderNotFound")
 		}
 
-		// 5.2 幂等检查
+		// 2. 幂等检查
 		if order.Status == model.OrderStatusPaid || order.Status == model.OrderStatusRefunded {
-			Logger.Info("Payment notify order already processed: ", outTradeNo)
+			Logger.Info("Pay order already processed: ", outTradeNo)
 			return nil // 已处理,直接返回成功
 		}
 		if order.Status == model.OrderStatusClosed {
 			// 订单可能被用户重新发起支付时关闭（例如支付网关不允许同 out_trade_no 重复提交）。
 			// 一旦网关侧实际支付成功，我们仍应正常入账，避免资金损失。
-			Logger.Warn("Payment notify for closed order, will still process: ", outTradeNo)
+			Logger.Warn("Pay order is closed, will still process: ", outTradeNo)
 		}
 
-		// 5.3 校验金额(使用分为单位比较,更精确)
-		moneyFen, err := ss.ParseMoneyToFen(money)
+		// 3. 校验金额(使用分为单位比较,更精确)
+		received, err := model.ParseMoney(money, order.Currency)
 		if err != nil {
-			Logger.Error("Payment notify parse money failed: ", err)
+			Logger.Error("Pay order parse money failed: ", err)
 			return errors.New("InvalidMoney")
 		}
-		if moneyFen != order.Amount {
-			Logger.Error("Payment notify amount mismatch, expected: ", order.Amount, " got: ", moneyFen)
+		if !received.Equal(order.Total()) {
+			Logger.Error("Pay order amount mismatch, expected: ", order.Total().Display(), " got: ", received.Display())
 			return errors.New("AmountMismatch")
 		}
 
-		// 5.4 更新订单状态(保存回调原始数据为JSON)
+		// 4. 更新订单状态(保存原始数据为JSON)
 		now := time.Now().Unix()
-		payloadBytes, _ := json.Marshal(params)
+		payloadBytes, _ := json.Marshal(payload)
 		if err := tx.Model(order).Updates(map[string]interface{}{
 			"trade_no":       tradeNo,
 			"status":         model.OrderStatusPaid,
 			"paid_at":        now,
 			"notify_payload": string(payloadBytes),
 		}).Error; err != nil {
-			Logger.Error("Payment notify update order failed: ", err)
+			Logger.Error("Pay order update order failed: ", err)
 			return err
 		}
 
-		// 3.5 激活/续期订阅
-		if err := ss.activateOrExtendSubscription(tx, order.UserId, order.PlanId, order.Id, now); err != nil {
-			Logger.Error("Payment notify activate subscription failed: ", err)
+		// 5. 激活/续期订阅; 商品订单进入待履约, 不影响订阅
+		if order.ProductId > 0 {
+			if err := tx.Model(order).Update("fulfillment_status", model.FulfillmentPending).Error; err != nil {
+				return err
+			}
+		} else if err := ss.activateOrExtendSubscription(tx, order.UserId, order.PlanId, order.Id, now, by); err != nil {
+			Logger.Error("Pay order activate subscription failed: ", err)
 			return err
 		}
 
-		Logger.Info("Payment notify success, order: ", outTradeNo, " user: ", order.UserId)
+		Logger.Info("Pay order success, order: ", outTradeNo, " user: ", order.UserId)
+		paid = order
 		return nil
 	})
+	if err == nil && paid != nil {
+		ss.dispatchPaidEvents(paid.Id)
+	}
+	return err
+}
+
+// dispatchPaidEvents 订单入账后(事务提交后)发布 order.paid 和 subscription.activated 事件, 商品订单发布 product.purchased
+func (ss *SubscriptionService) dispatchPaidEvents(orderId uint) {
+	order := ss.GetOrderById(orderId)
+	AllService.EventBus.Publish(OrderPaidEvent{Order: order})
+	if order.ProductId > 0 {
+		AllService.EventBus.Publish(ProductPurchasedEvent{Order: order})
+		return
+	}
+	ObserveFunnel(FunnelPaid, order.PlanId, orderProvider(order))
+	sub := ss.GetUserSubscription(order.UserId)
+	if sub.Id > 0 {
+		AllService.EventBus.Publish(SubscriptionActivatedEvent{Subscription: sub})
+	}
 }
 
 // activateOrExtendSubscription 激活或续期订阅(事务内调用)
-func (ss *SubscriptionService) activateOrExtendSubscription(tx *gorm.DB, userId, planId, orderId uint, now int64) error {
+func (ss *SubscriptionService) activateOrExtendSubscription(tx *gorm.DB, userId, planId, orderId uint, now int64, by subscriptionChange) error {
 	// 1. 获取套餐
 	plan := &model.SubscriptionPlan{}
 	if err := tx.Where("id = ?", planId).First(plan).Error; err != nil {
@@ -309,26 +627,45 @@ func (ss *SubscriptionService) activateOrExtendSubscription(tx *gorm.DB, userId,
 
 	// 3. 计算新的过期时间
 	var startAt, expireAt int64
+	period := plan.PlanPeriod()
+	// 首购赠送: 仅用户第一笔该套餐的付费订单
+	if plan.BonusDaysFirstPurchase > 0 && ss.isFirstPaidOrder(tx, userId, planId, orderId) {
+		period = period.Add(model.Period{Days: plan.BonusDaysFirstPurchase})
+		by.Metadata = withMetadata(by.Metadata, "bonus_days", plan.BonusDaysFirstPurchase)
+		if err := tx.Model(&model.Order{}).Where("id = ?", orderId).Update("bonus_days", plan.BonusDaysFirstPurchase).Error; err != nil {
+			return err
+		}
+	}
 	if err == gorm.ErrRecordNotFound {
 		// 新订阅
 		startAt = now
-		expireAt = ss.calcExpireTime(now, plan.PeriodUnit, plan.PeriodCount)
+		expireAt = period.AddToUnix(now)
 	} else if err != nil {
 		return err
 	} else {
-		// 续期: 如果当前订阅未过期,从过期时间续期;否则从现在开始
+		// 续期: 如果当前订阅未过期,从过期时间续期;暂停中从恢复后的到期时间续期;否则从现在开始
 		if sub.ExpireAt > now && sub.Status == model.SubscriptionStatusActive {
 			startAt = sub.StartAt
-			expireAt = ss.calcExpireTime(sub.ExpireAt, plan.PeriodUnit, plan.PeriodCount)
+			expireAt = period.AddToUnix(sub.ExpireAt)
+		} else if sub.Status == model.SubscriptionStatusPaused {
+			startAt = sub.StartAt
+			expireAt = period.AddToUnix(sub.ResumeExpireAt(now))
 		} else {
 			startAt = now
-			expireAt = ss.calcExpireTime(now, plan.PeriodUnit, plan.PeriodCount)
+			expireAt = period.AddToUnix(now)
 		}
 	}
 
+	if err := ss.guardExtension(tx, userId, planId, orderId, now, expireAt, &by); err != nil {
+		return err
+	}
+
 	// 4. 更新或创建订阅
+	before := *sub
+	typ := model.SubscriptionEventRenewed
 	if sub.Id == 0 {
 		// 创建新订阅
+		typ = model.SubscriptionEventCreated
 		sub = &model.UserSubscription{
 			UserId:      userId,
 			PlanId:      planId,
@@ -337,33 +674,103 @@ func (ss *SubscriptionService) activateOrExtendSubscription(tx *gorm.DB, userId,
 			ExpireAt:    expireAt,
 			Status:      model.SubscriptionStatusActive,
 		}
-		return tx.Create(sub).Error
+		if err := tx.Create(sub).Error; err != nil {
+			return err
+		}
 	} else {
 		// 更新订阅
-		return tx.Model(sub).Updates(map[string]interface{}{
-			"plan_id":       planId,
-			"last_order_id": orderId,
-			"start_at":      startAt,
-			"expire_at":     expireAt,
-			"status":        model.SubscriptionStatusActive,
-		}).Error
+		// 购买新周期后不再切换到预约的套餐
+		if err := tx.Model(sub).Updates(pausedAware(sub, now, expireAt, map[string]interface{}{
+			"plan_id":         planId,
+			"last_order_id":   orderId,
+			"start_at":        startAt,
+			"pending_plan_id": 0,
+		})).Error; err != nil {
+			return err
+		}
+	}
+	return ss.recordSubscriptionEvent(tx, typ, by, &before, sub, orderId)
+}
+
+// isFirstPaidOrder 订单是否为用户该套餐的首笔付费订单(事务内调用, 当前订单已置为已支付)
+func (ss *SubscriptionService) isFirstPaidOrder(tx *gorm.DB, userId, planId, orderId uint) bool {
+	order := &model.Order{}
+	if err := tx.Select("id", "amount").Where("id = ?", orderId).First(order).Error; err != nil || order.Amount <= 0 {
+		return false
+	}
+	var n int64
+	tx.Model(&model.Order{}).
+		Where("user_id = ? AND plan_id = ? AND id <> ? AND amount > 0 AND status IN ?", userId, planId, orderId,
+			[]int{model.OrderStatusPaid, model.OrderStatusRefunded}).
+		Count(&n)
+	return n == 0
+}
+
+// PreviewCheckout 下单预览: 按币种计算实付金额, 判断首购赠送, 并估算支付后的到期时间
+func (ss *SubscriptionService) PreviewCheckout(userId, planId uint, currency, payMethod, country string) (*model.CheckoutPreview, error) {
+	plan := ss.GetPlanById(planId)
+	if plan.Id == 0 {
+		return nil, errors.New("PlanNotFound")
+	}
+	if plan.Status != model.COMMON_STATUS_ENABLE {
+		return nil, errors.New("PlanDisabled")
+	}
+	if err := ss.CheckPlanRestrictions(DB, userId, plan); err != nil {
+		return nil, err
+	}
+	if payMethod == "" {
+		payMethod = model.PayMethodOnline
+	}
+	ss.LocalizePlans([]*model.SubscriptionPlan{plan}, currency)
+	ss.MarkBonusEligible([]*model.SubscriptionPlan{plan}, userId)
+	price := ss.PlanPrice(plan, currency)
+
+	res := &model.CheckoutPreview{
+		Plan:          plan,
+		PayMethod:     payMethod,
+		Currency:      price.Currency,
+		Amount:        price.Amount,
+		AmountDisplay: price.Display(),
 	}
+	// 与下单时一致计算税费
+	taxed := &model.Order{Amount: price.Amount, Currency: price.Currency}
+	applyTax(taxed, country)
+	res.TaxCountry = taxed.TaxCountry
+	res.TaxRate = taxed.TaxRate
+	res.TaxAmount = taxed.TaxAmount
+	res.TaxInclusive = taxed.TaxInclusive
+	res.Amount = taxed.Amount
+	res.AmountDisplay = taxed.Total().Display()
+	period := plan.PlanPeriod()
+	if plan.BonusEligible && price.Amount > 0 {
+		res.BonusDays = plan.BonusDaysFirstPurchase
+		period = period.Add(model.Period{Days: res.BonusDays})
+	}
+	// 与 activateOrExtendSubscription 一致: 有效订阅从到期时间续期, 否则从现在开始
+	now := time.Now().Unix()
+	base := now
+	if sub := ss.GetUserSubscription(userId); sub.Id > 0 && sub.Status == model.SubscriptionStatusActive && sub.ExpireAt > now {
+		res.CurrentExpireAt = sub.ExpireAt
+		base = sub.ExpireAt
+	}
+	res.ExpireAt = period.AddToUnix(base)
+	ObserveFunnel(FunnelCheckoutPreview, plan.Id, payMethod)
+	return res, nil
 }
 
-// calcExpireTime 计算过期时间
-func (ss *SubscriptionService) calcExpireTime(baseTime int64, periodUnit string, periodCount int) int64 {
-	t := time.Unix(baseTime, 0)
-	switch periodUnit {
-	case model.PeriodUnitDay:
-		t = t.AddDate(0, 0, periodCount)
-	case model.PeriodUnitMonth:
-		t = t.AddDate(0, periodCount, 0)
-	case model.PeriodUnitYear:
-		t = t.AddDate(periodCount, 0, 0)
-	default:
-		t = t.AddDate(0, periodCount, 0) // 默认按月
+// MarkBonusEligible 标记用户可享首购赠送的套餐
+func (ss *SubscriptionService) MarkBonusEligible(plans []*model.SubscriptionPlan, userId uint) {
+	var bought []uint
+	DB.Model(&model.Order{}).
+		Where("user_id = ? AND amount > 0 AND status IN ?", userId, []int{model.OrderStatusPaid, model.OrderStatusRefunded}).
+		Distinct().Pluck("plan_id", &bought)
+	boughtSet := make(map[uint]bool, len(bought))
+	for _, id := range bought {
+		boughtSet[id] = true
+	}
+	for _, plan := range plans {
+		plan.BonusEligible = plan.BonusDaysFirstPurchase > 0 && !boughtSet[plan.Id]
 	}
-	return t.Unix()
 }
 
 // ========== 订阅查询 ==========
@@ -371,25 +778,135 @@ func (ss *SubscriptionService) calcExpireTime(baseTime int64, periodUnit string,
 // GetUserSubscription 获取用户订阅
 func (ss *SubscriptionService) GetUserSubscription(userId uint) *model.UserSubscription {
 	sub := &model.UserSubscription{}
-	DB.Where("user_id = ?", userId).Preload("Plan").First(sub)
+	DB.Where("user_id = ?", userId).Preload("Plan").Preload("PendingPlan").First(sub)
 	return sub
 }
 
 // GetSubscriptionById 获取订阅详情(管理员)
 func (ss *SubscriptionService) GetSubscriptionById(id uint) *model.UserSubscription {
 	sub := &model.UserSubscription{}
-	DB.Where("id = ?", id).Preload("User").Preload("Plan").Preload("LastOrder").First(sub)
+	DB.Where("id = ?", id).Preload("User").Preload("Plan").Preload("PendingPlan").Preload("LastOrder").First(sub)
 	return sub
 }
 
-// IsSubscriptionActive 检查用户订阅是否有效
+// IsSubscriptionActive 检查用户订阅是否有效(含组织席位提供的订阅)
 func (ss *SubscriptionService) IsSubscriptionActive(userId uint) bool {
+	active, _ := subscriptionActiveAt(ss.EffectiveSubscription(userId), time.Now().Unix())
+	return active
+}
+
+// subscriptionActiveAt 按订阅记录判断 at 时刻是否有效, 返回原因
+func subscriptionActiveAt(sub *model.UserSubscription, at int64) (bool, string) {
+	switch {
+	case sub.Id == 0:
+		return false, "no_subscription"
+	case sub.Status == model.SubscriptionStatusCanceled:
+		return false, "canceled"
+	case sub.Status == model.SubscriptionStatusPaused:
+		return false, "paused"
+	case sub.Status != model.SubscriptionStatusActive, sub.ExpireAt <= at:
+		return false, "expired"
+	}
+	return true, "active"
+}
+
+// SimulateSubscription 模拟用户在 at 时刻的订阅有效性(只读, 不修改任何数据)
+// 同时给出两种结果: 按当前订阅记录判断, 以及按订单/退款/兑换记录重放到该时刻
+func (ss *SubscriptionService) SimulateSubscription(userId uint, at int64) *model.SubscriptionSimulation {
+	res := &model.SubscriptionSimulation{
+		UserId:         userId,
+		At:             at,
+		Now:            time.Now().Unix(),
+		PaymentEnabled: AllService.Payment().IsEnabled(),
+	}
+	u := AllService.UserService.InfoById(userId)
+	res.IsAdmin = u.IsAdmin != nil && *u.IsAdmin
+
 	sub := ss.GetUserSubscription(userId)
-	if sub.Id == 0 {
-		return false
+	if sub.Id > 0 {
+		res.Subscription = sub
 	}
-	now := time.Now().Unix()
-	return sub.Status == model.SubscriptionStatusActive && sub.ExpireAt > now
+	res.StoredActive, res.StoredReason = subscriptionActiveAt(sub, at)
+	res.Entitled = !res.PaymentEnabled || res.IsAdmin || res.StoredActive
+
+	ss.replaySubscription(res)
+	if res.StoredActive != res.ReplayActive {
+		res.Notes = append(res.Notes, "stored subscription and replayed history disagree at this time")
+	}
+	res.Notes = append(res.Notes, "admin grants and cancellations are not replayed, see subscription events for them")
+	return res
+}
+
+// replaySubscription 按时间顺序重放订单入账、退款和兑换记录, 计算 at 时刻的到期时间
+// 规则与 activateOrExtendSubscription/extendSubscriptionPeriod/RefundOrder 一致
+func (ss *SubscriptionService) replaySubscription(res *model.SubscriptionSimulation) {
+	type event struct {
+		entry  *model.SubscriptionTimelineEntry
+		period model.Period
+		deduct int64
+	}
+	var events []*event
+
+	var orders []*model.Order
+	DB.Where("user_id = ? AND paid_at > 0 AND paid_at <= ? AND status IN ?", res.UserId, res.At,
+		[]int{model.OrderStatusPaid, model.OrderStatusRefunded}).Preload("Plan").Find(&orders)
+	for _, o := range orders {
+		period := model.Period{Days: o.BonusDays}
+		if o.Plan != nil {
+			period = o.Plan.PlanPeriod().Add(period)
+		}
+		events = append(events, &event{
+			entry:  &model.SubscriptionTimelineEntry{At: o.PaidAt, Kind: "order_paid", RefId: o.Id, Detail: o.OutTradeNo + " +" + period.String()},
+			period: period,
+		})
+	}
+
+	var refunds []*model.Refund
+	DB.Where("user_id = ?", res.UserId).Find(&refunds)
+	for _, r := range refunds {
+		t := time.Time(r.CreatedAt).Unix()
+		if t > res.At {
+			continue
+		}
+		events = append(events, &event{
+			entry:  &model.SubscriptionTimelineEntry{At: t, Kind: "refund", RefId: r.Id, Detail: fmt.Sprintf("%s -%ds", r.Total().Display(), r.DeductSeconds)},
+			deduct: r.DeductSeconds,
+		})
+	}
+
+	var codes []*model.RedeemCode
+	DB.Where("used_by = ? AND used_at > 0 AND used_at <= ?", res.UserId, res.At).Find(&codes)
+	for _, rc := range codes {
+		period := model.Period{Days: rc.Days}
+		events = append(events, &event{
+			entry:  &model.SubscriptionTimelineEntry{At: rc.UsedAt, Kind: "redeem", RefId: rc.Id, Detail: rc.Code + " +" + period.String()},
+			period: period,
+		})
+	}
+
+	sort.SliceStable(events, func(i, j int) bool { return events[i].entry.At < events[j].entry.At })
+
+	var expireAt int64
+	res.Timeline = make([]*model.SubscriptionTimelineEntry, 0, len(events))
+	for _, e := range events {
+		t := e.entry.At
+		if e.deduct > 0 {
+			expireAt -= e.deduct
+			if expireAt < t {
+				expireAt = t
+			}
+		} else {
+			base := t
+			if expireAt > t {
+				base = expireAt
+			}
+			expireAt = e.period.AddToUnix(base)
+		}
+		e.entry.ExpireAt = expireAt
+		res.Timeline = append(res.Timeline, e.entry)
+	}
+	res.ReplayExpireAt = expireAt
+	res.ReplayActive = expireAt > res.At
 }
 
 // ListSubscriptions 获取订阅列表(分页)
@@ -408,59 +925,226 @@ func (ss *SubscriptionService) ListSubscriptions(page, pageSize uint, where func
 
 // ========== 退款处理 ==========
 
-// RefundOrder 退款订单
-func (ss *SubscriptionService) RefundOrder(orderId uint, reason string) error {
+// RefundOrder 订单退款, amount 为退款金额(分), 0 表示退还剩余全部金额
+// 部分退款按退款比例扣减订阅时长, 不再直接取消订阅; 每次退款记录一条 Refund
+func (ss *SubscriptionService) RefundOrder(orderId uint, amount int64, reason string, operatorId uint) (*model.Refund, error) {
+	lockKey := fmt.Sprintf("refund_order_%d", orderId)
+	Lock.Lock(lockKey)
+	defer Lock.UnLock(lockKey)
+
 	order := ss.GetOrderById(orderId)
 	if order.Id == 0 {
-		return errors.New("OrderNotFound")
+		return nil, errors.New("OrderNotFound")
 	}
 	if order.Status != model.OrderStatusPaid {
-		return errors.New("OrderNotPaid")
+		return nil, errors.New("OrderNotPaid")
 	}
 	if order.TradeNo == "" {
-		return errors.New("TradeNoEmpty")
+		return nil, errors.New("TradeNoEmpty")
+	}
+	refundable := order.Refundable()
+	if amount == 0 {
+		amount = refundable.Amount
+	}
+	refundMoney := model.NewMoney(amount, order.Currency)
+	if cmp, _ := refundMoney.Cmp(refundable); refundMoney.Amount <= 0 || cmp > 0 {
+		return nil, errors.New("RefundAmountInvalid")
 	}
 
 	// 调用支付网关退款
-	_, err := AllService.PaymentService.Refund(order.TradeNo, order.AmountYuan)
+	_, err := AllService.Payment().Refund(order.TradeNo, refundMoney)
 	if err != nil {
 		Logger.Error("Refund order failed: ", err)
-		return err
+		return nil, err
 	}
 
-	// 更新订单状态
-	now := time.Now().Unix()
-	if err := DB.Model(order).Updates(map[string]interface{}{
-		"status":      model.OrderStatusRefunded,
-		"refunded_at": now,
-	}).Error; err != nil {
-		return err
+	refund := &model.Refund{
+		OrderId:    order.Id,
+		UserId:     order.UserId,
+		Amount:     amount,
+		AmountYuan: refundMoney.String(),
+		Currency:   refundMoney.Currency,
+		Reason:     reason,
+		OperatorId: operatorId,
+		Source:     model.SubscriptionSourceAdmin,
+	}
+	err = ss.applyRefund(order, refund, subscriptionChange{Source: model.SubscriptionSourceAdmin, ActorId: operatorId})
+	if err != nil {
+		// 网关已退款但本地入账失败, 需人工核对
+		Logger.Error("Refund order save failed after gateway refund, order: ", order.OutTradeNo, " amount: ", refundMoney.Display(), " err: ", err)
+		return nil, err
 	}
 
-	// 取消用户订阅(标记取消并立即过期)
-	DB.Model(&model.UserSubscription{}).Where("user_id = ?", order.UserId).Updates(map[string]interface{}{
-		"status":    model.SubscriptionStatusCanceled,
-		"expire_at": now,
+	Logger.Info("Refund order success, order: ", order.OutTradeNo, " amount: ", refundMoney.Display(), " reason: ", reason)
+	AllService.EventBus.Publish(OrderRefundedEvent{Order: ss.GetOrderById(order.Id), Refund: refund})
+	return refund, nil
+}
+
+// applyRefund 退款本地入账: 更新订单退款金额/状态, 写入退款记录, 按比例扣减订阅时长并记录订阅事件
+// 管理员发起的退款与网关推送的退款共用
+func (ss *SubscriptionService) applyRefund(order *model.Order, refund *model.Refund, by subscriptionChange) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		now := time.Now().Unix()
+		refundMoney := refund.Total()
+		refunded := order.RefundedAmount + refund.Amount
+		updates := map[string]interface{}{
+			"refunded_amount": refunded,
+			"refunded_at":     now,
+		}
+		if refunded >= order.Amount {
+			updates["status"] = model.OrderStatusRefunded
+		}
+		if err := tx.Model(order).Updates(updates).Error; err != nil {
+			return err
+		}
+
+		// 按退款比例扣减该订单带来的订阅时长
+		plan := &model.SubscriptionPlan{}
+		if order.Amount > 0 && tx.Where("id = ?", order.PlanId).First(plan).Error == nil {
+			base := order.PaidAt
+			if base == 0 {
+				base = now
+			}
+			period := plan.PlanPeriod().Add(model.Period{Days: order.BonusDays})
+			refund.DeductSeconds = refundMoney.Ratio(period.Seconds(base), order.Total())
+		}
+		if err := tx.Create(refund).Error; err != nil {
+			return err
+		}
+		// 商品订单不涉及订阅, 全额退款时取消未完成的履约
+		if order.ProductId > 0 {
+			if refunded >= order.Amount && order.FulfillmentStatus != model.FulfillmentDone {
+				return tx.Model(order).Update("fulfillment_status", model.FulfillmentCancelled).Error
+			}
+			return nil
+		}
+
+		sub := &model.UserSubscription{}
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("user_id = ?", order.UserId).First(sub).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil
+			}
+			return err
+		}
+		before := *sub
+		expireAt := sub.ExpireAt - refund.DeductSeconds
+		subUpdates := map[string]interface{}{"expire_at": expireAt}
+		if sub.Status == model.SubscriptionStatusPaused {
+			// 暂停中从冻结的剩余时长扣减
+			expireAt = sub.ResumeExpireAt(now) - refund.DeductSeconds
+			subUpdates = map[string]interface{}{"pause_remaining": expireAt - now}
+		}
+		if expireAt <= now {
+			subUpdates["expire_at"] = now
+			subUpdates["status"] = model.SubscriptionStatusCanceled
+			subUpdates["pause_remaining"] = 0
+		}
+		if err := tx.Model(sub).Updates(subUpdates).Error; err != nil {
+			return err
+		}
+		by.Metadata = map[string]interface{}{
+			"refund_id":      refund.Id,
+			"amount":         refundMoney.String(),
+			"currency":       refundMoney.Currency,
+			"deduct_seconds": refund.DeductSeconds,
+			"reason":         refund.Reason,
+		}
+		if refund.GatewayRefundNo != "" {
+			by.Metadata["gateway_refund_no"] = refund.GatewayRefundNo
+		}
+		return ss.recordSubscriptionEvent(tx, model.SubscriptionEventRefunded, by, &before, sub, order.Id)
 	})
+}
 
-	Logger.Info("Refund order success, order: ", order.OutTradeNo, " reason: ", reason)
-	return nil
+// ListOrderRefunds 获取订单的退款记录
+func (ss *SubscriptionService) ListOrderRefunds(orderId uint) []*model.Refund {
+	var refunds []*model.Refund
+	DB.Where("order_id = ?", orderId).Order("id ASC").Find(&refunds)
+	return refunds
+}
+
+// ========== 统计 ==========
+
+// Dashboard 支付与订阅统计(全部使用聚合查询)
+func (ss *SubscriptionService) Dashboard() *model.PaymentDashboard {
+	now := time.Now()
+	y, m, d := now.Date()
+	today := time.Date(y, m, d, 0, 0, 0, 0, now.Location()).Unix()
+	days7 := now.AddDate(0, 0, -7).Unix()
+	days30 := now.AddDate(0, 0, -30).Unix()
+	nowUnix := now.Unix()
+
+	res := &model.PaymentDashboard{
+		RevenueToday:   ss.revenueSince(today),
+		Revenue7d:      ss.revenueSince(days7),
+		Revenue30d:     ss.revenueSince(days30),
+		OrdersByStatus: make(map[int]int64),
+	}
+
+	var statusRows []struct {
+		Status int
+		Cnt    int64
+	}
+	DB.Model(&model.Order{}).Select("status, count(*) as cnt").Group("status").Scan(&statusRows)
+	for _, r := range statusRows {
+		res.OrdersByStatus[r.Status] = r.Cnt
+	}
+
+	subs := DB.Model(&model.UserSubscription{})
+	subs.Session(&gorm.Session{}).Where("status = ? AND expire_at > ?", model.SubscriptionStatusActive, nowUnix).Count(&res.ActiveSubs)
+	// 暂停的订阅 expire_at 为暂停时间, 不计入到期和流失
+	inactive := []int{model.SubscriptionStatusCanceled, model.SubscriptionStatusPaused}
+	subs.Session(&gorm.Session{}).Where("status NOT IN (?) AND expire_at <= ?", inactive, nowUnix).Count(&res.ExpiredSubs)
+	subs.Session(&gorm.Session{}).Where("status = ?", model.SubscriptionStatusCanceled).Count(&res.CanceledSubs)
+	subs.Session(&gorm.Session{}).Where("status = ?", model.SubscriptionStatusPaused).Count(&res.PausedSubs)
+	subs.Session(&gorm.Session{}).Where("created_at >= ?", time.Unix(days7, 0)).Count(&res.NewSubscribers7d)
+	subs.Session(&gorm.Session{}).Where("created_at >= ?", time.Unix(days30, 0)).Count(&res.NewSubscribers30d)
+	subs.Session(&gorm.Session{}).Where("status <> ? AND expire_at > ? AND (expire_at <= ? OR status = ?)", model.SubscriptionStatusPaused, days30, nowUnix, model.SubscriptionStatusCanceled).Count(&res.Churned30d)
+
+	if total := res.ActiveSubs + res.Churned30d; total > 0 {
+		res.ChurnRate30d = float64(res.Churned30d) / float64(total)
+	}
+	return res
+}
+
+// revenueSince 统计指定时间以来的已支付收入(扣除退款), 按币种分组
+func (ss *SubscriptionService) revenueSince(since int64) []*model.RevenueStat {
+	stats := make([]*model.RevenueStat, 0)
+	DB.Model(&model.Order{}).
+		Select("currency, sum(amount - refunded_amount) as amount, count(*) as orders, sum(CASE WHEN status = ? THEN tax_amount ELSE 0 END) as tax", model.OrderStatusPaid).
+		Where("status IN (?) AND paid_at >= ?", []int{model.OrderStatusPaid, model.OrderStatusRefunded}, since).
+		Group("currency").Scan(&stats)
+	return stats
 }
 
 // ========== 管理员操作 ==========
 
 // GrantSubscription 管理员赠送订阅时长
-func (ss *SubscriptionService) GrantSubscription(userId, planId uint, days int) error {
+func (ss *SubscriptionService) GrantSubscription(userId, planId uint, period model.Period, operatorId uint) error {
 	plan := ss.GetPlanById(planId)
 	if plan.Id == 0 {
 		return errors.New("PlanNotFound")
 	}
 
-	now := time.Now().Unix()
-	expireAt := time.Unix(now, 0).AddDate(0, 0, days).Unix()
+	by := subscriptionChange{Source: model.SubscriptionSourceAdmin, ActorId: operatorId, Metadata: map[string]interface{}{"period": period.String()}}
+	return DB.Transaction(func(tx *gorm.DB) error {
+		return ss.extendSubscriptionPeriod(tx, userId, planId, period, time.Now().Unix(), by)
+	})
+}
 
-	sub := ss.GetUserSubscription(userId)
-	if sub.Id == 0 {
+// extendSubscriptionPeriod 按周期激活或续期订阅(事务内调用, 用于赠送/兑换)
+func (ss *SubscriptionService) extendSubscriptionPeriod(tx *gorm.DB, userId, planId uint, period model.Period, now int64, by subscriptionChange) error {
+	expireAt := period.AddToUnix(now)
+
+	sub := &model.UserSubscription{}
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("user_id = ?", userId).First(sub).Error
+	before := *sub
+	if err == gorm.ErrRecordNotFound {
+		if err := ss.guardExtension(tx, userId, planId, 0, now, expireAt, &by); err != nil {
+			return err
+		}
 		// 创建新订阅
 		sub = &model.UserSubscription{
 			UserId:   userId,
@@ -469,27 +1153,76 @@ func (ss *SubscriptionService) GrantSubscription(userId, planId uint, days int)
 			ExpireAt: expireAt,
 			Status:   model.SubscriptionStatusActive,
 		}
-		return DB.Create(sub).Error
-	} else {
-		// 续期
-		if sub.ExpireAt > now && sub.Status == model.SubscriptionStatusActive {
-			expireAt = time.Unix(sub.ExpireAt, 0).AddDate(0, 0, days).Unix()
+		if err := tx.Create(sub).Error; err != nil {
+			return err
 		}
-		return DB.Model(sub).Updates(map[string]interface{}{
-			"plan_id":   planId,
-			"expire_at": expireAt,
-			"status":    model.SubscriptionStatusActive,
-		}).Error
+		return ss.recordSubscriptionEvent(tx, model.SubscriptionEventGranted, by, &before, sub, 0)
+	} else if err != nil {
+		return err
 	}
+
+	// 续期
+	if sub.ExpireAt > now && sub.Status == model.SubscriptionStatusActive {
+		expireAt = period.AddToUnix(sub.ExpireAt)
+	} else if sub.Status == model.SubscriptionStatusPaused {
+		expireAt = period.AddToUnix(sub.ResumeExpireAt(now))
+	}
+	if err := ss.guardExtension(tx, userId, planId, 0, now, expireAt, &by); err != nil {
+		return err
+	}
+	if err := tx.Model(sub).Updates(pausedAware(sub, now, expireAt, map[string]interface{}{
+		"plan_id": planId,
+	})).Error; err != nil {
+		return err
+	}
+	return ss.recordSubscriptionEvent(tx, model.SubscriptionEventGranted, by, &before, sub, 0)
 }
 
 // CancelSubscription 管理员取消订阅
-func (ss *SubscriptionService) CancelSubscription(userId uint) error {
+func (ss *SubscriptionService) CancelSubscription(userId, operatorId uint) error {
 	now := time.Now().Unix()
-	return DB.Model(&model.UserSubscription{}).Where("user_id = ?", userId).Updates(map[string]interface{}{
-		"status":    model.SubscriptionStatusCanceled,
-		"expire_at": now,
-	}).Error
+	return DB.Transaction(func(tx *gorm.DB) error {
+		sub := &model.UserSubscription{}
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("user_id = ?", userId).First(sub).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil
+			}
+			return err
+		}
+		before := *sub
+		if err := tx.Model(sub).Updates(map[string]interface{}{
+			"status":    model.SubscriptionStatusCanceled,
+			"expire_at": now,
+		}).Error; err != nil {
+			return err
+		}
+		return ss.recordSubscriptionEvent(tx, model.SubscriptionEventCancelled,
+			subscriptionChange{Source: model.SubscriptionSourceAdmin, ActorId: operatorId}, &before, sub, 0)
+	})
+}
+
+// MarkOrderPaid 管理员手动标记订单已支付(线下收款: 银行转账、现金等)
+// 与支付回调共用入账流程, 同时激活/续期订阅
+func (ss *SubscriptionService) MarkOrderPaid(orderId uint, tradeNo string, operatorId uint, remark string) error {
+	order := ss.GetOrderById(orderId)
+	if order.Id == 0 {
+		return errors.New("OrderNotFound")
+	}
+	if order.Status != model.OrderStatusPending {
+		return errors.New("OrderNotPending")
+	}
+
+	payload := map[string]interface{}{
+		"method":      "manual",
+		"operator_id": operatorId,
+		"remark":      remark,
+	}
+	by := subscriptionChange{Source: model.SubscriptionSourceAdmin, ActorId: operatorId, Metadata: map[string]interface{}{"remark": remark}}
+	if err := ss.payOrder(context.Background(), order.OutTradeNo, tradeNo, order.AmountYuan, payload, by); err != nil {
+		return err
+	}
+	Logger.Info("Mark order paid, order: ", order.OutTradeNo, " operator: ", operatorId)
+	return nil
 }
 
 // CloseOrder 关闭待支付订单
@@ -505,9 +1238,276 @@ func (ss *SubscriptionService) CloseOrder(orderId uint) error {
 	return DB.Model(order).Update("status", model.OrderStatusClosed).Error
 }
 
-// ========== 辅助函数 ==========
+// CancelOrder 用户取消自己的待支付订单, 关闭后支付链接和扫码内容失效
+// 已上传付款凭证且待审核的线下订单不能取消
+func (ss *SubscriptionService) CancelOrder(userId uint, outTradeNo string) error {
+	order := ss.GetOrderByOutTradeNo(outTradeNo)
+	if order.Id == 0 || order.UserId != userId {
+		return errors.New("OrderNotFound")
+	}
+	if order.Status != model.OrderStatusPending {
+		return errors.New("OrderNotPending")
+	}
+	var proofs int64
+	DB.Model(&model.PaymentProof{}).Where("order_id = ? AND status = ?", order.Id, model.PaymentProofPending).Count(&proofs)
+	if proofs > 0 {
+		return errors.New("OrderProofPending")
+	}
+	res := DB.Model(&model.Order{}).Where("id = ? AND status = ?", order.Id, model.OrderStatusPending).
+		Updates(map[string]interface{}{"status": model.OrderStatusClosed, "qr_code": ""})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		// 并发入账或关闭
+		return errors.New("OrderNotPending")
+	}
+	pollQueried.Delete(order.OutTradeNo)
+	Logger.Info("Order cancelled by user, order: ", order.OutTradeNo, " user: ", userId)
+	return nil
+}
+
+// OrderExpireAfter 待支付订单超时关闭时长
+func (ss *SubscriptionService) OrderExpireAfter() time.Duration {
+	if Config.Payment.OrderExpire > 0 {
+		return Config.Payment.OrderExpire
+	}
+	return defaultOrderExpireAfter
+}
+
+// OrderExpireAfterFor 指定支付方式的待支付超时时长, 未单独配置时使用 OrderExpireAfter
+func (ss *SubscriptionService) OrderExpireAfterFor(payMethod string) time.Duration {
+	if d := Config.Payment.OrderExpireBy[payMethod]; d > 0 {
+		return d
+	}
+	return ss.OrderExpireAfter()
+}
+
+// OrderExpireAt 待支付订单的自动关闭时间(秒), 取按支付方式计算的超时时间和管理员延长保留时间中较晚者
+func (ss *SubscriptionService) OrderExpireAt(order *model.Order) int64 {
+	createdAt := time.Time(order.CreatedAt)
+	if createdAt.IsZero() {
+		return 0
+	}
+	expireAt := createdAt.Add(ss.OrderExpireAfterFor(order.PayMethod)).Unix()
+	if order.HoldUntil > expireAt {
+		expireAt = order.HoldUntil
+	}
+	return expireAt
+}
+
+// IsOrderExpired 判断待支付订单是否已超时
+func (ss *SubscriptionService) IsOrderExpired(order *model.Order) bool {
+	expireAt := ss.OrderExpireAt(order)
+	return expireAt > 0 && time.Now().Unix() > expireAt
+}
+
+// ExtendOrderHold 延长待支付订单的保留时间, 从当前关闭时间(已超时则从现在)起顺延 d, 返回新的关闭时间
+func (ss *SubscriptionService) ExtendOrderHold(orderId uint, d time.Duration, operatorId uint) (int64, error) {
+	order := ss.GetOrderById(orderId)
+	if order.Id == 0 {
+		return 0, errors.New("OrderNotFound")
+	}
+	if order.Status != model.OrderStatusPending {
+		return 0, errors.New("OrderNotPending")
+	}
+	base := ss.OrderExpireAt(order)
+	if now := time.Now().Unix(); base < now {
+		base = now
+	}
+	holdUntil := base + int64(d.Seconds())
+	res := DB.Model(&model.Order{}).
+		Where("id = ? AND status = ?", order.Id, model.OrderStatusPending).
+		Update("hold_until", holdUntil)
+	if res.Error != nil {
+		return 0, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return 0, errors.New("OrderNotPending")
+	}
+	Logger.Info("Extend order hold, order: ", order.OutTradeNo, " until: ", holdUntil, " operator: ", operatorId)
+	return holdUntil, nil
+}
+
+// CloseExpiredOrders 关闭超时未支付的订单，返回关闭数量
+// 超时时长按支付方式计算, 管理员延长保留期内的订单不关闭。
+// 关闭后订单的支付链接将无法再发起支付；若网关侧仍回调成功，HandleNotify 依旧会正常入账。
+func (ss *SubscriptionService) CloseExpiredOrders() (int64, error) {
+	now := time.Now()
+	// 已上传付款凭证、等待审核的线下转账订单不关闭
+	inReview := DB.Model(&model.PaymentProof{}).Select("order_id").Where("status = ?", model.PaymentProofPending)
+	var closed int64
+	for _, method := range []string{model.PayMethodOnline, model.PayMethodManual} {
+		before := now.Add(-ss.OrderExpireAfterFor(method))
+		res := DB.Model(&model.Order{}).
+			Where("status = ? AND pay_method = ? AND created_at < ? AND hold_until < ?", model.OrderStatusPending, method, before, now.Unix()).
+			Where("id NOT IN (?)", inReview).
+			Update("status", model.OrderStatusClosed)
+		if res.Error != nil {
+			return closed, res.Error
+		}
+		closed += res.RowsAffected
+	}
+	if closed > 0 {
+		Logger.Info("Close expired orders: ", closed)
+	}
+	return closed, nil
+}
+
+// StartOrderExpireJob 启动超时订单自动关闭任务
+func (ss *SubscriptionService) StartOrderExpireJob() {
+	go func() {
+		ticker := time.NewTicker(orderExpireCheckInterval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			if _, err := ss.CloseExpiredOrders(); err != nil {
+				Logger.Error("Close expired orders failed: ", err)
+			}
+		}
+	}()
+}
+
+// ExpiringSubscriptions 将在 days-1 到 days 天内到期的有效订阅(含套餐), 用于到期提醒
+func (ss *SubscriptionService) ExpiringSubscriptions(days int) []*model.UserSubscription {
+	now := time.Now().Unix()
+	from := now + int64(days-1)*86400
+	to := now + int64(days)*86400
+	var subs []*model.UserSubscription
+	DB.Where("status = ? AND expire_at > ? AND expire_at <= ?", model.SubscriptionStatusActive, from, to).
+		Preload("Plan").Limit(500).Find(&subs)
+	return subs
+}
+
+// LapsedSubscriptions 已到期 days-1 到 days 天且仍未续费的订阅(含套餐), 用于催缴提醒
+func (ss *SubscriptionService) LapsedSubscriptions(days int) []*model.UserSubscription {
+	now := time.Now().Unix()
+	from := now - int64(days)*86400
+	to := now - int64(days-1)*86400
+	var subs []*model.UserSubscription
+	DB.Where("status = ? AND expire_at > ? AND expire_at <= ?", model.SubscriptionStatusExpired, from, to).
+		Preload("Plan").Limit(500).Find(&subs)
+	return subs
+}
+
+// ExpireSubscriptions 将已到期的有效订阅标记为过期, 并发送 subscription.expired 事件
+func (ss *SubscriptionService) ExpireSubscriptions() (int, error) {
+	var subs []*model.UserSubscription
+	if err := DB.Where("status = ? AND expire_at <= ?", model.SubscriptionStatusActive, time.Now().Unix()).
+		Limit(500).Find(&subs).Error; err != nil {
+		return 0, err
+	}
+	n := 0
+	now := time.Now().Unix()
+	for _, sub := range subs {
+		// 预约的套餐变更在到期时应用, 免费套餐直接开始新周期
+		if sub.PendingPlanId > 0 {
+			renewed, err := ss.applyPendingPlan(sub, now)
+			if err != nil {
+				Logger.Error("Apply pending plan failed: ", err)
+				continue
+			}
+			if renewed {
+				continue
+			}
+		}
+		// 条件更新, 避免覆盖刚续期的订阅
+		res := DB.Model(&model.UserSubscription{}).
+			Where("id = ? AND status = ? AND expire_at = ?", sub.Id, model.SubscriptionStatusActive, sub.ExpireAt).
+			Update("status", model.SubscriptionStatusExpired)
+		if res.Error != nil {
+			Logger.Error("Expire subscription failed: ", res.Error)
+			continue
+		}
+		if res.RowsAffected == 0 {
+			continue
+		}
+		n++
+		before := *sub
+		sub.Status = model.SubscriptionStatusExpired
+		if err := ss.recordSubscriptionEvent(DB, model.SubscriptionEventExpired,
+			subscriptionChange{Source: model.SubscriptionSourceJob}, &before, sub, 0); err != nil {
+			Logger.Error("Record subscription event failed: ", err)
+		}
+		AllService.EventBus.Publish(SubscriptionExpiredEvent{Subscription: sub})
+	}
+	return n, nil
+}
+
+// StartSubscriptionExpireJob 启动订阅到期扫描任务
+func (ss *SubscriptionService) StartSubscriptionExpireJob() {
+	go func() {
+		ticker := time.NewTicker(subscriptionExpireCheckInterval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			if _, err := ss.ExpireSubscriptions(); err != nil {
+				Logger.Error("Expire subscriptions failed: ", err)
+			}
+			if _, err := ss.ResumeDuePaused(); err != nil {
+				Logger.Error("Resume paused subscriptions failed: ", err)
+			}
+		}
+	}()
+}
 
-// ParseMoneyToFen 解析金额字符串为分(使用字符串严格解析,避免浮点精度问题)
-func (ss *SubscriptionService) ParseMoneyToFen(money string) (int64, error) {
-	return model.YuanToFen(money)
+// ReconcileOrders 主动对账
+// 对已发起支付但仍未入账的订单向网关查询，网关确认支付成功则入账并激活订阅，
+// 用于弥补支付回调丢失的情况。返回入账的订单数量。
+func (ss *SubscriptionService) ReconcileOrders() (int, error) {
+	if !AllService.Payment().IsEnabled() {
+		return 0, nil
+	}
+
+	since := time.Now().Add(-reconcileLookback).Unix()
+	var orders []*model.Order
+	if err := DB.Where("status IN ? AND pay_submit_at > ? AND amount > 0",
+		[]int{model.OrderStatusPending, model.OrderStatusClosed}, since).
+		Order("id ASC").Find(&orders).Error; err != nil {
+		return 0, err
+	}
+
+	paid := 0
+	for _, order := range orders {
+		if ss.reconcileOrder(order, subscriptionChange{Source: model.SubscriptionSourceJob, Metadata: map[string]interface{}{"reconcile": true}}) {
+			paid++
+		}
+	}
+	return paid, nil
+}
+
+// reconcileOrder 向网关查询单个订单, 网关确认支付成功则入账, 返回是否入账
+func (ss *SubscriptionService) reconcileOrder(order *model.Order, by subscriptionChange) bool {
+	resp, err := AllService.Payment().Query(order.OutTradeNo)
+	if err != nil {
+		return false
+	}
+	// 网关未找到订单或尚未支付成功
+	if resp.Code != 1 || resp.Status != 1 || resp.OutTradeNo != order.OutTradeNo {
+		return false
+	}
+	if err := ss.payOrder(context.Background(), order.OutTradeNo, resp.TradeNo, resp.Money, resp, by); err != nil {
+		Logger.Error("Reconcile order failed, order: ", order.OutTradeNo, " err: ", err)
+		return false
+	}
+	Logger.Info("Reconcile order paid, order: ", order.OutTradeNo)
+	return true
+}
+
+// StartReconcileJob 启动主动对账任务
+func (ss *SubscriptionService) StartReconcileJob() {
+	interval := Config.Payment.ReconcileInterval
+	if interval <= 0 {
+		interval = defaultReconcileInterval
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			if _, err := ss.ReconcileOrders(); err != nil {
+				Logger.Error("Reconcile orders failed: ", err)
+			}
+		}
+	}()
 }
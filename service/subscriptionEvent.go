@@ -0,0 +1,66 @@
+package service
+
+import (
+	"encoding/json"
+
+	"github.com/lejianwen/rustdesk-api/v2/model"
+	"github.com/lejianwen/rustdesk-api/v2/model/custom_types"
+	"gorm.io/gorm"
+)
+
+// subscriptionChange 订阅变更的来源信息, 由调用方传入并写入变更记录
+type subscriptionChange struct {
+	Source   string
+	ActorId  uint
+	Metadata map[string]interface{}
+}
+
+// recordSubscriptionEvent 写入订阅变更记录(与变更在同一事务内调用), before 为变更前的订阅, 新建时为空订阅
+func (ss *SubscriptionService) recordSubscriptionEvent(tx *gorm.DB, typ string, by subscriptionChange, before, after *model.UserSubscription, orderId uint) error {
+	meta := by.Metadata
+	if meta == nil {
+		meta = map[string]interface{}{}
+	}
+	data, _ := json.Marshal(meta)
+	// 推送只通知前端重新拉取, 事务回滚时多推送一次不影响状态
+	AllService.PushService.SubscriptionChanged(typ, after)
+	return tx.Create(&model.SubscriptionEvent{
+		UserId:         after.UserId,
+		SubscriptionId: after.Id,
+		Type:           typ,
+		Source:         by.Source,
+		ActorId:        by.ActorId,
+		PlanId:         after.PlanId,
+		OrderId:        orderId,
+		StatusBefore:   before.Status,
+		StatusAfter:    after.Status,
+		ExpireBefore:   before.ExpireAt,
+		ExpireAfter:    after.ExpireAt,
+		Metadata:       custom_types.AutoJson(data),
+	}).Error
+}
+
+// ListSubscriptionEvents 订阅变更记录, 按时间倒序
+func (ss *SubscriptionService) ListSubscriptionEvents(page, pageSize uint, where func(tx *gorm.DB)) (res *model.SubscriptionEventList) {
+	res = &model.SubscriptionEventList{}
+	res.Page = int64(page)
+	res.PageSize = int64(pageSize)
+	tx := DB.Model(&model.SubscriptionEvent{})
+	if where != nil {
+		where(tx)
+	}
+	tx.Count(&res.Total)
+	tx.Scopes(Paginate(page, pageSize))
+	tx.Order("id DESC").Find(&res.SubscriptionEvents)
+	return
+}
+
+// withMetadata 追加一个元数据字段
+func withMetadata(meta map[string]interface{}, key string, value interface{}) map[string]interface{} {
+	m := make(map[string]interface{}, len(meta)+1)
+	for k, v := range meta {
+		m[k] = v
+	}
+	m[key] = value
+	return m
+}
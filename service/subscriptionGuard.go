@@ -0,0 +1,123 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lejianwen/rustdesk-api/v2/config"
+	"github.com/lejianwen/rustdesk-api/v2/model"
+	"gorm.io/gorm"
+)
+
+// 续期异常防护设置
+const (
+	SettingExtensionMaxHorizon   = "subscription.extension_guard.max_horizon"
+	SettingExtensionMaxPerMinute = "subscription.extension_guard.max_per_minute"
+	SettingExtensionAction       = "subscription.extension_guard.action"
+)
+
+// 续期异常的处理方式
+const (
+	ExtensionGuardReject = "reject"
+	ExtensionGuardFlag   = "flag"
+)
+
+const (
+	defaultExtensionMaxHorizon   = 10 * 365 * 24 * time.Hour
+	defaultExtensionMaxPerMinute = 10
+)
+
+// registerExtensionGuardSettings 注册续期异常防护设置, 默认值取自 payment.extension-guard
+func (s *SystemSettingService) registerExtensionGuardSettings(cfg config.ExtensionGuard) {
+	maxHorizon := cfg.MaxHorizon
+	if maxHorizon <= 0 {
+		maxHorizon = defaultExtensionMaxHorizon
+	}
+	maxPerMinute := int64(cfg.MaxPerMinute)
+	if maxPerMinute <= 0 {
+		maxPerMinute = defaultExtensionMaxPerMinute
+	}
+	action := cfg.Action
+	if action != ExtensionGuardFlag {
+		action = ExtensionGuardReject
+	}
+	zero := float64(0)
+	s.RegisterSetting(&model.SettingDefinition{
+		Key:         SettingExtensionMaxHorizon,
+		Type:        model.SettingTypeDuration,
+		Group:       "subscription",
+		Description: "Maximum distance of a subscription expiry from now, 0 disables the check",
+		Default:     maxHorizon,
+		Min:         &zero,
+	})
+	s.RegisterSetting(&model.SettingDefinition{
+		Key:         SettingExtensionMaxPerMinute,
+		Type:        model.SettingTypeInt,
+		Group:       "subscription",
+		Description: "Maximum subscription activations/extensions per user per minute, 0 disables the check",
+		Default:     maxPerMinute,
+		Min:         &zero,
+	})
+	s.RegisterSetting(&model.SettingDefinition{
+		Key:         SettingExtensionAction,
+		Type:        model.SettingTypeString,
+		Group:       "subscription",
+		Description: "Action on an anomalous extension: reject, or flag and allow",
+		Default:     action,
+		Options:     []string{ExtensionGuardReject, ExtensionGuardFlag},
+	})
+}
+
+// detectExtensionAnomaly 判断一次开通/续期是否异常, recent 为最近一分钟内的次数(含本次), 上限为 0 表示不限制
+func detectExtensionAnomaly(now, expireAt, recent int64, maxHorizon time.Duration, maxPerMinute int64) string {
+	if maxHorizon > 0 && expireAt > now+int64(maxHorizon/time.Second) {
+		return model.SubscriptionAnomalyHorizon
+	}
+	if maxPerMinute > 0 && recent > maxPerMinute {
+		return model.SubscriptionAnomalyRate
+	}
+	return ""
+}
+
+// guardExtension 在写入新的到期时间前检查(事务内调用)
+// 异常时记录指标并异步告警; 处理方式为 reject 时返回错误使事务回滚, flag 时在变更记录中标记后放行
+func (ss *SubscriptionService) guardExtension(tx *gorm.DB, userId, planId, orderId uint, now, expireAt int64, by *subscriptionChange) error {
+	settings := AllService.SystemSettingService
+	var recent int64
+	tx.Model(&model.SubscriptionEvent{}).
+		Where("user_id = ? AND type IN ? AND created_at >= ?", userId,
+			[]string{model.SubscriptionEventCreated, model.SubscriptionEventRenewed, model.SubscriptionEventGranted},
+			time.Unix(now, 0).Add(-time.Minute)).
+		Count(&recent)
+	recent++
+	kind := detectExtensionAnomaly(now, expireAt, recent,
+		settings.SettingDuration(SettingExtensionMaxHorizon), settings.SettingInt(SettingExtensionMaxPerMinute))
+	if kind == "" {
+		return nil
+	}
+	a := &model.SubscriptionAnomaly{
+		Kind:       kind,
+		Action:     settings.SettingString(SettingExtensionAction),
+		UserId:     userId,
+		PlanId:     planId,
+		OrderId:    orderId,
+		Source:     by.Source,
+		ExpireAt:   expireAt,
+		Recent:     recent,
+		DetectedAt: now,
+	}
+	if a.Action != ExtensionGuardFlag {
+		a.Action = ExtensionGuardReject
+	}
+	MetricSubscriptionAnomaly.Inc(a.Kind, a.Action)
+	Logger.Warn(fmt.Sprintf("Subscription extension anomaly, kind: %s action: %s user: %d plan: %d order: %d source: %s expire_at: %d recent: %d",
+		a.Kind, a.Action, a.UserId, a.PlanId, a.OrderId, a.Source, a.ExpireAt, a.Recent))
+	// 告警在独立协程中发送, 不依赖当前事务是否提交
+	go AllService.EventBus.Publish(SubscriptionAnomalyEvent{Anomaly: a})
+	if a.Action == ExtensionGuardReject {
+		return errors.New("SubscriptionExtensionRejected")
+	}
+	by.Metadata = withMetadata(by.Metadata, "anomaly", a.Kind)
+	return nil
+}
@@ -0,0 +1,36 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lejianwen/rustdesk-api/v2/model"
+)
+
+func TestDetectExtensionAnomaly(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC).Unix()
+	year := int64(365 * 86400)
+	horizon := 10 * 365 * 24 * time.Hour
+	cases := []struct {
+		name     string
+		expireAt int64
+		recent   int64
+		horizon  time.Duration
+		perMin   int64
+		want     string
+	}{
+		{"normal", now + year, 1, horizon, 10, ""},
+		{"at horizon", now + 10*year, 1, horizon, 10, ""},
+		{"beyond horizon", now + 10*year + 1, 1, horizon, 10, model.SubscriptionAnomalyHorizon},
+		{"horizon disabled", now + 100*year, 1, 0, 10, ""},
+		{"at rate limit", now + year, 10, horizon, 10, ""},
+		{"over rate limit", now + year, 11, horizon, 10, model.SubscriptionAnomalyRate},
+		{"rate disabled", now + year, 1000, horizon, 0, ""},
+		{"horizon first", now + 100*year, 1000, horizon, 10, model.SubscriptionAnomalyHorizon},
+	}
+	for _, c := range cases {
+		if got := detectExtensionAnomaly(now, c.expireAt, c.recent, c.horizon, c.perMin); got != c.want {
+			t.Errorf("%s: got %q, want %q", c.name, got, c.want)
+		}
+	}
+}
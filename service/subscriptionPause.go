@@ -0,0 +1,206 @@
+package service
+
+import (
+	"errors"
+	"time"
+
+	"github.com/lejianwen/rustdesk-api/v2/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 用户自助暂停设置, 管理员暂停不受限制
+const (
+	SettingPauseUserEnable = "subscription.pause.user_enable"
+	SettingPauseMaxDays    = "subscription.pause.max_days"
+)
+
+const (
+	PauseSourceAdmin = "admin"
+	PauseSourceUser  = "user"
+)
+
+// registerPauseSettings 注册订阅暂停设置
+func (s *SystemSettingService) registerPauseSettings() {
+	one := float64(1)
+	s.RegisterSetting(&model.SettingDefinition{
+		Key:         SettingPauseUserEnable,
+		Type:        model.SettingTypeBool,
+		Group:       "subscription",
+		Description: "Allow users to pause their own subscription",
+		Default:     false,
+	})
+	s.RegisterSetting(&model.SettingDefinition{
+		Key:         SettingPauseMaxDays,
+		Type:        model.SettingTypeInt,
+		Group:       "subscription",
+		Description: "Maximum days of a user-initiated pause, the subscription resumes automatically afterwards",
+		Default:     int64(30),
+		Min:         &one,
+	})
+}
+
+// pausedAware 续期订阅的更新字段: 暂停中的订阅保持暂停, 新的时长计入冻结的剩余时长, 否则激活并更新到期时间
+func pausedAware(sub *model.UserSubscription, now, expireAt int64, updates map[string]interface{}) map[string]interface{} {
+	if sub.Status == model.SubscriptionStatusPaused {
+		updates["pause_remaining"] = expireAt - now
+		return updates
+	}
+	updates["expire_at"] = expireAt
+	updates["status"] = model.SubscriptionStatusActive
+	return updates
+}
+
+// pauseUntil 计算自动恢复时间; days 为 0 表示不自动恢复(仅管理员), 用户暂停必须在 maxDays 以内
+func pauseUntil(now int64, days int, maxDays int64, source string) (int64, error) {
+	if days < 0 {
+		return 0, errors.New("ParamsError")
+	}
+	if source == PauseSourceUser && (days == 0 || int64(days) > maxDays) {
+		return 0, errors.New("PauseTooLong")
+	}
+	if days == 0 {
+		return 0, nil
+	}
+	return now + int64(days)*86400, nil
+}
+
+// PauseSubscription 管理员暂停订阅, days 为 0 时需手动恢复
+func (ss *SubscriptionService) PauseSubscription(userId uint, days int, reason string, operatorId uint) error {
+	return ss.pause(userId, days, reason, subscriptionChange{Source: model.SubscriptionSourceAdmin, ActorId: operatorId})
+}
+
+// UserPauseSubscription 用户自助暂停订阅, 需开启设置且不超过最长暂停天数, 到期自动恢复
+func (ss *SubscriptionService) UserPauseSubscription(userId uint, days int, reason string) error {
+	return ss.pause(userId, days, reason, subscriptionChange{Source: model.SubscriptionSourceUser, ActorId: userId})
+}
+
+// pause 暂停有效订阅, 冻结剩余时长; 暂停期间订阅视为无效, 续费的时长累加到冻结的剩余时长
+func (ss *SubscriptionService) pause(userId uint, days int, reason string, by subscriptionChange) error {
+	source := PauseSourceAdmin
+	if by.Source == model.SubscriptionSourceUser {
+		source = PauseSourceUser
+		if !AllService.SystemSettingService.SettingBool(SettingPauseUserEnable) {
+			return errors.New("SubscriptionPauseDisabled")
+		}
+	}
+	now := time.Now().Unix()
+	until, err := pauseUntil(now, days, AllService.SystemSettingService.SettingInt(SettingPauseMaxDays), source)
+	if err != nil {
+		return err
+	}
+	err = DB.Transaction(func(tx *gorm.DB) error {
+		sub := &model.UserSubscription{}
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("user_id = ?", userId).First(sub).Error; err != nil {
+			return errors.New("SubscriptionNotActive")
+		}
+		if sub.Status != model.SubscriptionStatusActive || sub.ExpireAt <= now {
+			return errors.New("SubscriptionNotActive")
+		}
+		before := *sub
+		if err := tx.Model(sub).Updates(map[string]interface{}{
+			"status":          model.SubscriptionStatusPaused,
+			"expire_at":       now,
+			"paused_at":       now,
+			"pause_until":     until,
+			"pause_remaining": before.ExpireAt - now,
+			"pause_source":    source,
+			"pause_reason":    reason,
+		}).Error; err != nil {
+			return err
+		}
+		by.Metadata = withMetadata(by.Metadata, "reason", reason)
+		return ss.recordSubscriptionEvent(tx, model.SubscriptionEventPaused, by, &before, sub, 0)
+	})
+	if err != nil {
+		return err
+	}
+	AllService.EntitlementService.Invalidate(userId)
+	return nil
+}
+
+// ResumeSubscription 管理员恢复暂停的订阅
+func (ss *SubscriptionService) ResumeSubscription(userId, operatorId uint) error {
+	return ss.resumeUser(userId, subscriptionChange{Source: model.SubscriptionSourceAdmin, ActorId: operatorId})
+}
+
+// UserResumeSubscription 用户恢复自己发起的暂停
+func (ss *SubscriptionService) UserResumeSubscription(userId uint) error {
+	return ss.resumeUser(userId, subscriptionChange{Source: model.SubscriptionSourceUser, ActorId: userId})
+}
+
+// resumeUser 恢复暂停的订阅, 到期时间为恢复时间加冻结的剩余时长
+func (ss *SubscriptionService) resumeUser(userId uint, by subscriptionChange) error {
+	now := time.Now().Unix()
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		sub := &model.UserSubscription{}
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("user_id = ?", userId).First(sub).Error; err != nil {
+			return errors.New("SubscriptionNotPaused")
+		}
+		if sub.Status != model.SubscriptionStatusPaused {
+			return errors.New("SubscriptionNotPaused")
+		}
+		if by.Source == model.SubscriptionSourceUser && sub.PauseSource != PauseSourceUser {
+			return errors.New("SubscriptionPausedByAdmin")
+		}
+		return ss.resume(tx, sub, now, by)
+	})
+	if err != nil {
+		return err
+	}
+	AllService.EntitlementService.Invalidate(userId)
+	return nil
+}
+
+func (ss *SubscriptionService) resume(tx *gorm.DB, sub *model.UserSubscription, now int64, by subscriptionChange) error {
+	before := *sub
+	if err := tx.Model(sub).Updates(map[string]interface{}{
+		"status":          model.SubscriptionStatusActive,
+		"expire_at":       sub.ResumeExpireAt(now),
+		"paused_at":       0,
+		"pause_until":     0,
+		"pause_remaining": 0,
+		"pause_source":    "",
+		"pause_reason":    "",
+	}).Error; err != nil {
+		return err
+	}
+	by.Metadata = withMetadata(by.Metadata, "paused_seconds", now-before.PausedAt)
+	return ss.recordSubscriptionEvent(tx, model.SubscriptionEventResumed, by, &before, sub, 0)
+}
+
+var errSkipResume = errors.New("skip resume")
+
+// ResumeDuePaused 恢复已到自动恢复时间的暂停订阅, 返回恢复的数量
+func (ss *SubscriptionService) ResumeDuePaused() (int, error) {
+	now := time.Now().Unix()
+	var subs []*model.UserSubscription
+	if err := DB.Where("status = ? AND pause_until > 0 AND pause_until <= ?", model.SubscriptionStatusPaused, now).
+		Limit(500).Find(&subs).Error; err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, s := range subs {
+		err := DB.Transaction(func(tx *gorm.DB) error {
+			sub := &model.UserSubscription{}
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", s.Id).First(sub).Error; err != nil {
+				return err
+			}
+			// 加锁后复查, 避免与手动恢复并发
+			if sub.Status != model.SubscriptionStatusPaused || sub.PauseUntil == 0 || sub.PauseUntil > now {
+				return errSkipResume
+			}
+			return ss.resume(tx, sub, now, subscriptionChange{Source: model.SubscriptionSourceJob})
+		})
+		if errors.Is(err, errSkipResume) {
+			continue
+		}
+		if err != nil {
+			Logger.Error("Resume paused subscription failed, id: ", s.Id, " err: ", err)
+			continue
+		}
+		n++
+		AllService.EntitlementService.Invalidate(s.UserId)
+	}
+	return n, nil
+}
@@ -0,0 +1,40 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/lejianwen/rustdesk-api/v2/model"
+)
+
+func TestPauseUntil(t *testing.T) {
+	now := int64(1000)
+	if until, err := pauseUntil(now, 0, 30, PauseSourceAdmin); err != nil || until != 0 {
+		t.Errorf("admin indefinite: got %d, %v", until, err)
+	}
+	if until, err := pauseUntil(now, 2, 30, PauseSourceUser); err != nil || until != now+2*86400 {
+		t.Errorf("user 2 days: got %d, %v", until, err)
+	}
+	if _, err := pauseUntil(now, 0, 30, PauseSourceUser); err == nil {
+		t.Error("user indefinite pause should fail")
+	}
+	if _, err := pauseUntil(now, 31, 30, PauseSourceUser); err == nil {
+		t.Error("user pause over max should fail")
+	}
+	if _, err := pauseUntil(now, -1, 30, PauseSourceAdmin); err == nil {
+		t.Error("negative days should fail")
+	}
+}
+
+func TestPausedAware(t *testing.T) {
+	now, expireAt := int64(1000), int64(5000)
+	paused := &model.UserSubscription{Status: model.SubscriptionStatusPaused}
+	u := pausedAware(paused, now, expireAt, map[string]interface{}{})
+	if u["pause_remaining"] != int64(4000) || u["expire_at"] != nil || u["status"] != nil {
+		t.Errorf("paused updates: %v", u)
+	}
+	active := &model.UserSubscription{Status: model.SubscriptionStatusActive}
+	u = pausedAware(active, now, expireAt, map[string]interface{}{})
+	if u["expire_at"] != expireAt || u["status"] != model.SubscriptionStatusActive {
+		t.Errorf("active updates: %v", u)
+	}
+}
@@ -0,0 +1,130 @@
+package service
+
+import (
+	"errors"
+	"time"
+
+	"github.com/lejianwen/rustdesk-api/v2/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// periodDays 周期的平均天数, 年按 365.25 天、月按其 1/12 折算, 结果与下单日期无关
+func periodDays(p model.Period) float64 {
+	return float64(p.Years)*365.25 + float64(p.Months)*365.25/12 + float64(p.Days)
+}
+
+// isDowngrade 预约变更只允许切换到每天价格不高于当前套餐的其他套餐, 升级应直接购买
+// Price 均为基础币种, 按周期平均天数折算后比较, 避免月付套餐切换到总价更低但更贵的周付套餐
+func isDowngrade(cur, next *model.SubscriptionPlan) bool {
+	if next.Id == cur.Id {
+		return false
+	}
+	curDays, nextDays := periodDays(cur.PlanPeriod()), periodDays(next.PlanPeriod())
+	if curDays <= 0 || nextDays <= 0 {
+		return next.Price <= cur.Price
+	}
+	return float64(next.Price)*curDays <= float64(cur.Price)*nextDays
+}
+
+// SchedulePlanChange 用户预约在当前周期结束时切换到 planId(降级), 到期任务自动应用
+func (ss *SubscriptionService) SchedulePlanChange(userId, planId uint) error {
+	plan := ss.GetPlanById(planId)
+	if plan.Id == 0 {
+		return errors.New("PlanNotFound")
+	}
+	if plan.Status != model.COMMON_STATUS_ENABLE {
+		return errors.New("PlanDisabled")
+	}
+	if err := ss.CheckPlanRestrictions(DB, userId, plan); err != nil {
+		return err
+	}
+	return ss.setPendingPlan(userId, plan)
+}
+
+// CancelPlanChange 取消预约的套餐变更
+func (ss *SubscriptionService) CancelPlanChange(userId uint) error {
+	return ss.setPendingPlan(userId, nil)
+}
+
+func (ss *SubscriptionService) setPendingPlan(userId uint, plan *model.SubscriptionPlan) error {
+	now := time.Now().Unix()
+	return DB.Transaction(func(tx *gorm.DB) error {
+		sub := &model.UserSubscription{}
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("user_id = ?", userId).First(sub).Error; err != nil {
+			return errors.New("SubscriptionNotActive")
+		}
+		active := sub.Status == model.SubscriptionStatusActive && sub.ExpireAt > now
+		if !active && sub.Status != model.SubscriptionStatusPaused {
+			return errors.New("SubscriptionNotActive")
+		}
+		var pendingId uint
+		if plan == nil {
+			if sub.PendingPlanId == 0 {
+				return errors.New("NoPendingPlanChange")
+			}
+		} else {
+			cur := &model.SubscriptionPlan{}
+			tx.Where("id = ?", sub.PlanId).First(cur)
+			if !isDowngrade(cur, plan) {
+				return errors.New("PlanChangeNotDowngrade")
+			}
+			pendingId = plan.Id
+		}
+		before := *sub
+		if err := tx.Model(sub).Update("pending_plan_id", pendingId).Error; err != nil {
+			return err
+		}
+		by := subscriptionChange{Source: model.SubscriptionSourceUser, ActorId: userId, Metadata: map[string]interface{}{
+			"pending_plan_id": pendingId,
+		}}
+		return ss.recordSubscriptionEvent(tx, model.SubscriptionEventScheduled, by, &before, sub, 0)
+	})
+}
+
+// applyPendingPlan 到期时切换到预约的套餐, 返回 true 表示已切换到免费套餐并开始新周期, 无需再置为过期
+// 付费套餐仅切换套餐, 订阅按原流程过期, 由用户按新套餐续费
+func (ss *SubscriptionService) applyPendingPlan(sub *model.UserSubscription, now int64) (bool, error) {
+	renewed := false
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		cur := &model.UserSubscription{}
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", sub.Id).First(cur).Error; err != nil {
+			return err
+		}
+		// 已被续期或变更, 跳过
+		if cur.Status != model.SubscriptionStatusActive || cur.ExpireAt != sub.ExpireAt || cur.PendingPlanId == 0 {
+			return nil
+		}
+		before := *cur
+		plan := &model.SubscriptionPlan{}
+		tx.Where("id = ?", cur.PendingPlanId).First(plan)
+		if plan.Id == 0 || plan.Status != model.COMMON_STATUS_ENABLE {
+			// 预约的套餐已下架, 放弃变更
+			return tx.Model(cur).Update("pending_plan_id", 0).Error
+		}
+		updates := map[string]interface{}{
+			"plan_id":         plan.Id,
+			"pending_plan_id": 0,
+		}
+		if plan.Price == 0 {
+			updates["start_at"] = now
+			updates["expire_at"] = plan.PlanPeriod().AddToUnix(now)
+			renewed = true
+		}
+		if err := tx.Model(cur).Updates(updates).Error; err != nil {
+			return err
+		}
+		sub.PlanId = plan.Id
+		by := subscriptionChange{Source: model.SubscriptionSourceJob, Metadata: map[string]interface{}{
+			"from_plan_id": before.PlanId,
+		}}
+		return ss.recordSubscriptionEvent(tx, model.SubscriptionEventChanged, by, &before, cur, 0)
+	})
+	if err != nil {
+		return false, err
+	}
+	if renewed {
+		AllService.EntitlementService.Invalidate(sub.UserId)
+	}
+	return renewed, nil
+}
@@ -0,0 +1,36 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/lejianwen/rustdesk-api/v2/model"
+)
+
+func TestIsDowngrade(t *testing.T) {
+	cur := &model.SubscriptionPlan{IdModel: model.IdModel{Id: 1}, Price: 1000, PeriodUnit: model.PeriodUnitMonth, PeriodCount: 1}
+	plan := func(price int64, period string) model.SubscriptionPlan {
+		return model.SubscriptionPlan{IdModel: model.IdModel{Id: 2}, Price: price, Period: period}
+	}
+	cases := []struct {
+		next model.SubscriptionPlan
+		want bool
+	}{
+		{plan(500, "P1M"), true},
+		{plan(0, "P1M"), true},
+		{plan(1000, "P1M"), true},
+		{plan(2000, "P1M"), false},
+		{plan(9000, "P1Y"), true},   // 年付总价更高, 但每月更便宜
+		{plan(300, "P7D"), false},   // 周付总价更低, 但每月更贵
+		{plan(1000, "P31D"), true},  // 同价, 周期更长
+		{plan(1000, "P30D"), false}, // 同价, 周期更短
+		{plan(12000, "P1Y"), true},  // 与 P12M 等价
+		{plan(3000, "P3M"), true},   // 同价
+		{plan(3001, "P3M"), false},
+		{model.SubscriptionPlan{IdModel: model.IdModel{Id: 1}, Price: 1000, Period: "P1M"}, false},
+	}
+	for _, c := range cases {
+		if got := isDowngrade(cur, &c.next); got != c.want {
+			t.Errorf("next %d/%s: got %v", c.next.Price, c.next.Period, got)
+		}
+	}
+}
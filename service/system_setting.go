@@ -5,12 +5,19 @@ import (
 	"sync"
 	"time"
 
+	"github.com/lejianwen/rustdesk-api/v2/lib/envelope"
 	"github.com/lejianwen/rustdesk-api/v2/model"
 )
 
 type SystemSettingService struct {
 	cache     map[string]*cacheItem
 	cacheLock sync.RWMutex
+
+	keyringOnce sync.Once
+	keyring     *envelope.Keyring // 未配置 encryption.key 时为 nil
+
+	defs     map[string]*model.SettingDefinition // 已注册的通用设置
+	defsLock sync.RWMutex
 }
 
 type cacheItem struct {
@@ -37,35 +44,40 @@ func (s *SystemSettingService) Get(key string) string {
 	if err := DB.Where("key = ?", key).First(&setting).Error; err != nil {
 		return ""
 	}
+	value := s.decryptSecrets(key, setting.Value)
 
-	// 写入缓存
+	// 写入缓存(解密后的值)
 	s.cacheLock.Lock()
 	if s.cache == nil {
 		s.cache = make(map[string]*cacheItem)
 	}
 	s.cache[key] = &cacheItem{
-		value:     setting.Value,
+		value:     value,
 		expiredAt: time.Now().Add(cacheTTL),
 	}
 	s.cacheLock.Unlock()
 
-	return setting.Value
+	return value
 }
 
-// Set 设置值
+// Set 设置值, 密钥类字段在配置了 encryption.key 时加密保存
 func (s *SystemSettingService) Set(key, value string) error {
+	stored, err := s.encryptSecrets(key, value)
+	if err != nil {
+		return err
+	}
 	var setting model.SystemSetting
-	err := DB.Where("key = ?", key).First(&setting).Error
+	err = DB.Where("key = ?", key).First(&setting).Error
 	if err != nil {
 		// 不存在则创建
 		setting = model.SystemSetting{
 			Key:   key,
-			Value: value,
+			Value: stored,
 		}
 		err = DB.Create(&setting).Error
 	} else {
 		// 存在则更新
-		err = DB.Model(&setting).Update("value", value).Error
+		err = DB.Model(&setting).Update("value", stored).Error
 	}
 
 	if err != nil {
@@ -115,13 +127,16 @@ func (s *SystemSettingService) GetPaymentConfig() *model.PaymentConfig {
 	if value == "" {
 		// 返回默认配置（从配置文件读取作为fallback）
 		return &model.PaymentConfig{
-			Enable:    Config.Payment.EasyPay.Enable,
-			BaseURL:   Config.Payment.EasyPay.BaseURL,
-			Pid:       Config.Payment.EasyPay.Pid,
-			Key:       Config.Payment.EasyPay.Key,
-			NotifyURL: Config.Payment.EasyPay.NotifyURL,
-			ReturnURL: Config.Payment.EasyPay.ReturnURL,
-			Timeout:   int(Config.Payment.EasyPay.Timeout.Seconds()),
+			Enable:      Config.Payment.EasyPay.Enable,
+			BaseURL:     Config.Payment.EasyPay.BaseURL,
+			Pid:         Config.Payment.EasyPay.Pid,
+			Key:         Config.Payment.EasyPay.Key,
+			NotifyURL:   Config.Payment.EasyPay.NotifyURL,
+			ReturnURL:   Config.Payment.EasyPay.ReturnURL,
+			RedirectURL: Config.Payment.EasyPay.RedirectURL,
+			Timeout:     int(Config.Payment.EasyPay.Timeout.Seconds()),
+			PayTypes:    Config.Payment.EasyPay.PayTypes,
+			Sandbox:     Config.Payment.EasyPay.Sandbox,
 		}
 	}
 
@@ -141,3 +156,61 @@ func (s *SystemSettingService) SetPaymentConfig(cfg *model.PaymentConfig) error
 	}
 	return s.Set(model.SettingKeyPaymentConfig, string(data))
 }
+
+// GetEmailConfig 获取邮件配置, 未配置时返回禁用状态的默认配置
+func (s *SystemSettingService) GetEmailConfig() *model.EmailConfig {
+	cfg := &model.EmailConfig{Port: 587, Encryption: model.EmailEncryptionStartTLS}
+	value := s.Get(model.SettingKeyEmailConfig)
+	if value == "" {
+		return cfg
+	}
+	if err := json.Unmarshal([]byte(value), cfg); err != nil {
+		Logger.Error("Parse email config failed: ", err)
+		return &model.EmailConfig{}
+	}
+	return cfg
+}
+
+// SetEmailConfig 保存邮件配置
+func (s *SystemSettingService) SetEmailConfig(cfg *model.EmailConfig) error {
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		return err
+	}
+	return s.Set(model.SettingKeyEmailConfig, string(data))
+}
+
+// GetStorageConfig 获取存储配置, 未保存时使用配置文件
+func (s *SystemSettingService) GetStorageConfig() *model.StorageConfig {
+	value := s.Get(model.SettingKeyStorageConfig)
+	if value == "" {
+		c := Config.Storage
+		return &model.StorageConfig{
+			Driver:      c.Driver,
+			LocalDir:    c.LocalDir,
+			UrlExpire:   int(c.UrlExpire.Seconds()),
+			S3Endpoint:  c.S3.Endpoint,
+			S3Region:    c.S3.Region,
+			S3Bucket:    c.S3.Bucket,
+			S3AccessKey: c.S3.AccessKey,
+			S3SecretKey: c.S3.SecretKey,
+			S3PathStyle: c.S3.PathStyle,
+			S3Prefix:    c.S3.Prefix,
+		}
+	}
+	var cfg model.StorageConfig
+	if err := json.Unmarshal([]byte(value), &cfg); err != nil {
+		Logger.Error("Parse storage config failed: ", err)
+		return &model.StorageConfig{}
+	}
+	return &cfg
+}
+
+// SetStorageConfig 保存存储配置
+func (s *SystemSettingService) SetStorageConfig(cfg *model.StorageConfig) error {
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		return err
+	}
+	return s.Set(model.SettingKeyStorageConfig, string(data))
+}
@@ -0,0 +1,157 @@
+package service
+
+import (
+	"encoding/json"
+
+	"github.com/lejianwen/rustdesk-api/v2/lib/envelope"
+	"github.com/lejianwen/rustdesk-api/v2/model"
+)
+
+// secretSettingFields 需要加密保存的设置: JSON 设置为其中的字段名, 为空表示整个值
+var secretSettingFields = map[string][]string{
+	model.SettingKeyPaymentConfig:  {"key"},
+	model.SettingKeyEmailConfig:    {"password"},
+	model.SettingKeyStorageConfig:  {"s3_secret_key"},
+	model.SettingKeyStorageSignKey: nil,
+}
+
+// secretFields 设置的密钥字段, 包括注册为 Secret 的通用设置(整个值加密)
+func (s *SystemSettingService) secretFields(key string) ([]string, bool) {
+	if fields, ok := secretSettingFields[key]; ok {
+		return fields, true
+	}
+	if def := s.Definition(key); def != nil && def.Secret {
+		return nil, true
+	}
+	return nil, false
+}
+
+// secretKeys 全部需要加密保存的设置 key
+func (s *SystemSettingService) secretKeys() []string {
+	keys := make([]string, 0, len(secretSettingFields))
+	for key := range secretSettingFields {
+		keys = append(keys, key)
+	}
+	for _, def := range s.Definitions() {
+		if def.Secret {
+			keys = append(keys, def.Key)
+		}
+	}
+	return keys
+}
+
+func (s *SystemSettingService) keys() *envelope.Keyring {
+	s.keyringOnce.Do(func() {
+		s.keyring = envelope.NewKeyring(Config.Encryption.Key, Config.Encryption.OldKeys...)
+	})
+	return s.keyring
+}
+
+// encryptSecrets 加密设置中的密钥字段, 未配置主密钥或不是密钥类设置时原样返回
+func (s *SystemSettingService) encryptSecrets(key, value string) (string, error) {
+	fields, ok := s.secretFields(key)
+	kr := s.keys()
+	if !ok || kr == nil || value == "" {
+		return value, nil
+	}
+	return s.transformSecrets(fields, value, func(v string) (string, error) {
+		if envelope.IsEncrypted(v) && !kr.NeedsRotate(v) {
+			return v, nil
+		}
+		if envelope.IsEncrypted(v) {
+			plain, err := kr.Decrypt(v)
+			if err != nil {
+				return "", err
+			}
+			v = string(plain)
+		}
+		return kr.Encrypt([]byte(v))
+	})
+}
+
+// decryptSecrets 解密设置中的密钥字段, 明文(加密启用前保存的)原样返回
+// 无法解密时(主密钥缺失或错误)该字段置空, 避免把密文当作密钥使用
+func (s *SystemSettingService) decryptSecrets(key, value string) string {
+	fields, ok := s.secretFields(key)
+	if !ok || value == "" {
+		return value
+	}
+	res, err := s.transformSecrets(fields, value, func(v string) (string, error) {
+		if !envelope.IsEncrypted(v) {
+			return v, nil
+		}
+		plain, err := s.keys().Decrypt(v)
+		if err != nil {
+			Logger.Error("Decrypt setting failed, key: ", key, " err: ", err)
+			return "", nil
+		}
+		return string(plain), nil
+	})
+	if err != nil {
+		return value
+	}
+	return res
+}
+
+// transformSecrets 对 fields 指定的字符串字段应用 fn, 其他字段保持不变
+func (s *SystemSettingService) transformSecrets(fields []string, value string, fn func(string) (string, error)) (string, error) {
+	if len(fields) == 0 {
+		return fn(value)
+	}
+	var m map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(value), &m); err != nil {
+		return "", err
+	}
+	changed := false
+	for _, f := range fields {
+		raw, ok := m[f]
+		if !ok {
+			continue
+		}
+		var v string
+		if err := json.Unmarshal(raw, &v); err != nil || v == "" {
+			continue
+		}
+		nv, err := fn(v)
+		if err != nil {
+			return "", err
+		}
+		if nv == v {
+			continue
+		}
+		m[f], _ = json.Marshal(nv)
+		changed = true
+	}
+	if !changed {
+		return value, nil
+	}
+	data, err := json.Marshal(m)
+	return string(data), err
+}
+
+// MigrateSecrets 启动时加密已有的明文密钥字段, 以及用新主密钥重新加密旧密钥加密的字段
+func (s *SystemSettingService) MigrateSecrets() {
+	if s.keys() == nil {
+		return
+	}
+	for _, key := range s.secretKeys() {
+		var setting model.SystemSetting
+		if err := DB.Where("key = ?", key).First(&setting).Error; err != nil {
+			continue
+		}
+		stored, err := s.encryptSecrets(key, setting.Value)
+		if err != nil {
+			Logger.Error("Encrypt setting failed, key: ", key, " err: ", err)
+			continue
+		}
+		if stored == setting.Value {
+			continue
+		}
+		if err := DB.Model(&setting).Update("value", stored).Error; err != nil {
+			Logger.Error("Save encrypted setting failed, key: ", key, " err: ", err)
+			continue
+		}
+		s.ClearCache(key)
+		Logger.Info("Setting secrets encrypted, key: ", key)
+	}
+}
@@ -0,0 +1,57 @@
+package service
+
+import (
+	"strings"
+
+	"github.com/lejianwen/rustdesk-api/v2/config"
+	"github.com/lejianwen/rustdesk-api/v2/model"
+)
+
+// ResolveTaxCountry 确定计税国家/地区: 请求参数优先, 其次取 Accept-Language 的地区部分(如 de-DE -> DE)
+func ResolveTaxCountry(country, acceptLanguage string) string {
+	if c := strings.ToUpper(strings.TrimSpace(country)); len(c) >= 2 && len(c) <= 8 {
+		return c
+	}
+	tag := strings.TrimSpace(strings.Split(strings.Split(acceptLanguage, ",")[0], ";")[0])
+	parts := strings.Split(strings.ReplaceAll(tag, "_", "-"), "-")
+	if len(parts) < 2 {
+		return ""
+	}
+	if region := parts[len(parts)-1]; len(region) == 2 {
+		return strings.ToUpper(region)
+	}
+	return ""
+}
+
+// taxRate 返回国家/地区适用的税率(百分比), 未单独配置时使用默认税率
+// viper 会将 map 的键转为小写, 因此按小写查找
+func taxRate(t config.Tax, country string) float64 {
+	if rate, ok := t.Rates[strings.ToLower(country)]; ok && country != "" {
+		return rate
+	}
+	return t.DefaultRate
+}
+
+// taxCountry 订单上记录的计税国家/地区, 未启用税费时为空, 用于复用待支付订单时匹配
+func taxCountry(country string) string {
+	if !Config.Payment.Tax.Enable {
+		return ""
+	}
+	return country
+}
+
+// applyTax 按配置计算订单税费, 不含税定价时订单金额加上税额
+func applyTax(order *model.Order, country string) {
+	t := Config.Payment.Tax
+	if !t.Enable || order.Amount <= 0 {
+		return
+	}
+	rate := taxRate(t, country)
+	tax, total := model.ComputeTax(order.Amount, rate, t.Inclusive)
+	order.TaxCountry = country
+	order.TaxRate = rate
+	order.TaxAmount = tax
+	order.TaxInclusive = t.Inclusive
+	order.Amount = total
+	order.AmountYuan = order.Total().String()
+}
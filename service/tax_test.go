@@ -0,0 +1,39 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/lejianwen/rustdesk-api/v2/config"
+)
+
+func TestResolveTaxCountry(t *testing.T) {
+	cases := []struct{ country, lang, want string }{
+		{"de", "fr-FR", "DE"},
+		{"", "fr-FR,fr;q=0.9", "FR"},
+		{"", "zh_CN", "CN"},
+		{"", "en", ""},
+		{"", "zh-Hans-CN", "CN"},
+		{"", "", ""},
+	}
+	for _, tc := range cases {
+		if got := ResolveTaxCountry(tc.country, tc.lang); got != tc.want {
+			t.Errorf("ResolveTaxCountry(%q, %q) = %q, want %q", tc.country, tc.lang, got, tc.want)
+		}
+	}
+}
+
+func TestTaxRate(t *testing.T) {
+	cfg := config.Tax{DefaultRate: 10, Rates: map[string]float64{"de": 19, "us": 0}}
+	if r := taxRate(cfg, "DE"); r != 19 {
+		t.Errorf("DE rate = %v", r)
+	}
+	if r := taxRate(cfg, "US"); r != 0 {
+		t.Errorf("US rate = %v", r)
+	}
+	if r := taxRate(cfg, "JP"); r != 10 {
+		t.Errorf("JP rate = %v", r)
+	}
+	if r := taxRate(cfg, ""); r != 10 {
+		t.Errorf("empty rate = %v", r)
+	}
+}
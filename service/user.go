@@ -97,14 +97,21 @@ func (us *UserService) GenerateToken(u *model.User) string {
 func (us *UserService) Login(u *model.User, llog *model.LoginLog) *model.UserToken {
 	token := us.GenerateToken(u)
 	ut := &model.UserToken{
-		UserId:     u.Id,
-		Token:      token,
-		DeviceUuid: llog.Uuid,
-		DeviceId:   llog.DeviceId,
-		ExpiredAt:  us.UserTokenExpireTimestamp(),
+		UserId:       u.Id,
+		Token:        token,
+		DeviceUuid:   llog.Uuid,
+		DeviceId:     llog.DeviceId,
+		ExpiredAt:    us.UserTokenExpireTimestamp(),
+		Client:       llog.Client,
+		Platform:     llog.Platform,
+		Ip:           llog.Ip,
+		LastActiveAt: time.Now().Unix(),
+	}
+	if us.RefreshTokenEnabled() {
+		us.issueRefreshToken(ut)
 	}
 	DB.Create(ut)
-	llog.UserTokenId = ut.UserId
+	llog.UserTokenId = ut.Id
 	DB.Create(llog)
 	if llog.Uuid != "" {
 		AllService.PeerService.UuidBindUserId(llog.DeviceId, llog.Uuid, u.Id)
@@ -193,15 +200,19 @@ func (us *UserService) GetUuidByToken(u *model.User, token string) string {
 	return ut.DeviceUuid
 }
 
-// Logout 退出登录 -> 删除token, 解绑uuid
+// Logout 退出登录 -> 删除token, 解绑uuid, 断开该会话的推送连接
 func (us *UserService) Logout(u *model.User, token string) error {
-	uuid := us.GetUuidByToken(u, token)
+	ut := &model.UserToken{}
+	DB.Where("user_id = ? and token = ?", u.Id, token).First(ut)
 	err := DB.Where("user_id = ? and token = ?", u.Id, token).Delete(&model.UserToken{}).Error
 	if err != nil {
 		return err
 	}
-	if uuid != "" {
-		AllService.PeerService.UuidUnbindUserId(uuid, u.Id)
+	if ut.Id > 0 {
+		AllService.PushService.DropSession(ut.Id)
+	}
+	if ut.DeviceUuid != "" {
+		AllService.PeerService.UuidUnbindUserId(ut.DeviceUuid, u.Id)
 	}
 	return nil
 }
@@ -209,7 +220,7 @@ func (us *UserService) Logout(u *model.User, token string) error {
 // Delete 删除用户和oauth信息
 func (us *UserService) Delete(u *model.User) error {
 	userCount := us.getAdminUserCount()
-	if userCount <= 1 && us.IsAdmin(u) {
+	if userCount <= 1 && us.IsSuperAdmin(u) {
 		return errors.New("The last admin user cannot be deleted")
 	}
 	tx := DB.Begin()
@@ -238,6 +249,33 @@ func (us *UserService) Delete(u *model.User) error {
 		tx.Rollback()
 		return err
 	}
+	//  删除关联的连接权限预设
+	if err := tx.Where("user_id = ?", u.Id).Delete(&model.ConnPermission{}).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	//  退出组织, 拥有的组织一并解散
+	if err := tx.Where("user_id = ? OR org_id IN (?)", u.Id, DB.Model(&model.Organization{}).Select("id").Where("owner_id = ?", u.Id)).Delete(&model.OrganizationMember{}).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Where("owner_id = ?", u.Id).Delete(&model.Organization{}).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	// 推荐码作废, 推荐记录保留用于统计
+	if err := tx.Where("user_id = ?", u.Id).Delete(&model.ReferralCode{}).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Where("user_id = ?", u.Id).Delete(&model.ReminderOptOut{}).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Where("user_id = ?", u.Id).Delete(&model.ApiToken{}).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
 	tx.Commit()
 	// 删除关联的peer
 	if err := AllService.PeerService.EraseUserId(u.Id); err != nil {
@@ -250,20 +288,42 @@ func (us *UserService) Delete(u *model.User) error {
 // Update 更新
 func (us *UserService) Update(u *model.User) error {
 	currentUser := us.InfoById(u.Id)
-	// 如果当前用户是管理员并且 IsAdmin 不为空，进行检查
-	if us.IsAdmin(currentUser) {
+	// 如果当前用户是超级管理员，进行检查
+	if us.IsSuperAdmin(currentUser) {
 		adminCount := us.getAdminUserCount()
-		// 如果这是唯一的管理员，确保不能禁用或取消管理员权限
-		if adminCount <= 1 && (!us.IsAdmin(u) || u.Status == model.COMMON_STATUS_DISABLED) {
+		after := *u
+		if after.AdminRole == "" {
+			after.AdminRole = currentUser.AdminRole
+		}
+		// 如果这是唯一的超级管理员，确保不能禁用、取消管理员权限或降为其他角色
+		if adminCount <= 1 && (!us.IsSuperAdmin(&after) || u.Status == model.COMMON_STATUS_DISABLED) {
 			return errors.New("The last admin user cannot be disabled or demoted")
 		}
 	}
-	return DB.Model(u).Updates(u).Error
+	if err := DB.Model(u).Updates(u).Error; err != nil {
+		return err
+	}
+	// 管理员修改邮箱后需要重新验证
+	if u.Email != "" && !strings.EqualFold(u.Email, currentUser.Email) {
+		DB.Model(u).Update("email_verified_at", 0)
+	}
+	if u.Status == model.COMMON_STATUS_DISABLED && currentUser.Status != model.COMMON_STATUS_DISABLED {
+		// 禁用后立即作废全部会话, 重新启用也需要重新登录
+		if err := us.FlushToken(u); err != nil {
+			Logger.Error("Flush token of disabled user failed: ", err)
+		}
+		AllService.EventBus.Publish(UserBannedEvent{User: us.InfoById(u.Id)})
+	}
+	return nil
 }
 
-// FlushToken 清空token
+// FlushToken 清空token, 并断开用户的推送连接
 func (us *UserService) FlushToken(u *model.User) error {
-	return DB.Where("user_id = ?", u.Id).Delete(&model.UserToken{}).Error
+	if err := DB.Where("user_id = ?", u.Id).Delete(&model.UserToken{}).Error; err != nil {
+		return err
+	}
+	AllService.PushService.DropUser(u.Id)
+	return nil
 }
 
 // FlushTokenByUuid 清空token
@@ -291,9 +351,27 @@ func (us *UserService) UpdatePassword(u *model.User, password string) error {
 	return err
 }
 
-// IsAdmin 是否管理员
+// IsAdmin 是否管理员(任意角色)
 func (us *UserService) IsAdmin(u *model.User) bool {
-	return u != nil && *u.IsAdmin
+	return u != nil && u.IsAdmin != nil && *u.IsAdmin
+}
+
+// IsSuperAdmin 是否超级管理员
+func (us *UserService) IsSuperAdmin(u *model.User) bool {
+	return us.IsAdmin(u) && model.NormalizeAdminRole(u.AdminRole) == model.AdminRoleSuper
+}
+
+// HasPermission 管理员是否拥有后台权限
+func (us *UserService) HasPermission(u *model.User, perm string) bool {
+	return us.IsAdmin(u) && model.AdminRoleAllows(u.AdminRole, perm)
+}
+
+// Permissions 管理员的后台权限, 非管理员为空
+func (us *UserService) Permissions(u *model.User) []string {
+	if !us.IsAdmin(u) {
+		return []string{}
+	}
+	return model.AdminRolePermissions[model.NormalizeAdminRole(u.AdminRole)]
 }
 
 // RouteNames
@@ -364,6 +442,10 @@ func (us *UserService) RegisterByOauth(oauthUser *model.OauthUser, op string) (e
 		GroupId:  1,
 	}
 	oauthUser.ToUser(user, false)
+	// 第三方已验证的邮箱视为已验证
+	if user.Email != "" && oauthUser.VerifiedEmail {
+		user.EmailVerifiedAt = time.Now().Unix()
+	}
 	tx.Create(user)
 	if user.Id == 0 {
 		tx.Rollback()
@@ -462,7 +544,11 @@ func (us *UserService) TokenInfoById(id uint) *model.UserToken {
 }
 
 func (us *UserService) DeleteToken(l *model.UserToken) error {
-	return DB.Delete(l).Error
+	if err := DB.Delete(l).Error; err != nil {
+		return err
+	}
+	AllService.PushService.DropSession(l.Id)
+	return nil
 }
 
 // Helper functions, used for formatting username
@@ -480,9 +566,10 @@ func (us *UserService) getUserCount() int64 {
 }
 
 // helper functions, getAdminUserCount
+// getAdminUserCount 超级管理员数量
 func (us *UserService) getAdminUserCount() int64 {
 	var count int64
-	DB.Model(&model.User{}).Where("is_admin = ?", true).Count(&count)
+	DB.Model(&model.User{}).Where("is_admin = ? AND admin_role IN ?", true, []string{"", model.AdminRoleSuper}).Count(&count)
 	return count
 }
 
@@ -502,11 +589,37 @@ func (us *UserService) RefreshAccessToken(ut *model.UserToken) {
 }
 
 func (us *UserService) AutoRefreshAccessToken(ut *model.UserToken) {
+	// 带刷新令牌的会话由客户端主动刷新
+	if ut.RefreshTokenHash != "" {
+		return
+	}
 	if ut.ExpiredAt-time.Now().Unix() < Config.App.TokenExpire.Milliseconds()/3000 {
 		us.RefreshAccessToken(ut)
 	}
 }
 
+// TouchAccessToken 记录会话最近活动时间和IP(1分钟内不重复写库)
+func (us *UserService) TouchAccessToken(ut *model.UserToken, ip string) {
+	now := time.Now().Unix()
+	if ut.Id == 0 || (now-ut.LastActiveAt < 60 && ut.Ip == ip) {
+		return
+	}
+	ut.LastActiveAt = now
+	ut.Ip = ip
+	DB.Model(ut).Updates(map[string]interface{}{"last_active_at": now, "ip": ip})
+}
+
+// ActiveSessionList 未过期的会话列表
+func (us *UserService) ActiveSessionList(page uint, size uint, f func(tx *gorm.DB)) *model.UserTokenList {
+	return us.TokenList(page, size, func(tx *gorm.DB) {
+		now := time.Now().Unix()
+		tx.Where("(expired_at > ? OR refresh_expired_at > ?)", now, now).Preload("User")
+		if f != nil {
+			f(tx)
+		}
+	})
+}
+
 func (us *UserService) BatchDeleteUserToken(ids []uint) error {
 	return DB.Where("id in ?", ids).Delete(&model.UserToken{}).Error
 }
@@ -0,0 +1,109 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/lejianwen/rustdesk-api/v2/model"
+)
+
+const (
+	refreshTokenPrefix       = "rrt_"
+	defaultAccessTokenExpire = 15 * time.Minute
+)
+
+var ErrRefreshTokenInvalid = errors.New("RefreshTokenInvalid")
+
+// RefreshTokenEnabled 是否签发刷新令牌, 由 app.refresh-token-expire 控制
+func (us *UserService) RefreshTokenEnabled() bool {
+	return Config.App.RefreshTokenExpire > 0
+}
+
+func accessTokenExpire() time.Duration {
+	if Config.App.AccessTokenExpire > 0 {
+		return Config.App.AccessTokenExpire
+	}
+	return defaultAccessTokenExpire
+}
+
+func hashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// issueRefreshToken 为会话生成新的刷新令牌, 并把访问令牌有效期改为 access-token-expire
+func (us *UserService) issueRefreshToken(ut *model.UserToken) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		Logger.Error("Generate refresh token failed: ", err)
+		return
+	}
+	now := time.Now()
+	ut.RefreshToken = refreshTokenPrefix + hex.EncodeToString(b)
+	ut.RefreshTokenHash = hashRefreshToken(ut.RefreshToken)
+	ut.RefreshExpiredAt = now.Add(Config.App.RefreshTokenExpire).Unix()
+	ut.ExpiredAt = now.Add(accessTokenExpire()).Unix()
+}
+
+// RefreshSession 使用刷新令牌换取新的访问令牌和刷新令牌, 旧令牌立即失效
+// 同一刷新令牌并发使用时只有一次成功
+func (us *UserService) RefreshSession(refreshToken, ip string) (*model.UserToken, error) {
+	if refreshToken == "" || !us.RefreshTokenEnabled() {
+		return nil, ErrRefreshTokenInvalid
+	}
+	ut := &model.UserToken{}
+	oldHash := hashRefreshToken(refreshToken)
+	DB.Where("refresh_token_hash = ?", oldHash).First(ut)
+	now := time.Now().Unix()
+	if ut.Id == 0 || ut.RefreshExpiredAt <= now {
+		return nil, ErrRefreshTokenInvalid
+	}
+	u := us.InfoById(ut.UserId)
+	if u.Id == 0 || !us.CheckUserEnable(u) {
+		return nil, ErrRefreshTokenInvalid
+	}
+	ut.Token = us.GenerateToken(u)
+	us.issueRefreshToken(ut)
+	ut.Ip = ip
+	ut.LastActiveAt = now
+	res := DB.Model(&model.UserToken{}).Where("id = ? AND refresh_token_hash = ?", ut.Id, oldHash).
+		Updates(map[string]interface{}{
+			"token":              ut.Token,
+			"expired_at":         ut.ExpiredAt,
+			"refresh_token_hash": ut.RefreshTokenHash,
+			"refresh_expired_at": ut.RefreshExpiredAt,
+			"ip":                 ut.Ip,
+			"last_active_at":     ut.LastActiveAt,
+		})
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return nil, ErrRefreshTokenInvalid
+	}
+	return ut, nil
+}
+
+// SessionsByUser 用户未过期的会话, 按最近活动时间倒序
+func (us *UserService) SessionsByUser(userId uint) []*model.UserToken {
+	res := make([]*model.UserToken, 0)
+	now := time.Now().Unix()
+	DB.Where("user_id = ? AND (expired_at > ? OR refresh_expired_at > ?)", userId, now, now).
+		Order("last_active_at desc").Find(&res)
+	return res
+}
+
+// RevokeSession 撤销会话并解绑设备, 与退出登录相同
+func (us *UserService) RevokeSession(ut *model.UserToken) error {
+	if err := DB.Delete(ut).Error; err != nil {
+		return err
+	}
+	AllService.PushService.DropSession(ut.Id)
+	if ut.DeviceUuid != "" {
+		AllService.PeerService.UuidUnbindUserId(ut.DeviceUuid, ut.UserId)
+	}
+	return nil
+}
@@ -0,0 +1,449 @@
+package service
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lejianwen/rustdesk-api/v2/model"
+	"gorm.io/gorm"
+)
+
+type WebhookService struct {
+}
+
+const (
+	webhookTimeout       = 10 * time.Second
+	webhookMaxAttempts   = 8
+	webhookRetryBase     = 30 * time.Second
+	webhookRetryInterval = 30 * time.Second
+	webhookRetryBatch    = 100
+)
+
+// WebhookEvent 外发事件内容
+type WebhookEvent struct {
+	Id        string      `json:"id"`
+	Type      string      `json:"type"`
+	CreatedAt int64       `json:"created_at"`
+	Data      interface{} `json:"data"`
+}
+
+func (ws *WebhookService) InfoById(id uint) *model.WebhookEndpoint {
+	e := &model.WebhookEndpoint{}
+	DB.Where("id = ?", id).First(e)
+	return e
+}
+
+func (ws *WebhookService) List(page, pageSize uint, where func(tx *gorm.DB)) (res *model.WebhookEndpointList) {
+	res = &model.WebhookEndpointList{}
+	res.Page = int64(page)
+	res.PageSize = int64(pageSize)
+	tx := DB.Model(&model.WebhookEndpoint{})
+	if where != nil {
+		where(tx)
+	}
+	tx.Count(&res.Total)
+	tx.Scopes(Paginate(page, pageSize))
+	tx.Find(&res.WebhookEndpoints)
+	return
+}
+
+// Create 创建, 未指定密钥时自动生成
+func (ws *WebhookService) Create(e *model.WebhookEndpoint) error {
+	if e.Secret == "" {
+		e.Secret = ws.GenerateSecret()
+	}
+	return DB.Create(e).Error
+}
+
+// Update 更新, 密钥为空时保留原密钥
+func (ws *WebhookService) Update(e *model.WebhookEndpoint) error {
+	tx := DB.Model(e).Select("name", "url", "events", "status")
+	if e.Secret != "" {
+		tx = DB.Model(e).Select("name", "url", "events", "status", "secret")
+	}
+	return tx.Updates(e).Error
+}
+
+func (ws *WebhookService) Delete(e *model.WebhookEndpoint) error {
+	return DB.Delete(e).Error
+}
+
+// GenerateSecret 生成签名密钥
+func (ws *WebhookService) GenerateSecret() string {
+	b := make([]byte, 24)
+	_, _ = rand.Read(b)
+	return "whsec_" + hex.EncodeToString(b)
+}
+
+func (ws *WebhookService) DeliveryList(page, pageSize uint, where func(tx *gorm.DB)) (res *model.WebhookDeliveryList) {
+	res = &model.WebhookDeliveryList{}
+	res.Page = int64(page)
+	res.PageSize = int64(pageSize)
+	tx := DB.Model(&model.WebhookDelivery{})
+	if where != nil {
+		where(tx)
+	}
+	tx.Count(&res.Total)
+	tx.Scopes(Paginate(page, pageSize))
+	tx.Find(&res.WebhookDeliveries)
+	return
+}
+
+// Dispatch 向订阅了该事件的启用地址投递事件(异步, 失败自动重试)
+// 应在业务事务提交之后调用
+func (ws *WebhookService) Dispatch(event string, data interface{}) {
+	var endpoints []*model.WebhookEndpoint
+	DB.Where("status = ?", model.COMMON_STATUS_ENABLE).Find(&endpoints)
+	if len(endpoints) == 0 {
+		return
+	}
+
+	evt, payload, err := ws.newEvent(uuid.New().String(), event, data)
+	if err != nil {
+		Logger.Error("Webhook marshal event failed: ", err)
+		return
+	}
+
+	for _, e := range endpoints {
+		if !e.Subscribes(event) {
+			continue
+		}
+		d := &model.WebhookDelivery{
+			EndpointId: e.Id,
+			EventId:    evt.Id,
+			Event:      event,
+			Payload:    string(payload),
+			Status:     model.WebhookDeliveryPending,
+			// 首次投递异步进行, 若进程在投递前退出则由重试任务兜底
+			NextRetryAt: time.Now().Add(webhookRetryBase).Unix(),
+		}
+		if err := DB.Create(d).Error; err != nil {
+			Logger.Error("Webhook create delivery failed: ", err)
+			continue
+		}
+		go ws.deliver(e, d)
+	}
+}
+
+func (ws *WebhookService) newEvent(id, event string, data interface{}) (*WebhookEvent, []byte, error) {
+	evt := &WebhookEvent{
+		Id:        id,
+		Type:      event,
+		CreatedAt: time.Now().Unix(),
+		Data:      data,
+	}
+	payload, err := json.Marshal(evt)
+	return evt, payload, err
+}
+
+// Redeliver 重新投递一条记录并同步返回结果
+// 新建投递记录, 沿用原事件ID和内容, 接收方可按 X-Webhook-Id 去重; 失败时按正常节奏继续重试
+func (ws *WebhookService) Redeliver(id uint) (*model.WebhookDelivery, error) {
+	src := &model.WebhookDelivery{}
+	DB.Where("id = ?", id).First(src)
+	if src.Id == 0 {
+		return nil, errors.New("ItemNotFound")
+	}
+	e := ws.InfoById(src.EndpointId)
+	if e.Id == 0 || e.Status != model.COMMON_STATUS_ENABLE {
+		return nil, errors.New("WebhookEndpointDisabled")
+	}
+	d := &model.WebhookDelivery{
+		EndpointId:  e.Id,
+		EventId:     src.EventId,
+		Event:       src.Event,
+		Payload:     src.Payload,
+		Status:      model.WebhookDeliveryPending,
+		NextRetryAt: time.Now().Add(webhookRetryBase).Unix(),
+		ReplayOf:    src.Id,
+		Test:        src.Test,
+	}
+	if err := DB.Create(d).Error; err != nil {
+		return nil, err
+	}
+	ws.deliver(e, d)
+	return d, nil
+}
+
+// RequeueFailed 将地址在 since(unix 秒) 之后重试耗尽的投递重新排队, 由重试任务投递; 用于接收方故障恢复后补发
+func (ws *WebhookService) RequeueFailed(endpointId uint, since int64) (int64, error) {
+	e := ws.InfoById(endpointId)
+	if e.Id == 0 || e.Status != model.COMMON_STATUS_ENABLE {
+		return 0, errors.New("WebhookEndpointDisabled")
+	}
+	res := DB.Model(&model.WebhookDelivery{}).
+		Where("endpoint_id = ? AND status = ? AND test = ? AND created_at >= ?", endpointId, model.WebhookDeliveryFailed, false, time.Unix(since, 0)).
+		Updates(map[string]interface{}{
+			"status":        model.WebhookDeliveryPending,
+			"attempts":      0,
+			"next_retry_at": time.Now().Unix(),
+		})
+	return res.RowsAffected, res.Error
+}
+
+// SampleEventData 各事件的示例数据, 与真实事件结构相同, 用于联调接收方
+func (ws *WebhookService) SampleEventData(event string) interface{} {
+	now := time.Now().Unix()
+	order := &model.Order{
+		OutTradeNo: "RDTEST00000000000000",
+		TradeNo:    "TEST0000000000",
+		UserId:     1,
+		PlanId:     1,
+		Amount:     1000,
+		AmountYuan: "10.00",
+		Currency:   model.CurrencyCNY,
+		Status:     model.OrderStatusPaid,
+		PaidAt:     now,
+	}
+	order.Id = 1
+	sub := &model.UserSubscription{UserId: 1, PlanId: 1, LastOrderId: 1, StartAt: now, ExpireAt: now + 30*86400, Status: model.SubscriptionStatusActive}
+	sub.Id = 1
+	switch event {
+	case model.WebhookEventOrderPaid:
+		return ws.OrderEventData(order)
+	case model.WebhookEventOrderRefunded:
+		order.Status = model.OrderStatusRefunded
+		order.RefundedAmount = order.Amount
+		data := ws.OrderEventData(order)
+		data["refund_amount"] = order.Amount
+		data["refund_reason"] = "test"
+		return data
+	case model.WebhookEventSubscriptionActivated:
+		return ws.SubscriptionEventData(sub)
+	case model.WebhookEventSubscriptionExpired:
+		sub.ExpireAt, sub.Status = now, model.SubscriptionStatusExpired
+		return ws.SubscriptionEventData(sub)
+	case model.WebhookEventUserBanned:
+		return map[string]interface{}{"id": 1, "username": "test", "email": "test@example.com", "status": model.COMMON_STATUS_DISABLED}
+	case model.WebhookEventProductPurchased, model.WebhookEventProductFulfilled:
+		order.PlanId, order.ProductId, order.FulfillmentStatus = 0, 1, model.FulfillmentPending
+		if event == model.WebhookEventProductFulfilled {
+			order.FulfillmentStatus, order.FulfillmentNote, order.FulfilledAt = model.FulfillmentDone, "test", now
+		}
+		return ws.OrderEventData(order)
+	case model.WebhookEventSubscriptionAnomaly:
+		return &model.SubscriptionAnomaly{Kind: model.SubscriptionAnomalyHorizon, Action: ExtensionGuardReject, UserId: 1, PlanId: 1, OrderId: 1,
+			Source: model.SubscriptionSourceNotify, ExpireAt: now + 20*365*86400, Recent: 1, DetectedAt: now}
+	case model.WebhookEventRelayAbuse:
+		incident := &model.RelayIncident{Kind: "test", Uuid: "test-uuid", UserId: 1, PeerId: "123456789", Count: 100, WindowSec: 60, FirstAt: now - 60, LastAt: now}
+		incident.Id = 1
+		return incident
+	}
+	return nil
+}
+
+// SendTest 向地址同步投递一条示例事件, 不重试; 投递记录标记为测试, 事件ID以 test_ 开头
+func (ws *WebhookService) SendTest(e *model.WebhookEndpoint, event string) (*model.WebhookDelivery, error) {
+	data := ws.SampleEventData(event)
+	if data == nil {
+		return nil, errors.New("ParamsError")
+	}
+	evt, payload, err := ws.newEvent("test_"+uuid.New().String(), event, data)
+	if err != nil {
+		return nil, err
+	}
+	d := &model.WebhookDelivery{
+		EndpointId: e.Id,
+		EventId:    evt.Id,
+		Event:      event,
+		Payload:    string(payload),
+		Test:       true,
+		Attempts:   1,
+	}
+	code, err := ws.post(e, d)
+	d.ResponseCode = code
+	d.Status = model.WebhookDeliverySuccess
+	if err != nil {
+		d.Status = model.WebhookDeliveryFailed
+		d.LastError = err.Error()
+	}
+	if err := DB.Create(d).Error; err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// deliver 投递一次并更新投递记录, 失败按指数退避安排下次重试
+func (ws *WebhookService) deliver(e *model.WebhookEndpoint, d *model.WebhookDelivery) {
+	code, err := ws.post(e, d)
+	d.Attempts++
+	d.ResponseCode = code
+	updates := map[string]interface{}{
+		"attempts":      d.Attempts,
+		"response_code": code,
+	}
+	if err == nil {
+		d.Status = model.WebhookDeliverySuccess
+		updates["status"] = d.Status
+		updates["last_error"] = ""
+	} else {
+		updates["last_error"] = err.Error()
+		if d.Attempts >= webhookMaxAttempts {
+			d.Status = model.WebhookDeliveryFailed
+			updates["status"] = d.Status
+			Logger.Warn("Webhook delivery failed permanently, endpoint: ", e.Id, " event: ", d.Event, " err: ", err)
+		} else {
+			// 30s, 1m, 2m, 4m ...
+			backoff := webhookRetryBase << (d.Attempts - 1)
+			updates["next_retry_at"] = time.Now().Add(backoff).Unix()
+		}
+	}
+	if err := DB.Model(d).Updates(updates).Error; err != nil {
+		Logger.Error("Webhook update delivery failed: ", err)
+	}
+}
+
+// post 发送签名请求, 签名头格式与 Stripe 相同: X-Webhook-Signature: t=时间戳,v1=HMAC-SHA256(secret, "t.body")
+func (ws *WebhookService) post(e *model.WebhookEndpoint, d *model.WebhookDelivery) (int, error) {
+	ts := strconv.FormatInt(time.Now().Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(e.Secret))
+	mac.Write([]byte(ts + "." + d.Payload))
+	sig := hex.EncodeToString(mac.Sum(nil))
+
+	req, err := http.NewRequest(http.MethodPost, e.Url, bytes.NewReader([]byte(d.Payload)))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "rustdesk-api-webhook")
+	req.Header.Set("X-Webhook-Id", d.EventId)
+	req.Header.Set("X-Webhook-Event", d.Event)
+	req.Header.Set("X-Webhook-Signature", fmt.Sprintf("t=%s,v1=%s", ts, sig))
+
+	client := &http.Client{Timeout: webhookTimeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, errors.New("unexpected status: " + resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+// RetryPending 重试到期的待投递记录
+func (ws *WebhookService) RetryPending() int {
+	var deliveries []*model.WebhookDelivery
+	DB.Where("status = ? AND next_retry_at <= ?", model.WebhookDeliveryPending, time.Now().Unix()).
+		Order("id ASC").Limit(webhookRetryBatch).Find(&deliveries)
+	for _, d := range deliveries {
+		e := ws.InfoById(d.EndpointId)
+		if e.Id == 0 || e.Status != model.COMMON_STATUS_ENABLE {
+			DB.Model(d).Updates(map[string]interface{}{
+				"status":     model.WebhookDeliveryFailed,
+				"last_error": "endpoint removed or disabled",
+			})
+			continue
+		}
+		ws.deliver(e, d)
+	}
+	return len(deliveries)
+}
+
+// StartRetryJob 启动重试任务
+func (ws *WebhookService) StartRetryJob() {
+	go func() {
+		ticker := time.NewTicker(webhookRetryInterval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			ws.RetryPending()
+		}
+	}()
+}
+
+// OrderEventData 订单事件数据
+func (ws *WebhookService) OrderEventData(o *model.Order) map[string]interface{} {
+	return map[string]interface{}{
+		"id":                 o.Id,
+		"out_trade_no":       o.OutTradeNo,
+		"trade_no":           o.TradeNo,
+		"user_id":            o.UserId,
+		"plan_id":            o.PlanId,
+		"amount":             o.Amount,
+		"amount_yuan":        o.AmountYuan,
+		"currency":           o.Currency,
+		"refunded_amount":    o.RefundedAmount,
+		"status":             o.Status,
+		"paid_at":            o.PaidAt,
+		"product_id":         o.ProductId,
+		"fulfillment_status": o.FulfillmentStatus,
+		"fulfillment_note":   o.FulfillmentNote,
+		"fulfilled_at":       o.FulfilledAt,
+	}
+}
+
+// SubscriptionEventData 订阅事件数据
+func (ws *WebhookService) SubscriptionEventData(s *model.UserSubscription) map[string]interface{} {
+	return map[string]interface{}{
+		"id":            s.Id,
+		"user_id":       s.UserId,
+		"plan_id":       s.PlanId,
+		"last_order_id": s.LastOrderId,
+		"start_at":      s.StartAt,
+		"expire_at":     s.ExpireAt,
+		"status":        s.Status,
+	}
+}
+
+// subscribeEvents 将内部事件转发为外发 webhook
+func (ws *WebhookService) subscribeEvents(bus *EventBus) {
+	On(bus, func(e OrderPaidEvent) {
+		ws.Dispatch(e.EventName(), ws.OrderEventData(e.Order))
+	})
+	On(bus, func(e OrderRefundedEvent) {
+		data := ws.OrderEventData(e.Order)
+		data["refund_amount"] = e.Refund.Amount
+		data["refund_reason"] = e.Refund.Reason
+		ws.Dispatch(e.EventName(), data)
+	})
+	On(bus, func(e SubscriptionActivatedEvent) {
+		ws.Dispatch(e.EventName(), ws.SubscriptionEventData(e.Subscription))
+	})
+	On(bus, func(e SubscriptionExpiredEvent) {
+		ws.Dispatch(e.EventName(), ws.SubscriptionEventData(e.Subscription))
+	})
+	On(bus, func(e SubscriptionExpiringEvent) {
+		data := ws.SubscriptionEventData(e.Subscription)
+		data["days"] = e.Days
+		ws.Dispatch(e.EventName(), data)
+	})
+	On(bus, func(e SubscriptionLapsedEvent) {
+		data := ws.SubscriptionEventData(e.Subscription)
+		data["days"] = e.Days
+		ws.Dispatch(e.EventName(), data)
+	})
+	On(bus, func(e UserBannedEvent) {
+		ws.Dispatch(e.EventName(), map[string]interface{}{
+			"id":       e.User.Id,
+			"username": e.User.Username,
+			"email":    e.User.Email,
+			"status":   e.User.Status,
+		})
+	})
+	On(bus, func(e RelayAbuseEvent) {
+		ws.Dispatch(e.EventName(), e.Incident)
+	})
+	On(bus, func(e SubscriptionAnomalyEvent) {
+		ws.Dispatch(e.EventName(), e.Anomaly)
+	})
+	On(bus, func(e ProductPurchasedEvent) {
+		ws.Dispatch(e.EventName(), ws.OrderEventData(e.Order))
+	})
+	On(bus, func(e ProductFulfilledEvent) {
+		ws.Dispatch(e.EventName(), ws.OrderEventData(e.Order))
+	})
+}
@@ -0,0 +1,97 @@
+package utils
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Locale 按请求语言和时区格式化金额、时间, 供接口返回展示字段
+type Locale struct {
+	Lang string         // 语言, 如 zh、en、de
+	Loc  *time.Location // 时区
+}
+
+var currencySymbols = map[string]string{
+	"CNY": "¥",
+	"USD": "$",
+	"EUR": "€",
+}
+
+// commaDecimalLangs 使用逗号作小数点, 货币符号后置的语言
+var commaDecimalLangs = map[string]bool{
+	"de": true, "fr": true, "es": true, "it": true, "nl": true, "pt": true, "ru": true,
+	"fi": true, "el": true, "sk": true, "sl": true, "et": true, "lv": true, "lt": true, "pl": true, "cs": true,
+}
+
+var timeLayouts = map[string]string{
+	"zh": "2006-01-02 15:04",
+	"ja": "2006/01/02 15:04",
+	"ko": "2006.01.02 15:04",
+	"en": "Jan 2, 2006 15:04",
+	"de": "02.01.2006 15:04",
+	"ru": "02.01.2006 15:04",
+}
+
+// NewLocale 根据 Accept-Language 和 IANA 时区名创建, 无法识别时使用 en 和服务器时区
+func NewLocale(acceptLanguage, timezone string) *Locale {
+	l := &Locale{Lang: "en", Loc: time.Local}
+	tag := strings.TrimSpace(strings.Split(strings.Split(acceptLanguage, ",")[0], ";")[0])
+	if tag != "" {
+		l.Lang = strings.ToLower(strings.Split(strings.ReplaceAll(tag, "_", "-"), "-")[0])
+	}
+	if tz := strings.TrimSpace(timezone); tz != "" {
+		if loc, err := time.LoadLocation(tz); err == nil {
+			l.Loc = loc
+		}
+	}
+	return l
+}
+
+// Money 格式化金额, amount 为最小货币单位(分), 如 ¥12.34、$1,234.00、12,34 €
+func (l *Locale) Money(amount int64, currency string) string {
+	sign := ""
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+	decimal, group := ".", ","
+	if commaDecimalLangs[l.Lang] {
+		decimal, group = ",", "."
+	}
+	whole := strconv.FormatInt(amount/100, 10)
+	var sb strings.Builder
+	for i, ch := range whole {
+		if i > 0 && (len(whole)-i)%3 == 0 {
+			sb.WriteString(group)
+		}
+		sb.WriteRune(ch)
+	}
+	frac := strconv.FormatInt(amount%100+100, 10)[1:]
+	number := sb.String() + decimal + frac
+
+	symbol, ok := currencySymbols[currency]
+	if !ok {
+		return sign + number + " " + currency
+	}
+	if commaDecimalLangs[l.Lang] {
+		return sign + number + " " + symbol
+	}
+	return sign + symbol + number
+}
+
+// Time 格式化 unix 秒时间戳, 0 返回空字符串
+func (l *Locale) Time(ts int64) string {
+	if ts == 0 {
+		return ""
+	}
+	layout, ok := timeLayouts[l.Lang]
+	if !ok {
+		if commaDecimalLangs[l.Lang] {
+			layout = "02/01/2006 15:04"
+		} else {
+			layout = timeLayouts["en"]
+		}
+	}
+	return time.Unix(ts, 0).In(l.Loc).Format(layout + " MST")
+}
@@ -0,0 +1,38 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLocaleMoney(t *testing.T) {
+	cases := []struct {
+		lang, currency string
+		amount         int64
+		want           string
+	}{
+		{"zh-CN,zh;q=0.9", "CNY", 1234, "¥12.34"},
+		{"en-US", "USD", 123456789, "$1,234,567.89"},
+		{"de-DE", "EUR", 123405, "1.234,05 €"},
+		{"en", "JPY", 500, "5.00 JPY"},
+		{"en", "USD", -5, "-$0.05"},
+	}
+	for _, tc := range cases {
+		if got := NewLocale(tc.lang, "").Money(tc.amount, tc.currency); got != tc.want {
+			t.Errorf("Money(%s, %d, %s) = %q, want %q", tc.lang, tc.amount, tc.currency, got, tc.want)
+		}
+	}
+}
+
+func TestLocaleTime(t *testing.T) {
+	ts := time.Date(2024, 3, 5, 16, 30, 0, 0, time.UTC).Unix()
+	if got := NewLocale("zh-CN", "Asia/Shanghai").Time(ts); got != "2024-03-06 00:30 CST" {
+		t.Errorf("zh Time = %q", got)
+	}
+	if got := NewLocale("en-US", "UTC").Time(ts); got != "Mar 5, 2024 16:30 UTC" {
+		t.Errorf("en Time = %q", got)
+	}
+	if got := NewLocale("en", "Invalid/Zone").Time(0); got != "" {
+		t.Errorf("zero Time = %q", got)
+	}
+}
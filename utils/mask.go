@@ -0,0 +1,70 @@
+package utils
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// MaskString 遮蔽字符串中间部分, 短字符串全部遮蔽
+func MaskString(s string) string {
+	if s == "" {
+		return ""
+	}
+	if len(s) <= 8 {
+		return "****"
+	}
+	return s[:4] + "****" + s[len(s)-4:]
+}
+
+// secretFieldNames 需要遮蔽的字段名(小写), 以及按后缀匹配的字段
+var (
+	secretFieldNames    = []string{"key", "pid", "secret", "password", "token"}
+	secretFieldSuffixes = []string{"_key", "_secret", "password", "_token"}
+)
+
+// IsSecretField 字段名是否为密钥类字段
+func IsSecretField(name string) bool {
+	name = strings.ToLower(name)
+	for _, n := range secretFieldNames {
+		if name == n {
+			return true
+		}
+	}
+	for _, s := range secretFieldSuffixes {
+		if strings.HasSuffix(name, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// MaskSecretJSON 将 v 序列化为 JSON, 并遮蔽其中密钥类字段的字符串值(递归处理嵌套对象和数组)
+func MaskSecretJSON(v interface{}) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var tree interface{}
+	if err := json.Unmarshal(data, &tree); err != nil {
+		return nil, err
+	}
+	return json.Marshal(maskSecretValue(tree))
+}
+
+func maskSecretValue(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for k, val := range t {
+			if s, ok := val.(string); ok && IsSecretField(k) {
+				t[k] = MaskString(s)
+				continue
+			}
+			t[k] = maskSecretValue(val)
+		}
+	case []interface{}:
+		for i, val := range t {
+			t[i] = maskSecretValue(val)
+		}
+	}
+	return v
+}
@@ -0,0 +1,38 @@
+package utils
+
+import "testing"
+
+func TestMaskString(t *testing.T) {
+	cases := map[string]string{
+		"":                 "",
+		"short":            "****",
+		"0123456789abcdef": "0123****cdef",
+	}
+	for in, want := range cases {
+		if got := MaskString(in); got != want {
+			t.Errorf("MaskString(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestMaskSecretJSON(t *testing.T) {
+	v := map[string]interface{}{
+		"pid":      "1001",
+		"key":      "0123456789abcdef",
+		"base_url": "https://pay.example.com",
+		"s3": map[string]interface{}{
+			"s3_secret_key": "secretsecretsecret",
+			"bucket":        "files",
+		},
+		"users":   []interface{}{map[string]interface{}{"password": "hunter2", "name": "a"}},
+		"timeout": 30,
+	}
+	got, err := MaskSecretJSON(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"base_url":"https://pay.example.com","key":"0123****cdef","pid":"****","s3":{"bucket":"files","s3_secret_key":"secr****cret"},"timeout":30,"users":[{"name":"a","password":"****"}]}`
+	if string(got) != want {
+		t.Fatalf("got %s\nwant %s", got, want)
+	}
+}
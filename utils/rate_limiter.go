@@ -0,0 +1,83 @@
+package utils
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// RateLimiter 按 key 的令牌桶限流, 桶满后闲置的 key 会被定期清理
+type RateLimiter struct {
+	mu        sync.Mutex
+	rate      float64 // 每秒补充的令牌数
+	burst     float64 // 桶容量
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+const rateLimiterSweepInterval = time.Minute
+
+// NewRateLimiter rate 为每秒补充的令牌数, burst 为桶容量(最少为 1)
+func NewRateLimiter(rate float64, burst int) *RateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &RateLimiter{
+		rate:    rate,
+		burst:   float64(burst),
+		buckets: make(map[string]*tokenBucket),
+		now:     time.Now,
+	}
+}
+
+// Allow 消耗 key 的一个令牌; 令牌不足时返回 false 和需要等待的时长
+func (rl *RateLimiter) Allow(key string) (bool, time.Duration) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	now := rl.now()
+	if now.Sub(rl.lastSweep) >= rateLimiterSweepInterval {
+		rl.sweep(now)
+		rl.lastSweep = now
+	}
+
+	b, ok := rl.buckets[key]
+	if !ok {
+		b = &tokenBucket{tokens: rl.burst, last: now}
+		rl.buckets[key] = b
+	} else {
+		b.tokens = math.Min(rl.burst, b.tokens+now.Sub(b.last).Seconds()*rl.rate)
+		b.last = now
+	}
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	if rl.rate <= 0 {
+		return false, time.Hour
+	}
+	wait := time.Duration((1 - b.tokens) / rl.rate * float64(time.Second))
+	return false, wait
+}
+
+// sweep 清理已补满的桶, 这些 key 重新出现时与新建桶等价
+func (rl *RateLimiter) sweep(now time.Time) {
+	for k, b := range rl.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*rl.rate >= rl.burst {
+			delete(rl.buckets, k)
+		}
+	}
+}
+
+// Len 当前跟踪的 key 数量
+func (rl *RateLimiter) Len() int {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	return len(rl.buckets)
+}
@@ -0,0 +1,51 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRateLimiter(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	rl := NewRateLimiter(1, 3)
+	rl.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		if ok, _ := rl.Allow("a"); !ok {
+			t.Fatalf("request %d should be allowed within burst", i)
+		}
+	}
+	ok, wait := rl.Allow("a")
+	if ok || wait != time.Second {
+		t.Fatalf("want rejected with 1s wait, got %v %v", ok, wait)
+	}
+	if ok, _ := rl.Allow("b"); !ok {
+		t.Fatal("keys should have independent buckets")
+	}
+
+	now = now.Add(500 * time.Millisecond)
+	if ok, wait := rl.Allow("a"); ok || wait != 500*time.Millisecond {
+		t.Fatalf("want rejected with 500ms wait, got %v %v", ok, wait)
+	}
+	now = now.Add(500 * time.Millisecond)
+	if ok, _ := rl.Allow("a"); !ok {
+		t.Fatal("token should be refilled after 1s")
+	}
+}
+
+func TestRateLimiterSweep(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	rl := NewRateLimiter(1, 2)
+	rl.now = func() time.Time { return now }
+
+	rl.Allow("a")
+	rl.Allow("b")
+	if rl.Len() != 2 {
+		t.Fatalf("want 2 buckets, got %d", rl.Len())
+	}
+	now = now.Add(2 * time.Minute)
+	rl.Allow("c")
+	if rl.Len() != 1 {
+		t.Fatalf("idle full buckets should be swept, got %d", rl.Len())
+	}
+}
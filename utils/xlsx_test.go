@@ -0,0 +1,50 @@
+package utils
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestXlsxColumn(t *testing.T) {
+	cases := map[int]string{0: "A", 25: "Z", 26: "AA", 27: "AB", 701: "ZZ", 702: "AAA"}
+	for i, want := range cases {
+		if got := xlsxColumn(i); got != want {
+			t.Errorf("xlsxColumn(%d) = %s, want %s", i, got, want)
+		}
+	}
+}
+
+func TestXlsxWriter(t *testing.T) {
+	buf := &bytes.Buffer{}
+	x, err := NewXlsxWriter(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := x.WriteRow([]string{"订单号", "a<b&c"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := x.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range zr.File {
+		if f.Name != "xl/worksheets/sheet1.xml" {
+			continue
+		}
+		rc, _ := f.Open()
+		data, _ := io.ReadAll(rc)
+		rc.Close()
+		if !strings.Contains(string(data), `<c r="B1" t="inlineStr"><is><t xml:space="preserve">a&lt;b&amp;c</t></is></c>`) {
+			t.Errorf("unexpected sheet xml: %s", data)
+		}
+		return
+	}
+	t.Fatal("sheet1.xml not found")
+}